@@ -46,6 +46,23 @@ func main() {
 		zap.String("config", configPath),
 		zap.Bool("tls_enabled", config.Server.TLS.Enabled))
 
+	// 初始化访问日志
+	var accessLogger *gateway.AccessLogger
+	if config.AccessLog.Enabled {
+		accessLogger, err = gateway.NewAccessLogger(&config.AccessLog)
+		if err != nil {
+			logger.Fatal("Failed to setup access log", zap.Error(err))
+		}
+		defer accessLogger.Close()
+	}
+
+	// 初始化管理API审计日志
+	auditLogger, err := gateway.NewAuditLogger(&config.Audit, logger)
+	if err != nil {
+		logger.Fatal("Failed to setup audit log", zap.Error(err))
+	}
+	defer auditLogger.Close()
+
 	// 初始化Redis客户端
 	var redisClient *redis.Client
 	if config.RateLimit.Redis {
@@ -71,19 +88,14 @@ func main() {
 	}
 
 	// 创建核心组件
-	gatewayRouter := gateway.NewRouter(logger, nil, nil)
 	loadBalancer := gateway.NewLoadBalancer(logger)
 	serviceDiscovery := gateway.NewServiceDiscovery(&config.LoadBalance.HealthCheck, logger)
+	gatewayRouter := gateway.NewRouter(logger, loadBalancer, serviceDiscovery)
 	metricsCollector := metrics.NewCollector(logger)
 
 	// 初始化认证器
-	authenticator := auth.NewAuthenticator(&auth.AuthConfig{
-		Strategy:    auth.AuthStrategy(config.Auth.Strategy),
-		JWTSecret:   config.Auth.JWTSecret,
-		TokenExpiry: config.Auth.TokenExpiry,
-		Issuer:      config.Auth.Issuer,
-		Audience:    config.Auth.Audience,
-	}, logger)
+	authConfig := config.Auth.AuthenticatorConfig()
+	authenticator := auth.NewAuthenticator(authConfig, logger)
 
 	// 创建限流器
 	rateLimiterConfig := &ratelimit.LimitConfig{
@@ -113,7 +125,8 @@ func main() {
 		logger.Fatal("Failed to load services", zap.Error(err))
 	}
 
-	// 启动服务发现
+	// 启动服务发现，健康检查结果同步到负载均衡目标
+	serviceDiscovery.OnHealthCheck(loadBalancer.ApplyHealthStatus)
 	ctx := context.Background()
 	if err := serviceDiscovery.Start(ctx); err != nil {
 		logger.Fatal("Failed to start service discovery", zap.Error(err))
@@ -126,6 +139,7 @@ func main() {
 		authenticator,
 		metricsCollector,
 		rateLimiter,
+		auditLogger,
 		logger,
 	)
 
@@ -135,7 +149,7 @@ func main() {
 	}
 
 	// 创建HTTP服务器
-	router := setupRouter(config, gatewayHandler, gatewayRouter, authenticator, rateLimiter, rateLimiterConfig, metricsCollector, logger)
+	router := setupRouter(config, gatewayHandler, gatewayRouter, authenticator, rateLimiter, rateLimiterConfig, metricsCollector, accessLogger, logger)
 
 	server := &http.Server{
 		Addr:           config.GetServerAddress(),
@@ -237,12 +251,16 @@ func setupRouter(
 	rateLimiter ratelimit.RateLimiter,
 	rateLimiterConfig *ratelimit.LimitConfig,
 	collector *metrics.Collector,
+	accessLogger *gateway.AccessLogger,
 	logger *zap.Logger,
 ) *gin.Engine {
 	router := gin.New()
 
 	// 中间件
 	router.Use(gin.Recovery())
+	if accessLogger != nil {
+		router.Use(accessLogger.Middleware())
+	}
 	router.Use(collector.Middleware())
 
 	// 健康检查（不需要认证和限流）
@@ -258,7 +276,7 @@ func setupRouter(
 
 	// 管理API（需要认证）
 	admin := router.Group("/admin")
-	if config.Auth.Strategy != "none" {
+	if config.Auth.AuthenticatorConfig().Enabled() {
 		admin.Use(authenticator.Middleware())
 		admin.Use(auth.RequireScope("admin"))
 	}
@@ -269,6 +287,7 @@ func setupRouter(
 		admin.GET("/routes/:method/*path", gatewayHandler.GetRoute)
 		admin.PUT("/routes/:method/*path", gatewayHandler.UpdateRoute)
 		admin.DELETE("/routes/:method/*path", gatewayHandler.DeleteRoute)
+		admin.GET("/routing/trace", gatewayHandler.TraceRoute)
 
 		// 负载均衡管理
 		admin.GET("/loadbalancer/stats", gatewayHandler.GetLoadBalancerStats)
@@ -299,7 +318,7 @@ func setupRouter(
 	proxy := router.Group("/")
 
 	// 认证中间件
-	if config.Auth.Strategy != "none" {
+	if config.Auth.AuthenticatorConfig().Enabled() {
 		proxy.Use(authenticator.Middleware())
 	}
 
@@ -324,8 +343,18 @@ func loadRoutesFromConfig(router *gateway.Router, config *gateway.Config) error
 			Target:      routeConfig.Target,
 			StripPrefix: routeConfig.StripPrefix,
 			Headers:     routeConfig.Headers,
+			Service:     routeConfig.Service,
 			Timeout:     routeConfig.Timeout,
 			Retries:     routeConfig.Retries,
+			Retry:       routeConfig.Retry,
+			Match:       routeConfig.Match,
+			Priority:    routeConfig.Priority,
+
+			Fallback:       routeConfig.Fallback,
+			CircuitBreaker: routeConfig.CircuitBreaker,
+		}
+		if routeConfig.Auth != nil {
+			route.Scopes = routeConfig.Auth.Scopes
 		}
 
 		if err := router.AddRoute(route); err != nil {
@@ -341,13 +370,16 @@ func loadServicesFromConfig(loadBalancer *gateway.LoadBalancer, discovery *gatew
 	for _, serviceConfig := range config.Services {
 		// 创建服务组
 		targets := make([]*gateway.Target, 0, len(serviceConfig.Targets))
-		for _, targetConfig := range serviceConfig.Targets {
+		for i := range serviceConfig.Targets {
+			targetConfig := &serviceConfig.Targets[i]
+			healthCheck := serviceConfig.TargetHealthCheck(targetConfig, config.LoadBalance.HealthCheck)
 			target := &gateway.Target{
 				ID:          targetConfig.ID,
 				URL:         targetConfig.URL,
 				Weight:      targetConfig.Weight,
 				Metadata:    targetConfig.Metadata,
-				IsHealthy:   true, // 初始假设健康
+				IsHealthy:   true, // 初始假设健康，由健康检查按阈值上下线
+				HealthCheck: &healthCheck,
 			}
 			targets = append(targets, target)
 		}
@@ -361,7 +393,7 @@ func loadServicesFromConfig(loadBalancer *gateway.LoadBalancer, discovery *gatew
 		loadBalancer.AddServiceGroup(serviceGroup)
 
 		// 注册服务到服务发现
-		serviceInfos := gateway.CreateServiceFromConfig(&serviceConfig)
+		serviceInfos := gateway.CreateServiceFromConfig(&serviceConfig, config.LoadBalance.HealthCheck)
 		for _, serviceInfo := range serviceInfos {
 			discovery.RegisterService(serviceInfo)
 		}
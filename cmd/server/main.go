@@ -87,9 +87,36 @@ func main() {
 		os.Exit(0)
 	}
 
+	// 初始化Redis（可选，失败时相关功能降级）
+	if err := database.InitializeRedis(cfg); err != nil {
+		zapLogger.Warn("Redis unavailable, features depending on it are degraded", zap.Error(err))
+	}
+
 	// 创建服务器
 	srv := server.NewServer(cfg, zapLogger)
 
+	// 监听配置文件变更，日志级别、限流和告警配置热加载，其余配置修改后提示重启
+	config.Watch(func(newCfg *config.Config, restartRequired []string, err error) {
+		if err != nil {
+			zapLogger.Error("Failed to reload config", zap.Error(err))
+			return
+		}
+
+		if err := logger.SetLevel(newCfg.Log.Level); err != nil {
+			zapLogger.Warn("Invalid log level in reloaded config", zap.Error(err))
+		}
+		srv.ApplyConfig(newCfg)
+
+		zapLogger.Info("Config reloaded",
+			zap.String("log_level", newCfg.Log.Level),
+			zap.Bool("rate_limit", newCfg.RateLimit.Enabled),
+			zap.Int("requests_per_minute", newCfg.RateLimit.RequestsPerMinute))
+		if len(restartRequired) > 0 {
+			zapLogger.Warn("Config changes require a restart to take effect",
+				zap.Strings("keys", restartRequired))
+		}
+	})
+
 	// 启动服务器
 	go func() {
 		zapLogger.Info("Starting HTTP server",
@@ -123,5 +150,10 @@ func main() {
 		zapLogger.Error("Failed to close database", zap.Error(err))
 	}
 
+	// 关闭Redis连接
+	if err := database.CloseRedis(); err != nil {
+		zapLogger.Error("Failed to close redis", zap.Error(err))
+	}
+
 	zapLogger.Info("Application stopped")
 }
\ No newline at end of file
@@ -0,0 +1,290 @@
+package alarm
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/env-data-platform/internal/config"
+)
+
+// 通知通道名称
+const (
+	ChannelEmail    = "email"
+	ChannelDingTalk = "dingtalk"
+	ChannelWeCom    = "wecom"
+	ChannelWebhook  = "webhook"
+)
+
+// postJSON 以JSON格式POST请求，返回响应体
+func postJSON(ctx context.Context, target string, payload interface{}, headers map[string]string) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("序列化通知内容失败: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return body, fmt.Errorf("HTTP状态码 %d: %s", resp.StatusCode, string(body))
+	}
+	return body, nil
+}
+
+// robotResponse 钉钉/企业微信机器人接口响应
+type robotResponse struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+// checkRobotResponse 校验机器人接口返回的错误码
+func checkRobotResponse(body []byte) error {
+	var resp robotResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("解析响应失败: %w", err)
+	}
+	if resp.ErrCode != 0 {
+		return fmt.Errorf("机器人返回错误 %d: %s", resp.ErrCode, resp.ErrMsg)
+	}
+	return nil
+}
+
+// emailChannel 邮件通知通道
+type emailChannel struct {
+	cfg config.EmailNotifyConfig
+}
+
+func newEmailChannel(cfg config.EmailNotifyConfig) *emailChannel {
+	return &emailChannel{cfg: cfg}
+}
+
+// Name 通道名称
+func (c *emailChannel) Name() string {
+	return ChannelEmail
+}
+
+// Send 发送告警邮件
+func (c *emailChannel) Send(ctx context.Context, event *AlarmEvent) error {
+	title, body := formatAlarmText(event)
+	return SendEmail(ctx, c.cfg, c.cfg.To, title, body)
+}
+
+// SendEmail 通过cfg配置的SMTP服务器向to发送纯文本邮件，cfg.To被忽略
+func SendEmail(ctx context.Context, cfg config.EmailNotifyConfig, to []string, subject, body string) error {
+	if len(to) == 0 {
+		return fmt.Errorf("未配置收件人")
+	}
+
+	msg := buildEmailMessage(cfg.From, to, subject, body)
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+
+	dialer := &net.Dialer{}
+	var conn net.Conn
+	var err error
+	if cfg.SSL {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: cfg.Host}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("连接邮件服务器失败: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("创建SMTP客户端失败: %w", err)
+	}
+	defer client.Close()
+
+	if !cfg.SSL {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: cfg.Host}); err != nil {
+				return fmt.Errorf("STARTTLS失败: %w", err)
+			}
+		}
+	}
+	if cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return fmt.Errorf("SMTP认证失败: %w", err)
+		}
+	}
+
+	if err := client.Mail(cfg.From); err != nil {
+		return fmt.Errorf("设置发件人失败: %w", err)
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("设置收件人%s失败: %w", rcpt, err)
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("发送邮件内容失败: %w", err)
+	}
+	if _, err := writer.Write(msg); err != nil {
+		writer.Close()
+		return fmt.Errorf("发送邮件内容失败: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("发送邮件内容失败: %w", err)
+	}
+	return client.Quit()
+}
+
+// buildEmailMessage 构建UTF-8纯文本邮件
+func buildEmailMessage(from string, to []string, subject, body string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("From: " + from + "\r\n")
+	buf.WriteString("To: " + strings.Join(to, ", ") + "\r\n")
+	buf.WriteString("Subject: =?UTF-8?B?" + base64.StdEncoding.EncodeToString([]byte(subject)) + "?=\r\n")
+	buf.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+	buf.WriteString(base64.StdEncoding.EncodeToString([]byte(body)))
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+// dingTalkChannel 钉钉机器人通知通道
+type dingTalkChannel struct {
+	cfg config.DingTalkNotifyConfig
+}
+
+func newDingTalkChannel(cfg config.DingTalkNotifyConfig) *dingTalkChannel {
+	return &dingTalkChannel{cfg: cfg}
+}
+
+// Name 通道名称
+func (c *dingTalkChannel) Name() string {
+	return ChannelDingTalk
+}
+
+// Send 发送钉钉机器人消息
+func (c *dingTalkChannel) Send(ctx context.Context, event *AlarmEvent) error {
+	title, body := formatAlarmText(event)
+	payload := map[string]interface{}{
+		"msgtype": "text",
+		"text":    map[string]string{"content": title + "\n" + body},
+		"at":      map[string]interface{}{"atMobiles": c.cfg.AtMobiles},
+	}
+
+	target := c.cfg.Webhook
+	if c.cfg.Secret != "" {
+		var err error
+		target, err = signDingTalkURL(target, c.cfg.Secret, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+
+	resp, err := postJSON(ctx, target, payload, nil)
+	if err != nil {
+		return err
+	}
+	return checkRobotResponse(resp)
+}
+
+// signDingTalkURL 为钉钉机器人webhook追加加签参数
+func signDingTalkURL(webhook, secret string, now time.Time) (string, error) {
+	u, err := url.Parse(webhook)
+	if err != nil {
+		return "", fmt.Errorf("无效的钉钉webhook地址: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(now.UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + secret))
+
+	query := u.Query()
+	query.Set("timestamp", timestamp)
+	query.Set("sign", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// weComChannel 企业微信机器人通知通道
+type weComChannel struct {
+	cfg config.WeComNotifyConfig
+}
+
+func newWeComChannel(cfg config.WeComNotifyConfig) *weComChannel {
+	return &weComChannel{cfg: cfg}
+}
+
+// Name 通道名称
+func (c *weComChannel) Name() string {
+	return ChannelWeCom
+}
+
+// Send 发送企业微信机器人消息
+func (c *weComChannel) Send(ctx context.Context, event *AlarmEvent) error {
+	title, body := formatAlarmText(event)
+	payload := map[string]interface{}{
+		"msgtype": "text",
+		"text":    map[string]string{"content": title + "\n" + body},
+	}
+
+	resp, err := postJSON(ctx, c.cfg.Webhook, payload, nil)
+	if err != nil {
+		return err
+	}
+	return checkRobotResponse(resp)
+}
+
+// webhookChannel 自定义webhook通知通道，直接推送告警事件JSON
+type webhookChannel struct {
+	cfg config.WebhookNotifyConfig
+}
+
+func newWebhookChannel(cfg config.WebhookNotifyConfig) *webhookChannel {
+	return &webhookChannel{cfg: cfg}
+}
+
+// Name 通道名称
+func (c *webhookChannel) Name() string {
+	return ChannelWebhook
+}
+
+// Send 推送告警事件
+func (c *webhookChannel) Send(ctx context.Context, event *AlarmEvent) error {
+	payload := *event
+	payload.RawData = nil
+	_, err := postJSON(ctx, c.cfg.URL, payload, c.cfg.Headers)
+	return err
+}
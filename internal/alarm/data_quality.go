@@ -0,0 +1,73 @@
+package alarm
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/factor"
+	"github.com/env-data-platform/internal/models"
+)
+
+// DataQualityAnomaly 收包实时质量校验发现异常数据时按因子保存告警记录并外发通知，
+// 无效数据为警告，可疑数据为信息
+func (d *Detector) DataQualityAnomaly(data *models.HJ212Data, issues []models.HJ212QualityIssue) {
+	systemCode, _ := data.ParsedData["system_code"].(string)
+
+	for _, issue := range issues {
+		event := &AlarmEvent{
+			ID:          d.generateAlarmID(),
+			SystemCode:  systemCode,
+			DeviceID:    data.DeviceID,
+			FactorCode:  issue.FactorCode,
+			FactorName:  factor.Default().Name(issue.FactorCode),
+			Value:       issue.Value,
+			Level:       dataQualityAlarmLevel(issue.Level),
+			Message:     fmt.Sprintf("设备%s数据异常: %s", data.DeviceID, issue.Message),
+			TriggeredAt: time.Now(),
+			Status:      models.AlarmStatusPending,
+			RawData: map[string]interface{}{
+				"data_id":       data.ID,
+				"command_code":  data.CommandCode,
+				"check":         issue.Check,
+				"quality_level": issue.Level,
+			},
+		}
+
+		if database.DB != nil {
+			alarmData := models.HJ212AlarmData{
+				DeviceID:     event.DeviceID,
+				FactorCode:   event.FactorCode,
+				Value:        event.Value,
+				AlarmType:    models.AlarmTypeDataAnomaly,
+				AlarmLevel:   string(event.Level),
+				AlarmDesc:    event.Message,
+				RawData:      d.marshalRawData(event.RawData),
+				ReceivedFrom: "quality",
+				ReceivedAt:   event.TriggeredAt,
+				Status:       event.Status,
+			}
+			if err := database.DB.Create(&alarmData).Error; err != nil {
+				d.logger.Error("Failed to save data quality alarm", zap.Error(err))
+			} else {
+				event.AlarmID = alarmData.ID
+			}
+		}
+
+		if d.wsHub != nil {
+			d.wsHub.BroadcastAlarm(event)
+		}
+
+		d.getNotifier().Notify(event)
+	}
+}
+
+// dataQualityAlarmLevel 无效数据为警告，可疑数据为信息
+func dataQualityAlarmLevel(qualityLevel string) AlarmLevel {
+	if qualityLevel == models.HJ212QualityInvalid {
+		return AlarmLevelWarning
+	}
+	return AlarmLevelInfo
+}
@@ -0,0 +1,68 @@
+package alarm
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/models"
+)
+
+// DataSourceHealthChanged 数据源巡检连续失败达到阈值时保存待处理的警告并外发通知，
+// 恢复正常时保存无需处理的信息
+func (d *Detector) DataSourceHealthChanged(dataSource *models.DataSource, healthy bool, failures int, message string) {
+	event := &AlarmEvent{
+		ID:          d.generateAlarmID(),
+		DeviceID:    dataSource.DeviceID,
+		Level:       AlarmLevelWarning,
+		Message:     dataSourceHealthMessage(dataSource, healthy, failures, message),
+		TriggeredAt: time.Now(),
+		Status:      models.AlarmStatusPending,
+		RawData: map[string]interface{}{
+			"datasource_id":   dataSource.ID,
+			"datasource_name": dataSource.Name,
+			"datasource_type": dataSource.Type,
+			"failures":        failures,
+		},
+	}
+	alarmType := models.AlarmTypeDataSourceUnreachable
+	if healthy {
+		event.Level = AlarmLevelInfo
+		event.Status = models.AlarmStatusClosed
+		alarmType = models.AlarmTypeDataSourceRecovered
+	}
+
+	if database.DB != nil {
+		alarmData := models.HJ212AlarmData{
+			DeviceID:     event.DeviceID,
+			AlarmType:    alarmType,
+			AlarmLevel:   string(event.Level),
+			AlarmDesc:    event.Message,
+			RawData:      d.marshalRawData(event.RawData),
+			ReceivedFrom: "health_check",
+			ReceivedAt:   event.TriggeredAt,
+			Status:       event.Status,
+		}
+		if err := database.DB.Create(&alarmData).Error; err != nil {
+			d.logger.Error("Failed to save data source health alarm", zap.Error(err))
+		} else {
+			event.AlarmID = alarmData.ID
+		}
+	}
+
+	if d.wsHub != nil {
+		d.wsHub.BroadcastAlarm(event)
+	}
+
+	d.getNotifier().Notify(event)
+}
+
+// dataSourceHealthMessage 生成数据源巡检告警消息
+func dataSourceHealthMessage(dataSource *models.DataSource, healthy bool, failures int, message string) string {
+	if healthy {
+		return fmt.Sprintf("数据源[%s]连接恢复正常，此前连续%d次巡检失败", dataSource.Name, failures)
+	}
+	return fmt.Sprintf("数据源[%s]连续%d次巡检连接失败: %s", dataSource.Name, failures, message)
+}
@@ -3,11 +3,13 @@ package alarm
 import (
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
 
 	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/factor"
 	"github.com/env-data-platform/internal/models"
 )
 
@@ -21,24 +23,13 @@ const (
 	AlarmLevelFatal    AlarmLevel = "fatal"    // 致命
 )
 
-// AlarmRule 告警规则
-type AlarmRule struct {
-	ID          string     `json:"id"`
-	Name        string     `json:"name"`
-	Description string     `json:"description"`
-	FactorCode  string     `json:"factor_code"`  // 监测因子代码
-	DeviceID    string     `json:"device_id"`    // 设备ID，空表示所有设备
-	Operator    string     `json:"operator"`     // 操作符：>, <, >=, <=, ==, !=
-	Threshold   float64    `json:"threshold"`    // 阈值
-	Level       AlarmLevel `json:"level"`        // 告警级别
-	Enabled     bool       `json:"enabled"`      // 是否启用
-	CooldownMin int        `json:"cooldown_min"` // 冷却时间（分钟）
-}
-
 // AlarmEvent 告警事件
 type AlarmEvent struct {
 	ID          string                 `json:"id"`
-	RuleID      string                 `json:"rule_id"`
+	AlarmID     uint                   `json:"alarm_id"` // 告警记录ID
+	RuleID      uint                   `json:"rule_id"`
+	RuleName    string                 `json:"rule_name"`
+	SystemCode  string                 `json:"system_code"`
 	DeviceID    string                 `json:"device_id"`
 	FactorCode  string                 `json:"factor_code"`
 	FactorName  string                 `json:"factor_name"`
@@ -49,14 +40,18 @@ type AlarmEvent struct {
 	Message     string                 `json:"message"`
 	RawData     map[string]interface{} `json:"raw_data"`
 	TriggeredAt time.Time              `json:"triggered_at"`
-	Status      string                 `json:"status"` // pending, acknowledged, resolved
+	Status      string                 `json:"status"` // pending, acknowledged, assigned, closed
 }
 
-// Detector 告警检测器
+// Detector 告警检测器，规则从数据库加载并缓存在内存中
 type Detector struct {
-	logger *zap.Logger
-	rules  map[string]*AlarmRule
-	wsHub  WSHub // WebSocket集线器接口
+	logger   *zap.Logger
+	mu       sync.RWMutex
+	rules    []*models.AlarmRule
+	wsHub    WSHub     // WebSocket集线器接口
+	notifier *Notifier // 告警通知分发器
+
+	suppressWindow time.Duration // 已确认告警的抑制窗口
 }
 
 // WSHub WebSocket集线器接口
@@ -67,108 +62,67 @@ type WSHub interface {
 }
 
 // NewDetector 创建新的告警检测器
-func NewDetector(logger *zap.Logger, wsHub WSHub) *Detector {
+func NewDetector(logger *zap.Logger, wsHub WSHub, notifier *Notifier, suppressWindow time.Duration) *Detector {
 	detector := &Detector{
-		logger: logger,
-		rules:  make(map[string]*AlarmRule),
-		wsHub:  wsHub,
+		logger:         logger,
+		wsHub:          wsHub,
+		notifier:       notifier,
+		suppressWindow: suppressWindow,
 	}
 
-	// 加载默认规则
-	detector.loadDefaultRules()
+	// 加载告警规则
+	if err := detector.LoadRules(); err != nil {
+		logger.Warn("Failed to load alarm rules", zap.Error(err))
+	}
 
 	return detector
 }
 
-// loadDefaultRules 加载默认告警规则
-func (d *Detector) loadDefaultRules() {
-	defaultRules := []*AlarmRule{
-		// 空气质量告警
-		{
-			ID:          "air_so2_high",
-			Name:        "SO2浓度过高",
-			Description: "二氧化硫浓度超过国家标准",
-			FactorCode:  "a21001",
-			Operator:    ">",
-			Threshold:   0.5, // mg/m³
-			Level:       AlarmLevelWarning,
-			Enabled:     true,
-			CooldownMin: 30,
-		},
-		{
-			ID:          "air_pm25_critical",
-			Name:        "PM2.5严重污染",
-			Description: "PM2.5浓度达到严重污染级别",
-			FactorCode:  "a34004",
-			Operator:    ">",
-			Threshold:   150, // μg/m³
-			Level:       AlarmLevelCritical,
-			Enabled:     true,
-			CooldownMin: 15,
-		},
-		{
-			ID:          "air_no_high",
-			Name:        "NO浓度异常",
-			Description: "一氧化氮浓度超标",
-			FactorCode:  "a21002",
-			Operator:    ">",
-			Threshold:   0.2, // mg/m³
-			Level:       AlarmLevelWarning,
-			Enabled:     true,
-			CooldownMin: 60,
-		},
-		// 水质告警
-		{
-			ID:          "water_ph_low",
-			Name:        "pH值过低",
-			Description: "水体pH值低于安全范围",
-			FactorCode:  "w01001",
-			Operator:    "<",
-			Threshold:   6.0,
-			Level:       AlarmLevelWarning,
-			Enabled:     true,
-			CooldownMin: 30,
-		},
-		{
-			ID:          "water_ph_high",
-			Name:        "pH值过高",
-			Description: "水体pH值高于安全范围",
-			FactorCode:  "w01001",
-			Operator:    ">",
-			Threshold:   9.0,
-			Level:       AlarmLevelWarning,
-			Enabled:     true,
-			CooldownMin: 30,
-		},
-		{
-			ID:          "water_cod_critical",
-			Name:        "COD严重超标",
-			Description: "化学需氧量严重超标",
-			FactorCode:  "w01009",
-			Operator:    ">",
-			Threshold:   50.0, // mg/L
-			Level:       AlarmLevelCritical,
-			Enabled:     true,
-			CooldownMin: 15,
-		},
-		{
-			ID:          "water_do_low",
-			Name:        "溶解氧过低",
-			Description: "水体溶解氧浓度过低",
-			FactorCode:  "w01003",
-			Operator:    "<",
-			Threshold:   3.0, // mg/L
-			Level:       AlarmLevelWarning,
-			Enabled:     true,
-			CooldownMin: 30,
-		},
+// LoadRules 从数据库重新加载已启用的告警规则，规则增删改后调用
+func (d *Detector) LoadRules() error {
+	if database.DB == nil {
+		return fmt.Errorf("database not initialized")
 	}
 
-	for _, rule := range defaultRules {
-		d.rules[rule.ID] = rule
+	var rules []*models.AlarmRule
+	if err := database.DB.Where("enabled = ?", true).Find(&rules).Error; err != nil {
+		return err
 	}
 
-	d.logger.Info("Loaded default alarm rules", zap.Int("count", len(defaultRules)))
+	d.mu.Lock()
+	d.rules = rules
+	d.mu.Unlock()
+
+	d.logger.Info("Loaded alarm rules", zap.Int("count", len(rules)))
+	return nil
+}
+
+// SetSuppressWindow 调整已确认告警的抑制窗口，配置热加载时调用
+func (d *Detector) SetSuppressWindow(window time.Duration) {
+	d.mu.Lock()
+	d.suppressWindow = window
+	d.mu.Unlock()
+}
+
+// SetNotifier 替换告警通知分发器，配置热加载时调用，已在发送中的通知不受影响
+func (d *Detector) SetNotifier(notifier *Notifier) {
+	d.mu.Lock()
+	d.notifier = notifier
+	d.mu.Unlock()
+}
+
+// getNotifier 获取当前的告警通知分发器
+func (d *Detector) getNotifier() *Notifier {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.notifier
+}
+
+// GetRules 获取当前生效的告警规则
+func (d *Detector) GetRules() []*models.AlarmRule {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.rules
 }
 
 // CheckData 检查HJ212数据是否触发告警
@@ -181,7 +135,9 @@ func (d *Detector) CheckData(data *models.HJ212Data) {
 	if !ok {
 		return
 	}
+	systemCode, _ := data.ParsedData["system_code"].(string)
 
+	rules := d.GetRules()
 	for factorCode, factorData := range factors {
 		factorInfo, ok := factorData.(map[string]interface{})
 		if !ok {
@@ -195,77 +151,90 @@ func (d *Detector) CheckData(data *models.HJ212Data) {
 		}
 
 		factorName, _ := factorInfo["name"].(string)
+		if factorName == "" {
+			factorName = factor.Default().Name(factorCode)
+		}
 
 		// 检查所有适用的规则
-		for _, rule := range d.rules {
-			if !rule.Enabled {
+		for _, rule := range applicableRules(rules, systemCode, factorCode, data.DeviceID) {
+			alarmType, limit, violated := rule.Evaluate(rtdValue)
+			if !violated {
 				continue
 			}
 
-			// 检查因子代码是否匹配
-			if rule.FactorCode != factorCode {
+			// 检查是否在冷却期内
+			if d.isInCooldown(rule, data.DeviceID) {
 				continue
 			}
 
-			// 检查设备ID是否匹配（空表示所有设备）
-			if rule.DeviceID != "" && rule.DeviceID != data.DeviceID {
+			// 相同告警已被确认处理中，窗口内不再重复产生
+			if d.isSuppressed(rule, data.DeviceID) {
 				continue
 			}
 
-			// 检查是否在冷却期内
-			if d.isInCooldown(rule.ID, data.DeviceID) {
-				continue
+			operator := ">"
+			if alarmType == models.AlarmTypeUnderLimit {
+				operator = "<"
 			}
 
-			// 检查是否触发告警
-			if d.checkThreshold(rtdValue, rule.Operator, rule.Threshold) {
-				event := &AlarmEvent{
-					ID:          d.generateAlarmID(),
-					RuleID:      rule.ID,
-					DeviceID:    data.DeviceID,
-					FactorCode:  factorCode,
-					FactorName:  factorName,
-					Value:       rtdValue,
-					Threshold:   rule.Threshold,
-					Operator:    rule.Operator,
-					Level:       rule.Level,
-					Message:     d.generateAlarmMessage(rule, factorName, rtdValue),
-					RawData:     data.ParsedData,
-					TriggeredAt: time.Now(),
-					Status:      "pending",
-				}
-
-				d.triggerAlarm(event)
+			event := &AlarmEvent{
+				ID:          d.generateAlarmID(),
+				RuleID:      rule.ID,
+				RuleName:    rule.Name,
+				SystemCode:  systemCode,
+				DeviceID:    data.DeviceID,
+				FactorCode:  factorCode,
+				FactorName:  factorName,
+				Value:       rtdValue,
+				Threshold:   limit,
+				Operator:    operator,
+				Level:       AlarmLevel(rule.Level),
+				Message:     d.generateAlarmMessage(rule, factorName, rtdValue, operator, limit),
+				RawData:     data.ParsedData,
+				TriggeredAt: time.Now(),
+				Status:      models.AlarmStatusPending,
 			}
+
+			d.triggerAlarm(event, alarmType)
 		}
 	}
 }
 
-// checkThreshold 检查阈值
-func (d *Detector) checkThreshold(value float64, operator string, threshold float64) bool {
-	switch operator {
-	case ">":
-		return value > threshold
-	case ">=":
-		return value >= threshold
-	case "<":
-		return value < threshold
-	case "<=":
-		return value <= threshold
-	case "==":
-		return value == threshold
-	case "!=":
-		return value != threshold
-	default:
-		return false
+// applicableRules 筛选适用于该因子的规则，因子没有配置规则时使用因子字典中的默认上下限
+func applicableRules(rules []*models.AlarmRule, systemCode, factorCode, deviceID string) []*models.AlarmRule {
+	var matched []*models.AlarmRule
+	for _, rule := range rules {
+		if rule.Matches(systemCode, factorCode, deviceID) {
+			matched = append(matched, rule)
+		}
+	}
+	if len(matched) > 0 {
+		return matched
 	}
+
+	f, ok := factor.Default().Lookup(factorCode)
+	if !ok || !f.HasLimits() {
+		return nil
+	}
+	return []*models.AlarmRule{{
+		Name:       f.Name + "默认限值",
+		FactorCode: factorCode,
+		UpperLimit: f.UpperLimit,
+		LowerLimit: f.LowerLimit,
+		Level:      models.AlarmLevelWarning,
+		Enabled:    true,
+	}}
 }
 
-// isInCooldown 检查是否在冷却期内
-func (d *Detector) isInCooldown(ruleID, deviceID string) bool {
+// isInCooldown 检查同一规则在该设备上是否仍处于冷却期内
+func (d *Detector) isInCooldown(rule *models.AlarmRule, deviceID string) bool {
+	if rule.CooldownMin <= 0 {
+		return false
+	}
+
 	// 查询最近的告警记录
 	var lastAlarm models.HJ212AlarmData
-	err := database.DB.Where("device_id = ? AND alarm_type = ?", deviceID, ruleID).
+	err := database.DB.Where("device_id = ? AND rule_id = ?", deviceID, rule.ID).
 		Order("received_at DESC").
 		First(&lastAlarm).Error
 
@@ -274,20 +243,44 @@ func (d *Detector) isInCooldown(ruleID, deviceID string) bool {
 		return false
 	}
 
-	rule, exists := d.rules[ruleID]
-	if !exists {
-		return false
-	}
-
 	// 检查时间间隔
 	cooldownDuration := time.Duration(rule.CooldownMin) * time.Minute
 	return time.Since(lastAlarm.ReceivedAt) < cooldownDuration
 }
 
+// isSuppressed 检查同设备同规则是否存在抑制窗口内确认且未关闭的告警
+func (d *Detector) isSuppressed(rule *models.AlarmRule, deviceID string) bool {
+	d.mu.RLock()
+	suppressWindow := d.suppressWindow
+	d.mu.RUnlock()
+	if suppressWindow <= 0 {
+		return false
+	}
+
+	var count int64
+	err := database.DB.Model(&models.HJ212AlarmData{}).
+		Where("device_id = ? AND rule_id = ? AND factor_code = ?", deviceID, rule.ID, rule.FactorCode).
+		Where("status IN ?", []string{models.AlarmStatusAcknowledged, models.AlarmStatusAssigned}).
+		Where("acknowledged_at >= ?", time.Now().Add(-suppressWindow)).
+		Count(&count).Error
+	if err != nil {
+		d.logger.Error("Failed to check alarm suppression", zap.Error(err))
+		return false
+	}
+
+	if count > 0 {
+		d.logger.Debug("Alarm suppressed by acknowledged alarm",
+			zap.Uint("rule_id", rule.ID),
+			zap.String("device_id", deviceID))
+		return true
+	}
+	return false
+}
+
 // triggerAlarm 触发告警
-func (d *Detector) triggerAlarm(event *AlarmEvent) {
+func (d *Detector) triggerAlarm(event *AlarmEvent, alarmType string) {
 	d.logger.Warn("Alarm triggered",
-		zap.String("rule_id", event.RuleID),
+		zap.Uint("rule_id", event.RuleID),
 		zap.String("device_id", event.DeviceID),
 		zap.String("factor_code", event.FactorCode),
 		zap.Float64("value", event.Value),
@@ -297,7 +290,10 @@ func (d *Detector) triggerAlarm(event *AlarmEvent) {
 	// 保存告警到数据库
 	alarmData := models.HJ212AlarmData{
 		DeviceID:     event.DeviceID,
-		AlarmType:    event.RuleID,
+		RuleID:       event.RuleID,
+		FactorCode:   event.FactorCode,
+		Value:        event.Value,
+		AlarmType:    alarmType,
 		AlarmLevel:   string(event.Level),
 		AlarmDesc:    event.Message,
 		RawData:      d.marshalRawData(event.RawData),
@@ -308,12 +304,17 @@ func (d *Detector) triggerAlarm(event *AlarmEvent) {
 
 	if err := database.DB.Create(&alarmData).Error; err != nil {
 		d.logger.Error("Failed to save alarm data", zap.Error(err))
+	} else {
+		event.AlarmID = alarmData.ID
 	}
 
 	// 通过WebSocket广播告警
 	if d.wsHub != nil {
 		d.wsHub.BroadcastAlarm(event)
 	}
+
+	// 外发告警通知
+	d.getNotifier().Notify(event)
 }
 
 // marshalRawData 序列化原始数据
@@ -334,34 +335,11 @@ func (d *Detector) generateAlarmID() string {
 }
 
 // generateAlarmMessage 生成告警消息
-func (d *Detector) generateAlarmMessage(rule *AlarmRule, factorName string, value float64) string {
+func (d *Detector) generateAlarmMessage(rule *models.AlarmRule, factorName string, value float64, operator string, limit float64) string {
 	if factorName == "" {
 		factorName = rule.FactorCode
 	}
 
 	return fmt.Sprintf("%s: %s当前值%.2f %s %.2f（阈值）",
-		rule.Name, factorName, value, rule.Operator, rule.Threshold)
-}
-
-// AddRule 添加告警规则
-func (d *Detector) AddRule(rule *AlarmRule) {
-	d.rules[rule.ID] = rule
-	d.logger.Info("Added alarm rule", zap.String("rule_id", rule.ID), zap.String("name", rule.Name))
+		rule.Name, factorName, value, operator, limit)
 }
-
-// RemoveRule 移除告警规则
-func (d *Detector) RemoveRule(ruleID string) {
-	delete(d.rules, ruleID)
-	d.logger.Info("Removed alarm rule", zap.String("rule_id", ruleID))
-}
-
-// GetRules 获取所有告警规则
-func (d *Detector) GetRules() map[string]*AlarmRule {
-	return d.rules
-}
-
-// UpdateRule 更新告警规则
-func (d *Detector) UpdateRule(rule *AlarmRule) {
-	d.rules[rule.ID] = rule
-	d.logger.Info("Updated alarm rule", zap.String("rule_id", rule.ID))
-}
\ No newline at end of file
@@ -0,0 +1,62 @@
+package alarm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/database/dbtest"
+	"github.com/env-data-platform/internal/models"
+)
+
+func TestDetectorIsSuppressed(t *testing.T) {
+	rule := &models.AlarmRule{FactorCode: "a34004"}
+	rule.ID = 5
+
+	tests := []struct {
+		name         string
+		window       time.Duration
+		acknowledged int64
+		expected     bool
+	}{
+		{name: "未配置抑制窗口", window: 0, acknowledged: 1},
+		{name: "窗口内有已确认告警", window: 30 * time.Minute, acknowledged: 1, expected: true},
+		{name: "窗口内没有已确认告警", window: 30 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.New(t)
+			db.On(dbtest.KindQuery, func(tx *gorm.DB) {
+				if count, ok := tx.Statement.Dest.(*int64); ok {
+					*count = tt.acknowledged
+					tx.RowsAffected = 1
+				}
+			})
+			db.Replace(t, &database.DB)
+			d := &Detector{logger: zap.NewNop(), suppressWindow: tt.window}
+
+			before := time.Now()
+			assert.Equal(t, tt.expected, d.isSuppressed(rule, "MN1"))
+
+			statements := db.Statements()
+			if tt.window <= 0 {
+				assert.Empty(t, statements)
+				return
+			}
+			// 只有同设备同规则、已确认或已指派且在窗口内确认的告警才抑制
+			require.Len(t, statements, 1)
+			stmt := statements[0]
+			assert.Contains(t, stmt.SQL, "device_id = ? AND rule_id = ? AND factor_code = ?")
+			assert.Contains(t, stmt.SQL, "status IN (?,?)")
+			assert.Contains(t, stmt.SQL, "acknowledged_at >= ?")
+			assert.Equal(t, []interface{}{"MN1", uint(5), "a34004", models.AlarmStatusAcknowledged, models.AlarmStatusAssigned}, stmt.Vars[:5])
+			ackedAfter := stmt.Vars[5].(time.Time)
+			assert.WithinDuration(t, before.Add(-tt.window), ackedAfter, time.Second)
+		})
+	}
+}
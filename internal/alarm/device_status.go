@@ -0,0 +1,74 @@
+package alarm
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/models"
+)
+
+// DeviceStatusChanged 设备上下线时保存告警记录、广播设备状态并外发通知；
+// 离线为待处理的警告，恢复上线为无需处理的信息
+func (d *Detector) DeviceStatusChanged(event *models.DeviceStatusEvent) {
+	online := event.Event == models.DeviceEventOnline
+
+	alarmEvent := &AlarmEvent{
+		ID:          d.generateAlarmID(),
+		DeviceID:    event.DeviceID,
+		Level:       AlarmLevelWarning,
+		Message:     deviceStatusMessage(event),
+		TriggeredAt: event.OccurredAt,
+		Status:      models.AlarmStatusPending,
+	}
+	alarmType := models.AlarmTypeDeviceOffline
+	if online {
+		alarmEvent.Level = AlarmLevelInfo
+		alarmEvent.Status = models.AlarmStatusClosed
+		alarmType = models.AlarmTypeDeviceOnline
+	}
+
+	if database.DB != nil {
+		alarmData := models.HJ212AlarmData{
+			DeviceID:     event.DeviceID,
+			AlarmType:    alarmType,
+			AlarmLevel:   string(alarmEvent.Level),
+			AlarmDesc:    alarmEvent.Message,
+			ReceivedFrom: "system",
+			ReceivedAt:   event.OccurredAt,
+			Status:       alarmEvent.Status,
+		}
+		if err := database.DB.Create(&alarmData).Error; err != nil {
+			d.logger.Error("Failed to save device status alarm", zap.Error(err))
+		} else {
+			alarmEvent.AlarmID = alarmData.ID
+		}
+	}
+
+	if d.wsHub != nil {
+		status := map[string]interface{}{
+			"online":      online,
+			"event":       event.Event,
+			"occurred_at": event.OccurredAt,
+		}
+		if event.LastActiveAt != nil {
+			status["last_active_at"] = *event.LastActiveAt
+		}
+		d.wsHub.BroadcastDeviceStatus(event.DeviceID, status)
+		d.wsHub.BroadcastAlarm(alarmEvent)
+	}
+
+	d.getNotifier().Notify(alarmEvent)
+}
+
+// deviceStatusMessage 生成设备上下线告警消息
+func deviceStatusMessage(event *models.DeviceStatusEvent) string {
+	if event.Event == models.DeviceEventOnline {
+		return fmt.Sprintf("设备%s恢复上线", event.DeviceID)
+	}
+	if event.LastActiveAt == nil {
+		return fmt.Sprintf("设备%s离线", event.DeviceID)
+	}
+	return fmt.Sprintf("设备%s离线，最后收包时间%s", event.DeviceID, event.LastActiveAt.Format("2006-01-02 15:04:05"))
+}
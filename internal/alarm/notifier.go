@@ -0,0 +1,195 @@
+package alarm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/env-data-platform/internal/config"
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/models"
+)
+
+// Channel 告警通知通道
+type Channel interface {
+	// Name 通道名称，记录在发送记录中
+	Name() string
+	// Send 发送一条告警通知
+	Send(ctx context.Context, event *AlarmEvent) error
+}
+
+// routedChannel 带级别路由的通知通道
+type routedChannel struct {
+	channel Channel
+	levels  map[AlarmLevel]bool // 为空表示接收全部级别
+}
+
+// accepts 判断通道是否接收该级别的告警
+func (r routedChannel) accepts(level AlarmLevel) bool {
+	return len(r.levels) == 0 || r.levels[level]
+}
+
+// Notifier 告警通知分发器，按级别将告警路由到各通道并负责失败重试与发送记录
+type Notifier struct {
+	logger        *zap.Logger
+	channels      []routedChannel
+	maxRetries    int
+	retryInterval time.Duration
+	timeout       time.Duration
+}
+
+// NewNotifier 根据配置创建通知分发器，未启用时返回不包含任何通道的分发器
+func NewNotifier(cfg config.AlarmNotifyConfig, logger *zap.Logger) *Notifier {
+	n := &Notifier{
+		logger:        logger,
+		maxRetries:    cfg.MaxRetries,
+		retryInterval: cfg.RetryInterval,
+		timeout:       cfg.Timeout,
+	}
+	if !cfg.Enabled {
+		return n
+	}
+
+	if cfg.Email.Enabled {
+		n.AddChannel(newEmailChannel(cfg.Email), cfg.Email.Levels...)
+	}
+	if cfg.DingTalk.Enabled {
+		n.AddChannel(newDingTalkChannel(cfg.DingTalk), cfg.DingTalk.Levels...)
+	}
+	if cfg.WeCom.Enabled {
+		n.AddChannel(newWeComChannel(cfg.WeCom), cfg.WeCom.Levels...)
+	}
+	if cfg.Webhook.Enabled {
+		n.AddChannel(newWebhookChannel(cfg.Webhook), cfg.Webhook.Levels...)
+	}
+
+	logger.Info("Alarm notifier initialized", zap.Int("channels", len(n.channels)))
+	return n
+}
+
+// AddChannel 注册通知通道，levels为空表示接收全部级别
+func (n *Notifier) AddChannel(channel Channel, levels ...string) {
+	routed := routedChannel{channel: channel}
+	if len(levels) > 0 {
+		routed.levels = make(map[AlarmLevel]bool, len(levels))
+		for _, level := range levels {
+			routed.levels[AlarmLevel(level)] = true
+		}
+	}
+	n.channels = append(n.channels, routed)
+}
+
+// Notify 异步将告警发送到所有匹配级别的通道
+func (n *Notifier) Notify(event *AlarmEvent) {
+	if n == nil {
+		return
+	}
+	for _, routed := range n.channels {
+		if !routed.accepts(event.Level) {
+			continue
+		}
+		go n.deliver(routed.channel, event)
+	}
+}
+
+// deliver 向单个通道发送告警，失败时按配置重试，并保存发送记录
+func (n *Notifier) deliver(channel Channel, event *AlarmEvent) *models.AlarmNotification {
+	record := &models.AlarmNotification{
+		AlarmID:  event.AlarmID,
+		RuleID:   event.RuleID,
+		DeviceID: event.DeviceID,
+		Level:    string(event.Level),
+		Channel:  channel.Name(),
+		Status:   models.NotificationStatusFailed,
+		Content:  event.Message,
+	}
+
+	for attempt := 0; attempt <= n.maxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * n.retryInterval)
+		}
+		record.Attempts++
+
+		err := n.send(channel, event)
+		if err == nil {
+			now := time.Now()
+			record.Status = models.NotificationStatusSuccess
+			record.LastError = ""
+			record.SentAt = &now
+			break
+		}
+
+		record.LastError = err.Error()
+		n.logger.Warn("Failed to send alarm notification",
+			zap.String("channel", channel.Name()),
+			zap.String("device_id", event.DeviceID),
+			zap.Int("attempt", record.Attempts),
+			zap.Error(err))
+	}
+
+	if record.Status == models.NotificationStatusFailed {
+		n.logger.Error("Alarm notification not delivered",
+			zap.String("channel", channel.Name()),
+			zap.Uint("alarm_id", event.AlarmID),
+			zap.String("error", record.LastError))
+	}
+
+	if database.DB != nil {
+		if err := database.DB.Create(record).Error; err != nil {
+			n.logger.Error("Failed to save alarm notification record", zap.Error(err))
+		}
+	}
+	return record
+}
+
+// send 在超时控制下发送一次通知
+func (n *Notifier) send(channel Channel, event *AlarmEvent) error {
+	ctx := context.Background()
+	if n.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, n.timeout)
+		defer cancel()
+	}
+	return channel.Send(ctx, event)
+}
+
+// formatAlarmText 生成通知标题和正文
+func formatAlarmText(event *AlarmEvent) (title, body string) {
+	title = fmt.Sprintf("[%s] 环境监测告警", levelNames[event.Level])
+	// 数据质量告警按规则展示
+	if event.FactorCode == "" && event.RuleName != "" {
+		body = fmt.Sprintf("规则：%s\n时间：%s\n详情：%s",
+			event.RuleName,
+			event.TriggeredAt.Format("2006-01-02 15:04:05"),
+			event.Message)
+		return title, body
+	}
+	// 设备上下线等非因子告警没有监测值和阈值
+	if event.FactorCode == "" {
+		body = fmt.Sprintf("设备：%s\n时间：%s\n详情：%s",
+			event.DeviceID,
+			event.TriggeredAt.Format("2006-01-02 15:04:05"),
+			event.Message)
+		return title, body
+	}
+	body = fmt.Sprintf("规则：%s\n设备：%s\n因子：%s\n监测值：%.2f\n阈值：%s %.2f\n时间：%s\n详情：%s",
+		event.RuleName,
+		event.DeviceID,
+		event.FactorCode,
+		event.Value,
+		event.Operator,
+		event.Threshold,
+		event.TriggeredAt.Format("2006-01-02 15:04:05"),
+		event.Message)
+	return title, body
+}
+
+// levelNames 告警级别中文名称
+var levelNames = map[AlarmLevel]string{
+	AlarmLevelInfo:     "信息",
+	AlarmLevelWarning:  "警告",
+	AlarmLevelCritical: "严重",
+	AlarmLevelFatal:    "致命",
+}
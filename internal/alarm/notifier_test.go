@@ -0,0 +1,121 @@
+package alarm
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/env-data-platform/internal/models"
+)
+
+// fakeChannel 前N次发送失败的测试通道
+type fakeChannel struct {
+	failures int
+	calls    int
+}
+
+func (f *fakeChannel) Name() string { return "fake" }
+
+func (f *fakeChannel) Send(ctx context.Context, event *AlarmEvent) error {
+	f.calls++
+	if f.calls <= f.failures {
+		return errors.New("send failed")
+	}
+	return nil
+}
+
+func TestRoutedChannelAccepts(t *testing.T) {
+	n := &Notifier{logger: zap.NewNop()}
+	n.AddChannel(&fakeChannel{})
+	n.AddChannel(&fakeChannel{}, "critical", "fatal")
+
+	assert.True(t, n.channels[0].accepts(AlarmLevelInfo))
+	assert.False(t, n.channels[1].accepts(AlarmLevelWarning))
+	assert.True(t, n.channels[1].accepts(AlarmLevelFatal))
+}
+
+func TestNotifierDeliverRetry(t *testing.T) {
+	n := &Notifier{logger: zap.NewNop(), maxRetries: 2}
+	event := &AlarmEvent{AlarmID: 1, DeviceID: "MN001", Level: AlarmLevelCritical}
+
+	recovered := n.deliver(&fakeChannel{failures: 2}, event)
+	assert.Equal(t, models.NotificationStatusSuccess, recovered.Status)
+	assert.Equal(t, 3, recovered.Attempts)
+	assert.NotNil(t, recovered.SentAt)
+
+	failed := n.deliver(&fakeChannel{failures: 5}, event)
+	assert.Equal(t, models.NotificationStatusFailed, failed.Status)
+	assert.Equal(t, 3, failed.Attempts)
+	assert.Equal(t, "send failed", failed.LastError)
+}
+
+func TestSignDingTalkURL(t *testing.T) {
+	now := time.UnixMilli(1700000000000)
+	signed, err := signDingTalkURL("https://oapi.dingtalk.com/robot/send?access_token=abc", "SECxyz", now)
+	require.NoError(t, err)
+
+	u, err := url.Parse(signed)
+	require.NoError(t, err)
+	assert.Equal(t, "abc", u.Query().Get("access_token"))
+	assert.Equal(t, "1700000000000", u.Query().Get("timestamp"))
+	assert.NotEmpty(t, u.Query().Get("sign"))
+}
+
+func TestFormatDeviceStatusAlarm(t *testing.T) {
+	lastActive := time.Date(2024, 3, 1, 8, 0, 0, 0, time.Local)
+	event := &models.DeviceStatusEvent{
+		DeviceID:     "MN001",
+		Event:        models.DeviceEventOffline,
+		LastActiveAt: &lastActive,
+		OccurredAt:   lastActive.Add(10 * time.Minute),
+	}
+	message := deviceStatusMessage(event)
+	assert.Equal(t, "设备MN001离线，最后收包时间2024-03-01 08:00:00", message)
+
+	title, body := formatAlarmText(&AlarmEvent{
+		DeviceID:    event.DeviceID,
+		Level:       AlarmLevelWarning,
+		Message:     message,
+		TriggeredAt: event.OccurredAt,
+	})
+	assert.Equal(t, "[警告] 环境监测告警", title)
+	assert.Equal(t, "设备：MN001\n时间：2024-03-01 08:10:00\n详情："+message, body)
+
+	event.Event = models.DeviceEventOnline
+	assert.Equal(t, "设备MN001恢复上线", deviceStatusMessage(event))
+}
+
+func TestFormatQualityAlarm(t *testing.T) {
+	rule := &models.QualityRule{Name: "站点数据完整性", Threshold: 95, AlertLevel: "critical"}
+	report := &models.QualityReport{Score: 80.5, FailCount: 39}
+
+	message := qualityAlarmMessage(rule, report, nil)
+	assert.Equal(t, "质量规则[站点数据完整性]检查得分80.50，低于阈值95.00，失败记录39条", message)
+	assert.Equal(t, AlarmLevelCritical, qualityAlarmLevel(rule.AlertLevel))
+	assert.Equal(t, AlarmLevelWarning, qualityAlarmLevel(""))
+
+	_, body := formatAlarmText(&AlarmEvent{
+		RuleName:    rule.Name,
+		Level:       AlarmLevelCritical,
+		Message:     message,
+		TriggeredAt: time.Date(2024, 3, 1, 8, 0, 0, 0, time.Local),
+	})
+	assert.Equal(t, "规则：站点数据完整性\n时间：2024-03-01 08:00:00\n详情："+message, body)
+}
+
+func TestApplicableRulesFallbackToFactorLimits(t *testing.T) {
+	upper := 75.0
+	rules := []*models.AlarmRule{{FactorCode: "a34004", Name: "PM2.5超标", UpperLimit: &upper}}
+
+	matched := applicableRules(rules, "22", "a34004", "MN001")
+	assert.Equal(t, rules, matched)
+
+	// 因子字典中未配置默认限值时不告警
+	assert.Empty(t, applicableRules(rules, "22", "a21026", "MN001"))
+}
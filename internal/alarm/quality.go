@@ -0,0 +1,74 @@
+package alarm
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/models"
+)
+
+// QualityCheckAlarm 定时质量检查执行失败或分数低于阈值时保存告警记录并外发通知，
+// 告警级别取质量规则配置的级别
+func (d *Detector) QualityCheckAlarm(rule *models.QualityRule, report *models.QualityReport, checkErr error) {
+	event := &AlarmEvent{
+		ID:          d.generateAlarmID(),
+		RuleName:    rule.Name,
+		Threshold:   rule.Threshold,
+		Operator:    "<",
+		Level:       qualityAlarmLevel(rule.AlertLevel),
+		Message:     qualityAlarmMessage(rule, report, checkErr),
+		TriggeredAt: time.Now(),
+		Status:      models.AlarmStatusPending,
+	}
+	if rule.DataSource != nil {
+		event.DeviceID = rule.DataSource.DeviceID
+	}
+	if report != nil {
+		event.Value = report.Score
+		event.TriggeredAt = report.CheckTime
+	}
+
+	if database.DB != nil {
+		alarmData := models.HJ212AlarmData{
+			DeviceID:     event.DeviceID,
+			Value:        event.Value,
+			AlarmType:    models.AlarmTypeQualityCheck,
+			AlarmLevel:   string(event.Level),
+			AlarmDesc:    event.Message,
+			ReceivedFrom: "quality",
+			ReceivedAt:   event.TriggeredAt,
+			Status:       event.Status,
+		}
+		if err := database.DB.Create(&alarmData).Error; err != nil {
+			d.logger.Error("Failed to save quality alarm", zap.Error(err))
+		} else {
+			event.AlarmID = alarmData.ID
+		}
+	}
+
+	if d.wsHub != nil {
+		d.wsHub.BroadcastAlarm(event)
+	}
+
+	d.getNotifier().Notify(event)
+}
+
+// qualityAlarmLevel 质量规则告警级别，未配置或无法识别时为警告
+func qualityAlarmLevel(level string) AlarmLevel {
+	if _, ok := levelNames[AlarmLevel(level)]; ok {
+		return AlarmLevel(level)
+	}
+	return AlarmLevelWarning
+}
+
+// qualityAlarmMessage 生成质量告警消息
+func qualityAlarmMessage(rule *models.QualityRule, report *models.QualityReport, checkErr error) string {
+	if checkErr != nil {
+		return fmt.Sprintf("质量规则[%s]定时检查执行失败: %v", rule.Name, checkErr)
+	}
+	return fmt.Sprintf("质量规则[%s]检查得分%.2f，低于阈值%.2f，失败记录%d条",
+		rule.Name, report.Score, rule.Threshold, report.FailCount)
+}
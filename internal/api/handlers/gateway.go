@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/env-data-platform/internal/gateway"
@@ -20,6 +23,7 @@ type GatewayHandler struct {
 	authenticator *auth.Authenticator
 	collector    *metrics.Collector
 	rateLimiter  ratelimit.RateLimiter
+	audit        *gateway.AuditLogger
 	logger       *zap.Logger
 }
 
@@ -30,6 +34,7 @@ func NewGatewayHandler(
 	authenticator *auth.Authenticator,
 	collector *metrics.Collector,
 	rateLimiter ratelimit.RateLimiter,
+	audit *gateway.AuditLogger,
 	logger *zap.Logger,
 ) *GatewayHandler {
 	return &GatewayHandler{
@@ -38,6 +43,7 @@ func NewGatewayHandler(
 		authenticator: authenticator,
 		collector:    collector,
 		rateLimiter:  rateLimiter,
+		audit:        audit,
 		logger:       logger,
 	}
 }
@@ -88,11 +94,12 @@ func (h *GatewayHandler) CreateRoute(c *gin.Context) {
 	}
 
 	if err := h.router.AddRoute(&route); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(routeErrorStatus(err), gin.H{
 			"success": false,
 			"error":   "failed to create route",
 			"message": err.Error(),
 		})
+		h.audit.Record(c, "route:"+route.ID, nil, route, err)
 		return
 	}
 
@@ -106,6 +113,7 @@ func (h *GatewayHandler) CreateRoute(c *gin.Context) {
 		"success": true,
 		"data":    route,
 	})
+	h.audit.Record(c, "route:"+route.ID, nil, route, nil)
 }
 
 // UpdateRoute 更新路由
@@ -124,7 +132,8 @@ func (h *GatewayHandler) UpdateRoute(c *gin.Context) {
 	}
 
 	// 检查路由是否存在
-	if _, exists := h.router.GetRoute(method, path); !exists {
+	existing, exists := h.lookupRoute(c, method, path)
+	if !exists {
 		c.JSON(http.StatusNotFound, gin.H{
 			"success": false,
 			"error":   "route not found",
@@ -132,14 +141,17 @@ func (h *GatewayHandler) UpdateRoute(c *gin.Context) {
 		return
 	}
 
-	// 删除旧路由，添加新路由
-	h.router.RemoveRoute(method, path)
+	// 删除旧路由，添加新路由，失败时恢复旧路由
+	before := *existing
+	h.removeRoute(existing)
 	if err := h.router.AddRoute(&route); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		h.router.AddRoute(existing)
+		c.JSON(routeErrorStatus(err), gin.H{
 			"success": false,
 			"error":   "failed to update route",
 			"message": err.Error(),
 		})
+		h.audit.Record(c, "route:"+existing.ID, before, route, err)
 		return
 	}
 
@@ -147,6 +159,7 @@ func (h *GatewayHandler) UpdateRoute(c *gin.Context) {
 		"success": true,
 		"data":    route,
 	})
+	h.audit.Record(c, "route:"+existing.ID, before, route, nil)
 }
 
 // DeleteRoute 删除路由
@@ -154,7 +167,8 @@ func (h *GatewayHandler) DeleteRoute(c *gin.Context) {
 	method := c.Param("method")
 	path := c.Param("path")
 
-	if _, exists := h.router.GetRoute(method, path); !exists {
+	route, exists := h.lookupRoute(c, method, path)
+	if !exists {
 		c.JSON(http.StatusNotFound, gin.H{
 			"success": false,
 			"error":   "route not found",
@@ -162,7 +176,7 @@ func (h *GatewayHandler) DeleteRoute(c *gin.Context) {
 		return
 	}
 
-	h.router.RemoveRoute(method, path)
+	h.removeRoute(route)
 
 	h.logger.Info("Route deleted via API",
 		zap.String("method", method),
@@ -172,6 +186,7 @@ func (h *GatewayHandler) DeleteRoute(c *gin.Context) {
 		"success": true,
 		"message": "route deleted successfully",
 	})
+	h.audit.Record(c, "route:"+route.ID, route, nil, nil)
 }
 
 // GetRoute 获取单个路由
@@ -179,7 +194,7 @@ func (h *GatewayHandler) GetRoute(c *gin.Context) {
 	method := c.Param("method")
 	path := c.Param("path")
 
-	route, exists := h.router.GetRoute(method, path)
+	route, exists := h.lookupRoute(c, method, path)
 	if !exists {
 		c.JSON(http.StatusNotFound, gin.H{
 			"success": false,
@@ -194,6 +209,72 @@ func (h *GatewayHandler) GetRoute(c *gin.Context) {
 	})
 }
 
+// TraceRoute 查看请求会命中哪条路由
+// 查询参数：method（默认GET）、path、host，header可重复，格式为 "Name: Value"
+func (h *GatewayHandler) TraceRoute(c *gin.Context) {
+	path := c.Query("path")
+	if path == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "path parameter is required",
+		})
+		return
+	}
+
+	req := &http.Request{
+		Method: strings.ToUpper(c.DefaultQuery("method", http.MethodGet)),
+		URL:    &url.URL{Path: path},
+		Host:   c.Query("host"),
+		Header: make(http.Header),
+	}
+	for _, header := range c.QueryArray("header") {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "invalid header parameter",
+				"message": "header must be in the form \"Name: Value\"",
+			})
+			return
+		}
+		req.Header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    h.router.TraceRoute(req),
+	})
+}
+
+// lookupRoute 查找管理接口指定的路由，带匹配条件的路由通过查询参数id定位
+func (h *GatewayHandler) lookupRoute(c *gin.Context, method, path string) (*gateway.Route, bool) {
+	if id := c.Query("id"); id != "" {
+		route, exists := h.router.GetRouteByID(id)
+		if !exists || route.Method != method || route.Path != path {
+			return nil, false
+		}
+		return route, true
+	}
+	return h.router.GetRoute(method, path)
+}
+
+// removeRoute 删除lookupRoute找到的路由
+func (h *GatewayHandler) removeRoute(route *gateway.Route) {
+	if route.Match != nil {
+		h.router.RemoveRouteByID(route.ID)
+		return
+	}
+	h.router.RemoveRoute(route.Method, route.Path)
+}
+
+// routeErrorStatus 路由冲突返回409，其他错误返回500
+func routeErrorStatus(err error) int {
+	if errors.Is(err, gateway.ErrRouteConflict) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
 // LoadBalancer 负载均衡管理
 
 // GetLoadBalancerStats 获取负载均衡统计
@@ -229,11 +310,13 @@ func (h *GatewayHandler) UpdateTargetHealth(c *gin.Context) {
 		"success": true,
 		"message": "target health updated",
 	})
+	h.audit.Record(c, "target:"+groupID+"/"+targetID, nil, gin.H{"is_healthy": req.IsHealthy}, nil)
 }
 
 // Authentication 认证管理
 
 // CreateAPIKey 创建API密钥
+// scopes为密钥可访问的接口范围，过期时间可用expires_at指定时间点或expires_in指定有效期（如"720h"），二者只能选一
 func (h *GatewayHandler) CreateAPIKey(c *gin.Context) {
 	var req struct {
 		UserID    string     `json:"user_id" binding:"required"`
@@ -241,6 +324,7 @@ func (h *GatewayHandler) CreateAPIKey(c *gin.Context) {
 		Scopes    []string   `json:"scopes"`
 		RateLimit int        `json:"rate_limit"`
 		ExpiresAt *time.Time `json:"expires_at"`
+		ExpiresIn string     `json:"expires_in"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -256,6 +340,19 @@ func (h *GatewayHandler) CreateAPIKey(c *gin.Context) {
 	if req.RateLimit == 0 {
 		req.RateLimit = 1000 // 默认每秒1000请求
 	}
+	if req.ExpiresIn != "" {
+		ttl, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || ttl <= 0 || req.ExpiresAt != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "invalid expires_in",
+				"message": "expires_in must be a positive duration and cannot be used with expires_at",
+			})
+			return
+		}
+		expiresAt := time.Now().Add(ttl)
+		req.ExpiresAt = &expiresAt
+	}
 
 	apiKey, err := h.authenticator.CreateAPIKey(
 		req.UserID,
@@ -265,18 +362,22 @@ func (h *GatewayHandler) CreateAPIKey(c *gin.Context) {
 		req.ExpiresAt,
 	)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
 			"error":   "failed to create API key",
 			"message": err.Error(),
 		})
+		h.audit.Record(c, "apikey:"+req.Name, nil, req, err)
 		return
 	}
 
+	// 完整的Key和Secret只在创建时返回一次
 	c.JSON(http.StatusCreated, gin.H{
 		"success": true,
 		"data":    apiKey,
 	})
+	created, _ := h.authenticator.GetAPIKey(apiKey.Key)
+	h.audit.Record(c, "apikey:"+apiKey.ID, nil, created, nil)
 }
 
 // ListAPIKeys 列出API密钥
@@ -302,12 +403,22 @@ func (h *GatewayHandler) ListAPIKeys(c *gin.Context) {
 func (h *GatewayHandler) RevokeAPIKey(c *gin.Context) {
 	key := c.Param("key")
 
+	before, exists := h.authenticator.GetAPIKey(key)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "API key not found",
+		})
+		return
+	}
+
 	if err := h.authenticator.RevokeAPIKey(key); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"success": false,
 			"error":   "API key not found",
 			"message": err.Error(),
 		})
+		h.audit.Record(c, "apikey:"+before.ID, before, nil, err)
 		return
 	}
 
@@ -315,6 +426,8 @@ func (h *GatewayHandler) RevokeAPIKey(c *gin.Context) {
 		"success": true,
 		"message": "API key revoked successfully",
 	})
+	after, _ := h.authenticator.GetAPIKey(key)
+	h.audit.Record(c, "apikey:"+before.ID, before, after, nil)
 }
 
 // Metrics 指标管理
@@ -331,6 +444,7 @@ func (h *GatewayHandler) GetMetrics(c *gin.Context) {
 			"router":       routerMetrics,
 			"load_balancer": loadBalancerMetrics,
 			"collector":    collectorMetrics,
+			"breakdown":    h.collector.GetBreakdown(),
 			"timestamp":    time.Now().Unix(),
 		},
 	})
@@ -365,6 +479,7 @@ func (h *GatewayHandler) ResetMetrics(c *gin.Context) {
 		"success": true,
 		"message": "metrics reset successfully",
 	})
+	h.audit.Record(c, "metrics", nil, nil, nil)
 }
 
 // Rate Limiting 限流管理
@@ -400,12 +515,16 @@ func (h *GatewayHandler) GetRateLimitStats(c *gin.Context) {
 func (h *GatewayHandler) ResetRateLimit(c *gin.Context) {
 	key := c.Param("key")
 
+	// 记录重置前的限流状态
+	before, _ := h.rateLimiter.GetStats(c.Request.Context(), key)
+
 	if err := h.rateLimiter.Reset(c.Request.Context(), key); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"error":   "failed to reset rate limit",
 			"message": err.Error(),
 		})
+		h.audit.Record(c, "ratelimit:"+key, before, nil, err)
 		return
 	}
 
@@ -417,6 +536,7 @@ func (h *GatewayHandler) ResetRateLimit(c *gin.Context) {
 		"success": true,
 		"message": "rate limit reset successfully",
 	})
+	h.audit.Record(c, "ratelimit:"+key, before, nil, nil)
 }
 
 // System 系统管理
@@ -33,6 +33,11 @@ func NewJWTManager(cfg *config.Config) *JWTManager {
 	}
 }
 
+// Expire 令牌有效期
+func (j *JWTManager) Expire() time.Duration {
+	return j.expire
+}
+
 // GenerateToken 生成JWT令牌
 func (j *JWTManager) GenerateToken(userID uint, username string, roleID uint, roleName string) (string, error) {
 	now := time.Now()
@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/env-data-platform/internal/config"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// ErrLDAPInvalidCredentials LDAP用户不存在或密码错误
+var ErrLDAPInvalidCredentials = errors.New("LDAP用户名或密码错误")
+
+// LDAPUser LDAP认证成功后映射出的用户属性
+type LDAPUser struct {
+	DN         string
+	Username   string
+	Email      string
+	RealName   string
+	Phone      string
+	Department string
+}
+
+// LDAPAuthenticator LDAP/AD绑定认证器
+type LDAPAuthenticator struct {
+	config config.LDAPConfig
+}
+
+// NewLDAPAuthenticator 创建LDAP认证器
+func NewLDAPAuthenticator(cfg config.LDAPConfig) *LDAPAuthenticator {
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = "(sAMAccountName=%s)"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.Attributes.Username == "" {
+		cfg.Attributes.Username = "sAMAccountName"
+	}
+	if cfg.Attributes.Email == "" {
+		cfg.Attributes.Email = "mail"
+	}
+	if cfg.Attributes.RealName == "" {
+		cfg.Attributes.RealName = "displayName"
+	}
+
+	return &LDAPAuthenticator{config: cfg}
+}
+
+// Enabled 是否启用LDAP认证
+func (a *LDAPAuthenticator) Enabled() bool {
+	return a.config.Enabled && a.config.URL != ""
+}
+
+// DefaultRole 自动创建用户时分配的默认角色编码
+func (a *LDAPAuthenticator) DefaultRole() string {
+	return a.config.DefaultRole
+}
+
+// Authenticate 使用服务账号检索用户DN，再以用户DN和密码绑定完成认证
+func (a *LDAPAuthenticator) Authenticate(username, password string) (*LDAPUser, error) {
+	// 空密码在多数LDAP服务器上会被当作匿名绑定而成功，必须拒绝
+	if username == "" || password == "" {
+		return nil, ErrLDAPInvalidCredentials
+	}
+
+	conn, err := a.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// 服务账号绑定（未配置时使用匿名检索）
+	if a.config.BindDN != "" {
+		if err := conn.Bind(a.config.BindDN, a.config.BindPassword); err != nil {
+			return nil, fmt.Errorf("LDAP服务账号绑定失败: %w", err)
+		}
+	}
+
+	attrs := a.config.Attributes
+	searchRequest := ldap.NewSearchRequest(
+		a.config.BaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		2, // 只需判断是否唯一
+		int(a.config.Timeout.Seconds()),
+		false,
+		fmt.Sprintf(a.config.UserFilter, ldap.EscapeFilter(username)),
+		[]string{"dn", attrs.Username, attrs.Email, attrs.RealName, attrs.Phone, attrs.Department},
+		nil,
+	)
+
+	result, err := conn.Search(searchRequest)
+	if err != nil {
+		return nil, fmt.Errorf("LDAP检索用户失败: %w", err)
+	}
+	if len(result.Entries) != 1 {
+		return nil, ErrLDAPInvalidCredentials
+	}
+	entry := result.Entries[0]
+
+	// 以用户身份绑定验证密码
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrLDAPInvalidCredentials
+		}
+		return nil, fmt.Errorf("LDAP用户绑定失败: %w", err)
+	}
+
+	user := &LDAPUser{
+		DN:         entry.DN,
+		Username:   entry.GetAttributeValue(attrs.Username),
+		Email:      entry.GetAttributeValue(attrs.Email),
+		RealName:   entry.GetAttributeValue(attrs.RealName),
+		Phone:      entry.GetAttributeValue(attrs.Phone),
+		Department: entry.GetAttributeValue(attrs.Department),
+	}
+	if user.Username == "" {
+		user.Username = username
+	}
+
+	return user, nil
+}
+
+// dial 建立LDAP连接，按配置启用StartTLS
+func (a *LDAPAuthenticator) dial() (*ldap.Conn, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: a.config.InsecureSkipVerify}
+
+	conn, err := ldap.DialURL(a.config.URL,
+		ldap.DialWithTLSConfig(tlsConfig),
+		ldap.DialWithDialer(&net.Dialer{Timeout: a.config.Timeout}))
+	if err != nil {
+		return nil, fmt.Errorf("连接LDAP服务器失败: %w", err)
+	}
+	conn.SetTimeout(a.config.Timeout)
+
+	if a.config.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("LDAP StartTLS失败: %w", err)
+		}
+	}
+
+	return conn, nil
+}
@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/env-data-platform/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	loginFailUserKeyPrefix = "login:fail:user:"
+	loginFailIPKeyPrefix   = "login:fail:ip:"
+	loginLockUserKeyPrefix = "login:lock:user:"
+	loginLockIPKeyPrefix   = "login:lock:ip:"
+)
+
+// LoginGuard 登录防暴力破解守卫，失败计数与锁定状态存储在Redis
+type LoginGuard struct {
+	client *redis.Client
+	config config.LoginSecurityConfig
+}
+
+// LoginLockStatus 登录锁定状态
+type LoginLockStatus struct {
+	Locked    bool          `json:"locked"`
+	Remaining time.Duration `json:"remaining"`
+	Failures  int64         `json:"failures"`
+}
+
+// RetryMinutes 提示用户多少分钟后重试，不足一分钟按一分钟计
+func (s *LoginLockStatus) RetryMinutes() int {
+	minutes := int(math.Ceil(s.Remaining.Minutes()))
+	if minutes < 1 {
+		return 1
+	}
+	return minutes
+}
+
+// NewLoginGuard 创建登录守卫，client为nil时守卫不生效
+func NewLoginGuard(client *redis.Client, cfg config.LoginSecurityConfig) *LoginGuard {
+	if cfg.MaxFailures <= 0 {
+		cfg.MaxFailures = 5
+	}
+	if cfg.IPMaxFailures <= 0 {
+		cfg.IPMaxFailures = 20
+	}
+	if cfg.FailureWindow <= 0 {
+		cfg.FailureWindow = 15 * time.Minute
+	}
+	if cfg.LockDuration <= 0 {
+		cfg.LockDuration = 30 * time.Minute
+	}
+
+	return &LoginGuard{
+		client: client,
+		config: cfg,
+	}
+}
+
+// Enabled 守卫是否生效
+func (g *LoginGuard) Enabled() bool {
+	return g.config.Enabled && g.client != nil
+}
+
+// LoginAccount 返回失败计数和锁定使用的账户标识
+// 用户存在时按用户ID计数，使用户名、邮箱及其大小写变体共享同一计数；不存在时按规范化后的登录名计数
+func LoginAccount(userID uint, username string) string {
+	if userID != 0 {
+		return "id:" + strconv.FormatUint(uint64(userID), 10)
+	}
+	return "name:" + strings.ToLower(strings.TrimSpace(username))
+}
+
+// CheckLocked 检查账户或IP是否处于锁定状态，account由 LoginAccount 生成
+func (g *LoginGuard) CheckLocked(ctx context.Context, account, ip string) (*LoginLockStatus, error) {
+	status := &LoginLockStatus{}
+	if !g.Enabled() {
+		return status, nil
+	}
+
+	for _, key := range []string{loginLockUserKeyPrefix + account, loginLockIPKeyPrefix + ip} {
+		ttl, err := g.client.TTL(ctx, key).Result()
+		if err != nil {
+			return nil, fmt.Errorf("查询锁定状态失败: %w", err)
+		}
+		// TTL为负数表示key不存在
+		if ttl > 0 && ttl > status.Remaining {
+			status.Locked = true
+			status.Remaining = ttl
+		}
+	}
+
+	return status, nil
+}
+
+// RecordFailure 记录一次登录失败，达到阈值时锁定账户或IP
+func (g *LoginGuard) RecordFailure(ctx context.Context, account, ip string) (*LoginLockStatus, error) {
+	status := &LoginLockStatus{}
+	if !g.Enabled() {
+		return status, nil
+	}
+
+	userFailures, err := g.incrFailure(ctx, loginFailUserKeyPrefix+account)
+	if err != nil {
+		return nil, err
+	}
+	ipFailures, err := g.incrFailure(ctx, loginFailIPKeyPrefix+ip)
+	if err != nil {
+		return nil, err
+	}
+	status.Failures = userFailures
+
+	if userFailures >= int64(g.config.MaxFailures) {
+		if err := g.lock(ctx, loginLockUserKeyPrefix+account, loginFailUserKeyPrefix+account); err != nil {
+			return nil, err
+		}
+		status.Locked = true
+		status.Remaining = g.config.LockDuration
+	}
+	if ipFailures >= int64(g.config.IPMaxFailures) {
+		if err := g.lock(ctx, loginLockIPKeyPrefix+ip, loginFailIPKeyPrefix+ip); err != nil {
+			return nil, err
+		}
+		status.Locked = true
+		status.Remaining = g.config.LockDuration
+	}
+
+	return status, nil
+}
+
+// RecordSuccess 登录成功后清除失败计数
+func (g *LoginGuard) RecordSuccess(ctx context.Context, account, ip string) error {
+	if !g.Enabled() {
+		return nil
+	}
+	return g.client.Del(ctx, loginFailUserKeyPrefix+account, loginFailIPKeyPrefix+ip).Err()
+}
+
+// Unlock 管理员手动解锁账户，同时清除失败计数
+func (g *LoginGuard) Unlock(ctx context.Context, account string) error {
+	if g.client == nil {
+		return errors.New("Redis未启用")
+	}
+	return g.client.Del(ctx, loginLockUserKeyPrefix+account, loginFailUserKeyPrefix+account).Err()
+}
+
+// UnlockIP 管理员手动解除IP锁定，同时清除该IP的失败计数
+func (g *LoginGuard) UnlockIP(ctx context.Context, ip string) error {
+	if g.client == nil {
+		return errors.New("Redis未启用")
+	}
+	return g.client.Del(ctx, loginLockIPKeyPrefix+ip, loginFailIPKeyPrefix+ip).Err()
+}
+
+// RemainingAttempts 剩余可尝试次数
+func (g *LoginGuard) RemainingAttempts(failures int64) int64 {
+	remaining := int64(g.config.MaxFailures) - failures
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// incrFailure 失败计数加一，首次计数时设置统计窗口
+func (g *LoginGuard) incrFailure(ctx context.Context, key string) (int64, error) {
+	count, err := g.client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("记录登录失败次数失败: %w", err)
+	}
+	if count == 1 {
+		if err := g.client.Expire(ctx, key, g.config.FailureWindow).Err(); err != nil {
+			return 0, fmt.Errorf("设置失败计数窗口失败: %w", err)
+		}
+	}
+	return count, nil
+}
+
+// lock 设置锁定标记并清除对应的失败计数
+func (g *LoginGuard) lock(ctx context.Context, lockKey, failKey string) error {
+	pipe := g.client.TxPipeline()
+	pipe.Set(ctx, lockKey, time.Now().Unix(), g.config.LockDuration)
+	pipe.Del(ctx, failKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("设置登录锁定失败: %w", err)
+	}
+	return nil
+}
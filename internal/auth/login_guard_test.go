@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/env-data-platform/internal/config"
+)
+
+func TestLoginAccount(t *testing.T) {
+	// 已存在的用户按ID计数，用户名、邮箱登录共享锁定
+	assert.Equal(t, "id:42", LoginAccount(42, "Admin"))
+	assert.Equal(t, LoginAccount(42, "admin@example.com"), LoginAccount(42, "admin"))
+
+	// 不存在的用户按规范化登录名计数，大小写和首尾空白不能绕过
+	assert.Equal(t, "name:admin", LoginAccount(0, " ADMIN "))
+	assert.Equal(t, LoginAccount(0, "admin"), LoginAccount(0, "Admin\t"))
+	assert.NotEqual(t, LoginAccount(0, "42"), LoginAccount(42, "42"))
+}
+
+func TestLoginLockStatusRetryMinutes(t *testing.T) {
+	tests := []struct {
+		remaining time.Duration
+		expected  int
+	}{
+		// 刚锁定时与锁定时长一致，不额外加一分钟
+		{30 * time.Minute, 30},
+		{29*time.Minute + time.Second, 30},
+		{90 * time.Second, 2},
+		{time.Second, 1},
+		{0, 1},
+	}
+	for _, tt := range tests {
+		status := &LoginLockStatus{Locked: true, Remaining: tt.remaining}
+		assert.Equal(t, tt.expected, status.RetryMinutes(), tt.remaining.String())
+	}
+}
+
+func TestLoginGuardUnlockIPWithoutRedis(t *testing.T) {
+	g := NewLoginGuard(nil, config.LoginSecurityConfig{Enabled: true})
+	assert.Error(t, g.UnlockIP(context.Background(), "10.0.0.1"))
+}
@@ -9,6 +9,8 @@ import (
 	"strings"
 
 	"golang.org/x/crypto/argon2"
+
+	"github.com/env-data-platform/internal/config"
 )
 
 // PasswordConfig 密码配置
@@ -34,13 +36,34 @@ type PasswordManager struct {
 	config *PasswordConfig
 }
 
-// NewPasswordManager 创建密码管理器
+// NewPasswordManager 创建使用默认参数的密码管理器
 func NewPasswordManager() *PasswordManager {
 	return &PasswordManager{
 		config: DefaultPasswordConfig,
 	}
 }
 
+// NewPasswordManagerWithConfig 按配置的Argon2参数创建密码管理器，未配置的参数使用默认值
+func NewPasswordManagerWithConfig(cfg config.Argon2Config) *PasswordManager {
+	p := *DefaultPasswordConfig
+	if cfg.Memory > 0 {
+		p.Memory = cfg.Memory
+	}
+	if cfg.Iterations > 0 {
+		p.Iterations = cfg.Iterations
+	}
+	if cfg.Parallelism > 0 {
+		p.Parallelism = cfg.Parallelism
+	}
+	if cfg.SaltLength > 0 {
+		p.SaltLength = cfg.SaltLength
+	}
+	if cfg.KeyLength > 0 {
+		p.KeyLength = cfg.KeyLength
+	}
+	return &PasswordManager{config: &p}
+}
+
 // HashPassword 哈希密码
 func (pm *PasswordManager) HashPassword(password string) (string, error) {
 	// 生成随机盐
@@ -81,6 +104,28 @@ func (pm *PasswordManager) VerifyPassword(password, encodedHash string) (bool, e
 	return false, nil
 }
 
+// NeedsRehash 散列串的参数与当前配置不一致时返回true，用于登录成功后升级旧散列
+func (pm *PasswordManager) NeedsRehash(encodedHash string) bool {
+	p, _, _, err := pm.decodeHash(encodedHash)
+	if err != nil {
+		return true
+	}
+	return p.Memory != pm.config.Memory ||
+		p.Iterations != pm.config.Iterations ||
+		p.Parallelism != pm.config.Parallelism ||
+		p.SaltLength != pm.config.SaltLength ||
+		p.KeyLength != pm.config.KeyLength
+}
+
+// GenerateRandomPassword 生成指定字节长度的随机密码（URL安全的base64编码）
+func (pm *PasswordManager) GenerateRandomPassword(n uint32) (string, error) {
+	b, err := pm.generateRandomBytes(n)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
 // generateRandomBytes 生成随机字节
 func (pm *PasswordManager) generateRandomBytes(n uint32) ([]byte, error) {
 	b := make([]byte, n)
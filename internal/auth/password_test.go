@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/env-data-platform/internal/config"
+)
+
+func TestPasswordManagerWithConfig(t *testing.T) {
+	legacy := NewPasswordManager()
+	legacyHash, err := legacy.HashPassword("Secret#123")
+	require.NoError(t, err)
+
+	pm := NewPasswordManagerWithConfig(config.Argon2Config{Memory: 8 * 1024, Iterations: 1, Parallelism: 1})
+	hash, err := pm.HashPassword("Secret#123")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=8192,t=1,p=1$"))
+
+	// 散列串自带参数，两种配置互相可校验
+	for _, m := range []*PasswordManager{legacy, pm} {
+		for _, h := range []string{legacyHash, hash} {
+			ok, err := m.VerifyPassword("Secret#123", h)
+			require.NoError(t, err)
+			assert.True(t, ok)
+
+			ok, err = m.VerifyPassword("wrong", h)
+			require.NoError(t, err)
+			assert.False(t, ok)
+		}
+	}
+
+	assert.True(t, pm.NeedsRehash(legacyHash))
+	assert.False(t, pm.NeedsRehash(hash))
+	assert.False(t, legacy.NeedsRehash(legacyHash))
+	assert.True(t, pm.NeedsRehash("invalid"))
+}
@@ -0,0 +1,47 @@
+package auth
+
+import "strings"
+
+// PermissionWildcard 权限通配符
+const PermissionWildcard = "*"
+
+// MatchPermission 判断已授予的权限code是否覆盖所需权限
+// 权限code按":"分段，形如"资源:动作"：
+//   - "*" 匹配所有权限
+//   - 中间的"*"匹配任意单个分段，如"*:read"匹配"datasource:read"
+//   - 末尾的"*"匹配其后的所有分段（至少一个），如"etl:*"匹配"etl:job"、"etl:job:execute"，不匹配"etl"
+func MatchPermission(granted, required string) bool {
+	if granted == "" || required == "" {
+		return false
+	}
+	if granted == PermissionWildcard || granted == required {
+		return true
+	}
+
+	grantedParts := strings.Split(granted, ":")
+	requiredParts := strings.Split(required, ":")
+
+	for i, part := range grantedParts {
+		if i >= len(requiredParts) {
+			return false
+		}
+		if part == PermissionWildcard && i == len(grantedParts)-1 {
+			return true
+		}
+		if part != PermissionWildcard && part != requiredParts[i] {
+			return false
+		}
+	}
+
+	return len(grantedParts) == len(requiredParts)
+}
+
+// HasPermission 判断权限集合中是否存在覆盖所需权限的code
+func HasPermission(granted []string, required string) bool {
+	for _, code := range granted {
+		if MatchPermission(code, required) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchPermission(t *testing.T) {
+	cases := []struct {
+		granted  string
+		required string
+		expected bool
+	}{
+		{"*", "datasource:read", true},
+		{"datasource:read", "datasource:read", true},
+		{"datasource:read", "datasource:write", false},
+		{"etl:*", "etl:job", true},
+		{"etl:*", "etl:job:execute", true},
+		{"etl:*", "etl", false},
+		{"etl:job:*", "etl:job", false},
+		{"etl:*", "quality:rule", false},
+		{"*:read", "datasource:read", true},
+		{"*:read", "datasource:write", false},
+		{"*:read", "datasource:table:read", false},
+		{"etl:job", "etl:job:execute", false},
+		{"etl:job:execute", "etl:job", false},
+		{"", "etl:job", false},
+	}
+
+	for _, tc := range cases {
+		assert.Equal(t, tc.expected, MatchPermission(tc.granted, tc.required),
+			"granted=%s required=%s", tc.granted, tc.required)
+	}
+}
+
+func TestHasPermission(t *testing.T) {
+	granted := []string{"datasource:read", "etl:*"}
+
+	assert.True(t, HasPermission(granted, "etl:job:execute"))
+	assert.True(t, HasPermission(granted, "datasource:read"))
+	assert.False(t, HasPermission(granted, "datasource:delete"))
+	assert.False(t, HasPermission(nil, "datasource:read"))
+}
@@ -10,15 +10,26 @@ import (
 
 // Config 应用配置结构
 type Config struct {
-	App      AppConfig      `mapstructure:"app"`
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Redis    RedisConfig    `mapstructure:"redis"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
-	Log      LogConfig      `mapstructure:"log"`
-	Monitor  MonitorConfig  `mapstructure:"monitor"`
-	ETL      ETLConfig      `mapstructure:"etl"`
-	HJ212    HJ212Config    `mapstructure:"hj212"`
+	App       AppConfig       `mapstructure:"app"`
+	Server    ServerConfig    `mapstructure:"server"`
+	Database  DatabaseConfig  `mapstructure:"database"`
+	Redis     RedisConfig     `mapstructure:"redis"`
+	JWT       JWTConfig       `mapstructure:"jwt"`
+	Log       LogConfig       `mapstructure:"log"`
+	Monitor   MonitorConfig   `mapstructure:"monitor"`
+	ETL       ETLConfig       `mapstructure:"etl"`
+	HJ212     HJ212Config     `mapstructure:"hj212"`
+	Security  SecurityConfig  `mapstructure:"security"`
+	LDAP      LDAPConfig      `mapstructure:"ldap"`
+	Dashboard DashboardConfig `mapstructure:"dashboard"`
+	Alarm     AlarmConfig     `mapstructure:"alarm"`
+	Upload    UploadConfig    `mapstructure:"upload"`
+	Storage   StorageConfig   `mapstructure:"storage"`
+	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
+	AuditLog  AuditLogConfig  `mapstructure:"audit_log"`
+	Export    ExportConfig    `mapstructure:"export"`
+
+	DataSource DataSourceConfig `mapstructure:"datasource"`
 }
 
 // AppConfig 应用基础配置
@@ -86,6 +97,56 @@ type LogConfig struct {
 	Compress   bool   `mapstructure:"compress"`
 }
 
+// RateLimitConfig 按客户端IP的接口限流配置，支持热加载
+type RateLimitConfig struct {
+	Enabled           bool `mapstructure:"enabled"`
+	RequestsPerMinute int  `mapstructure:"requests_per_minute"`
+	Burst             int  `mapstructure:"burst"`
+}
+
+// AuditLogConfig 操作日志和登录日志保留配置
+type AuditLogConfig struct {
+	Cleanup AuditLogCleanupConfig `mapstructure:"cleanup"`
+}
+
+// ExportConfig 列表数据导出配置
+type ExportConfig struct {
+	MaxRows   int `mapstructure:"max_rows"`   // 单次导出的最大行数
+	BatchSize int `mapstructure:"batch_size"` // 流式导出时每批查询的行数
+}
+
+// AuditLogCleanupConfig 过期日志自动清理配置
+type AuditLogCleanupConfig struct {
+	Enabled                bool          `mapstructure:"enabled"`
+	Interval               time.Duration `mapstructure:"interval"`                 // 清理周期
+	OperationRetentionDays int           `mapstructure:"operation_retention_days"` // 操作日志保留天数
+	LoginRetentionDays     int           `mapstructure:"login_retention_days"`     // 登录日志保留天数
+	BatchSize              int           `mapstructure:"batch_size"`               // 每批删除条数，避免长事务锁表
+}
+
+// DataSourceConfig 数据源配置
+type DataSourceConfig struct {
+	HealthCheck DataSourceHealthCheckConfig `mapstructure:"health_check"`
+	Preview     DataSourcePreviewConfig     `mapstructure:"preview"`
+}
+
+// DataSourcePreviewConfig 数据预览配置，创建ETL作业前查看源表样例数据
+type DataSourcePreviewConfig struct {
+	DefaultRows int           `mapstructure:"default_rows"` // 未指定行数时返回的行数
+	MaxRows     int           `mapstructure:"max_rows"`     // 单次预览最大行数
+	Timeout     time.Duration `mapstructure:"timeout"`      // 预览查询超时
+	MaskColumns []string      `mapstructure:"mask_columns"` // 列名包含这些关键字（不区分大小写）时脱敏
+}
+
+// DataSourceHealthCheckConfig 数据源健康巡检配置
+type DataSourceHealthCheckConfig struct {
+	Enabled          bool          `mapstructure:"enabled"`
+	Interval         time.Duration `mapstructure:"interval"`          // 巡检周期
+	Timeout          time.Duration `mapstructure:"timeout"`           // 单个数据源连通性检查超时
+	FailureThreshold int           `mapstructure:"failure_threshold"` // 连续失败多少次后告警
+	Concurrency      int           `mapstructure:"concurrency"`       // 同时检查的数据源数
+}
+
 // MonitorConfig 监控配置
 type MonitorConfig struct {
 	Enabled    bool   `mapstructure:"enabled"`
@@ -96,6 +157,14 @@ type MonitorConfig struct {
 		Enabled bool   `mapstructure:"enabled"`
 		Path    string `mapstructure:"path"`
 	} `mapstructure:"prometheus"`
+	Runtime RuntimeMonitorConfig `mapstructure:"runtime"`
+}
+
+// RuntimeMonitorConfig 健康检查的Go运行时告警阈值，为0表示不检查该项
+type RuntimeMonitorConfig struct {
+	MaxGoroutines int           `mapstructure:"max_goroutines"` // goroutine数量上限，持续超过通常意味着泄漏
+	MaxHeapMB     int           `mapstructure:"max_heap_mb"`    // 堆内存上限(MB)
+	MaxGCPause    time.Duration `mapstructure:"max_gc_pause"`   // 最近GC单次暂停时间上限
 }
 
 // ETLConfig ETL配置
@@ -111,15 +180,306 @@ type ETLConfig struct {
 		TempPath    string `mapstructure:"temp_path"`
 		MaxParallel int    `mapstructure:"max_parallel"`
 	} `mapstructure:"pipeline"`
+	Scheduler ETLSchedulerConfig `mapstructure:"scheduler"`
+}
+
+// ETLSchedulerConfig 定时调度配置，作业调度在启动时从数据库重新加载
+type ETLSchedulerConfig struct {
+	// 启动时补跑停机期间错过的调度，每个作业最多补跑一次
+	CatchUp bool `mapstructure:"catch_up"`
+	// 错过超过该时长的调度不再补跑，0表示不限制
+	MaxCatchUpDelay time.Duration `mapstructure:"max_catch_up_delay"`
+	// 执行锁心跳超过该时长未刷新视为持有实例已停止，执行记录标记为失败且作业可被重新调度
+	StaleRunTimeout time.Duration `mapstructure:"stale_run_timeout"`
 }
 
 // HJ212Config HJ212协议配置
 type HJ212Config struct {
-	Enabled        bool          `mapstructure:"enabled"`
-	TCPPort        int           `mapstructure:"tcp_port"`
-	BufferSize     int           `mapstructure:"buffer_size"`
-	Timeout        time.Duration `mapstructure:"timeout"`
-	MaxConnections int           `mapstructure:"max_connections"`
+	Enabled        bool            `mapstructure:"enabled"`
+	TCPPort        int             `mapstructure:"tcp_port"`
+	BufferSize     int             `mapstructure:"buffer_size"`
+	Timeout        time.Duration   `mapstructure:"timeout"` // 单次读取超时，0表示不限制
+	MaxConnections int             `mapstructure:"max_connections"`
+	Auth           HJ212AuthConfig `mapstructure:"auth"`
+	TLS            HJ212TLSConfig  `mapstructure:"tls"`
+	UDP            HJ212UDPConfig  `mapstructure:"udp"`
+	// 连接超过HeartbeatTimeout未收到有效数据包时服务端主动断开并清理设备映射，0表示不限制
+	HeartbeatTimeout time.Duration `mapstructure:"heartbeat_timeout"`
+	// 超过OfflineTimeout未收到数据包的设备判定为离线
+	OfflineTimeout       time.Duration         `mapstructure:"offline_timeout"`
+	OfflineCheckInterval time.Duration         `mapstructure:"offline_check_interval"`
+	Archive              HJ212ArchiveConfig    `mapstructure:"archive"`
+	DeadLetter           HJ212DeadLetterConfig `mapstructure:"dead_letter"`
+	Quality              HJ212QualityConfig    `mapstructure:"quality"`
+	// 同一设备同一QN的数据包在DedupWindow内按重传处理，应答成功但不重复入库；更早的重传由数据库唯一索引拦截
+	DedupWindow time.Duration `mapstructure:"dedup_window"`
+}
+
+// HJ212QualityConfig 收包时的实时质量校验配置，异常数据标记质量等级，可触发告警
+type HJ212QualityConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// 与上一条数据相比变化量超过上一条值的MaxChangeRatio倍判定为突变，0表示不检查变化率
+	MaxChangeRatio float64 `mapstructure:"max_change_ratio"`
+	// 与上一条数据间隔超过ChangeWindow时不比较变化率
+	ChangeWindow  time.Duration `mapstructure:"change_window"`
+	Alarm         bool          `mapstructure:"alarm"`
+	AlarmCooldown time.Duration `mapstructure:"alarm_cooldown"` // 同一设备同一因子同类异常的告警间隔
+	// 按因子编码配置合理区间，覆盖内置区间
+	Ranges map[string]HJ212FactorRange `mapstructure:"ranges"`
+}
+
+// HJ212FactorRange 因子值的合理区间，为空表示不限制
+type HJ212FactorRange struct {
+	Min *float64 `mapstructure:"min"`
+	Max *float64 `mapstructure:"max"`
+}
+
+// HJ212DeadLetterConfig 解析或校验失败报文的死信记录配置
+type HJ212DeadLetterConfig struct {
+	Enabled   bool          `mapstructure:"enabled"`
+	Retention time.Duration `mapstructure:"retention"` // 超过保留时长的死信被删除，0表示不清理
+}
+
+// HJ212ArchiveConfig 原始报文归档配置，收发的原始包按设备MN和日期写入 Dir/MN/日期.log
+type HJ212ArchiveConfig struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	Dir      string `mapstructure:"dir"`
+	MaxSize  int    `mapstructure:"max_size"` // 单个文件大小上限(MB)，超过后轮转
+	MaxAge   int    `mapstructure:"max_age"`  // 归档保留天数，0表示不清理
+	Compress bool   `mapstructure:"compress"`
+}
+
+// HJ212TLSConfig HJ212 TLS监听配置，Only为false时明文端口与TLS端口同时监听以兼容老设备
+type HJ212TLSConfig struct {
+	Enabled      bool   `mapstructure:"enabled"`
+	Port         int    `mapstructure:"port"`
+	CertFile     string `mapstructure:"cert_file"`
+	KeyFile      string `mapstructure:"key_file"`
+	ClientCAFile string `mapstructure:"client_ca_file"` // 配置后要求设备提供客户端证书
+	Only         bool   `mapstructure:"only"`
+}
+
+// HJ212UDPConfig HJ212 UDP监听配置，UDP设备无连接，按MN和最后收包时间跟踪在线状态
+type HJ212UDPConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	Port    int  `mapstructure:"port"`
+}
+
+// HJ212AuthConfig HJ212设备接入鉴权配置，白名单来自hj212类型的数据源
+type HJ212AuthConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	CheckPassword   bool          `mapstructure:"check_password"`
+	RefreshInterval time.Duration `mapstructure:"refresh_interval"`
+}
+
+// SecurityConfig 安全配置
+type SecurityConfig struct {
+	Login      LoginSecurityConfig    `mapstructure:"login"`
+	Password   PasswordSecurityConfig `mapstructure:"password"`
+	LoginAudit LoginAuditConfig       `mapstructure:"login_audit"`
+}
+
+// LoginSecurityConfig 登录防暴力破解配置
+type LoginSecurityConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	MaxFailures   int           `mapstructure:"max_failures"`    // 同一用户连续失败次数阈值
+	IPMaxFailures int           `mapstructure:"ip_max_failures"` // 同一IP连续失败次数阈值
+	FailureWindow time.Duration `mapstructure:"failure_window"`  // 失败计数统计窗口
+	LockDuration  time.Duration `mapstructure:"lock_duration"`   // 锁定时长
+}
+
+// LoginAuditConfig 登录审计配置，登录日志记录设备和地理信息
+type LoginAuditConfig struct {
+	GeoIPFile        string `mapstructure:"geoip_file"`         // IP地址库CSV文件，为空时只识别内网地址
+	RemoteLoginAlert bool   `mapstructure:"remote_login_alert"` // 异地登录时通过邮件提醒用户
+}
+
+// PasswordSecurityConfig 密码策略配置
+type PasswordSecurityConfig struct {
+	HistoryCount int                 `mapstructure:"history_count"` // 禁止重复使用最近N次密码
+	Argon2       Argon2Config        `mapstructure:"argon2"`
+	Reset        PasswordResetConfig `mapstructure:"reset"`
+}
+
+// PasswordResetConfig 自助密码重置配置，重置邮件通过 alarm.notify.email 配置的SMTP服务器发送
+type PasswordResetConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	TokenTTL        time.Duration `mapstructure:"token_ttl"`        // 重置令牌有效期
+	RequestInterval time.Duration `mapstructure:"request_interval"` // 同一用户两次申请的最小间隔
+	ResetURL        string        `mapstructure:"reset_url"`        // 前端重置页面地址，令牌以token参数附加
+}
+
+// Argon2Config Argon2id密码散列参数，参数随散列串保存，调整后旧密码仍可校验，用户登录时自动按新参数重新散列
+type Argon2Config struct {
+	Memory      uint32 `mapstructure:"memory"`      // 内存开销(KiB)
+	Iterations  uint32 `mapstructure:"iterations"`  // 迭代次数
+	Parallelism uint8  `mapstructure:"parallelism"` // 并行度
+	SaltLength  uint32 `mapstructure:"salt_length"` // 盐长度(字节)
+	KeyLength   uint32 `mapstructure:"key_length"`  // 散列长度(字节)
+}
+
+// LDAPConfig LDAP/AD认证配置
+type LDAPConfig struct {
+	Enabled            bool          `mapstructure:"enabled"`
+	URL                string        `mapstructure:"url"`           // 如 ldap://ad.example.com:389 或 ldaps://ad.example.com:636
+	BindDN             string        `mapstructure:"bind_dn"`       // 检索用户使用的服务账号
+	BindPassword       string        `mapstructure:"bind_password"` // 服务账号密码
+	BaseDN             string        `mapstructure:"base_dn"`
+	UserFilter         string        `mapstructure:"user_filter"` // 如 (sAMAccountName=%s)
+	StartTLS           bool          `mapstructure:"start_tls"`
+	InsecureSkipVerify bool          `mapstructure:"insecure_skip_verify"`
+	Timeout            time.Duration `mapstructure:"timeout"`
+	DefaultRole        string        `mapstructure:"default_role"` // 自动创建用户时分配的角色编码
+	Attributes         struct {
+		Username   string `mapstructure:"username"`
+		Email      string `mapstructure:"email"`
+		RealName   string `mapstructure:"real_name"`
+		Phone      string `mapstructure:"phone"`
+		Department string `mapstructure:"department"`
+	} `mapstructure:"attributes"`
+}
+
+// DashboardConfig 仪表板配置
+type DashboardConfig struct {
+	Cache struct {
+		Enabled bool          `mapstructure:"enabled"`
+		TTL     time.Duration `mapstructure:"ttl"` // 概览数据缓存时长，数据变更时会主动失效
+	} `mapstructure:"cache"`
+	Push DashboardPushConfig `mapstructure:"push"`
+}
+
+// DashboardPushConfig 仪表板WebSocket推送配置
+type DashboardPushConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	DefaultInterval time.Duration `mapstructure:"default_interval"` // 订阅未指定频率时的定时推送间隔
+	MinInterval     time.Duration `mapstructure:"min_interval"`     // 订阅允许的最小间隔，数据变化触发的推送也按此合并
+}
+
+// AlarmConfig 告警配置
+type AlarmConfig struct {
+	SuppressWindow time.Duration     `mapstructure:"suppress_window"` // 已确认告警的抑制窗口，窗口内相同告警不再产生，0表示不抑制
+	Notify         AlarmNotifyConfig `mapstructure:"notify"`
+}
+
+// AlarmNotifyConfig 告警通知配置，各通道可独立开关并按告警级别路由
+type AlarmNotifyConfig struct {
+	Enabled       bool                 `mapstructure:"enabled"`
+	MaxRetries    int                  `mapstructure:"max_retries"`    // 发送失败后的重试次数
+	RetryInterval time.Duration        `mapstructure:"retry_interval"` // 首次重试间隔，之后按次数线性递增
+	Timeout       time.Duration        `mapstructure:"timeout"`        // 单次发送超时
+	Email         EmailNotifyConfig    `mapstructure:"email"`
+	DingTalk      DingTalkNotifyConfig `mapstructure:"dingtalk"`
+	WeCom         WeComNotifyConfig    `mapstructure:"wecom"`
+	Webhook       WebhookNotifyConfig  `mapstructure:"webhook"`
+}
+
+// NotifyChannelConfig 通知通道公共配置
+type NotifyChannelConfig struct {
+	Enabled bool     `mapstructure:"enabled"`
+	Levels  []string `mapstructure:"levels"` // 接收的告警级别，为空表示全部级别
+}
+
+// EmailNotifyConfig 邮件通知配置
+type EmailNotifyConfig struct {
+	NotifyChannelConfig `mapstructure:",squash"`
+	Host                string   `mapstructure:"host"`
+	Port                int      `mapstructure:"port"`
+	Username            string   `mapstructure:"username"`
+	Password            string   `mapstructure:"password"`
+	From                string   `mapstructure:"from"`
+	To                  []string `mapstructure:"to"`
+	SSL                 bool     `mapstructure:"ssl"` // 使用SSL直连（如465端口），否则在服务器支持时使用STARTTLS
+}
+
+// DingTalkNotifyConfig 钉钉机器人通知配置
+type DingTalkNotifyConfig struct {
+	NotifyChannelConfig `mapstructure:",squash"`
+	Webhook             string   `mapstructure:"webhook"`
+	Secret              string   `mapstructure:"secret"` // 加签密钥，为空表示不加签
+	AtMobiles           []string `mapstructure:"at_mobiles"`
+}
+
+// WeComNotifyConfig 企业微信机器人通知配置
+type WeComNotifyConfig struct {
+	NotifyChannelConfig `mapstructure:",squash"`
+	Webhook             string `mapstructure:"webhook"`
+}
+
+// WebhookNotifyConfig 自定义Webhook通知配置
+type WebhookNotifyConfig struct {
+	NotifyChannelConfig `mapstructure:",squash"`
+	URL                 string            `mapstructure:"url"`
+	Headers             map[string]string `mapstructure:"headers"`
+}
+
+// UploadConfig 文件上传配置
+type UploadConfig struct {
+	UploadPath string                        `mapstructure:"upload_path"`
+	TempPath   string                        `mapstructure:"temp_path"` // 分片等临时文件目录
+	MaxSize    int64                         `mapstructure:"max_size"`  // 分类未设置上限时的默认单文件上限(字节)
+	Categories map[string]FileCategoryConfig `mapstructure:"categories"`
+	Chunk      ChunkUploadConfig             `mapstructure:"chunk"`
+	Zip        ZipDownloadConfig             `mapstructure:"zip"`
+	Import     TableImportConfig             `mapstructure:"import"`
+}
+
+// FileCategoryConfig 文件分类的上传限制，扩展名与实际MIME需同时命中才允许上传
+type FileCategoryConfig struct {
+	Extensions []string `mapstructure:"extensions"` // 如 .csv，不区分大小写
+	MimeTypes  []string `mapstructure:"mime_types"` // 按文件内容识别出的MIME，支持 text/* 形式的通配
+	MaxSize    int64    `mapstructure:"max_size"`   // 单文件上限(字节)，0表示使用upload.max_size
+}
+
+// ChunkUploadConfig 分片上传配置
+type ChunkUploadConfig struct {
+	MaxFileSize     int64         `mapstructure:"max_file_size"`    // 分片上传单文件大小上限(字节)
+	MinChunkSize    int64         `mapstructure:"min_chunk_size"`   // 分片大小下限(字节)，最后一片除外
+	MaxChunkSize    int64         `mapstructure:"max_chunk_size"`   // 分片大小上限(字节)
+	Expire          time.Duration `mapstructure:"expire"`           // 未完成的上传自最后一次活动起保留时长
+	CleanupInterval time.Duration `mapstructure:"cleanup_interval"` // 过期分片清理间隔
+}
+
+// ZipDownloadConfig 多文件打包下载配置
+type ZipDownloadConfig struct {
+	MaxFiles     int   `mapstructure:"max_files"`      // 单次打包文件数上限
+	MaxTotalSize int64 `mapstructure:"max_total_size"` // 单次打包原始文件总大小上限(字节)
+}
+
+// TableImportConfig CSV/Excel导入为数据源的配置
+type TableImportConfig struct {
+	MaxRows   int `mapstructure:"max_rows"`   // 单个文件导入的最大数据行数
+	BatchSize int `mapstructure:"batch_size"` // 每条INSERT写入的行数
+}
+
+// StorageConfig 文件存储配置
+type StorageConfig struct {
+	Type            string           `mapstructure:"type"`             // local, s3, oss
+	PresignDownload bool             `mapstructure:"presign_download"` // 下载时重定向到预签名URL而非由服务端代理文件流
+	PresignExpire   time.Duration    `mapstructure:"presign_expire"`
+	S3              S3StorageConfig  `mapstructure:"s3"`
+	OSS             OSSStorageConfig `mapstructure:"oss"`
+}
+
+// S3StorageConfig S3兼容对象存储配置（AWS S3、MinIO等）
+type S3StorageConfig struct {
+	Endpoint  string `mapstructure:"endpoint"` // 如 s3.cn-north-1.amazonaws.com.cn 或 minio:9000
+	Region    string `mapstructure:"region"`
+	Bucket    string `mapstructure:"bucket"`
+	AccessKey string `mapstructure:"access_key"`
+	SecretKey string `mapstructure:"secret_key"`
+	UseSSL    bool   `mapstructure:"use_ssl"`
+	PathStyle bool   `mapstructure:"path_style"` // MinIO等需使用路径风格访问
+	Prefix    string `mapstructure:"prefix"`     // 对象key前缀
+}
+
+// OSSStorageConfig 阿里云OSS配置
+type OSSStorageConfig struct {
+	Endpoint        string `mapstructure:"endpoint"` // 如 oss-cn-hangzhou.aliyuncs.com
+	Bucket          string `mapstructure:"bucket"`
+	AccessKeyID     string `mapstructure:"access_key_id"`
+	AccessKeySecret string `mapstructure:"access_key_secret"`
+	UseSSL          bool   `mapstructure:"use_ssl"`
+	Prefix          string `mapstructure:"prefix"` // 对象key前缀
 }
 
 // GlobalConfig 全局配置实例
@@ -153,6 +513,22 @@ func Load(configPath string) (*Config, error) {
 		}
 	}
 
+	config, err := unmarshal()
+	if err != nil {
+		return nil, err
+	}
+
+	// 校验配置，一次性报告全部问题
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	GlobalConfig = config
+	return config, nil
+}
+
+// unmarshal 将viper中的配置解析为Config并补齐环境变量和内置默认值
+func unmarshal() (*Config, error) {
 	var config Config
 	if err := viper.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("解析配置失败: %w", err)
@@ -161,7 +537,11 @@ func Load(configPath string) (*Config, error) {
 	// 从环境变量覆盖敏感配置
 	overrideFromEnv(&config)
 
-	GlobalConfig = &config
+	// 未配置上传分类时使用内置白名单
+	if len(config.Upload.Categories) == 0 {
+		config.Upload.Categories = defaultUploadCategories()
+	}
+
 	return &config, nil
 }
 
@@ -179,6 +559,11 @@ func setDefaults() {
 	viper.SetDefault("server.read_timeout", 30)
 	viper.SetDefault("server.write_timeout", 30)
 
+	// 限流配置默认值
+	viper.SetDefault("rate_limit.enabled", true)
+	viper.SetDefault("rate_limit.requests_per_minute", 6000)
+	viper.SetDefault("rate_limit.burst", 200)
+
 	// 数据库配置默认值
 	viper.SetDefault("database.driver", "mysql")
 	viper.SetDefault("database.host", "localhost")
@@ -218,6 +603,9 @@ func setDefaults() {
 	viper.SetDefault("monitor.path", "/metrics")
 	viper.SetDefault("monitor.prometheus.enabled", true)
 	viper.SetDefault("monitor.prometheus.path", "/metrics")
+	viper.SetDefault("monitor.runtime.max_goroutines", 10000)
+	viper.SetDefault("monitor.runtime.max_heap_mb", 1024)
+	viper.SetDefault("monitor.runtime.max_gc_pause", "100ms")
 
 	// ETL配置默认值
 	viper.SetDefault("etl.hop_server.host", "localhost")
@@ -226,6 +614,149 @@ func setDefaults() {
 	viper.SetDefault("etl.pipeline.base_path", "./pipelines")
 	viper.SetDefault("etl.pipeline.temp_path", "./temp")
 	viper.SetDefault("etl.pipeline.max_parallel", 5)
+	viper.SetDefault("etl.scheduler.catch_up", false)
+	viper.SetDefault("etl.scheduler.max_catch_up_delay", "24h")
+	viper.SetDefault("etl.scheduler.stale_run_timeout", "5m")
+
+	// HJ212配置默认值
+	viper.SetDefault("hj212.auth.enabled", false)
+	viper.SetDefault("hj212.auth.check_password", true)
+	viper.SetDefault("hj212.auth.refresh_interval", "1m")
+	viper.SetDefault("hj212.heartbeat_timeout", "5m")
+	viper.SetDefault("hj212.offline_timeout", "10m")
+	viper.SetDefault("hj212.offline_check_interval", "1m")
+	viper.SetDefault("hj212.dedup_window", "10m")
+	viper.SetDefault("hj212.tls.enabled", false)
+	viper.SetDefault("hj212.tls.port", 9213)
+	viper.SetDefault("hj212.tls.only", false)
+	viper.SetDefault("hj212.udp.enabled", false)
+	viper.SetDefault("hj212.udp.port", 9212)
+	viper.SetDefault("hj212.archive.enabled", false)
+	viper.SetDefault("hj212.archive.dir", "logs/hj212-archive")
+	viper.SetDefault("hj212.archive.max_size", 100)
+	viper.SetDefault("hj212.archive.max_age", 30)
+	viper.SetDefault("hj212.archive.compress", false)
+	viper.SetDefault("hj212.dead_letter.enabled", true)
+	viper.SetDefault("hj212.dead_letter.retention", "720h")
+	viper.SetDefault("hj212.quality.enabled", true)
+	viper.SetDefault("hj212.quality.max_change_ratio", 5)
+	viper.SetDefault("hj212.quality.change_window", "30m")
+	viper.SetDefault("hj212.quality.alarm", true)
+	viper.SetDefault("hj212.quality.alarm_cooldown", "30m")
+
+	// 安全配置默认值
+	viper.SetDefault("security.login.enabled", true)
+	viper.SetDefault("security.login.max_failures", 5)
+	viper.SetDefault("security.login.ip_max_failures", 20)
+	viper.SetDefault("security.login.failure_window", "15m")
+	viper.SetDefault("security.login.lock_duration", "30m")
+	viper.SetDefault("security.login_audit.geoip_file", "")
+	viper.SetDefault("security.login_audit.remote_login_alert", false)
+	viper.SetDefault("security.password.history_count", 5)
+	viper.SetDefault("security.password.argon2.memory", 64*1024) // 64MB
+	viper.SetDefault("security.password.argon2.iterations", 3)
+	viper.SetDefault("security.password.argon2.parallelism", 2)
+	viper.SetDefault("security.password.argon2.salt_length", 16)
+	viper.SetDefault("security.password.argon2.key_length", 32)
+	viper.SetDefault("security.password.reset.enabled", false)
+	viper.SetDefault("security.password.reset.token_ttl", "30m")
+	viper.SetDefault("security.password.reset.request_interval", "1m")
+
+	// LDAP配置默认值
+	viper.SetDefault("ldap.enabled", false)
+	viper.SetDefault("ldap.user_filter", "(sAMAccountName=%s)")
+	viper.SetDefault("ldap.timeout", "5s")
+	viper.SetDefault("ldap.default_role", "viewer")
+	viper.SetDefault("ldap.attributes.username", "sAMAccountName")
+	viper.SetDefault("ldap.attributes.email", "mail")
+	viper.SetDefault("ldap.attributes.real_name", "displayName")
+	viper.SetDefault("ldap.attributes.phone", "telephoneNumber")
+	viper.SetDefault("ldap.attributes.department", "department")
+
+	// 仪表板配置默认值
+	viper.SetDefault("dashboard.cache.enabled", true)
+	viper.SetDefault("dashboard.cache.ttl", "30s")
+	viper.SetDefault("dashboard.push.enabled", true)
+	viper.SetDefault("dashboard.push.default_interval", "30s")
+	viper.SetDefault("dashboard.push.min_interval", "2s")
+
+	// 文件上传配置默认值
+	viper.SetDefault("upload.upload_path", "./uploads")
+	viper.SetDefault("upload.temp_path", "./temp")
+	viper.SetDefault("upload.max_size", 50*1024*1024)                // 50MB
+	viper.SetDefault("upload.chunk.max_file_size", 2*1024*1024*1024) // 2GB
+	viper.SetDefault("upload.chunk.min_chunk_size", 1024*1024)       // 1MB
+	viper.SetDefault("upload.chunk.max_chunk_size", 20*1024*1024)    // 20MB
+	viper.SetDefault("upload.chunk.expire", "24h")
+	viper.SetDefault("upload.chunk.cleanup_interval", "1h")
+	viper.SetDefault("upload.zip.max_files", 200)
+	viper.SetDefault("upload.zip.max_total_size", 1024*1024*1024) // 1GB
+	viper.SetDefault("upload.import.max_rows", 200000)
+	viper.SetDefault("upload.import.batch_size", 500)
+
+	// 文件存储配置默认值
+	viper.SetDefault("storage.type", "local")
+	viper.SetDefault("storage.presign_download", false)
+	viper.SetDefault("storage.presign_expire", "15m")
+	viper.SetDefault("storage.s3.region", "us-east-1")
+	viper.SetDefault("storage.s3.use_ssl", true)
+	viper.SetDefault("storage.oss.use_ssl", true)
+
+	// 日志保留配置默认值
+	viper.SetDefault("audit_log.cleanup.enabled", true)
+	viper.SetDefault("audit_log.cleanup.interval", "24h")
+	viper.SetDefault("audit_log.cleanup.operation_retention_days", 180)
+	viper.SetDefault("audit_log.cleanup.login_retention_days", 180)
+	viper.SetDefault("audit_log.cleanup.batch_size", 1000)
+	viper.SetDefault("export.max_rows", 100000)
+	viper.SetDefault("export.batch_size", 500)
+
+	// 数据源健康巡检默认值
+	viper.SetDefault("datasource.health_check.enabled", true)
+	viper.SetDefault("datasource.health_check.interval", "5m")
+	viper.SetDefault("datasource.health_check.timeout", "10s")
+	viper.SetDefault("datasource.health_check.failure_threshold", 3)
+	viper.SetDefault("datasource.health_check.concurrency", 4)
+	viper.SetDefault("datasource.preview.default_rows", 20)
+	viper.SetDefault("datasource.preview.max_rows", 200)
+	viper.SetDefault("datasource.preview.timeout", "10s")
+	viper.SetDefault("datasource.preview.mask_columns", []string{"password", "passwd", "secret", "token", "phone", "mobile", "email", "id_card", "idcard"})
+
+	// 告警配置默认值
+	viper.SetDefault("alarm.suppress_window", "30m")
+	viper.SetDefault("alarm.notify.enabled", false)
+	viper.SetDefault("alarm.notify.max_retries", 3)
+	viper.SetDefault("alarm.notify.retry_interval", "5s")
+	viper.SetDefault("alarm.notify.timeout", "10s")
+	viper.SetDefault("alarm.notify.email.port", 25)
+}
+
+// defaultUploadCategories 内置的上传文件分类白名单
+func defaultUploadCategories() map[string]FileCategoryConfig {
+	return map[string]FileCategoryConfig{
+		"data": {
+			Extensions: []string{".csv", ".txt", ".json", ".xml", ".xlsx", ".xls"},
+			MimeTypes: []string{
+				"text/plain", "text/csv", "application/json", "text/xml", "application/xml",
+				"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+				"application/vnd.ms-excel", "application/x-ole-storage",
+			},
+			MaxSize: 100 * 1024 * 1024,
+		},
+		"document": {
+			Extensions: []string{".pdf", ".doc", ".docx"},
+			MimeTypes: []string{
+				"application/pdf", "application/msword", "application/x-ole-storage",
+				"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+			},
+			MaxSize: 50 * 1024 * 1024,
+		},
+		"image": {
+			Extensions: []string{".jpg", ".jpeg", ".png", ".gif"},
+			MimeTypes:  []string{"image/jpeg", "image/png", "image/gif"},
+			MaxSize:    10 * 1024 * 1024,
+		},
+	}
 }
 
 // overrideFromEnv 从环境变量覆盖敏感配置
@@ -239,6 +770,18 @@ func overrideFromEnv(config *Config) {
 	if jwtSecret := os.Getenv("JWT_SECRET"); jwtSecret != "" {
 		config.JWT.Secret = jwtSecret
 	}
+	if ldapBindPassword := os.Getenv("LDAP_BIND_PASSWORD"); ldapBindPassword != "" {
+		config.LDAP.BindPassword = ldapBindPassword
+	}
+	if smtpPassword := os.Getenv("ALARM_SMTP_PASSWORD"); smtpPassword != "" {
+		config.Alarm.Notify.Email.Password = smtpPassword
+	}
+	if s3SecretKey := os.Getenv("S3_SECRET_KEY"); s3SecretKey != "" {
+		config.Storage.S3.SecretKey = s3SecretKey
+	}
+	if ossSecret := os.Getenv("OSS_ACCESS_KEY_SECRET"); ossSecret != "" {
+		config.Storage.OSS.AccessKeySecret = ossSecret
+	}
 	if hopPassword := os.Getenv("HOP_PASSWORD"); hopPassword != "" {
 		config.ETL.HopServer.Password = hopPassword
 	}
@@ -287,4 +830,4 @@ func (c *AppConfig) IsProduction() bool {
 // IsDevelopment 判断是否为开发环境
 func (c *AppConfig) IsDevelopment() bool {
 	return c.Environment == "development"
-}
\ No newline at end of file
+}
@@ -0,0 +1,382 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultJWTSecret 内置的JWT密钥，仅用于开发环境
+const defaultJWTSecret = "env-data-platform-secret-key"
+
+// ValidationError 配置校验错误，聚合全部不合法的配置项
+type ValidationError struct {
+	Problems []string
+}
+
+// Error 实现error接口
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("配置校验失败，共%d项:\n  - %s", len(e.Problems), strings.Join(e.Problems, "\n  - "))
+}
+
+// validator 收集校验问题
+type validator struct {
+	problems []string
+}
+
+func (v *validator) addf(format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
+
+func (v *validator) required(key, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.addf("%s 不能为空", key)
+	}
+}
+
+func (v *validator) port(key string, port int) {
+	if port < 1 || port > 65535 {
+		v.addf("%s 必须在1-65535之间，当前为%d", key, port)
+	}
+}
+
+func (v *validator) oneOf(key, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.addf("%s 取值无效: %q，可选值: %s", key, value, strings.Join(allowed, ", "))
+}
+
+func (v *validator) nonNegative(key string, value int64) {
+	if value < 0 {
+		v.addf("%s 不能为负数", key)
+	}
+}
+
+// file 校验证书等文件，checkExists为true时要求文件存在
+func (v *validator) file(key, path string, checkExists bool) {
+	if strings.TrimSpace(path) == "" {
+		v.addf("%s 不能为空", key)
+		return
+	}
+	if !checkExists {
+		return
+	}
+	if info, err := os.Stat(path); err != nil {
+		v.addf("%s 文件不可用: %v", key, err)
+	} else if info.IsDir() {
+		v.addf("%s 不能是目录: %s", key, path)
+	}
+}
+
+// Validate 校验配置的必填项、取值范围、枚举和互斥关系，返回包含全部问题的*ValidationError
+func (c *Config) Validate() error {
+	v := &validator{}
+	production := c.App.IsProduction()
+
+	// 应用
+	v.oneOf("app.environment", c.App.Environment, "development", "testing", "staging", "production")
+
+	// HTTP服务
+	v.port("server.port", c.Server.Port)
+	v.nonNegative("server.read_timeout", int64(c.Server.ReadTimeout))
+	v.nonNegative("server.write_timeout", int64(c.Server.WriteTimeout))
+	if c.Server.TLS.Enabled {
+		v.file("server.tls.cert_file", c.Server.TLS.CertFile, production)
+		v.file("server.tls.key_file", c.Server.TLS.KeyFile, production)
+	}
+
+	// 数据库
+	v.oneOf("database.driver", c.Database.Driver, "mysql")
+	v.required("database.host", c.Database.Host)
+	v.port("database.port", c.Database.Port)
+	v.required("database.database", c.Database.Name)
+	v.required("database.username", c.Database.Username)
+	if c.Database.Loc != "" {
+		loc, err := url.QueryUnescape(c.Database.Loc)
+		if err == nil {
+			_, err = time.LoadLocation(loc)
+		}
+		if err != nil {
+			v.addf("database.loc 时区无效: %q", c.Database.Loc)
+		}
+	}
+	if c.Database.ConnMaxLifetime != "" {
+		if _, err := time.ParseDuration(c.Database.ConnMaxLifetime); err != nil {
+			v.addf("database.conn_max_lifetime 格式无效: %q", c.Database.ConnMaxLifetime)
+		}
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		v.addf("database.max_idle_conns(%d) 不能大于 database.max_open_conns(%d)", c.Database.MaxIdleConns, c.Database.MaxOpenConns)
+	}
+
+	// Redis
+	if c.Redis.Host != "" {
+		v.port("redis.port", c.Redis.Port)
+	}
+
+	// JWT
+	v.required("jwt.secret", c.JWT.Secret)
+	if production && c.JWT.Secret == defaultJWTSecret {
+		v.addf("jwt.secret 生产环境不能使用内置默认密钥，请通过 JWT_SECRET 环境变量或配置文件设置")
+	}
+
+	// 日志
+	v.oneOf("log.level", c.Log.Level, "debug", "info", "warn", "error", "panic", "fatal")
+	v.oneOf("log.format", c.Log.Format, "json", "console")
+	v.oneOf("log.output", c.Log.Output, "stdout", "stderr", "file", "both")
+
+	// 限流
+	if c.RateLimit.Enabled {
+		if c.RateLimit.RequestsPerMinute <= 0 {
+			v.addf("rate_limit.requests_per_minute 启用限流时必须大于0")
+		}
+		if c.RateLimit.Burst <= 0 {
+			v.addf("rate_limit.burst 启用限流时必须大于0")
+		}
+	}
+
+	// 日志自动清理
+	if cleanup := c.AuditLog.Cleanup; cleanup.Enabled {
+		if cleanup.Interval <= 0 {
+			v.addf("audit_log.cleanup.interval 启用自动清理时必须大于0")
+		}
+		if cleanup.OperationRetentionDays <= 0 {
+			v.addf("audit_log.cleanup.operation_retention_days 启用自动清理时必须大于0")
+		}
+		if cleanup.LoginRetentionDays <= 0 {
+			v.addf("audit_log.cleanup.login_retention_days 启用自动清理时必须大于0")
+		}
+		if cleanup.BatchSize <= 0 {
+			v.addf("audit_log.cleanup.batch_size 启用自动清理时必须大于0")
+		}
+	}
+
+	// 数据源健康巡检
+	if check := c.DataSource.HealthCheck; check.Enabled {
+		if check.Interval <= 0 {
+			v.addf("datasource.health_check.interval 启用健康巡检时必须大于0")
+		}
+		if check.Timeout <= 0 {
+			v.addf("datasource.health_check.timeout 启用健康巡检时必须大于0")
+		}
+		if check.FailureThreshold <= 0 {
+			v.addf("datasource.health_check.failure_threshold 启用健康巡检时必须大于0")
+		}
+		if check.Concurrency <= 0 {
+			v.addf("datasource.health_check.concurrency 启用健康巡检时必须大于0")
+		}
+	}
+
+	// 数据预览
+	if preview := c.DataSource.Preview; preview.MaxRows <= 0 {
+		v.addf("datasource.preview.max_rows 必须大于0")
+	} else if preview.DefaultRows <= 0 || preview.DefaultRows > preview.MaxRows {
+		v.addf("datasource.preview.default_rows 必须在1到max_rows(%d)之间", preview.MaxRows)
+	}
+	if c.DataSource.Preview.Timeout <= 0 {
+		v.addf("datasource.preview.timeout 必须大于0")
+	}
+
+	// 列表导出
+	if c.Export.MaxRows <= 0 {
+		v.addf("export.max_rows 必须大于0")
+	}
+	if c.Export.BatchSize <= 0 {
+		v.addf("export.batch_size 必须大于0")
+	}
+
+	// ETL
+	v.nonNegative("etl.scheduler.max_catch_up_delay", int64(c.ETL.Scheduler.MaxCatchUpDelay))
+	v.nonNegative("etl.scheduler.stale_run_timeout", int64(c.ETL.Scheduler.StaleRunTimeout))
+
+	// 监控
+	v.nonNegative("monitor.runtime.max_goroutines", int64(c.Monitor.Runtime.MaxGoroutines))
+	v.nonNegative("monitor.runtime.max_heap_mb", int64(c.Monitor.Runtime.MaxHeapMB))
+	v.nonNegative("monitor.runtime.max_gc_pause", int64(c.Monitor.Runtime.MaxGCPause))
+	if c.Monitor.Enabled {
+		v.port("monitor.port", c.Monitor.Port)
+	}
+
+	// HJ212
+	if c.HJ212.Enabled {
+		tlsOnly := c.HJ212.TLS.Enabled && c.HJ212.TLS.Only
+		if !tlsOnly {
+			v.port("hj212.tcp_port", c.HJ212.TCPPort)
+		}
+		if c.HJ212.TLS.Enabled {
+			v.port("hj212.tls.port", c.HJ212.TLS.Port)
+			v.file("hj212.tls.cert_file", c.HJ212.TLS.CertFile, production)
+			v.file("hj212.tls.key_file", c.HJ212.TLS.KeyFile, production)
+			if c.HJ212.TLS.ClientCAFile != "" {
+				v.file("hj212.tls.client_ca_file", c.HJ212.TLS.ClientCAFile, production)
+			}
+			if !tlsOnly && c.HJ212.TLS.Port == c.HJ212.TCPPort {
+				v.addf("hj212.tls.port 与 hj212.tcp_port 不能相同(%d)", c.HJ212.TCPPort)
+			}
+		}
+		if c.HJ212.UDP.Enabled {
+			v.port("hj212.udp.port", c.HJ212.UDP.Port)
+		}
+	}
+	if c.HJ212.TLS.Only && !c.HJ212.TLS.Enabled {
+		v.addf("hj212.tls.only 需要同时开启 hj212.tls.enabled")
+	}
+	if c.HJ212.Archive.Enabled {
+		v.required("hj212.archive.dir", c.HJ212.Archive.Dir)
+		v.nonNegative("hj212.archive.max_size", int64(c.HJ212.Archive.MaxSize))
+		v.nonNegative("hj212.archive.max_age", int64(c.HJ212.Archive.MaxAge))
+	}
+	if c.HJ212.DeadLetter.Enabled {
+		v.nonNegative("hj212.dead_letter.retention", int64(c.HJ212.DeadLetter.Retention))
+	}
+	v.nonNegative("hj212.timeout", int64(c.HJ212.Timeout))
+	v.nonNegative("hj212.heartbeat_timeout", int64(c.HJ212.HeartbeatTimeout))
+	v.nonNegative("hj212.dedup_window", int64(c.HJ212.DedupWindow))
+	if c.HJ212.Quality.Enabled {
+		if c.HJ212.Quality.MaxChangeRatio < 0 {
+			v.addf("hj212.quality.max_change_ratio 不能为负数(%g)", c.HJ212.Quality.MaxChangeRatio)
+		}
+		v.nonNegative("hj212.quality.change_window", int64(c.HJ212.Quality.ChangeWindow))
+		v.nonNegative("hj212.quality.alarm_cooldown", int64(c.HJ212.Quality.AlarmCooldown))
+		for code, r := range c.HJ212.Quality.Ranges {
+			if r.Min != nil && r.Max != nil && *r.Min > *r.Max {
+				v.addf("hj212.quality.ranges.%s 下限(%g)不能大于上限(%g)", code, *r.Min, *r.Max)
+			}
+		}
+	}
+
+	// 登录安全
+	if c.Security.Login.Enabled {
+		if c.Security.Login.MaxFailures <= 0 {
+			v.addf("security.login.max_failures 必须大于0")
+		}
+		if c.Security.Login.LockDuration <= 0 {
+			v.addf("security.login.lock_duration 必须大于0")
+		}
+	}
+
+	// 异地登录提醒通过邮件发送
+	if c.Security.LoginAudit.RemoteLoginAlert {
+		v.required("alarm.notify.email.host", c.Alarm.Notify.Email.Host)
+		v.port("alarm.notify.email.port", c.Alarm.Notify.Email.Port)
+		v.required("alarm.notify.email.from", c.Alarm.Notify.Email.From)
+	}
+
+	// 密码散列
+	argon := c.Security.Password.Argon2
+	if argon.Iterations < 1 {
+		v.addf("security.password.argon2.iterations 必须大于0")
+	}
+	if argon.Parallelism < 1 {
+		v.addf("security.password.argon2.parallelism 必须大于0")
+	}
+	if argon.Memory < 8*1024 || argon.Memory < 8*uint32(argon.Parallelism) {
+		v.addf("security.password.argon2.memory 不能小于8192KiB且不能小于8倍并行度，当前为%d", argon.Memory)
+	}
+	if argon.SaltLength < 8 {
+		v.addf("security.password.argon2.salt_length 不能小于8字节")
+	}
+	if argon.KeyLength < 16 {
+		v.addf("security.password.argon2.key_length 不能小于16字节")
+	}
+
+	// 自助密码重置
+	if reset := c.Security.Password.Reset; reset.Enabled {
+		if reset.TokenTTL <= 0 {
+			v.addf("security.password.reset.token_ttl 启用密码重置时必须大于0")
+		}
+		v.nonNegative("security.password.reset.request_interval", int64(reset.RequestInterval))
+		if reset.ResetURL != "" {
+			if u, err := url.Parse(reset.ResetURL); err != nil || u.Scheme == "" || u.Host == "" {
+				v.addf("security.password.reset.reset_url 不是有效的URL: %q", reset.ResetURL)
+			}
+		} else {
+			v.addf("security.password.reset.reset_url 启用密码重置时不能为空")
+		}
+		v.required("alarm.notify.email.host", c.Alarm.Notify.Email.Host)
+		v.port("alarm.notify.email.port", c.Alarm.Notify.Email.Port)
+		v.required("alarm.notify.email.from", c.Alarm.Notify.Email.From)
+	}
+
+	// LDAP
+	if c.LDAP.Enabled {
+		v.required("ldap.url", c.LDAP.URL)
+		v.required("ldap.base_dn", c.LDAP.BaseDN)
+		if c.LDAP.URL != "" && !strings.HasPrefix(c.LDAP.URL, "ldap://") && !strings.HasPrefix(c.LDAP.URL, "ldaps://") {
+			v.addf("ldap.url 必须以 ldap:// 或 ldaps:// 开头")
+		}
+		if c.LDAP.StartTLS && strings.HasPrefix(c.LDAP.URL, "ldaps://") {
+			v.addf("ldap.start_tls 与 ldaps:// 不能同时使用")
+		}
+	}
+
+	// 仪表板推送
+	if push := c.Dashboard.Push; push.Enabled {
+		if push.MinInterval <= 0 {
+			v.addf("dashboard.push.min_interval 启用推送时必须大于0")
+		}
+		if push.DefaultInterval < push.MinInterval {
+			v.addf("dashboard.push.default_interval(%s) 不能小于 dashboard.push.min_interval(%s)", push.DefaultInterval, push.MinInterval)
+		}
+	}
+
+	// 告警通知
+	if c.Alarm.SuppressWindow < 0 {
+		v.addf("alarm.suppress_window 不能为负数")
+	}
+	if notify := c.Alarm.Notify; notify.Enabled {
+		v.nonNegative("alarm.notify.max_retries", int64(notify.MaxRetries))
+		if notify.Email.Enabled {
+			v.required("alarm.notify.email.host", notify.Email.Host)
+			v.port("alarm.notify.email.port", notify.Email.Port)
+			v.required("alarm.notify.email.from", notify.Email.From)
+			if len(notify.Email.To) == 0 {
+				v.addf("alarm.notify.email.to 不能为空")
+			}
+		}
+		if notify.DingTalk.Enabled {
+			v.required("alarm.notify.dingtalk.webhook", notify.DingTalk.Webhook)
+		}
+		if notify.WeCom.Enabled {
+			v.required("alarm.notify.wecom.webhook", notify.WeCom.Webhook)
+		}
+		if notify.Webhook.Enabled {
+			v.required("alarm.notify.webhook.url", notify.Webhook.URL)
+		}
+	}
+
+	// 文件上传
+	if c.Upload.MaxSize <= 0 {
+		v.addf("upload.max_size 必须大于0")
+	}
+	if chunk := c.Upload.Chunk; chunk.MinChunkSize > chunk.MaxChunkSize {
+		v.addf("upload.chunk.min_chunk_size(%d) 不能大于 upload.chunk.max_chunk_size(%d)", chunk.MinChunkSize, chunk.MaxChunkSize)
+	}
+	if c.Upload.Import.MaxRows <= 0 {
+		v.addf("upload.import.max_rows 必须大于0")
+	}
+	if c.Upload.Import.BatchSize <= 0 {
+		v.addf("upload.import.batch_size 必须大于0")
+	}
+
+	// 文件存储
+	v.oneOf("storage.type", c.Storage.Type, "local", "s3", "oss")
+	switch c.Storage.Type {
+	case "s3":
+		v.required("storage.s3.endpoint", c.Storage.S3.Endpoint)
+		v.required("storage.s3.bucket", c.Storage.S3.Bucket)
+	case "oss":
+		v.required("storage.oss.endpoint", c.Storage.OSS.Endpoint)
+		v.required("storage.oss.bucket", c.Storage.OSS.Bucket)
+	}
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDefaultConfigFile(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	_, err := Load(filepath.Join("..", "..", "config", "config.yaml"))
+	assert.NoError(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	setDefaults()
+	cfg, err := unmarshal()
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+
+	cfg.App.Environment = "production"
+	cfg.Server.Port = 0
+	cfg.Server.TLS.Enabled = true
+	cfg.Server.TLS.CertFile = "/nonexistent/server.crt"
+	cfg.Database.Loc = "Mars%2FOlympus"
+	cfg.Log.Level = "verbose"
+	cfg.HJ212.TLS.Only = true
+
+	err = cfg.Validate()
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+
+	joined := verr.Error()
+	for _, key := range []string{
+		"server.port",
+		"server.tls.cert_file",
+		"server.tls.key_file",
+		"database.loc",
+		"jwt.secret",
+		"log.level",
+		"hj212.tls.only",
+	} {
+		assert.Contains(t, joined, key)
+	}
+	assert.Len(t, verr.Problems, 7)
+}
+
+func TestValidateAuditLogCleanup(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	setDefaults()
+
+	tests := []struct {
+		name   string
+		modify func(c *AuditLogCleanupConfig)
+		key    string
+	}{
+		{name: "默认配置", modify: func(c *AuditLogCleanupConfig) {}},
+		{name: "清理周期为零", modify: func(c *AuditLogCleanupConfig) { c.Interval = 0 }, key: "audit_log.cleanup.interval"},
+		{name: "操作日志保留天数为零", modify: func(c *AuditLogCleanupConfig) { c.OperationRetentionDays = 0 }, key: "audit_log.cleanup.operation_retention_days"},
+		{name: "登录日志保留天数为负", modify: func(c *AuditLogCleanupConfig) { c.LoginRetentionDays = -1 }, key: "audit_log.cleanup.login_retention_days"},
+		{name: "批大小为零", modify: func(c *AuditLogCleanupConfig) { c.BatchSize = 0 }, key: "audit_log.cleanup.batch_size"},
+		// 未启用自动清理时不校验其余字段
+		{name: "未启用", modify: func(c *AuditLogCleanupConfig) { *c = AuditLogCleanupConfig{} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := unmarshal()
+			require.NoError(t, err)
+			tt.modify(&cfg.AuditLog.Cleanup)
+
+			err = cfg.Validate()
+			if tt.key == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var verr *ValidationError
+			require.ErrorAs(t, err, &verr)
+			require.Len(t, verr.Problems, 1)
+			assert.Contains(t, verr.Problems[0], tt.key)
+		})
+	}
+}
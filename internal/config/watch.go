@@ -0,0 +1,97 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// hotReloadKeys 修改后可在运行时生效的配置项，其余配置项修改后需重启服务
+var hotReloadKeys = map[string]bool{
+	"log.level":  true,
+	"rate_limit": true,
+	"alarm":      true,
+}
+
+// ReloadFunc 配置文件变更后的回调，restartRequired为已修改但需重启服务才能生效的配置项
+type ReloadFunc func(cfg *Config, restartRequired []string, err error)
+
+var reloadMutex sync.Mutex
+
+// Watch 监听配置文件变更并热加载可运行时生效的配置项
+func Watch(onReload ReloadFunc) {
+	viper.OnConfigChange(func(fsnotify.Event) {
+		cfg, restartRequired, err := Reload()
+		onReload(cfg, restartRequired, err)
+	})
+	viper.WatchConfig()
+}
+
+// Reload 重新解析并校验配置，仅将可热加载的配置项合并进当前配置，返回合并后的配置和需重启才能生效的配置项，
+// 校验失败时保持当前配置不变
+func Reload() (*Config, []string, error) {
+	reloadMutex.Lock()
+	defer reloadMutex.Unlock()
+
+	next, err := unmarshal()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := next.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	current := GlobalConfig
+	if current == nil {
+		GlobalConfig = next
+		return next, nil, nil
+	}
+
+	restartRequired := changedKeys("", reflect.ValueOf(*current), reflect.ValueOf(*next))
+
+	merged := *current
+	merged.Log.Level = next.Log.Level
+	merged.RateLimit = next.RateLimit
+	merged.Alarm = next.Alarm
+
+	GlobalConfig = &merged
+	return &merged, restartRequired, nil
+}
+
+// changedKeys 逐级比较两份配置，返回取值不同且不支持热加载的配置项
+func changedKeys(prefix string, a, b reflect.Value) []string {
+	var keys []string
+	t := a.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("mapstructure")
+
+		key := prefix
+		if !strings.Contains(tag, "squash") {
+			name := strings.Split(tag, ",")[0]
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+			if key != "" {
+				key += "."
+			}
+			key += name
+		}
+		if hotReloadKeys[key] {
+			continue
+		}
+
+		fa, fb := a.Field(i), b.Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			keys = append(keys, changedKeys(key, fa, fb)...)
+			continue
+		}
+		if !reflect.DeepEqual(fa.Interface(), fb.Interface()) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
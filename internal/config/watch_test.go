@@ -0,0 +1,60 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReload(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+database:
+  host: "db1"
+log:
+  level: "info"
+  format: "json"
+`), 0644))
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, 6000, cfg.RateLimit.RequestsPerMinute)
+
+	require.NoError(t, os.WriteFile(path, []byte(`
+database:
+  host: "db2"
+log:
+  level: "debug"
+  format: "console"
+rate_limit:
+  requests_per_minute: 600
+alarm:
+  suppress_window: "5m"
+  notify:
+    email:
+      enabled: true
+`), 0644))
+	require.NoError(t, viper.ReadInConfig())
+
+	reloaded, restartRequired, err := Reload()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"database.host", "log.format"}, restartRequired)
+
+	assert.Equal(t, "debug", reloaded.Log.Level)
+	assert.Equal(t, 600, reloaded.RateLimit.RequestsPerMinute)
+	assert.Equal(t, 5*time.Minute, reloaded.Alarm.SuppressWindow)
+	assert.True(t, reloaded.Alarm.Notify.Email.Enabled)
+
+	// 需重启的配置项保持原值
+	assert.Equal(t, "db1", reloaded.Database.Host)
+	assert.Equal(t, "json", reloaded.Log.Format)
+	assert.Same(t, reloaded, GlobalConfig)
+}
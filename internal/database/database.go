@@ -9,7 +9,9 @@ import (
 	"github.com/env-data-platform/internal/models"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
 )
 
 // DB 全局数据库连接
@@ -60,6 +62,11 @@ func Initialize(cfg *config.Config) error {
 		return fmt.Errorf("failed to ping database: %w", err)
 	}
 
+	// 暴露连接池指标
+	if err := registerPoolMetrics(sqlDB, cfg.Database.Name); err != nil {
+		log.Printf("Failed to register database pool metrics: %v", err)
+	}
+
 	log.Println("Database connected successfully")
 	return nil
 }
@@ -78,21 +85,35 @@ func AutoMigrate() error {
 		&models.Permission{},
 		&models.LoginLog{},
 		&models.OperationLog{},
+		&models.PasswordHistory{},
+		&models.PasswordResetToken{},
+		&models.DataScope{},
 
 		// 数据源相关
 		&models.DataSource{},
 		&models.DataTable{},
 		&models.DataColumn{},
+		&models.DataIndex{},
+		&models.MetadataSnapshot{},
 		&models.HJ212Data{},
 		&models.HJ212AlarmData{},
+		&models.DeviceStatusEvent{},
+		&models.HJ212DeadLetter{},
+		&models.AlarmRule{},
+		&models.MonitorFactor{},
+		&models.AlarmNotification{},
+		&models.AlarmAction{},
 		&models.FileUploadRecord{},
+		&models.FileRecord{},
+		&models.FileUploadSession{},
 
 		// ETL相关（基础表）
 		&models.ETLJob{},
-		// &models.ETLExecution{}, // 暂时移除
+		&models.ETLExecution{},
 		&models.ETLTemplate{},
 		&models.QualityRule{},
 		&models.QualityReport{},
+		&models.QualityCheckBatch{},
 	}
 
 	// 第一阶段迁移
@@ -109,7 +130,7 @@ func AutoMigrate() error {
 	relationModels := []interface{}{
 		&models.UserRole{},
 		&models.RolePermission{},
-		// &models.ETLExecutionStep{}, // 暂时移除
+		&models.ETLExecutionStep{},
 	}
 
 	// 第二阶段迁移
@@ -122,10 +143,70 @@ func AutoMigrate() error {
 		log.Printf("Successfully migrated: %T", model)
 	}
 
+	// 第三阶段：日志类大表的游标分页索引
+	if err := ensureCursorIndexes(&models.LoginLog{}, &models.OperationLog{}); err != nil {
+		return err
+	}
+
+	// 第四阶段：早期文件导入的数据源在配置中保存了平台数据库账号，改为标记导入表并清除账号
+	if err := scrubImportedDataSources(); err != nil {
+		return err
+	}
+
 	log.Println("Database migration completed successfully")
 	return nil
 }
 
+// scrubImportedDataSources 为旧的文件导入数据源登记导入表，并从配置中删除平台数据库的连接参数
+func scrubImportedDataSources() error {
+	var dataSources []models.DataSource
+	if err := DB.Where("tags = ? AND (import_table IS NULL OR import_table = '')", "imported").
+		Find(&dataSources).Error; err != nil {
+		return fmt.Errorf("failed to load imported data sources: %w", err)
+	}
+
+	for i := range dataSources {
+		ds := &dataSources[i]
+		cfg, err := ds.GetConfig()
+		if err != nil {
+			log.Printf("Skip imported data source %d with invalid config: %v", ds.ID, err)
+			continue
+		}
+		table, _ := cfg.CustomConfig["table"].(string)
+		if table == "" {
+			continue
+		}
+
+		cfg.Host, cfg.Port, cfg.Database, cfg.Username, cfg.Password = "", 0, "", "", ""
+		if err := ds.SetConfig(*cfg); err != nil {
+			return fmt.Errorf("failed to encode config of data source %d: %w", ds.ID, err)
+		}
+		if err := DB.Model(ds).Updates(map[string]interface{}{
+			"config":       ds.Config,
+			"import_table": table,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to scrub imported data source %d: %w", ds.ID, err)
+		}
+		log.Printf("Scrubbed credentials of imported data source %d", ds.ID)
+	}
+	return nil
+}
+
+// ensureCursorIndexes 为游标分页创建 (created_at, id) 联合索引
+func ensureCursorIndexes(tables ...schema.Tabler) error {
+	for _, table := range tables {
+		name := "idx_" + table.TableName() + "_created_at_id"
+		if DB.Migrator().HasIndex(table, name) {
+			continue
+		}
+		if err := DB.Exec("CREATE INDEX ? ON ? (created_at, id)", clause.Column{Name: name}, clause.Table{Name: table.TableName()}).Error; err != nil {
+			return fmt.Errorf("failed to create cursor index on %s: %w", table.TableName(), err)
+		}
+		log.Printf("Created cursor index: %s", name)
+	}
+	return nil
+}
+
 // InitializeData 初始化基础数据
 func InitializeData() error {
 	if DB == nil {
@@ -147,6 +228,11 @@ func InitializeData() error {
 		return fmt.Errorf("failed to create default admin: %w", err)
 	}
 
+	// 创建默认告警规则
+	if err := createDefaultAlarmRules(); err != nil {
+		return fmt.Errorf("failed to create default alarm rules: %w", err)
+	}
+
 	log.Println("Default data initialized successfully")
 	return nil
 }
@@ -195,6 +281,8 @@ func createDefaultPermissions() error {
 		{models.Permission{Name: "数据源创建", Code: "datasource:create", Type: "button", Sort: 2, IsSystem: true}, "datasource"},
 		{models.Permission{Name: "数据源编辑", Code: "datasource:edit", Type: "button", Sort: 3, IsSystem: true}, "datasource"},
 		{models.Permission{Name: "数据源删除", Code: "datasource:delete", Type: "button", Sort: 4, IsSystem: true}, "datasource"},
+		{models.Permission{Name: "数据源导入", Code: "datasource:import", Type: "button", Sort: 5, IsSystem: true}, "datasource"},
+		{models.Permission{Name: "数据预览", Code: "datasource:preview", Type: "button", Sort: 6, IsSystem: true}, "datasource"},
 
 		// ETL管理子项
 		{models.Permission{Name: "ETL作业", Code: "etl:job", Type: "menu", Path: "/etl/job", Icon: "job", Sort: 1, IsSystem: true}, "etl"},
@@ -208,6 +296,7 @@ func createDefaultPermissions() error {
 		// 监控管理子项
 		{models.Permission{Name: "系统监控", Code: "monitor:system", Type: "menu", Path: "/monitor/system", Icon: "system", Sort: 1, IsSystem: true}, "monitor"},
 		{models.Permission{Name: "数据监控", Code: "monitor:data", Type: "menu", Path: "/monitor/data", Icon: "data", Sort: 2, IsSystem: true}, "monitor"},
+		{models.Permission{Name: "HJ212设备控制", Code: "hj212:control", Type: "button", Sort: 3, IsSystem: true}, "monitor"},
 	}
 
 	// 创建子权限
@@ -298,6 +387,30 @@ func createDefaultAdmin() error {
 	return DB.Create(&userRole).Error
 }
 
+// createDefaultAlarmRules 创建默认告警规则，仅在规则表为空时写入
+func createDefaultAlarmRules() error {
+	var count int64
+	DB.Model(&models.AlarmRule{}).Count(&count)
+	if count > 0 {
+		return nil
+	}
+
+	limit := func(v float64) *float64 { return &v }
+	rules := []models.AlarmRule{
+		{Name: "SO2浓度过高", Description: "二氧化硫浓度超过国家标准", FactorCode: "a21001", UpperLimit: limit(0.5), Level: models.AlarmLevelWarning, CooldownMin: 30},
+		{Name: "PM2.5严重污染", Description: "PM2.5浓度达到严重污染级别", FactorCode: "a34004", UpperLimit: limit(150), Level: models.AlarmLevelCritical, CooldownMin: 15},
+		{Name: "NO浓度异常", Description: "一氧化氮浓度超标", FactorCode: "a21002", UpperLimit: limit(0.2), Level: models.AlarmLevelWarning, CooldownMin: 60},
+		{Name: "pH值异常", Description: "水体pH值超出6~9安全范围", FactorCode: "w01001", LowerLimit: limit(6), UpperLimit: limit(9), Level: models.AlarmLevelWarning, CooldownMin: 30},
+		{Name: "COD严重超标", Description: "化学需氧量严重超标", FactorCode: "w01009", UpperLimit: limit(50), Level: models.AlarmLevelCritical, CooldownMin: 15},
+		{Name: "溶解氧过低", Description: "水体溶解氧浓度过低", FactorCode: "w01003", LowerLimit: limit(3), Level: models.AlarmLevelWarning, CooldownMin: 30},
+	}
+	for i := range rules {
+		rules[i].Enabled = true
+	}
+
+	return DB.Create(&rules).Error
+}
+
 // Close 关闭数据库连接
 func Close() error {
 	if DB == nil {
@@ -0,0 +1,148 @@
+// Package dbtest 提供测试用的DryRun数据库：不连接真实数据库，记录GORM生成的SQL和参数，
+// 测试通过断言语句验证查询条件，并按需为查询填充结果或设置影响行数。
+//
+// 测试不应在回调中模拟WHERE、ORDER、LIMIT等语义，而应断言生成的SQL和参数。
+package dbtest
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// 语句类型，对应GORM的回调处理器
+const (
+	KindQuery  = "query"
+	KindCreate = "create"
+	KindUpdate = "update"
+	KindDelete = "delete"
+	KindRow    = "row"
+)
+
+// Statement 一条生成的SQL语句
+type Statement struct {
+	Kind  string
+	Table string
+	SQL   string
+	Vars  []interface{}
+}
+
+// Handler 语句生成后调用，可以填充 tx.Statement.Dest、设置 tx.RowsAffected 或 tx.AddError
+// Count查询的结果只有在RowsAffected为1时才会保留，因此填充计数时需同时设置 tx.RowsAffected = 1
+type Handler func(tx *gorm.DB)
+
+// DB DryRun数据库，记录全部语句，可在并发测试中使用
+type DB struct {
+	*gorm.DB
+
+	mu         sync.Mutex
+	statements []Statement
+	handlers   map[string][]Handler
+}
+
+// New 创建DryRun数据库，使用MySQL方言生成SQL
+func New(t testing.TB) *DB {
+	db, err := gorm.Open(mysql.New(mysql.Config{SkipInitializeWithVersion: true}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Discard,
+	})
+	require.NoError(t, err)
+
+	d := &DB{DB: db, handlers: make(map[string][]Handler)}
+	callbacks := db.Callback()
+	require.NoError(t, callbacks.Query().After("gorm:query").Register("dbtest:query", d.callback(KindQuery)))
+	require.NoError(t, callbacks.Create().After("gorm:create").Register("dbtest:create", d.callback(KindCreate)))
+	require.NoError(t, callbacks.Update().After("gorm:update").Register("dbtest:update", d.callback(KindUpdate)))
+	require.NoError(t, callbacks.Delete().After("gorm:delete").Register("dbtest:delete", d.callback(KindDelete)))
+	require.NoError(t, callbacks.Row().After("gorm:row").Register("dbtest:row", d.callback(KindRow)))
+	return d
+}
+
+// callback 记录语句后依次调用该类型的处理函数
+func (d *DB) callback(kind string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		d.mu.Lock()
+		d.statements = append(d.statements, Statement{
+			Kind:  kind,
+			Table: tx.Statement.Table,
+			SQL:   tx.Statement.SQL.String(),
+			Vars:  append([]interface{}(nil), tx.Statement.Vars...),
+		})
+		handlers := d.handlers[kind]
+		d.mu.Unlock()
+
+		for _, handler := range handlers {
+			handler(tx)
+		}
+	}
+}
+
+// On 为指定类型的语句注册处理函数
+func (d *DB) On(kind string, handler Handler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handlers[kind] = append(d.handlers[kind], handler)
+}
+
+// Replace 测试期间用该数据库替换全局连接（如 database.DB），测试结束后恢复
+func (d *DB) Replace(t testing.TB, global **gorm.DB) {
+	previous := *global
+	*global = d.DB
+	t.Cleanup(func() { *global = previous })
+}
+
+// Statements 已记录语句的副本，kinds非空时只返回这些类型的语句
+func (d *DB) Statements(kinds ...string) []Statement {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	statements := make([]Statement, 0, len(d.statements))
+	for _, statement := range d.statements {
+		if len(kinds) == 0 || containsKind(kinds, statement.Kind) {
+			statements = append(statements, statement)
+		}
+	}
+	return statements
+}
+
+// SQL 已记录语句的SQL文本
+func (d *DB) SQL(kinds ...string) []string {
+	statements := d.Statements(kinds...)
+	sqls := make([]string, len(statements))
+	for i, statement := range statements {
+		sqls[i] = statement.SQL
+	}
+	return sqls
+}
+
+// Find 返回第一条包含fragment的语句
+func (d *DB) Find(fragment string) (Statement, bool) {
+	for _, statement := range d.Statements() {
+		if strings.Contains(statement.SQL, fragment) {
+			return statement, true
+		}
+	}
+	return Statement{}, false
+}
+
+// Reset 清空已记录的语句
+func (d *DB) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.statements = nil
+}
+
+func containsKind(kinds []string, kind string) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
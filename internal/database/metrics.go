@@ -0,0 +1,19 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// registerPoolMetrics 将连接池统计（打开连接、使用中、空闲、等待次数和等待时长）注册为Prometheus指标
+func registerPoolMetrics(sqlDB *sql.DB, dbName string) error {
+	err := prometheus.Register(collectors.NewDBStatsCollector(sqlDB, dbName))
+	var already prometheus.AlreadyRegisteredError
+	if errors.As(err, &already) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,160 @@
+package database
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// likeEscaper 转义LIKE中的通配符，使关键字按字面匹配
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// ListQuery 列表的关键字搜索和多字段排序
+//
+// SearchColumns 为参与关键字模糊匹配的列；SortColumns 为允许排序的字段，
+// 键为请求参数中的字段名，值为数据库列名，未在其中的字段拒绝排序以防止注入；
+// DefaultSort 为未指定排序时使用的排序，格式同 sort 参数。
+type ListQuery struct {
+	SearchColumns []string
+	SortColumns   map[string]string
+	DefaultSort   string
+}
+
+// Search 按关键字模糊匹配，关键字按空白拆分为多个词，每个词需命中任意一列
+func (q ListQuery) Search(keyword string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(q.SearchColumns) == 0 {
+			return db
+		}
+		for _, term := range strings.Fields(keyword) {
+			pattern := "%" + likeEscaper.Replace(term) + "%"
+			conditions := make([]string, len(q.SearchColumns))
+			args := make([]interface{}, len(q.SearchColumns))
+			for i, column := range q.SearchColumns {
+				conditions[i] = column + " LIKE ?"
+				args[i] = pattern
+			}
+			db = db.Where(strings.Join(conditions, " OR "), args...)
+		}
+		return db
+	}
+}
+
+// Sort 解析排序参数，返回排序scope
+//
+// sort 为逗号分隔的多个字段，字段后可用 ":asc" 或 ":desc" 指定方向，如
+// "last_active_at:desc,created_at"；未指定方向的字段使用 order（asc/desc，默认asc）。
+// sort 为空时使用 DefaultSort。
+func (q ListQuery) Sort(sort, order string) (func(*gorm.DB) *gorm.DB, error) {
+	order = strings.ToLower(strings.TrimSpace(order))
+	if order != "" && order != "asc" && order != "desc" {
+		return nil, fmt.Errorf("排序方向只能是asc或desc: %s", order)
+	}
+	if strings.TrimSpace(sort) == "" {
+		sort, order = q.DefaultSort, ""
+	}
+
+	var columns []clause.OrderByColumn
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(sort, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		field, direction := item, order
+		if i := strings.Index(item, ":"); i >= 0 {
+			field, direction = strings.TrimSpace(item[:i]), strings.ToLower(strings.TrimSpace(item[i+1:]))
+			if direction != "asc" && direction != "desc" {
+				return nil, fmt.Errorf("排序方向只能是asc或desc: %s", item)
+			}
+		}
+		column, ok := q.SortColumns[field]
+		if !ok {
+			return nil, fmt.Errorf("不支持按该字段排序: %s", field)
+		}
+		if seen[field] {
+			continue
+		}
+		seen[field] = true
+		columns = append(columns, clause.OrderByColumn{
+			Column: clause.Column{Name: column},
+			Desc:   direction == "desc",
+		})
+	}
+
+	return func(db *gorm.DB) *gorm.DB {
+		for _, column := range columns {
+			db = db.Order(column)
+		}
+		return db
+	}, nil
+}
+
+// ErrInvalidCursor 游标格式错误
+var ErrInvalidCursor = errors.New("无效的分页游标")
+
+// Cursor 基于 created_at+id 的分页游标，指向上一页的最后一条记录
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uint      `json:"id"`
+}
+
+// Encode 编码为可放在URL中的字符串
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor 解析游标，空字符串表示第一页，返回nil
+func DecodeCursor(s string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// CursorPage 按 created_at、id 倒序取游标之后的一页
+//
+// 多取一条用于判断是否还有下一页，查询结果需交给 TrimCursorPage 截断。
+// 列名带当前表名，联表查询时不会产生歧义。
+func CursorPage(cursor *Cursor, limit int) func(*gorm.DB) *gorm.DB {
+	createdAt := clause.Column{Table: clause.CurrentTable, Name: "created_at"}
+	id := clause.Column{Table: clause.CurrentTable, Name: "id"}
+	return func(db *gorm.DB) *gorm.DB {
+		if cursor != nil {
+			db = db.Where(clause.Or(
+				clause.Lt{Column: createdAt, Value: cursor.CreatedAt},
+				clause.And(
+					clause.Eq{Column: createdAt, Value: cursor.CreatedAt},
+					clause.Lt{Column: id, Value: cursor.ID},
+				),
+			))
+		}
+		return db.Order(clause.OrderByColumn{Column: createdAt, Desc: true}).
+			Order(clause.OrderByColumn{Column: id, Desc: true}).
+			Limit(limit + 1)
+	}
+}
+
+// TrimCursorPage 截断 CursorPage 多取的一条，返回本页数据和下一页游标，没有下一页时游标为空
+func TrimCursorPage[T any](items []T, limit int, key func(T) Cursor) ([]T, string) {
+	if len(items) <= limit {
+		return items, ""
+	}
+	items = items[:limit]
+	return items, key(items[limit-1]).Encode()
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/database/dbtest"
+)
+
+type listQueryItem struct {
+	ID   uint
+	Name string
+}
+
+func dryRunDB(t *testing.T) *gorm.DB {
+	return dbtest.New(t).DB
+}
+
+func TestListQuery(t *testing.T) {
+	q := ListQuery{
+		SearchColumns: []string{"name", "description"},
+		SortColumns:   map[string]string{"created_at": "created_at", "active": "last_active_at"},
+		DefaultSort:   "created_at:desc",
+	}
+
+	sortScope, err := q.Sort("active:desc, created_at", "asc")
+	require.NoError(t, err)
+	stmt := dryRunDB(t).Scopes(q.Search("站点 50%_"), sortScope).Find(&[]listQueryItem{}).Statement
+	assert.Equal(t, "SELECT * FROM `list_query_items` WHERE (name LIKE ? OR description LIKE ?) AND (name LIKE ? OR description LIKE ?) ORDER BY `last_active_at` DESC,`created_at`", stmt.SQL.String())
+	assert.Equal(t, []interface{}{"%站点%", "%站点%", `%50\%\_%`, `%50\%\_%`}, stmt.Vars)
+
+	// 未指定排序时使用默认排序，空关键字不加条件
+	sortScope, err = q.Sort("", "asc")
+	require.NoError(t, err)
+	stmt = dryRunDB(t).Scopes(q.Search(" "), sortScope).Find(&[]listQueryItem{}).Statement
+	assert.Equal(t, "SELECT * FROM `list_query_items` ORDER BY `created_at` DESC", stmt.SQL.String())
+
+	_, err = q.Sort("password", "")
+	assert.Error(t, err)
+	_, err = q.Sort("created_at:up", "")
+	assert.Error(t, err)
+	_, err = q.Sort("created_at", "random")
+	assert.Error(t, err)
+}
+
+func TestCursorPage(t *testing.T) {
+	cursor, err := DecodeCursor("")
+	require.NoError(t, err)
+	assert.Nil(t, cursor)
+	_, err = DecodeCursor("not-a-cursor")
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	createdAt := time.Date(2024, 5, 1, 10, 0, 0, 123000000, time.UTC)
+	cursor, err = DecodeCursor(Cursor{CreatedAt: createdAt, ID: 42}.Encode())
+	require.NoError(t, err)
+	assert.True(t, createdAt.Equal(cursor.CreatedAt))
+	assert.Equal(t, uint(42), cursor.ID)
+
+	stmt := dryRunDB(t).Scopes(CursorPage(cursor, 20)).Find(&[]listQueryItem{}).Statement
+	assert.Equal(t, "SELECT * FROM `list_query_items` WHERE (`list_query_items`.`created_at` < ? OR (`list_query_items`.`created_at` = ? AND `list_query_items`.`id` < ?)) ORDER BY `list_query_items`.`created_at` DESC,`list_query_items`.`id` DESC LIMIT 21", stmt.SQL.String())
+
+	items := []listQueryItem{{ID: 3}, {ID: 2}, {ID: 1}}
+	key := func(item listQueryItem) Cursor { return Cursor{CreatedAt: createdAt, ID: item.ID} }
+	page, next := TrimCursorPage(items, 2, key)
+	assert.Len(t, page, 2)
+	cursor, err = DecodeCursor(next)
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), cursor.ID)
+
+	page, next = TrimCursorPage(items, 3, key)
+	assert.Len(t, page, 3)
+	assert.Empty(t, next)
+}
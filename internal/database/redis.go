@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/env-data-platform/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis 全局Redis客户端，未配置或连接失败时为nil
+var Redis *redis.Client
+
+// InitializeRedis 初始化Redis连接
+func InitializeRedis(cfg *config.Config) error {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.GetRedisAddr(),
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.Database,
+		PoolSize: cfg.Redis.PoolSize,
+	})
+
+	// 测试连接
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return fmt.Errorf("failed to ping redis: %w", err)
+	}
+
+	Redis = client
+	log.Println("Redis connected successfully")
+	return nil
+}
+
+// GetRedis 获取Redis客户端
+func GetRedis() *redis.Client {
+	return Redis
+}
+
+// CloseRedis 关闭Redis连接
+func CloseRedis() error {
+	if Redis == nil {
+		return nil
+	}
+	return Redis.Close()
+}
@@ -0,0 +1,122 @@
+package factor
+
+import (
+	"errors"
+	"sync"
+
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/models"
+)
+
+// Factor 监测因子
+type Factor struct {
+	Code       string   `json:"code"`
+	Name       string   `json:"name"`
+	Unit       string   `json:"unit"`
+	UpperLimit *float64 `json:"upper_limit"`
+	LowerLimit *float64 `json:"lower_limit"`
+}
+
+// HasLimits 是否配置了默认上下限
+func (f Factor) HasLimits() bool {
+	return f.UpperLimit != nil || f.LowerLimit != nil
+}
+
+// builtinFactors 内置的常用因子，数据库中同编码的配置会覆盖内置项
+var builtinFactors = []Factor{
+	{Code: "a01001", Name: "温度", Unit: "℃"},
+	{Code: "a01002", Name: "湿度", Unit: "%"},
+	{Code: "a01006", Name: "气压", Unit: "kPa"},
+	{Code: "a01007", Name: "风速", Unit: "m/s"},
+	{Code: "a01008", Name: "风向", Unit: "°"},
+	{Code: "a21001", Name: "SO2", Unit: "μg/m³"},
+	{Code: "a21002", Name: "NO", Unit: "μg/m³"},
+	{Code: "a21003", Name: "NO2", Unit: "μg/m³"},
+	{Code: "a21004", Name: "NOx", Unit: "μg/m³"},
+	{Code: "a21005", Name: "CO", Unit: "mg/m³"},
+	{Code: "a21026", Name: "O3", Unit: "μg/m³"},
+	{Code: "a34001", Name: "TSP", Unit: "μg/m³"},
+	{Code: "a34002", Name: "PM10", Unit: "μg/m³"},
+	{Code: "a34004", Name: "PM2.5", Unit: "μg/m³"},
+	{Code: "w01001", Name: "pH值"},
+	{Code: "w01003", Name: "溶解氧", Unit: "mg/L"},
+	{Code: "w01009", Name: "化学需氧量", Unit: "mg/L"},
+	{Code: "w01010", Name: "五日生化需氧量", Unit: "mg/L"},
+	{Code: "w01018", Name: "总磷", Unit: "mg/L"},
+	{Code: "w01019", Name: "总氮", Unit: "mg/L"},
+	{Code: "w21001", Name: "氨氮", Unit: "mg/L"},
+	{Code: "w21003", Name: "总氮", Unit: "mg/L"},
+	{Code: "w21011", Name: "总磷", Unit: "mg/L"},
+}
+
+// Dictionary 监测因子字典，内置因子叠加数据库配置，支持运行时刷新
+type Dictionary struct {
+	mu      sync.RWMutex
+	factors map[string]Factor
+}
+
+// NewDictionary 创建仅包含内置因子的字典
+func NewDictionary() *Dictionary {
+	d := &Dictionary{}
+	d.set(nil)
+	return d
+}
+
+var defaultDictionary = NewDictionary()
+
+// Default 全局因子字典，解析和告警共用
+func Default() *Dictionary {
+	return defaultDictionary
+}
+
+// Load 从数据库重新加载因子字典，因子增删改后调用
+func (d *Dictionary) Load(db *gorm.DB) error {
+	if db == nil {
+		return errors.New("database not initialized")
+	}
+
+	var rows []models.MonitorFactor
+	if err := db.Find(&rows).Error; err != nil {
+		return err
+	}
+	d.set(rows)
+	return nil
+}
+
+// set 用内置因子和数据库配置重建查询表
+func (d *Dictionary) set(rows []models.MonitorFactor) {
+	factors := make(map[string]Factor, len(builtinFactors)+len(rows))
+	for _, f := range builtinFactors {
+		factors[f.Code] = f
+	}
+	for _, row := range rows {
+		factors[row.Code] = Factor{
+			Code:       row.Code,
+			Name:       row.Name,
+			Unit:       row.Unit,
+			UpperLimit: row.UpperLimit,
+			LowerLimit: row.LowerLimit,
+		}
+	}
+
+	d.mu.Lock()
+	d.factors = factors
+	d.mu.Unlock()
+}
+
+// Lookup 按编码查询因子
+func (d *Dictionary) Lookup(code string) (Factor, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	f, ok := d.factors[code]
+	return f, ok
+}
+
+// Name 因子名称，未登记的因子返回编码本身
+func (d *Dictionary) Name(code string) string {
+	if f, ok := d.Lookup(code); ok && f.Name != "" {
+		return f.Name
+	}
+	return code
+}
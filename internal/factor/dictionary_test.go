@@ -0,0 +1,32 @@
+package factor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/env-data-platform/internal/models"
+)
+
+func TestDictionary(t *testing.T) {
+	d := NewDictionary()
+	assert.Equal(t, "PM2.5", d.Name("a34004"))
+	assert.Equal(t, "x99999", d.Name("x99999"))
+
+	upper := 75.0
+	d.set([]models.MonitorFactor{
+		{Code: "a34004", Name: "细颗粒物", Unit: "μg/m³", UpperLimit: &upper},
+		{Code: "x99999", Name: "自定义因子", Unit: "mg/L"},
+	})
+
+	f, ok := d.Lookup("a34004")
+	assert.True(t, ok)
+	assert.Equal(t, "细颗粒物", f.Name)
+	assert.True(t, f.HasLimits())
+	assert.Equal(t, "自定义因子", d.Name("x99999"))
+
+	// 内置因子保留
+	f, ok = d.Lookup("a21026")
+	assert.True(t, ok)
+	assert.False(t, f.HasLimits())
+}
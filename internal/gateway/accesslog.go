@@ -0,0 +1,166 @@
+package gateway
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	"github.com/env-data-platform/internal/gateway/auth"
+)
+
+// TraceIDHeader 访问日志使用的链路ID请求头，缺失时由网关生成并透传给上游
+const TraceIDHeader = "X-Request-ID"
+
+// AccessLogger 网关访问日志，独立于运行日志写入单独的文件
+type AccessLogger struct {
+	logger *zap.Logger
+	closer io.Closer
+}
+
+// NewAccessLogger 创建访问日志，文件按大小轮转，开启Daily时每天额外轮转一次
+func NewAccessLogger(config *AccessLogConfig) (*AccessLogger, error) {
+	var (
+		writer zapcore.WriteSyncer
+		closer io.Closer
+	)
+	switch config.File {
+	case "stdout":
+		writer = zapcore.AddSync(os.Stdout)
+	case "stderr":
+		writer = zapcore.AddSync(os.Stderr)
+	default:
+		if err := os.MkdirAll(filepath.Dir(config.File), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create access log directory: %w", err)
+		}
+		rotator := &lumberjack.Logger{
+			Filename:   config.File,
+			MaxSize:    config.MaxSize,
+			MaxBackups: config.MaxBackups,
+			MaxAge:     config.MaxAge,
+			Compress:   config.Compress,
+			LocalTime:  true,
+		}
+		closer = rotator
+		if config.Daily {
+			writer = zapcore.AddSync(newDailyRotateWriter(rotator, time.Now))
+		} else {
+			writer = zapcore.AddSync(rotator)
+		}
+	}
+
+	encoderConfig := zapcore.EncoderConfig{
+		TimeKey:        "time",
+		MessageKey:     "msg",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeDuration: zapcore.MillisDurationEncoder,
+	}
+	var encoder zapcore.Encoder
+	if config.Format == "text" {
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	} else {
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	}
+
+	return &AccessLogger{
+		logger: zap.New(zapcore.NewCore(encoder, writer, zapcore.InfoLevel)),
+		closer: closer,
+	}, nil
+}
+
+// Middleware 访问日志中间件，需注册在认证和代理之前以记录所有请求
+func (l *AccessLogger) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		method := c.Request.Method
+		path := c.Request.URL.Path
+		query := c.Request.URL.RawQuery
+
+		traceID := c.GetHeader(TraceIDHeader)
+		if traceID == "" {
+			traceID = uuid.New().String()
+			c.Request.Header.Set(TraceIDHeader, traceID)
+		}
+		c.Header(TraceIDHeader, traceID)
+
+		c.Next()
+
+		fields := []zap.Field{
+			zap.String("trace_id", traceID),
+			zap.String("method", method),
+			zap.String("path", path),
+			zap.String("query", query),
+			zap.Int("status", c.Writer.Status()),
+			zap.Duration("latency", time.Since(start)),
+			zap.Int("bytes", c.Writer.Size()),
+			zap.String("client_ip", c.ClientIP()),
+			zap.String("user_agent", c.Request.UserAgent()),
+			zap.String("route_id", c.GetString(ContextKeyRouteID)),
+			zap.String("upstream", c.GetString(ContextKeyUpstream)),
+			zap.Int("attempts", c.GetInt(ContextKeyAttempts)),
+			zap.String("fallback", c.GetString(ContextKeyFallback)),
+		}
+		if identity, exists := auth.GetIdentity(c); exists {
+			fields = append(fields, zap.String("user_id", identity.UserID), zap.String("user", identity.Username))
+		} else if user, exists := auth.GetCurrentUser(c); exists {
+			fields = append(fields, zap.String("user_id", user.ID), zap.String("user", user.Username))
+		} else {
+			fields = append(fields, zap.String("user_id", ""), zap.String("user", ""))
+		}
+		fields = append(fields, zap.String("auth_strategy", c.GetString(auth.ContextKeyStrategy)))
+		if len(c.Errors) > 0 {
+			fields = append(fields, zap.String("error", c.Errors.String()))
+		}
+
+		l.logger.Info("access", fields...)
+	}
+}
+
+// Close 刷新并关闭日志文件
+func (l *AccessLogger) Close() error {
+	l.logger.Sync()
+	if l.closer != nil {
+		return l.closer.Close()
+	}
+	return nil
+}
+
+// dailyRotateWriter 跨天后第一次写入前轮转文件，与lumberjack的按大小轮转叠加
+type dailyRotateWriter struct {
+	rotator *lumberjack.Logger
+	now     func() time.Time
+	day     string
+	mutex   sync.Mutex
+}
+
+func newDailyRotateWriter(rotator *lumberjack.Logger, now func() time.Time) *dailyRotateWriter {
+	w := &dailyRotateWriter{rotator: rotator, now: now}
+	// 沿用已有文件的日期，重启后跨天写入同样会轮转
+	if info, err := os.Stat(rotator.Filename); err == nil {
+		w.day = info.ModTime().Format("2006-01-02")
+	}
+	return w
+}
+
+func (w *dailyRotateWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	day := w.now().Format("2006-01-02")
+	if w.day != "" && w.day != day {
+		if err := w.rotator.Rotate(); err != nil {
+			return 0, err
+		}
+	}
+	w.day = day
+	return w.rotator.Write(p)
+}
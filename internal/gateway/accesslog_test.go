@@ -0,0 +1,105 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	"github.com/env-data-platform/internal/gateway/auth"
+)
+
+func TestAccessLogMiddleware(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "trace-1", r.Header.Get(TraceIDHeader))
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer upstream.Close()
+
+	file := filepath.Join(t.TempDir(), "access.log")
+	accessLogger, err := NewAccessLogger(&AccessLogConfig{File: file, Format: "json", MaxSize: 1})
+	require.NoError(t, err)
+
+	router := NewRouter(zap.NewNop(), nil, nil)
+	require.NoError(t, router.AddRoute(&Route{ID: "devices", Path: "/api/devices", Method: "POST", Target: upstream.URL}))
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(accessLogger.Middleware())
+	engine.Use(func(c *gin.Context) {
+		c.Set("user", &auth.User{ID: "u1", Username: "operator"})
+	})
+	engine.Any("/*path", router.HandleRequest())
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	req, _ := http.NewRequest("POST", server.URL+"/api/devices?mn=MN001", nil)
+	req.Header.Set(TraceIDHeader, "trace-1")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "trace-1", resp.Header.Get(TraceIDHeader))
+
+	// 未携带链路ID时由网关生成
+	resp, err = http.Get(server.URL + "/missing")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.NotEmpty(t, resp.Header.Get(TraceIDHeader))
+	require.NoError(t, accessLogger.Close())
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(lines[0], &entry))
+	assert.Equal(t, "trace-1", entry["trace_id"])
+	assert.Equal(t, "POST", entry["method"])
+	assert.Equal(t, "/api/devices", entry["path"])
+	assert.Equal(t, "mn=MN001", entry["query"])
+	assert.Equal(t, float64(http.StatusCreated), entry["status"])
+	assert.Equal(t, "devices", entry["route_id"])
+	assert.Equal(t, upstream.URL, entry["upstream"])
+	assert.Equal(t, float64(1), entry["attempts"])
+	assert.Equal(t, "operator", entry["user"])
+	assert.Contains(t, entry, "latency")
+
+	require.NoError(t, json.Unmarshal(lines[1], &entry))
+	assert.Equal(t, float64(http.StatusNotFound), entry["status"])
+	assert.Equal(t, "", entry["upstream"])
+}
+
+func TestDailyRotateWriter(t *testing.T) {
+	dir := t.TempDir()
+	rotator := &lumberjack.Logger{Filename: filepath.Join(dir, "access.log"), LocalTime: true}
+	defer rotator.Close()
+
+	now := time.Date(2024, 5, 1, 23, 59, 0, 0, time.Local)
+	writer := newDailyRotateWriter(rotator, func() time.Time { return now })
+
+	_, err := writer.Write([]byte("day1\n"))
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("day1 again\n"))
+	require.NoError(t, err)
+	now = now.Add(2 * time.Minute)
+	_, err = writer.Write([]byte("day2\n"))
+	require.NoError(t, err)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+	data, err := os.ReadFile(rotator.Filename)
+	require.NoError(t, err)
+	assert.Equal(t, "day2\n", string(data))
+}
@@ -0,0 +1,147 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+
+	"github.com/env-data-platform/internal/gateway/auth"
+	"github.com/env-data-platform/internal/models"
+)
+
+// AuditModule 网关管理操作在系统操作日志中的模块名
+const AuditModule = "gateway"
+
+// AuditLogger 管理API审计日志，记录谁在何时修改了哪个路由、API密钥或限流，以及变更前后的值
+//
+// 审计日志复用平台的系统操作日志表，开启时写入数据库，始终同时写一条运行日志。
+type AuditLogger struct {
+	db      *gorm.DB
+	timeout time.Duration
+	logger  *zap.Logger
+}
+
+// NewAuditLogger 创建审计日志，未开启时只写运行日志
+func NewAuditLogger(config *AuditConfig, logger *zap.Logger) (*AuditLogger, error) {
+	audit := &AuditLogger{timeout: config.Timeout, logger: logger}
+	if !config.Enabled {
+		return audit, nil
+	}
+
+	db, err := gorm.Open(mysql.Open(config.DSN), &gorm.Config{
+		Logger: gormlogger.Default.LogMode(gormlogger.Silent),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect audit database: %w", err)
+	}
+	audit.db = db
+	return audit, nil
+}
+
+// Record 记录一次管理操作，需在写出响应之后调用以取得状态码
+//
+// resource 为被修改的对象，如 "route:<id>"；before/after 为变更前后的值，新建时before为nil，删除时after为nil
+func (a *AuditLogger) Record(c *gin.Context, resource string, before, after interface{}, opErr error) {
+	if a == nil {
+		return
+	}
+
+	entry := newAuditEntry(c, resource, before, after, opErr)
+	a.logger.Info("Gateway admin change",
+		zap.String("user", entry.Username),
+		zap.String("action", entry.Action),
+		zap.String("resource", entry.Resource),
+		zap.Int("status_code", entry.StatusCode),
+		zap.String("old_value", entry.OldValue),
+		zap.String("new_value", entry.NewValue))
+
+	if a.db == nil {
+		return
+	}
+	ctx := context.Background()
+	if a.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.timeout)
+		defer cancel()
+	}
+	if err := a.db.WithContext(ctx).Create(entry).Error; err != nil {
+		a.logger.Error("Failed to save gateway audit log",
+			zap.String("resource", entry.Resource),
+			zap.Error(err))
+	}
+}
+
+// Close 关闭审计数据库连接
+func (a *AuditLogger) Close() error {
+	if a == nil || a.db == nil {
+		return nil
+	}
+	sqlDB, err := a.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// setAuditUserID 网关用户ID为数字时关联平台用户
+func setAuditUserID(entry *models.OperationLog, id string) {
+	if n, err := strconv.ParseUint(id, 10, 64); err == nil {
+		userID := uint(n)
+		entry.UserID = &userID
+	}
+}
+
+// newAuditEntry 构建操作日志，优先使用认证中间件识别出的身份
+func newAuditEntry(c *gin.Context, resource string, before, after interface{}, opErr error) *models.OperationLog {
+	statusCode := c.Writer.Status()
+	entry := &models.OperationLog{
+		Username:   "unknown",
+		Module:     AuditModule,
+		Action:     c.Request.Method + " " + c.FullPath(),
+		Resource:   resource,
+		Method:     c.Request.Method,
+		URL:        c.Request.URL.String(),
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+		Status:     1,
+		StatusCode: statusCode,
+		OldValue:   auditValue(before),
+		NewValue:   auditValue(after),
+	}
+	if identity, exists := auth.GetIdentity(c); exists {
+		if identity.Username != "" {
+			entry.Username = identity.Username
+		}
+		setAuditUserID(entry, identity.UserID)
+	} else if user, exists := auth.GetCurrentUser(c); exists {
+		entry.Username = user.Username
+		setAuditUserID(entry, user.ID)
+	}
+	if opErr != nil || statusCode >= 400 {
+		entry.Status = 0
+	}
+	if opErr != nil {
+		entry.ErrorMsg = opErr.Error()
+	}
+	return entry
+}
+
+// auditValue 将变更前后的值序列化为JSON，nil记为空
+func auditValue(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%+v", v)
+	}
+	return string(data)
+}
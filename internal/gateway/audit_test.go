@@ -0,0 +1,64 @@
+package gateway
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/env-data-platform/internal/gateway/auth"
+	"github.com/env-data-platform/internal/models"
+)
+
+func TestAuditEntry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	audit, err := NewAuditLogger(&AuditConfig{}, zap.NewNop())
+	require.NoError(t, err)
+
+	var entries []*models.OperationLog
+	engine := gin.New()
+	engine.Use(func(c *gin.Context) {
+		c.Set("user", &auth.User{ID: "7", Username: "admin"})
+	})
+	engine.PUT("/admin/routes/:method/*path", func(c *gin.Context) {
+		before := &Route{ID: "r1", Path: "/api/data", Method: "GET", Target: "http://a"}
+		after := &Route{ID: "r1", Path: "/api/data", Method: "GET", Target: "http://b"}
+		c.JSON(http.StatusOK, gin.H{"success": true})
+		entries = append(entries, newAuditEntry(c, "route:r1", before, after, nil))
+		audit.Record(c, "route:r1", before, after, nil)
+	})
+	engine.DELETE("/admin/ratelimit/:key", func(c *gin.Context) {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false})
+		entries = append(entries, newAuditEntry(c, "ratelimit:"+c.Param("key"), nil, nil, errors.New("redis down")))
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("PUT", "/admin/routes/GET/api/data", nil))
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("DELETE", "/admin/ratelimit/ip:1.2.3.4", nil))
+	require.Len(t, entries, 2)
+
+	entry := entries[0]
+	assert.Equal(t, AuditModule, entry.Module)
+	assert.Equal(t, "PUT /admin/routes/:method/*path", entry.Action)
+	assert.Equal(t, "route:r1", entry.Resource)
+	assert.Equal(t, "admin", entry.Username)
+	require.NotNil(t, entry.UserID)
+	assert.Equal(t, uint(7), *entry.UserID)
+	assert.Equal(t, 1, entry.Status)
+	assert.Equal(t, http.StatusOK, entry.StatusCode)
+	assert.Contains(t, entry.OldValue, `"target":"http://a"`)
+	assert.Contains(t, entry.NewValue, `"target":"http://b"`)
+
+	entry = entries[1]
+	assert.Equal(t, "ratelimit:ip:1.2.3.4", entry.Resource)
+	assert.Equal(t, 0, entry.Status)
+	assert.Equal(t, "redis down", entry.ErrorMsg)
+	assert.Empty(t, entry.OldValue)
+	assert.Empty(t, entry.NewValue)
+}
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// API密钥状态
+const (
+	APIKeyStatusActive  = "active"
+	APIKeyStatusExpired = "expired"
+	APIKeyStatusRevoked = "revoked"
+)
+
+var (
+	// ErrAPIKeyNotFound API密钥不存在
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	// ErrInvalidExpiry 过期时间早于当前时间
+	ErrInvalidExpiry = errors.New("expires_at must be in the future")
+)
+
+// Status 密钥当前状态，过期的密钥即使未撤销也不能再使用
+func (k *APIKey) Status(now time.Time) string {
+	if !k.IsActive {
+		return APIKeyStatusRevoked
+	}
+	if k.ExpiresAt != nil && !k.ExpiresAt.After(now) {
+		return APIKeyStatusExpired
+	}
+	return APIKeyStatusActive
+}
+
+// APIKeyInfo 列表展示的密钥信息，不含Secret，Key只保留前缀
+type APIKeyInfo struct {
+	ID         string     `json:"id"`
+	Key        string     `json:"key"`
+	UserID     string     `json:"user_id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	RateLimit  int        `json:"rate_limit"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	Status     string     `json:"status"`
+}
+
+// Info 生成密钥的展示信息，调用方需持有读锁
+func (k *APIKey) Info(now time.Time) *APIKeyInfo {
+	return &APIKeyInfo{
+		ID:         k.ID,
+		Key:        MaskAPIKey(k.Key),
+		UserID:     k.UserID,
+		Name:       k.Name,
+		Scopes:     k.Scopes,
+		RateLimit:  k.RateLimit,
+		CreatedAt:  k.CreatedAt,
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		Status:     k.Status(now),
+	}
+}
+
+// MaskAPIKey 只保留密钥前缀，用于日志和列表展示
+func MaskAPIKey(key string) string {
+	const visible = 12
+	if len(key) <= visible {
+		return strings.Repeat("*", len(key))
+	}
+	return key[:visible] + "****"
+}
+
+// normalizeScopes 去掉空白和重复的scope
+func normalizeScopes(scopes []string) ([]string, error) {
+	result := make([]string, 0, len(scopes))
+	seen := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if scope == "" {
+			return nil, fmt.Errorf("scope must not be empty")
+		}
+		if strings.ContainsAny(scope, " \t") {
+			return nil, fmt.Errorf("invalid scope: %q", scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	return result, nil
+}
+
+// Authenticated 请求是否已通过认证（用户或API密钥）
+func Authenticated(c *gin.Context) bool {
+	if _, exists := c.Get("user"); exists {
+		return true
+	}
+	_, exists := c.Get("api_key")
+	return exists
+}
+
+// HasAnyScope 是否拥有任意一个指定的scope
+func HasAnyScope(c *gin.Context, scopes []string) bool {
+	for _, scope := range scopes {
+		if HasScope(c, scope) {
+			return true
+		}
+	}
+	return false
+}
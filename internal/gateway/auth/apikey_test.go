@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestAPIKeyScopesAndExpiry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	authenticator := NewAuthenticator(&AuthConfig{Strategy: APIKeyAuth}, zap.NewNop())
+
+	_, err := authenticator.CreateAPIKey("u1", "bad", []string{" "}, 10, nil)
+	assert.Error(t, err)
+	past := time.Now().Add(-time.Minute)
+	_, err = authenticator.CreateAPIKey("u1", "bad", nil, 10, &past)
+	assert.ErrorIs(t, err, ErrInvalidExpiry)
+
+	reader, err := authenticator.CreateAPIKey("u1", "reader", []string{"data:read", " data:read"}, 10, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"data:read"}, reader.Scopes)
+	expiresAt := time.Now().Add(time.Hour)
+	expiring, err := authenticator.CreateAPIKey("u1", "expiring", []string{"data:*"}, 10, &expiresAt)
+	require.NoError(t, err)
+
+	engine := gin.New()
+	engine.Use(authenticator.Middleware())
+	engine.GET("/read", RequireScope("data:read"), func(c *gin.Context) { c.Status(http.StatusOK) })
+	engine.GET("/write", RequireScope("data:write"), func(c *gin.Context) { c.Status(http.StatusOK) })
+	call := func(path, key string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("X-API-Key", key)
+		engine.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, call("/read", reader.Key))
+	assert.Equal(t, http.StatusForbidden, call("/write", reader.Key))
+	assert.Equal(t, http.StatusOK, call("/write", expiring.Key))
+	assert.Equal(t, http.StatusUnauthorized, call("/read", "short"))
+
+	// 到期后自动失效
+	authenticator.mu.Lock()
+	expired := time.Now().Add(-time.Second)
+	authenticator.apiKeys[expiring.Key].ExpiresAt = &expired
+	authenticator.mu.Unlock()
+	assert.Equal(t, http.StatusUnauthorized, call("/write", expiring.Key))
+
+	require.NoError(t, authenticator.RevokeAPIKey(reader.Key))
+	assert.Equal(t, http.StatusUnauthorized, call("/read", reader.Key))
+	assert.ErrorIs(t, authenticator.RevokeAPIKey("missing"), ErrAPIKeyNotFound)
+
+	keys := authenticator.ListAPIKeys("u1")
+	require.Len(t, keys, 2)
+	assert.Equal(t, "reader", keys[0].Name)
+	assert.Equal(t, APIKeyStatusRevoked, keys[0].Status)
+	assert.NotNil(t, keys[0].LastUsedAt)
+	assert.Equal(t, MaskAPIKey(reader.Key), keys[0].Key)
+	assert.NotContains(t, keys[0].Key, reader.Key)
+	assert.Equal(t, APIKeyStatusExpired, keys[1].Status)
+	assert.Equal(t, []string{"data:*"}, keys[1].Scopes)
+}
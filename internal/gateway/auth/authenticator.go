@@ -3,11 +3,15 @@ package auth
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	platformauth "github.com/env-data-platform/internal/auth"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"go.uber.org/zap"
@@ -50,13 +54,15 @@ type APIKey struct {
 	IsActive    bool              `json:"is_active"`
 }
 
-// AuthConfig 认证配置
+// AuthConfig 认证配置，Strategies非空时按顺序组合多个策略，否则只使用Strategy
 type AuthConfig struct {
-	Strategy    AuthStrategy `json:"strategy" yaml:"strategy"`
-	JWTSecret   string       `json:"jwt_secret" yaml:"jwt_secret"`
-	TokenExpiry time.Duration `json:"token_expiry" yaml:"token_expiry"`
-	Issuer      string       `json:"issuer" yaml:"issuer"`
-	Audience    string       `json:"audience" yaml:"audience"`
+	Strategy    AuthStrategy   `json:"strategy" yaml:"strategy"`
+	Strategies  []AuthStrategy `json:"strategies" yaml:"strategies"`
+	Mode        AuthMode       `json:"mode" yaml:"mode"`
+	JWTSecret   string         `json:"jwt_secret" yaml:"jwt_secret"`
+	TokenExpiry time.Duration  `json:"token_expiry" yaml:"token_expiry"`
+	Issuer      string         `json:"issuer" yaml:"issuer"`
+	Audience    string         `json:"audience" yaml:"audience"`
 }
 
 // Authenticator 认证器
@@ -65,6 +71,7 @@ type Authenticator struct {
 	apiKeys   map[string]*APIKey
 	users     map[string]*User
 	logger    *zap.Logger
+	mu        sync.RWMutex // 保护apiKeys及密钥的使用时间和状态
 }
 
 // NewAuthenticator 创建认证器
@@ -77,48 +84,33 @@ func NewAuthenticator(config *AuthConfig, logger *zap.Logger) *Authenticator {
 	}
 }
 
-// Middleware 认证中间件
+// Middleware 认证中间件，按策略链认证并把识别出的身份写入上下文
 func (a *Authenticator) Middleware() gin.HandlerFunc {
+	strategies := a.config.StrategyChain()
 	return func(c *gin.Context) {
-		switch a.config.Strategy {
-		case NoAuth:
+		if len(strategies) == 0 {
 			c.Next()
 			return
-		case APIKeyAuth:
-			if err := a.validateAPIKey(c); err != nil {
-				c.JSON(http.StatusUnauthorized, gin.H{
-					"error": "unauthorized",
-					"message": err.Error(),
-				})
-				c.Abort()
-				return
-			}
-		case JWT:
-			if err := a.validateJWT(c); err != nil {
-				c.JSON(http.StatusUnauthorized, gin.H{
-					"error": "unauthorized",
-					"message": err.Error(),
-				})
-				c.Abort()
-				return
-			}
-		case BasicAuth:
-			if err := a.validateBasicAuth(c); err != nil {
-				c.JSON(http.StatusUnauthorized, gin.H{
-					"error": "unauthorized",
-					"message": err.Error(),
-				})
-				c.Abort()
-				return
-			}
-		default:
+		}
+
+		identity, err := a.authenticate(c, strategies)
+		if errors.Is(err, errInvalidStrategy) {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": "invalid auth strategy",
 			})
 			c.Abort()
 			return
 		}
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "unauthorized",
+				"message": err.Error(),
+			})
+			c.Abort()
+			return
+		}
 
+		setIdentity(c, identity)
 		c.Next()
 	}
 }
@@ -136,25 +128,28 @@ func (a *Authenticator) validateAPIKey(c *gin.Context) error {
 	}
 
 	// 查找API密钥
+	a.mu.Lock()
 	keyInfo, exists := a.apiKeys[apiKey]
 	if !exists {
-		a.logger.Warn("Invalid API key attempted", zap.String("key", apiKey[:8]+"..."))
+		a.mu.Unlock()
+		a.logger.Warn("Invalid API key attempted", zap.String("key", MaskAPIKey(apiKey)))
 		return fmt.Errorf("invalid API key")
 	}
 
-	// 检查密钥是否激活
-	if !keyInfo.IsActive {
+	// 检查密钥状态，到期的密钥自动失效
+	now := time.Now()
+	switch keyInfo.Status(now) {
+	case APIKeyStatusRevoked:
+		a.mu.Unlock()
 		return fmt.Errorf("API key is disabled")
-	}
-
-	// 检查过期时间
-	if keyInfo.ExpiresAt != nil && keyInfo.ExpiresAt.Before(time.Now()) {
+	case APIKeyStatusExpired:
+		a.mu.Unlock()
 		return fmt.Errorf("API key expired")
 	}
 
 	// 更新最后使用时间
-	now := time.Now()
 	keyInfo.LastUsedAt = &now
+	a.mu.Unlock()
 
 	// 设置用户信息到上下文
 	if user, exists := a.users[keyInfo.UserID]; exists {
@@ -256,8 +251,16 @@ func (a *Authenticator) validateBasicAuth(c *gin.Context) error {
 	return nil
 }
 
-// CreateAPIKey 创建API密钥
+// CreateAPIKey 创建API密钥，scopes限定密钥可访问的接口范围，expiresAt为空时永不过期
 func (a *Authenticator) CreateAPIKey(userID, name string, scopes []string, rateLimit int, expiresAt *time.Time) (*APIKey, error) {
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, err
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, ErrInvalidExpiry
+	}
+
 	// 生成API密钥
 	key := a.generateAPIKey()
 	secret := a.generateSecret()
@@ -276,24 +279,31 @@ func (a *Authenticator) CreateAPIKey(userID, name string, scopes []string, rateL
 		IsActive:   true,
 	}
 
+	a.mu.Lock()
 	a.apiKeys[key] = apiKey
+	a.mu.Unlock()
 
 	a.logger.Info("API key created",
 		zap.String("key_id", apiKey.ID),
 		zap.String("user_id", userID),
-		zap.String("name", name))
+		zap.String("name", name),
+		zap.Strings("scopes", scopes),
+		zap.Timep("expires_at", expiresAt))
 
 	return apiKey, nil
 }
 
 // RevokeAPIKey 撤销API密钥
 func (a *Authenticator) RevokeAPIKey(key string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
 	if apiKey, exists := a.apiKeys[key]; exists {
 		apiKey.IsActive = false
 		a.logger.Info("API key revoked", zap.String("key_id", apiKey.ID))
 		return nil
 	}
-	return fmt.Errorf("API key not found")
+	return ErrAPIKeyNotFound
 }
 
 // CreateJWT 创建JWT令牌
@@ -338,14 +348,33 @@ func (a *Authenticator) GetUser(userID string) (*User, bool) {
 	return user, exists
 }
 
-// ListAPIKeys 列出用户的API密钥
-func (a *Authenticator) ListAPIKeys(userID string) []*APIKey {
-	keys := make([]*APIKey, 0)
+// GetAPIKey 获取API密钥的展示信息
+func (a *Authenticator) GetAPIKey(key string) (*APIKeyInfo, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	apiKey, exists := a.apiKeys[key]
+	if !exists {
+		return nil, false
+	}
+	return apiKey.Info(time.Now()), true
+}
+
+// ListAPIKeys 列出用户的API密钥，按创建时间排序，包含scope、过期时间、最近使用时间和状态
+func (a *Authenticator) ListAPIKeys(userID string) []*APIKeyInfo {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	now := time.Now()
+	keys := make([]*APIKeyInfo, 0)
 	for _, key := range a.apiKeys {
 		if key.UserID == userID {
-			keys = append(keys, key)
+			keys = append(keys, key.Info(now))
 		}
 	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
 	return keys
 }
 
@@ -357,22 +386,18 @@ func GetCurrentUser(c *gin.Context) (*User, bool) {
 	return nil, false
 }
 
-// HasScope 检查用户是否有指定权限
+// HasScope 检查用户是否有指定权限，scope支持"资源:动作"形式及通配符（如"routes:*"）
 func HasScope(c *gin.Context, scope string) bool {
 	if user, exists := GetCurrentUser(c); exists {
-		for _, s := range user.Scopes {
-			if s == scope || s == "*" {
-				return true
-			}
+		if platformauth.HasPermission(user.Scopes, scope) {
+			return true
 		}
 	}
 
 	if apiKey, exists := c.Get("api_key"); exists {
 		key := apiKey.(*APIKey)
-		for _, s := range key.Scopes {
-			if s == scope || s == "*" {
-				return true
-			}
+		if platformauth.HasPermission(key.Scopes, scope) {
+			return true
 		}
 	}
 
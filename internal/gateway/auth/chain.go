@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthMode 多个认证策略的组合方式
+type AuthMode string
+
+const (
+	// AuthModeAny 按顺序尝试，任一策略通过即可
+	AuthModeAny AuthMode = "any"
+	// AuthModeAll 所有策略都必须通过，且识别出的用户需一致
+	AuthModeAll AuthMode = "all"
+)
+
+// 认证通过后写入gin上下文的键
+const (
+	ContextKeyIdentity = "auth_identity"
+	ContextKeyStrategy = "auth_strategy"
+	ContextKeyUserID   = "user_id"
+)
+
+// errInvalidStrategy 配置了未实现的认证策略
+var errInvalidStrategy = errors.New("invalid auth strategy")
+
+// Identity 认证识别出的调用方身份，供限流、审计等后续中间件使用
+type Identity struct {
+	Strategy   AuthStrategy   `json:"strategy"`   // 首个通过的策略
+	Strategies []AuthStrategy `json:"strategies"` // 所有通过的策略
+	UserID     string         `json:"user_id"`
+	Username   string         `json:"username"`
+	APIKeyID   string         `json:"api_key_id,omitempty"`
+}
+
+// ValidStrategy 是否为已实现的认证策略
+func ValidStrategy(strategy AuthStrategy) bool {
+	switch strategy {
+	case NoAuth, APIKeyAuth, JWT, BasicAuth:
+		return true
+	}
+	return false
+}
+
+// StrategyChain 按顺序返回需要尝试的认证策略，未配置Strategies时沿用Strategy，none不参与认证
+func (c *AuthConfig) StrategyChain() []AuthStrategy {
+	strategies := c.Strategies
+	if len(strategies) == 0 {
+		strategies = []AuthStrategy{c.Strategy}
+	}
+	chain := make([]AuthStrategy, 0, len(strategies))
+	for _, strategy := range strategies {
+		if strategy != "" && strategy != NoAuth {
+			chain = append(chain, strategy)
+		}
+	}
+	return chain
+}
+
+// Enabled 是否需要认证
+func (c *AuthConfig) Enabled() bool {
+	return len(c.StrategyChain()) > 0
+}
+
+func (c *AuthConfig) mode() AuthMode {
+	if c.Mode == AuthModeAll {
+		return AuthModeAll
+	}
+	return AuthModeAny
+}
+
+// authenticate 按顺序执行认证策略链。any模式返回首个通过策略的身份；
+// all模式要求全部通过，身份以首个策略为准并合并API密钥等信息
+func (a *Authenticator) authenticate(c *gin.Context, strategies []AuthStrategy) (*Identity, error) {
+	mode := a.config.mode()
+
+	var identity *Identity
+	var failures []string
+	for _, strategy := range strategies {
+		validate, ok := a.validator(strategy)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", errInvalidStrategy, strategy)
+		}
+
+		if err := validate(c); err != nil {
+			if len(strategies) == 1 {
+				return nil, err
+			}
+			if mode == AuthModeAll {
+				return nil, fmt.Errorf("%s: %w", strategy, err)
+			}
+			failures = append(failures, fmt.Sprintf("%s: %v", strategy, err))
+			continue
+		}
+
+		current := identify(c, strategy)
+		if identity == nil {
+			identity = current
+		} else {
+			if identity.UserID != "" && current.UserID != "" && identity.UserID != current.UserID {
+				return nil, fmt.Errorf("credentials identify different users")
+			}
+			identity.merge(current)
+		}
+		if mode == AuthModeAny {
+			return identity, nil
+		}
+	}
+
+	if identity == nil {
+		return nil, errors.New(strings.Join(failures, "; "))
+	}
+	return identity, nil
+}
+
+func (a *Authenticator) validator(strategy AuthStrategy) (func(*gin.Context) error, bool) {
+	switch strategy {
+	case APIKeyAuth:
+		return a.validateAPIKey, true
+	case JWT:
+		return a.validateJWT, true
+	case BasicAuth:
+		return a.validateBasicAuth, true
+	}
+	return nil, false
+}
+
+// identify 从上下文中读取策略校验成功后写入的用户和API密钥。API密钥以密钥所属用户为准，
+// 避免all模式下沿用前一个策略写入的用户
+func identify(c *gin.Context, strategy AuthStrategy) *Identity {
+	identity := &Identity{
+		Strategy:   strategy,
+		Strategies: []AuthStrategy{strategy},
+	}
+	user, hasUser := GetCurrentUser(c)
+	if strategy == APIKeyAuth {
+		if value, exists := c.Get("api_key"); exists {
+			key := value.(*APIKey)
+			identity.APIKeyID = key.ID
+			identity.UserID = key.UserID
+			if hasUser && user.ID == key.UserID {
+				identity.Username = user.Username
+			}
+		}
+		return identity
+	}
+	if hasUser {
+		identity.UserID = user.ID
+		identity.Username = user.Username
+	}
+	return identity
+}
+
+func (i *Identity) merge(other *Identity) {
+	i.Strategies = append(i.Strategies, other.Strategy)
+	if i.UserID == "" {
+		i.UserID = other.UserID
+	}
+	if i.Username == "" {
+		i.Username = other.Username
+	}
+	if i.APIKeyID == "" {
+		i.APIKeyID = other.APIKeyID
+	}
+}
+
+// setIdentity 写入身份，user_id与限流的UserIDFunc、指标采集读取的键一致
+func setIdentity(c *gin.Context, identity *Identity) {
+	c.Set(ContextKeyIdentity, identity)
+	c.Set(ContextKeyStrategy, string(identity.Strategy))
+	if identity.UserID != "" {
+		c.Set(ContextKeyUserID, identity.UserID)
+	}
+}
+
+// GetIdentity 从上下文获取认证识别出的身份
+func GetIdentity(c *gin.Context) (*Identity, bool) {
+	if value, exists := c.Get(ContextKeyIdentity); exists {
+		if identity, ok := value.(*Identity); ok {
+			return identity, true
+		}
+	}
+	return nil, false
+}
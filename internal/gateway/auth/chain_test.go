@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestStrategyChain(t *testing.T) {
+	assert.Empty(t, (&AuthConfig{Strategy: NoAuth}).StrategyChain())
+	assert.Equal(t, []AuthStrategy{JWT}, (&AuthConfig{Strategy: JWT}).StrategyChain())
+	config := &AuthConfig{Strategy: BasicAuth, Strategies: []AuthStrategy{JWT, NoAuth, APIKeyAuth}}
+	assert.Equal(t, []AuthStrategy{JWT, APIKeyAuth}, config.StrategyChain())
+	assert.True(t, config.Enabled())
+}
+
+func TestMultiStrategyMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newEngine := func(mode AuthMode) (*Authenticator, *gin.Engine) {
+		authenticator := NewAuthenticator(&AuthConfig{
+			Strategies:  []AuthStrategy{JWT, APIKeyAuth},
+			Mode:        mode,
+			JWTSecret:   "secret",
+			TokenExpiry: time.Hour,
+		}, zap.NewNop())
+		engine := gin.New()
+		engine.Use(authenticator.Middleware())
+		engine.GET("/whoami", func(c *gin.Context) {
+			identity, _ := GetIdentity(c)
+			c.JSON(http.StatusOK, gin.H{"identity": identity, "user_id": c.GetString(ContextKeyUserID)})
+		})
+		return authenticator, engine
+	}
+	call := func(engine *gin.Engine, token, key string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/whoami", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	authenticator, engine := newEngine(AuthModeAny)
+	token, err := authenticator.CreateJWT(&User{ID: "u1", Username: "alice"})
+	require.NoError(t, err)
+	apiKey, err := authenticator.CreateAPIKey("u2", "svc", nil, 10, nil)
+	require.NoError(t, err)
+
+	w := call(engine, token, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"strategy":"jwt"`)
+	assert.Contains(t, w.Body.String(), `"user_id":"u1"`)
+
+	w = call(engine, "", apiKey.Key)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"strategy":"apikey"`)
+	assert.Contains(t, w.Body.String(), `"api_key_id":"`+apiKey.ID+`"`)
+	assert.Contains(t, w.Body.String(), `"user_id":"u2"`)
+
+	w = call(engine, "", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "jwt: authorization header required; apikey: API key required")
+
+	authenticator, engine = newEngine(AuthModeAll)
+	token, err = authenticator.CreateJWT(&User{ID: "u1", Username: "alice"})
+	require.NoError(t, err)
+	ownKey, err := authenticator.CreateAPIKey("u1", "own", nil, 10, nil)
+	require.NoError(t, err)
+	otherKey, err := authenticator.CreateAPIKey("u2", "other", nil, 10, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusUnauthorized, call(engine, token, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, call(engine, token, otherKey.Key).Code)
+	w = call(engine, token, ownKey.Key)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"strategies":["jwt","apikey"]`)
+	assert.Contains(t, w.Body.String(), `"username":"alice"`)
+}
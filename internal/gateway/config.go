@@ -1,10 +1,12 @@
 package gateway
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"time"
 
+	"github.com/env-data-platform/internal/gateway/auth"
 	"gopkg.in/yaml.v3"
 )
 
@@ -16,6 +18,8 @@ type Config struct {
 	LoadBalance LoadBalanceConfig `yaml:"load_balance"`
 	Metrics     MetricsConfig     `yaml:"metrics"`
 	Logging     LoggingConfig     `yaml:"logging"`
+	AccessLog   AccessLogConfig   `yaml:"access_log"`
+	Audit       AuditConfig       `yaml:"audit"`
 	Redis       RedisConfig       `yaml:"redis"`
 	Routes      []RouteConfig     `yaml:"routes"`
 	Services    []ServiceConfig   `yaml:"services"`
@@ -44,6 +48,8 @@ type TLSConfig struct {
 // AuthConfig 认证配置
 type AuthConfig struct {
 	Strategy    string        `yaml:"strategy" default:"none"`
+	Strategies  []string      `yaml:"strategies"`         // 多策略组合，按顺序尝试，配置后忽略strategy
+	Mode        string        `yaml:"mode" default:"any"` // any: 任一策略通过即可；all: 全部通过
 	JWTSecret   string        `yaml:"jwt_secret"`
 	TokenExpiry time.Duration `yaml:"token_expiry" default:"24h"`
 	Issuer      string        `yaml:"issuer" default:"env-data-platform"`
@@ -75,6 +81,79 @@ type HealthCheckConfig struct {
 	Timeout  time.Duration `yaml:"timeout" default:"5s"`
 	Path     string        `yaml:"path" default:"/health"`
 	Method   string        `yaml:"method" default:"GET"`
+	// 不健康的目标连续成功HealthyThreshold次后恢复，连续失败UnhealthyThreshold次后下线
+	HealthyThreshold   int `yaml:"healthy_threshold" default:"2"`
+	UnhealthyThreshold int `yaml:"unhealthy_threshold" default:"3"`
+}
+
+// MarshalJSON 输出可读的时长，用于管理接口展示
+func (c HealthCheckConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"enabled":             c.Enabled,
+		"interval":            c.Interval.String(),
+		"timeout":             c.Timeout.String(),
+		"path":                c.Path,
+		"method":              c.Method,
+		"healthy_threshold":   c.HealthyThreshold,
+		"unhealthy_threshold": c.UnhealthyThreshold,
+	})
+}
+
+// HealthCheckOverride 服务或目标级的健康检查配置，未设置的字段继承上一级
+type HealthCheckOverride struct {
+	Enabled            *bool         `yaml:"enabled"`
+	Interval           time.Duration `yaml:"interval"`
+	Timeout            time.Duration `yaml:"timeout"`
+	Path               string        `yaml:"path"`
+	Method             string        `yaml:"method"`
+	HealthyThreshold   int           `yaml:"healthy_threshold"`
+	UnhealthyThreshold int           `yaml:"unhealthy_threshold"`
+}
+
+// Apply 用已设置的字段覆盖base
+func (o *HealthCheckOverride) Apply(base HealthCheckConfig) HealthCheckConfig {
+	if o == nil {
+		return base
+	}
+	if o.Enabled != nil {
+		base.Enabled = *o.Enabled
+	}
+	if o.Interval > 0 {
+		base.Interval = o.Interval
+	}
+	if o.Timeout > 0 {
+		base.Timeout = o.Timeout
+	}
+	if o.Path != "" {
+		base.Path = o.Path
+	}
+	if o.Method != "" {
+		base.Method = o.Method
+	}
+	if o.HealthyThreshold > 0 {
+		base.HealthyThreshold = o.HealthyThreshold
+	}
+	if o.UnhealthyThreshold > 0 {
+		base.UnhealthyThreshold = o.UnhealthyThreshold
+	}
+	return base
+}
+
+// validate 检查启用的健康检查配置
+func (c HealthCheckConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Interval <= 0 || c.Timeout <= 0 {
+		return fmt.Errorf("health check interval and timeout must be positive")
+	}
+	if c.Path == "" || c.Path[0] != '/' {
+		return fmt.Errorf("health check path must start with /")
+	}
+	if c.HealthyThreshold < 1 || c.UnhealthyThreshold < 1 {
+		return fmt.Errorf("health check thresholds must be at least 1")
+	}
+	return nil
 }
 
 // MetricsConfig 指标配置
@@ -96,6 +175,25 @@ type LoggingConfig struct {
 	Compress   bool   `yaml:"compress" default:"true"`
 }
 
+// AccessLogConfig 访问日志配置
+type AccessLogConfig struct {
+	Enabled    bool   `yaml:"enabled" default:"false"`
+	File       string `yaml:"file" default:"logs/gateway-access.log"` // 文件路径，也可以是stdout/stderr
+	Format     string `yaml:"format" default:"json"`                  // json, text
+	MaxSize    int    `yaml:"max_size" default:"100"`                 // 单个文件大小上限(MB)
+	MaxBackups int    `yaml:"max_backups" default:"30"`
+	MaxAge     int    `yaml:"max_age" default:"30"` // 保留天数
+	Compress   bool   `yaml:"compress" default:"true"`
+	Daily      bool   `yaml:"daily" default:"true"` // 每天轮转一次
+}
+
+// AuditConfig 管理API审计日志配置，写入平台数据库的系统操作日志表
+type AuditConfig struct {
+	Enabled bool          `yaml:"enabled" default:"false"`
+	DSN     string        `yaml:"dsn"`                   // 平台MySQL数据库DSN
+	Timeout time.Duration `yaml:"timeout" default:"3s"` // 单条审计日志写入超时
+}
+
 // RedisConfig Redis配置
 type RedisConfig struct {
 	Host     string `yaml:"host" default:"localhost"`
@@ -118,6 +216,11 @@ type RouteConfig struct {
 	Retries     int               `yaml:"retries" default:"3"`
 	Auth        *RouteAuthConfig  `yaml:"auth"`
 	RateLimit   *RouteRateLimitConfig `yaml:"rate_limit"`
+	Retry       *RetryPolicy      `yaml:"retry"`
+	Match       *RouteMatch       `yaml:"match"`
+	Priority    int               `yaml:"priority"`
+	Fallback    *FallbackPolicy   `yaml:"fallback"`
+	CircuitBreaker *CircuitBreakerPolicy `yaml:"circuit_breaker"`
 }
 
 // RouteAuthConfig 路由认证配置
@@ -138,19 +241,25 @@ type RouteRateLimitConfig struct {
 
 // ServiceConfig 服务配置
 type ServiceConfig struct {
-	ID          string         `yaml:"id"`
-	Name        string         `yaml:"name"`
-	Targets     []TargetConfig `yaml:"targets"`
-	Strategy    string         `yaml:"strategy" default:"round_robin"`
-	HealthCheck HealthCheckConfig `yaml:"health_check"`
+	ID          string               `yaml:"id"`
+	Name        string               `yaml:"name"`
+	Targets     []TargetConfig       `yaml:"targets"`
+	Strategy    string               `yaml:"strategy" default:"round_robin"`
+	HealthCheck *HealthCheckOverride `yaml:"health_check"`
 }
 
 // TargetConfig 目标配置
 type TargetConfig struct {
-	ID       string            `yaml:"id"`
-	URL      string            `yaml:"url"`
-	Weight   int               `yaml:"weight" default:"1"`
-	Metadata map[string]string `yaml:"metadata"`
+	ID          string               `yaml:"id"`
+	URL         string               `yaml:"url"`
+	Weight      int                  `yaml:"weight" default:"1"`
+	Metadata    map[string]string    `yaml:"metadata"`
+	HealthCheck *HealthCheckOverride `yaml:"health_check"`
+}
+
+// TargetHealthCheck 目标实际使用的健康检查配置：全局配置依次被服务级、目标级配置覆盖
+func (s *ServiceConfig) TargetHealthCheck(target *TargetConfig, defaults HealthCheckConfig) HealthCheckConfig {
+	return target.HealthCheck.Apply(s.HealthCheck.Apply(defaults))
 }
 
 // LoadConfig 加载配置文件
@@ -168,6 +277,7 @@ func LoadConfig(configPath string) (*Config, error) {
 		},
 		Auth: AuthConfig{
 			Strategy:    "none",
+			Mode:        "any",
 			TokenExpiry: 24 * time.Hour,
 			Issuer:      "env-data-platform",
 			Audience:    "api",
@@ -188,6 +298,9 @@ func LoadConfig(configPath string) (*Config, error) {
 				Timeout:  5 * time.Second,
 				Path:     "/health",
 				Method:   "GET",
+
+				HealthyThreshold:   2,
+				UnhealthyThreshold: 3,
 			},
 			VirtualNodes: 100,
 		},
@@ -205,6 +318,18 @@ func LoadConfig(configPath string) (*Config, error) {
 			MaxAge:     28,
 			Compress:   true,
 		},
+		AccessLog: AccessLogConfig{
+			File:       "logs/gateway-access.log",
+			Format:     "json",
+			MaxSize:    100,
+			MaxBackups: 30,
+			MaxAge:     30,
+			Compress:   true,
+			Daily:      true,
+		},
+		Audit: AuditConfig{
+			Timeout: 3 * time.Second,
+		},
 		Redis: RedisConfig{
 			Host:     "localhost",
 			Port:     6379,
@@ -264,6 +389,42 @@ func (c *Config) overrideFromEnv() {
 	if redisPassword := os.Getenv("REDIS_PASSWORD"); redisPassword != "" {
 		c.Redis.Password = redisPassword
 	}
+	if auditDSN := os.Getenv("GATEWAY_AUDIT_DSN"); auditDSN != "" {
+		c.Audit.DSN = auditDSN
+	}
+}
+
+// AuthenticatorConfig 转换为认证器配置
+func (a *AuthConfig) AuthenticatorConfig() *auth.AuthConfig {
+	strategies := make([]auth.AuthStrategy, 0, len(a.Strategies))
+	for _, strategy := range a.Strategies {
+		strategies = append(strategies, auth.AuthStrategy(strategy))
+	}
+	return &auth.AuthConfig{
+		Strategy:    auth.AuthStrategy(a.Strategy),
+		Strategies:  strategies,
+		Mode:        auth.AuthMode(a.Mode),
+		JWTSecret:   a.JWTSecret,
+		TokenExpiry: a.TokenExpiry,
+		Issuer:      a.Issuer,
+		Audience:    a.Audience,
+	}
+}
+
+// validate 验证认证策略链和组合方式，启用jwt时必须配置密钥
+func (a *AuthConfig) validate() error {
+	if a.Mode != "" && a.Mode != string(auth.AuthModeAny) && a.Mode != string(auth.AuthModeAll) {
+		return fmt.Errorf("invalid auth mode: %s", a.Mode)
+	}
+	for _, strategy := range a.AuthenticatorConfig().StrategyChain() {
+		if !auth.ValidStrategy(strategy) {
+			return fmt.Errorf("invalid auth strategy: %s", strategy)
+		}
+		if strategy == auth.JWT && a.JWTSecret == "" {
+			return fmt.Errorf("JWT secret is required when auth strategy is jwt")
+		}
+	}
+	return nil
 }
 
 // validate 验证配置
@@ -272,8 +433,8 @@ func (c *Config) validate() error {
 		return fmt.Errorf("invalid server port: %d", c.Server.Port)
 	}
 
-	if c.Auth.Strategy == "jwt" && c.Auth.JWTSecret == "" {
-		return fmt.Errorf("JWT secret is required when auth strategy is jwt")
+	if err := c.Auth.validate(); err != nil {
+		return err
 	}
 
 	if c.Server.TLS.Enabled {
@@ -282,6 +443,19 @@ func (c *Config) validate() error {
 		}
 	}
 
+	if c.AccessLog.Enabled {
+		if c.AccessLog.File == "" {
+			return fmt.Errorf("access log file is required when access log is enabled")
+		}
+		if c.AccessLog.Format != "json" && c.AccessLog.Format != "text" {
+			return fmt.Errorf("invalid access log format: %s", c.AccessLog.Format)
+		}
+	}
+
+	if c.Audit.Enabled && c.Audit.DSN == "" {
+		return fmt.Errorf("audit dsn is required when audit is enabled")
+	}
+
 	// 验证路由配置
 	for i, route := range c.Routes {
 		if route.Path == "" {
@@ -293,6 +467,21 @@ func (c *Config) validate() error {
 		if route.Target == "" && route.Service == "" {
 			return fmt.Errorf("route[%d]: either target or service is required", i)
 		}
+		if err := route.Match.validate(); err != nil {
+			return fmt.Errorf("route[%d]: %w", i, err)
+		}
+		if route.Retries < 0 {
+			return fmt.Errorf("route[%d]: retries must not be negative", i)
+		}
+		if route.Retry != nil && (route.Retry.BaseDelay < 0 || route.Retry.MaxDelay < 0) {
+			return fmt.Errorf("route[%d]: retry delays must not be negative", i)
+		}
+		if err := route.Fallback.validate(); err != nil {
+			return fmt.Errorf("route[%d]: %w", i, err)
+		}
+		if err := route.CircuitBreaker.validate(); err != nil {
+			return fmt.Errorf("route[%d]: %w", i, err)
+		}
 	}
 
 	// 验证服务配置
@@ -307,6 +496,9 @@ func (c *Config) validate() error {
 			if target.URL == "" {
 				return fmt.Errorf("service[%d].target[%d]: url is required", i, j)
 			}
+			if err := service.TargetHealthCheck(&service.Targets[j], c.LoadBalance.HealthCheck).validate(); err != nil {
+				return fmt.Errorf("service[%d].target[%d]: %w", i, j, err)
+			}
 		}
 	}
 
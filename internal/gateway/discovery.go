@@ -3,36 +3,62 @@ package gateway
 import (
 	"context"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"go.uber.org/zap"
 )
 
+// 健康状态
+const (
+	HealthStatusHealthy   = "healthy"
+	HealthStatusUnhealthy = "unhealthy"
+	HealthStatusUnknown   = "unknown"
+)
+
+// healthCheckTick 调度健康检查的粒度，各服务按自己的间隔到期后检查
+const healthCheckTick = time.Second
+
 // ServiceInfo 服务信息
 type ServiceInfo struct {
-	ID          string            `json:"id"`
-	Name        string            `json:"name"`
-	Address     string            `json:"address"`
-	Port        int               `json:"port"`
-	Tags        []string          `json:"tags"`
-	Metadata    map[string]string `json:"metadata"`
-	Health      HealthStatus      `json:"health"`
-	RegisteredAt time.Time        `json:"registered_at"`
-	LastSeen    time.Time         `json:"last_seen"`
+	ID           string            `json:"id"`
+	Name         string            `json:"name"`
+	Group        string            `json:"group"`
+	URL          string            `json:"url"`
+	Address      string            `json:"address"`
+	Port         int               `json:"port"`
+	Tags         []string          `json:"tags"`
+	Metadata     map[string]string `json:"metadata"`
+	HealthCheck  HealthCheckConfig `json:"health_check"`
+	Health       HealthStatus      `json:"health"`
+	RegisteredAt time.Time         `json:"registered_at"`
+	LastSeen     time.Time         `json:"last_seen"`
+
+	nextCheck time.Time // 下次检查时间
+	checking  bool      // 检查进行中，避免慢目标的检查堆积
 }
 
 // HealthStatus 健康状态
 type HealthStatus struct {
-	Status      string    `json:"status"`      // healthy, unhealthy, unknown
-	LastCheck   time.Time `json:"last_check"`
-	CheckCount  int64     `json:"check_count"`
-	FailCount   int64     `json:"fail_count"`
-	Latency     time.Duration `json:"latency"`
-	Message     string    `json:"message"`
+	Status               string        `json:"status"` // healthy, unhealthy, unknown
+	LastCheck            time.Time     `json:"last_check"`
+	CheckCount           int64         `json:"check_count"`
+	FailCount            int64         `json:"fail_count"`
+	ConsecutiveSuccesses int           `json:"consecutive_successes"`
+	ConsecutiveFailures  int           `json:"consecutive_failures"`
+	Latency              time.Duration `json:"latency"`
+	Message              string        `json:"message"`
 }
 
+// HealthCheckFunc 每次健康检查完成后的回调，group为服务组ID，service为目标ID
+type HealthCheckFunc func(group, service string, health HealthStatus)
+
 // ServiceDiscovery 服务发现
 type ServiceDiscovery struct {
 	services    map[string]*ServiceInfo
@@ -41,22 +67,23 @@ type ServiceDiscovery struct {
 	logger      *zap.Logger
 	stopCh      chan struct{}
 	wg          sync.WaitGroup
+	tick        time.Duration
+	onCheck     HealthCheckFunc
 }
 
 // HealthChecker 健康检查器
 type HealthChecker struct {
-	config   *HealthCheckConfig
-	client   *http.Client
-	logger   *zap.Logger
+	config *HealthCheckConfig // 未单独配置的服务使用的默认配置
+	client *http.Client
+	logger *zap.Logger
 }
 
 // NewServiceDiscovery 创建服务发现
 func NewServiceDiscovery(config *HealthCheckConfig, logger *zap.Logger) *ServiceDiscovery {
 	healthChecker := &HealthChecker{
 		config: config,
-		client: &http.Client{
-			Timeout: config.Timeout,
-		},
+		// 超时按服务的配置在每次请求上设置
+		client: &http.Client{},
 		logger: logger,
 	}
 
@@ -65,18 +92,21 @@ func NewServiceDiscovery(config *HealthCheckConfig, logger *zap.Logger) *Service
 		healthCheck: healthChecker,
 		logger:      logger,
 		stopCh:      make(chan struct{}),
+		tick:        healthCheckTick,
 	}
 }
 
+// OnHealthCheck 设置健康检查回调，用于同步负载均衡目标的上下线，应在Start之前调用
+func (sd *ServiceDiscovery) OnHealthCheck(fn HealthCheckFunc) {
+	sd.onCheck = fn
+}
+
 // Start 启动服务发现
 func (sd *ServiceDiscovery) Start(ctx context.Context) error {
-	if !sd.healthCheck.config.Enabled {
-		sd.logger.Info("Health check disabled, skipping service discovery")
-		return nil
-	}
-
+	// 全局关闭时服务或目标仍可单独开启健康检查
 	sd.logger.Info("Starting service discovery",
-		zap.Duration("interval", sd.healthCheck.config.Interval))
+		zap.Bool("default_enabled", sd.healthCheck.config.Enabled),
+		zap.Duration("default_interval", sd.healthCheck.config.Interval))
 
 	sd.wg.Add(1)
 	go sd.healthCheckLoop(ctx)
@@ -100,11 +130,15 @@ func (sd *ServiceDiscovery) RegisterService(service *ServiceInfo) {
 	service.RegisteredAt = time.Now()
 	service.LastSeen = time.Now()
 	service.Health = HealthStatus{
-		Status:     "unknown",
+		Status:     HealthStatusUnknown,
 		LastCheck:  time.Time{},
 		CheckCount: 0,
 		FailCount:  0,
 	}
+	if service.HealthCheck == (HealthCheckConfig{}) {
+		service.HealthCheck = *sd.healthCheck.config
+	}
+	service.nextCheck = time.Time{}
 
 	sd.services[service.ID] = service
 
@@ -156,7 +190,7 @@ func (sd *ServiceDiscovery) GetHealthyServices() []*ServiceInfo {
 
 	services := make([]*ServiceInfo, 0)
 	for _, service := range sd.services {
-		if service.Health.Status == "healthy" {
+		if service.Health.Status == HealthStatusHealthy {
 			services = append(services, service)
 		}
 	}
@@ -195,94 +229,132 @@ func (sd *ServiceDiscovery) UpdateServiceHealth(serviceID string, health HealthS
 func (sd *ServiceDiscovery) healthCheckLoop(ctx context.Context) {
 	defer sd.wg.Done()
 
-	ticker := time.NewTicker(sd.healthCheck.config.Interval)
+	ticker := time.NewTicker(sd.tick)
 	defer ticker.Stop()
 
+	sd.performHealthChecks(time.Now())
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-sd.stopCh:
 			return
-		case <-ticker.C:
-			sd.performHealthChecks()
+		case now := <-ticker.C:
+			sd.performHealthChecks(now)
 		}
 	}
 }
 
-// performHealthChecks 执行健康检查
-func (sd *ServiceDiscovery) performHealthChecks() {
-	services := sd.GetServices()
+// performHealthChecks 检查已到期且未在检查中的服务
+func (sd *ServiceDiscovery) performHealthChecks(now time.Time) {
+	sd.mutex.Lock()
+	due := make([]*ServiceInfo, 0)
+	for _, service := range sd.services {
+		if !service.HealthCheck.Enabled || service.checking || now.Before(service.nextCheck) {
+			continue
+		}
+		service.checking = true
+		service.nextCheck = now.Add(service.HealthCheck.Interval)
+		due = append(due, service)
+	}
+	sd.mutex.Unlock()
 
-	for _, service := range services {
+	for _, service := range due {
 		go sd.checkServiceHealth(service)
 	}
 }
 
-// checkServiceHealth 检查单个服务健康状态
+// checkServiceHealth 检查单个服务健康状态，2xx为成功
 func (sd *ServiceDiscovery) checkServiceHealth(service *ServiceInfo) {
-	start := time.Now()
+	sd.mutex.RLock()
+	cfg := service.HealthCheck
+	checkURL := service.healthCheckURL()
+	sd.mutex.RUnlock()
 
-	url := fmt.Sprintf("http://%s:%d%s",
-		service.Address,
-		service.Port,
-		sd.healthCheck.config.Path)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
 
-	req, err := http.NewRequest(sd.healthCheck.config.Method, url, nil)
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, cfg.Method, checkURL, nil)
 	if err != nil {
-		sd.updateHealthStatus(service.ID, "unhealthy", time.Since(start), err.Error())
+		sd.updateHealthStatus(service.ID, false, time.Since(start), err.Error())
 		return
 	}
 
 	resp, err := sd.healthCheck.client.Do(req)
 	if err != nil {
-		sd.updateHealthStatus(service.ID, "unhealthy", time.Since(start), err.Error())
+		sd.updateHealthStatus(service.ID, false, time.Since(start), err.Error())
 		return
 	}
-	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
 
 	latency := time.Since(start)
-
 	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		sd.updateHealthStatus(service.ID, "healthy", latency, "")
+		sd.updateHealthStatus(service.ID, true, latency, "")
 	} else {
-		sd.updateHealthStatus(service.ID, "unhealthy", latency,
-			fmt.Sprintf("HTTP %d", resp.StatusCode))
+		sd.updateHealthStatus(service.ID, false, latency, fmt.Sprintf("HTTP %d", resp.StatusCode))
 	}
 }
 
-// updateHealthStatus 更新健康状态
-func (sd *ServiceDiscovery) updateHealthStatus(serviceID, status string, latency time.Duration, message string) {
-	sd.mutex.Lock()
-	defer sd.mutex.Unlock()
+// healthCheckURL 健康检查地址，有URL时沿用其scheme和host
+func (s *ServiceInfo) healthCheckURL() string {
+	if s.URL != "" {
+		return strings.TrimSuffix(s.URL, "/") + s.HealthCheck.Path
+	}
+	return fmt.Sprintf("http://%s%s", net.JoinHostPort(s.Address, strconv.Itoa(s.Port)), s.HealthCheck.Path)
+}
 
+// updateHealthStatus 记录一次检查结果。状态未知的服务首次成功即为健康；
+// 健康或未知的服务连续失败达到UnhealthyThreshold后下线，不健康的服务连续成功达到HealthyThreshold后恢复
+func (sd *ServiceDiscovery) updateHealthStatus(serviceID string, success bool, latency time.Duration, message string) {
+	sd.mutex.Lock()
 	service, exists := sd.services[serviceID]
 	if !exists {
+		sd.mutex.Unlock()
 		return
 	}
-
-	// 更新健康状态
-	oldStatus := service.Health.Status
-	service.Health.Status = status
-	service.Health.LastCheck = time.Now()
-	service.Health.CheckCount++
-	service.Health.Latency = latency
-	service.Health.Message = message
-
-	if status == "unhealthy" {
-		service.Health.FailCount++
+	service.checking = false
+
+	health := &service.Health
+	oldStatus := health.Status
+	health.LastCheck = time.Now()
+	health.CheckCount++
+	health.Latency = latency
+	health.Message = message
+
+	if success {
+		health.ConsecutiveSuccesses++
+		health.ConsecutiveFailures = 0
+		if oldStatus != HealthStatusUnhealthy || health.ConsecutiveSuccesses >= service.HealthCheck.HealthyThreshold {
+			health.Status = HealthStatusHealthy
+		}
+	} else {
+		health.FailCount++
+		health.ConsecutiveFailures++
+		health.ConsecutiveSuccesses = 0
+		if health.ConsecutiveFailures >= service.HealthCheck.UnhealthyThreshold {
+			health.Status = HealthStatusUnhealthy
+		}
 	}
+	service.LastSeen = time.Now()
+	snapshot := *health
+	group := service.Group
+	sd.mutex.Unlock()
 
 	// 记录状态变化
-	if oldStatus != status {
+	if oldStatus != snapshot.Status {
 		sd.logger.Info("Service health status changed",
 			zap.String("service_id", serviceID),
 			zap.String("service_name", service.Name),
 			zap.String("old_status", oldStatus),
-			zap.String("new_status", status),
+			zap.String("new_status", snapshot.Status),
 			zap.Duration("latency", latency),
 			zap.String("message", message))
 	}
+	if sd.onCheck != nil {
+		sd.onCheck(group, serviceID, snapshot)
+	}
 }
 
 // GetStats 获取统计信息
@@ -297,9 +369,9 @@ func (sd *ServiceDiscovery) GetStats() map[string]interface{} {
 
 	for _, service := range sd.services {
 		switch service.Health.Status {
-		case "healthy":
+		case HealthStatusHealthy:
 			healthy++
-		case "unhealthy":
+		case HealthStatusUnhealthy:
 			unhealthy++
 		default:
 			unknown++
@@ -318,44 +390,61 @@ func (sd *ServiceDiscovery) GetStats() map[string]interface{} {
 
 // CreateServiceFromTarget 从目标配置创建服务信息
 func CreateServiceFromTarget(target *Target) *ServiceInfo {
-	// 解析URL获取地址和端口
-	address := "localhost"
-	port := 80
-
-	// 这里应该解析target.URL获取实际的地址和端口
-	// 简化处理，实际应该使用url.Parse
+	address, port := splitTargetURL(target.URL)
 
 	return &ServiceInfo{
 		ID:       target.ID,
 		Name:     fmt.Sprintf("Target-%s", target.ID),
+		URL:      target.URL,
 		Address:  address,
 		Port:     port,
 		Tags:     []string{"gateway", "target"},
 		Metadata: target.Metadata,
 		Health: HealthStatus{
-			Status: "unknown",
+			Status: HealthStatusUnknown,
 		},
 	}
 }
 
-// CreateServiceFromConfig 从配置创建服务信息
-func CreateServiceFromConfig(config *ServiceConfig) []*ServiceInfo {
+// CreateServiceFromConfig 从配置创建服务信息，每个目标按服务级、目标级配置覆盖defaults得到自己的健康检查配置
+func CreateServiceFromConfig(config *ServiceConfig, defaults HealthCheckConfig) []*ServiceInfo {
 	services := make([]*ServiceInfo, 0, len(config.Targets))
 
-	for _, target := range config.Targets {
+	for i := range config.Targets {
+		target := &config.Targets[i]
+		address, port := splitTargetURL(target.URL)
 		service := &ServiceInfo{
-			ID:       target.ID,
-			Name:     config.Name,
-			Address:  target.URL, // 简化处理
-			Port:     80,         // 应该从URL解析
-			Tags:     []string{config.ID},
-			Metadata: target.Metadata,
+			ID:          target.ID,
+			Name:        config.Name,
+			Group:       config.ID,
+			URL:         target.URL,
+			Address:     address,
+			Port:        port,
+			Tags:        []string{config.ID},
+			Metadata:    target.Metadata,
+			HealthCheck: config.TargetHealthCheck(target, defaults),
 			Health: HealthStatus{
-				Status: "unknown",
+				Status: HealthStatusUnknown,
 			},
 		}
 		services = append(services, service)
 	}
 
 	return services
-}
\ No newline at end of file
+}
+
+// splitTargetURL 解析目标地址的主机和端口，未指定端口时按scheme取默认端口
+func splitTargetURL(rawURL string) (string, int) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL, 80
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		port = 80
+		if u.Scheme == "https" {
+			port = 443
+		}
+	}
+	return u.Hostname(), port
+}
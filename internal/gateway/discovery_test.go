@@ -0,0 +1,136 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTargetHealthCheckOverride(t *testing.T) {
+	defaults := HealthCheckConfig{
+		Enabled: true, Interval: 30 * time.Second, Timeout: 5 * time.Second,
+		Path: "/health", Method: "GET", HealthyThreshold: 2, UnhealthyThreshold: 3,
+	}
+	disabled := false
+	service := ServiceConfig{
+		ID:          "svc",
+		HealthCheck: &HealthCheckOverride{Interval: 10 * time.Second, Path: "/status"},
+		Targets: []TargetConfig{
+			{ID: "a", URL: "http://127.0.0.1:8001"},
+			{ID: "b", URL: "http://127.0.0.1:8002", HealthCheck: &HealthCheckOverride{Path: "/ready", UnhealthyThreshold: 1}},
+			{ID: "c", URL: "http://127.0.0.1:8003", HealthCheck: &HealthCheckOverride{Enabled: &disabled}},
+		},
+	}
+
+	a := service.TargetHealthCheck(&service.Targets[0], defaults)
+	assert.Equal(t, "/status", a.Path)
+	assert.Equal(t, 10*time.Second, a.Interval)
+	assert.Equal(t, 3, a.UnhealthyThreshold)
+
+	b := service.TargetHealthCheck(&service.Targets[1], defaults)
+	assert.Equal(t, "/ready", b.Path)
+	assert.Equal(t, 10*time.Second, b.Interval)
+	assert.Equal(t, 1, b.UnhealthyThreshold)
+	assert.Equal(t, 2, b.HealthyThreshold)
+
+	assert.False(t, service.TargetHealthCheck(&service.Targets[2], defaults).Enabled)
+
+	defaults.HealthyThreshold = 0
+	assert.Error(t, defaults.validate())
+}
+
+func TestServiceDiscoveryThresholds(t *testing.T) {
+	var healthy atomic.Bool
+	healthy.Store(true)
+	var lastPath atomic.Value
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastPath.Store(r.URL.Path)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	logger := zap.NewNop()
+	defaults := HealthCheckConfig{
+		Enabled: true, Interval: time.Minute, Timeout: time.Second,
+		Path: "/health", Method: "GET", HealthyThreshold: 2, UnhealthyThreshold: 3,
+	}
+	service := ServiceConfig{
+		ID: "svc",
+		Targets: []TargetConfig{
+			{ID: "t1", URL: backend.URL, HealthCheck: &HealthCheckOverride{Path: "/ready", UnhealthyThreshold: 2}},
+		},
+	}
+
+	lb := NewLoadBalancer(logger)
+	lb.AddServiceGroup(&ServiceGroup{ID: "svc", Strategy: RoundRobin, Targets: []*Target{{ID: "t1", URL: backend.URL, IsHealthy: true}}})
+	sd := NewServiceDiscovery(&defaults, logger)
+	sd.OnHealthCheck(lb.ApplyHealthStatus)
+	for _, info := range CreateServiceFromConfig(&service, defaults) {
+		sd.RegisterService(info)
+	}
+
+	check := func() HealthStatus {
+		info, ok := sd.GetService("t1")
+		require.True(t, ok)
+		sd.checkServiceHealth(info)
+		return info.Health
+	}
+
+	assert.Equal(t, HealthStatusHealthy, check().Status)
+	assert.Equal(t, "/ready", lastPath.Load())
+
+	// 连续失败达到目标级阈值2次后下线
+	healthy.Store(false)
+	health := check()
+	assert.Equal(t, HealthStatusHealthy, health.Status)
+	assert.Equal(t, 1, health.ConsecutiveFailures)
+	assert.Equal(t, HealthStatusUnhealthy, check().Status)
+	assert.Equal(t, "", lb.SelectTarget("svc"))
+
+	// 连续成功达到全局阈值2次后恢复
+	healthy.Store(true)
+	assert.Equal(t, HealthStatusUnhealthy, check().Status)
+	assert.Equal(t, HealthStatusHealthy, check().Status)
+	assert.Equal(t, backend.URL, lb.SelectTarget("svc"))
+
+	group := lb.GetStats()["svc"].(map[string]interface{})
+	target := group["targets"].([]*Target)[0]
+	require.NotNil(t, target.Health)
+	assert.Equal(t, int64(5), target.Health.CheckCount)
+	assert.Equal(t, int64(2), target.Health.FailCount)
+}
+
+func TestPerformHealthChecksSchedulesByInterval(t *testing.T) {
+	sd := NewServiceDiscovery(&HealthCheckConfig{}, zap.NewNop())
+	sd.RegisterService(&ServiceInfo{ID: "off", URL: "http://127.0.0.1:1"})
+	sd.RegisterService(&ServiceInfo{ID: "on", URL: "http://127.0.0.1:1", HealthCheck: HealthCheckConfig{
+		Enabled: true, Interval: time.Minute, Timeout: 10 * time.Millisecond, Path: "/", Method: "GET",
+		HealthyThreshold: 1, UnhealthyThreshold: 1,
+	}})
+
+	now := time.Now()
+	sd.performHealthChecks(now)
+	on, _ := sd.GetService("on")
+	off, _ := sd.GetService("off")
+
+	sd.mutex.RLock()
+	assert.Equal(t, now.Add(time.Minute), on.nextCheck)
+	assert.True(t, off.nextCheck.IsZero())
+	sd.mutex.RUnlock()
+
+	// 未到期不重复检查
+	sd.performHealthChecks(now.Add(time.Second))
+	sd.mutex.RLock()
+	assert.Equal(t, now.Add(time.Minute), on.nextCheck)
+	sd.mutex.RUnlock()
+}
@@ -0,0 +1,423 @@
+package gateway
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 降级响应的来源
+const (
+	FallbackModeStatic = "static" // 返回配置的静态内容
+	FallbackModeCache  = "cache"  // 返回该请求上次成功的响应，没有缓存时返回静态内容
+)
+
+// 触发降级的原因
+const (
+	FallbackReasonUpstreamStatus = "upstream_status" // 后端返回触发降级的状态码
+	FallbackReasonUpstreamError  = "upstream_error"  // 后端不可达或超时
+	FallbackReasonCircuitOpen    = "circuit_open"    // 熔断器打开，未转发到后端
+)
+
+// 降级响应的标记头
+const (
+	FallbackHeader       = "X-Gateway-Fallback"
+	FallbackReasonHeader = "X-Gateway-Fallback-Reason"
+)
+
+const (
+	defaultFallbackContentType = "application/json"
+	defaultFallbackBody        = `{"error":"service degraded","fallback":true}`
+	defaultFallbackCacheTTL    = 10 * time.Minute
+	// maxFallbackCacheBodySize 超过该大小的响应不缓存
+	maxFallbackCacheBodySize = 1 << 20
+	// maxFallbackCacheEntries 单个路由最多缓存的响应数
+	maxFallbackCacheEntries = 1000
+
+	defaultBreakerFailureThreshold = 5
+	defaultBreakerOpenTimeout      = 30 * time.Second
+)
+
+// FallbackPolicy 路由降级策略，后端不可用或返回5xx时用降级内容代替原始错误
+type FallbackPolicy struct {
+	Enabled bool   `json:"enabled" yaml:"enabled"`
+	Mode    string `json:"mode" yaml:"mode"` // static, cache，默认static
+	// Status 静态降级内容的状态码，默认200，便于前端按正常数据渲染
+	Status      int    `json:"status,omitempty" yaml:"status"`
+	ContentType string `json:"content_type,omitempty" yaml:"content_type"`
+	Body        string `json:"body,omitempty" yaml:"body"`
+	// CacheTTL cache模式下成功响应可用于降级的时长，默认10分钟
+	CacheTTL time.Duration `json:"cache_ttl,omitempty" yaml:"cache_ttl"`
+	// StatusCodes 触发降级的后端状态码，为空时为全部5xx
+	StatusCodes []int `json:"status_codes,omitempty" yaml:"status_codes"`
+}
+
+// active 是否启用降级
+func (p *FallbackPolicy) active() bool {
+	return p != nil && p.Enabled
+}
+
+// triggers 后端状态码是否触发降级
+func (p *FallbackPolicy) triggers(code int) bool {
+	if len(p.StatusCodes) == 0 {
+		return code >= 500
+	}
+	for _, c := range p.StatusCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// caching 是否缓存成功响应
+func (p *FallbackPolicy) caching() bool {
+	return p.active() && p.Mode == FallbackModeCache
+}
+
+func (p *FallbackPolicy) cacheTTL() time.Duration {
+	if p.CacheTTL > 0 {
+		return p.CacheTTL
+	}
+	return defaultFallbackCacheTTL
+}
+
+// static 静态降级响应
+func (p *FallbackPolicy) static() *cachedResponse {
+	status, contentType, body := p.Status, p.ContentType, p.Body
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if contentType == "" {
+		contentType = defaultFallbackContentType
+	}
+	if body == "" {
+		body = defaultFallbackBody
+	}
+	header := make(http.Header)
+	header.Set("Content-Type", contentType)
+	return &cachedResponse{status: status, header: header, body: []byte(body)}
+}
+
+// validate 检查降级策略
+func (p *FallbackPolicy) validate() error {
+	if p == nil {
+		return nil
+	}
+	if p.Mode != "" && p.Mode != FallbackModeStatic && p.Mode != FallbackModeCache {
+		return fmt.Errorf("invalid fallback mode: %s", p.Mode)
+	}
+	if p.Status != 0 && (p.Status < 100 || p.Status > 599) {
+		return fmt.Errorf("invalid fallback status: %d", p.Status)
+	}
+	if p.CacheTTL < 0 {
+		return fmt.Errorf("fallback cache_ttl must not be negative")
+	}
+	if p.Body != "" && (p.ContentType == "" || p.ContentType == defaultFallbackContentType) && !json.Valid([]byte(p.Body)) {
+		return fmt.Errorf("fallback body must be valid JSON")
+	}
+	return nil
+}
+
+// CircuitBreakerPolicy 路由熔断策略，连续失败达到阈值后打开，OpenTimeout后放行一个探测请求
+type CircuitBreakerPolicy struct {
+	Enabled          bool          `json:"enabled" yaml:"enabled"`
+	FailureThreshold int           `json:"failure_threshold" yaml:"failure_threshold"` // 默认5
+	OpenTimeout      time.Duration `json:"open_timeout" yaml:"open_timeout"`           // 默认30s
+}
+
+func (p *CircuitBreakerPolicy) active() bool {
+	return p != nil && p.Enabled
+}
+
+// validate 检查熔断策略
+func (p *CircuitBreakerPolicy) validate() error {
+	if p == nil {
+		return nil
+	}
+	if p.FailureThreshold < 0 || p.OpenTimeout < 0 {
+		return fmt.Errorf("circuit breaker failure_threshold and open_timeout must not be negative")
+	}
+	return nil
+}
+
+// 熔断器状态
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+// circuitBreaker 单个路由的熔断器
+type circuitBreaker struct {
+	mutex     sync.Mutex
+	state     string
+	failures  int
+	openedAt  time.Time
+	probing   bool
+	probeAt   time.Time
+	threshold int
+	timeout   time.Duration
+}
+
+func newCircuitBreaker(policy *CircuitBreakerPolicy) *circuitBreaker {
+	b := &circuitBreaker{
+		state:     BreakerClosed,
+		threshold: defaultBreakerFailureThreshold,
+		timeout:   defaultBreakerOpenTimeout,
+	}
+	if policy.FailureThreshold > 0 {
+		b.threshold = policy.FailureThreshold
+	}
+	if policy.OpenTimeout > 0 {
+		b.timeout = policy.OpenTimeout
+	}
+	return b
+}
+
+// allow 是否放行请求。打开超过timeout后进入半开状态只放行一个探测请求，
+// 探测请求超过timeout仍无结果（如客户端中断）时允许下一个请求继续探测
+func (b *circuitBreaker) allow(now time.Time) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch b.state {
+	case BreakerClosed:
+		return true
+	case BreakerOpen:
+		if now.Sub(b.openedAt) < b.timeout {
+			return false
+		}
+		b.state = BreakerHalfOpen
+	}
+	if b.probing && now.Sub(b.probeAt) < b.timeout {
+		return false
+	}
+	b.probing = true
+	b.probeAt = now
+	return true
+}
+
+// record 记录请求结果：成功时关闭熔断器，失败次数达到阈值或探测失败时打开
+func (b *circuitBreaker) record(success bool, now time.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.probing = false
+	if success {
+		b.state = BreakerClosed
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.state = BreakerOpen
+		b.openedAt = now
+	}
+}
+
+// snapshot 当前状态和连续失败次数
+func (b *circuitBreaker) snapshot() (string, int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.state, b.failures
+}
+
+// cachedResponse 用于降级的响应
+type cachedResponse struct {
+	status   int
+	header   http.Header
+	body     []byte
+	storedAt time.Time
+}
+
+// responseCache 单个路由成功响应的缓存，按请求方法、URL和调用方区分
+type responseCache struct {
+	mutex   sync.RWMutex
+	entries map[string]*cachedResponse
+}
+
+func newResponseCache() *responseCache {
+	return &responseCache{entries: make(map[string]*cachedResponse)}
+}
+
+// responseCacheKey 降级缓存键，userID为网关认证的用户，为空时按请求携带的Authorization和Cookie区分调用方，
+// 避免一个用户的响应在降级时返回给其他用户
+func responseCacheKey(req *http.Request, userID string) string {
+	key := req.Method + " " + req.URL.RequestURI()
+	if userID != "" {
+		return key + " user:" + userID
+	}
+
+	authorization, cookies := req.Header.Values("Authorization"), req.Header.Values("Cookie")
+	if len(authorization) == 0 && len(cookies) == 0 {
+		return key
+	}
+	h := sha256.New()
+	for _, v := range authorization {
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	}
+	h.Write([]byte{1})
+	for _, v := range cookies {
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	}
+	return key + " credential:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// cacheable 响应是否允许被网关缓存：Cache-Control为no-store或private时不缓存
+func cacheable(header http.Header) bool {
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			directive = strings.ToLower(strings.TrimSpace(directive))
+			if directive == "no-store" || directive == "private" || strings.HasPrefix(directive, "private=") {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// get 取未过期的缓存响应
+func (c *responseCache) get(key string, ttl time.Duration, now time.Time) *cachedResponse {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	entry, ok := c.entries[key]
+	if !ok || now.Sub(entry.storedAt) > ttl {
+		return nil
+	}
+	return entry
+}
+
+// put 缓存响应，缓存已满时先清理过期条目，仍满则不缓存新的URL
+func (c *responseCache) put(key string, entry *cachedResponse, ttl time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= maxFallbackCacheEntries {
+		for k, e := range c.entries {
+			if entry.storedAt.Sub(e.storedAt) > ttl {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxFallbackCacheEntries {
+			return
+		}
+	}
+	c.entries[key] = entry
+}
+
+// routeResilience 路由的熔断器和降级缓存，路由更新或删除时重建
+type routeResilience struct {
+	breaker *circuitBreaker
+	cache   *responseCache
+}
+
+// newRouteResilience 按路由配置创建熔断器和降级缓存，未启用的部分为nil
+func newRouteResilience(route *Route) *routeResilience {
+	state := &routeResilience{}
+	if route.CircuitBreaker.active() {
+		state.breaker = newCircuitBreaker(route.CircuitBreaker)
+	}
+	if route.Fallback.caching() {
+		state.cache = newResponseCache()
+	}
+	return state
+}
+
+// resilience 获取路由的熔断器和降级缓存，路由已被替换或删除时返回空状态
+func (r *Router) resilience(route *Route) *routeResilience {
+	r.resilienceMutex.Lock()
+	defer r.resilienceMutex.Unlock()
+
+	if state, ok := r.resilienceStates[route]; ok {
+		return state
+	}
+	return &routeResilience{}
+}
+
+// addResilience 注册路由的熔断和缓存状态
+func (r *Router) addResilience(route *Route) {
+	r.resilienceMutex.Lock()
+	defer r.resilienceMutex.Unlock()
+	r.resilienceStates[route] = newRouteResilience(route)
+}
+
+// dropResilience 路由被替换或删除时丢弃其熔断和缓存状态
+func (r *Router) dropResilience(route *Route) {
+	r.resilienceMutex.Lock()
+	defer r.resilienceMutex.Unlock()
+	delete(r.resilienceStates, route)
+}
+
+// fallbackResponse 请求对应的降级响应，cache模式优先使用未过期的缓存
+func (r *Router) fallbackResponse(route *Route, cacheKey string) (*cachedResponse, string) {
+	if cache := r.resilience(route).cache; cache != nil {
+		if entry := cache.get(cacheKey, route.Fallback.cacheTTL(), time.Now()); entry != nil {
+			return entry, FallbackModeCache
+		}
+	}
+	return route.Fallback.static(), FallbackModeStatic
+}
+
+// cacheResponse 缓存GET请求的2xx响应，响应体读出后放回供代理继续写出
+func (r *Router) cacheResponse(route *Route, cacheKey string, resp *http.Response) {
+	cache := r.resilience(route).cache
+	if cache == nil || resp.Request.Method != http.MethodGet || resp.StatusCode < 200 || resp.StatusCode >= 300 || !cacheable(resp.Header) {
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFallbackCacheBodySize+1))
+	if err != nil || len(data) > maxFallbackCacheBodySize {
+		resp.Body = readCloser{io.MultiReader(bytes.NewReader(data), resp.Body), resp.Body}
+		return
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+
+	// 降级时返回的缓存不能为调用方设置会话
+	header := resp.Header.Clone()
+	header.Del("Set-Cookie")
+	cache.put(cacheKey, &cachedResponse{
+		status:   resp.StatusCode,
+		header:   header,
+		body:     data,
+		storedAt: time.Now(),
+	}, route.Fallback.cacheTTL())
+}
+
+// replaceWithFallback 用降级内容替换后端响应
+func replaceWithFallback(resp *http.Response, fallback *cachedResponse, source, reason string) {
+	resp.Body.Close()
+	resp.StatusCode = fallback.status
+	resp.Status = fmt.Sprintf("%d %s", fallback.status, http.StatusText(fallback.status))
+	resp.Header = fallback.header.Clone()
+	resp.Header.Set(FallbackHeader, source)
+	resp.Header.Set(FallbackReasonHeader, reason)
+	resp.Header.Set("Content-Length", strconv.Itoa(len(fallback.body)))
+	resp.ContentLength = int64(len(fallback.body))
+	resp.Body = io.NopCloser(bytes.NewReader(fallback.body))
+}
+
+// writeFallback 直接写出降级内容
+func writeFallback(w http.ResponseWriter, fallback *cachedResponse, source, reason string) {
+	header := w.Header()
+	for name, values := range fallback.header {
+		header[name] = append([]string(nil), values...)
+	}
+	header.Set(FallbackHeader, source)
+	header.Set(FallbackReasonHeader, reason)
+	header.Set("Content-Length", strconv.Itoa(len(fallback.body)))
+	w.WriteHeader(fallback.status)
+	w.Write(fallback.body)
+}
@@ -0,0 +1,227 @@
+package gateway
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func getWithHeaders(t *testing.T, url string) (*http.Response, string) {
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp, string(data)
+}
+
+func TestRouteFallback(t *testing.T) {
+	var failing int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("stack trace"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":"` + r.URL.Query().Get("q") + `"}`))
+	}))
+	defer backend.Close()
+
+	router := NewRouter(zap.NewNop(), nil, nil)
+	require.NoError(t, router.AddRoute(&Route{
+		ID: "static", Path: "/static/*", Method: "GET", Target: backend.URL,
+		Fallback: &FallbackPolicy{Enabled: true, Body: `{"data":[]}`},
+	}))
+	require.NoError(t, router.AddRoute(&Route{
+		ID: "cache", Path: "/cache/*", Method: "GET", Target: backend.URL, StripPrefix: true,
+		Fallback: &FallbackPolicy{Enabled: true, Mode: FallbackModeCache, Body: `{"data":null}`},
+	}))
+	require.NoError(t, router.AddRoute(&Route{
+		ID: "disabled", Path: "/disabled/*", Method: "GET", Target: backend.URL,
+		Fallback: &FallbackPolicy{Enabled: false, Body: `{"data":[]}`},
+	}))
+	gateway := newGatewayServer(router)
+	defer gateway.Close()
+
+	resp, body := getWithHeaders(t, gateway.URL+"/cache/items?q=a")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `{"data":"a"}`, body)
+	assert.Empty(t, resp.Header.Get(FallbackHeader))
+
+	atomic.StoreInt32(&failing, 1)
+
+	resp, body = getWithHeaders(t, gateway.URL+"/static/items")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `{"data":[]}`, body)
+	assert.Equal(t, FallbackModeStatic, resp.Header.Get(FallbackHeader))
+	assert.Equal(t, FallbackReasonUpstreamStatus, resp.Header.Get(FallbackReasonHeader))
+	assert.Equal(t, "static", resp.Header.Get("X-Gateway-Route"))
+
+	// 缓存按入口URL区分，未缓存过的URL返回静态内容
+	resp, body = getWithHeaders(t, gateway.URL+"/cache/items?q=a")
+	assert.Equal(t, `{"data":"a"}`, body)
+	assert.Equal(t, FallbackModeCache, resp.Header.Get(FallbackHeader))
+	resp, body = getWithHeaders(t, gateway.URL+"/cache/items?q=b")
+	assert.Equal(t, `{"data":null}`, body)
+	assert.Equal(t, FallbackModeStatic, resp.Header.Get(FallbackHeader))
+
+	resp, body = getWithHeaders(t, gateway.URL+"/disabled/items")
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal(t, "stack trace", body)
+
+	// 后端不可达
+	backend.Close()
+	resp, body = getWithHeaders(t, gateway.URL+"/static/items")
+	assert.Equal(t, `{"data":[]}`, body)
+	assert.Equal(t, FallbackReasonUpstreamError, resp.Header.Get(FallbackReasonHeader))
+
+	assert.Error(t, router.AddRoute(&Route{ID: "bad", Path: "/bad", Method: "GET", Target: backend.URL,
+		Fallback: &FallbackPolicy{Enabled: true, Body: "not json"}}))
+}
+
+func getAs(t *testing.T, url, authorization string) (*http.Response, string) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", authorization)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp, string(data)
+}
+
+func TestRouteFallbackCachePerPrincipal(t *testing.T) {
+	var failing int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if r.URL.Path == "/private" {
+			w.Header().Set("Cache-Control", "private, max-age=60")
+		}
+		w.Header().Set("Set-Cookie", "session="+r.Header.Get("Authorization"))
+		w.Write([]byte(`{"user":"` + r.Header.Get("Authorization") + `"}`))
+	}))
+	defer backend.Close()
+
+	router := NewRouter(zap.NewNop(), nil, nil)
+	require.NoError(t, router.AddRoute(&Route{
+		ID: "cache", Path: "/cache/*", Method: "GET", Target: backend.URL, StripPrefix: true,
+		Fallback: &FallbackPolicy{Enabled: true, Mode: FallbackModeCache, Body: `{"data":null}`},
+	}))
+	gateway := newGatewayServer(router)
+	defer gateway.Close()
+
+	_, body := getAs(t, gateway.URL+"/cache/profile", "alice")
+	assert.Equal(t, `{"user":"alice"}`, body)
+	_, body = getAs(t, gateway.URL+"/cache/private", "alice")
+	assert.Equal(t, `{"user":"alice"}`, body)
+
+	atomic.StoreInt32(&failing, 1)
+
+	// 缓存按调用方区分，且不带上游设置的Cookie
+	resp, body := getAs(t, gateway.URL+"/cache/profile", "alice")
+	assert.Equal(t, `{"user":"alice"}`, body)
+	assert.Equal(t, FallbackModeCache, resp.Header.Get(FallbackHeader))
+	assert.Empty(t, resp.Header.Values("Set-Cookie"))
+
+	resp, body = getAs(t, gateway.URL+"/cache/profile", "bob")
+	assert.Equal(t, `{"data":null}`, body)
+	assert.Equal(t, FallbackModeStatic, resp.Header.Get(FallbackHeader))
+
+	// Cache-Control: private 的响应不缓存
+	resp, body = getAs(t, gateway.URL+"/cache/private", "alice")
+	assert.Equal(t, `{"data":null}`, body)
+	assert.Equal(t, FallbackModeStatic, resp.Header.Get(FallbackHeader))
+}
+
+func TestResponseCacheKey(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/items?q=a", nil)
+	anonymous := responseCacheKey(req, "")
+	assert.Equal(t, "GET /items?q=a", anonymous)
+	assert.NotEqual(t, responseCacheKey(req, "1"), responseCacheKey(req, "2"))
+
+	req.Header.Set("Cookie", "session=a")
+	withCookie := responseCacheKey(req, "")
+	assert.NotEqual(t, anonymous, withCookie)
+	req.Header.Set("Cookie", "session=b")
+	assert.NotEqual(t, withCookie, responseCacheKey(req, ""))
+
+	assert.True(t, cacheable(http.Header{"Cache-Control": {"max-age=60"}}))
+	assert.False(t, cacheable(http.Header{"Cache-Control": {"public, No-Store"}}))
+	assert.False(t, cacheable(http.Header{"Cache-Control": {`private="Set-Cookie"`}}))
+}
+
+func TestRouteCircuitBreaker(t *testing.T) {
+	var hits int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer backend.Close()
+
+	router := NewRouter(zap.NewNop(), nil, nil)
+	breaker := &CircuitBreakerPolicy{Enabled: true, FailureThreshold: 2, OpenTimeout: time.Minute}
+	require.NoError(t, router.AddRoute(&Route{
+		ID: "degraded", Path: "/degraded", Method: "GET", Target: backend.URL,
+		Fallback: &FallbackPolicy{Enabled: true}, CircuitBreaker: breaker,
+	}))
+	require.NoError(t, router.AddRoute(&Route{
+		ID: "plain", Path: "/plain", Method: "GET", Target: backend.URL, CircuitBreaker: breaker,
+	}))
+	gateway := newGatewayServer(router)
+	defer gateway.Close()
+
+	for i := 0; i < 3; i++ {
+		resp, body := getWithHeaders(t, gateway.URL+"/degraded")
+		assert.Equal(t, defaultFallbackBody, body)
+		if i < 2 {
+			assert.Equal(t, FallbackReasonUpstreamStatus, resp.Header.Get(FallbackReasonHeader))
+		} else {
+			assert.Equal(t, FallbackReasonCircuitOpen, resp.Header.Get(FallbackReasonHeader))
+		}
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&hits))
+
+	for i := 0; i < 3; i++ {
+		getWithHeaders(t, gateway.URL+"/plain")
+	}
+	resp, body := getWithHeaders(t, gateway.URL+"/plain")
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Contains(t, body, "circuit open")
+	assert.Equal(t, int32(4), atomic.LoadInt32(&hits))
+
+	breakers := router.GetMetrics()["circuit_breakers"].(map[string]interface{})
+	assert.Equal(t, BreakerOpen, breakers["degraded"].(map[string]interface{})["state"])
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	b := newCircuitBreaker(&CircuitBreakerPolicy{Enabled: true, FailureThreshold: 1, OpenTimeout: time.Second})
+	now := time.Now()
+
+	assert.True(t, b.allow(now))
+	b.record(false, now)
+	assert.False(t, b.allow(now.Add(500*time.Millisecond)))
+
+	// 半开状态只放行一个探测请求，探测失败重新打开
+	assert.True(t, b.allow(now.Add(time.Second)))
+	assert.False(t, b.allow(now.Add(time.Second)))
+	b.record(false, now.Add(time.Second))
+	assert.False(t, b.allow(now.Add(1500*time.Millisecond)))
+
+	// 探测成功后关闭
+	assert.True(t, b.allow(now.Add(2*time.Second)))
+	b.record(true, now.Add(2*time.Second))
+	state, failures := b.snapshot()
+	assert.Equal(t, BreakerClosed, state)
+	assert.Zero(t, failures)
+	assert.True(t, b.allow(now.Add(2*time.Second)))
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"hash/crc32"
 	"math/rand"
+	"net/url"
 	"sort"
 	"sync"
 	"time"
@@ -24,13 +25,15 @@ const (
 
 // Target 目标服务器
 type Target struct {
-	ID          string            `json:"id" yaml:"id"`
-	URL         string            `json:"url" yaml:"url"`
-	Weight      int               `json:"weight" yaml:"weight"`
-	Metadata    map[string]string `json:"metadata" yaml:"metadata"`
-	Connections int               `json:"connections"`
-	IsHealthy   bool              `json:"is_healthy"`
-	LastCheck   time.Time         `json:"last_check"`
+	ID          string             `json:"id" yaml:"id"`
+	URL         string             `json:"url" yaml:"url"`
+	Weight      int                `json:"weight" yaml:"weight"`
+	Metadata    map[string]string  `json:"metadata" yaml:"metadata"`
+	Connections int                `json:"connections"`
+	IsHealthy   bool               `json:"is_healthy"`
+	LastCheck   time.Time          `json:"last_check"`
+	HealthCheck *HealthCheckConfig `json:"health_check,omitempty"` // 实际生效的健康检查配置
+	Health      *HealthStatus      `json:"health,omitempty"`       // 最近一次健康检查结果
 }
 
 // ServiceGroup 服务组
@@ -180,6 +183,21 @@ func (lb *LoadBalancer) RemoveTarget(groupID, targetID string) error {
 
 // SelectTarget 选择目标服务器
 func (lb *LoadBalancer) SelectTarget(groupID string) string {
+	return lb.SelectTargetExcept(groupID, nil)
+}
+
+// hasGroup 服务组是否存在
+func (lb *LoadBalancer) hasGroup(groupID string) bool {
+	lb.mutex.RLock()
+	defer lb.mutex.RUnlock()
+
+	_, exists := lb.groups[groupID]
+	return exists
+}
+
+// SelectTargetExcept 选择目标服务器，优先避开已失败的目标（scheme://host）；
+// 除此之外没有健康目标时仍从全部健康目标中选择
+func (lb *LoadBalancer) SelectTargetExcept(groupID string, exclude []string) string {
 	lb.mutex.RLock()
 	group, exists := lb.groups[groupID]
 	lb.mutex.RUnlock()
@@ -205,6 +223,18 @@ func (lb *LoadBalancer) SelectTarget(groupID string) string {
 		return ""
 	}
 
+	if len(exclude) > 0 {
+		remaining := make([]*Target, 0, len(healthyTargets))
+		for _, target := range healthyTargets {
+			if !targetExcluded(exclude, target.URL) {
+				remaining = append(remaining, target)
+			}
+		}
+		if len(remaining) > 0 {
+			healthyTargets = remaining
+		}
+	}
+
 	var selected *Target
 
 	switch group.Strategy {
@@ -400,6 +430,45 @@ func (lb *LoadBalancer) UpdateTargetHealth(groupID, targetID string, isHealthy b
 	}
 }
 
+// ApplyHealthStatus 同步健康检查结果：状态变为healthy/unhealthy时自动上下线目标，unknown时保持原状
+func (lb *LoadBalancer) ApplyHealthStatus(groupID, targetID string, health HealthStatus) {
+	lb.mutex.RLock()
+	group, exists := lb.groups[groupID]
+	lb.mutex.RUnlock()
+
+	if !exists {
+		return
+	}
+
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+
+	for _, target := range group.Targets {
+		if target.ID != targetID {
+			continue
+		}
+		target.Health = &health
+		target.LastCheck = health.LastCheck
+
+		wasHealthy := target.IsHealthy
+		switch health.Status {
+		case HealthStatusHealthy:
+			target.IsHealthy = true
+		case HealthStatusUnhealthy:
+			target.IsHealthy = false
+		}
+		if target.IsHealthy != wasHealthy {
+			lb.logger.Warn("Target health changed by health check",
+				zap.String("group_id", groupID),
+				zap.String("target_id", targetID),
+				zap.Bool("is_healthy", target.IsHealthy),
+				zap.Int("consecutive_failures", health.ConsecutiveFailures),
+				zap.String("message", health.Message))
+		}
+		return
+	}
+}
+
 // GetStats 获取负载均衡统计信息
 func (lb *LoadBalancer) GetStats() map[string]interface{} {
 	lb.mutex.RLock()
@@ -429,4 +498,18 @@ func (lb *LoadBalancer) GetStats() map[string]interface{} {
 	}
 
 	return stats
-}
\ No newline at end of file
+}
+
+// targetExcluded 按scheme://host比较目标是否在排除列表中
+func targetExcluded(exclude []string, rawURL string) bool {
+	origin := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		origin = targetOrigin(u)
+	}
+	for _, v := range exclude {
+		if v == origin {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,200 @@
+package metrics
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// latencyBuckets 分维度时延直方图的桶上限(秒)，与Prometheus直方图一致
+var latencyBuckets = prometheus.DefBuckets
+
+// seriesKey 路由、后端target和状态码组成的统计维度
+type seriesKey struct {
+	route  string
+	target string
+	status int
+}
+
+// seriesStats 单个维度组合的累计统计
+type seriesStats struct {
+	count   int64
+	total   time.Duration
+	max     time.Duration
+	buckets []int64 // 各桶内（非累计）的请求数，最后一个为超出最大桶上限的请求
+}
+
+// LatencyBucket 时延直方图的一个桶，Count为时延不超过LE秒的累计请求数
+type LatencyBucket struct {
+	LE    float64 `json:"le"`
+	Count int64   `json:"count"`
+}
+
+// SeriesMetrics 路由+target+状态码维度的请求量与时延
+type SeriesMetrics struct {
+	Route        string          `json:"route"`
+	Target       string          `json:"target"`
+	Status       int             `json:"status"`
+	Count        int64           `json:"count"`
+	AvgLatencyMs float64         `json:"avg_latency_ms"`
+	MaxLatencyMs float64         `json:"max_latency_ms"`
+	Buckets      []LatencyBucket `json:"buckets"`
+}
+
+// DimensionMetrics 按单个维度（路由或target）汇总的请求量、错误数和时延
+type DimensionMetrics struct {
+	Name         string           `json:"name"`
+	Count        int64            `json:"count"`
+	ClientErrors int64            `json:"client_errors"`
+	ServerErrors int64            `json:"server_errors"`
+	ErrorRate    float64          `json:"error_rate"` // 5xx占比
+	AvgLatencyMs float64          `json:"avg_latency_ms"`
+	MaxLatencyMs float64          `json:"max_latency_ms"`
+	StatusCounts map[string]int64 `json:"status_counts"`
+}
+
+// Breakdown 分维度统计快照
+type Breakdown struct {
+	Routes  []DimensionMetrics `json:"routes"`
+	Targets []DimensionMetrics `json:"targets"`
+	Series  []SeriesMetrics    `json:"series"`
+}
+
+// breakdownRecorder 按路由、target、状态码累计代理请求，供管理接口返回结构化统计
+type breakdownRecorder struct {
+	mutex  sync.Mutex
+	series map[seriesKey]*seriesStats
+}
+
+func newBreakdownRecorder() *breakdownRecorder {
+	return &breakdownRecorder{series: make(map[seriesKey]*seriesStats)}
+}
+
+// observe 记录一次代理请求
+func (b *breakdownRecorder) observe(route, target string, status int, duration time.Duration) {
+	key := seriesKey{route: route, target: target, status: status}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	stats, ok := b.series[key]
+	if !ok {
+		stats = &seriesStats{buckets: make([]int64, len(latencyBuckets)+1)}
+		b.series[key] = stats
+	}
+	stats.count++
+	stats.total += duration
+	if duration > stats.max {
+		stats.max = duration
+	}
+	stats.buckets[sort.SearchFloat64s(latencyBuckets, duration.Seconds())]++
+}
+
+// reset 清空累计统计
+func (b *breakdownRecorder) reset() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.series = make(map[seriesKey]*seriesStats)
+}
+
+// snapshot 生成分维度统计，路由和target按请求量降序，明细按路由、target、状态码排序
+func (b *breakdownRecorder) snapshot() *Breakdown {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	routes := make(map[string]*dimensionAccumulator)
+	targets := make(map[string]*dimensionAccumulator)
+	series := make([]SeriesMetrics, 0, len(b.series))
+
+	for key, stats := range b.series {
+		buckets := make([]LatencyBucket, len(latencyBuckets))
+		var cumulative int64
+		for i, le := range latencyBuckets {
+			cumulative += stats.buckets[i]
+			buckets[i] = LatencyBucket{LE: le, Count: cumulative}
+		}
+		series = append(series, SeriesMetrics{
+			Route:        key.route,
+			Target:       key.target,
+			Status:       key.status,
+			Count:        stats.count,
+			AvgLatencyMs: durationMs(stats.total) / float64(stats.count),
+			MaxLatencyMs: durationMs(stats.max),
+			Buckets:      buckets,
+		})
+
+		accumulate(routes, key.route, key.status, stats)
+		accumulate(targets, key.target, key.status, stats)
+	}
+
+	sort.Slice(series, func(i, j int) bool {
+		a, b := series[i], series[j]
+		if a.Route != b.Route {
+			return a.Route < b.Route
+		}
+		if a.Target != b.Target {
+			return a.Target < b.Target
+		}
+		return a.Status < b.Status
+	})
+
+	return &Breakdown{
+		Routes:  dimensionMetrics(routes),
+		Targets: dimensionMetrics(targets),
+		Series:  series,
+	}
+}
+
+// dimensionAccumulator 汇总单个维度时的中间结果
+type dimensionAccumulator struct {
+	metrics DimensionMetrics
+	total   time.Duration
+	max     time.Duration
+}
+
+func accumulate(dims map[string]*dimensionAccumulator, name string, status int, stats *seriesStats) {
+	acc, ok := dims[name]
+	if !ok {
+		acc = &dimensionAccumulator{metrics: DimensionMetrics{Name: name, StatusCounts: make(map[string]int64)}}
+		dims[name] = acc
+	}
+	acc.metrics.Count += stats.count
+	acc.metrics.StatusCounts[strconv.Itoa(status)] += stats.count
+	switch {
+	case status >= 500:
+		acc.metrics.ServerErrors += stats.count
+	case status >= 400:
+		acc.metrics.ClientErrors += stats.count
+	}
+	acc.total += stats.total
+	if stats.max > acc.max {
+		acc.max = stats.max
+	}
+}
+
+func dimensionMetrics(dims map[string]*dimensionAccumulator) []DimensionMetrics {
+	result := make([]DimensionMetrics, 0, len(dims))
+	for _, acc := range dims {
+		m := acc.metrics
+		if m.Count > 0 {
+			m.ErrorRate = float64(m.ServerErrors) / float64(m.Count)
+			m.AvgLatencyMs = durationMs(acc.total) / float64(m.Count)
+		}
+		m.MaxLatencyMs = durationMs(acc.max)
+		result = append(result, m)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
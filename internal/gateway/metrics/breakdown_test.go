@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreakdownRecorder(t *testing.T) {
+	b := newBreakdownRecorder()
+	b.observe("api", "http://a:8080", 200, 10*time.Millisecond)
+	b.observe("api", "http://a:8080", 200, 30*time.Millisecond)
+	b.observe("api", "http://b:8080", 502, 2*time.Second)
+	b.observe("files", "http://b:8080", 404, 5*time.Millisecond)
+
+	snapshot := b.snapshot()
+
+	require.Len(t, snapshot.Series, 3)
+	first := snapshot.Series[0]
+	assert.Equal(t, "api", first.Route)
+	assert.Equal(t, "http://a:8080", first.Target)
+	assert.Equal(t, 200, first.Status)
+	assert.Equal(t, int64(2), first.Count)
+	assert.InDelta(t, 20, first.AvgLatencyMs, 0.001)
+	assert.InDelta(t, 30, first.MaxLatencyMs, 0.001)
+	// 10ms落在0.01桶，30ms落在0.05桶，直方图为累计计数
+	assert.Equal(t, LatencyBucket{LE: 0.01, Count: 1}, first.Buckets[1])
+	assert.Equal(t, LatencyBucket{LE: 0.05, Count: 2}, first.Buckets[3])
+	assert.Equal(t, int64(2), first.Buckets[len(first.Buckets)-1].Count)
+
+	require.Len(t, snapshot.Routes, 2)
+	api := snapshot.Routes[0]
+	assert.Equal(t, "api", api.Name)
+	assert.Equal(t, int64(3), api.Count)
+	assert.Equal(t, int64(1), api.ServerErrors)
+	assert.InDelta(t, 1.0/3, api.ErrorRate, 0.0001)
+	assert.Equal(t, map[string]int64{"200": 2, "502": 1}, api.StatusCounts)
+
+	require.Len(t, snapshot.Targets, 2)
+	slow := snapshot.Targets[1]
+	assert.Equal(t, "http://b:8080", slow.Name)
+	assert.Equal(t, int64(1), slow.ClientErrors)
+	assert.Equal(t, int64(1), slow.ServerErrors)
+	assert.InDelta(t, 2000, slow.MaxLatencyMs, 0.001)
+
+	b.reset()
+	assert.Empty(t, b.snapshot().Series)
+}
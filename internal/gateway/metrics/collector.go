@@ -5,6 +5,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/env-data-platform/internal/gateway"
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -28,6 +29,11 @@ type Collector struct {
 	upstreamDuration  *prometheus.HistogramVec
 	upstreamStatus    *prometheus.CounterVec
 
+	// 按路由、后端target、状态码细分的代理请求指标
+	routeRequests     *prometheus.CounterVec
+	routeDuration     *prometheus.HistogramVec
+	breakdown         *breakdownRecorder
+
 	// 连接指标
 	activeConnections prometheus.Gauge
 	connectionErrors  *prometheus.CounterVec
@@ -120,6 +126,22 @@ func NewCollector(logger *zap.Logger) *Collector {
 			},
 			[]string{"upstream", "method", "path", "status"},
 		),
+		routeRequests: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_route_requests_total",
+				Help: "Total number of proxied requests by route, upstream target and status",
+			},
+			[]string{"route", "target", "status"},
+		),
+		routeDuration: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "gateway_route_request_duration_seconds",
+				Help:    "Proxied request duration in seconds by route, upstream target and status",
+				Buckets: latencyBuckets,
+			},
+			[]string{"route", "target", "status"},
+		),
+		breakdown: newBreakdownRecorder(),
 		activeConnections: promauto.NewGauge(
 			prometheus.GaugeOpts{
 				Name: "gateway_active_connections",
@@ -220,6 +242,11 @@ func (c *Collector) Middleware() gin.HandlerFunc {
 			UserID:       userID,
 		})
 
+		// 代理请求按路由、target、状态码细分，管理接口和未匹配路由的请求不计入
+		if routeID := ctx.GetString(gateway.ContextKeyRouteID); routeID != "" {
+			c.recordRouteRequest(routeID, ctx.GetString(gateway.ContextKeyUpstream), statusCode, duration)
+		}
+
 		c.logger.Debug("Request processed",
 			zap.String("method", ctx.Request.Method),
 			zap.String("path", path),
@@ -268,6 +295,19 @@ func (c *Collector) recordRequest(metrics *RequestMetrics) {
 	}
 }
 
+// recordRouteRequest 记录代理请求的分维度指标
+func (c *Collector) recordRouteRequest(route, target string, status int, duration time.Duration) {
+	statusStr := strconv.Itoa(status)
+	c.routeRequests.WithLabelValues(route, target, statusStr).Inc()
+	c.routeDuration.WithLabelValues(route, target, statusStr).Observe(duration.Seconds())
+	c.breakdown.observe(route, target, status, duration)
+}
+
+// GetBreakdown 获取按路由、后端target、状态码细分的请求量与时延统计
+func (c *Collector) GetBreakdown() *Breakdown {
+	return c.breakdown.snapshot()
+}
+
 // RecordUpstreamRequest 记录上游请求指标
 func (c *Collector) RecordUpstreamRequest(upstream, method, path string, status int, duration time.Duration) {
 	statusStr := strconv.Itoa(status)
@@ -394,6 +434,9 @@ func (c *Collector) ResetMetrics() {
 	c.errorsTotal.Reset()
 	c.upstreamDuration.Reset()
 	c.upstreamStatus.Reset()
+	c.routeRequests.Reset()
+	c.routeDuration.Reset()
+	c.breakdown.reset()
 	c.connectionErrors.Reset()
 	c.rateLimitHits.Reset()
 	c.rateLimitRemaining.Reset()
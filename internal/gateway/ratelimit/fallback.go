@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// FallbackLimiter Redis限流器出错时改用相同语义的内存限流器，Redis恢复后切回。
+// 两者计数不共享，切换时窗口内已有的计数从零开始
+type FallbackLimiter struct {
+	primary   RateLimiter
+	secondary RateLimiter
+	logger    *zap.Logger
+	degraded  int32 // 1表示当前使用内存限流器
+}
+
+// NewFallbackLimiter 创建带回退的限流器
+func NewFallbackLimiter(primary, secondary RateLimiter, logger *zap.Logger) *FallbackLimiter {
+	return &FallbackLimiter{
+		primary:   primary,
+		secondary: secondary,
+		logger:    logger,
+	}
+}
+
+// Allow 检查是否允许请求
+func (f *FallbackLimiter) Allow(ctx context.Context, key string) (bool, error) {
+	allowed, err := f.primary.Allow(ctx, key)
+	if err == nil {
+		f.markRecovered()
+		return allowed, nil
+	}
+	f.markDegraded(err)
+	return f.secondary.Allow(ctx, key)
+}
+
+// GetStats 获取统计信息，取当前生效的限流器
+func (f *FallbackLimiter) GetStats(ctx context.Context, key string) (*LimitStats, error) {
+	if !f.Degraded() {
+		if stats, err := f.primary.GetStats(ctx, key); err == nil {
+			return stats, nil
+		}
+	}
+	return f.secondary.GetStats(ctx, key)
+}
+
+// Reset 同时重置两个限流器，Redis出错时仍返回错误
+func (f *FallbackLimiter) Reset(ctx context.Context, key string) error {
+	f.secondary.Reset(ctx, key)
+	return f.primary.Reset(ctx, key)
+}
+
+// Degraded 当前是否已回退到内存限流器
+func (f *FallbackLimiter) Degraded() bool {
+	return atomic.LoadInt32(&f.degraded) == 1
+}
+
+// markDegraded 切换到内存限流器，只在切换时记录日志
+func (f *FallbackLimiter) markDegraded(err error) {
+	if atomic.CompareAndSwapInt32(&f.degraded, 0, 1) {
+		f.logger.Warn("Redis rate limiter unavailable, falling back to in-memory rate limiting", zap.Error(err))
+	}
+}
+
+// markRecovered Redis恢复后切回
+func (f *FallbackLimiter) markRecovered() {
+	if atomic.CompareAndSwapInt32(&f.degraded, 1, 0) {
+		f.logger.Info("Redis rate limiter recovered")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -23,12 +24,14 @@ const (
 	FixedWindow   LimitStrategy = "fixed_window"
 )
 
-// LimitConfig 限流配置
+// LimitConfig 限流配置。Rate对所有策略都表示每秒请求数：令牌桶按Rate补充令牌、最多积累Burst个；
+// 滑动窗口和固定窗口每个Window内放行Rate×Window个请求，不使用Burst。
+// Redis与内存实现按相同语义计算，Redis不可用时回退到内存实现不改变放行结果
 type LimitConfig struct {
 	Strategy    LimitStrategy `json:"strategy" yaml:"strategy"`
 	Rate        int           `json:"rate" yaml:"rate"`           // 每秒请求数
-	Burst       int           `json:"burst" yaml:"burst"`         // 突发请求数
-	Window      time.Duration `json:"window" yaml:"window"`       // 时间窗口
+	Burst       int           `json:"burst" yaml:"burst"`         // 令牌桶容量，<=0时等于Rate
+	Window      time.Duration `json:"window" yaml:"window"`       // 窗口策略的时间窗口，<=0时为1秒
 	KeyFunc     KeyFunc       `json:"-" yaml:"-"`                 // 键生成函数
 	SkipFunc    SkipFunc      `json:"-" yaml:"-"`                 // 跳过函数
 	Message     string        `json:"message" yaml:"message"`     // 限流消息
@@ -66,14 +69,17 @@ type TokenBucketLimiter struct {
 	rate     rate.Limit
 	burst    int
 	logger   *zap.Logger
+	now      func() time.Time
 }
 
-// SlidingWindowLimiter 滑动窗口限流器
+// SlidingWindowLimiter 滑动窗口限流器，窗口内只记录放行的请求
 type SlidingWindowLimiter struct {
+	seq      uint64 // 同一纳秒内的请求用序号区分成员，放在首位保证原子操作对齐
 	redis    *redis.Client
 	window   time.Duration
 	limit    int
 	logger   *zap.Logger
+	now      func() time.Time
 }
 
 // FixedWindowLimiter 固定窗口限流器
@@ -82,6 +88,7 @@ type FixedWindowLimiter struct {
 	window   time.Duration
 	limit    int
 	logger   *zap.Logger
+	now      func() time.Time
 }
 
 // NewTokenBucketLimiter 创建令牌桶限流器
@@ -89,8 +96,9 @@ func NewTokenBucketLimiter(rateLimit int, burst int, logger *zap.Logger) *TokenB
 	return &TokenBucketLimiter{
 		limiters: make(map[string]*rate.Limiter),
 		rate:     rate.Limit(rateLimit),
-		burst:    burst,
+		burst:    normalizeBurst(rateLimit, burst),
 		logger:   logger,
+		now:      time.Now,
 	}
 }
 
@@ -98,9 +106,10 @@ func NewTokenBucketLimiter(rateLimit int, burst int, logger *zap.Logger) *TokenB
 func NewSlidingWindowLimiter(redis *redis.Client, window time.Duration, limit int, logger *zap.Logger) *SlidingWindowLimiter {
 	return &SlidingWindowLimiter{
 		redis:  redis,
-		window: window,
+		window: normalizeWindow(window),
 		limit:  limit,
 		logger: logger,
+		now:    time.Now,
 	}
 }
 
@@ -108,9 +117,10 @@ func NewSlidingWindowLimiter(redis *redis.Client, window time.Duration, limit in
 func NewFixedWindowLimiter(redis *redis.Client, window time.Duration, limit int, logger *zap.Logger) *FixedWindowLimiter {
 	return &FixedWindowLimiter{
 		redis:  redis,
-		window: window,
+		window: normalizeWindow(window),
 		limit:  limit,
 		logger: logger,
+		now:    time.Now,
 	}
 }
 
@@ -124,7 +134,7 @@ func (tbl *TokenBucketLimiter) Allow(ctx context.Context, key string) (bool, err
 	}
 	tbl.mutex.Unlock()
 
-	return limiter.Allow(), nil
+	return limiter.AllowN(tbl.now(), 1), nil
 }
 
 // GetStats 获取统计信息
@@ -141,7 +151,10 @@ func (tbl *TokenBucketLimiter) GetStats(ctx context.Context, key string) (*Limit
 		}, nil
 	}
 
-	tokens := int(limiter.Tokens())
+	tokens := int(limiter.TokensAt(tbl.now()))
+	if tokens < 0 {
+		tokens = 0
+	}
 	return &LimitStats{
 		Key:       key,
 		Remaining: tokens,
@@ -158,10 +171,12 @@ func (tbl *TokenBucketLimiter) Reset(ctx context.Context, key string) error {
 	return nil
 }
 
-// Allow 检查是否允许请求
+// Allow 检查是否允许请求，窗口为(now-window, now]，放行数未达到上限时记录本次请求；
+// 被拒绝的请求从窗口中移除，不占用后续的配额
 func (swl *SlidingWindowLimiter) Allow(ctx context.Context, key string) (bool, error) {
-	now := time.Now()
+	now := swl.now()
 	windowStart := now.Add(-swl.window)
+	member := fmt.Sprintf("%d-%d", now.UnixNano(), atomic.AddUint64(&swl.seq, 1))
 
 	pipe := swl.redis.Pipeline()
 
@@ -171,38 +186,41 @@ func (swl *SlidingWindowLimiter) Allow(ctx context.Context, key string) (bool, e
 	// 添加当前请求
 	pipe.ZAdd(ctx, key, redis.Z{
 		Score:  float64(now.UnixNano()),
-		Member: fmt.Sprintf("%d", now.UnixNano()),
+		Member: member,
 	})
 
 	// 获取窗口内的请求数
-	pipe.ZCard(ctx, key)
+	count := pipe.ZCard(ctx, key)
 
 	// 设置过期时间
-	pipe.Expire(ctx, key, swl.window)
+	pipe.PExpire(ctx, key, swl.window)
 
-	results, err := pipe.Exec(ctx)
-	if err != nil {
+	if _, err := pipe.Exec(ctx); err != nil {
 		swl.logger.Error("Redis pipeline failed", zap.Error(err))
 		return false, err
 	}
 
-	count := results[2].(*redis.IntCmd).Val()
-
 	swl.logger.Debug("Sliding window check",
 		zap.String("key", key),
-		zap.Int64("count", count),
+		zap.Int64("count", count.Val()),
 		zap.Int("limit", swl.limit))
 
-	return count <= int64(swl.limit), nil
+	if count.Val() <= int64(swl.limit) {
+		return true, nil
+	}
+	if err := swl.redis.ZRem(ctx, key, member).Err(); err != nil {
+		swl.logger.Warn("Failed to remove rejected request from window", zap.Error(err))
+	}
+	return false, nil
 }
 
 // GetStats 获取统计信息
 func (swl *SlidingWindowLimiter) GetStats(ctx context.Context, key string) (*LimitStats, error) {
-	now := time.Now()
+	now := swl.now()
 	windowStart := now.Add(-swl.window)
 
 	count, err := swl.redis.ZCount(ctx, key,
-		strconv.FormatInt(windowStart.UnixNano(), 10),
+		"("+strconv.FormatInt(windowStart.UnixNano(), 10),
 		strconv.FormatInt(now.UnixNano(), 10)).Result()
 	if err != nil {
 		return nil, err
@@ -210,7 +228,7 @@ func (swl *SlidingWindowLimiter) GetStats(ctx context.Context, key string) (*Lim
 
 	return &LimitStats{
 		Key:          key,
-		Remaining:    swl.limit - int(count),
+		Remaining:    remaining(swl.limit, count),
 		Limit:        swl.limit,
 		ResetTime:    now.Add(swl.window),
 		Window:       swl.window,
@@ -223,23 +241,20 @@ func (swl *SlidingWindowLimiter) Reset(ctx context.Context, key string) error {
 	return swl.redis.Del(ctx, key).Err()
 }
 
-// Allow 检查是否允许请求
+// Allow 检查是否允许请求，窗口按window对齐，窗口内第limit个之后的请求被拒绝
 func (fwl *FixedWindowLimiter) Allow(ctx context.Context, key string) (bool, error) {
-	now := time.Now()
-	window := now.Truncate(fwl.window).Unix()
-	windowKey := fmt.Sprintf("%s:%d", key, window)
+	windowKey, _ := fwl.windowKey(key)
 
 	pipe := fwl.redis.Pipeline()
-	pipe.Incr(ctx, windowKey)
-	pipe.Expire(ctx, windowKey, fwl.window)
+	incr := pipe.Incr(ctx, windowKey)
+	pipe.PExpire(ctx, windowKey, fwl.window)
 
-	results, err := pipe.Exec(ctx)
-	if err != nil {
+	if _, err := pipe.Exec(ctx); err != nil {
 		fwl.logger.Error("Redis pipeline failed", zap.Error(err))
 		return false, err
 	}
 
-	count := results[0].(*redis.IntCmd).Val()
+	count := incr.Val()
 
 	fwl.logger.Debug("Fixed window check",
 		zap.String("key", key),
@@ -251,22 +266,18 @@ func (fwl *FixedWindowLimiter) Allow(ctx context.Context, key string) (bool, err
 
 // GetStats 获取统计信息
 func (fwl *FixedWindowLimiter) GetStats(ctx context.Context, key string) (*LimitStats, error) {
-	now := time.Now()
-	window := now.Truncate(fwl.window).Unix()
-	windowKey := fmt.Sprintf("%s:%d", key, window)
+	windowKey, start := fwl.windowKey(key)
 
 	count, err := fwl.redis.Get(ctx, windowKey).Int64()
 	if err != nil && err != redis.Nil {
 		return nil, err
 	}
 
-	resetTime := time.Unix(window, 0).Add(fwl.window)
-
 	return &LimitStats{
 		Key:          key,
-		Remaining:    fwl.limit - int(count),
+		Remaining:    remaining(fwl.limit, count),
 		Limit:        fwl.limit,
-		ResetTime:    resetTime,
+		ResetTime:    start.Add(fwl.window),
 		Window:       fwl.window,
 		RequestCount: count,
 	}, nil
@@ -274,13 +285,16 @@ func (fwl *FixedWindowLimiter) GetStats(ctx context.Context, key string) (*Limit
 
 // Reset 重置限流器
 func (fwl *FixedWindowLimiter) Reset(ctx context.Context, key string) error {
-	now := time.Now()
-	window := now.Truncate(fwl.window).Unix()
-	windowKey := fmt.Sprintf("%s:%d", key, window)
-
+	windowKey, _ := fwl.windowKey(key)
 	return fwl.redis.Del(ctx, windowKey).Err()
 }
 
+// windowKey 当前窗口的Redis键和窗口起点
+func (fwl *FixedWindowLimiter) windowKey(key string) (string, time.Time) {
+	start := fwl.now().Truncate(fwl.window)
+	return fmt.Sprintf("%s:%d", key, start.Unix()), start
+}
+
 // Middleware 限流中间件
 func Middleware(limiter RateLimiter, config *LimitConfig) gin.HandlerFunc {
 	if config.KeyFunc == nil {
@@ -373,16 +387,66 @@ func SkipHealthCheckFunc(c *gin.Context) bool {
 	return c.Request.URL.Path == "/health" || c.Request.URL.Path == "/ping"
 }
 
-// CreateRateLimiter 创建限流器
+// CreateRateLimiter 创建限流器。令牌桶在进程内计数；窗口策略在redis为空时使用内存实现，
+// 否则优先使用Redis，Redis出错时回退到相同语义的内存实现
 func CreateRateLimiter(strategy LimitStrategy, config *LimitConfig, redis *redis.Client, logger *zap.Logger) (RateLimiter, error) {
 	switch strategy {
 	case TokenBucket:
 		return NewTokenBucketLimiter(config.Rate, config.Burst, logger), nil
-	case SlidingWindow:
-		return NewSlidingWindowLimiter(redis, config.Window, config.Rate, logger), nil
-	case FixedWindow:
-		return NewFixedWindowLimiter(redis, config.Window, config.Rate, logger), nil
+	case SlidingWindow, FixedWindow:
+		limit := windowLimit(config.Rate, config.Window)
+		memory := newMemoryWindowLimiter(strategy, config.Window, limit)
+		if redis == nil {
+			logger.Info("Redis not available, using in-memory rate limiter",
+				zap.String("strategy", string(strategy)),
+				zap.Int("limit", limit))
+			return memory, nil
+		}
+
+		var primary RateLimiter
+		if strategy == SlidingWindow {
+			primary = NewSlidingWindowLimiter(redis, config.Window, limit, logger)
+		} else {
+			primary = NewFixedWindowLimiter(redis, config.Window, limit, logger)
+		}
+		return NewFallbackLimiter(primary, memory, logger), nil
 	default:
 		return nil, fmt.Errorf("unsupported limit strategy: %s", strategy)
 	}
-}
\ No newline at end of file
+}
+
+// normalizeBurst 令牌桶容量未配置时等于每秒速率，避免容量为0拒绝所有请求
+func normalizeBurst(rateLimit, burst int) int {
+	if burst > 0 {
+		return burst
+	}
+	if rateLimit > 0 {
+		return rateLimit
+	}
+	return 1
+}
+
+// normalizeWindow 窗口未配置时为1秒
+func normalizeWindow(window time.Duration) time.Duration {
+	if window <= 0 {
+		return time.Second
+	}
+	return window
+}
+
+// windowLimit 窗口策略每个窗口放行的请求数，按每秒速率折算，至少为1
+func windowLimit(rateLimit int, window time.Duration) int {
+	limit := int(float64(rateLimit) * normalizeWindow(window).Seconds())
+	if limit < 1 {
+		return 1
+	}
+	return limit
+}
+
+// remaining 剩余配额，不小于0
+func remaining(limit int, used int64) int {
+	if left := int64(limit) - used; left > 0 {
+		return int(left)
+	}
+	return 0
+}
@@ -0,0 +1,222 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeClock 测试用时钟
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+// requestSteps 请求间隔序列，覆盖同一时刻的突发、窗口内分散请求和恰好跨过窗口边界的情况
+var requestSteps = []time.Duration{
+	0, 0, 0, 200 * time.Millisecond, 400 * time.Millisecond, 0,
+	time.Second, 0, 0, 0, 2 * time.Second, 200 * time.Millisecond,
+	5 * time.Second, 0, 0, 0, 0, 0, 0, 400 * time.Millisecond,
+}
+
+// TestWindowLimitersRedisMatchMemory 相同配置和请求序列下，Redis与内存实现的放行结果和剩余配额一致
+func TestWindowLimitersRedisMatchMemory(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+	window := 5 * time.Second
+	limit := windowLimit(1, window)
+
+	for _, strategy := range []LimitStrategy{SlidingWindow, FixedWindow} {
+		t.Run(string(strategy), func(t *testing.T) {
+			clock := &fakeClock{now: time.Unix(1700000000, 0)}
+			client := newFakeRedisClient(t)
+
+			var redisLimiter, memoryLimiter RateLimiter
+			if strategy == SlidingWindow {
+				r := NewSlidingWindowLimiter(client, window, limit, logger)
+				r.now = clock.Now
+				m := NewMemorySlidingWindowLimiter(window, limit)
+				m.now = clock.Now
+				redisLimiter, memoryLimiter = r, m
+			} else {
+				r := NewFixedWindowLimiter(client, window, limit, logger)
+				r.now = clock.Now
+				m := NewMemoryFixedWindowLimiter(window, limit)
+				m.now = clock.Now
+				redisLimiter, memoryLimiter = r, m
+			}
+
+			var allowed, denied int
+			for round := 0; round < 5; round++ {
+				for i, step := range requestSteps {
+					clock.now = clock.now.Add(step)
+					for _, key := range []string{"ratelimit:ip:a", "ratelimit:ip:b"} {
+						// b的请求量是a的两倍
+						n := 1
+						if key == "ratelimit:ip:b" {
+							n = 2
+						}
+						for j := 0; j < n; j++ {
+							r, err := redisLimiter.Allow(ctx, key)
+							require.NoError(t, err)
+							m, err := memoryLimiter.Allow(ctx, key)
+							require.NoError(t, err)
+							require.Equal(t, r, m, "round %d step %d key %s", round, i, key)
+							if r {
+								allowed++
+							} else {
+								denied++
+							}
+						}
+
+						rs, err := redisLimiter.GetStats(ctx, key)
+						require.NoError(t, err)
+						ms, err := memoryLimiter.GetStats(ctx, key)
+						require.NoError(t, err)
+						assert.Equal(t, rs.Remaining, ms.Remaining)
+						assert.Equal(t, rs.Limit, ms.Limit)
+						assert.Equal(t, rs.ResetTime.Unix(), ms.ResetTime.Unix())
+					}
+				}
+			}
+			// 序列需同时覆盖放行和拒绝
+			assert.NotZero(t, allowed)
+			assert.NotZero(t, denied)
+
+			// 重置后两者都恢复完整配额
+			require.NoError(t, redisLimiter.Reset(ctx, "ratelimit:ip:b"))
+			require.NoError(t, memoryLimiter.Reset(ctx, "ratelimit:ip:b"))
+			rs, _ := redisLimiter.GetStats(ctx, "ratelimit:ip:b")
+			ms, _ := memoryLimiter.GetStats(ctx, "ratelimit:ip:b")
+			assert.Equal(t, limit, rs.Remaining)
+			assert.Equal(t, limit, ms.Remaining)
+		})
+	}
+}
+
+// TestSlidingWindowRejectedNotCounted 被拒绝的请求不占用配额，窗口滑过后恢复放行
+func TestSlidingWindowRejectedNotCounted(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	limiter := NewSlidingWindowLimiter(newFakeRedisClient(t), time.Second, 2, zap.NewNop())
+	limiter.now = clock.Now
+
+	for i := 0; i < 2; i++ {
+		allowed, err := limiter.Allow(ctx, "k")
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	}
+	clock.now = clock.now.Add(600 * time.Millisecond)
+	for i := 0; i < 5; i++ {
+		allowed, _ := limiter.Allow(ctx, "k")
+		assert.False(t, allowed)
+	}
+
+	// 前两个请求滑出窗口后立即放行，不受期间被拒绝请求的影响
+	clock.now = clock.now.Add(600 * time.Millisecond)
+	allowed, _ := limiter.Allow(ctx, "k")
+	assert.True(t, allowed)
+}
+
+func TestWindowLimit(t *testing.T) {
+	assert.Equal(t, 6000, windowLimit(100, time.Minute))
+	assert.Equal(t, 100, windowLimit(100, 0))
+	assert.Equal(t, 1, windowLimit(1, 100*time.Millisecond))
+	assert.Equal(t, 10, normalizeBurst(10, 0))
+	assert.Equal(t, 20, normalizeBurst(10, 20))
+}
+
+func TestCreateRateLimiterWithoutRedis(t *testing.T) {
+	cfg := &LimitConfig{Rate: 2, Window: time.Second}
+	for _, strategy := range []LimitStrategy{SlidingWindow, FixedWindow} {
+		limiter, err := CreateRateLimiter(strategy, cfg, nil, zap.NewNop())
+		require.NoError(t, err)
+
+		var allowed int
+		for i := 0; i < 5; i++ {
+			if ok, err := limiter.Allow(context.Background(), "k"); err == nil && ok {
+				allowed++
+			}
+		}
+		assert.Equal(t, 2, allowed, string(strategy))
+	}
+
+	_, err := CreateRateLimiter("leaky_bucket", cfg, nil, zap.NewNop())
+	assert.Error(t, err)
+}
+
+// stubLimiter 可控制是否出错的限流器
+type stubLimiter struct {
+	err   error
+	calls int
+}
+
+func (s *stubLimiter) Allow(ctx context.Context, key string) (bool, error) {
+	s.calls++
+	return s.err == nil, s.err
+}
+
+func (s *stubLimiter) GetStats(ctx context.Context, key string) (*LimitStats, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &LimitStats{Key: key, Limit: 100}, nil
+}
+
+func (s *stubLimiter) Reset(ctx context.Context, key string) error {
+	return s.err
+}
+
+func TestFallbackLimiter(t *testing.T) {
+	ctx := context.Background()
+	primary := &stubLimiter{}
+	memory := NewMemoryFixedWindowLimiter(time.Minute, 1)
+	limiter := NewFallbackLimiter(primary, memory, zap.NewNop())
+
+	allowed, err := limiter.Allow(ctx, "k")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.False(t, limiter.Degraded())
+
+	// Redis出错时使用内存限流，不向调用方返回错误
+	primary.err = errors.New("connection refused")
+	allowed, err = limiter.Allow(ctx, "k")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	allowed, _ = limiter.Allow(ctx, "k")
+	assert.False(t, allowed)
+	assert.True(t, limiter.Degraded())
+
+	stats, err := limiter.GetStats(ctx, "k")
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Limit)
+
+	// Redis恢复后切回
+	primary.err = nil
+	allowed, _ = limiter.Allow(ctx, "k")
+	assert.True(t, allowed)
+	assert.False(t, limiter.Degraded())
+	assert.Equal(t, 4, primary.calls)
+}
+
+func ExampleCreateRateLimiter() {
+	limiter, _ := CreateRateLimiter(FixedWindow, &LimitConfig{Rate: 1, Window: 3 * time.Second}, nil, zap.NewNop())
+	for i := 0; i < 4; i++ {
+		allowed, _ := limiter.Allow(context.Background(), "ratelimit:ip:127.0.0.1")
+		fmt.Println(allowed)
+	}
+	// Output:
+	// true
+	// true
+	// true
+	// false
+}
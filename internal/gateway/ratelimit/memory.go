@@ -0,0 +1,189 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemorySlidingWindowLimiter 进程内滑动窗口限流器，与SlidingWindowLimiter语义相同：
+// 窗口为(now-window, now]，只记录放行的请求
+type MemorySlidingWindowLimiter struct {
+	mutex     sync.Mutex
+	requests  map[string][]time.Time // 键 -> 窗口内放行请求的时间，按时间递增
+	window    time.Duration
+	limit     int
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+// MemoryFixedWindowLimiter 进程内固定窗口限流器，与FixedWindowLimiter语义相同：
+// 窗口按window对齐，被拒绝的请求也计数
+type MemoryFixedWindowLimiter struct {
+	mutex    sync.Mutex
+	counters map[string]*fixedWindowCounter
+	current  time.Time // 最近一次计数所在窗口的起点
+	window   time.Duration
+	limit    int
+	now      func() time.Time
+}
+
+// fixedWindowCounter 当前窗口的计数
+type fixedWindowCounter struct {
+	start time.Time
+	count int64
+}
+
+// NewMemorySlidingWindowLimiter 创建进程内滑动窗口限流器
+func NewMemorySlidingWindowLimiter(window time.Duration, limit int) *MemorySlidingWindowLimiter {
+	return &MemorySlidingWindowLimiter{
+		requests: make(map[string][]time.Time),
+		window:   normalizeWindow(window),
+		limit:    limit,
+		now:      time.Now,
+	}
+}
+
+// NewMemoryFixedWindowLimiter 创建进程内固定窗口限流器
+func NewMemoryFixedWindowLimiter(window time.Duration, limit int) *MemoryFixedWindowLimiter {
+	return &MemoryFixedWindowLimiter{
+		counters: make(map[string]*fixedWindowCounter),
+		window:   normalizeWindow(window),
+		limit:    limit,
+		now:      time.Now,
+	}
+}
+
+// newMemoryWindowLimiter 按窗口策略创建进程内限流器
+func newMemoryWindowLimiter(strategy LimitStrategy, window time.Duration, limit int) RateLimiter {
+	if strategy == SlidingWindow {
+		return NewMemorySlidingWindowLimiter(window, limit)
+	}
+	return NewMemoryFixedWindowLimiter(window, limit)
+}
+
+// Allow 检查是否允许请求
+func (l *MemorySlidingWindowLimiter) Allow(ctx context.Context, key string) (bool, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	requests := pruneBefore(l.requests[key], now.Add(-l.window))
+	if len(requests) >= l.limit {
+		l.requests[key] = requests
+		return false, nil
+	}
+	l.requests[key] = append(requests, now)
+	return true, nil
+}
+
+// GetStats 获取统计信息
+func (l *MemorySlidingWindowLimiter) GetStats(ctx context.Context, key string) (*LimitStats, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	count := int64(len(pruneBefore(l.requests[key], now.Add(-l.window))))
+	return &LimitStats{
+		Key:          key,
+		Remaining:    remaining(l.limit, count),
+		Limit:        l.limit,
+		ResetTime:    now.Add(l.window),
+		Window:       l.window,
+		RequestCount: count,
+	}, nil
+}
+
+// Reset 重置限流器
+func (l *MemorySlidingWindowLimiter) Reset(ctx context.Context, key string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	delete(l.requests, key)
+	return nil
+}
+
+// sweep 每个窗口清理一次已无窗口内请求的键，避免键无限增长
+func (l *MemorySlidingWindowLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	l.lastSweep = now
+
+	windowStart := now.Add(-l.window)
+	for key, requests := range l.requests {
+		if len(requests) == 0 || !requests[len(requests)-1].After(windowStart) {
+			delete(l.requests, key)
+		}
+	}
+}
+
+// pruneBefore 去掉不晚于windowStart的请求，与Redis按分数删除[0, windowStart]一致
+func pruneBefore(requests []time.Time, windowStart time.Time) []time.Time {
+	i := 0
+	for i < len(requests) && !requests[i].After(windowStart) {
+		i++
+	}
+	return requests[i:]
+}
+
+// Allow 检查是否允许请求
+func (l *MemoryFixedWindowLimiter) Allow(ctx context.Context, key string) (bool, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	counter := l.counter(key)
+	counter.count++
+	return counter.count <= int64(l.limit), nil
+}
+
+// GetStats 获取统计信息
+func (l *MemoryFixedWindowLimiter) GetStats(ctx context.Context, key string) (*LimitStats, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	start := l.now().Truncate(l.window)
+	var count int64
+	if counter, ok := l.counters[key]; ok && counter.start.Equal(start) {
+		count = counter.count
+	}
+	return &LimitStats{
+		Key:          key,
+		Remaining:    remaining(l.limit, count),
+		Limit:        l.limit,
+		ResetTime:    start.Add(l.window),
+		Window:       l.window,
+		RequestCount: count,
+	}, nil
+}
+
+// Reset 重置限流器
+func (l *MemoryFixedWindowLimiter) Reset(ctx context.Context, key string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	delete(l.counters, key)
+	return nil
+}
+
+// counter 当前窗口的计数，进入新窗口时清理所有键的过期计数
+func (l *MemoryFixedWindowLimiter) counter(key string) *fixedWindowCounter {
+	start := l.now().Truncate(l.window)
+	if start.After(l.current) {
+		for k, c := range l.counters {
+			if c.start.Before(start) {
+				delete(l.counters, k)
+			}
+		}
+		l.current = start
+	}
+
+	counter, ok := l.counters[key]
+	if !ok || !counter.start.Equal(start) {
+		counter = &fixedWindowCounter{start: start}
+		l.counters[key] = counter
+	}
+	return counter
+}
@@ -0,0 +1,177 @@
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeRedis 仅实现限流器用到的命令的内存Redis，通过net.Pipe与客户端通信，不过期键
+type fakeRedis struct {
+	mu      sync.Mutex
+	strings map[string]int64
+	zsets   map[string]map[string]float64
+}
+
+// newFakeRedisClient 创建连接到fakeRedis的客户端
+func newFakeRedisClient(t *testing.T) *redis.Client {
+	f := &fakeRedis{
+		strings: make(map[string]int64),
+		zsets:   make(map[string]map[string]float64),
+	}
+	client := redis.NewClient(&redis.Options{
+		Protocol:         2,
+		DisableIndentity: true,
+		Dialer: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, server := net.Pipe()
+			go f.serve(server)
+			return conn, nil
+		},
+	})
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// serve 处理一个连接，读完客户端已发送的命令后再统一写回应答，支持pipeline
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		w.WriteString(f.exec(args))
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readCommand 读取RESP数组形式的命令
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		header, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "$")))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func (f *fakeRedis) exec(args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "INCR":
+		f.strings[args[1]]++
+		return integer(f.strings[args[1]])
+	case "GET":
+		v, ok := f.strings[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		s := strconv.FormatInt(v, 10)
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+	case "EXPIRE", "PEXPIRE":
+		return integer(1)
+	case "DEL":
+		var n int64
+		for _, key := range args[1:] {
+			if _, ok := f.strings[key]; ok {
+				n++
+			}
+			if _, ok := f.zsets[key]; ok {
+				n++
+			}
+			delete(f.strings, key)
+			delete(f.zsets, key)
+		}
+		return integer(n)
+	case "ZADD":
+		set := f.zsets[args[1]]
+		if set == nil {
+			set = make(map[string]float64)
+			f.zsets[args[1]] = set
+		}
+		var added int64
+		for i := 2; i+1 < len(args); i += 2 {
+			score, _ := strconv.ParseFloat(args[i], 64)
+			if _, ok := set[args[i+1]]; !ok {
+				added++
+			}
+			set[args[i+1]] = score
+		}
+		return integer(added)
+	case "ZREM":
+		var n int64
+		for _, member := range args[2:] {
+			if _, ok := f.zsets[args[1]][member]; ok {
+				delete(f.zsets[args[1]], member)
+				n++
+			}
+		}
+		return integer(n)
+	case "ZCARD":
+		return integer(int64(len(f.zsets[args[1]])))
+	case "ZCOUNT", "ZREMRANGEBYSCORE":
+		min, minExclusive := parseScore(args[2])
+		max, maxExclusive := parseScore(args[3])
+		var n int64
+		for member, score := range f.zsets[args[1]] {
+			if score < min || (minExclusive && score == min) || score > max || (maxExclusive && score == max) {
+				continue
+			}
+			n++
+			if strings.ToUpper(args[0]) == "ZREMRANGEBYSCORE" {
+				delete(f.zsets[args[1]], member)
+			}
+		}
+		return integer(n)
+	default:
+		return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+	}
+}
+
+func integer(n int64) string {
+	return fmt.Sprintf(":%d\r\n", n)
+}
+
+// parseScore 解析分数区间端点，"("前缀表示不含端点
+func parseScore(s string) (float64, bool) {
+	exclusive := strings.HasPrefix(s, "(")
+	v, _ := strconv.ParseFloat(strings.TrimPrefix(s, "("), 64)
+	return v, exclusive
+}
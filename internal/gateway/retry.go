@@ -0,0 +1,236 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultRetryBaseDelay = 100 * time.Millisecond
+	defaultRetryMaxDelay  = 2 * time.Second
+	// maxRetryBodySize 超过该大小的请求体不缓存，也就不重试
+	maxRetryBodySize = 1 << 20
+)
+
+// defaultRetryStatusCodes 默认触发重试的后端响应状态码
+var defaultRetryStatusCodes = []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+
+// RetryPolicy 路由重试策略，重试次数由 Route.Retries 指定，总超时由 Route.Timeout 指定
+//
+// 第n次重试前等待 min(MaxDelay, BaseDelay*2^n) 的一半加上随机的另一半（抖动），
+// 剩余时间不足以等待时不再重试。
+type RetryPolicy struct {
+	BaseDelay time.Duration `json:"base_delay" yaml:"base_delay"`
+	MaxDelay  time.Duration `json:"max_delay" yaml:"max_delay"`
+	// StatusCodes 触发重试的状态码，为空时使用502/503/504
+	StatusCodes []int `json:"status_codes,omitempty" yaml:"status_codes"`
+	// RetryNonIdempotent 是否重试POST/PATCH等非幂等请求，默认不重试
+	RetryNonIdempotent bool `json:"retry_non_idempotent" yaml:"retry_non_idempotent"`
+}
+
+// backoff 第attempt次重试（从0开始）前的等待时间
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	base, max := defaultRetryBaseDelay, defaultRetryMaxDelay
+	if p != nil && p.BaseDelay > 0 {
+		base = p.BaseDelay
+	}
+	if p != nil && p.MaxDelay > 0 {
+		max = p.MaxDelay
+	}
+
+	delay := base
+	for i := 0; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+// retryableStatus 状态码是否触发重试
+func (p *RetryPolicy) retryableStatus(code int) bool {
+	codes := defaultRetryStatusCodes
+	if p != nil && len(p.StatusCodes) > 0 {
+		codes = p.StatusCodes
+	}
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// retryableMethod 只有幂等方法默认允许重试
+func (p *RetryPolicy) retryableMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return p != nil && p.RetryNonIdempotent
+}
+
+// proxyStateKey 请求上下文中proxyState的键
+type proxyStateKey struct{}
+
+// proxyState 记录一次代理请求实际转发的目标、尝试次数和降级情况
+type proxyState struct {
+	target   string
+	attempts int
+	cacheKey string // 按入口请求计算的降级缓存键，不受前缀剥离和目标路径影响
+	fallback string
+}
+
+// requestProxyState 取请求上下文中的proxyState，不经HandleRequest的请求按当前URL生成
+func requestProxyState(req *http.Request) *proxyState {
+	if state, ok := req.Context().Value(proxyStateKey{}).(*proxyState); ok && state != nil {
+		return state
+	}
+	return &proxyState{cacheKey: responseCacheKey(req, "")}
+}
+
+func (s *proxyState) record(u *url.URL) {
+	if s == nil {
+		return
+	}
+	s.target = targetOrigin(u)
+	s.attempts++
+}
+
+// retryTransport 在反向代理的单次转发内执行重试，失败后切换到服务组中的下一个健康目标
+type retryTransport struct {
+	router *Router
+	base   http.RoundTripper
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	state, _ := ctx.Value(proxyStateKey{}).(*proxyState)
+	route, _ := ctx.Value("route").(*Route)
+	if route == nil || route.Retries <= 0 || !route.Retry.retryableMethod(req.Method) {
+		state.record(req.URL)
+		return t.base.RoundTrip(req)
+	}
+
+	newBody, ok := replayableBody(req)
+	if !ok {
+		state.record(req.URL)
+		return t.base.RoundTrip(req)
+	}
+
+	tried := []string{targetOrigin(req.URL)}
+	for attempt := 0; ; attempt++ {
+		attemptReq := req.Clone(ctx)
+		attemptReq.Body = newBody()
+
+		state.record(attemptReq.URL)
+		resp, err := t.base.RoundTrip(attemptReq)
+		if attempt >= route.Retries || !t.shouldRetry(ctx, resp, err, route.Retry) {
+			return resp, err
+		}
+
+		delay := route.Retry.backoff(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, maxRetryBodySize))
+			resp.Body.Close()
+		}
+
+		t.router.logger.Warn("Retrying upstream request",
+			zap.String("route_id", route.ID),
+			zap.String("method", req.Method),
+			zap.String("target", tried[len(tried)-1]),
+			zap.Int("attempt", attempt+1),
+			zap.Duration("backoff", delay),
+			zap.Error(err))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		if next := t.router.nextTarget(route, tried); next != nil {
+			req.URL.Scheme = next.Scheme
+			req.URL.Host = next.Host
+			req.Host = next.Host
+			tried = append(tried, targetOrigin(next))
+		}
+	}
+}
+
+// shouldRetry 网络错误或指定状态码时重试，请求被取消或超时则不再重试
+func (t *retryTransport) shouldRetry(ctx context.Context, resp *http.Response, err error, policy *RetryPolicy) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return policy.retryableStatus(resp.StatusCode)
+}
+
+// nextTarget 从路由的服务组中选择未尝试过的健康目标，没有服务组时返回nil继续使用原目标
+func (r *Router) nextTarget(route *Route, tried []string) *url.URL {
+	if r.balancer == nil || !r.balancer.hasGroup(route.serviceGroup()) {
+		return nil
+	}
+	target := r.balancer.SelectTargetExcept(route.serviceGroup(), tried)
+	if target == "" {
+		return nil
+	}
+	targetURL, err := url.Parse(target)
+	if err != nil {
+		return nil
+	}
+	return targetURL
+}
+
+// replayableBody 缓存请求体以便重试时重新发送，请求体过大时返回false
+func replayableBody(req *http.Request) (func() io.ReadCloser, bool) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return func() io.ReadCloser { return req.Body }, true
+	}
+	if req.GetBody != nil {
+		return func() io.ReadCloser {
+			body, err := req.GetBody()
+			if err != nil {
+				return http.NoBody
+			}
+			return body
+		}, true
+	}
+
+	data, err := io.ReadAll(io.LimitReader(req.Body, maxRetryBodySize+1))
+	if err != nil || len(data) > maxRetryBodySize {
+		// 已读取的部分放回请求体，按不重试处理
+		req.Body = readCloser{io.MultiReader(bytes.NewReader(data), req.Body), req.Body}
+		return nil, false
+	}
+	req.Body.Close()
+	return func() io.ReadCloser { return io.NopCloser(bytes.NewReader(data)) }, true
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// targetOrigin 目标的scheme://host形式，与负载均衡器中的目标URL比较
+func targetOrigin(u *url.URL) string {
+	return u.Scheme + "://" + u.Host
+}
@@ -0,0 +1,166 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ErrRouteConflict 相同方法、路径和匹配条件的路由已被其他ID占用
+var ErrRouteConflict = errors.New("route conflict")
+
+// RouteMatch 路由的附加匹配条件
+//
+// Host 支持精确匹配和 "*.example.com" 形式的子域名通配；Headers 的值为 "*" 时
+// 只要求请求头存在，否则要求值完全相等。所有条件同时满足才算命中。
+type RouteMatch struct {
+	Host    string            `json:"host,omitempty" yaml:"host"`
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers"`
+}
+
+// conditions 条件数量，条件越多路由越具体
+func (m *RouteMatch) conditions() int {
+	if m == nil {
+		return 0
+	}
+	n := len(m.Headers)
+	if m.Host != "" {
+		n++
+	}
+	return n
+}
+
+// signature 条件的规范化表示，用于生成路由键和冲突检测
+func (m *RouteMatch) signature() string {
+	if m.conditions() == 0 {
+		return ""
+	}
+	parts := make([]string, 0, m.conditions())
+	if m.Host != "" {
+		parts = append(parts, "host="+strings.ToLower(m.Host))
+	}
+	for name, value := range m.Headers {
+		parts = append(parts, http.CanonicalHeaderKey(name)+"="+value)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "&")
+}
+
+// validate 校验匹配条件
+func (m *RouteMatch) validate() error {
+	if m == nil {
+		return nil
+	}
+	for name := range m.Headers {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("match header name is required")
+		}
+	}
+	return nil
+}
+
+// mismatch 返回第一个不满足的条件，全部满足时返回空字符串
+func (m *RouteMatch) mismatch(req *http.Request) string {
+	if m == nil {
+		return ""
+	}
+	if m.Host != "" && !hostMatches(m.Host, req.Host) {
+		return fmt.Sprintf("host %q does not match %q", requestHost(req.Host), m.Host)
+	}
+	names := make([]string, 0, len(m.Headers))
+	for name := range m.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		want := m.Headers[name]
+		values, ok := req.Header[http.CanonicalHeaderKey(name)]
+		if !ok {
+			return fmt.Sprintf("header %s is missing", http.CanonicalHeaderKey(name))
+		}
+		if want != "*" && (len(values) == 0 || values[0] != want) {
+			return fmt.Sprintf("header %s does not match %q", http.CanonicalHeaderKey(name), want)
+		}
+	}
+	return ""
+}
+
+// hostMatches 比较请求Host，忽略端口和大小写
+func hostMatches(pattern, host string) bool {
+	pattern = strings.ToLower(pattern)
+	host = requestHost(host)
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(host, pattern[1:])
+	}
+	return host == pattern
+}
+
+func requestHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
+
+// methodMatches 路由方法为 "*" 或 "ANY" 时匹配任意方法
+func methodMatches(routeMethod, method string) bool {
+	return isAnyMethod(routeMethod) || strings.EqualFold(routeMethod, method)
+}
+
+func isAnyMethod(method string) bool {
+	return method == "*" || strings.EqualFold(method, "ANY")
+}
+
+// routePrefix 去掉路径末尾的 "*" 通配符
+func routePrefix(path string) string {
+	return strings.TrimSuffix(path, "*")
+}
+
+// pathMatches 路径相等或以路由路径为前缀
+func pathMatches(routePath, path string) bool {
+	return strings.HasPrefix(path, routePrefix(routePath))
+}
+
+// routeLess 路由匹配优先级，排在前面的先匹配：
+//  1. Priority 大的优先
+//  2. 路径（去掉通配符后）更长的优先，因此精确路径总是先于其前缀路由
+//  3. 匹配条件（Host和每个请求头各算一个）更多的优先
+//  4. 指定方法的优先于 "*"
+//  5. 以上都相同时按ID排序，保证结果稳定
+func routeLess(a, b *Route) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	if la, lb := len(routePrefix(a.Path)), len(routePrefix(b.Path)); la != lb {
+		return la > lb
+	}
+	if ca, cb := a.Match.conditions(), b.Match.conditions(); ca != cb {
+		return ca > cb
+	}
+	if aa, ba := isAnyMethod(a.Method), isAnyMethod(b.Method); aa != ba {
+		return ba
+	}
+	if a.ID != b.ID {
+		return a.ID < b.ID
+	}
+	return a.Path < b.Path
+}
+
+// RouteCandidate 路由匹配过程中评估的一条路由
+type RouteCandidate struct {
+	Route   *Route `json:"route"`
+	Matched bool   `json:"matched"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// RouteTrace 请求的路由匹配结果，按匹配优先级列出方法和路径符合的候选路由
+type RouteTrace struct {
+	Method     string           `json:"method"`
+	Path       string           `json:"path"`
+	Host       string           `json:"host"`
+	Matched    *Route           `json:"matched"`
+	Candidates []RouteCandidate `json:"candidates"`
+}
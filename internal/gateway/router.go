@@ -2,16 +2,28 @@ package gateway
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+
+	"github.com/env-data-platform/internal/gateway/auth"
+)
+
+// 代理请求完成后写入gin上下文的键
+const (
+	ContextKeyRouteID  = "gateway_route_id"
+	ContextKeyUpstream = "gateway_upstream"
+	ContextKeyAttempts = "gateway_attempts"
+	ContextKeyFallback = "gateway_fallback" // 降级响应来源，未降级时为空
 )
 
 // Route 定义API路由配置
@@ -22,36 +34,88 @@ type Route struct {
 	Target      string            `json:"target" yaml:"target"`
 	StripPrefix bool              `json:"strip_prefix" yaml:"strip_prefix"`
 	Headers     map[string]string `json:"headers" yaml:"headers"`
-	Timeout     time.Duration     `json:"timeout" yaml:"timeout"`
-	Retries     int               `json:"retries" yaml:"retries"`
+	Service     string            `json:"service,omitempty" yaml:"service"`
+	// Timeout 单个请求含重试在内的总超时
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+	Retries int           `json:"retries" yaml:"retries"`
+	Retry   *RetryPolicy  `json:"retry,omitempty" yaml:"retry"`
+	// Match 按请求头/Host区分同一路径的附加条件，Headers字段则是转发时注入的请求头
+	Match    *RouteMatch `json:"match,omitempty" yaml:"match"`
+	Priority int         `json:"priority" yaml:"priority"`
+	// Scopes 调用该路由所需的scope，满足任意一个即可，为空时已认证的请求均可访问
+	Scopes []string `json:"scopes,omitempty" yaml:"scopes"`
+	// Fallback 后端不可用或5xx时的降级响应，CircuitBreaker 连续失败后暂停转发
+	Fallback       *FallbackPolicy       `json:"fallback,omitempty" yaml:"fallback"`
+	CircuitBreaker *CircuitBreakerPolicy `json:"circuit_breaker,omitempty" yaml:"circuit_breaker"`
+}
+
+// serviceGroup 路由使用的负载均衡服务组，未指定服务时使用路由ID
+func (route *Route) serviceGroup() string {
+	if route.Service != "" {
+		return route.Service
+	}
+	return route.ID
 }
 
 // Router API网关路由器
 type Router struct {
 	routes    map[string]*Route
 	proxies   map[string]*httputil.ReverseProxy
+	ordered   []string // 按匹配优先级排序的路由键
 	mutex     sync.RWMutex
 	logger    *zap.Logger
 	balancer  *LoadBalancer
 	discovery *ServiceDiscovery
+	transport http.RoundTripper
+
+	resilienceMutex  sync.Mutex
+	resilienceStates map[*Route]*routeResilience
 }
 
 // NewRouter 创建新的路由器
 func NewRouter(logger *zap.Logger, balancer *LoadBalancer, discovery *ServiceDiscovery) *Router {
-	return &Router{
+	r := &Router{
 		routes:    make(map[string]*Route),
 		proxies:   make(map[string]*httputil.ReverseProxy),
 		logger:    logger,
 		balancer:  balancer,
 		discovery: discovery,
+
+		resilienceStates: make(map[*Route]*routeResilience),
 	}
+	r.transport = &retryTransport{router: r, base: http.DefaultTransport}
+	return r
+}
+
+// newProxy 创建转发到目标的反向代理
+func (r *Router) newProxy(target *url.URL) *httputil.ReverseProxy {
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.Transport = r.transport
+	proxy.ModifyResponse = r.modifyResponse
+	proxy.ErrorHandler = r.errorHandler
+	return proxy
 }
 
 // AddRoute 添加路由
 func (r *Router) AddRoute(route *Route) error {
+	if err := route.Match.validate(); err != nil {
+		return err
+	}
+	if err := route.Fallback.validate(); err != nil {
+		return err
+	}
+	if err := route.CircuitBreaker.validate(); err != nil {
+		return err
+	}
+
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
+	routeKey := makeRouteKey(route.Method, route.Path, route.Match)
+	if existing, exists := r.routes[routeKey]; exists && existing.ID != route.ID {
+		return fmt.Errorf("%w: %s %s is already served by route %s", ErrRouteConflict, route.Method, route.Path, existing.ID)
+	}
+
 	// 解析目标URL
 	target, err := url.Parse(route.Target)
 	if err != nil {
@@ -59,54 +123,115 @@ func (r *Router) AddRoute(route *Route) error {
 	}
 
 	// 创建反向代理
-	proxy := httputil.NewSingleHostReverseProxy(target)
-	proxy.ModifyResponse = r.modifyResponse
-	proxy.ErrorHandler = r.errorHandler
+	proxy := r.newProxy(target)
 
-	routeKey := fmt.Sprintf("%s:%s", route.Method, route.Path)
+	r.removeKey(routeKey)
 	r.routes[routeKey] = route
 	r.proxies[routeKey] = proxy
+	r.addResilience(route)
+	index := sort.Search(len(r.ordered), func(i int) bool {
+		return routeLess(route, r.routes[r.ordered[i]])
+	})
+	r.ordered = append(r.ordered, "")
+	copy(r.ordered[index+1:], r.ordered[index:])
+	r.ordered[index] = routeKey
 
 	r.logger.Info("Route added",
 		zap.String("method", route.Method),
 		zap.String("path", route.Path),
+		zap.String("match", route.Match.signature()),
 		zap.String("target", route.Target))
 
 	return nil
 }
 
-// RemoveRoute 删除路由
+// makeRouteKey 生成路由键，带匹配条件的路由在键中附加条件签名
+func makeRouteKey(method, path string, match *RouteMatch) string {
+	key := fmt.Sprintf("%s:%s", method, path)
+	if signature := match.signature(); signature != "" {
+		key += "?" + signature
+	}
+	return key
+}
+
+// removeKey 删除路由键对应的路由，调用方需持有写锁
+func (r *Router) removeKey(routeKey string) bool {
+	route, exists := r.routes[routeKey]
+	if !exists {
+		return false
+	}
+	r.dropResilience(route)
+	delete(r.routes, routeKey)
+	delete(r.proxies, routeKey)
+	for i, key := range r.ordered {
+		if key == routeKey {
+			r.ordered = append(r.ordered[:i], r.ordered[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// RemoveRoute 删除不带匹配条件的路由
 func (r *Router) RemoveRoute(method, path string) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	routeKey := fmt.Sprintf("%s:%s", method, path)
-	delete(r.routes, routeKey)
-	delete(r.proxies, routeKey)
+	r.removeKey(makeRouteKey(method, path, nil))
 
 	r.logger.Info("Route removed",
 		zap.String("method", method),
 		zap.String("path", path))
 }
 
-// GetRoute 获取路由
+// RemoveRouteByID 按ID删除路由，可用于删除带匹配条件的路由
+func (r *Router) RemoveRouteByID(id string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for key, route := range r.routes {
+		if route.ID == id {
+			r.removeKey(key)
+			r.logger.Info("Route removed",
+				zap.String("id", id),
+				zap.String("method", route.Method),
+				zap.String("path", route.Path))
+			return true
+		}
+	}
+	return false
+}
+
+// GetRoute 获取不带匹配条件的路由
 func (r *Router) GetRoute(method, path string) (*Route, bool) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
-	routeKey := fmt.Sprintf("%s:%s", method, path)
-	route, exists := r.routes[routeKey]
+	route, exists := r.routes[makeRouteKey(method, path, nil)]
 	return route, exists
 }
 
-// ListRoutes 列出所有路由
-func (r *Router) ListRoutes() []*Route {
+// GetRouteByID 按ID获取路由
+func (r *Router) GetRouteByID(id string) (*Route, bool) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
-	routes := make([]*Route, 0, len(r.routes))
 	for _, route := range r.routes {
-		routes = append(routes, route)
+		if route.ID == id {
+			return route, true
+		}
+	}
+	return nil, false
+}
+
+// ListRoutes 按匹配优先级列出所有路由
+func (r *Router) ListRoutes() []*Route {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	routes := make([]*Route, 0, len(r.ordered))
+	for _, key := range r.ordered {
+		routes = append(routes, r.routes[key])
 	}
 	return routes
 }
@@ -117,7 +242,7 @@ func (r *Router) HandleRequest() gin.HandlerFunc {
 		startTime := time.Now()
 
 		// 查找匹配的路由
-		route, proxy := r.findRoute(c.Request.Method, c.Request.URL.Path)
+		route, proxy := r.findRoute(c.Request)
 		if route == nil {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error": "route not found",
@@ -126,21 +251,54 @@ func (r *Router) HandleRequest() gin.HandlerFunc {
 			return
 		}
 
+		// 已认证的用户或API密钥需拥有路由要求的scope
+		if len(route.Scopes) > 0 && auth.Authenticated(c) && !auth.HasAnyScope(c, route.Scopes) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":           "insufficient permissions",
+				"required_scopes": route.Scopes,
+			})
+			return
+		}
+
 		// 记录请求信息
 		r.logger.Info("Processing request",
 			zap.String("method", c.Request.Method),
 			zap.String("path", c.Request.URL.Path),
+			zap.String("route_id", route.ID),
 			zap.String("target", route.Target),
 			zap.String("client_ip", c.ClientIP()))
 
 		// 设置请求上下文
 		ctx := context.WithValue(c.Request.Context(), "route", route)
 		ctx = context.WithValue(ctx, "start_time", startTime)
+		state := &proxyState{cacheKey: responseCacheKey(c.Request, c.GetString(auth.ContextKeyUserID))}
+		ctx = context.WithValue(ctx, proxyStateKey{}, state)
+		if route.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, route.Timeout)
+			defer cancel()
+		}
 		c.Request = c.Request.WithContext(ctx)
 
+		// 熔断器打开时不转发，配置了降级则返回降级内容
+		if breaker := r.resilience(route).breaker; breaker != nil && !breaker.allow(time.Now()) {
+			if route.Fallback.active() {
+				fallback, source := r.fallbackResponse(route, state.cacheKey)
+				writeFallback(c.Writer, fallback, source, FallbackReasonCircuitOpen)
+				c.Set(ContextKeyFallback, source)
+			} else {
+				c.JSON(http.StatusServiceUnavailable, gin.H{
+					"error":    "circuit open",
+					"route_id": route.ID,
+				})
+			}
+			c.Set(ContextKeyRouteID, route.ID)
+			return
+		}
+
 		// 处理路径前缀
 		if route.StripPrefix {
-			c.Request.URL.Path = strings.TrimPrefix(c.Request.URL.Path, route.Path)
+			c.Request.URL.Path = strings.TrimPrefix(c.Request.URL.Path, routePrefix(route.Path))
 			if c.Request.URL.Path == "" {
 				c.Request.URL.Path = "/"
 			}
@@ -152,36 +310,35 @@ func (r *Router) HandleRequest() gin.HandlerFunc {
 		}
 
 		// 使用负载均衡器选择目标服务器
-		if r.balancer != nil {
-			if target := r.balancer.SelectTarget(route.ID); target != "" {
+		if r.balancer != nil && r.balancer.hasGroup(route.serviceGroup()) {
+			if target := r.balancer.SelectTarget(route.serviceGroup()); target != "" {
 				if targetURL, err := url.Parse(target); err == nil {
-					proxy = httputil.NewSingleHostReverseProxy(targetURL)
-					proxy.ModifyResponse = r.modifyResponse
-					proxy.ErrorHandler = r.errorHandler
+					proxy = r.newProxy(targetURL)
 				}
 			}
 		}
 
 		// 执行代理请求
 		proxy.ServeHTTP(c.Writer, c.Request)
+
+		// 供访问日志和指标使用
+		c.Set(ContextKeyRouteID, route.ID)
+		c.Set(ContextKeyUpstream, state.target)
+		c.Set(ContextKeyAttempts, state.attempts)
+		c.Set(ContextKeyFallback, state.fallback)
 	}
 }
 
-// findRoute 查找匹配的路由
-func (r *Router) findRoute(method, path string) (*Route, *httputil.ReverseProxy) {
+// findRoute 按优先级查找第一个方法、路径和匹配条件都满足的路由
+func (r *Router) findRoute(req *http.Request) (*Route, *httputil.ReverseProxy) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
-	// 精确匹配
-	routeKey := fmt.Sprintf("%s:%s", method, path)
-	if route, exists := r.routes[routeKey]; exists {
-		return route, r.proxies[routeKey]
-	}
-
-	// 前缀匹配
-	for key, route := range r.routes {
-		if strings.HasPrefix(key, method+":") &&
-			strings.HasPrefix(path, strings.TrimPrefix(key, method+":")) {
+	for _, key := range r.ordered {
+		route := r.routes[key]
+		if methodMatches(route.Method, req.Method) &&
+			pathMatches(route.Path, req.URL.Path) &&
+			route.Match.mismatch(req) == "" {
 			return route, r.proxies[key]
 		}
 	}
@@ -189,31 +346,99 @@ func (r *Router) findRoute(method, path string) (*Route, *httputil.ReverseProxy)
 	return nil, nil
 }
 
-// modifyResponse 修改响应
+// TraceRoute 返回请求的路由匹配过程，用于排查请求命中了哪条路由
+func (r *Router) TraceRoute(req *http.Request) *RouteTrace {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	trace := &RouteTrace{
+		Method:     req.Method,
+		Path:       req.URL.Path,
+		Host:       requestHost(req.Host),
+		Candidates: []RouteCandidate{},
+	}
+	for _, key := range r.ordered {
+		route := r.routes[key]
+		if !methodMatches(route.Method, req.Method) || !pathMatches(route.Path, req.URL.Path) {
+			continue
+		}
+		candidate := RouteCandidate{Route: route, Reason: route.Match.mismatch(req)}
+		switch {
+		case candidate.Reason != "":
+		case trace.Matched == nil:
+			candidate.Matched = true
+			trace.Matched = route
+		default:
+			candidate.Reason = fmt.Sprintf("shadowed by higher priority route %s", trace.Matched.ID)
+		}
+		trace.Candidates = append(trace.Candidates, candidate)
+	}
+	return trace
+}
+
+// modifyResponse 修改响应：记录熔断结果，触发降级的状态码替换为降级内容，cache模式缓存成功响应
 func (r *Router) modifyResponse(resp *http.Response) error {
+	route, _ := resp.Request.Context().Value("route").(*Route)
+	if route != nil {
+		state := requestProxyState(resp.Request)
+		if breaker := r.resilience(route).breaker; breaker != nil {
+			breaker.record(resp.StatusCode < 500, time.Now())
+		}
+		if route.Fallback.active() && route.Fallback.triggers(resp.StatusCode) {
+			fallback, source := r.fallbackResponse(route, state.cacheKey)
+			r.logger.Warn("Upstream response replaced by fallback",
+				zap.String("route_id", route.ID),
+				zap.Int("upstream_status", resp.StatusCode),
+				zap.String("source", source))
+			replaceWithFallback(resp, fallback, source, FallbackReasonUpstreamStatus)
+			state.fallback = source
+		} else {
+			r.cacheResponse(route, state.cacheKey, resp)
+		}
+	}
+
 	// 添加网关标识头
 	resp.Header.Set("X-Gateway", "env-data-platform")
 	resp.Header.Set("X-Gateway-Version", "1.0.0")
 
 	// 记录响应信息
-	if route := resp.Request.Context().Value("route"); route != nil {
+	if route != nil {
+		resp.Header.Set("X-Gateway-Route", route.ID)
 		r.logger.Info("Response processed",
 			zap.String("status", resp.Status),
-			zap.String("target", route.(*Route).Target))
+			zap.String("target", route.Target))
 	}
 
 	return nil
 }
 
-// errorHandler 处理代理错误
+// errorHandler 处理代理错误，客户端取消以外的错误计入熔断，配置了降级时返回降级内容
 func (r *Router) errorHandler(w http.ResponseWriter, req *http.Request, err error) {
 	r.logger.Error("Proxy error",
 		zap.Error(err),
 		zap.String("method", req.Method),
 		zap.String("path", req.URL.Path))
 
+	if route, _ := req.Context().Value("route").(*Route); route != nil && !errors.Is(err, context.Canceled) {
+		if breaker := r.resilience(route).breaker; breaker != nil {
+			breaker.record(false, time.Now())
+		}
+		if route.Fallback.active() {
+			state := requestProxyState(req)
+			fallback, source := r.fallbackResponse(route, state.cacheKey)
+			writeFallback(w, fallback, source, FallbackReasonUpstreamError)
+			state.fallback = source
+			return
+		}
+	}
+
+	status := http.StatusBadGateway
+	if errors.Is(err, context.DeadlineExceeded) {
+		status = http.StatusGatewayTimeout
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusBadGateway)
+	w.WriteHeader(status)
 	w.Write([]byte(`{"error":"service unavailable","message":"` + err.Error() + `"}`))
 }
 
@@ -241,8 +466,17 @@ func (r *Router) GetMetrics() map[string]interface{} {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
+	breakers := make(map[string]interface{})
+	for _, route := range r.routes {
+		if breaker := r.resilience(route).breaker; breaker != nil {
+			state, failures := breaker.snapshot()
+			breakers[route.ID] = map[string]interface{}{"state": state, "failures": failures}
+		}
+	}
+
 	return map[string]interface{}{
-		"total_routes": len(r.routes),
-		"routes":       r.routes,
+		"total_routes":     len(r.routes),
+		"routes":           r.routes,
+		"circuit_breakers": breakers,
 	}
 }
\ No newline at end of file
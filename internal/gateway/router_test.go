@@ -2,14 +2,20 @@ package gateway
 
 import (
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+
+	"github.com/env-data-platform/internal/gateway/auth"
 )
 
 func TestRouter(t *testing.T) {
@@ -184,4 +190,218 @@ func TestRouterConcurrency(t *testing.T) {
 	// 验证所有路由都添加成功
 	routes := router.ListRoutes()
 	assert.Len(t, routes, 100)
-}
\ No newline at end of file
+}
+func TestRouteHeaderMatching(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	router := NewRouter(logger, nil, nil)
+
+	routes := []*Route{
+		{ID: "data-v1", Path: "/api/data", Method: "GET", Target: "http://v1.example.com"},
+		{ID: "data-v2", Path: "/api/data", Method: "GET", Target: "http://v2.example.com",
+			Match: &RouteMatch{Headers: map[string]string{"X-Api-Version": "2"}}},
+		{ID: "data-internal", Path: "/api/data", Method: "*", Target: "http://internal.example.com",
+			Match: &RouteMatch{Host: "*.internal.local", Headers: map[string]string{"x-api-version": "*"}}},
+		{ID: "api-any", Path: "/api/*", Method: "*", Target: "http://fallback.example.com"},
+		{ID: "pinned", Path: "/api", Method: "GET", Target: "http://pinned.example.com", Priority: 10,
+			Match: &RouteMatch{Headers: map[string]string{"X-Debug": "1"}}},
+	}
+	for _, route := range routes {
+		assert.NoError(t, router.AddRoute(route))
+	}
+
+	find := func(method, host string, headers map[string]string) string {
+		req := httptest.NewRequest(method, "/api/data", nil)
+		req.Host = host
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		route, proxy := router.findRoute(req)
+		if route == nil {
+			return ""
+		}
+		assert.NotNil(t, proxy)
+		return route.ID
+	}
+
+	assert.Equal(t, "data-v1", find("GET", "gw.example.com", nil))
+	assert.Equal(t, "data-v2", find("GET", "gw.example.com", map[string]string{"X-Api-Version": "2"}))
+	assert.Equal(t, "data-v1", find("GET", "gw.example.com", map[string]string{"X-Api-Version": "3"}))
+	// 条件更多的路由优先，即使方法是通配
+	assert.Equal(t, "data-internal", find("GET", "etl.internal.local:8080", map[string]string{"X-Api-Version": "2"}))
+	assert.Equal(t, "api-any", find("POST", "gw.example.com", nil))
+	// 显式优先级高于路径长度
+	assert.Equal(t, "pinned", find("GET", "gw.example.com", map[string]string{"X-Api-Version": "2", "X-Debug": "1"}))
+
+	req := httptest.NewRequest("GET", "/api/data", nil)
+	req.Header.Set("X-Api-Version", "2")
+	trace := router.TraceRoute(req)
+	assert.Equal(t, "data-v2", trace.Matched.ID)
+	ids := make([]string, 0, len(trace.Candidates))
+	for _, candidate := range trace.Candidates {
+		ids = append(ids, candidate.Route.ID)
+	}
+	assert.Equal(t, []string{"pinned", "data-internal", "data-v2", "data-v1", "api-any"}, ids)
+	assert.Contains(t, trace.Candidates[0].Reason, "X-Debug")
+	assert.True(t, trace.Candidates[2].Matched)
+	assert.Contains(t, trace.Candidates[3].Reason, "shadowed")
+
+	// 相同方法、路径和条件的路由冲突，相同ID则视为更新
+	err := router.AddRoute(&Route{ID: "other", Path: "/api/data", Method: "GET", Target: "http://x.example.com",
+		Match: &RouteMatch{Headers: map[string]string{"x-api-version": "2"}}})
+	assert.ErrorIs(t, err, ErrRouteConflict)
+	assert.NoError(t, router.AddRoute(&Route{ID: "data-v2", Path: "/api/data", Method: "GET", Target: "http://v2b.example.com",
+		Match: &RouteMatch{Headers: map[string]string{"X-Api-Version": "2"}}}))
+	assert.Len(t, router.ListRoutes(), len(routes))
+
+	assert.True(t, router.RemoveRouteByID("data-v2"))
+	assert.Equal(t, "data-v1", find("GET", "gw.example.com", map[string]string{"X-Api-Version": "2"}))
+	_, exists := router.GetRouteByID("data-v2")
+	assert.False(t, exists)
+}
+
+// newGatewayServer 启动转发所有请求的网关服务，反向代理需要真实的ResponseWriter
+func newGatewayServer(router *Router) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Any("/*path", router.HandleRequest())
+	return httptest.NewServer(engine)
+}
+
+func doRequest(t *testing.T, method, url, body string) (int, string) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	assert.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return 0, ""
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(data)
+}
+
+func TestRouteRetryWithBackoff(t *testing.T) {
+	logger := zap.NewNop()
+
+	var failing, healthy int32
+	failingServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&failing, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failingServer.Close()
+	healthyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&healthy, 1)
+		body, _ := io.ReadAll(r.Body)
+		w.Write(append([]byte("ok:"), body...))
+	}))
+	defer healthyServer.Close()
+
+	balancer := NewLoadBalancer(logger)
+	balancer.AddServiceGroup(&ServiceGroup{
+		ID:       "data-service",
+		Strategy: RoundRobin,
+		Targets: []*Target{
+			{ID: "bad", URL: failingServer.URL, IsHealthy: true},
+			{ID: "good", URL: healthyServer.URL, IsHealthy: true},
+		},
+	})
+	router := NewRouter(logger, balancer, nil)
+	policy := &RetryPolicy{BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+	assert.NoError(t, router.AddRoute(&Route{ID: "data", Path: "/api/", Method: "*", Target: failingServer.URL,
+		Service: "data-service", Retries: 2, Retry: policy}))
+	gateway := newGatewayServer(router)
+	defer gateway.Close()
+
+	// 幂等请求失败后切换到另一个健康目标，请求体可重放
+	for i := 0; i < 2; i++ {
+		code, body := doRequest(t, "PUT", gateway.URL+"/api/data", "payload")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "ok:payload", body)
+	}
+	// 轮询每次都从失败目标开始，目标下线由健康检查负责
+	assert.Equal(t, int32(2), atomic.LoadInt32(&failing))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&healthy))
+
+	// 非幂等请求默认不重试
+	atomic.StoreInt32(&failing, 0)
+	balancer.UpdateTargetHealth("data-service", "good", false)
+	code, _ := doRequest(t, "POST", gateway.URL+"/api/data", "")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&failing))
+
+	// 只剩一个健康目标时重试同一目标，直到用完重试次数
+	atomic.StoreInt32(&failing, 0)
+	code, _ = doRequest(t, "GET", gateway.URL+"/api/data", "")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&failing))
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := &RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt, max := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		max *= time.Millisecond
+		delay := policy.backoff(attempt)
+		assert.GreaterOrEqual(t, delay, max/2)
+		assert.LessOrEqual(t, delay, max)
+	}
+
+	var defaults *RetryPolicy
+	assert.True(t, defaults.retryableMethod("GET"))
+	assert.False(t, defaults.retryableMethod("POST"))
+	assert.True(t, (&RetryPolicy{RetryNonIdempotent: true}).retryableMethod("POST"))
+	assert.True(t, defaults.retryableStatus(http.StatusBadGateway))
+	assert.False(t, defaults.retryableStatus(http.StatusInternalServerError))
+}
+
+func TestRouteTimeout(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer slow.Close()
+
+	router := NewRouter(zap.NewNop(), nil, nil)
+	assert.NoError(t, router.AddRoute(&Route{ID: "slow", Path: "/slow", Method: "GET", Target: slow.URL,
+		Timeout: 50 * time.Millisecond, Retries: 3}))
+	gateway := newGatewayServer(router)
+	defer gateway.Close()
+
+	start := time.Now()
+	code, _ := doRequest(t, "GET", gateway.URL+"/slow", "")
+	assert.Equal(t, http.StatusGatewayTimeout, code)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestRouteScopes(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	authenticator := auth.NewAuthenticator(&auth.AuthConfig{Strategy: auth.APIKeyAuth}, zap.NewNop())
+	reader, err := authenticator.CreateAPIKey("u1", "reader", []string{"data:read"}, 10, nil)
+	require.NoError(t, err)
+
+	router := NewRouter(zap.NewNop(), nil, nil)
+	require.NoError(t, router.AddRoute(&Route{ID: "query", Path: "/api/query", Method: "GET", Target: upstream.URL, Scopes: []string{"data:read", "data:admin"}}))
+	require.NoError(t, router.AddRoute(&Route{ID: "write", Path: "/api/write", Method: "POST", Target: upstream.URL, Scopes: []string{"data:write"}}))
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(authenticator.Middleware())
+	engine.Any("/*path", router.HandleRequest())
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	call := func(method, path string) int {
+		req, _ := http.NewRequest(method, server.URL+path, nil)
+		req.Header.Set("X-API-Key", reader.Key)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Equal(t, http.StatusOK, call("GET", "/api/query"))
+	assert.Equal(t, http.StatusForbidden, call("POST", "/api/write"))
+}
@@ -0,0 +1,552 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/middleware"
+	"github.com/env-data-platform/internal/models"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// AlarmRuleLoader 告警规则加载器，规则变更后通知检测器重新加载
+type AlarmRuleLoader interface {
+	LoadRules() error
+}
+
+// AlarmHandler 告警处理器
+type AlarmHandler struct {
+	db         *gorm.DB
+	logger     *zap.Logger
+	ruleLoader AlarmRuleLoader
+}
+
+// NewAlarmHandler 创建告警处理器
+func NewAlarmHandler(logger *zap.Logger, ruleLoader AlarmRuleLoader) *AlarmHandler {
+	return &AlarmHandler{
+		db:         database.GetDB(),
+		logger:     logger,
+		ruleLoader: ruleLoader,
+	}
+}
+
+// ListAlarmRules 获取告警规则列表
+func (h *AlarmHandler) ListAlarmRules(c *gin.Context) {
+	var req struct {
+		models.PageRequest
+		Name       string `form:"name"`
+		SystemCode string `form:"system_code"`
+		FactorCode string `form:"factor_code"`
+		Level      string `form:"level"`
+		Enabled    *bool  `form:"enabled"`
+	}
+
+	if !bindPageQuery(c, &req) {
+		return
+	}
+
+	query := h.db.Model(&models.AlarmRule{})
+
+	if req.Name != "" {
+		query = query.Where("name LIKE ?", "%"+req.Name+"%")
+	}
+	if req.SystemCode != "" {
+		query = query.Where("system_code = ?", req.SystemCode)
+	}
+	if req.FactorCode != "" {
+		query = query.Where("factor_code = ?", req.FactorCode)
+	}
+	if req.Level != "" {
+		query = query.Where("level = ?", req.Level)
+	}
+	if req.Enabled != nil {
+		query = query.Where("enabled = ?", *req.Enabled)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var rules []models.AlarmRule
+	offset := req.Offset()
+	if err := query.Offset(offset).Limit(req.PageSize).
+		Order("id DESC").
+		Find(&rules).Error; err != nil {
+		h.logger.Error("Failed to list alarm rules", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{
+		"list":      rules,
+		"total":     total,
+		"page":      req.Page,
+		"page_size": req.PageSize,
+	}))
+}
+
+// CreateAlarmRule 创建告警规则
+func (h *AlarmHandler) CreateAlarmRule(c *gin.Context) {
+	var req models.AlarmRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
+		return
+	}
+	if msg := validateAlarmRuleLimits(&req); msg != "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, msg))
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	rule := models.AlarmRule{
+		Name:        req.Name,
+		Description: req.Description,
+		SystemCode:  req.SystemCode,
+		FactorCode:  req.FactorCode,
+		DeviceID:    req.DeviceID,
+		UpperLimit:  req.UpperLimit,
+		LowerLimit:  req.LowerLimit,
+		Level:       req.Level,
+		Enabled:     req.Enabled == nil || *req.Enabled,
+		CooldownMin: req.CooldownMin,
+	}
+	rule.CreatedBy = userID
+	rule.UpdatedBy = userID
+
+	if err := h.db.Create(&rule).Error; err != nil {
+		h.logger.Error("Failed to create alarm rule", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "创建失败"))
+		return
+	}
+
+	h.reloadRules()
+	c.JSON(http.StatusOK, models.SuccessResponse(rule))
+}
+
+// GetAlarmRule 获取告警规则详情
+func (h *AlarmHandler) GetAlarmRule(c *gin.Context) {
+	rule, ok := h.findAlarmRule(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(rule))
+}
+
+// UpdateAlarmRule 更新告警规则
+func (h *AlarmHandler) UpdateAlarmRule(c *gin.Context) {
+	rule, ok := h.findAlarmRule(c)
+	if !ok {
+		return
+	}
+
+	var req models.AlarmRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
+		return
+	}
+	if msg := validateAlarmRuleLimits(&req); msg != "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, msg))
+		return
+	}
+
+	updates := map[string]interface{}{
+		"name":         req.Name,
+		"description":  req.Description,
+		"system_code":  req.SystemCode,
+		"factor_code":  req.FactorCode,
+		"device_id":    req.DeviceID,
+		"upper_limit":  req.UpperLimit,
+		"lower_limit":  req.LowerLimit,
+		"level":        req.Level,
+		"cooldown_min": req.CooldownMin,
+		"updated_by":   c.GetUint("user_id"),
+	}
+	if req.Enabled != nil {
+		updates["enabled"] = *req.Enabled
+	}
+
+	if err := h.db.Model(rule).Updates(updates).Error; err != nil {
+		h.logger.Error("Failed to update alarm rule", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "更新失败"))
+		return
+	}
+
+	h.db.First(rule, rule.ID)
+	h.reloadRules()
+	c.JSON(http.StatusOK, models.SuccessResponse(rule))
+}
+
+// DeleteAlarmRule 删除告警规则
+func (h *AlarmHandler) DeleteAlarmRule(c *gin.Context) {
+	rule, ok := h.findAlarmRule(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Delete(rule).Error; err != nil {
+		h.logger.Error("Failed to delete alarm rule", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "删除失败"))
+		return
+	}
+
+	h.reloadRules()
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{"message": "删除成功"}))
+}
+
+// EnableAlarmRule 启用告警规则
+func (h *AlarmHandler) EnableAlarmRule(c *gin.Context) {
+	h.setAlarmRuleEnabled(c, true)
+}
+
+// DisableAlarmRule 禁用告警规则
+func (h *AlarmHandler) DisableAlarmRule(c *gin.Context) {
+	h.setAlarmRuleEnabled(c, false)
+}
+
+// setAlarmRuleEnabled 切换告警规则启用状态
+func (h *AlarmHandler) setAlarmRuleEnabled(c *gin.Context, enabled bool) {
+	rule, ok := h.findAlarmRule(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Model(rule).Updates(map[string]interface{}{
+		"enabled":    enabled,
+		"updated_by": c.GetUint("user_id"),
+	}).Error; err != nil {
+		h.logger.Error("Failed to update alarm rule status", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "更新失败"))
+		return
+	}
+
+	rule.Enabled = enabled
+	h.reloadRules()
+	c.JSON(http.StatusOK, models.SuccessResponse(rule))
+}
+
+// ListAlarmNotifications 获取告警通知发送记录
+func (h *AlarmHandler) ListAlarmNotifications(c *gin.Context) {
+	var req struct {
+		models.PageRequest
+		AlarmID  uint   `form:"alarm_id"`
+		DeviceID string `form:"device_id"`
+		Channel  string `form:"channel"`
+		Status   string `form:"status"`
+	}
+
+	if !bindPageQuery(c, &req) {
+		return
+	}
+
+	query := h.db.Model(&models.AlarmNotification{})
+
+	if req.AlarmID > 0 {
+		query = query.Where("alarm_id = ?", req.AlarmID)
+	}
+	if req.DeviceID != "" {
+		query = query.Where("device_id = ?", req.DeviceID)
+	}
+	if req.Channel != "" {
+		query = query.Where("channel = ?", req.Channel)
+	}
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var records []models.AlarmNotification
+	offset := req.Offset()
+	if err := query.Offset(offset).Limit(req.PageSize).
+		Order("id DESC").
+		Find(&records).Error; err != nil {
+		h.logger.Error("Failed to list alarm notifications", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{
+		"list":      records,
+		"total":     total,
+		"page":      req.Page,
+		"page_size": req.PageSize,
+	}))
+}
+
+// errAlarmStatusChanged 告警状态已被他人修改
+var errAlarmStatusChanged = errors.New("alarm status changed")
+
+// ListAlarms 获取告警列表，status支持逗号分隔多个状态
+func (h *AlarmHandler) ListAlarms(c *gin.Context) {
+	var req struct {
+		models.PageRequest
+		Status     string     `form:"status"`
+		DeviceID   string     `form:"device_id"`
+		RuleID     uint       `form:"rule_id"`
+		Level      string     `form:"level"`
+		AssigneeID uint       `form:"assignee_id"`
+		StartTime  *time.Time `form:"start_time" time_format:"2006-01-02 15:04:05"`
+		EndTime    *time.Time `form:"end_time" time_format:"2006-01-02 15:04:05"`
+	}
+
+	if !bindPageQuery(c, &req) {
+		return
+	}
+
+	query := h.db.Model(&models.HJ212AlarmData{}).Scopes(middleware.DeviceScope(c, "device_id"))
+
+	if req.Status != "" {
+		query = query.Where("status IN ?", strings.Split(req.Status, ","))
+	}
+	if req.DeviceID != "" {
+		query = query.Where("device_id = ?", req.DeviceID)
+	}
+	if req.RuleID > 0 {
+		query = query.Where("rule_id = ?", req.RuleID)
+	}
+	if req.Level != "" {
+		query = query.Where("alarm_level = ?", req.Level)
+	}
+	if req.AssigneeID > 0 {
+		query = query.Where("assignee_id = ?", req.AssigneeID)
+	}
+	if req.StartTime != nil {
+		query = query.Where("received_at >= ?", *req.StartTime)
+	}
+	if req.EndTime != nil {
+		query = query.Where("received_at <= ?", *req.EndTime)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var alarms []models.HJ212AlarmData
+	offset := req.Offset()
+	if err := query.Offset(offset).Limit(req.PageSize).
+		Order("received_at DESC").
+		Find(&alarms).Error; err != nil {
+		h.logger.Error("Failed to list alarms", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{
+		"list":      alarms,
+		"total":     total,
+		"page":      req.Page,
+		"page_size": req.PageSize,
+	}))
+}
+
+// GetAlarm 获取告警详情及处理记录
+func (h *AlarmHandler) GetAlarm(c *gin.Context) {
+	alarm, ok := h.findAlarm(c)
+	if !ok {
+		return
+	}
+
+	var actions []models.AlarmAction
+	if err := h.db.Where("alarm_id = ?", alarm.ID).Order("id ASC").Find(&actions).Error; err != nil {
+		h.logger.Error("Failed to get alarm actions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{
+		"alarm":   alarm,
+		"actions": actions,
+	}))
+}
+
+// AcknowledgeAlarm 确认告警
+func (h *AlarmHandler) AcknowledgeAlarm(c *gin.Context) {
+	// 备注可选，允许不带请求体
+	var req models.AlarmHandleRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	now := time.Now()
+	h.transitAlarm(c, models.AlarmActionAcknowledge, models.AlarmStatusAcknowledged, req.Remark, nil, map[string]interface{}{
+		"acknowledged_by": userID,
+		"acknowledged_at": now,
+	})
+}
+
+// AssignAlarm 指派告警处理人
+func (h *AlarmHandler) AssignAlarm(c *gin.Context) {
+	var req models.AlarmAssignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
+		return
+	}
+
+	var count int64
+	h.db.Model(&models.User{}).Where("id = ?", req.AssigneeID).Count(&count)
+	if count == 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "指派的处理人不存在"))
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	now := time.Now()
+	h.transitAlarm(c, models.AlarmActionAssign, models.AlarmStatusAssigned, req.Remark, &req.AssigneeID, map[string]interface{}{
+		"assignee_id":     req.AssigneeID,
+		"assigned_at":     now,
+		"acknowledged_by": gorm.Expr("COALESCE(acknowledged_by, ?)", userID),
+		"acknowledged_at": gorm.Expr("COALESCE(acknowledged_at, ?)", now),
+	})
+}
+
+// CloseAlarm 关闭告警
+func (h *AlarmHandler) CloseAlarm(c *gin.Context) {
+	// 备注可选，允许不带请求体
+	var req models.AlarmHandleRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	h.transitAlarm(c, models.AlarmActionClose, models.AlarmStatusClosed, req.Remark, nil, map[string]interface{}{
+		"closed_by":    userID,
+		"processed_at": time.Now(),
+	})
+}
+
+// transitAlarm 执行告警状态流转并写入处理记录
+func (h *AlarmHandler) transitAlarm(c *gin.Context, action, toStatus, remark string, assigneeID *uint, updates map[string]interface{}) {
+	alarm, ok := h.findAlarm(c)
+	if !ok {
+		return
+	}
+
+	if !models.CanTransitAlarm(alarm.Status, action) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "当前告警状态不允许该操作"))
+		return
+	}
+
+	fromStatus := alarm.Status
+	updates["status"] = toStatus
+	if remark != "" {
+		updates["handle_remark"] = remark
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		// 以原状态为条件更新，防止并发处理时覆盖他人的操作
+		result := tx.Model(&models.HJ212AlarmData{}).
+			Where("id = ? AND status = ?", alarm.ID, fromStatus).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errAlarmStatusChanged
+		}
+
+		return tx.Create(&models.AlarmAction{
+			AlarmID:    alarm.ID,
+			Action:     action,
+			FromStatus: fromStatus,
+			ToStatus:   toStatus,
+			OperatorID: c.GetUint("user_id"),
+			AssigneeID: assigneeID,
+			Remark:     remark,
+		}).Error
+	})
+	if errors.Is(err, errAlarmStatusChanged) {
+		c.JSON(http.StatusConflict, models.ErrorResponse(http.StatusConflict, "告警状态已变更，请刷新后重试"))
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to update alarm status", zap.Error(err), zap.String("action", action))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "处理失败"))
+		return
+	}
+
+	// 概览中展示最新告警及其确认状态
+	invalidateDashboardCache(c, h.logger)
+
+	h.db.First(alarm, alarm.ID)
+	c.JSON(http.StatusOK, models.SuccessResponse(alarm))
+}
+
+// findAlarm 根据路径ID查询告警并校验数据权限，失败时已写入响应
+func (h *AlarmHandler) findAlarm(c *gin.Context) (*models.HJ212AlarmData, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "无效的ID"))
+		return nil, false
+	}
+
+	var alarm models.HJ212AlarmData
+	if err := h.db.First(&alarm, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "告警不存在"))
+			return nil, false
+		}
+		h.logger.Error("Failed to get alarm", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return nil, false
+	}
+
+	if !middleware.DeviceAllowed(c, alarm.DeviceID) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "告警不存在"))
+		return nil, false
+	}
+
+	return &alarm, true
+}
+
+// findAlarmRule 根据路径ID查询告警规则，失败时已写入响应
+func (h *AlarmHandler) findAlarmRule(c *gin.Context) (*models.AlarmRule, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "无效的ID"))
+		return nil, false
+	}
+
+	var rule models.AlarmRule
+	if err := h.db.First(&rule, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "告警规则不存在"))
+			return nil, false
+		}
+		h.logger.Error("Failed to get alarm rule", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return nil, false
+	}
+
+	return &rule, true
+}
+
+// reloadRules 通知检测器重新加载规则
+func (h *AlarmHandler) reloadRules() {
+	if h.ruleLoader == nil {
+		return
+	}
+	if err := h.ruleLoader.LoadRules(); err != nil {
+		h.logger.Error("Failed to reload alarm rules", zap.Error(err))
+	}
+}
+
+// validateAlarmRuleLimits 校验上下限配置，返回错误信息
+func validateAlarmRuleLimits(req *models.AlarmRuleRequest) string {
+	if req.UpperLimit == nil && req.LowerLimit == nil {
+		return "上限和下限至少配置一项"
+	}
+	if req.UpperLimit != nil && req.LowerLimit != nil && *req.LowerLimit > *req.UpperLimit {
+		return "下限不能大于上限"
+	}
+	return ""
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/env-data-platform/internal/database/dbtest"
+	"github.com/env-data-platform/internal/models"
+)
+
+// countingRuleLoader 记录规则重新加载次数
+type countingRuleLoader struct {
+	loads int
+}
+
+func (l *countingRuleLoader) LoadRules() error {
+	l.loads++
+	return nil
+}
+
+func TestValidateAlarmRuleLimits(t *testing.T) {
+	value := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name     string
+		upper    *float64
+		lower    *float64
+		expected string
+	}{
+		{name: "未配置限值", expected: "上限和下限至少配置一项"},
+		{name: "只配置上限", upper: value(150)},
+		{name: "只配置下限", lower: value(0)},
+		{name: "上下限相等", upper: value(50), lower: value(50)},
+		{name: "下限大于上限", upper: value(10), lower: value(50), expected: "下限不能大于上限"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &models.AlarmRuleRequest{UpperLimit: tt.upper, LowerLimit: tt.lower}
+			assert.Equal(t, tt.expected, validateAlarmRuleLimits(req))
+		})
+	}
+}
+
+func TestCreateAlarmRule(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{name: "缺少因子编码", body: `{"name":"PM2.5超标","level":"warning","upper_limit":150}`, expected: http.StatusBadRequest},
+		{name: "未知告警级别", body: `{"name":"PM2.5超标","factor_code":"a34004","level":"urgent","upper_limit":150}`, expected: http.StatusBadRequest},
+		{name: "冷却时间为负", body: `{"name":"PM2.5超标","factor_code":"a34004","level":"warning","upper_limit":150,"cooldown_min":-1}`, expected: http.StatusBadRequest},
+		{name: "未配置限值", body: `{"name":"PM2.5超标","factor_code":"a34004","level":"warning"}`, expected: http.StatusBadRequest},
+		{name: "下限大于上限", body: `{"name":"PM2.5超标","factor_code":"a34004","level":"warning","upper_limit":10,"lower_limit":50}`, expected: http.StatusBadRequest},
+		{name: "创建成功", body: `{"name":"PM2.5超标","factor_code":"a34004","level":"critical","upper_limit":150}`, expected: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.New(t)
+			loader := &countingRuleLoader{}
+			h := &AlarmHandler{db: db.DB, logger: zap.NewNop(), ruleLoader: loader}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/alarms/rules", strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Set("user_id", uint(3))
+			h.CreateAlarmRule(c)
+			assert.Equal(t, tt.expected, w.Code)
+
+			if tt.expected != http.StatusOK {
+				// 校验失败时不写库也不重新加载规则
+				assert.Empty(t, db.Statements())
+				assert.Zero(t, loader.loads)
+				return
+			}
+			statements := db.Statements()
+			require.Len(t, statements, 1)
+			assert.Equal(t, dbtest.KindCreate, statements[0].Kind)
+			assert.Equal(t, "env_alarm_rules", statements[0].Table)
+			assert.Contains(t, statements[0].Vars, "a34004")
+			// 未指定enabled时默认启用
+			assert.Contains(t, statements[0].Vars, true)
+			assert.Equal(t, 1, loader.loads)
+		})
+	}
+}
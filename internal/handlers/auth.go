@@ -1,31 +1,49 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 
 	"github.com/env-data-platform/internal/auth"
 	"github.com/env-data-platform/internal/config"
 	"github.com/env-data-platform/internal/database"
 	"github.com/env-data-platform/internal/models"
+	"github.com/env-data-platform/internal/services"
 )
 
+// errLDAPUserConflict LDAP用户名与非LDAP来源的本地账户重名，不能自动关联
+var errLDAPUserConflict = errors.New("username is taken by a non-ldap account")
+
 // AuthHandler 认证处理器
 type AuthHandler struct {
 	logger          *zap.Logger
 	jwtManager      *auth.JWTManager
 	passwordManager *auth.PasswordManager
+	passwordHistory *services.PasswordHistoryService
+	passwordReset   *services.PasswordResetService
+	loginGuard        *auth.LoginGuard
+	ldapAuthenticator *auth.LDAPAuthenticator
+	loginAudit        *services.LoginAuditService
 }
 
 // NewAuthHandler 创建认证处理器
 func NewAuthHandler(cfg *config.Config, logger *zap.Logger) *AuthHandler {
+	passwordManager := auth.NewPasswordManagerWithConfig(cfg.Security.Password.Argon2)
 	return &AuthHandler{
 		logger:          logger,
 		jwtManager:      auth.NewJWTManager(cfg),
-		passwordManager: auth.NewPasswordManager(),
+		passwordManager: passwordManager,
+		passwordHistory: services.NewPasswordHistoryService(passwordManager, cfg.Security.Password.HistoryCount),
+		passwordReset:   services.NewPasswordResetService(cfg, logger),
+		loginGuard:        auth.NewLoginGuard(database.GetRedis(), cfg.Security.Login),
+		ldapAuthenticator: auth.NewLDAPAuthenticator(cfg.LDAP),
+		loginAudit:        services.NewLoginAuditService(cfg, logger),
 	}
 }
 
@@ -53,6 +71,17 @@ type ChangePasswordRequest struct {
 	NewPassword string `json:"new_password" binding:"required"`
 }
 
+// ForgotPasswordRequest 忘记密码请求
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email" example:"user@example.com"`
+}
+
+// TokenResetPasswordRequest 凭令牌重置密码请求
+type TokenResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
 // Login 用户登录
 // @Summary 用户登录
 // @Description 用户登录获取访问令牌
@@ -68,40 +97,113 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.logger.Warn("Invalid login request", zap.Error(err))
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "请求参数错误"))
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
 		return
 	}
 
-	// 查找用户
+	ctx := c.Request.Context()
+	ip := c.ClientIP()
+
+	// 查找本地用户
 	var user models.User
+	userFound := true
 	if err := database.DB.Where("username = ? OR email = ?", req.Username, req.Username).
 		Preload("Roles").First(&user).Error; err != nil {
-		h.logger.Warn("User not found", zap.String("username", req.Username))
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse(http.StatusUnauthorized, "用户名或密码错误"))
+		if err != gorm.ErrRecordNotFound {
+			h.logger.Error("Failed to find user", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询用户失败"))
+			return
+		}
+		userFound = false
+	}
+
+	// 检查账户/IP是否被锁定，已存在的用户按ID计数，用户名、邮箱及大小写变体共享锁定状态
+	account := auth.LoginAccount(user.ID, req.Username)
+	lockStatus, err := h.loginGuard.CheckLocked(ctx, account, ip)
+	if err != nil {
+		// 锁定检查失败时不阻断登录，仅记录日志
+		h.logger.Error("Failed to check login lock", zap.Error(err))
+	} else if lockStatus.Locked {
+		h.logger.Warn("Login rejected, account or ip locked",
+			zap.String("username", req.Username),
+			zap.String("ip", ip))
+		h.recordLoginLog(c, user.ID, req.Username, 0, "账户已锁定")
+		c.JSON(http.StatusTooManyRequests, models.CodeErrorResponse(models.CodeAccountLocked,
+			fmt.Sprintf("登录失败次数过多，请%d分钟后重试", lockStatus.RetryMinutes())))
 		return
 	}
 
 	// 检查用户状态
-	if user.Status != models.UserStatusActive {
+	if userFound && user.Status != models.UserStatusActive {
 		h.logger.Warn("User account disabled", zap.Uint("user_id", user.ID))
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse(http.StatusUnauthorized, "账户已被禁用"))
+		h.recordLoginLog(c, user.ID, user.Username, 0, "账户已被禁用")
+		c.JSON(http.StatusUnauthorized, models.CodeErrorResponse(models.CodeAccountDisabled, ""))
 		return
 	}
 
-	// 验证密码
-	valid, err := h.passwordManager.VerifyPassword(req.Password, user.Password)
-	if err != nil {
-		h.logger.Error("Password verification failed", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "密码验证失败"))
+	// 先验证本地密码
+	valid := false
+	if userFound {
+		valid, err = h.passwordManager.VerifyPassword(req.Password, user.Password)
+		if err != nil {
+			h.logger.Error("Password verification failed", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "密码验证失败"))
+			return
+		}
+		if valid && h.passwordManager.NeedsRehash(user.Password) {
+			h.rehashPassword(&user, req.Password)
+		}
+	}
+
+	// 本地认证失败时尝试LDAP绑定认证
+	if !valid && h.ldapAuthenticator.Enabled() {
+		ldapUser, ldapErr := h.ldapAuthenticator.Authenticate(req.Username, req.Password)
+		if ldapErr == nil {
+			synced, syncErr := h.syncLDAPUser(ldapUser)
+			if errors.Is(syncErr, errLDAPUserConflict) {
+				// 目录账户不能接管同名的本地账户，否则知道目录密码即可登录本地管理员等账户
+				h.logger.Warn("LDAP login rejected, username taken by local account",
+					zap.String("username", ldapUser.Username),
+					zap.String("ip", ip))
+				h.recordLoginLog(c, 0, req.Username, 0, "LDAP账户与本地账户重名")
+				c.JSON(http.StatusUnauthorized, models.CodeErrorResponse(models.CodeLDAPUserConflict, ""))
+				return
+			}
+			if syncErr != nil {
+				h.logger.Error("Failed to sync ldap user", zap.String("username", req.Username), zap.Error(syncErr))
+				c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "同步LDAP用户失败"))
+				return
+			}
+			if synced.Status != models.UserStatusActive {
+				h.recordLoginLog(c, synced.ID, synced.Username, 0, "账户已被禁用")
+				c.JSON(http.StatusUnauthorized, models.CodeErrorResponse(models.CodeAccountDisabled, ""))
+				return
+			}
+			user = *synced
+			userFound = true
+			valid = true
+		} else if ldapErr != auth.ErrLDAPInvalidCredentials {
+			h.logger.Error("LDAP authentication error", zap.String("username", req.Username), zap.Error(ldapErr))
+		}
+	}
+
+	if !userFound {
+		h.logger.Warn("User not found", zap.String("username", req.Username))
+		h.handleLoginFailure(c, 0, req.Username, "用户不存在")
 		return
 	}
 
 	if !valid {
 		h.logger.Warn("Invalid password", zap.String("username", req.Username))
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse(http.StatusUnauthorized, "用户名或密码错误"))
+		h.handleLoginFailure(c, user.ID, req.Username, "密码错误")
 		return
 	}
 
+	// 登录成功，清除失败计数
+	if err := h.loginGuard.RecordSuccess(ctx, account, ip); err != nil {
+		h.logger.Error("Failed to reset login failures", zap.Error(err))
+	}
+
 	// 生成JWT令牌
 	token, err := h.jwtManager.GenerateToken(user.ID, user.Username, user.GetRoleID(), user.GetRoleName())
 	if err != nil {
@@ -110,42 +212,205 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	// 更新最后登录时间
+	// 更新最后登录时间、IP、次数及会话过期时间
 	now := time.Now()
+	expiresAt := now.Add(h.jwtManager.Expire())
 	user.LastLoginAt = &now
-	if err := database.DB.Save(&user).Error; err != nil {
+	user.LoginIP = ip
+	user.LoginCount++
+	user.SessionExpiresAt = &expiresAt
+	if err := database.DB.Model(&models.User{}).Where("id = ?", user.ID).UpdateColumns(map[string]interface{}{
+		"last_login_at":      now,
+		"login_ip":           ip,
+		"login_count":        gorm.Expr("login_count + 1"),
+		"session_expires_at": expiresAt,
+	}).Error; err != nil {
 		h.logger.Error("Failed to update last login time", zap.Error(err))
 	}
 
 	// 记录登录日志
-	loginLog := models.LoginLog{
-		UserID:    user.ID,
-		Username:  user.Username,
-		IP:        c.ClientIP(),
-		UserAgent: c.GetHeader("User-Agent"),
-		Status:    1, // 1表示成功
-		Message:   "登录成功",
-	}
-	if err := database.DB.Create(&loginLog).Error; err != nil {
-		h.logger.Error("Failed to create login log", zap.Error(err))
-	}
+	h.recordLoginLog(c, user.ID, user.Username, 1, "登录成功")
 
 	// 返回响应
 	userInfo := user.ToUserInfo()
 	response := LoginResponse{
 		Token:     token,
-		ExpiresAt: time.Now().Add(time.Hour * 24), // 这里应该从配置读取
+		ExpiresAt: expiresAt,
 		User:      userInfo,
 	}
 
 	h.logger.Info("User logged in successfully",
 		zap.Uint("user_id", user.ID),
 		zap.String("username", user.Username),
-		zap.String("ip", c.ClientIP()))
+		zap.String("ip", ip))
 
 	c.JSON(http.StatusOK, models.SuccessResponse(response))
 }
 
+// updateSessionExpiry 更新用户会话过期时间，expiresAt为nil表示会话结束，失败时仅记录日志
+func (h *AuthHandler) updateSessionExpiry(userID uint, expiresAt *time.Time) {
+	if err := database.DB.Model(&models.User{}).Where("id = ?", userID).
+		UpdateColumn("session_expires_at", expiresAt).Error; err != nil {
+		h.logger.Error("Failed to update session expiry", zap.Uint("user_id", userID), zap.Error(err))
+	}
+}
+
+// syncLDAPUser 按LDAP属性创建或更新本地用户，新用户分配默认角色
+// 只关联来源为LDAP的已有用户，与本地账户重名时返回 errLDAPUserConflict
+func (h *AuthHandler) syncLDAPUser(ldapUser *auth.LDAPUser) (*models.User, error) {
+	var user models.User
+	err := database.DB.Where("username = ?", ldapUser.Username).Preload("Roles").First(&user).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	// 已存在的用户只同步目录中有值的属性
+	if err == nil {
+		if user.AuthSource != models.AuthSourceLDAP {
+			return nil, errLDAPUserConflict
+		}
+		updates := map[string]interface{}{}
+		if ldapUser.Email != "" {
+			updates["email"] = ldapUser.Email
+		}
+		if ldapUser.RealName != "" {
+			updates["real_name"] = ldapUser.RealName
+		}
+		if ldapUser.Phone != "" {
+			updates["phone"] = ldapUser.Phone
+		}
+		if ldapUser.Department != "" {
+			updates["department"] = ldapUser.Department
+		}
+		if len(updates) > 0 {
+			if err := database.DB.Model(&user).Updates(updates).Error; err != nil {
+				return nil, err
+			}
+		}
+		return &user, nil
+	}
+
+	// 新用户：本地密码设为随机值，只能通过LDAP登录
+	randomPassword, err := h.passwordManager.GenerateRandomPassword(32)
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := h.passwordManager.HashPassword(randomPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	email := ldapUser.Email
+	if email == "" {
+		// 邮箱为唯一非空字段，目录中缺失时生成占位邮箱
+		email = ldapUser.Username + "@ldap.local"
+	}
+
+	user = models.User{
+		Username:   ldapUser.Username,
+		Email:      email,
+		RealName:   ldapUser.RealName,
+		Phone:      ldapUser.Phone,
+		Department: ldapUser.Department,
+		Password:   hashedPassword,
+		Status:     models.UserStatusActive,
+		AuthSource: models.AuthSourceLDAP,
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+
+		var role models.Role
+		if err := tx.Where("code = ?", h.ldapAuthenticator.DefaultRole()).First(&role).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				h.logger.Warn("LDAP default role not found", zap.String("role", h.ldapAuthenticator.DefaultRole()))
+				return nil
+			}
+			return err
+		}
+		return tx.Create(&models.UserRole{UserID: user.ID, RoleID: role.ID}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := database.DB.Preload("Roles").First(&user, user.ID).Error; err != nil {
+		return nil, err
+	}
+
+	h.logger.Info("LDAP user provisioned", zap.Uint("user_id", user.ID), zap.String("username", user.Username))
+	return &user, nil
+}
+
+// rehashPassword 按当前Argon2参数重新散列密码，失败时不影响本次登录
+func (h *AuthHandler) rehashPassword(user *models.User, password string) {
+	hashedPassword, err := h.passwordManager.HashPassword(password)
+	if err != nil {
+		h.logger.Warn("Failed to rehash password", zap.Uint("user_id", user.ID), zap.Error(err))
+		return
+	}
+	if err := database.DB.Model(&models.User{}).Where("id = ?", user.ID).
+		UpdateColumn("password", hashedPassword).Error; err != nil {
+		h.logger.Warn("Failed to save rehashed password", zap.Uint("user_id", user.ID), zap.Error(err))
+		return
+	}
+	user.Password = hashedPassword
+	h.logger.Info("Password rehashed with current parameters", zap.Uint("user_id", user.ID))
+}
+
+// handleLoginFailure 处理登录失败：累计失败次数、记录日志并返回统一的错误信息
+func (h *AuthHandler) handleLoginFailure(c *gin.Context, userID uint, username, reason string) {
+	status, err := h.loginGuard.RecordFailure(c.Request.Context(), auth.LoginAccount(userID, username), c.ClientIP())
+	if err != nil {
+		h.logger.Error("Failed to record login failure", zap.Error(err))
+	}
+
+	if status != nil && status.Locked {
+		h.recordLoginLog(c, userID, username, 0, reason+"，账户已锁定")
+		c.JSON(http.StatusTooManyRequests, models.CodeErrorResponse(models.CodeAccountLocked,
+			fmt.Sprintf("登录失败次数过多，请%d分钟后重试", status.RetryMinutes())))
+		return
+	}
+
+	h.recordLoginLog(c, userID, username, 0, reason)
+
+	message := "用户名或密码错误"
+	if status != nil && h.loginGuard.Enabled() {
+		message = fmt.Sprintf("用户名或密码错误，还可尝试%d次", h.loginGuard.RemainingAttempts(status.Failures))
+	}
+	c.JSON(http.StatusUnauthorized, models.CodeErrorResponse(models.CodeInvalidCredentials, message))
+}
+
+// recordLoginLog 记录登录日志，status 1成功 0失败；userID为0表示用户不存在
+func (h *AuthHandler) recordLoginLog(c *gin.Context, userID uint, username string, status int, message string) {
+	if len(username) > 50 {
+		username = username[:50]
+	}
+
+	loginLog := models.LoginLog{
+		Username:  username,
+		IP:        c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+		Status:    status,
+		Message:   message,
+	}
+	if userID > 0 {
+		loginLog.UserID = &userID
+	}
+	h.loginAudit.Enrich(&loginLog)
+	remote := h.loginAudit.MarkRemoteLogin(&loginLog)
+
+	if err := database.DB.Create(&loginLog).Error; err != nil {
+		h.logger.Error("Failed to create login log", zap.Error(err))
+		return
+	}
+	if remote {
+		h.loginAudit.NotifyRemoteLogin(loginLog)
+	}
+}
+
 // Logout 用户登出
 // @Summary 用户登出
 // @Description 用户登出（客户端清除令牌）
@@ -158,21 +423,13 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 	// 获取当前用户信息
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse(http.StatusUnauthorized, "用户未登录"))
+		c.JSON(http.StatusUnauthorized, models.CodeErrorResponse(models.CodeNotLoggedIn, ""))
 		return
 	}
 
-	// 记录登出日志
-	loginLog := models.LoginLog{
-		UserID:    userID.(uint),
-		IP:        c.ClientIP(),
-		UserAgent: c.GetHeader("User-Agent"),
-		Status:    0, // 0表示登出（或失败）
-		Message:   "登出成功",
-	}
-	if err := database.DB.Create(&loginLog).Error; err != nil {
-		h.logger.Error("Failed to create logout log", zap.Error(err))
-	}
+	// 记录登出日志，0表示登出（或失败）
+	h.recordLoginLog(c, userID.(uint), c.GetString("username"), 0, "登出成功")
+	h.updateSessionExpiry(userID.(uint), nil)
 
 	h.logger.Info("User logged out", zap.Any("user_id", userID))
 	c.JSON(http.StatusOK, models.SuccessResponse(nil))
@@ -190,7 +447,7 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 func (h *AuthHandler) GetMe(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse(http.StatusUnauthorized, "用户未登录"))
+		c.JSON(http.StatusUnauthorized, models.CodeErrorResponse(models.CodeNotLoggedIn, ""))
 		return
 	}
 
@@ -199,7 +456,7 @@ func (h *AuthHandler) GetMe(c *gin.Context) {
 	if err := database.DB.Where("id = ?", userID).
 		Preload("Roles").First(&user).Error; err != nil {
 		h.logger.Error("Failed to get user info", zap.Error(err))
-		c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "用户不存在"))
+		c.JSON(http.StatusNotFound, models.CodeErrorResponse(models.CodeUserNotFound, ""))
 		return
 	}
 
@@ -221,15 +478,24 @@ func (h *AuthHandler) GetMe(c *gin.Context) {
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	var req RefreshRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "请求参数错误"))
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
 		return
 	}
 
+	// 密码重置前签发的令牌不允许刷新
+	if claims, err := h.jwtManager.ParseToken(req.Token); err == nil {
+		if revoked, err := services.IsSessionRevoked(claims.UserID, claims.IssuedAt.Time); err != nil || revoked {
+			h.logger.Warn("Refusing to refresh revoked token", zap.Uint("user_id", claims.UserID), zap.Error(err))
+			c.JSON(http.StatusUnauthorized, models.CodeErrorResponse(models.CodeTokenRefreshFailed, ""))
+			return
+		}
+	}
+
 	// 刷新令牌
 	newToken, err := h.jwtManager.RefreshToken(req.Token)
 	if err != nil {
 		h.logger.Warn("Failed to refresh token", zap.Error(err))
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse(http.StatusUnauthorized, "令牌刷新失败"))
+		c.JSON(http.StatusUnauthorized, models.CodeErrorResponse(models.CodeTokenRefreshFailed, ""))
 		return
 	}
 
@@ -241,12 +507,16 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
+	// 刷新令牌延长会话
+	expiresAt := claims.ExpiresAt.Time
+	h.updateSessionExpiry(claims.UserID, &expiresAt)
+
 	// 查询用户信息
 	var user models.User
 	if err := database.DB.Where("id = ?", claims.UserID).
 		Preload("Roles").First(&user).Error; err != nil {
 		h.logger.Error("Failed to get user info", zap.Error(err))
-		c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "用户不存在"))
+		c.JSON(http.StatusNotFound, models.CodeErrorResponse(models.CodeUserNotFound, ""))
 		return
 	}
 
@@ -276,26 +546,26 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 func (h *AuthHandler) ChangePassword(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse(http.StatusUnauthorized, "用户未登录"))
+		c.JSON(http.StatusUnauthorized, models.CodeErrorResponse(models.CodeNotLoggedIn, ""))
 		return
 	}
 
 	var req ChangePasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "请求参数错误"))
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
 		return
 	}
 
 	// 验证新密码强度
 	if err := h.passwordManager.ValidatePasswordStrength(req.NewPassword); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "密码强度不足"))
+		c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeWeakPassword, ""))
 		return
 	}
 
 	// 查询用户
 	var user models.User
 	if err := database.DB.Where("id = ?", userID).First(&user).Error; err != nil {
-		c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "用户不存在"))
+		c.JSON(http.StatusNotFound, models.CodeErrorResponse(models.CodeUserNotFound, ""))
 		return
 	}
 
@@ -308,7 +578,18 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 	}
 
 	if !valid {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse(http.StatusUnauthorized, "原密码错误"))
+		c.JSON(http.StatusUnauthorized, models.CodeErrorResponse(models.CodeWrongOldPassword, ""))
+		return
+	}
+
+	// 检查是否重复使用近期密码
+	if err := h.passwordHistory.CheckReuse(user.ID, user.Password, req.NewPassword); err != nil {
+		if err == services.ErrPasswordReused {
+			c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodePasswordReused, err.Error()))
+			return
+		}
+		h.logger.Error("Failed to check password history", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "密码历史校验失败"))
 		return
 	}
 
@@ -320,9 +601,14 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	// 更新密码
+	// 更新密码并记录历史
 	user.Password = hashedPassword
-	if err := database.DB.Save(&user).Error; err != nil {
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+		return h.passwordHistory.Record(tx, user.ID, hashedPassword)
+	}); err != nil {
 		h.logger.Error("Failed to update password", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "密码更新失败"))
 		return
@@ -330,4 +616,135 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 
 	h.logger.Info("Password changed successfully", zap.Uint("user_id", user.ID))
 	c.JSON(http.StatusOK, models.SuccessResponse(nil))
-}
\ No newline at end of file
+}
+
+// ForgotPassword 申请密码重置
+// @Summary 忘记密码
+// @Description 提交邮箱申请重置密码，账号存在时向该邮箱发送一次性重置链接；无论邮箱是否存在均返回成功
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body ForgotPasswordRequest true "忘记密码请求"
+// @Success 200 {object} models.Response "申请已受理"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 403 {object} models.Response "未启用自助密码重置"
+// @Router /api/v1/auth/password/forgot [post]
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	if !h.passwordReset.Enabled() {
+		c.JSON(http.StatusForbidden, models.CodeErrorResponse(models.CodeResetDisabled, ""))
+		return
+	}
+
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
+		return
+	}
+
+	if err := h.passwordReset.Request(req.Email, c.ClientIP()); err != nil {
+		h.logger.Error("Failed to handle password reset request", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "密码重置申请失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(nil))
+}
+
+// ResetPassword 凭重置令牌设置新密码
+// @Summary 重置密码
+// @Description 使用邮件中的一次性令牌设置新密码，成功后该用户已签发的令牌全部失效
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body TokenResetPasswordRequest true "重置密码请求"
+// @Success 200 {object} models.Response "重置成功"
+// @Failure 400 {object} models.Response "请求参数错误或令牌无效"
+// @Failure 403 {object} models.Response "未启用自助密码重置"
+// @Router /api/v1/auth/password/reset [post]
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	if !h.passwordReset.Enabled() {
+		c.JSON(http.StatusForbidden, models.CodeErrorResponse(models.CodeResetDisabled, ""))
+		return
+	}
+
+	var req TokenResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
+		return
+	}
+
+	// 验证新密码强度
+	if err := h.passwordManager.ValidatePasswordStrength(req.NewPassword); err != nil {
+		c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeWeakPassword, ""))
+		return
+	}
+
+	record, err := h.passwordReset.Lookup(req.Token)
+	if err != nil {
+		if err == services.ErrResetTokenInvalid {
+			c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeResetTokenInvalid, err.Error()))
+			return
+		}
+		h.logger.Error("Failed to look up password reset token", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "密码重置失败"))
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Where("id = ?", record.UserID).First(&user).Error; err != nil {
+		c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeResetTokenInvalid, services.ErrResetTokenInvalid.Error()))
+		return
+	}
+
+	// 检查是否重复使用近期密码
+	if err := h.passwordHistory.CheckReuse(user.ID, user.Password, req.NewPassword); err != nil {
+		if err == services.ErrPasswordReused {
+			c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodePasswordReused, err.Error()))
+			return
+		}
+		h.logger.Error("Failed to check password history", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "密码历史校验失败"))
+		return
+	}
+
+	hashedPassword, err := h.passwordManager.HashPassword(req.NewPassword)
+	if err != nil {
+		h.logger.Error("Failed to hash password", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "密码加密失败"))
+		return
+	}
+
+	// 作废令牌、更新密码并记录历史，密码重置时间之前签发的令牌随之失效
+	now := time.Now()
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := h.passwordReset.Consume(tx, record); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+			"password":            hashedPassword,
+			"password_changed_at": now,
+			"session_expires_at":  nil,
+		}).Error; err != nil {
+			return err
+		}
+		return h.passwordHistory.Record(tx, user.ID, hashedPassword)
+	}); err != nil {
+		if err == services.ErrResetTokenInvalid {
+			c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeResetTokenInvalid, err.Error()))
+			return
+		}
+		h.logger.Error("Failed to reset password", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "密码重置失败"))
+		return
+	}
+
+	// 已通过邮箱验证身份，解除登录锁定以便立即使用新密码登录
+	if h.loginGuard.Enabled() {
+		if err := h.loginGuard.Unlock(c.Request.Context(), auth.LoginAccount(user.ID, user.Username)); err != nil {
+			h.logger.Warn("Failed to unlock user after password reset", zap.Uint("user_id", user.ID), zap.Error(err))
+		}
+	}
+
+	h.logger.Info("Password reset via email token", zap.Uint("user_id", user.ID), zap.String("ip", c.ClientIP()))
+	c.JSON(http.StatusOK, models.SuccessResponse(nil))
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/auth"
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/database/dbtest"
+	"github.com/env-data-platform/internal/models"
+)
+
+// withExistingUser 将 database.DB 替换为DryRun数据库，按用户名查询时返回指定来源的已有用户
+func withExistingUser(t *testing.T, authSource string) *dbtest.DB {
+	db := dbtest.New(t)
+	db.On(dbtest.KindQuery, func(tx *gorm.DB) {
+		if user, ok := tx.Statement.Dest.(*models.User); ok {
+			user.ID = 5
+			user.Username = "alice"
+			user.AuthSource = authSource
+		}
+	})
+	db.Replace(t, &database.DB)
+	return db
+}
+
+func TestSyncLDAPUserLinksOnlyLDAPAccounts(t *testing.T) {
+	h := &AuthHandler{logger: zap.NewNop()}
+	ldapUser := &auth.LDAPUser{Username: "alice", Email: "alice@example.com"}
+
+	// 同名本地账户不能被目录账户接管
+	db := withExistingUser(t, models.AuthSourceLocal)
+	user, err := h.syncLDAPUser(ldapUser)
+	assert.ErrorIs(t, err, errLDAPUserConflict)
+	assert.Nil(t, user)
+	assert.Empty(t, db.Statements(dbtest.KindUpdate))
+	lookup, ok := db.Find("WHERE username = ?")
+	require.True(t, ok)
+	assert.Equal(t, "alice", lookup.Vars[0])
+
+	db = withExistingUser(t, models.AuthSourceLDAP)
+	user, err = h.syncLDAPUser(ldapUser)
+	require.NoError(t, err)
+	assert.Equal(t, uint(5), user.ID)
+	updates := db.Statements(dbtest.KindUpdate)
+	require.Len(t, updates, 1)
+	assert.Contains(t, updates[0].SQL, "AND `id` = ?")
+	assert.Equal(t, uint(5), updates[0].Vars[len(updates[0].Vars)-1])
+}
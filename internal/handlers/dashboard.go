@@ -1,29 +1,55 @@
 package handlers
 
 import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
 	"fmt"
+	"math"
 	"net/http"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 
+	"github.com/env-data-platform/internal/config"
 	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/middleware"
 	"github.com/env-data-platform/internal/models"
+	"github.com/env-data-platform/internal/services"
+	"github.com/env-data-platform/internal/websocket"
 )
 
 // DashboardHandler 仪表板处理器
 type DashboardHandler struct {
 	logger *zap.Logger
+	cache  *services.DashboardCache
 }
 
 // NewDashboardHandler 创建仪表板处理器
-func NewDashboardHandler(logger *zap.Logger) *DashboardHandler {
+func NewDashboardHandler(cfg *config.Config, logger *zap.Logger) *DashboardHandler {
+	var ttl time.Duration
+	if cfg.Dashboard.Cache.Enabled {
+		ttl = cfg.Dashboard.Cache.TTL
+	}
+
 	return &DashboardHandler{
 		logger: logger,
+		cache:  services.NewDashboardCache(database.GetRedis(), ttl),
 	}
 }
 
+// invalidateDashboardCache 底层数据变更后清除仪表板缓存并通知WebSocket推送，清除失败时依赖缓存TTL兜底
+func invalidateDashboardCache(c *gin.Context, logger *zap.Logger) {
+	if err := services.InvalidateDashboardCache(c.Request.Context()); err != nil {
+		logger.Warn("Failed to invalidate dashboard cache", zap.Error(err))
+	}
+	services.NotifyDashboardChange(services.DashboardTriggerUpdate)
+}
+
 // DashboardOverview 仪表板概览数据
 type DashboardOverview struct {
 	CoreStats       CoreStatsData       `json:"core_stats"`
@@ -153,8 +179,15 @@ type ETLTaskStatusInfo struct {
 type AlertInfo struct {
 	ID          uint      `json:"id"`
 	Level       string    `json:"level"`       // "info", "warning", "error"
+	AlarmLevel  string    `json:"alarm_level"` // 原始告警级别：info/warning/critical/fatal
 	Title       string    `json:"title"`
 	Message     string    `json:"message"`
+	DeviceID    string    `json:"device_id"`
+	FactorCode  string    `json:"factor_code,omitempty"`
+	Status      string    `json:"status"`
+	// Acknowledged 是否已确认，已指派或关闭的告警同样视为已确认
+	Acknowledged   bool       `json:"acknowledged"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
 	RelativeTime string   `json:"relative_time"`
 	Icon        string    `json:"icon"`
@@ -167,54 +200,112 @@ type AlertInfo struct {
 // @Tags 仪表板
 // @Produce json
 // @Security BearerAuth
+// @Param period query string false "时间周期" Enums(today,week,month) default(today)
 // @Success 200 {object} models.Response{data=DashboardOverview} "获取成功"
 // @Router /api/v1/dashboard/overview [get]
 func (h *DashboardHandler) GetDashboardOverview(c *gin.Context) {
-	overview := DashboardOverview{
-		CoreStats:       h.getCoreStats(),
-		EnvironmentData: h.getEnvironmentData(),
-		ChartData:       h.getChartData(),
+	period, ok := newDashboardPeriod(c.DefaultQuery("period", "today"), time.Now())
+	if !ok {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "时间周期参数错误"))
+		return
+	}
+
+	scope := middleware.GetDataScope(c)
+	cacheKey := dashboardOverviewCacheKey(period.Name, scope)
+	var overview DashboardOverview
+	hit, err := h.cache.Get(c.Request.Context(), cacheKey, &overview)
+	if err != nil {
+		h.logger.Warn("Failed to read dashboard cache", zap.Error(err))
+	}
+	if hit {
+		c.JSON(http.StatusOK, models.SuccessResponse(overview))
+		return
+	}
+
+	overview = DashboardOverview{
+		CoreStats:       h.getCoreStats(scope),
+		EnvironmentData: h.getEnvironmentData(period, scope),
+		ChartData:       h.getChartData(period, scope),
 		ETLTaskStatus:   h.getETLTaskStatus(),
-		LatestAlerts:    h.getLatestAlerts(),
+		LatestAlerts:    h.getLatestAlerts(period, scope),
 		LastUpdated:     time.Now(),
 	}
 
+	if err := h.cache.Set(c.Request.Context(), cacheKey, overview); err != nil {
+		h.logger.Warn("Failed to write dashboard cache", zap.Error(err))
+	}
+
 	c.JSON(http.StatusOK, models.SuccessResponse(overview))
 }
 
-// getCoreStats 获取核心统计数据
-func (h *DashboardHandler) getCoreStats() CoreStatsData {
+// dashboardOverviewCacheKey 概览缓存键，数据范围受限的用户按可见设备和区域区分
+func dashboardOverviewCacheKey(period string, scope *services.ResolvedDataScope) string {
+	key := "overview:" + period
+	if scope.Unrestricted {
+		return key
+	}
+	devices := append([]string(nil), scope.DeviceIDs...)
+	regions := append([]string(nil), scope.Regions...)
+	sort.Strings(devices)
+	sort.Strings(regions)
+	sum := sha256.Sum256([]byte(strings.Join(devices, ",") + "|" + strings.Join(regions, ",")))
+	return key + ":scope:" + hex.EncodeToString(sum[:8])
+}
+
+// getCoreStats 获取核心统计数据，数据源和HJ212数据按数据范围过滤
+func (h *DashboardHandler) getCoreStats(scope *services.ResolvedDataScope) CoreStatsData {
+	now := time.Now()
+	today := startOfDay(now)
+	dataSources := middleware.ScopeDataSources(scope)
+	devices := middleware.ScopeDevices(scope, "device_id")
+
 	// 获取数据源统计
-	var totalDataSources, activeDataSources int64
-	database.DB.Model(&models.DataSource{}).Count(&totalDataSources)
-	database.DB.Model(&models.DataSource{}).Where("status = 'active'").Count(&activeDataSources)
+	var totalDataSources, activeDataSources, newDataSources int64
+	database.DB.Model(&models.DataSource{}).Scopes(dataSources).Count(&totalDataSources)
+	database.DB.Model(&models.DataSource{}).Scopes(dataSources).Where("status = 'active'").Count(&activeDataSources)
+	database.DB.Model(&models.DataSource{}).Scopes(dataSources).Where("created_at >= ?", today).Count(&newDataSources)
 
 	// 获取ETL任务统计
 	var totalETLJobs, runningETLJobs int64
 	database.DB.Model(&models.ETLJob{}).Count(&totalETLJobs)
 	database.DB.Model(&models.ETLJob{}).Where("status = 'running'").Count(&runningETLJobs)
 
-	// 模拟API调用统计(实际应该从日志或统计表获取)
-	apiCallsToday := 45200
+	// 实时数据流量：最近一分钟与前一分钟的HJ212入库条数
+	var currentFlow, previousFlow int64
+	database.DB.Model(&models.HJ212Data{}).Scopes(devices).
+		Where("received_at >= ?", now.Add(-time.Minute)).Count(&currentFlow)
+	database.DB.Model(&models.HJ212Data{}).Scopes(devices).
+		Where("received_at >= ? AND received_at < ?", now.Add(-2*time.Minute), now.Add(-time.Minute)).
+		Count(&previousFlow)
+
+	// API调用量：今日与昨日同时段的操作日志条数
+	var apiCallsToday, apiCallsYesterday int64
+	database.DB.Model(&models.OperationLog{}).Where("created_at >= ?", today).Count(&apiCallsToday)
+	database.DB.Model(&models.OperationLog{}).
+		Where("created_at >= ? AND created_at < ?", today.AddDate(0, 0, -1), now.AddDate(0, 0, -1)).
+		Count(&apiCallsYesterday)
 
 	// 计算系统健康度
 	healthScore := h.calculateSystemHealth(activeDataSources, totalDataSources, runningETLJobs)
 
+	flowChange := changePercent(float64(currentFlow), float64(previousFlow))
+	apiChange := changePercent(float64(apiCallsToday), float64(apiCallsYesterday))
+
 	return CoreStatsData{
 		DataSourceConnections: DataSourceStat{
 			Current: int(activeDataSources),
-			Change:  3,
-			Trend:   "up",
+			Change:  int(newDataSources),
+			Trend:   trendOf(float64(newDataSources)),
 		},
 		RealTimeDataFlow: DataFlowStat{
-			Current:       1200.0,
-			ChangePercent: 15.0,
-			Trend:         "up",
+			Current:       math.Round(float64(currentFlow)/60*100) / 100,
+			ChangePercent: flowChange,
+			Trend:         trendOf(flowChange),
 		},
 		APICallsToday: APICallStat{
-			Current:       apiCallsToday,
-			ChangePercent: -5.0,
-			Trend:         "down",
+			Current:       int(apiCallsToday),
+			ChangePercent: apiChange,
+			Trend:         trendOf(apiChange),
 		},
 		SystemHealth: HealthStat{
 			Current: healthScore,
@@ -223,206 +314,301 @@ func (h *DashboardHandler) getCoreStats() CoreStatsData {
 	}
 }
 
-// getEnvironmentData 获取环境监测数据
-func (h *DashboardHandler) getEnvironmentData() EnvironmentData {
+// getEnvironmentData 按时间周期聚合HJ212监测数据得到环境指标
+// 指标取周期内全部有效数据的平均值（在数据库中聚合），站点状态取各设备最近一条数据，无数据时指标值为空
+func (h *DashboardHandler) getEnvironmentData(period dashboardPeriod, scope *services.ResolvedDataScope) EnvironmentData {
+	devices := middleware.ScopeDevices(scope, "device_id")
+	inPeriod := func(db *gorm.DB) *gorm.DB {
+		return db.Model(&models.HJ212Data{}).Scopes(devices).
+			Where("received_at >= ? AND is_valid = ?", period.Start, true)
+	}
+
+	var averages struct {
+		PM25       sql.NullFloat64
+		PM10       sql.NullFloat64
+		COD        sql.NullFloat64
+		Ammonia    sql.NullFloat64
+		Phosphorus sql.NullFloat64
+	}
+	if err := database.DB.Scopes(inPeriod).
+		Select(fmt.Sprintf("AVG(%s) AS pm25, AVG(%s) AS pm10, AVG(%s) AS cod, AVG(%s) AS ammonia, AVG(%s) AS phosphorus",
+			factorValueSQL(factorPM25), factorValueSQL(factorPM10),
+			factorValueSQL(factorCOD), factorValueSQL(factorAmmonia), factorValueSQL(factorPhosphorus))).
+		Scan(&averages).Error; err != nil {
+		h.logger.Error("Failed to aggregate environment data", zap.Error(err))
+	}
+
+	var rows []struct {
+		DeviceID   string
+		ReceivedAt time.Time
+		ParsedData models.JSONMap
+	}
+	latest := database.DB.Scopes(inPeriod).Select("MAX(id)").Group("device_id")
+	if err := database.DB.Model(&models.HJ212Data{}).
+		Select("device_id, received_at, parsed_data").
+		Where("id IN (?)", latest).
+		Scan(&rows).Error; err != nil {
+		h.logger.Error("Failed to query latest environment data", zap.Error(err))
+	}
+
+	onlineSince := time.Now().Add(-dashboardOnlineWindow)
+	air := newFactorAggregator()
+	water := newFactorAggregator()
+	air.setAverage(factorPM25, averages.PM25)
+	air.setAverage(factorPM10, averages.PM10)
+	water.setAverage(factorCOD, averages.COD)
+	water.setAverage(factorAmmonia, averages.Ammonia)
+	water.setAverage(factorPhosphorus, averages.Phosphorus)
+	for _, row := range rows {
+		online := row.ReceivedAt.After(onlineSince)
+		for code := range extractFactorValues(row.ParsedData) {
+			switch {
+			case strings.HasPrefix(code, "a"):
+				air.addDevice(row.DeviceID, online)
+			case strings.HasPrefix(code, "w"):
+				water.addDevice(row.DeviceID, online)
+			}
+		}
+	}
+
 	return EnvironmentData{
-		AirQuality: AirQualityData{
-			PM25: EnvironmentMetric{
-				Value:  "35",
-				Unit:   "μg/m³",
-				Level:  "良",
-				Status: "success",
-			},
-			PM10: EnvironmentMetric{
-				Value:  "52",
-				Unit:   "μg/m³",
-				Level:  "良",
-				Status: "success",
-			},
-			AQI: EnvironmentMetric{
-				Value:  "68",
-				Unit:   "",
-				Level:  "良",
-				Status: "success",
-			},
-			Stations: StationStatus{
-				Total:  127,
-				Online: 124,
-				Rate:   97.6,
-			},
-			Status: "online",
-		},
-		WaterQuality: WaterQualityData{
-			COD: EnvironmentMetric{
-				Value:  "15.2",
-				Unit:   "mg/L",
-				Level:  "Ⅱ类",
-				Status: "info",
-			},
-			Ammonia: EnvironmentMetric{
-				Value:  "0.45",
-				Unit:   "mg/L",
-				Level:  "Ⅱ类",
-				Status: "info",
-			},
-			Phosphorus: EnvironmentMetric{
-				Value:  "0.08",
-				Unit:   "mg/L",
-				Level:  "Ⅰ类",
-				Status: "success",
-			},
-			Sections: StationStatus{
-				Total:  89,
-				Online: 87,
-				Rate:   97.8,
-			},
-			Status: "online",
-		},
-		PollutionSources: PollutionSourcesData{
-			Enterprises:      156,
-			Devices:          342,
-			TransmissionRate: 94.2,
-			AbnormalDevices:  8,
-			Status:           "warning",
-		},
+		AirQuality:       h.buildAirQuality(air),
+		WaterQuality:     h.buildWaterQuality(water),
+		PollutionSources: h.getPollutionSources(period, onlineSince, scope),
+	}
+}
+
+// buildAirQuality 构建空气质量指标
+func (h *DashboardHandler) buildAirQuality(agg *factorAggregator) AirQualityData {
+	data := AirQualityData{
+		Stations: agg.stationStatus(),
+		Status:   stationsStatus(agg.stationStatus()),
+	}
+
+	pm25, hasPM25 := agg.average(factorPM25)
+	pm10, hasPM10 := agg.average(factorPM10)
+	if hasPM25 {
+		data.PM25 = aqiMetric(fmt.Sprintf("%.0f", pm25), "μg/m³", calculateIAQI(pm25, pm25Breakpoints))
+	}
+	if hasPM10 {
+		data.PM10 = aqiMetric(fmt.Sprintf("%.0f", pm10), "μg/m³", calculateIAQI(pm10, pm10Breakpoints))
+	}
+	if hasPM25 || hasPM10 {
+		aqi := 0
+		if hasPM25 {
+			aqi = calculateIAQI(pm25, pm25Breakpoints)
+		}
+		if hasPM10 {
+			if iaqi := calculateIAQI(pm10, pm10Breakpoints); iaqi > aqi {
+				aqi = iaqi
+			}
+		}
+		data.AQI = aqiMetric(fmt.Sprintf("%d", aqi), "", aqi)
+	}
+
+	return data
+}
+
+// buildWaterQuality 构建水质指标
+func (h *DashboardHandler) buildWaterQuality(agg *factorAggregator) WaterQualityData {
+	data := WaterQualityData{
+		Sections: agg.stationStatus(),
+		Status:   stationsStatus(agg.stationStatus()),
+	}
+
+	if value, ok := agg.average(factorCOD); ok {
+		data.COD = waterMetric(value, codGradeLimits)
+	}
+	if value, ok := agg.average(factorAmmonia); ok {
+		data.Ammonia = waterMetric(value, ammoniaGradeLimits)
+	}
+	if value, ok := agg.average(factorPhosphorus); ok {
+		data.Phosphorus = waterMetric(value, phosphorusGradeLimits)
+	}
+
+	return data
+}
+
+// getPollutionSources 统计污染源设备在线与异常情况
+func (h *DashboardHandler) getPollutionSources(period dashboardPeriod, onlineSince time.Time, scope *services.ResolvedDataScope) PollutionSourcesData {
+	deviceScope := middleware.ScopeDevices(scope, "device_id")
+	var enterprises, devices, onlineDevices, abnormalDevices int64
+	database.DB.Model(&models.DataSource{}).Scopes(middleware.ScopeDataSources(scope)).
+		Where("type = ?", models.DataSourceTypeHJ212).Count(&enterprises)
+	database.DB.Model(&models.HJ212Data{}).Scopes(deviceScope).
+		Where("received_at >= ?", period.Start).
+		Distinct("device_id").Count(&devices)
+	database.DB.Model(&models.HJ212Data{}).Scopes(deviceScope).
+		Where("received_at >= ?", onlineSince).
+		Distinct("device_id").Count(&onlineDevices)
+	database.DB.Model(&models.HJ212AlarmData{}).Scopes(deviceScope).
+		Where("received_at >= ? AND status <> ?", period.Start, "resolved").
+		Distinct("device_id").Count(&abnormalDevices)
+
+	data := PollutionSourcesData{
+		Enterprises:     int(enterprises),
+		Devices:         int(devices),
+		AbnormalDevices: int(abnormalDevices),
+		Status:          "offline",
 	}
+	if devices > 0 {
+		data.TransmissionRate = math.Round(float64(onlineDevices)/float64(devices)*1000) / 10
+		data.Status = "online"
+		if abnormalDevices > 0 || onlineDevices < devices {
+			data.Status = "warning"
+		}
+	}
+
+	return data
 }
 
 // getChartData 获取图表数据
-func (h *DashboardHandler) getChartData() ChartData {
+func (h *DashboardHandler) getChartData(period dashboardPeriod, scope *services.ResolvedDataScope) ChartData {
 	return ChartData{
-		DataFlowTrend: DataFlowTrendData{
-			Labels: []string{"00:00", "04:00", "08:00", "12:00", "16:00", "20:00", "24:00"},
-			Values: []float64{800, 600, 1200, 1500, 1800, 1400, 1000},
-		},
-		APICallStats: APICallStatsData{
-			Labels: []string{"空气质量", "水质监测", "污染源", "元数据", "数据导出", "其他"},
-			Values: []int{12500, 8900, 6700, 4200, 3800, 2100},
-		},
+		DataFlowTrend: h.getDataFlowTrend(period, scope),
+		APICallStats:  h.getAPICallStats(period),
 	}
 }
 
-// getETLTaskStatus 获取ETL任务状态
+// getDataFlowTrend 按周期分桶统计HJ212入库条数，无数据的时间段补0
+func (h *DashboardHandler) getDataFlowTrend(period dashboardPeriod, scope *services.ResolvedDataScope) DataFlowTrendData {
+	var rows []struct {
+		Bucket string
+		Count  int64
+	}
+	if err := database.DB.Model(&models.HJ212Data{}).Scopes(middleware.ScopeDevices(scope, "device_id")).
+		Select("DATE_FORMAT(received_at, ?) as bucket, COUNT(*) as count", period.SQLFormat).
+		Where("received_at >= ?", period.Start).
+		Group("bucket").
+		Scan(&rows).Error; err != nil {
+		h.logger.Error("Failed to query data flow trend", zap.Error(err))
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Bucket] = row.Count
+	}
+
+	trend := DataFlowTrendData{
+		Labels: make([]string, 0, period.Buckets),
+		Values: make([]float64, 0, period.Buckets),
+	}
+	for i := 0; i < period.Buckets; i++ {
+		bucket := period.bucketTime(i)
+		trend.Labels = append(trend.Labels, bucket.Format(period.LabelLayout))
+		trend.Values = append(trend.Values, float64(counts[bucket.Format(period.KeyLayout)]))
+	}
+
+	return trend
+}
+
+// getAPICallStats 按资源统计周期内的接口调用量（取前6类）
+func (h *DashboardHandler) getAPICallStats(period dashboardPeriod) APICallStatsData {
+	var rows []struct {
+		Resource string
+		Count    int64
+	}
+	if err := database.DB.Model(&models.OperationLog{}).
+		Select("resource, COUNT(*) as count").
+		Where("created_at >= ? AND resource <> ''", period.Start).
+		Group("resource").
+		Order("count DESC").
+		Limit(6).
+		Scan(&rows).Error; err != nil {
+		h.logger.Error("Failed to query api call stats", zap.Error(err))
+	}
+
+	stats := APICallStatsData{
+		Labels: make([]string, 0, len(rows)),
+		Values: make([]int, 0, len(rows)),
+	}
+	for _, row := range rows {
+		stats.Labels = append(stats.Labels, row.Resource)
+		stats.Values = append(stats.Values, int(row.Count))
+	}
+
+	return stats
+}
+
+// getETLTaskStatus 获取最近的ETL执行状态
 func (h *DashboardHandler) getETLTaskStatus() []ETLTaskStatusInfo {
-	var tasks []models.ETLJob
-	// 获取最近的ETL任务
-	database.DB.Order("updated_at DESC").Limit(4).Find(&tasks)
-
-	statusInfo := []ETLTaskStatusInfo{}
-
-	// 如果没有真实数据，返回模拟数据
-	if len(tasks) == 0 {
-		statusInfo = []ETLTaskStatusInfo{
-			{
-				ID:         1,
-				Name:       "环保数据同步任务",
-				Status:     "completed",
-				StatusText: "成功",
-				LastRun:    time.Now().Add(-2 * time.Minute),
-				Duration:   "5分钟",
-				Message:    "执行成功 - 2分钟前",
-				Icon:       "fas fa-check-circle",
-				ColorClass: "forest",
-			},
-			{
-				ID:         2,
-				Name:       "HJ212数据清洗",
-				Status:     "running",
-				StatusText: "运行中",
-				LastRun:    time.Now().Add(-10 * time.Minute),
-				Duration:   "剩余5分钟",
-				Message:    "正在执行 - 剩余5分钟",
-				Icon:       "fas fa-clock",
-				ColorClass: "air",
-			},
-			{
-				ID:         3,
-				Name:       "数据质量检查",
-				Status:     "warning",
-				StatusText: "警告",
-				LastRun:    time.Now().Add(-15 * time.Minute),
-				Duration:   "8分钟",
-				Message:    "执行警告 - 15分钟前",
-				Icon:       "fas fa-exclamation-triangle",
-				ColorClass: "soil",
-			},
-			{
-				ID:         4,
-				Name:       "API数据导入",
-				Status:     "failed",
-				StatusText: "失败",
-				LastRun:    time.Now().Add(-1 * time.Hour),
-				Duration:   "失败",
-				Message:    "执行失败 - 1小时前",
-				Icon:       "fas fa-times-circle",
-				ColorClass: "danger",
-			},
-		}
-	} else {
-		// 转换真实数据
-		for _, task := range tasks {
-			info := ETLTaskStatusInfo{
-				ID:         task.ID,
-				Name:       task.Name,
-				Status:     task.Status,
-				StatusText: h.getETLStatusText(task.Status),
-				LastRun:    task.UpdatedAt,
-				Message:    h.getETLStatusMessage(task.Status, task.UpdatedAt),
-				Icon:       h.getETLStatusIcon(task.Status),
-				ColorClass: h.getETLStatusColor(task.Status),
-			}
-			statusInfo = append(statusInfo, info)
+	var executions []models.ETLExecution
+	if err := database.DB.Preload("Job").
+		Order("start_time DESC").
+		Limit(4).
+		Find(&executions).Error; err != nil {
+		h.logger.Error("Failed to query etl executions", zap.Error(err))
+	}
+
+	statusInfo := make([]ETLTaskStatusInfo, 0, len(executions))
+	for _, execution := range executions {
+		name := fmt.Sprintf("作业#%d", execution.JobID)
+		if execution.Job != nil {
+			name = execution.Job.Name
 		}
+
+		statusInfo = append(statusInfo, ETLTaskStatusInfo{
+			ID:         execution.JobID,
+			Name:       name,
+			Status:     execution.Status,
+			StatusText: h.getETLStatusText(execution.Status),
+			LastRun:    execution.StartTime,
+			Duration:   formatDurationMillis(execution.Duration),
+			Message:    h.getETLStatusMessage(execution.Status, execution.StartTime),
+			Icon:       h.getETLStatusIcon(execution.Status),
+			ColorClass: h.getETLStatusColor(execution.Status),
+		})
 	}
 
 	return statusInfo
 }
 
-// getLatestAlerts 获取最新告警
-func (h *DashboardHandler) getLatestAlerts() []AlertInfo {
-	// 模拟告警数据(实际应该从告警表获取)
-	return []AlertInfo{
-		{
-			ID:           1,
-			Level:        "error",
-			Title:        "数据源连接中断",
-			Message:      "监测站点SH-001连接异常，请及时处理",
-			CreatedAt:    time.Now().Add(-2 * time.Minute),
-			RelativeTime: "2分钟前",
-			Icon:         "fas fa-exclamation-circle",
-			ColorClass:   "danger",
-		},
-		{
-			ID:           2,
-			Level:        "warning",
-			Title:        "数据质量异常",
-			Message:      "PM2.5数据存在异常值，建议检查传感器",
-			CreatedAt:    time.Now().Add(-15 * time.Minute),
-			RelativeTime: "15分钟前",
-			Icon:         "fas fa-exclamation-triangle",
-			ColorClass:   "warning",
-		},
-		{
-			ID:           3,
-			Level:        "info",
-			Title:        "系统维护通知",
-			Message:      "计划于今晚22:00进行系统升级维护",
-			CreatedAt:    time.Now().Add(-1 * time.Hour),
-			RelativeTime: "1小时前",
-			Icon:         "fas fa-info-circle",
-			ColorClass:   "info",
-		},
-		{
-			ID:           4,
-			Level:        "success",
-			Title:        "备份任务完成",
-			Message:      "每日数据备份任务执行成功",
-			CreatedAt:    time.Now().Add(-2 * time.Hour),
-			RelativeTime: "2小时前",
-			Icon:         "fas fa-check-circle",
-			ColorClass:   "success",
-		},
+// getLatestAlerts 获取周期内数据范围内设备的最新告警
+func (h *DashboardHandler) getLatestAlerts(period dashboardPeriod, scope *services.ResolvedDataScope) []AlertInfo {
+	var alarms []models.HJ212AlarmData
+	if err := database.DB.Scopes(middleware.ScopeDevices(scope, "device_id")).
+		Where("received_at >= ?", period.Start).
+		Order("received_at DESC").
+		Limit(5).
+		Find(&alarms).Error; err != nil {
+		h.logger.Error("Failed to query latest alarms", zap.Error(err))
+	}
+
+	alerts := make([]AlertInfo, 0, len(alarms))
+	for _, alarm := range alarms {
+		level := alertLevel(alarm.AlarmLevel)
+		title := fmt.Sprintf("设备%s告警", alarm.DeviceID)
+		if alarm.FactorCode != "" {
+			title = fmt.Sprintf("设备%s因子%s告警", alarm.DeviceID, alarm.FactorCode)
+		}
+		alerts = append(alerts, AlertInfo{
+			ID:             alarm.ID,
+			Level:          level,
+			AlarmLevel:     alarm.AlarmLevel,
+			Title:          title,
+			Message:        alarm.AlarmDesc,
+			DeviceID:       alarm.DeviceID,
+			FactorCode:     alarm.FactorCode,
+			Status:         alarm.Status,
+			Acknowledged:   alarmAcknowledged(&alarm),
+			AcknowledgedAt: alarm.AcknowledgedAt,
+			CreatedAt:      alarm.ReceivedAt,
+			RelativeTime: h.getRelativeTime(alarm.ReceivedAt),
+			Icon:         alertIcons[level],
+			ColorClass:   alertColors[level],
+		})
 	}
+
+	return alerts
+}
+
+// alarmAcknowledged 告警是否已被确认，已确认后指派或关闭的告警状态不再是acknowledged
+func alarmAcknowledged(alarm *models.HJ212AlarmData) bool {
+	switch alarm.Status {
+	case models.AlarmStatusAcknowledged, models.AlarmStatusAssigned, models.AlarmStatusClosed:
+		return true
+	}
+	return alarm.AcknowledgedAt != nil
 }
 
 // 辅助方法
@@ -453,11 +639,14 @@ func (h *DashboardHandler) getHealthStatus(health float64) string {
 
 func (h *DashboardHandler) getETLStatusText(status string) string {
 	statusMap := map[string]string{
+		"success":   "成功",
 		"completed": "成功",
 		"running":   "运行中",
 		"failed":    "失败",
 		"warning":   "警告",
 		"pending":   "等待中",
+		"retrying":  "重试中",
+		"timeout":   "超时",
 	}
 	if text, ok := statusMap[status]; ok {
 		return text
@@ -469,12 +658,16 @@ func (h *DashboardHandler) getETLStatusMessage(status string, lastRun time.Time)
 	relativeTime := h.getRelativeTime(lastRun)
 
 	switch status {
-	case "completed":
+	case "success", "completed":
 		return "执行成功 - " + relativeTime
 	case "running":
 		return "正在执行 - " + relativeTime
 	case "failed":
 		return "执行失败 - " + relativeTime
+	case "retrying":
+		return "失败待重试 - " + relativeTime
+	case "timeout":
+		return "执行超时 - " + relativeTime
 	case "warning":
 		return "执行警告 - " + relativeTime
 	default:
@@ -484,11 +677,14 @@ func (h *DashboardHandler) getETLStatusMessage(status string, lastRun time.Time)
 
 func (h *DashboardHandler) getETLStatusIcon(status string) string {
 	iconMap := map[string]string{
+		"success":   "fas fa-check-circle",
 		"completed": "fas fa-check-circle",
 		"running":   "fas fa-clock",
 		"failed":    "fas fa-times-circle",
 		"warning":   "fas fa-exclamation-triangle",
 		"pending":   "fas fa-clock",
+		"retrying":  "fas fa-redo",
+		"timeout":   "fas fa-hourglass-end",
 	}
 	if icon, ok := iconMap[status]; ok {
 		return icon
@@ -498,11 +694,14 @@ func (h *DashboardHandler) getETLStatusIcon(status string) string {
 
 func (h *DashboardHandler) getETLStatusColor(status string) string {
 	colorMap := map[string]string{
+		"success":   "forest",
 		"completed": "forest",
 		"running":   "air",
 		"failed":    "danger",
 		"warning":   "soil",
 		"pending":   "water",
+		"retrying":  "soil",
+		"timeout":   "danger",
 	}
 	if color, ok := colorMap[status]; ok {
 		return color
@@ -536,10 +735,46 @@ func (h *DashboardHandler) getRelativeTime(t time.Time) string {
 // @Success 200 {object} models.Response{data=CoreStatsData} "获取成功"
 // @Router /api/v1/dashboard/realtime [get]
 func (h *DashboardHandler) GetRealTimeData(c *gin.Context) {
-	coreStats := h.getCoreStats()
+	coreStats := h.getCoreStats(middleware.GetDataScope(c))
 	c.JSON(http.StatusOK, models.SuccessResponse(coreStats))
 }
 
+// DashboardScope 解析WebSocket连接用户的数据范围，未登录或解析失败时不能订阅仪表板推送
+func (h *DashboardHandler) DashboardScope(c *gin.Context) (websocket.DashboardScope, bool) {
+	userID := c.GetUint("user_id")
+	if userID == 0 {
+		return websocket.DashboardScope{}, false
+	}
+	scope, err := services.NewDataScopeService().Resolve(userID)
+	if err != nil {
+		h.logger.Warn("Failed to resolve dashboard data scope", zap.Uint("user_id", userID), zap.Error(err))
+		return websocket.DashboardScope{}, false
+	}
+	return websocket.DashboardScope{Unrestricted: scope.Unrestricted, DeviceIDs: scope.DeviceIDs, Regions: scope.Regions}, true
+}
+
+// DashboardSnapshot 按订阅者的数据范围生成WebSocket推送的仪表板快照，最新告警取当天数据，与概览默认周期一致
+func (h *DashboardHandler) DashboardSnapshot(dashboardScope websocket.DashboardScope, contents []string) map[string]interface{} {
+	scope := &services.ResolvedDataScope{
+		Unrestricted: dashboardScope.Unrestricted,
+		DeviceIDs:    dashboardScope.DeviceIDs,
+		Regions:      dashboardScope.Regions,
+	}
+	snapshot := make(map[string]interface{}, len(contents))
+	for _, content := range contents {
+		switch content {
+		case websocket.DashboardCoreStats:
+			snapshot[content] = h.getCoreStats(scope)
+		case websocket.DashboardLatestAlerts:
+			period, _ := newDashboardPeriod("today", time.Now())
+			snapshot[content] = h.getLatestAlerts(period, scope)
+		case websocket.DashboardETLTaskStatus:
+			snapshot[content] = h.getETLTaskStatus()
+		}
+	}
+	return snapshot
+}
+
 // GetChartData 获取图表数据
 // @Summary 获取图表数据
 // @Description 获取数据流趋势和API调用统计图表数据
@@ -550,37 +785,12 @@ func (h *DashboardHandler) GetRealTimeData(c *gin.Context) {
 // @Success 200 {object} models.Response{data=ChartData} "获取成功"
 // @Router /api/v1/dashboard/charts [get]
 func (h *DashboardHandler) GetChartData(c *gin.Context) {
-	period := c.DefaultQuery("period", "today")
+	period, ok := newDashboardPeriod(c.DefaultQuery("period", "today"), time.Now())
+	if !ok {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "时间周期参数错误"))
+		return
+	}
 
-	chartData := h.getChartDataByPeriod(period)
+	chartData := h.getChartData(period, middleware.GetDataScope(c))
 	c.JSON(http.StatusOK, models.SuccessResponse(chartData))
 }
-
-func (h *DashboardHandler) getChartDataByPeriod(period string) ChartData {
-	switch period {
-	case "week":
-		return ChartData{
-			DataFlowTrend: DataFlowTrendData{
-				Labels: []string{"周一", "周二", "周三", "周四", "周五", "周六", "周日"},
-				Values: []float64{1200, 1100, 1300, 1250, 1400, 900, 800},
-			},
-			APICallStats: APICallStatsData{
-				Labels: []string{"空气质量", "水质监测", "污染源", "元数据", "数据导出", "其他"},
-				Values: []int{87500, 62300, 46900, 29400, 26600, 14700},
-			},
-		}
-	case "month":
-		return ChartData{
-			DataFlowTrend: DataFlowTrendData{
-				Labels: []string{"第1周", "第2周", "第3周", "第4周"},
-				Values: []float64{8500, 9200, 8800, 9500},
-			},
-			APICallStats: APICallStatsData{
-				Labels: []string{"空气质量", "水质监测", "污染源", "元数据", "数据导出", "其他"},
-				Values: []int{375000, 267200, 201300, 126400, 114200, 63000},
-			},
-		}
-	default: // today
-		return h.getChartData()
-	}
-}
\ No newline at end of file
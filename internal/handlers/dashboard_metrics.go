@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/env-data-platform/internal/models"
+)
+
+// dashboardOnlineWindow 设备在该时间窗口内有数据上报即视为在线
+const dashboardOnlineWindow = 10 * time.Minute
+
+// 仪表板使用的监测因子编码
+const (
+	factorPM25       = "a34004" // PM2.5
+	factorPM10       = "a34002" // PM10
+	factorCOD        = "w01009" // 化学需氧量
+	factorAmmonia    = "w21001" // 氨氮
+	factorPhosphorus = "w21011" // 总磷
+)
+
+// dashboardPeriod 仪表板统计时间周期
+type dashboardPeriod struct {
+	Name        string
+	Start       time.Time
+	Step        time.Duration
+	Buckets     int
+	SQLFormat   string // 数据库分桶格式
+	KeyLayout   string // 与SQLFormat对应的Go时间格式
+	LabelLayout string // 图表标签格式
+}
+
+// newDashboardPeriod 解析时间周期参数：today按小时分桶，week/month按天分桶
+func newDashboardPeriod(name string, now time.Time) (dashboardPeriod, bool) {
+	today := startOfDay(now)
+	switch name {
+	case "today":
+		return dashboardPeriod{
+			Name: name, Start: today, Step: time.Hour, Buckets: 24,
+			SQLFormat: "%Y-%m-%d %H:00", KeyLayout: "2006-01-02 15:00", LabelLayout: "15:00",
+		}, true
+	case "week":
+		return dashboardPeriod{
+			Name: name, Start: today.AddDate(0, 0, -6), Step: 24 * time.Hour, Buckets: 7,
+			SQLFormat: "%Y-%m-%d", KeyLayout: "2006-01-02", LabelLayout: "01-02",
+		}, true
+	case "month":
+		return dashboardPeriod{
+			Name: name, Start: today.AddDate(0, 0, -29), Step: 24 * time.Hour, Buckets: 30,
+			SQLFormat: "%Y-%m-%d", KeyLayout: "2006-01-02", LabelLayout: "01-02",
+		}, true
+	default:
+		return dashboardPeriod{}, false
+	}
+}
+
+// bucketTime 第i个分桶的起始时间
+func (p dashboardPeriod) bucketTime(i int) time.Time {
+	if p.Step >= 24*time.Hour {
+		return p.Start.AddDate(0, 0, i)
+	}
+	return p.Start.Add(time.Duration(i) * p.Step)
+}
+
+// startOfDay 当天零点
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// factorAggregator 记录各因子的平均值和上报设备
+type factorAggregator struct {
+	averages      map[string]float64
+	devices       map[string]bool
+	onlineDevices map[string]bool
+}
+
+func newFactorAggregator() *factorAggregator {
+	return &factorAggregator{
+		averages:      make(map[string]float64),
+		devices:       make(map[string]bool),
+		onlineDevices: make(map[string]bool),
+	}
+}
+
+// setAverage 记录数据库聚合得到的因子平均值，周期内无数据（NULL）时忽略
+func (a *factorAggregator) setAverage(code string, value sql.NullFloat64) {
+	if value.Valid {
+		a.averages[code] = value.Float64
+	}
+}
+
+func (a *factorAggregator) addDevice(deviceID string, online bool) {
+	a.devices[deviceID] = true
+	if online {
+		a.onlineDevices[deviceID] = true
+	}
+}
+
+// average 因子平均值，无数据时返回false
+func (a *factorAggregator) average(code string) (float64, bool) {
+	value, ok := a.averages[code]
+	return value, ok
+}
+
+func (a *factorAggregator) stationStatus() StationStatus {
+	status := StationStatus{
+		Total:  len(a.devices),
+		Online: len(a.onlineDevices),
+	}
+	if status.Total > 0 {
+		status.Rate = math.Round(float64(status.Online)/float64(status.Total)*1000) / 10
+	}
+	return status
+}
+
+// stationsStatus 根据站点在线情况得出整体状态
+func stationsStatus(status StationStatus) string {
+	switch {
+	case status.Online == 0:
+		return "offline"
+	case status.Online < status.Total:
+		return "warning"
+	default:
+		return "online"
+	}
+}
+
+// extractFactorValues 从解析后的HJ212数据中提取各因子数值
+// 兼容因子位于"factors"键下和直接位于顶层两种存储结构
+// 解析器对缺失的字段填0，因此优先取非零的实时值，其次取非零的均值
+func extractFactorValues(parsed models.JSONMap) map[string]float64 {
+	values := make(map[string]float64)
+	source := map[string]interface{}(parsed)
+	if factors, ok := parsed["factors"].(map[string]interface{}); ok {
+		source = factors
+	}
+
+	for code, raw := range source {
+		factor, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		rtd, hasRtd := toFloat(factor["rtd"])
+		avg, hasAvg := toFloat(factor["avg"])
+		switch {
+		case hasRtd && rtd != 0:
+			values[code] = rtd
+		case hasAvg && avg != 0:
+			values[code] = avg
+		case hasRtd:
+			values[code] = rtd
+		}
+	}
+	return values
+}
+
+// factorValueSQL 读取因子数值的SQL表达式，与 extractFactorValues 一致：
+// 兼容因子位于"factors"键下和直接位于顶层两种存储结构，优先取非零的实时值，其次取非零的均值
+func factorValueSQL(code string) string {
+	field := func(name string) string {
+		return fmt.Sprintf("CAST(JSON_UNQUOTE(COALESCE(JSON_EXTRACT(parsed_data, '$.factors.%[1]s.%[2]s'), JSON_EXTRACT(parsed_data, '$.%[1]s.%[2]s'))) AS DECIMAL(20,6))", code, name)
+	}
+	return fmt.Sprintf("COALESCE(NULLIF(%[1]s, 0), NULLIF(%[2]s, 0), %[1]s)", field("rtd"), field("avg"))
+}
+
+// toFloat 将JSON数值或数值字符串转换为float64
+func toFloat(v interface{}) (float64, bool) {
+	switch value := v.(type) {
+	case float64:
+		return value, true
+	case string:
+		f, err := strconv.ParseFloat(value, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// aqiBreakpoint 空气质量分指数分段
+type aqiBreakpoint struct {
+	Concentration float64
+	IAQI          int
+}
+
+// pm25Breakpoints PM2.5 24小时平均浓度分段（HJ 633-2012，μg/m³）
+var pm25Breakpoints = []aqiBreakpoint{
+	{0, 0}, {35, 50}, {75, 100}, {115, 150}, {150, 200}, {250, 300}, {350, 400}, {500, 500},
+}
+
+// pm10Breakpoints PM10 24小时平均浓度分段（HJ 633-2012，μg/m³）
+var pm10Breakpoints = []aqiBreakpoint{
+	{0, 0}, {50, 50}, {150, 100}, {250, 150}, {350, 200}, {420, 300}, {500, 400}, {600, 500},
+}
+
+// calculateIAQI 按分段线性插值计算空气质量分指数，超出上限按500计
+func calculateIAQI(concentration float64, breakpoints []aqiBreakpoint) int {
+	if concentration <= 0 {
+		return 0
+	}
+	for i := 1; i < len(breakpoints); i++ {
+		low, high := breakpoints[i-1], breakpoints[i]
+		if concentration <= high.Concentration {
+			iaqi := float64(high.IAQI-low.IAQI)/(high.Concentration-low.Concentration)*
+				(concentration-low.Concentration) + float64(low.IAQI)
+			return int(math.Ceil(iaqi))
+		}
+	}
+	return breakpoints[len(breakpoints)-1].IAQI
+}
+
+// aqiMetric 根据(分)指数构建空气质量指标
+func aqiMetric(value, unit string, aqi int) EnvironmentMetric {
+	metric := EnvironmentMetric{Value: value, Unit: unit}
+	switch {
+	case aqi <= 50:
+		metric.Level, metric.Status = "优", "success"
+	case aqi <= 100:
+		metric.Level, metric.Status = "良", "success"
+	case aqi <= 150:
+		metric.Level, metric.Status = "轻度污染", "warning"
+	case aqi <= 200:
+		metric.Level, metric.Status = "中度污染", "warning"
+	case aqi <= 300:
+		metric.Level, metric.Status = "重度污染", "danger"
+	default:
+		metric.Level, metric.Status = "严重污染", "danger"
+	}
+	return metric
+}
+
+// 地表水环境质量标准（GB 3838-2002）Ⅰ~Ⅴ类标准限值，单位mg/L
+var (
+	codGradeLimits        = []float64{15, 15, 20, 30, 40}
+	ammoniaGradeLimits    = []float64{0.15, 0.5, 1.0, 1.5, 2.0}
+	phosphorusGradeLimits = []float64{0.02, 0.1, 0.2, 0.3, 0.4}
+)
+
+var waterGradeNames = []string{"Ⅰ类", "Ⅱ类", "Ⅲ类", "Ⅳ类", "Ⅴ类"}
+
+// waterGrade 返回水质类别序号（0~4对应Ⅰ~Ⅴ类，5为劣Ⅴ类）
+func waterGrade(value float64, limits []float64) int {
+	for i, limit := range limits {
+		if value <= limit {
+			return i
+		}
+	}
+	return len(limits)
+}
+
+// waterMetric 根据水质类别构建水质指标
+func waterMetric(value float64, limits []float64) EnvironmentMetric {
+	metric := EnvironmentMetric{Value: fmt.Sprintf("%.2f", value), Unit: "mg/L", Level: "劣Ⅴ类", Status: "danger"}
+	grade := waterGrade(value, limits)
+	if grade < len(waterGradeNames) {
+		metric.Level = waterGradeNames[grade]
+	}
+	switch {
+	case grade <= 1:
+		metric.Status = "success"
+	case grade == 2:
+		metric.Status = "info"
+	case grade <= 4:
+		metric.Status = "warning"
+	}
+	return metric
+}
+
+// changePercent 计算环比变化百分比，基数为0时返回0
+func changePercent(current, previous float64) float64 {
+	if previous == 0 {
+		return 0
+	}
+	return math.Round((current-previous)/previous*1000) / 10
+}
+
+// trendOf 根据变化量得出趋势
+func trendOf(change float64) string {
+	switch {
+	case change > 0:
+		return "up"
+	case change < 0:
+		return "down"
+	default:
+		return "stable"
+	}
+}
+
+// formatDurationMillis 格式化执行时长（毫秒）
+func formatDurationMillis(ms int64) string {
+	if ms <= 0 {
+		return ""
+	}
+	d := time.Duration(ms) * time.Millisecond
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%d秒", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%d分钟", int(d.Minutes()))
+	default:
+		return fmt.Sprintf("%.1f小时", d.Hours())
+	}
+}
+
+// alertLevel 将告警级别映射为前端展示级别
+func alertLevel(level string) string {
+	switch level {
+	case "critical", "fatal":
+		return "error"
+	case "warning":
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+var alertIcons = map[string]string{
+	"error":   "fas fa-exclamation-circle",
+	"warning": "fas fa-exclamation-triangle",
+	"info":    "fas fa-info-circle",
+}
+
+var alertColors = map[string]string{
+	"error":   "danger",
+	"warning": "warning",
+	"info":    "info",
+}
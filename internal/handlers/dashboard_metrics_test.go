@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/database/dbtest"
+	"github.com/env-data-platform/internal/models"
+	"github.com/env-data-platform/internal/services"
+)
+
+func TestCalculateIAQI(t *testing.T) {
+	assert.Equal(t, 0, calculateIAQI(0, pm25Breakpoints))
+	assert.Equal(t, 50, calculateIAQI(35, pm25Breakpoints))
+	assert.Equal(t, 75, calculateIAQI(55, pm25Breakpoints))
+	assert.Equal(t, 100, calculateIAQI(150, pm10Breakpoints))
+	assert.Equal(t, 500, calculateIAQI(800, pm25Breakpoints))
+}
+
+func TestWaterMetric(t *testing.T) {
+	assert.Equal(t, "Ⅰ类", waterMetric(0.01, phosphorusGradeLimits).Level)
+	assert.Equal(t, "Ⅲ类", waterMetric(0.8, ammoniaGradeLimits).Level)
+	assert.Equal(t, "info", waterMetric(0.8, ammoniaGradeLimits).Status)
+	assert.Equal(t, "劣Ⅴ类", waterMetric(55, codGradeLimits).Level)
+	assert.Equal(t, "danger", waterMetric(55, codGradeLimits).Status)
+}
+
+func TestExtractFactorValues(t *testing.T) {
+	// server.go 的存储结构：因子位于 factors 键下
+	nested := models.JSONMap{
+		"qn": "20240101000000001",
+		"factors": map[string]interface{}{
+			"a34004": map[string]interface{}{"rtd": 35.5, "avg": 0.0},
+			"w01009": map[string]interface{}{"rtd": 0.0, "avg": 12.3},
+		},
+	}
+	assert.Equal(t, map[string]float64{"a34004": 35.5, "w01009": 12.3}, extractFactorValues(nested))
+
+	// server_v2.go 的存储结构：因子直接位于顶层
+	flat := models.JSONMap{
+		"system_code": "22",
+		"a34002":      map[string]interface{}{"rtd": 60.0},
+	}
+	assert.Equal(t, map[string]float64{"a34002": 60}, extractFactorValues(flat))
+}
+
+func TestNewDashboardPeriod(t *testing.T) {
+	now := time.Date(2024, 3, 10, 15, 30, 0, 0, time.Local)
+
+	today, ok := newDashboardPeriod("today", now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2024, 3, 10, 0, 0, 0, 0, time.Local), today.Start)
+	assert.Equal(t, "23:00", today.bucketTime(23).Format(today.LabelLayout))
+
+	week, ok := newDashboardPeriod("week", now)
+	assert.True(t, ok)
+	assert.Equal(t, "03-04", week.bucketTime(0).Format(week.LabelLayout))
+	assert.Equal(t, "03-10", week.bucketTime(week.Buckets-1).Format(week.LabelLayout))
+
+	_, ok = newDashboardPeriod("year", now)
+	assert.False(t, ok)
+}
+
+func TestAlarmAcknowledged(t *testing.T) {
+	now := time.Now()
+	assert.False(t, alarmAcknowledged(&models.HJ212AlarmData{Status: models.AlarmStatusActive}))
+	assert.False(t, alarmAcknowledged(&models.HJ212AlarmData{Status: models.AlarmStatusPending}))
+	assert.True(t, alarmAcknowledged(&models.HJ212AlarmData{Status: models.AlarmStatusAcknowledged, AcknowledgedAt: &now}))
+	assert.True(t, alarmAcknowledged(&models.HJ212AlarmData{Status: models.AlarmStatusClosed}))
+	assert.True(t, alarmAcknowledged(&models.HJ212AlarmData{Status: "unknown", AcknowledgedAt: &now}))
+}
+
+func TestDashboardOverviewCacheKey(t *testing.T) {
+	assert.Equal(t, "overview:today", dashboardOverviewCacheKey("today", &services.ResolvedDataScope{Unrestricted: true}))
+
+	// 受限用户按可见范围区分缓存，范围相同时与设备顺序无关
+	a := dashboardOverviewCacheKey("today", &services.ResolvedDataScope{DeviceIDs: []string{"MN1", "MN2"}})
+	b := dashboardOverviewCacheKey("today", &services.ResolvedDataScope{DeviceIDs: []string{"MN2", "MN1"}})
+	c := dashboardOverviewCacheKey("today", &services.ResolvedDataScope{DeviceIDs: []string{"MN3"}})
+	assert.Equal(t, a, b)
+	assert.NotEqual(t, a, c)
+	assert.NotEqual(t, "overview:today", a)
+	assert.NotEqual(t, dashboardOverviewCacheKey("today", &services.ResolvedDataScope{}), a)
+}
+
+func TestLatestAlertsDeviceScope(t *testing.T) {
+	db := dbtest.New(t)
+	db.Replace(t, &database.DB)
+
+	h := &DashboardHandler{logger: zap.NewNop()}
+	period, _ := newDashboardPeriod("today", time.Now())
+
+	h.getLatestAlerts(period, &services.ResolvedDataScope{DeviceIDs: []string{"MN1", "MN2"}})
+	statements := db.Statements()
+	require.Len(t, statements, 1)
+	assert.Contains(t, statements[0].SQL, "device_id IN (?,?)")
+	assert.Contains(t, statements[0].Vars, "MN1")
+	assert.Contains(t, statements[0].Vars, "MN2")
+
+	// 没有可见设备时不返回任何告警
+	h.getLatestAlerts(period, &services.ResolvedDataScope{})
+	h.getLatestAlerts(period, &services.ResolvedDataScope{Unrestricted: true})
+	sqls := db.SQL()
+	require.Len(t, sqls, 3)
+	assert.Contains(t, sqls[1], "1 = 0")
+	assert.NotContains(t, sqls[2], "device_id")
+}
+
+func TestEnvironmentDataAggregatesInDatabase(t *testing.T) {
+	db := dbtest.New(t)
+	db.Replace(t, &database.DB)
+
+	h := &DashboardHandler{logger: zap.NewNop()}
+	period, _ := newDashboardPeriod("month", time.Now())
+	h.getEnvironmentData(period, &services.ResolvedDataScope{DeviceIDs: []string{"MN1"}})
+
+	// 因子平均值覆盖周期内全部数据，站点状态只读取各设备最近一条
+	statements := db.SQL(dbtest.KindRow)
+	require.GreaterOrEqual(t, len(statements), 2)
+	assert.Contains(t, statements[0], "AVG(")
+	assert.Contains(t, statements[0], "$.factors.a34004.rtd")
+	assert.Contains(t, statements[0], "device_id IN (?)")
+	assert.NotContains(t, statements[0], "LIMIT")
+	assert.Contains(t, statements[1], "id IN (SELECT MAX(id)")
+	assert.Contains(t, statements[1], "GROUP BY `device_id`")
+	assert.NotContains(t, statements[1], "LIMIT")
+}
+
+func TestFactorValueSQL(t *testing.T) {
+	expr := factorValueSQL(factorCOD)
+	// 优先非零实时值，其次非零均值，兼容factors键下和顶层两种结构
+	assert.True(t, strings.HasPrefix(expr, "COALESCE(NULLIF(CAST(JSON_UNQUOTE(COALESCE(JSON_EXTRACT(parsed_data, '$.factors.w01009.rtd')"))
+	assert.Contains(t, expr, "JSON_EXTRACT(parsed_data, '$.w01009.rtd')")
+	assert.Contains(t, expr, "NULLIF(CAST(JSON_UNQUOTE(COALESCE(JSON_EXTRACT(parsed_data, '$.factors.w01009.avg')")
+
+	agg := newFactorAggregator()
+	agg.setAverage(factorCOD, sql.NullFloat64{Float64: 12.5, Valid: true})
+	agg.setAverage(factorAmmonia, sql.NullFloat64{})
+	value, ok := agg.average(factorCOD)
+	assert.True(t, ok)
+	assert.Equal(t, 12.5, value)
+	_, ok = agg.average(factorAmmonia)
+	assert.False(t, ok)
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/middleware"
+	"github.com/env-data-platform/internal/models"
+	"github.com/env-data-platform/internal/services"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// DataScopeHandler 数据权限范围处理器
+type DataScopeHandler struct {
+	db           *gorm.DB
+	logger       *zap.Logger
+	scopeService *services.DataScopeService
+}
+
+// NewDataScopeHandler 创建数据权限范围处理器
+func NewDataScopeHandler(logger *zap.Logger) *DataScopeHandler {
+	return &DataScopeHandler{
+		db:           database.GetDB(),
+		logger:       logger,
+		scopeService: services.NewDataScopeService(),
+	}
+}
+
+// DataScopeRequest 数据范围配置请求
+type DataScopeRequest struct {
+	DeviceIDs []string `json:"device_ids"`
+	Regions   []string `json:"regions"`
+}
+
+// DataScopeResponse 数据范围配置响应
+type DataScopeResponse struct {
+	SubjectType string   `json:"subject_type"`
+	SubjectID   uint     `json:"subject_id"`
+	Configured  bool     `json:"configured"`
+	DeviceIDs   []string `json:"device_ids"`
+	Regions     []string `json:"regions"`
+}
+
+// GetRoleDataScope 获取角色数据范围
+func (h *DataScopeHandler) GetRoleDataScope(c *gin.Context) {
+	h.getDataScope(c, models.DataScopeSubjectRole)
+}
+
+// UpdateRoleDataScope 设置角色数据范围
+func (h *DataScopeHandler) UpdateRoleDataScope(c *gin.Context) {
+	h.updateDataScope(c, models.DataScopeSubjectRole)
+}
+
+// DeleteRoleDataScope 清除角色数据范围
+func (h *DataScopeHandler) DeleteRoleDataScope(c *gin.Context) {
+	h.deleteDataScope(c, models.DataScopeSubjectRole)
+}
+
+// GetUserDataScope 获取用户数据范围
+func (h *DataScopeHandler) GetUserDataScope(c *gin.Context) {
+	h.getDataScope(c, models.DataScopeSubjectUser)
+}
+
+// UpdateUserDataScope 设置用户数据范围，优先于角色配置
+func (h *DataScopeHandler) UpdateUserDataScope(c *gin.Context) {
+	h.updateDataScope(c, models.DataScopeSubjectUser)
+}
+
+// DeleteUserDataScope 清除用户数据范围，回退到角色配置
+func (h *DataScopeHandler) DeleteUserDataScope(c *gin.Context) {
+	h.deleteDataScope(c, models.DataScopeSubjectUser)
+}
+
+// GetCurrentDataScope 获取当前用户生效的数据范围
+func (h *DataScopeHandler) GetCurrentDataScope(c *gin.Context) {
+	c.JSON(http.StatusOK, models.SuccessResponse(middleware.GetDataScope(c)))
+}
+
+// getDataScope 查询角色或用户的数据范围配置
+func (h *DataScopeHandler) getDataScope(c *gin.Context, subjectType string) {
+	subjectID, ok := h.parseSubject(c, subjectType)
+	if !ok {
+		return
+	}
+
+	scope, err := h.scopeService.GetScope(subjectType, subjectID)
+	if err != nil {
+		h.logger.Error("Failed to get data scope", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(toDataScopeResponse(subjectType, subjectID, scope)))
+}
+
+// updateDataScope 保存角色或用户的数据范围配置
+func (h *DataScopeHandler) updateDataScope(c *gin.Context, subjectType string) {
+	subjectID, ok := h.parseSubject(c, subjectType)
+	if !ok {
+		return
+	}
+
+	var req DataScopeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
+		return
+	}
+
+	scope, err := h.scopeService.SaveScope(subjectType, subjectID, req.DeviceIDs, req.Regions)
+	if err != nil {
+		h.logger.Error("Failed to save data scope", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "保存失败"))
+		return
+	}
+
+	h.logger.Info("Data scope updated",
+		zap.String("subject_type", subjectType),
+		zap.Uint("subject_id", subjectID),
+		zap.Uint("operator_id", c.GetUint("user_id")))
+	c.JSON(http.StatusOK, models.SuccessResponse(toDataScopeResponse(subjectType, subjectID, scope)))
+}
+
+// deleteDataScope 删除角色或用户的数据范围配置
+func (h *DataScopeHandler) deleteDataScope(c *gin.Context, subjectType string) {
+	subjectID, ok := h.parseSubject(c, subjectType)
+	if !ok {
+		return
+	}
+
+	if err := h.scopeService.DeleteScope(subjectType, subjectID); err != nil {
+		h.logger.Error("Failed to delete data scope", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "删除失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(nil))
+}
+
+// parseSubject 解析路径中的角色或用户ID并校验其存在
+func (h *DataScopeHandler) parseSubject(c *gin.Context, subjectType string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "无效的ID"))
+		return 0, false
+	}
+
+	var model interface{} = &models.Role{}
+	notFound := "角色不存在"
+	if subjectType == models.DataScopeSubjectUser {
+		model = &models.User{}
+		notFound = "用户不存在"
+	}
+
+	var count int64
+	if err := h.db.Model(model).Where("id = ?", id).Count(&count).Error; err != nil {
+		h.logger.Error("Failed to check data scope subject", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return 0, false
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, notFound))
+		return 0, false
+	}
+
+	return uint(id), true
+}
+
+// toDataScopeResponse 转换数据范围配置为响应结构
+func toDataScopeResponse(subjectType string, subjectID uint, scope *models.DataScope) *DataScopeResponse {
+	resp := &DataScopeResponse{
+		SubjectType: subjectType,
+		SubjectID:   subjectID,
+		DeviceIDs:   []string{},
+		Regions:     []string{},
+	}
+	if scope != nil {
+		resp.Configured = true
+		resp.DeviceIDs = append(resp.DeviceIDs, services.SplitScopeValues(scope.DeviceIDs)...)
+		resp.Regions = append(resp.Regions, services.SplitScopeValues(scope.Regions)...)
+	}
+	return resp
+}
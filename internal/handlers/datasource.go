@@ -3,10 +3,13 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/middleware"
 	"github.com/env-data-platform/internal/models"
 	"github.com/env-data-platform/internal/services"
 	"github.com/gin-gonic/gin"
@@ -20,6 +23,8 @@ type DataSourceHandler struct {
 	logger            *zap.Logger
 	connectionService *services.ConnectionTestService
 	metadataService   *services.MetadataSyncService
+	snapshotService   *services.MetadataSnapshotService
+	previewService    *services.DataPreviewService
 }
 
 // NewDataSourceHandler 创建数据源处理器
@@ -29,25 +34,72 @@ func NewDataSourceHandler(logger *zap.Logger) *DataSourceHandler {
 		logger:            logger,
 		connectionService: services.NewConnectionTestService(),
 		metadataService:   services.NewMetadataSyncService(),
+		snapshotService:   services.NewMetadataSnapshotService(),
+		previewService:    services.NewDataPreviewService(),
 	}
 }
 
+// dataSourceListQuery 数据源列表的关键字搜索列和可排序字段
+var dataSourceListQuery = database.ListQuery{
+	SearchColumns: []string{"name", "description", "type"},
+	SortColumns: map[string]string{
+		"created_at":     "created_at",
+		"updated_at":     "updated_at",
+		"last_active_at": "last_active_at",
+		"last_test_at":   "last_test_at",
+		"name":           "name",
+		"priority":       "priority",
+	},
+	DefaultSort: "created_at:desc",
+}
+
+// findDataSource 在当前用户的数据范围内按ID查询数据源，范围外的数据源与不存在一样返回404
+func (h *DataSourceHandler) findDataSource(c *gin.Context, id uint64, dataSource *models.DataSource) bool {
+	if err := h.db.Scopes(middleware.DataSourceScope(c)).First(dataSource, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "数据源不存在"))
+			return false
+		}
+		h.logger.Error("Failed to get data source", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return false
+	}
+	return true
+}
+
+// dataSourceScopeAllowed 校验数据源绑定的设备、区域在当前用户的数据范围内，避免越权创建或迁出范围
+func dataSourceScopeAllowed(c *gin.Context, req *models.DataSourceRequest) bool {
+	if middleware.DataSourceAllowed(c, req.DeviceID, req.Region) {
+		return true
+	}
+	c.JSON(http.StatusForbidden, models.ErrorResponse(http.StatusForbidden, "设备或区域超出数据权限范围"))
+	return false
+}
+
 // ListDataSources 获取数据源列表
+//
+// keyword 跨名称、描述、类型模糊搜索；health_status 按巡检健康状态筛选；
+// sort 支持多字段排序，如 sort=last_active_at:desc,created_at:desc
 func (h *DataSourceHandler) ListDataSources(c *gin.Context) {
 	var req struct {
-		Page     int    `form:"page" binding:"required,min=1"`
-		PageSize int    `form:"page_size" binding:"required,min=1,max=100"`
-		Name     string `form:"name"`
-		Type     string `form:"type"`
-		Status   string `form:"status"`
+		models.PageRequest
+		Name   string `form:"name"`
+		Type   string `form:"type"`
+		Status string `form:"status"`
+		Health string `form:"health_status"`
 	}
 
-	if err := c.ShouldBindQuery(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "参数错误"))
+	if !bindPageQuery(c, &req) {
+		return
+	}
+
+	sortScope, err := dataSourceListQuery.Sort(req.Sort, req.Order)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, err.Error()))
 		return
 	}
 
-	query := h.db.Model(&models.DataSource{})
+	query := h.db.Model(&models.DataSource{}).Scopes(middleware.DataSourceScope(c), dataSourceListQuery.Search(req.Keyword))
 
 	if req.Name != "" {
 		query = query.Where("name LIKE ?", "%"+req.Name+"%")
@@ -58,14 +110,18 @@ func (h *DataSourceHandler) ListDataSources(c *gin.Context) {
 	if req.Status != "" {
 		query = query.Where("status = ?", req.Status)
 	}
+	if req.Health != "" {
+		query = query.Where("health_status = ?", req.Health)
+	}
 
 	var total int64
 	query.Count(&total)
 
 	var dataSources []models.DataSource
-	offset := (req.Page - 1) * req.PageSize
+	offset := req.Offset()
 	if err := query.Offset(offset).Limit(req.PageSize).
 		Preload("Creator").
+		Scopes(sortScope).
 		Find(&dataSources).Error; err != nil {
 		h.logger.Error("Failed to list data sources", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
@@ -84,7 +140,10 @@ func (h *DataSourceHandler) ListDataSources(c *gin.Context) {
 func (h *DataSourceHandler) CreateDataSource(c *gin.Context) {
 	var req models.DataSourceRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "参数错误"))
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
+		return
+	}
+	if !dataSourceScopeAllowed(c, &req) {
 		return
 	}
 
@@ -102,6 +161,8 @@ func (h *DataSourceHandler) CreateDataSource(c *gin.Context) {
 		Name:        req.Name,
 		Type:        req.Type,
 		Description: req.Description,
+		DeviceID:    req.DeviceID,
+		Region:      req.Region,
 		Config:      string(configBytes),
 		ConfigData:  configBytes,
 		Status:      "active",
@@ -116,6 +177,7 @@ func (h *DataSourceHandler) CreateDataSource(c *gin.Context) {
 		return
 	}
 
+	invalidateDashboardCache(c, h.logger)
 	c.JSON(http.StatusOK, models.SuccessResponse(dataSource))
 }
 
@@ -128,7 +190,8 @@ func (h *DataSourceHandler) GetDataSource(c *gin.Context) {
 	}
 
 	var dataSource models.DataSource
-	if err := h.db.Preload("Creator").Preload("Updater").
+	if err := h.db.Scopes(middleware.DataSourceScope(c)).
+		Preload("Creator").Preload("Updater").
 		First(&dataSource, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "数据源不存在"))
@@ -152,18 +215,20 @@ func (h *DataSourceHandler) UpdateDataSource(c *gin.Context) {
 
 	var req models.DataSourceRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "参数错误"))
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
 		return
 	}
 
 	var dataSource models.DataSource
-	if err := h.db.First(&dataSource, id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "数据源不存在"))
-			return
-		}
-		h.logger.Error("Failed to get data source", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+	if !h.findDataSource(c, id, &dataSource) {
+		return
+	}
+	if !dataSourceScopeAllowed(c, &req) {
+		return
+	}
+	// 文件导入的数据源固定连接平台数据库中的导入表，不能改为其他类型
+	if dataSource.IsImported() && req.Type != dataSource.Type {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "文件导入的数据源不能修改类型"))
 		return
 	}
 
@@ -179,6 +244,8 @@ func (h *DataSourceHandler) UpdateDataSource(c *gin.Context) {
 		"name":        req.Name,
 		"type":        req.Type,
 		"description": req.Description,
+		"device_id":   req.DeviceID,
+		"region":      req.Region,
 		"config":      string(configBytes),
 		"priority":    req.Priority,
 		"updated_by":  c.GetUint("user_id"),
@@ -190,6 +257,7 @@ func (h *DataSourceHandler) UpdateDataSource(c *gin.Context) {
 		return
 	}
 
+	invalidateDashboardCache(c, h.logger)
 	c.JSON(http.StatusOK, models.SuccessResponse(dataSource))
 }
 
@@ -202,13 +270,7 @@ func (h *DataSourceHandler) DeleteDataSource(c *gin.Context) {
 	}
 
 	var dataSource models.DataSource
-	if err := h.db.First(&dataSource, id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "数据源不存在"))
-			return
-		}
-		h.logger.Error("Failed to get data source", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+	if !h.findDataSource(c, id, &dataSource) {
 		return
 	}
 
@@ -226,6 +288,7 @@ func (h *DataSourceHandler) DeleteDataSource(c *gin.Context) {
 		return
 	}
 
+	invalidateDashboardCache(c, h.logger)
 	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{"message": "删除成功"}))
 }
 
@@ -238,13 +301,7 @@ func (h *DataSourceHandler) TestDataSource(c *gin.Context) {
 	}
 
 	var dataSource models.DataSource
-	if err := h.db.First(&dataSource, id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "数据源不存在"))
-			return
-		}
-		h.logger.Error("Failed to get data source", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+	if !h.findDataSource(c, id, &dataSource) {
 		return
 	}
 
@@ -289,19 +346,31 @@ func (h *DataSourceHandler) SyncDataSource(c *gin.Context) {
 	}
 
 	var dataSource models.DataSource
-	if err := h.db.First(&dataSource, id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "数据源不存在"))
-			return
-		}
-		h.logger.Error("Failed to get data source", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+	if !h.findDataSource(c, id, &dataSource) {
+		return
+	}
+
+	// 列统计代价较高，按请求开启
+	var opts struct {
+		ColumnStats     bool  `form:"column_stats"`
+		SampleThreshold int64 `form:"sample_threshold" binding:"min=0"`
+		SampleSize      int64 `form:"sample_size" binding:"min=0,max=1000000"`
+		SampleValues    int   `form:"sample_values" binding:"min=0,max=20"`
+	}
+	if err := c.ShouldBindQuery(&opts); err != nil {
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &opts))
 		return
 	}
 
 	// 执行真实的元数据同步
 	ctx := context.Background()
-	result := h.metadataService.SyncMetadata(ctx, &dataSource)
+	dataSource.ConfigData = json.RawMessage(dataSource.Config)
+	result := h.metadataService.SyncMetadataWithOptions(ctx, &dataSource, services.MetadataSyncOptions{
+		ColumnStats:     opts.ColumnStats,
+		SampleThreshold: opts.SampleThreshold,
+		SampleSize:      opts.SampleSize,
+		SampleValues:    opts.SampleValues,
+	})
 
 	// 更新同步时间和状态
 	updates := map[string]interface{}{
@@ -323,13 +392,119 @@ func (h *DataSourceHandler) SyncDataSource(c *gin.Context) {
 		"synced_at": result.SyncedAt,
 	}
 
-	if result.Success {
-		c.JSON(http.StatusOK, models.SuccessResponse(responseData))
-	} else {
+	if !result.Success {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, result.Message))
+		return
+	}
+
+	// 保存元数据版本并返回相对上一版本的变更
+	saved, err := h.snapshotService.Save(dataSource.ID, result)
+	if err != nil {
+		h.logger.Error("Failed to save metadata snapshot", zap.Error(err), zap.Uint("data_source_id", dataSource.ID))
+	} else {
+		diff := saved.Diff
+		responseData["version"] = saved.Snapshot.Version
+		responseData["changes"] = diff
+		responseData["applied"] = saved.Applied
+		if diff.HasChanges && diff.FromVersion > 0 {
+			h.logger.Info("Data source schema changed",
+				zap.Uint("data_source_id", dataSource.ID),
+				zap.Int("from_version", diff.FromVersion),
+				zap.Int("to_version", diff.ToVersion),
+				zap.Int("tables_added", len(diff.AddedTables)),
+				zap.Int("tables_removed", len(diff.RemovedTables)),
+				zap.Int("tables_modified", len(diff.ModifiedTables)))
+		}
 	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(responseData))
 }
 
+// GetMetadataDiff 获取数据源两个元数据版本之间的变更
+// 默认比较最新版本与其上一版本，可通过from、to指定版本号
+func (h *DataSourceHandler) GetMetadataDiff(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "无效的ID"))
+		return
+	}
+
+	var req struct {
+		From int `form:"from" binding:"min=0"`
+		To   int `form:"to" binding:"min=0"`
+	}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
+		return
+	}
+
+	var dataSource models.DataSource
+	if !h.findDataSource(c, id, &dataSource) {
+		return
+	}
+
+	diff, err := h.snapshotService.Diff(uint(id), req.From, req.To)
+	if err != nil {
+		if errors.Is(err, services.ErrSnapshotNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "元数据版本不存在，请先同步数据源"))
+			return
+		}
+		h.logger.Error("Failed to diff metadata", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "计算元数据变更失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(diff))
+}
+
+
+// PreviewData 预览数据源样例数据
+//
+// 按表名或只读查询返回前N行，供创建ETL作业配置字段映射时参考；行数受上限约束，敏感列脱敏
+// 自定义查询仅限超级管理员
+func (h *DataSourceHandler) PreviewData(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "无效的ID"))
+		return
+	}
+
+	var req services.DataPreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
+		return
+	}
+
+	// 脱敏按结果列名匹配，自定义查询可用别名或表达式绕过，仅允许超级管理员使用
+	if strings.TrimSpace(req.Query) != "" && !middleware.IsSuperAdmin(c) {
+		c.JSON(http.StatusForbidden, models.CodeErrorResponse(models.CodePermissionDenied, "仅超级管理员可使用自定义查询预览"))
+		return
+	}
+
+	var dataSource models.DataSource
+	if !h.findDataSource(c, id, &dataSource) {
+		return
+	}
+
+	preview, err := h.previewService.Preview(c.Request.Context(), &dataSource, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidPreviewRequest):
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, err.Error()))
+		case errors.Is(err, context.DeadlineExceeded):
+			c.JSON(http.StatusGatewayTimeout, models.ErrorResponse(http.StatusGatewayTimeout, err.Error()))
+		default:
+			h.logger.Warn("Failed to preview data source",
+				zap.Uint64("data_source_id", id),
+				zap.String("table", req.Table),
+				zap.Error(err))
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "预览失败: "+err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(preview))
+}
 
 // GetDataSourceTables 获取数据源的表列表
 func (h *DataSourceHandler) GetDataSourceTables(c *gin.Context) {
@@ -339,8 +514,16 @@ func (h *DataSourceHandler) GetDataSourceTables(c *gin.Context) {
 		return
 	}
 
+	var dataSource models.DataSource
+	if !h.findDataSource(c, id, &dataSource) {
+		return
+	}
+
 	var tables []models.DataTable
 	if err := h.db.Where("data_source_id = ?", id).
+		Preload("Columns", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Preload("Indexes", func(db *gorm.DB) *gorm.DB { return db.Order("index_name") }).
+		Order("`schema`, name").
 		Find(&tables).Error; err != nil {
 		h.logger.Error("Failed to get data source tables", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
@@ -348,4 +531,74 @@ func (h *DataSourceHandler) GetDataSourceTables(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, models.SuccessResponse(tables))
-}
\ No newline at end of file
+}
+
+// GetMetadata 获取数据源元数据，默认最新版本，可通过version查询历史版本
+func (h *DataSourceHandler) GetMetadata(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "无效的ID"))
+		return
+	}
+
+	var req struct {
+		Version int `form:"version" binding:"min=0"`
+	}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
+		return
+	}
+
+	var dataSource models.DataSource
+	if !h.findDataSource(c, id, &dataSource) {
+		return
+	}
+
+	snapshot, tables, err := h.snapshotService.Tables(uint(id), req.Version)
+	if err != nil {
+		if errors.Is(err, services.ErrSnapshotNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "元数据版本不存在，请先同步数据源"))
+			return
+		}
+		h.logger.Error("Failed to get metadata snapshot", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{
+		"version":        snapshot.Version,
+		"checksum":       snapshot.Checksum,
+		"table_count":    snapshot.TableCount,
+		"created_at":     snapshot.CreatedAt,
+		"last_synced_at": snapshot.LastSyncedAt,
+		"tables":         tables,
+	}))
+}
+
+// ListMetadataVersions 获取数据源元数据版本列表
+func (h *DataSourceHandler) ListMetadataVersions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "无效的ID"))
+		return
+	}
+
+	var req models.PageRequest
+	if !bindPageQuery(c, &req) {
+		return
+	}
+
+	var dataSource models.DataSource
+	if !h.findDataSource(c, id, &dataSource) {
+		return
+	}
+
+	snapshots, total, err := h.snapshotService.List(uint(id), req.Page, req.PageSize)
+	if err != nil {
+		h.logger.Error("Failed to list metadata versions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(models.NewPageResponse(snapshots, total, req.Page, req.PageSize)))
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/database/dbtest"
+	"github.com/env-data-platform/internal/models"
+	"github.com/env-data-platform/internal/services"
+)
+
+// scopedDataSourceHandler 返回使用DryRun数据库的处理器，found为false时按ID查询一律视为不存在
+func scopedDataSourceHandler(t *testing.T, found bool) (*DataSourceHandler, *dbtest.DB) {
+	db := dbtest.New(t)
+	db.On(dbtest.KindQuery, func(tx *gorm.DB) {
+		if !found {
+			tx.AddError(gorm.ErrRecordNotFound)
+		} else if dataSource, ok := tx.Statement.Dest.(*models.DataSource); ok {
+			dataSource.ID = 7
+		}
+	})
+	return &DataSourceHandler{db: db.DB, logger: zap.NewNop()}, db
+}
+
+func serveDataSource(handler gin.HandlerFunc, scope *services.ResolvedDataScope, method, target, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, target, strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: "7"}}
+	c.Set("user_id", uint(3))
+	c.Set("data_scope", scope)
+	handler(c)
+	return w
+}
+
+func TestDataSourceMutationsRespectDataScope(t *testing.T) {
+	scope := &services.ResolvedDataScope{DeviceIDs: []string{"MN001"}, Regions: []string{"east"}}
+	inScope := `{"name":"s","type":"mysql","device_id":"MN001","config":{}}`
+
+	h, db := scopedDataSourceHandler(t, false)
+	cases := []struct {
+		name    string
+		handler gin.HandlerFunc
+		method  string
+		body    string
+	}{
+		{"update", h.UpdateDataSource, http.MethodPut, inScope},
+		{"delete", h.DeleteDataSource, http.MethodDelete, ""},
+		{"test", h.TestDataSource, http.MethodPost, ""},
+		{"sync", h.SyncDataSource, http.MethodPost, ""},
+		{"tables", h.GetDataSourceTables, http.MethodGet, ""},
+		{"metadata", h.GetMetadata, http.MethodGet, ""},
+		{"metadata versions", h.ListMetadataVersions, http.MethodGet, ""},
+		{"metadata diff", h.GetMetadataDiff, http.MethodGet, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db.Reset()
+			w := serveDataSource(tc.handler, scope, tc.method, "/api/v1/datasources/7", tc.body)
+			assert.Equal(t, http.StatusNotFound, w.Code)
+			statements := db.Statements()
+			require.Len(t, statements, 1)
+			assert.Contains(t, statements[0].SQL, "(device_id IN (?) OR region IN (?))")
+			assert.Contains(t, statements[0].Vars, "MN001")
+			assert.Contains(t, statements[0].Vars, "east")
+		})
+	}
+
+	// 无任何授权范围的用户看不到任何数据源
+	db.Reset()
+	w := serveDataSource(h.DeleteDataSource, &services.ResolvedDataScope{}, http.MethodDelete, "/api/v1/datasources/7", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	statements := db.SQL()
+	require.Len(t, statements, 1)
+	assert.Contains(t, statements[0], "1 = 0")
+}
+
+func TestDataSourceWritesOutsideScope(t *testing.T) {
+	scope := &services.ResolvedDataScope{DeviceIDs: []string{"MN001"}, Regions: []string{"east"}}
+	h, db := scopedDataSourceHandler(t, true)
+
+	for _, body := range []string{
+		`{"name":"s","type":"mysql","device_id":"MN002","config":{}}`,
+		`{"name":"s","type":"mysql","region":"west","config":{}}`,
+		`{"name":"s","type":"mysql","config":{}}`,
+	} {
+		w := serveDataSource(h.CreateDataSource, scope, http.MethodPost, "/api/v1/datasources", body)
+		assert.Equal(t, http.StatusForbidden, w.Code, body)
+	}
+	assert.Empty(t, db.Statements())
+
+	w := serveDataSource(h.CreateDataSource, scope, http.MethodPost, "/api/v1/datasources", `{"name":"s","type":"mysql","region":"east","config":{}}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	statements := db.Statements()
+	require.Len(t, statements, 1)
+	assert.Equal(t, dbtest.KindCreate, statements[0].Kind)
+	assert.Equal(t, "env_data_sources", statements[0].Table)
+	assert.Contains(t, statements[0].Vars, "east")
+
+	// 不受限用户可绑定任意设备
+	db.Reset()
+	w = serveDataSource(h.CreateDataSource, &services.ResolvedDataScope{Unrestricted: true}, http.MethodPost, "/api/v1/datasources", `{"name":"s","type":"mysql","device_id":"MN002","config":{}}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 更新时不能把范围内的数据源迁到范围外
+	db.Reset()
+	w = serveDataSource(h.UpdateDataSource, scope, http.MethodPut, "/api/v1/datasources/7", `{"name":"s","type":"mysql","device_id":"MN002","config":{}}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	statements = db.Statements()
+	require.Len(t, statements, 1)
+	assert.Equal(t, dbtest.KindQuery, statements[0].Kind)
+
+	db.Reset()
+	w = serveDataSource(h.UpdateDataSource, scope, http.MethodPut, "/api/v1/datasources/7", `{"name":"s","type":"mysql","device_id":"MN001","config":{}}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	statements = db.Statements()
+	require.Len(t, statements, 2)
+	assert.True(t, strings.HasPrefix(statements[1].SQL, "UPDATE `env_data_sources`"))
+	assert.Contains(t, statements[1].Vars, "MN001")
+}
+
+func TestPreviewQueryRequiresSuperAdmin(t *testing.T) {
+	h, db := scopedDataSourceHandler(t, true)
+	h.previewService = services.NewDataPreviewService()
+
+	// 别名可绕过按列名脱敏，普通用户不能使用自定义查询
+	w := serveDataSource(h.PreviewData, &services.ResolvedDataScope{Unrestricted: true}, http.MethodPost,
+		"/api/v1/datasources/7/preview", `{"query":"SELECT password AS p FROM users"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, db.Statements())
+
+	// 按表预览不受限制，继续校验数据源
+	w = serveDataSource(h.PreviewData, &services.ResolvedDataScope{Unrestricted: true}, http.MethodPost,
+		"/api/v1/datasources/7/preview", `{"table":"users"}`)
+	assert.NotEqual(t, http.StatusForbidden, w.Code)
+	assert.NotEmpty(t, db.Statements())
+}
@@ -3,7 +3,9 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
@@ -35,23 +37,42 @@ func NewETLHandler(logger *zap.Logger) *ETLHandler {
 	}
 }
 
+// etlJobListQuery ETL作业列表的关键字搜索列和可排序字段
+var etlJobListQuery = database.ListQuery{
+	SearchColumns: []string{"name", "description"},
+	SortColumns: map[string]string{
+		"created_at":  "created_at",
+		"updated_at":  "updated_at",
+		"last_run_at": "last_run_at",
+		"next_run_at": "next_run_at",
+		"name":        "name",
+		"priority":    "priority",
+	},
+	DefaultSort: "created_at:desc",
+}
+
 // ListETLJobs 获取ETL作业列表
 func (h *ETLHandler) ListETLJobs(c *gin.Context) {
 	var req struct {
-		Page     int    `form:"page" binding:"required,min=1"`
-		PageSize int    `form:"page_size" binding:"required,min=1,max=100"`
+		models.PageRequest
 		Name     string `form:"name"`
 		Status   string `form:"status"`
+		IsPaused *bool  `form:"is_paused"`
 		SourceID uint   `form:"source_id"`
 		TargetID uint   `form:"target_id"`
 	}
 
-	if err := c.ShouldBindQuery(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "参数错误"))
+	if !bindPageQuery(c, &req) {
+		return
+	}
+
+	sortScope, err := etlJobListQuery.Sort(req.Sort, req.Order)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, err.Error()))
 		return
 	}
 
-	query := h.db.Model(&models.ETLJob{})
+	query := h.db.Model(&models.ETLJob{}).Scopes(etlJobListQuery.Search(req.Keyword))
 
 	if req.Name != "" {
 		query = query.Where("name LIKE ?", "%"+req.Name+"%")
@@ -59,6 +80,9 @@ func (h *ETLHandler) ListETLJobs(c *gin.Context) {
 	if req.Status != "" {
 		query = query.Where("status = ?", req.Status)
 	}
+	if req.IsPaused != nil {
+		query = query.Where("is_paused = ?", *req.IsPaused)
+	}
 	if req.SourceID > 0 {
 		query = query.Where("source_id = ?", req.SourceID)
 	}
@@ -70,10 +94,10 @@ func (h *ETLHandler) ListETLJobs(c *gin.Context) {
 	query.Count(&total)
 
 	var jobs []models.ETLJob
-	offset := (req.Page - 1) * req.PageSize
+	offset := req.Offset()
 	if err := query.Offset(offset).Limit(req.PageSize).
 		Preload("Source").Preload("Target").Preload("Creator").
-		Order("created_at DESC").
+		Scopes(sortScope).
 		Find(&jobs).Error; err != nil {
 		h.logger.Error("Failed to list ETL jobs", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
@@ -92,7 +116,13 @@ func (h *ETLHandler) ListETLJobs(c *gin.Context) {
 func (h *ETLHandler) CreateETLJob(c *gin.Context) {
 	var req models.ETLJobRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "参数错误"))
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
+		return
+	}
+
+	nextRunAt, err := validateCronExpr(req.CronExpr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, err.Error()))
 		return
 	}
 
@@ -129,6 +159,9 @@ func (h *ETLHandler) CreateETLJob(c *gin.Context) {
 	}
 	job.CreatedBy = userID
 	job.UpdatedBy = userID
+	if job.IsEnabled {
+		job.NextRunAt = nextRunAt
+	}
 
 	// 设置配置数据
 	if err := job.SetConfig(req.Config); err != nil {
@@ -150,6 +183,7 @@ func (h *ETLHandler) CreateETLJob(c *gin.Context) {
 		}
 	}
 
+	invalidateDashboardCache(c, h.logger)
 	c.JSON(http.StatusOK, models.SuccessResponse(job))
 }
 
@@ -186,7 +220,7 @@ func (h *ETLHandler) UpdateETLJob(c *gin.Context) {
 
 	var req models.ETLJobRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "参数错误"))
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
 		return
 	}
 
@@ -207,6 +241,15 @@ func (h *ETLHandler) UpdateETLJob(c *gin.Context) {
 		return
 	}
 
+	nextRunAt, err := validateCronExpr(req.CronExpr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, err.Error()))
+		return
+	}
+	if !req.IsEnabled || job.IsPaused {
+		nextRunAt = nil
+	}
+
 	// 验证数据源是否存在
 	var sourceExists, targetExists bool
 	h.db.Model(&models.DataSource{}).Where("id = ?", req.SourceID).Select("count(*) > 0").Find(&sourceExists)
@@ -242,6 +285,7 @@ func (h *ETLHandler) UpdateETLJob(c *gin.Context) {
 		"priority":     req.Priority,
 		"max_retries":  req.MaxRetries,
 		"timeout":      req.Timeout,
+		"next_run_at":  nextRunAt,
 		"updated_by":   c.GetUint("user_id"),
 	}
 
@@ -251,14 +295,15 @@ func (h *ETLHandler) UpdateETLJob(c *gin.Context) {
 		return
 	}
 
-	// 重新调度作业
+	// 重新调度作业，暂停中的作业等恢复时再挂载
 	h.scheduler.UnscheduleJob(uint(id))
-	if req.IsEnabled && req.CronExpr != "" {
+	if req.IsEnabled && req.CronExpr != "" && !job.IsPaused {
 		if err := h.scheduler.ScheduleJob(&job); err != nil {
 			h.logger.Warn("Failed to reschedule job", zap.Error(err), zap.Uint("job_id", job.ID))
 		}
 	}
 
+	invalidateDashboardCache(c, h.logger)
 	c.JSON(http.StatusOK, models.SuccessResponse(job))
 }
 
@@ -304,6 +349,7 @@ func (h *ETLHandler) DeleteETLJob(c *gin.Context) {
 		return
 	}
 
+	invalidateDashboardCache(c, h.logger)
 	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{"message": "删除成功"}))
 }
 
@@ -320,7 +366,7 @@ func (h *ETLHandler) ExecuteETLJob(c *gin.Context) {
 		Parameters  map[string]interface{} `json:"parameters"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "参数错误"))
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
 		return
 	}
 
@@ -340,33 +386,48 @@ func (h *ETLHandler) ExecuteETLJob(c *gin.Context) {
 		return
 	}
 
-	if job.Status == "running" {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "作业正在运行"))
+	if job.IsPaused {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "作业已暂停，请先恢复"))
+		return
+	}
+
+	// 取得作业执行锁，与定时调度或其他请求并发触发时只有一个能执行
+	if err := services.ClaimETLJobRun(h.db, job.ID); err != nil {
+		if errors.Is(err, services.ErrETLJobRunning) {
+			c.JSON(http.StatusConflict, models.ErrorResponse(http.StatusConflict, err.Error()))
+			return
+		}
+		h.logger.Error("Failed to lock ETL job", zap.Uint("job_id", job.ID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "启动作业失败"))
 		return
 	}
 
 	// 创建执行记录
+	triggerBy := c.GetUint("user_id")
 	execution := models.ETLExecution{
 		JobID:       job.ID,
 		ExecutionID: generateExecutionID(),
 		Status:      "running",
 		StartTime:   time.Now(),
 		TriggerType: req.TriggerType,
-		TriggerBy:   c.GetUint("user_id"),
+		TriggerBy:   &triggerBy,
 	}
 
 	if err := h.db.Create(&execution).Error; err != nil {
 		h.logger.Error("Failed to create execution record", zap.Error(err))
+		if err := services.ReleaseETLJobRun(h.db, job.ID); err != nil {
+			h.logger.Error("Failed to release ETL job lock", zap.Uint("job_id", job.ID), zap.Error(err))
+		}
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "创建执行记录失败"))
 		return
 	}
 
-	// 更新作业状态
+	// 更新作业运行信息，状态已在取锁时置为运行中
 	h.db.Model(&job).Updates(map[string]interface{}{
-		"status":       "running",
-		"last_run_at":  gorm.Expr("NOW()"),
-		"run_count":    gorm.Expr("run_count + 1"),
+		"last_run_at": gorm.Expr("NOW()"),
+		"run_count":   gorm.Expr("run_count + 1"),
 	})
+	services.NotifyDashboardChange(services.DashboardTriggerETL)
 
 	// 异步执行ETL作业
 	go h.executeJobAsync(&job, &execution, req.Parameters)
@@ -424,46 +485,236 @@ func (h *ETLHandler) StopETLJob(c *gin.Context) {
 	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{"message": "作业已停止"}))
 }
 
-// ListETLExecutions 获取ETL执行记录列表
-func (h *ETLHandler) ListETLExecutions(c *gin.Context) {
+// CloneETLJob 基于现有作业复制出新作业，新作业默认禁用且不包含执行历史
+func (h *ETLHandler) CloneETLJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "无效的ID"))
+		return
+	}
+
 	var req struct {
-		Page        int    `form:"page" binding:"required,min=1"`
-		PageSize    int    `form:"page_size" binding:"required,min=1,max=100"`
-		JobID       uint   `form:"job_id"`
-		Status      string `form:"status"`
-		TriggerType string `form:"trigger_type"`
-		StartDate   string `form:"start_date"`
-		EndDate     string `form:"end_date"`
+		Name string `json:"name" binding:"max=100"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
+		return
+	}
+
+	var source models.ETLJob
+	if err := h.db.First(&source, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "ETL作业不存在"))
+			return
+		}
+		h.logger.Error("Failed to get ETL job", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return
 	}
 
+	name := req.Name
+	if name == "" {
+		name = cloneJobName(source.Name)
+	}
+
+	userID := c.GetUint("user_id")
+	job := models.ETLJob{
+		Name:        name,
+		Description: source.Description,
+		SourceID:    source.SourceID,
+		TargetID:    source.TargetID,
+		PipelineXML: source.PipelineXML,
+		ConfigData:  source.ConfigData,
+		CronExpr:    source.CronExpr,
+		Status:      models.ETLStatusCreated,
+		Priority:    source.Priority,
+		MaxRetries:  source.MaxRetries,
+		Timeout:     source.Timeout,
+	}
+	job.CreatedBy = userID
+	job.UpdatedBy = userID
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&job).Error; err != nil {
+			return err
+		}
+		// is_enabled 列默认值为true，零值不会写入，创建后单独置为禁用
+		return tx.Model(&job).Update("is_enabled", false).Error
+	})
+	if err != nil {
+		h.logger.Error("Failed to clone ETL job", zap.Error(err), zap.Uint64("source_job_id", id))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "克隆失败"))
+		return
+	}
+
+	h.logger.Info("ETL job cloned",
+		zap.Uint("source_job_id", source.ID),
+		zap.Uint("job_id", job.ID))
+
+	invalidateDashboardCache(c, h.logger)
+	c.JSON(http.StatusOK, models.SuccessResponse(job))
+}
+
+// PreviewCronExpr 校验定时表达式并返回接下来的执行时间，供保存作业前确认
+func (h *ETLHandler) PreviewCronExpr(c *gin.Context) {
+	var req struct {
+		Expr  string `form:"expr" binding:"required"`
+		Count int    `form:"count" binding:"omitempty,min=1,max=20"`
+	}
 	if err := c.ShouldBindQuery(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "参数错误"))
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
 		return
 	}
+	if req.Count == 0 {
+		req.Count = 5
+	}
 
-	query := h.db.Model(&models.ETLExecution{})
+	runs, err := services.NextCronRuns(req.Expr, time.Now(), req.Count)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, fmt.Sprintf("定时表达式无效: %v", err)))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{
+		"expr":      req.Expr,
+		"next_runs": runs,
+	}))
+}
 
-	if req.JobID > 0 {
-		query = query.Where("job_id = ?", req.JobID)
+// PauseETLJob 暂停ETL作业，从调度器摘除但保留配置，正在进行的执行不受影响，等待中的失败重试不再执行
+func (h *ETLHandler) PauseETLJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "无效的ID"))
+		return
 	}
-	if req.Status != "" {
-		query = query.Where("status = ?", req.Status)
+
+	var job models.ETLJob
+	if err := h.db.First(&job, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "ETL作业不存在"))
+			return
+		}
+		h.logger.Error("Failed to get ETL job", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return
 	}
-	if req.TriggerType != "" {
-		query = query.Where("trigger_type = ?", req.TriggerType)
+
+	if job.IsPaused {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "作业已暂停"))
+		return
+	}
+
+	h.scheduler.UnscheduleJob(job.ID)
+
+	if err := h.db.Model(&job).Updates(map[string]interface{}{
+		"is_paused":   true,
+		"paused_at":   time.Now(),
+		"next_run_at": nil,
+		"updated_by":  c.GetUint("user_id"),
+	}).Error; err != nil {
+		h.logger.Error("Failed to pause ETL job", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "暂停失败"))
+		return
+	}
+
+	h.db.First(&job, job.ID)
+	invalidateDashboardCache(c, h.logger)
+	c.JSON(http.StatusOK, models.SuccessResponse(job))
+}
+
+// ResumeETLJob 恢复暂停的ETL作业，已启用且配置了定时表达式时重新挂载调度
+func (h *ETLHandler) ResumeETLJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "无效的ID"))
+		return
+	}
+
+	var job models.ETLJob
+	if err := h.db.First(&job, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "ETL作业不存在"))
+			return
+		}
+		h.logger.Error("Failed to get ETL job", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return
+	}
+
+	if !job.IsPaused {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "作业未暂停"))
+		return
+	}
+
+	if err := h.db.Model(&job).Updates(map[string]interface{}{
+		"is_paused":  false,
+		"paused_at":  nil,
+		"updated_by": c.GetUint("user_id"),
+	}).Error; err != nil {
+		h.logger.Error("Failed to resume ETL job", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "恢复失败"))
+		return
+	}
+
+	if job.IsEnabled && job.CronExpr != "" {
+		if err := h.scheduler.ScheduleJob(&job); err != nil {
+			h.logger.Warn("Failed to reschedule resumed job", zap.Error(err), zap.Uint("job_id", job.ID))
+		}
+	}
+
+	h.db.First(&job, job.ID)
+	invalidateDashboardCache(c, h.logger)
+	c.JSON(http.StatusOK, models.SuccessResponse(job))
+}
+
+// ETLExecutionFilter ETL执行记录筛选条件，列表和导出共用
+type ETLExecutionFilter struct {
+	JobID       uint   `form:"job_id"`
+	Status      string `form:"status"`
+	TriggerType string `form:"trigger_type"`
+	StartDate   string `form:"start_date"`
+	EndDate     string `form:"end_date"`
+}
+
+// apply 应用筛选条件
+func (f *ETLExecutionFilter) apply(query *gorm.DB) *gorm.DB {
+	if f.JobID > 0 {
+		query = query.Where("job_id = ?", f.JobID)
+	}
+	if f.Status != "" {
+		query = query.Where("status = ?", f.Status)
 	}
-	if req.StartDate != "" {
-		query = query.Where("start_time >= ?", req.StartDate)
+	if f.TriggerType != "" {
+		query = query.Where("trigger_type = ?", f.TriggerType)
 	}
-	if req.EndDate != "" {
-		query = query.Where("start_time <= ?", req.EndDate)
+	if f.StartDate != "" {
+		query = query.Where("start_time >= ?", f.StartDate)
 	}
+	if f.EndDate != "" {
+		query = query.Where("start_time <= ?", f.EndDate)
+	}
+	return query
+}
+
+// ListETLExecutions 获取ETL执行记录列表
+func (h *ETLHandler) ListETLExecutions(c *gin.Context) {
+	var req struct {
+		models.PageRequest
+		ETLExecutionFilter
+	}
+
+	if !bindPageQuery(c, &req) {
+		return
+	}
+
+	query := req.ETLExecutionFilter.apply(h.db.Model(&models.ETLExecution{}))
 
 	var total int64
 	query.Count(&total)
 
 	var executions []models.ETLExecution
-	offset := (req.Page - 1) * req.PageSize
+	offset := req.Offset()
 	if err := query.Offset(offset).Limit(req.PageSize).
 		Preload("Job").Preload("Trigger").
 		Order("start_time DESC").
@@ -486,6 +737,56 @@ func (h *ETLHandler) ListETLExecutions(c *gin.Context) {
 	}))
 }
 
+// ExportETLExecutions 按列表查询条件导出ETL执行记录为CSV或Excel
+func (h *ETLHandler) ExportETLExecutions(c *gin.Context) {
+	var filter ETLExecutionFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &filter))
+		return
+	}
+
+	var executions []models.ETLExecution
+	query := filter.apply(h.db.Model(&models.ETLExecution{})).Omit("log_content").Preload("Job").Preload("Trigger")
+	streamListExport(c, h.logger, query, listExport{
+		Name: "etl_executions",
+		Headers: []string{"ID", "执行ID", "作业ID", "作业名称", "状态", "触发类型", "触发人", "开始时间", "结束时间",
+			"执行时长(毫秒)", "输入行数", "输出行数", "错误行数", "跳过行数", "错误信息"},
+		Dest: &executions,
+		Rows: func() [][]string {
+			rows := make([][]string, 0, len(executions))
+			for i := range executions {
+				execution := &executions[i]
+				execution.CalculateDuration()
+				var jobName, trigger string
+				if execution.Job != nil {
+					jobName = execution.Job.Name
+				}
+				if execution.Trigger != nil {
+					trigger = execution.Trigger.Username
+				}
+				rows = append(rows, []string{
+					strconv.FormatUint(uint64(execution.ID), 10),
+					execution.ExecutionID,
+					strconv.FormatUint(uint64(execution.JobID), 10),
+					jobName,
+					execution.Status,
+					execution.TriggerType,
+					trigger,
+					formatExportTime(&execution.StartTime),
+					formatExportTime(execution.EndTime),
+					strconv.FormatInt(execution.Duration, 10),
+					strconv.FormatInt(execution.InputRows, 10),
+					strconv.FormatInt(execution.OutputRows, 10),
+					strconv.FormatInt(execution.ErrorRows, 10),
+					strconv.FormatInt(execution.SkippedRows, 10),
+					execution.ErrorMessage,
+				})
+			}
+			return rows
+		},
+	})
+}
+
 // GetETLExecution 获取ETL执行记录详情
 func (h *ETLHandler) GetETLExecution(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
@@ -495,7 +796,8 @@ func (h *ETLHandler) GetETLExecution(c *gin.Context) {
 	}
 
 	var execution models.ETLExecution
-	if err := h.db.Preload("Job").Preload("Trigger").
+	if err := h.db.Preload("Job").Preload("Trigger").Preload("OutputFile").
+		Preload("Steps", func(db *gorm.DB) *gorm.DB { return db.Order("step_order") }).
 		First(&execution, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "执行记录不存在"))
@@ -507,6 +809,7 @@ func (h *ETLHandler) GetETLExecution(c *gin.Context) {
 	}
 
 	execution.CalculateDuration()
+	execution.CalculateThroughput()
 
 	c.JSON(http.StatusOK, models.SuccessResponse(execution))
 }
@@ -538,14 +841,12 @@ func (h *ETLHandler) GetETLExecutionLogs(c *gin.Context) {
 // ListETLTemplates 获取ETL模板列表
 func (h *ETLHandler) ListETLTemplates(c *gin.Context) {
 	var req struct {
-		Page     int    `form:"page" binding:"required,min=1"`
-		PageSize int    `form:"page_size" binding:"required,min=1,max=100"`
+		models.PageRequest
 		Category string `form:"category"`
 		IsPublic *bool  `form:"is_public"`
 	}
 
-	if err := c.ShouldBindQuery(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "参数错误"))
+	if !bindPageQuery(c, &req) {
 		return
 	}
 
@@ -562,7 +863,7 @@ func (h *ETLHandler) ListETLTemplates(c *gin.Context) {
 	query.Count(&total)
 
 	var templates []models.ETLTemplate
-	offset := (req.Page - 1) * req.PageSize
+	offset := req.Offset()
 	if err := query.Offset(offset).Limit(req.PageSize).
 		Preload("Creator").
 		Order("use_count DESC, created_at DESC").
@@ -588,7 +889,10 @@ func (h *ETLHandler) GetETLStats(c *gin.Context) {
 	h.db.Model(&models.ETLJob{}).Count(&stats.TotalJobs)
 
 	// 活跃作业数
-	h.db.Model(&models.ETLJob{}).Where("is_enabled = ? AND status != ?", true, "error").Count(&stats.ActiveJobs)
+	h.db.Model(&models.ETLJob{}).Where("is_enabled = ? AND is_paused = ? AND status != ?", true, false, "error").Count(&stats.ActiveJobs)
+
+	// 暂停中的作业数
+	h.db.Model(&models.ETLJob{}).Where("is_paused = ?", true).Count(&stats.PausedJobs)
 
 	// 总执行次数
 	h.db.Model(&models.ETLExecution{}).Count(&stats.TotalExecutions)
@@ -606,11 +910,42 @@ func (h *ETLHandler) GetETLStats(c *gin.Context) {
 		stats.SuccessRate = float64(successCount) / float64(stats.TotalExecutions) * 100
 	}
 
+	// 平均吞吐率，只统计成功的执行
+	h.db.Model(&models.ETLExecution{}).
+		Where("status = ? AND rows_per_second > 0", "success").
+		Select("COALESCE(AVG(rows_per_second), 0)").
+		Scan(&stats.AvgRowsPerSecond)
+
 	c.JSON(http.StatusOK, models.SuccessResponse(stats))
 }
 
 // 辅助函数
 
+// cloneJobName 克隆作业的默认名称，超出长度时截断原名称
+func cloneJobName(name string) string {
+	const suffix = " - 副本"
+	runes := []rune(name)
+	if max := 100 - len([]rune(suffix)); len(runes) > max {
+		runes = runes[:max]
+	}
+	return string(runes) + suffix
+}
+
+// validateCronExpr 校验定时表达式并返回下次执行时间，表达式为空时不调度
+func validateCronExpr(expr string) (*time.Time, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	runs, err := services.NextCronRuns(expr, time.Now(), 1)
+	if err != nil {
+		return nil, fmt.Errorf("定时表达式无效: %v", err)
+	}
+	if len(runs) == 0 {
+		return nil, fmt.Errorf("定时表达式无效: 没有可执行的时间")
+	}
+	return &runs[0], nil
+}
+
 // generateExecutionID 生成执行ID
 func generateExecutionID() string {
 	return "exec_" + strconv.FormatInt(time.Now().UnixNano(), 36)
@@ -618,20 +953,36 @@ func generateExecutionID() string {
 
 // executeJobAsync 异步执行ETL作业
 func (h *ETLHandler) executeJobAsync(job *models.ETLJob, execution *models.ETLExecution, parameters map[string]interface{}) {
-	ctx := context.Background()
-	result := h.executor.ExecuteJob(ctx, job, execution, parameters)
+	// 执行期间续期执行锁，避免被其他实例视为已停止而接管
+	stopHeartbeat := services.StartETLRunHeartbeat(h.db, job.ID)
+	defer stopHeartbeat()
+
+	// 可重试的失败按MaxRetries重试，execution为最后一次执行的记录
+	execution, result := h.executor.ExecuteJobWithRetry(context.Background(), job, execution, parameters)
 
 	// 更新执行记录
 	endTime := time.Now()
+	execution.EndTime = &endTime
+	execution.Duration = endTime.Sub(execution.StartTime).Milliseconds()
+	execution.InputRows = result.InputRows
+	execution.CalculateThroughput()
+
 	updates := map[string]interface{}{
 		"status":     result.Status,
 		"end_time":   endTime,
-		"duration":   endTime.Sub(execution.StartTime).Milliseconds(),
+		"duration":   execution.Duration,
 		"input_rows": result.InputRows,
 		"output_rows": result.OutputRows,
 		"error_rows": result.ErrorRows,
 		"error_message": result.ErrorMessage,
 		"log_content": result.LogContent,
+		"rows_per_second": execution.RowsPerSecond,
+	}
+	if result.Status == "success" {
+		updates["progress"] = 100
+	}
+	if result.OutputFileID != nil {
+		updates["output_file_id"] = *result.OutputFileID
 	}
 
 	h.db.Model(execution).Updates(updates)
@@ -648,12 +999,14 @@ func (h *ETLHandler) executeJobAsync(job *models.ETLJob, execution *models.ETLEx
 	}
 
 	h.db.Model(job).Updates(jobUpdates)
+	services.NotifyDashboardChange(services.DashboardTriggerETL)
 
 	h.logger.Info("ETL job execution completed",
 		zap.Uint("job_id", job.ID),
 		zap.String("execution_id", execution.ExecutionID),
 		zap.String("status", result.Status),
-		zap.Int64("duration_ms", endTime.Sub(execution.StartTime).Milliseconds()),
+		zap.Int64("duration_ms", execution.Duration),
+		zap.Float64("rows_per_second", execution.RowsPerSecond),
 	)
 }
 
@@ -694,7 +1047,7 @@ type CreateJobFromTemplateRequest struct {
 func (h *ETLHandler) CreateETLTemplate(c *gin.Context) {
 	var req CreateETLTemplateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "请求参数错误"))
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
 		return
 	}
 
@@ -757,7 +1110,7 @@ func (h *ETLHandler) UpdateETLTemplate(c *gin.Context) {
 
 	var req UpdateETLTemplateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "请求参数错误"))
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
 		return
 	}
 
@@ -849,7 +1202,7 @@ func (h *ETLHandler) CreateJobFromTemplate(c *gin.Context) {
 
 	var req CreateJobFromTemplateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "请求参数错误"))
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
 		return
 	}
 
@@ -865,6 +1218,12 @@ func (h *ETLHandler) CreateJobFromTemplate(c *gin.Context) {
 		return
 	}
 
+	nextRunAt, err := validateCronExpr(req.Schedule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, err.Error()))
+		return
+	}
+
 	// 使用模板XML并应用变量替换
 	templateXML := template.TemplateXML
 	for key, value := range req.Variables {
@@ -882,6 +1241,7 @@ func (h *ETLHandler) CreateJobFromTemplate(c *gin.Context) {
 		CronExpr:    req.Schedule,
 		IsEnabled:   true,
 		Status:      models.ETLStatusCreated,
+		NextRunAt:   nextRunAt,
 	}
 
 	if err := database.DB.Create(&job).Error; err != nil {
@@ -890,6 +1250,12 @@ func (h *ETLHandler) CreateJobFromTemplate(c *gin.Context) {
 		return
 	}
 
+	if job.CronExpr != "" {
+		if err := h.scheduler.ScheduleJob(&job); err != nil {
+			h.logger.Warn("Failed to schedule job", zap.Error(err), zap.Uint("job_id", job.ID))
+		}
+	}
+
 	h.logger.Info("ETL job created from template successfully",
 		zap.Uint("job_id", job.ID),
 		zap.Uint("template_id", template.ID))
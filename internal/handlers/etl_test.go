@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/database/dbtest"
+	"github.com/env-data-platform/internal/models"
+)
+
+func TestCloneJobName(t *testing.T) {
+	assert.Equal(t, "小时数据同步 - 副本", cloneJobName("小时数据同步"))
+
+	long := cloneJobName(strings.Repeat("站", 100))
+	assert.Equal(t, 100, utf8.RuneCountInString(long))
+	assert.True(t, strings.HasSuffix(long, " - 副本"))
+}
+
+func TestExecuteETLJobPausedOrDisabled(t *testing.T) {
+	tests := []struct {
+		name     string
+		job      models.ETLJob
+		expected int
+		message  string
+	}{
+		{name: "已暂停", job: models.ETLJob{IsEnabled: true, IsPaused: true}, expected: http.StatusBadRequest, message: "作业已暂停，请先恢复"},
+		{name: "已禁用", job: models.ETLJob{IsPaused: true}, expected: http.StatusBadRequest, message: "作业已禁用"},
+		// DryRun下争抢执行锁影响0行，视为作业正在运行
+		{name: "启用", job: models.ETLJob{IsEnabled: true}, expected: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.New(t)
+			db.On(dbtest.KindQuery, func(tx *gorm.DB) {
+				if job, ok := tx.Statement.Dest.(*models.ETLJob); ok {
+					*job = tt.job
+					job.ID = 7
+				}
+			})
+			h := &ETLHandler{db: db.DB, logger: zap.NewNop()}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/etl/jobs/7/execute", strings.NewReader(`{"trigger_type":"manual"}`))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Params = gin.Params{{Key: "id", Value: "7"}}
+			h.ExecuteETLJob(c)
+			assert.Equal(t, tt.expected, w.Code)
+			if tt.message != "" {
+				assert.Contains(t, w.Body.String(), tt.message)
+			}
+
+			// 暂停或禁用的作业在争抢执行锁之前拒绝，不修改作业状态
+			updates := db.Statements(dbtest.KindUpdate)
+			if tt.expected == http.StatusBadRequest {
+				assert.Empty(t, updates)
+			} else {
+				require.Len(t, updates, 1)
+				assert.Equal(t, "env_etl_jobs", updates[0].Table)
+			}
+			assert.Empty(t, db.Statements(dbtest.KindCreate))
+		})
+	}
+}
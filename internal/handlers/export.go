@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/config"
+	"github.com/env-data-platform/internal/middleware"
+	"github.com/env-data-platform/internal/models"
+	"github.com/env-data-platform/internal/services"
+)
+
+// 列表导出默认值，配置未加载时使用
+const (
+	defaultExportMaxRows   = 100000
+	defaultExportBatchSize = 500
+)
+
+// listExport 列表导出描述，各列表handler按当前查询条件构建query后交由streamListExport流式写出
+type listExport struct {
+	Name    string   // 文件名前缀
+	Headers []string // 表头
+	// Dest 每批查询结果的接收切片指针，Rows在每批查询后将其转换为行数据
+	Dest interface{}
+	Rows func() [][]string
+}
+
+// exportLimits 读取导出行数上限和每批查询行数
+func exportLimits() (maxRows, batchSize int) {
+	maxRows, batchSize = defaultExportMaxRows, defaultExportBatchSize
+	if cfg := config.GlobalConfig; cfg != nil {
+		if cfg.Export.MaxRows > 0 {
+			maxRows = cfg.Export.MaxRows
+		}
+		if cfg.Export.BatchSize > 0 {
+			batchSize = cfg.Export.BatchSize
+		}
+	}
+	return maxRows, batchSize
+}
+
+// parseExportFormat 解析format查询参数，支持csv（默认）、xlsx和excel
+func parseExportFormat(c *gin.Context) (string, error) {
+	format := strings.ToLower(strings.TrimSpace(c.DefaultQuery("format", services.ExportFormatCSV)))
+	switch format {
+	case services.ExportFormatCSV:
+		return services.ExportFormatCSV, nil
+	case services.ExportFormatXLSX, "excel":
+		return services.ExportFormatXLSX, nil
+	default:
+		return "", fmt.Errorf("不支持的导出格式: %s，可选值: csv, xlsx", format)
+	}
+}
+
+// streamListExport 按query的查询条件全量导出为CSV或Excel。先统计行数并校验上限，
+// 再按主键分批查询边查边写，避免大数据量占用内存。导出结果记入操作日志
+func streamListExport(c *gin.Context, logger *zap.Logger, query *gorm.DB, export listExport) {
+	format, err := parseExportFormat(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	maxRows, batchSize := exportLimits()
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		logger.Error("Failed to count export rows", zap.String("export", export.Name), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return
+	}
+	if total > int64(maxRows) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest,
+			fmt.Sprintf("待导出数据共%d行，超过单次导出上限%d行，请缩小查询范围", total, maxRows)))
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if format == services.ExportFormatXLSX {
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	filename := fmt.Sprintf("%s_%s.%s", export.Name, time.Now().Format("20060102150405"), format)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(filename))
+	c.Status(http.StatusOK)
+
+	// 响应头已发出，之后的错误只能中断输出，客户端会收到不完整的文件
+	start := time.Now()
+	writer, err := services.NewRowWriter(c.Writer, format, true)
+	if err == nil {
+		err = writer.WriteRow(export.Headers)
+	}
+
+	var written int64
+	if err == nil {
+		err = query.Session(&gorm.Session{}).FindInBatches(export.Dest, batchSize, func(tx *gorm.DB, batch int) error {
+			for _, row := range export.Rows() {
+				if written >= int64(maxRows) {
+					return nil
+				}
+				if err := writer.WriteRow(row); err != nil {
+					return err
+				}
+				written++
+			}
+			c.Writer.Flush()
+			return c.Request.Context().Err()
+		}).Error
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+
+	c.Set(middleware.ExportRowsKey, written)
+	if err != nil {
+		logger.Error("Failed to stream export", zap.String("export", export.Name), zap.Int64("rows", written), zap.Error(err))
+		_ = c.Error(err)
+		c.Abort()
+		return
+	}
+
+	logger.Info("List exported",
+		zap.String("export", export.Name),
+		zap.String("format", format),
+		zap.Int64("rows", written),
+		zap.Uint("user_id", c.GetUint("user_id")),
+		zap.Duration("elapsed", time.Since(start)))
+}
+
+// formatExportTime 导出中的时间格式
+func formatExportTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/env-data-platform/internal/services"
+)
+
+func TestParseExportFormat(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := map[string]string{
+		"":              services.ExportFormatCSV,
+		"?format=CSV":   services.ExportFormatCSV,
+		"?format=xlsx":  services.ExportFormatXLSX,
+		"?format=excel": services.ExportFormatXLSX,
+	}
+	for query, expected := range cases {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/api/v1/users/export"+query, nil)
+		format, err := parseExportFormat(c)
+		assert.NoError(t, err, query)
+		assert.Equal(t, expected, format, query)
+	}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/api/v1/users/export?format=pdf", nil)
+	_, err := parseExportFormat(c)
+	assert.Error(t, err)
+}
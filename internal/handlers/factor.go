@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/factor"
+	"github.com/env-data-platform/internal/models"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// FactorHandler 监测因子字典处理器
+type FactorHandler struct {
+	db         *gorm.DB
+	logger     *zap.Logger
+	dictionary *factor.Dictionary
+}
+
+// NewFactorHandler 创建监测因子字典处理器
+func NewFactorHandler(logger *zap.Logger) *FactorHandler {
+	return &FactorHandler{
+		db:         database.GetDB(),
+		logger:     logger,
+		dictionary: factor.Default(),
+	}
+}
+
+// ListFactors 获取数据库中配置的监测因子
+func (h *FactorHandler) ListFactors(c *gin.Context) {
+	var req struct {
+		models.PageRequest
+	}
+
+	if !bindPageQuery(c, &req) {
+		return
+	}
+
+	query := h.db.Model(&models.MonitorFactor{})
+	if req.Keyword != "" {
+		query = query.Where("code LIKE ? OR name LIKE ?", "%"+req.Keyword+"%", "%"+req.Keyword+"%")
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var factors []models.MonitorFactor
+	offset := req.Offset()
+	if err := query.Offset(offset).Limit(req.PageSize).
+		Order("code ASC").
+		Find(&factors).Error; err != nil {
+		h.logger.Error("Failed to list monitor factors", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{
+		"list":      factors,
+		"total":     total,
+		"page":      req.Page,
+		"page_size": req.PageSize,
+	}))
+}
+
+// LookupFactor 按编码查询生效的因子定义，包含内置因子
+func (h *FactorHandler) LookupFactor(c *gin.Context) {
+	f, ok := h.dictionary.Lookup(c.Param("code"))
+	if !ok {
+		c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "监测因子不存在"))
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse(f))
+}
+
+// CreateFactor 创建监测因子
+func (h *FactorHandler) CreateFactor(c *gin.Context) {
+	var req models.MonitorFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
+		return
+	}
+	if msg := validateFactorLimits(&req); msg != "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, msg))
+		return
+	}
+
+	var count int64
+	h.db.Model(&models.MonitorFactor{}).Where("code = ?", req.Code).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "因子编码已存在"))
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	f := models.MonitorFactor{
+		Code:        req.Code,
+		Name:        req.Name,
+		Unit:        req.Unit,
+		UpperLimit:  req.UpperLimit,
+		LowerLimit:  req.LowerLimit,
+		Description: req.Description,
+	}
+	f.CreatedBy = userID
+	f.UpdatedBy = userID
+
+	if err := h.db.Create(&f).Error; err != nil {
+		h.logger.Error("Failed to create monitor factor", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "创建失败"))
+		return
+	}
+
+	h.reload()
+	c.JSON(http.StatusOK, models.SuccessResponse(f))
+}
+
+// UpdateFactor 更新监测因子，编码不可修改
+func (h *FactorHandler) UpdateFactor(c *gin.Context) {
+	f, ok := h.findFactor(c)
+	if !ok {
+		return
+	}
+
+	var req models.MonitorFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
+		return
+	}
+	if req.Code != f.Code {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "因子编码不可修改"))
+		return
+	}
+	if msg := validateFactorLimits(&req); msg != "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, msg))
+		return
+	}
+
+	if err := h.db.Model(f).Updates(map[string]interface{}{
+		"name":        req.Name,
+		"unit":        req.Unit,
+		"upper_limit": req.UpperLimit,
+		"lower_limit": req.LowerLimit,
+		"description": req.Description,
+		"updated_by":  c.GetUint("user_id"),
+	}).Error; err != nil {
+		h.logger.Error("Failed to update monitor factor", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "更新失败"))
+		return
+	}
+
+	h.db.First(f, f.ID)
+	h.reload()
+	c.JSON(http.StatusOK, models.SuccessResponse(f))
+}
+
+// DeleteFactor 删除监测因子，内置因子恢复为内置定义
+func (h *FactorHandler) DeleteFactor(c *gin.Context) {
+	f, ok := h.findFactor(c)
+	if !ok {
+		return
+	}
+
+	// 硬删除，避免软删除记录占用唯一编码
+	if err := h.db.Unscoped().Delete(f).Error; err != nil {
+		h.logger.Error("Failed to delete monitor factor", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "删除失败"))
+		return
+	}
+
+	h.reload()
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{"message": "删除成功"}))
+}
+
+// ReloadFactors 从数据库重新加载因子字典
+func (h *FactorHandler) ReloadFactors(c *gin.Context) {
+	if err := h.dictionary.Load(h.db); err != nil {
+		h.logger.Error("Failed to reload factor dictionary", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "加载失败"))
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{"message": "加载成功"}))
+}
+
+// findFactor 根据路径参数查找监测因子
+func (h *FactorHandler) findFactor(c *gin.Context) (*models.MonitorFactor, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "无效的ID"))
+		return nil, false
+	}
+
+	var f models.MonitorFactor
+	if err := h.db.First(&f, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "监测因子不存在"))
+			return nil, false
+		}
+		h.logger.Error("Failed to get monitor factor", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return nil, false
+	}
+
+	return &f, true
+}
+
+// reload 因子变更后刷新字典，失败时保留旧字典
+func (h *FactorHandler) reload() {
+	if err := h.dictionary.Load(h.db); err != nil {
+		h.logger.Warn("Failed to reload factor dictionary", zap.Error(err))
+	}
+}
+
+// validateFactorLimits 校验默认上下限
+func validateFactorLimits(req *models.MonitorFactorRequest) string {
+	if req.UpperLimit != nil && req.LowerLimit != nil && *req.LowerLimit > *req.UpperLimit {
+		return "下限不能大于上限"
+	}
+	return ""
+}
@@ -1,44 +1,87 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 
+	"github.com/env-data-platform/internal/config"
 	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/middleware"
 	"github.com/env-data-platform/internal/models"
+	"github.com/env-data-platform/internal/storage"
 )
 
 // FileHandler 文件处理器
 type FileHandler struct {
-	logger    *zap.Logger
-	uploadDir string
+	logger     *zap.Logger
+	storage    storage.Storage
+	storageCfg config.StorageConfig
+	policy     *fileTypePolicy
+	chunkDir   string
+	chunkCfg   config.ChunkUploadConfig
+	zipCfg     config.ZipDownloadConfig
+	importCfg  config.TableImportConfig
+	dbCfg      config.DatabaseConfig
 }
 
 // NewFileHandler 创建文件处理器
-func NewFileHandler(logger *zap.Logger) *FileHandler {
-	uploadDir := "./uploads"
+func NewFileHandler(cfg *config.Config, logger *zap.Logger) *FileHandler {
+	uploadDir := cfg.Upload.UploadPath
 	if dir := os.Getenv("UPLOAD_DIR"); dir != "" {
 		uploadDir = dir
 	}
+	chunkDir := filepath.Join(cfg.Upload.TempPath, "chunks")
 
-	// 确保上传目录存在
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		logger.Error("Failed to create upload directory", zap.Error(err))
+	// 确保分片临时目录存在
+	if err := os.MkdirAll(chunkDir, 0755); err != nil {
+		logger.Error("Failed to create chunk directory", zap.Error(err), zap.String("dir", chunkDir))
 	}
 
-	return &FileHandler{
-		logger:    logger,
-		uploadDir: uploadDir,
+	store, err := storage.New(cfg.Storage, uploadDir)
+	if err != nil {
+		logger.Error("Failed to create file storage, falling back to local storage",
+			zap.Error(err), zap.String("type", cfg.Storage.Type))
+		store = storage.NewLocal(uploadDir)
+	}
+	logger.Info("File storage initialized", zap.String("type", store.Type()))
+
+	h := &FileHandler{
+		logger:     logger,
+		storage:    store,
+		storageCfg: cfg.Storage,
+		policy:     newFileTypePolicy(cfg.Upload),
+		chunkDir:   chunkDir,
+		chunkCfg:   cfg.Upload.Chunk,
+		zipCfg:     cfg.Upload.Zip,
+		importCfg:  cfg.Upload.Import,
+		dbCfg:      cfg.Database,
+	}
+
+	// 定期清理过期的未完成分片上传
+	if h.chunkCfg.CleanupInterval > 0 {
+		go h.runChunkCleanup()
+	}
+
+	return h
+}
+
+// storageAvailable 文件是否存放在当前配置的存储后端中
+func (h *FileHandler) storageAvailable(record *models.FileRecord) bool {
+	storageType := record.StorageType
+	if storageType == "" {
+		storageType = storage.TypeLocal
 	}
+	return storageType == h.storage.Type()
 }
 
 // UploadFileRequest 文件上传请求
@@ -80,7 +123,7 @@ func (h *FileHandler) UploadFile(c *gin.Context) {
 	// 绑定表单参数
 	var req UploadFileRequest
 	if err := c.ShouldBind(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "参数错误"))
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
 		return
 	}
 
@@ -91,43 +134,39 @@ func (h *FileHandler) UploadFile(c *gin.Context) {
 		return
 	}
 
-	// 验证文件大小 (最大50MB)
-	const maxFileSize = 50 * 1024 * 1024
-	if file.Size > maxFileSize {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "文件大小不能超过50MB"))
+	// 验证文件类型和大小
+	rule, msg := h.policy.checkName(file.Filename, file.Size)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, msg))
 		return
 	}
 
-	// 验证文件类型
-	allowedTypes := map[string]bool{
-		".txt":  true,
-		".csv":  true,
-		".json": true,
-		".xml":  true,
-		".xlsx": true,
-		".xls":  true,
-		".pdf":  true,
-		".doc":  true,
-		".docx": true,
-		".jpg":  true,
-		".jpeg": true,
-		".png":  true,
-		".gif":  true,
-	}
+	// 生成唯一文件名
+	timestamp := time.Now().Unix()
+	filename := fmt.Sprintf("%d_%s", timestamp, filepath.Base(file.Filename))
 
-	ext := strings.ToLower(filepath.Ext(file.Filename))
-	if !allowedTypes[ext] {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "不支持的文件类型"))
+	// 保存文件
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "读取上传文件失败"))
 		return
 	}
+	defer src.Close()
 
-	// 生成唯一文件名
-	timestamp := time.Now().Unix()
-	filename := fmt.Sprintf("%d_%s", timestamp, file.Filename)
-	filePath := filepath.Join(h.uploadDir, filename)
+	// 按文件内容校验实际类型，防止修改扩展名伪装
+	mimeType, msg := h.policy.checkContent(rule, src)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, msg))
+		return
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "读取上传文件失败"))
+		return
+	}
 
-	// 保存文件
-	if err := c.SaveUploadedFile(file, filePath); err != nil {
+	// 相同内容已存储时复用存储对象，只新建文件记录
+	stored, err := h.storeContent(c.Request.Context(), filename, src, file.Size, mimeType)
+	if err != nil {
 		h.logger.Error("Failed to save uploaded file", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "文件保存失败"))
 		return
@@ -136,20 +175,24 @@ func (h *FileHandler) UploadFile(c *gin.Context) {
 	// 创建文件记录
 	fileRecord := models.FileRecord{
 		OriginalName: file.Filename,
-		StoredName:   filename,
-		FilePath:     filePath,
+		StoredName:   stored.Name,
+		FilePath:     h.storage.Locate(stored.Name),
+		StorageType:  h.storage.Type(),
 		FileSize:     file.Size,
-		FileType:     models.GetFileTypeByMime(file.Header.Get("Content-Type")),
-		MimeType:     file.Header.Get("Content-Type"),
+		FileType:     models.GetFileTypeByMime(mimeType),
+		MimeType:     mimeType,
+		Category:     rule.Category,
+		SHA256Hash:   stored.SHA256,
 		Description:  req.Description,
 		Tags:         req.Tags,
 		Status:       models.FileStatusActive,
+		Deduplicated: stored.Reused,
 	}
 	fileRecord.CreatedBy = userID.(uint)
 
 	if err := database.DB.Create(&fileRecord).Error; err != nil {
-		// 如果数据库保存失败，删除已上传的文件
-		os.Remove(filePath)
+		// 如果数据库保存失败，删除本次上传的文件
+		h.discardStored(c.Request.Context(), stored)
 		h.logger.Error("Failed to create file record", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "文件记录创建失败"))
 		return
@@ -158,7 +201,8 @@ func (h *FileHandler) UploadFile(c *gin.Context) {
 	h.logger.Info("File uploaded successfully",
 		zap.Uint("file_id", fileRecord.ID),
 		zap.String("filename", file.Filename),
-		zap.Int64("size", file.Size))
+		zap.Int64("size", file.Size),
+		zap.Bool("deduplicated", stored.Reused))
 
 	c.JSON(http.StatusOK, models.SuccessResponse(fileRecord))
 }
@@ -179,19 +223,10 @@ func (h *FileHandler) UploadFile(c *gin.Context) {
 // @Router /api/v1/files/records [get]
 func (h *FileHandler) ListFiles(c *gin.Context) {
 	var query FileListQuery
-	if err := c.ShouldBindQuery(&query); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "查询参数错误"))
+	if !bindPageQuery(c, &query) {
 		return
 	}
 
-	// 设置默认分页参数
-	if query.Page <= 0 {
-		query.Page = 1
-	}
-	if query.PageSize <= 0 {
-		query.PageSize = 10
-	}
-
 	// 构建查询
 	db := database.DB.Model(&models.FileRecord{}).Preload("Uploader")
 
@@ -222,7 +257,7 @@ func (h *FileHandler) ListFiles(c *gin.Context) {
 
 	// 分页查询
 	var files []models.FileRecord
-	offset := (query.Page - 1) * query.PageSize
+	offset := query.Offset()
 	if err := db.Offset(offset).Limit(query.PageSize).Order("created_at DESC").Find(&files).Error; err != nil {
 		h.logger.Error("Failed to list files", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
@@ -233,6 +268,11 @@ func (h *FileHandler) ListFiles(c *gin.Context) {
 	c.JSON(http.StatusOK, models.SuccessResponse(result))
 }
 
+// canDownloadFile 下载权限检查：上传者或超级管理员可以下载，单文件和打包下载共用
+func canDownloadFile(c *gin.Context, record *models.FileRecord) bool {
+	return record.CreatedBy == c.GetUint("user_id") || middleware.IsSuperAdmin(c)
+}
+
 // DownloadFile 文件下载
 // @Summary 文件下载
 // @Description 根据文件ID下载文件
@@ -268,30 +308,66 @@ func (h *FileHandler) DownloadFile(c *gin.Context) {
 		return
 	}
 
-	// 权限检查：只有上传者才能下载（暂时简化权限控制）
-	if fileRecord.CreatedBy != userID.(uint) {
+	if !canDownloadFile(c, &fileRecord) {
 		c.JSON(http.StatusForbidden, models.ErrorResponse(http.StatusForbidden, "无权限下载此文件"))
 		return
 	}
 
-	// 检查文件是否存在
-	if _, err := os.Stat(fileRecord.FilePath); os.IsNotExist(err) {
-		h.logger.Error("File not found on disk", zap.String("path", fileRecord.FilePath))
-		c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "文件不存在"))
+	if !h.storageAvailable(&fileRecord) {
+		h.logger.Error("File stored in unavailable storage",
+			zap.Uint("file_id", fileRecord.ID), zap.String("storage_type", fileRecord.StorageType))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "文件所在存储不可用"))
 		return
 	}
 
+	// 预签名下载：presign=true时返回URL，配置开启时直接重定向
+	wantURL := c.Query("presign") == "true"
+	if wantURL || h.storageCfg.PresignDownload {
+		url, err := h.storage.PresignGet(c.Request.Context(), fileRecord.StoredName, fileRecord.OriginalName, h.storageCfg.PresignExpire)
+		switch {
+		case err == nil:
+			database.DB.Model(&fileRecord).UpdateColumn("download_count", gorm.Expr("download_count + 1"))
+			if wantURL {
+				c.JSON(http.StatusOK, models.SuccessResponse(gin.H{
+					"url":        url,
+					"expires_at": time.Now().Add(h.storageCfg.PresignExpire),
+				}))
+			} else {
+				c.Redirect(http.StatusFound, url)
+			}
+			return
+		case !errors.Is(err, storage.ErrPresignNotSupported):
+			h.logger.Error("Failed to presign download url", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "生成下载地址失败"))
+			return
+		case wantURL:
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "当前存储不支持预签名下载"))
+			return
+		}
+	}
+
+	reader, err := h.storage.Get(c.Request.Context(), fileRecord.StoredName)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			h.logger.Error("File not found in storage", zap.String("path", fileRecord.FilePath))
+			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "文件不存在"))
+		} else {
+			h.logger.Error("Failed to read file from storage", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "读取文件失败"))
+		}
+		return
+	}
+	defer reader.Close()
+
 	// 更新下载次数
 	database.DB.Model(&fileRecord).UpdateColumn("download_count", gorm.Expr("download_count + 1"))
 
-	// 设置响应头
-	c.Header("Content-Description", "File Transfer")
-	c.Header("Content-Transfer-Encoding", "binary")
-	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileRecord.OriginalName))
-	c.Header("Content-Type", "application/octet-stream")
-
 	// 发送文件
-	c.File(fileRecord.FilePath)
+	c.DataFromReader(http.StatusOK, fileRecord.FileSize, "application/octet-stream", reader, map[string]string{
+		"Content-Description":       "File Transfer",
+		"Content-Transfer-Encoding": "binary",
+		"Content-Disposition":       fmt.Sprintf("attachment; filename=%s", fileRecord.OriginalName),
+	})
 
 	h.logger.Info("File downloaded",
 		zap.Uint("file_id", fileRecord.ID),
@@ -301,7 +377,7 @@ func (h *FileHandler) DownloadFile(c *gin.Context) {
 
 // DeleteFile 删除文件
 // @Summary 删除文件
-// @Description 删除文件记录和物理文件
+// @Description 将文件标记为已删除，物理文件保留至管理员彻底删除
 // @Tags 文件管理
 // @Produce json
 // @Security BearerAuth
@@ -347,11 +423,7 @@ func (h *FileHandler) DeleteFile(c *gin.Context) {
 		return
 	}
 
-	// 删除物理文件（可选，也可以通过定时任务清理）
-	if err := os.Remove(fileRecord.FilePath); err != nil {
-		h.logger.Warn("Failed to remove physical file", zap.Error(err), zap.String("path", fileRecord.FilePath))
-		// 不返回错误，因为数据库记录已经删除
-	}
+	// 存储中的文件保留，管理员可从回收站恢复或彻底删除
 
 	h.logger.Info("File deleted successfully",
 		zap.Uint("file_id", fileRecord.ID),
@@ -361,6 +433,163 @@ func (h *FileHandler) DeleteFile(c *gin.Context) {
 	c.JSON(http.StatusOK, models.SuccessResponse(nil))
 }
 
+// ListDeletedFiles 列出已删除的文件
+// @Summary 已删除文件列表
+// @Description 分页列出已删除的文件，供管理员恢复或彻底删除
+// @Tags 文件管理
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页大小" default(10)
+// @Success 200 {object} models.Response{data=models.PageResponse} "获取成功"
+// @Router /api/v1/files/deleted [get]
+func (h *FileHandler) ListDeletedFiles(c *gin.Context) {
+	var query FileListQuery
+	if !bindPageQuery(c, &query) {
+		return
+	}
+
+	db := database.DB.Model(&models.FileRecord{}).Preload("Uploader").
+		Where("status = ?", models.FileStatusDeleted)
+	if query.UserID != nil {
+		db = db.Where("uploader_id = ?", *query.UserID)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		h.logger.Error("Failed to count deleted files", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return
+	}
+
+	var files []models.FileRecord
+	offset := query.Offset()
+	if err := db.Offset(offset).Limit(query.PageSize).Order("updated_at DESC").Find(&files).Error; err != nil {
+		h.logger.Error("Failed to list deleted files", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(models.NewPageResponse(files, total, query.Page, query.PageSize)))
+}
+
+// findDeletedFile 查找已删除的文件记录，未找到时写入响应并返回nil
+func (h *FileHandler) findDeletedFile(c *gin.Context) *models.FileRecord {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "文件ID无效"))
+		return nil
+	}
+
+	var fileRecord models.FileRecord
+	if err := database.DB.Where("id = ? AND status = ?", id, models.FileStatusDeleted).First(&fileRecord).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "文件不存在或未被删除"))
+		} else {
+			h.logger.Error("Failed to find file record", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		}
+		return nil
+	}
+	return &fileRecord
+}
+
+// RestoreFile 恢复已删除的文件
+// @Summary 恢复文件
+// @Description 恢复已删除的文件，物理文件已不存在时无法恢复
+// @Tags 文件管理
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文件ID"
+// @Success 200 {object} models.Response{data=models.FileRecord} "恢复成功"
+// @Failure 409 {object} models.Response "物理文件已不存在"
+// @Router /api/v1/files/{id}/restore [post]
+func (h *FileHandler) RestoreFile(c *gin.Context) {
+	fileRecord := h.findDeletedFile(c)
+	if fileRecord == nil {
+		return
+	}
+
+	// 检查物理文件是否仍存在
+	if !h.storageAvailable(fileRecord) {
+		c.JSON(http.StatusConflict, models.ErrorResponse(http.StatusConflict, "文件所在存储不可用，无法恢复"))
+		return
+	}
+	reader, err := h.storage.Get(c.Request.Context(), fileRecord.StoredName)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusConflict, models.ErrorResponse(http.StatusConflict, "物理文件已不存在，无法恢复"))
+		} else {
+			h.logger.Error("Failed to check physical file", zap.Error(err), zap.String("path", fileRecord.FilePath))
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "检查物理文件失败"))
+		}
+		return
+	}
+	reader.Close()
+
+	if err := database.DB.Model(fileRecord).Update("status", models.FileStatusActive).Error; err != nil {
+		h.logger.Error("Failed to restore file record", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "恢复失败"))
+		return
+	}
+
+	h.logger.Info("File restored",
+		zap.Uint("file_id", fileRecord.ID),
+		zap.Uint("operator", c.GetUint("user_id")),
+		zap.String("filename", fileRecord.OriginalName))
+
+	c.JSON(http.StatusOK, models.SuccessResponse(fileRecord))
+}
+
+// PurgeFile 彻底删除文件
+// @Summary 彻底删除文件
+// @Description 删除已删除文件的记录，物理文件没有其他记录引用时一并删除，操作不可恢复
+// @Tags 文件管理
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文件ID"
+// @Success 200 {object} models.Response "删除成功"
+// @Router /api/v1/files/{id}/purge [delete]
+func (h *FileHandler) PurgeFile(c *gin.Context) {
+	fileRecord := h.findDeletedFile(c)
+	if fileRecord == nil {
+		return
+	}
+
+	// 存储对象可能被内容相同的其他文件记录共用，仍有引用时只删除记录
+	refs, err := storedObjectRefs(fileRecord)
+	if err != nil {
+		h.logger.Error("Failed to count file references", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "删除失败"))
+		return
+	}
+
+	if refs > 0 {
+		h.logger.Info("Stored object still referenced, skip removing",
+			zap.Uint("file_id", fileRecord.ID), zap.String("stored_name", fileRecord.StoredName), zap.Int64("refs", refs))
+	} else if !h.storageAvailable(fileRecord) {
+		h.logger.Warn("File stored in unavailable storage, skip removing",
+			zap.Uint("file_id", fileRecord.ID), zap.String("storage_type", fileRecord.StorageType))
+	} else if err := h.storage.Delete(c.Request.Context(), fileRecord.StoredName); err != nil {
+		h.logger.Error("Failed to remove physical file", zap.Error(err), zap.String("path", fileRecord.FilePath))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "删除物理文件失败"))
+		return
+	}
+
+	if err := database.DB.Unscoped().Delete(fileRecord).Error; err != nil {
+		h.logger.Error("Failed to purge file record", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "删除失败"))
+		return
+	}
+
+	h.logger.Info("File purged",
+		zap.Uint("file_id", fileRecord.ID),
+		zap.Uint("operator", c.GetUint("user_id")),
+		zap.String("filename", fileRecord.OriginalName))
+
+	c.JSON(http.StatusOK, models.SuccessResponse(nil))
+}
+
 // GetFileInfo 获取文件信息
 // @Summary 获取文件信息
 // @Description 根据文件ID获取文件详细信息
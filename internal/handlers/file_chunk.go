@@ -0,0 +1,545 @@
+package handlers
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/models"
+)
+
+// chunkFileSuffix 已校验通过的分片文件后缀
+const chunkFileSuffix = ".part"
+
+// errUploadSessionClosed 上传会话已被合并或取消
+var errUploadSessionClosed = errors.New("upload session closed")
+
+// InitChunkUpload 初始化分片上传
+// @Summary 初始化分片上传
+// @Description 创建分片上传会话；相同用户、文件MD5和大小的未完成上传会直接返回，用于断点续传
+// @Tags 文件管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.ChunkUploadInitRequest true "上传信息"
+// @Success 200 {object} models.Response "初始化成功"
+// @Router /api/v1/files/chunks/init [post]
+func (h *FileHandler) InitChunkUpload(c *gin.Context) {
+	var req models.ChunkUploadInitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
+		return
+	}
+
+	if req.FileSize > h.chunkCfg.MaxFileSize {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest,
+			fmt.Sprintf("文件大小不能超过%dMB", h.chunkCfg.MaxFileSize/1024/1024)))
+		return
+	}
+	if req.ChunkSize < h.chunkCfg.MinChunkSize || req.ChunkSize > h.chunkCfg.MaxChunkSize {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest,
+			fmt.Sprintf("分片大小需在%d~%d字节之间", h.chunkCfg.MinChunkSize, h.chunkCfg.MaxChunkSize)))
+		return
+	}
+
+	fileName := filepath.Base(req.FileName)
+	if _, msg := h.policy.checkName(fileName, req.FileSize); msg != "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, msg))
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	fileMD5 := strings.ToLower(req.FileMD5)
+
+	// 断点续传：复用同一文件未完成的上传会话
+	if fileMD5 != "" {
+		var existing models.FileUploadSession
+		err := database.DB.Where("created_by = ? AND file_md5 = ? AND file_size = ? AND chunk_size = ? AND status = ? AND expires_at > ?",
+			userID, fileMD5, req.FileSize, req.ChunkSize, models.UploadSessionUploading, time.Now()).
+			Order("id DESC").First(&existing).Error
+		if err == nil {
+			h.respondChunkSession(c, &existing)
+			return
+		}
+	}
+
+	session := models.FileUploadSession{
+		UploadID:    uuid.New().String(),
+		FileName:    fileName,
+		FileSize:    req.FileSize,
+		FileMD5:     fileMD5,
+		MimeType:    req.MimeType,
+		ChunkSize:   req.ChunkSize,
+		TotalChunks: chunkCount(req.FileSize, req.ChunkSize),
+		Description: req.Description,
+		Tags:        req.Tags,
+		Status:      models.UploadSessionUploading,
+		ExpiresAt:   time.Now().Add(h.chunkCfg.Expire),
+	}
+	session.CreatedBy = userID
+	session.UpdatedBy = userID
+
+	if err := os.MkdirAll(h.sessionDir(session.UploadID), 0755); err != nil {
+		h.logger.Error("Failed to create chunk directory", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "初始化上传失败"))
+		return
+	}
+
+	if err := database.DB.Create(&session).Error; err != nil {
+		os.RemoveAll(h.sessionDir(session.UploadID))
+		h.logger.Error("Failed to create upload session", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "初始化上传失败"))
+		return
+	}
+
+	h.respondChunkSession(c, &session)
+}
+
+// GetChunkUpload 查询分片上传进度
+// @Summary 查询分片上传进度
+// @Description 返回上传会话信息和已上传的分片序号，客户端据此续传缺失分片
+// @Tags 文件管理
+// @Produce json
+// @Security BearerAuth
+// @Param upload_id path string true "上传ID"
+// @Success 200 {object} models.Response "查询成功"
+// @Router /api/v1/files/chunks/{upload_id} [get]
+func (h *FileHandler) GetChunkUpload(c *gin.Context) {
+	session, ok := h.findChunkSession(c)
+	if !ok {
+		return
+	}
+
+	h.respondChunkSession(c, session)
+}
+
+// UploadChunk 上传单个分片
+// @Summary 上传分片
+// @Description 上传指定序号(从0开始)的分片，可携带md5字段校验分片内容；重复上传会覆盖同序号分片
+// @Tags 文件管理
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param upload_id path string true "上传ID"
+// @Param index path int true "分片序号"
+// @Param chunk formData file true "分片内容"
+// @Param md5 formData string false "分片MD5"
+// @Success 200 {object} models.Response "上传成功"
+// @Router /api/v1/files/chunks/{upload_id}/{index} [put]
+func (h *FileHandler) UploadChunk(c *gin.Context) {
+	session, ok := h.findChunkSession(c)
+	if !ok {
+		return
+	}
+	if session.Status != models.UploadSessionUploading {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "上传会话已结束"))
+		return
+	}
+
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil || index < 0 || index >= session.TotalChunks {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "分片序号无效"))
+		return
+	}
+
+	chunk, err := c.FormFile("chunk")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "请选择要上传的分片"))
+		return
+	}
+	if chunk.Size != expectedChunkSize(session.FileSize, session.ChunkSize, index) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "分片大小不正确"))
+		return
+	}
+
+	src, err := chunk.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "读取分片失败"))
+		return
+	}
+	defer src.Close()
+
+	// 先写临时文件，校验通过后再重命名，避免留下不完整的分片
+	dir := h.sessionDir(session.UploadID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		h.logger.Error("Failed to create chunk directory", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "分片保存失败"))
+		return
+	}
+	tmp, err := os.CreateTemp(dir, "upload-*")
+	if err != nil {
+		h.logger.Error("Failed to create chunk file", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "分片保存失败"))
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := md5.New()
+	_, err = io.Copy(io.MultiWriter(tmp, hash), src)
+	tmp.Close()
+	if err != nil {
+		h.logger.Error("Failed to save chunk", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "分片保存失败"))
+		return
+	}
+
+	chunkMD5 := hex.EncodeToString(hash.Sum(nil))
+	if expected := c.PostForm("md5"); expected != "" && !strings.EqualFold(expected, chunkMD5) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "分片校验失败"))
+		return
+	}
+
+	if err := os.Rename(tmp.Name(), chunkPath(dir, index)); err != nil {
+		h.logger.Error("Failed to store chunk", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "分片保存失败"))
+		return
+	}
+
+	// 每次上传分片都顺延过期时间
+	database.DB.Model(session).Update("expires_at", time.Now().Add(h.chunkCfg.Expire))
+
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{
+		"upload_id": session.UploadID,
+		"index":     index,
+		"md5":       chunkMD5,
+	}))
+}
+
+// CompleteChunkUpload 合并分片
+// @Summary 合并分片
+// @Description 所有分片上传完成后合并为完整文件并创建文件记录
+// @Tags 文件管理
+// @Produce json
+// @Security BearerAuth
+// @Param upload_id path string true "上传ID"
+// @Success 200 {object} models.Response{data=models.FileRecord} "合并成功"
+// @Router /api/v1/files/chunks/{upload_id}/complete [post]
+func (h *FileHandler) CompleteChunkUpload(c *gin.Context) {
+	session, ok := h.findChunkSession(c)
+	if !ok {
+		return
+	}
+	if session.Status != models.UploadSessionUploading {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "上传会话已结束"))
+		return
+	}
+
+	dir := h.sessionDir(session.UploadID)
+	if missing := missingChunks(listUploadedChunks(dir), session.TotalChunks); len(missing) > 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest,
+			fmt.Sprintf("还有%d个分片未上传", len(missing))))
+		return
+	}
+
+	// 先在分片目录中合并并校验，再写入文件存储
+	mergedPath := filepath.Join(dir, "merged")
+	dst, err := os.Create(mergedPath)
+	if err != nil {
+		h.logger.Error("Failed to create merged file", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "合并分片失败"))
+		return
+	}
+	fileMD5, size, err := mergeChunks(dir, session.TotalChunks, dst)
+	dst.Close()
+	defer os.Remove(mergedPath)
+	if err != nil {
+		h.logger.Error("Failed to merge chunks", zap.Error(err), zap.String("upload_id", session.UploadID))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "合并分片失败"))
+		return
+	}
+
+	if size != session.FileSize || (session.FileMD5 != "" && session.FileMD5 != fileMD5) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "文件校验失败，请重新上传"))
+		return
+	}
+
+	rule, msg := h.policy.checkName(session.FileName, size)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, msg))
+		return
+	}
+
+	merged, err := os.Open(mergedPath)
+	if err != nil {
+		h.logger.Error("Failed to open merged file", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "合并分片失败"))
+		return
+	}
+	defer merged.Close()
+
+	// 按文件内容校验实际类型，防止修改扩展名伪装
+	mimeType, msg := h.policy.checkContent(rule, merged)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, msg))
+		return
+	}
+	if _, err := merged.Seek(0, io.SeekStart); err != nil {
+		h.logger.Error("Failed to rewind merged file", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "合并分片失败"))
+		return
+	}
+
+	storedName := fmt.Sprintf("%d_%s", time.Now().Unix(), session.FileName)
+	stored, err := h.storeContent(c.Request.Context(), storedName, merged, size, mimeType)
+	if err != nil {
+		h.logger.Error("Failed to save merged file", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "文件保存失败"))
+		return
+	}
+
+	fileRecord := models.FileRecord{
+		OriginalName: session.FileName,
+		StoredName:   stored.Name,
+		FilePath:     h.storage.Locate(stored.Name),
+		StorageType:  h.storage.Type(),
+		FileSize:     size,
+		FileType:     models.GetFileTypeByMime(mimeType),
+		MimeType:     mimeType,
+		Category:     rule.Category,
+		MD5Hash:      fileMD5,
+		SHA256Hash:   stored.SHA256,
+		Description:  session.Description,
+		Tags:         session.Tags,
+		Status:       models.FileStatusActive,
+		Deduplicated: stored.Reused,
+	}
+	fileRecord.CreatedBy = session.CreatedBy
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&fileRecord).Error; err != nil {
+			return err
+		}
+		// 以上传中状态为条件更新，防止重复合并
+		result := tx.Model(&models.FileUploadSession{}).
+			Where("id = ? AND status = ?", session.ID, models.UploadSessionUploading).
+			Updates(map[string]interface{}{
+				"status":  models.UploadSessionCompleted,
+				"file_id": fileRecord.ID,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errUploadSessionClosed
+		}
+		return nil
+	})
+	if errors.Is(err, errUploadSessionClosed) {
+		h.discardStored(c.Request.Context(), stored)
+		c.JSON(http.StatusConflict, models.ErrorResponse(http.StatusConflict, "上传会话已结束"))
+		return
+	}
+	if err != nil {
+		h.discardStored(c.Request.Context(), stored)
+		h.logger.Error("Failed to create file record", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "文件记录创建失败"))
+		return
+	}
+
+	os.RemoveAll(dir)
+
+	h.logger.Info("Chunked upload completed",
+		zap.Uint("file_id", fileRecord.ID),
+		zap.String("upload_id", session.UploadID),
+		zap.Int64("size", size),
+		zap.Bool("deduplicated", stored.Reused))
+
+	c.JSON(http.StatusOK, models.SuccessResponse(fileRecord))
+}
+
+// AbortChunkUpload 取消分片上传
+// @Summary 取消分片上传
+// @Description 取消上传并删除已上传的分片
+// @Tags 文件管理
+// @Produce json
+// @Security BearerAuth
+// @Param upload_id path string true "上传ID"
+// @Success 200 {object} models.Response "取消成功"
+// @Router /api/v1/files/chunks/{upload_id} [delete]
+func (h *FileHandler) AbortChunkUpload(c *gin.Context) {
+	session, ok := h.findChunkSession(c)
+	if !ok {
+		return
+	}
+	if session.Status != models.UploadSessionUploading {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "上传会话已结束"))
+		return
+	}
+
+	if err := database.DB.Model(session).Update("status", models.UploadSessionAborted).Error; err != nil {
+		h.logger.Error("Failed to abort upload session", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "取消上传失败"))
+		return
+	}
+	os.RemoveAll(h.sessionDir(session.UploadID))
+
+	c.JSON(http.StatusOK, models.SuccessResponse(nil))
+}
+
+// findChunkSession 查询当前用户的上传会话，失败时已写入响应
+func (h *FileHandler) findChunkSession(c *gin.Context) (*models.FileUploadSession, bool) {
+	uploadID := c.Param("upload_id")
+	if _, err := uuid.Parse(uploadID); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "上传ID无效"))
+		return nil, false
+	}
+
+	var session models.FileUploadSession
+	err := database.DB.Where("upload_id = ? AND created_by = ?", uploadID, c.GetUint("user_id")).First(&session).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "上传会话不存在"))
+		} else {
+			h.logger.Error("Failed to find upload session", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		}
+		return nil, false
+	}
+
+	return &session, true
+}
+
+// respondChunkSession 返回上传会话及已上传分片
+func (h *FileHandler) respondChunkSession(c *gin.Context, session *models.FileUploadSession) {
+	uploaded := []int{}
+	if session.Status == models.UploadSessionUploading {
+		uploaded = listUploadedChunks(h.sessionDir(session.UploadID))
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{
+		"session":  session,
+		"uploaded": uploaded,
+	}))
+}
+
+// sessionDir 上传会话的分片目录
+func (h *FileHandler) sessionDir(uploadID string) string {
+	return filepath.Join(h.chunkDir, uploadID)
+}
+
+// runChunkCleanup 定期清理过期的未完成上传
+func (h *FileHandler) runChunkCleanup() {
+	ticker := time.NewTicker(h.chunkCfg.CleanupInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		h.cleanupExpiredChunks()
+	}
+}
+
+// cleanupExpiredChunks 将过期会话标记为过期并删除其临时分片
+func (h *FileHandler) cleanupExpiredChunks() {
+	if database.DB == nil {
+		return
+	}
+
+	var sessions []models.FileUploadSession
+	if err := database.DB.Where("status = ? AND expires_at < ?", models.UploadSessionUploading, time.Now()).
+		Find(&sessions).Error; err != nil {
+		h.logger.Error("Failed to query expired upload sessions", zap.Error(err))
+		return
+	}
+
+	for i := range sessions {
+		if err := os.RemoveAll(h.sessionDir(sessions[i].UploadID)); err != nil {
+			h.logger.Warn("Failed to remove expired chunks", zap.Error(err), zap.String("upload_id", sessions[i].UploadID))
+			continue
+		}
+		database.DB.Model(&sessions[i]).Update("status", models.UploadSessionExpired)
+	}
+
+	if len(sessions) > 0 {
+		h.logger.Info("Cleaned up expired chunk uploads", zap.Int("count", len(sessions)))
+	}
+}
+
+// chunkCount 计算分片数量
+func chunkCount(fileSize, chunkSize int64) int {
+	return int((fileSize + chunkSize - 1) / chunkSize)
+}
+
+// expectedChunkSize 第index个分片的应有大小，最后一片可能不足chunkSize
+func expectedChunkSize(fileSize, chunkSize int64, index int) int64 {
+	remaining := fileSize - int64(index)*chunkSize
+	if remaining < chunkSize {
+		return remaining
+	}
+	return chunkSize
+}
+
+// chunkPath 分片文件路径
+func chunkPath(dir string, index int) string {
+	return filepath.Join(dir, strconv.Itoa(index)+chunkFileSuffix)
+}
+
+// listUploadedChunks 列出目录中已完成的分片序号
+func listUploadedChunks(dir string) []int {
+	indexes := []int{}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return indexes
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasSuffix(name, chunkFileSuffix) {
+			continue
+		}
+		if index, err := strconv.Atoi(strings.TrimSuffix(name, chunkFileSuffix)); err == nil {
+			indexes = append(indexes, index)
+		}
+	}
+	sort.Ints(indexes)
+	return indexes
+}
+
+// missingChunks 返回尚未上传的分片序号
+func missingChunks(uploaded []int, total int) []int {
+	done := make(map[int]bool, len(uploaded))
+	for _, index := range uploaded {
+		done[index] = true
+	}
+	var missing []int
+	for i := 0; i < total; i++ {
+		if !done[i] {
+			missing = append(missing, i)
+		}
+	}
+	return missing
+}
+
+// mergeChunks 按序号顺序将分片写入dst，返回合并后文件的MD5和大小
+func mergeChunks(dir string, total int, dst io.Writer) (string, int64, error) {
+	hash := md5.New()
+	writer := io.MultiWriter(dst, hash)
+
+	var size int64
+	for i := 0; i < total; i++ {
+		f, err := os.Open(chunkPath(dir, i))
+		if err != nil {
+			return "", 0, fmt.Errorf("打开分片%d失败: %w", i, err)
+		}
+		n, err := io.Copy(writer, f)
+		f.Close()
+		if err != nil {
+			return "", 0, fmt.Errorf("写入分片%d失败: %w", i, err)
+		}
+		size += n
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
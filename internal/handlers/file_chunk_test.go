@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkSizes(t *testing.T) {
+	assert.Equal(t, 3, chunkCount(25, 10))
+	assert.Equal(t, 2, chunkCount(20, 10))
+	assert.Equal(t, int64(10), expectedChunkSize(25, 10, 1))
+	assert.Equal(t, int64(5), expectedChunkSize(25, 10, 2))
+}
+
+func TestMergeChunks(t *testing.T) {
+	dir := t.TempDir()
+	parts := []string{"hello ", "chunked ", "world"}
+	// 乱序写入，合并时应按序号拼接
+	for _, i := range []int{2, 0, 1} {
+		require.NoError(t, os.WriteFile(chunkPath(dir, i), []byte(parts[i]), 0644))
+	}
+	require.NoError(t, os.WriteFile(dir+"/upload-123", []byte("tmp"), 0644))
+
+	assert.Equal(t, []int{0, 1, 2}, listUploadedChunks(dir))
+	assert.Equal(t, []int{3}, missingChunks(listUploadedChunks(dir), 4))
+
+	var buf bytes.Buffer
+	sum, size, err := mergeChunks(dir, 3, &buf)
+	require.NoError(t, err)
+
+	expected := md5.Sum([]byte("hello chunked world"))
+	assert.Equal(t, "hello chunked world", buf.String())
+	assert.Equal(t, int64(19), size)
+	assert.Equal(t, hex.EncodeToString(expected[:]), sum)
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+
+	"go.uber.org/zap"
+
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/models"
+	"github.com/env-data-platform/internal/storage"
+)
+
+// storedObject 文件内容在存储后端中的对象
+type storedObject struct {
+	Name   string // 存储名
+	SHA256 string // 内容SHA256
+	Reused bool   // 复用了已有记录的存储对象
+}
+
+// contentSHA256 计算内容的SHA256，完成后将读取位置恢复到开头
+func contentSHA256(r io.ReadSeeker) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return "", err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// storeContent 按内容SHA256去重写入存储：当前存储后端中已有相同内容的对象时复用其存储名，
+// 只新建文件记录引用；否则以storedName写入新对象
+func (h *FileHandler) storeContent(ctx context.Context, storedName string, r io.ReadSeeker, size int64, mimeType string) (*storedObject, error) {
+	sum, err := contentSHA256(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing := h.findStoredDuplicate(ctx, sum, size); existing != nil {
+		return &storedObject{Name: existing.StoredName, SHA256: sum, Reused: true}, nil
+	}
+
+	if err := h.storage.Put(ctx, storedName, r, size, mimeType); err != nil {
+		return nil, err
+	}
+	return &storedObject{Name: storedName, SHA256: sum}, nil
+}
+
+// findStoredDuplicate 查找当前存储后端中内容相同且对象仍存在的文件记录
+func (h *FileHandler) findStoredDuplicate(ctx context.Context, sum string, size int64) *models.FileRecord {
+	var records []models.FileRecord
+	if err := database.DB.Where("sha256_hash = ? AND file_size = ? AND storage_type = ?", sum, size, h.storage.Type()).
+		Order("id").Find(&records).Error; err != nil {
+		h.logger.Warn("Failed to find duplicate file", zap.Error(err))
+		return nil
+	}
+
+	checked := make(map[string]bool)
+	for i := range records {
+		name := records[i].StoredName
+		if checked[name] {
+			continue
+		}
+		checked[name] = true
+
+		reader, err := h.storage.Get(ctx, name)
+		if err != nil {
+			if !errors.Is(err, storage.ErrNotFound) {
+				h.logger.Warn("Failed to check duplicate file", zap.Error(err), zap.String("stored_name", name))
+			}
+			continue
+		}
+		reader.Close()
+		return &records[i]
+	}
+	return nil
+}
+
+// discardStored 文件记录创建失败时删除本次新写入的对象，复用的对象仍被其他记录引用，保留
+func (h *FileHandler) discardStored(ctx context.Context, obj *storedObject) {
+	if obj.Reused {
+		return
+	}
+	h.storage.Delete(ctx, obj.Name)
+}
+
+// storedObjectRefs 除record外仍引用同一存储对象的文件记录数，含已删除待清理的记录
+func storedObjectRefs(record *models.FileRecord) (int64, error) {
+	// 早期记录的存储类型为空，表示本地存储
+	storageTypes := []string{record.StorageType}
+	if record.StorageType == "" || record.StorageType == storage.TypeLocal {
+		storageTypes = []string{"", storage.TypeLocal}
+	}
+
+	var count int64
+	err := database.DB.Model(&models.FileRecord{}).
+		Where("stored_name = ? AND id <> ? AND storage_type IN ?", record.StoredName, record.ID, storageTypes).
+		Count(&count).Error
+	return count, err
+}
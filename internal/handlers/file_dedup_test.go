@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentSHA256(t *testing.T) {
+	r := strings.NewReader("same content")
+	sum, err := contentSHA256(r)
+	require.NoError(t, err)
+
+	expected := sha256.Sum256([]byte("same content"))
+	assert.Equal(t, hex.EncodeToString(expected[:]), sum)
+
+	// 计算后仍可从头读取内容写入存储
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "same content", string(data))
+}
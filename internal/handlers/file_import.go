@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/models"
+	"github.com/env-data-platform/internal/services"
+)
+
+// ImportDataSourceRequest CSV/Excel导入为数据源请求
+type ImportDataSourceRequest struct {
+	Name        string `json:"name" binding:"max=100"` // 为空时使用文件名
+	Description string `json:"description" binding:"max=500"`
+	Region      string `json:"region" binding:"max=100"`
+}
+
+// ImportAsDataSource CSV/Excel文件导入为数据源
+// @Summary 文件导入为数据源
+// @Description 解析已上传的CSV或XLSX文件，按表头建表并推断列类型，导入数据行后注册为MySQL数据源，可直接用于ETL和质量检查。
+// @Description 无法转换为列类型或写入失败的行记入导入报告，所有行都失败时不创建数据源
+// @Tags 文件管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文件ID"
+// @Param request body ImportDataSourceRequest false "数据源信息"
+// @Success 200 {object} models.Response{data=services.ImportReport} "导入完成"
+// @Failure 400 {object} models.Response "文件格式错误"
+// @Failure 422 {object} models.Response{data=services.ImportReport} "所有数据行均导入失败"
+// @Router /api/v1/files/{id}/import-datasource [post]
+func (h *FileHandler) ImportAsDataSource(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "文件ID无效"))
+		return
+	}
+
+	var req ImportDataSourceRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
+			return
+		}
+	}
+
+	userID := c.GetUint("user_id")
+	var fileRecord models.FileRecord
+	if err := database.DB.Where("id = ? AND status = ?", id, models.FileStatusActive).First(&fileRecord).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "文件不存在"))
+		} else {
+			h.logger.Error("Failed to find file record", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		}
+		return
+	}
+
+	// 权限检查：只有上传者才能导入
+	if fileRecord.CreatedBy != userID {
+		c.JSON(http.StatusForbidden, models.ErrorResponse(http.StatusForbidden, "无权限导入此文件"))
+		return
+	}
+	if !h.storageAvailable(&fileRecord) {
+		c.JSON(http.StatusConflict, models.ErrorResponse(http.StatusConflict, "文件所在存储不可用"))
+		return
+	}
+
+	data, err := h.readStoredFile(c, &fileRecord)
+	if err != nil {
+		h.logger.Error("Failed to read file for import", zap.Error(err), zap.Uint("file_id", fileRecord.ID))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "读取文件失败"))
+		return
+	}
+
+	table, err := services.ReadImportTable(data, filepath.Ext(fileRecord.OriginalName), h.importCfg.MaxRows)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	tableName := services.ImportTableName(fileRecord.OriginalName, fileRecord.ID)
+	if database.DB.Migrator().HasTable(tableName) {
+		// 同一文件重复导入时建新表，不覆盖已有数据源的数据
+		tableName = fmt.Sprintf("%s_%d", tableName, time.Now().Unix())
+	}
+
+	importer := services.NewTableImporter(database.DB, h.logger, h.importCfg.BatchSize)
+	report, err := importer.Import(c.Request.Context(), tableName, table)
+	if err != nil {
+		if report == nil {
+			h.logger.Error("Failed to import file", zap.Error(err), zap.Uint("file_id", fileRecord.ID))
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "导入失败: "+err.Error()))
+			return
+		}
+		resp := models.ErrorResponse(http.StatusUnprocessableEntity, err.Error())
+		resp.Data = report
+		c.JSON(http.StatusUnprocessableEntity, resp)
+		return
+	}
+
+	dataSource, err := h.registerImportedDataSource(&fileRecord, &req, report, userID)
+	if err != nil {
+		importer.DropTable(tableName)
+		h.logger.Error("Failed to register imported data source", zap.Error(err), zap.Uint("file_id", fileRecord.ID))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "注册数据源失败"))
+		return
+	}
+	report.DataSourceID = dataSource.ID
+
+	h.logger.Info("File imported as data source",
+		zap.Uint("file_id", fileRecord.ID),
+		zap.Uint("datasource_id", dataSource.ID),
+		zap.String("table", tableName),
+		zap.Int("imported_rows", report.ImportedRows),
+		zap.Int("failed_rows", report.FailedRows))
+
+	invalidateDashboardCache(c, h.logger)
+	c.JSON(http.StatusOK, models.SuccessResponse(report))
+}
+
+// readStoredFile 读取存储中的文件内容
+func (h *FileHandler) readStoredFile(c *gin.Context, record *models.FileRecord) ([]byte, error) {
+	reader, err := h.storage.Get(c.Request.Context(), record.StoredName)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// registerImportedDataSource 将导入表注册为MySQL数据源，同时登记表和列的元数据
+func (h *FileHandler) registerImportedDataSource(record *models.FileRecord, req *ImportDataSourceRequest, report *services.ImportReport, userID uint) (*models.DataSource, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = strings.TrimSuffix(record.OriginalName, filepath.Ext(record.OriginalName))
+	}
+	description := req.Description
+	if description == "" {
+		description = fmt.Sprintf("由文件 %s 导入，数据表 %s", record.OriginalName, report.TableName)
+	}
+
+	// 导入表位于平台数据库中，连接参数在使用时取自平台配置，不写入数据源配置，访问范围由ImportTable限定
+	dsConfig := models.DataSourceConfig{
+		FilePath:   record.FilePath,
+		FileFormat: strings.TrimPrefix(strings.ToLower(filepath.Ext(record.OriginalName)), "."),
+		CustomConfig: map[string]interface{}{
+			"table":          report.TableName,
+			"source_file_id": record.ID,
+		},
+	}
+	configBytes, err := json.Marshal(dsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	dataSource := &models.DataSource{
+		Name:         name,
+		Type:         "mysql",
+		Description:  description,
+		Region:       req.Region,
+		Config:       string(configBytes),
+		ConfigData:   configBytes,
+		Status:       models.DataSourceStatusActive,
+		IsConnected:  true,
+		LastSyncAt:   &now,
+		Tags:         "imported",
+		HealthStatus: models.DataSourceHealthUnknown,
+		ImportTable:  report.TableName,
+	}
+	dataSource.CreatedBy = userID
+	dataSource.UpdatedBy = userID
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(dataSource).Error; err != nil {
+			return err
+		}
+
+		dataTable := &models.DataTable{
+			DataSourceID: dataSource.ID,
+			Name:         report.TableName,
+			TableComment: record.OriginalName,
+			Schema:       h.dbCfg.Name,
+			RowCount:     int64(report.ImportedRows),
+			LastSyncAt:   &now,
+			IsActive:     true,
+		}
+		if err := tx.Create(dataTable).Error; err != nil {
+			return err
+		}
+
+		columns := make([]models.DataColumn, 0, len(report.Columns)+1)
+		columns = append(columns, models.DataColumn{
+			TableID:      dataTable.ID,
+			ColumnName:   services.ImportRowColumn,
+			DataType:     "int",
+			IsPrimaryKey: true,
+			Comment:      "源文件行号",
+			Position:     1,
+		})
+		for i, column := range report.Columns {
+			columns = append(columns, models.DataColumn{
+				TableID:    dataTable.ID,
+				ColumnName: column.Name,
+				DataType:   strings.ToLower(column.SQLType),
+				IsNullable: true,
+				Comment:    column.Header,
+				Position:   i + 2,
+			})
+		}
+		return tx.Create(&columns).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dataSource, nil
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+
+	"github.com/env-data-platform/internal/config"
+)
+
+// fileCategoryRule 单个文件分类的上传限制
+type fileCategoryRule struct {
+	Category  string
+	MaxSize   int64
+	MimeTypes []string
+}
+
+// fileTypePolicy 上传文件白名单，按扩展名确定分类，再校验大小和实际MIME
+type fileTypePolicy struct {
+	byExt map[string]*fileCategoryRule
+}
+
+// newFileTypePolicy 根据上传配置构建白名单
+func newFileTypePolicy(cfg config.UploadConfig) *fileTypePolicy {
+	policy := &fileTypePolicy{byExt: make(map[string]*fileCategoryRule)}
+	for name, category := range cfg.Categories {
+		rule := &fileCategoryRule{
+			Category:  name,
+			MaxSize:   category.MaxSize,
+			MimeTypes: category.MimeTypes,
+		}
+		if rule.MaxSize <= 0 {
+			rule.MaxSize = cfg.MaxSize
+		}
+		for _, ext := range category.Extensions {
+			ext = strings.ToLower(ext)
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			policy.byExt[ext] = rule
+		}
+	}
+	return policy
+}
+
+// checkName 校验文件扩展名和大小，返回所属分类；不通过时返回错误信息
+func (p *fileTypePolicy) checkName(filename string, size int64) (*fileCategoryRule, string) {
+	rule, ok := p.byExt[strings.ToLower(filepath.Ext(filename))]
+	if !ok {
+		return nil, "不支持的文件类型"
+	}
+	if rule.MaxSize > 0 && size > rule.MaxSize {
+		return nil, fmt.Sprintf("文件大小不能超过%s", formatFileSize(rule.MaxSize))
+	}
+	return rule, ""
+}
+
+// checkContent 按文件内容识别MIME并校验是否属于该分类，返回识别出的MIME；分类未配置MIME时不限制
+func (p *fileTypePolicy) checkContent(rule *fileCategoryRule, r io.Reader) (string, string) {
+	detected, err := mimetype.DetectReader(r)
+	if err != nil {
+		return "", "读取文件内容失败"
+	}
+	if len(rule.MimeTypes) == 0 {
+		return baseMime(detected.String()), ""
+	}
+
+	// 识别结果及其父类型任一命中即可，如csv的父类型为text/plain
+	for m := detected; m != nil; m = m.Parent() {
+		if mimeAllowed(baseMime(m.String()), rule.MimeTypes) {
+			return baseMime(detected.String()), ""
+		}
+	}
+	return "", "文件内容与扩展名不符"
+}
+
+// mimeAllowed 判断MIME是否在允许列表中，支持 type/* 通配
+func mimeAllowed(mime string, allowed []string) bool {
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == mime {
+			return true
+		}
+		if strings.HasSuffix(pattern, "/*") && strings.HasPrefix(mime, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// baseMime 去掉MIME中的参数部分，如 text/plain; charset=utf-8
+func baseMime(mime string) string {
+	if i := strings.Index(mime, ";"); i >= 0 {
+		mime = mime[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(mime))
+}
+
+// formatFileSize 格式化文件大小
+func formatFileSize(size int64) string {
+	switch {
+	case size >= 1024*1024*1024:
+		return fmt.Sprintf("%.1fGB", float64(size)/1024/1024/1024)
+	case size >= 1024*1024:
+		return fmt.Sprintf("%dMB", size/1024/1024)
+	default:
+		return fmt.Sprintf("%dKB", size/1024)
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/env-data-platform/internal/config"
+)
+
+func testFilePolicy() *fileTypePolicy {
+	return newFileTypePolicy(config.UploadConfig{
+		MaxSize: 1024,
+		Categories: map[string]config.FileCategoryConfig{
+			"data":  {Extensions: []string{"csv", ".JSON"}, MimeTypes: []string{"text/*", "application/json"}},
+			"image": {Extensions: []string{".png"}, MimeTypes: []string{"image/png"}, MaxSize: 2048},
+		},
+	})
+}
+
+func TestFileTypePolicy_CheckName(t *testing.T) {
+	policy := testFilePolicy()
+
+	rule, msg := policy.checkName("监测数据.CSV", 100)
+	require.Empty(t, msg)
+	assert.Equal(t, "data", rule.Category)
+
+	_, msg = policy.checkName("run.exe", 100)
+	assert.Equal(t, "不支持的文件类型", msg)
+
+	// 分类未设置上限时使用全局上限
+	_, msg = policy.checkName("data.json", 1025)
+	assert.Equal(t, "文件大小不能超过1KB", msg)
+
+	rule, msg = policy.checkName("photo.png", 2000)
+	require.Empty(t, msg)
+	assert.Equal(t, "image", rule.Category)
+}
+
+func TestFileTypePolicy_CheckContent(t *testing.T) {
+	policy := testFilePolicy()
+	pngHeader := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+	rule, _ := policy.checkName("data.csv", 20)
+	mime, msg := policy.checkContent(rule, strings.NewReader("id,value\n1,2\n3,4\n"))
+	require.Empty(t, msg)
+	assert.True(t, strings.HasPrefix(mime, "text/"))
+
+	// 扩展名伪装的图片被拒绝
+	_, msg = policy.checkContent(rule, bytes.NewReader(pngHeader))
+	assert.Equal(t, "文件内容与扩展名不符", msg)
+
+	rule, _ = policy.checkName("photo.png", 20)
+	mime, msg = policy.checkContent(rule, bytes.NewReader(pngHeader))
+	require.Empty(t, msg)
+	assert.Equal(t, "image/png", mime)
+}
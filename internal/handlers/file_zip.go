@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/models"
+	"github.com/env-data-platform/internal/storage"
+)
+
+// DownloadFilesZip 多文件打包下载
+// @Summary 多文件打包下载
+// @Description 将多个文件流式打包为zip返回，只打包当前用户有权限的文件，无权限或不存在的文件ID通过X-Skipped-Files响应头返回
+// @Tags 文件管理
+// @Accept json
+// @Produce application/zip
+// @Security BearerAuth
+// @Param request body models.FileZipDownloadRequest true "文件ID列表"
+// @Success 200 {file} binary "zip文件"
+// @Router /api/v1/files/zip [post]
+func (h *FileHandler) DownloadFilesZip(c *gin.Context) {
+	var req models.FileZipDownloadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse(http.StatusUnauthorized, "未授权"))
+		return
+	}
+
+	ids := uniqueFileIDs(req.FileIDs)
+	if h.zipCfg.MaxFiles > 0 && len(ids) > h.zipCfg.MaxFiles {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest,
+			fmt.Sprintf("单次最多打包%d个文件", h.zipCfg.MaxFiles)))
+		return
+	}
+
+	var records []models.FileRecord
+	if err := database.DB.Where("id IN ? AND status = ?", ids, models.FileStatusActive).
+		Find(&records).Error; err != nil {
+		h.logger.Error("Failed to query file records", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return
+	}
+
+	found := make(map[uint]*models.FileRecord, len(records))
+	for i := range records {
+		// 权限检查与单文件下载一致
+		if canDownloadFile(c, &records[i]) && h.storageAvailable(&records[i]) {
+			found[records[i].ID] = &records[i]
+		}
+	}
+
+	var files []*models.FileRecord
+	var skipped []string
+	var totalSize int64
+	for _, id := range ids {
+		record, ok := found[id]
+		if !ok {
+			skipped = append(skipped, strconv.FormatUint(uint64(id), 10))
+			continue
+		}
+		files = append(files, record)
+		totalSize += record.FileSize
+	}
+
+	if len(files) == 0 {
+		c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "没有可下载的文件"))
+		return
+	}
+	if h.zipCfg.MaxTotalSize > 0 && totalSize > h.zipCfg.MaxTotalSize {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest,
+			fmt.Sprintf("打包文件总大小不能超过%s", formatFileSize(h.zipCfg.MaxTotalSize))))
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = "files_" + time.Now().Format("20060102150405")
+	}
+	name = filepath.Base(name) + ".zip"
+
+	// 首个文件在发出响应头之前打开，存储不可读时仍能返回错误状态码
+	first, err := h.storage.Get(c.Request.Context(), files[0].StoredName)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			h.logger.Error("File not found in storage", zap.Uint("file_id", files[0].ID), zap.String("path", files[0].FilePath))
+			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "文件不存在"))
+		} else {
+			h.logger.Error("Failed to read file from storage", zap.Error(err), zap.Uint("file_id", files[0].ID))
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "读取文件失败"))
+		}
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(name))
+	if len(skipped) > 0 {
+		c.Header("X-Skipped-Files", strings.Join(skipped, ","))
+	}
+	c.Status(http.StatusOK)
+
+	// 响应头已发出，之后的错误只能中断输出，客户端会收到不完整的压缩包
+	zw := zip.NewWriter(c.Writer)
+	usedNames := make(map[string]bool, len(files))
+	var packed []uint
+	for _, record := range files {
+		// 只有首个文件已提前打开，其余在写入时读取
+		reader := first
+		first = nil
+		if err := h.writeZipEntry(c, zw, record, uniqueZipName(record.OriginalName, usedNames), reader); err != nil {
+			h.logger.Error("Failed to write zip entry",
+				zap.Error(err), zap.Uint("file_id", record.ID), zap.String("filename", record.OriginalName))
+			c.Abort()
+			return
+		}
+		packed = append(packed, record.ID)
+	}
+	if err := zw.Close(); err != nil {
+		h.logger.Error("Failed to finish zip stream", zap.Error(err))
+		c.Abort()
+		return
+	}
+
+	database.DB.Model(&models.FileRecord{}).Where("id IN ?", packed).Updates(map[string]interface{}{
+		"access_count": gorm.Expr("access_count + 1"),
+		"last_access":  time.Now(),
+	})
+
+	h.logger.Info("Files downloaded as zip",
+		zap.Uint("user_id", userID.(uint)),
+		zap.Int("files", len(packed)),
+		zap.Int("skipped", len(skipped)),
+		zap.Int64("total_size", totalSize))
+}
+
+// writeZipEntry 将文件写入压缩包，reader为空时从存储读取，传入的reader同样在写入后关闭
+func (h *FileHandler) writeZipEntry(c *gin.Context, zw *zip.Writer, record *models.FileRecord, name string, reader io.ReadCloser) error {
+	if reader == nil {
+		var err error
+		if reader, err = h.storage.Get(c.Request.Context(), record.StoredName); err != nil {
+			return err
+		}
+	}
+	defer reader.Close()
+
+	header := &zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: record.CreatedAt,
+	}
+	// 图片等已压缩格式直接存储，避免无效的CPU开销
+	if record.FileType == models.FileTypeImage || record.FileType == models.FileTypeArchive {
+		header.Method = zip.Store
+	}
+
+	w, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, reader)
+	return err
+}
+
+// uniqueFileIDs 按原顺序去重
+func uniqueFileIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// uniqueZipName 生成压缩包内不重复的文件名，重名时追加序号，如 data(1).csv
+func uniqueZipName(name string, used map[string]bool) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" || name == "" {
+		name = "file"
+	}
+
+	candidate := name
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	for i := 1; used[strings.ToLower(candidate)]; i++ {
+		candidate = fmt.Sprintf("%s(%d)%s", stem, i, ext)
+	}
+	used[strings.ToLower(candidate)] = true
+	return candidate
+}
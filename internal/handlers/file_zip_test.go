@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/database/dbtest"
+	"github.com/env-data-platform/internal/models"
+	"github.com/env-data-platform/internal/storage"
+)
+
+func TestUniqueZipName(t *testing.T) {
+	used := make(map[string]bool)
+	assert.Equal(t, "data.csv", uniqueZipName("data.csv", used))
+	assert.Equal(t, "DATA(1).csv", uniqueZipName("DATA.csv", used))
+	assert.Equal(t, "data(2).csv", uniqueZipName("../data.csv", used))
+	assert.Equal(t, "report", uniqueZipName(`C:\tmp\report`, used))
+	assert.Equal(t, "file", uniqueZipName("", used))
+}
+
+func TestUniqueFileIDs(t *testing.T) {
+	assert.Equal(t, []uint{3, 1, 2}, uniqueFileIDs([]uint{3, 1, 3, 2, 1}))
+}
+
+// zipFileHandler 返回使用本地存储的处理器，database.DB 替换为DryRun数据库，查询文件记录时返回用户9上传的 a.csv
+func zipFileHandler(t *testing.T) (*FileHandler, string) {
+	db := dbtest.New(t)
+	db.On(dbtest.KindQuery, func(tx *gorm.DB) {
+		if records, ok := tx.Statement.Dest.(*[]models.FileRecord); ok {
+			record := models.FileRecord{OriginalName: "a.csv", StoredName: "a.csv", Status: models.FileStatusActive}
+			record.ID = 1
+			record.CreatedBy = 9
+			*records = []models.FileRecord{record}
+		}
+	})
+	db.Replace(t, &database.DB)
+
+	root := t.TempDir()
+	return &FileHandler{logger: zap.NewNop(), storage: storage.NewLocal(root)}, root
+}
+
+func serveFilesZip(h *FileHandler, userID uint, roleName string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/files/zip", strings.NewReader(`{"file_ids":[1,2]}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("user_id", userID)
+	c.Set("role_name", roleName)
+	h.DownloadFilesZip(c)
+	return w
+}
+
+func TestDownloadFilesZipPermission(t *testing.T) {
+	h, root := zipFileHandler(t)
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.csv"), []byte("a,b\n1,2\n"), 0o644))
+
+	// 非上传者与单文件下载一样无权限
+	w := serveFilesZip(h, 3, "普通用户")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// 超级管理员可以打包他人上传的文件
+	w = serveFilesZip(h, 3, "admin")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("X-Skipped-Files"))
+
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	require.NoError(t, err)
+	require.Len(t, zr.File, 1)
+	f, err := zr.File[0].Open()
+	require.NoError(t, err)
+	content, err := io.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, "a,b\n1,2\n", string(content))
+}
+
+func TestDownloadFilesZipFirstEntryMissing(t *testing.T) {
+	h, _ := zipFileHandler(t)
+
+	// 存储中的文件已丢失时在发出zip响应头之前返回错误
+	w := serveFilesZip(h, 9, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Disposition"))
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -10,6 +12,7 @@ import (
 
 	"github.com/env-data-platform/internal/database"
 	"github.com/env-data-platform/internal/hj212"
+	"github.com/env-data-platform/internal/middleware"
 	"github.com/env-data-platform/internal/models"
 )
 
@@ -51,6 +54,19 @@ type SendCommandRequest struct {
 	Command  string `json:"command" binding:"required"`
 }
 
+// defaultControlTimeout 等待设备执行控制命令的默认时长
+const defaultControlTimeout = 30 * time.Second
+
+// DeviceControlRequest 控制命令下发请求
+type DeviceControlRequest struct {
+	Type       string                      `json:"type" binding:"required"`
+	SystemTime *time.Time                  `json:"system_time"`
+	PolID      string                      `json:"pol_id" binding:"max=20"`
+	Interval   int                         `json:"interval" binding:"omitempty,min=1,max=86400"`
+	Limits     map[string]hj212.LimitValue `json:"limits"`
+	Timeout    int                         `json:"timeout" binding:"omitempty,min=1,max=300"` // 等待执行结果的秒数，默认30
+}
+
 // QueryData 查询HJ212数据
 // @Summary 查询HJ212监测数据
 // @Description 分页查询HJ212监测数据，支持多条件筛选
@@ -69,21 +85,12 @@ type SendCommandRequest struct {
 // @Router /api/v1/hj212/data [get]
 func (h *HJ212Handler) QueryData(c *gin.Context) {
 	var query HJ212DataQuery
-	if err := c.ShouldBindQuery(&query); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "查询参数错误"))
+	if !bindPageQuery(c, &query) {
 		return
 	}
 
-	// 设置默认分页参数
-	if query.Page <= 0 {
-		query.Page = 1
-	}
-	if query.PageSize <= 0 {
-		query.PageSize = 10
-	}
-
 	// 构建查询
-	db := database.DB.Model(&models.HJ212Data{})
+	db := database.DB.Model(&models.HJ212Data{}).Scopes(middleware.DeviceScope(c, "device_id"))
 
 	// 应用筛选条件
 	if query.DeviceID != nil && *query.DeviceID != "" {
@@ -112,7 +119,7 @@ func (h *HJ212Handler) QueryData(c *gin.Context) {
 
 	// 分页查询
 	var data []models.HJ212Data
-	offset := (query.Page - 1) * query.PageSize
+	offset := query.Offset()
 	if err := db.Offset(offset).Limit(query.PageSize).Order("received_at DESC").Find(&data).Error; err != nil {
 		h.logger.Error("Failed to query HJ212 data", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
@@ -140,7 +147,7 @@ func (h *HJ212Handler) QueryData(c *gin.Context) {
 func (h *HJ212Handler) GetStats(c *gin.Context) {
 	var query HJ212StatsQuery
 	if err := c.ShouldBindQuery(&query); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "查询参数错误"))
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &query))
 		return
 	}
 
@@ -162,6 +169,7 @@ func (h *HJ212Handler) GetStats(c *gin.Context) {
 
 	// 基础统计查询
 	baseQuery := database.DB.Model(&models.HJ212Data{}).
+		Scopes(middleware.DeviceScope(c, "device_id")).
 		Where("received_at >= ? AND received_at <= ?", *query.StartTime, *query.EndTime)
 
 	if query.DeviceID != nil && *query.DeviceID != "" {
@@ -194,6 +202,7 @@ func (h *HJ212Handler) GetStats(c *gin.Context) {
 		Count    int64  `json:"count"`
 	}
 	deviceQuery := database.DB.Model(&models.HJ212Data{}).
+		Scopes(middleware.DeviceScope(c, "device_id")).
 		Select("device_id, COUNT(*) as count").
 		Where("received_at >= ? AND received_at <= ?", *query.StartTime, *query.EndTime).
 		Group("device_id")
@@ -240,7 +249,7 @@ func (h *HJ212Handler) GetStats(c *gin.Context) {
 		"data_type_stats":  dataTypeStats,
 		"device_stats":     deviceStats,
 		"trend_stats":      trendStats,
-		"connected_devices": h.server.GetConnectedDevices(),
+		"connected_devices": middleware.FilterDevices(c, h.server.GetConnectedDevices()),
 		"time_range": map[string]interface{}{
 			"start_time": query.StartTime,
 			"end_time":   query.EndTime,
@@ -260,7 +269,7 @@ func (h *HJ212Handler) GetStats(c *gin.Context) {
 // @Success 200 {object} models.Response{data=[]string} "获取成功"
 // @Router /api/v1/hj212/devices [get]
 func (h *HJ212Handler) GetConnectedDevices(c *gin.Context) {
-	devices := h.server.GetConnectedDevices()
+	devices := middleware.FilterDevices(c, h.server.GetConnectedDevices())
 	c.JSON(http.StatusOK, models.SuccessResponse(devices))
 }
 
@@ -279,7 +288,12 @@ func (h *HJ212Handler) GetConnectedDevices(c *gin.Context) {
 func (h *HJ212Handler) SendCommand(c *gin.Context) {
 	var req SendCommandRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "请求参数错误"))
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
+		return
+	}
+
+	if !middleware.DeviceAllowed(c, req.DeviceID) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse(http.StatusForbidden, "无权操作该设备"))
 		return
 	}
 
@@ -299,6 +313,91 @@ func (h *HJ212Handler) SendCommand(c *gin.Context) {
 	c.JSON(http.StatusOK, models.SuccessResponse(nil))
 }
 
+// ControlDevice 向在线设备下发控制命令并等待执行结果
+// @Summary 下发控制命令
+// @Description 向在线HJ212设备下发设置时间、采样、校零校标、设置上下限等命令，等待设备返回执行结果(CN 9012)
+// @Tags HJ212数据
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param mn path string true "设备MN"
+// @Param request body DeviceControlRequest true "控制命令"
+// @Success 200 {object} models.Response{data=hj212.CommandResult} "执行成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Failure 404 {object} models.Response "设备未连接"
+// @Failure 502 {object} models.Response{data=hj212.CommandResult} "设备拒绝或执行失败"
+// @Failure 504 {object} models.Response{data=hj212.CommandResult} "等待设备应答超时"
+// @Router /api/v1/hj212/devices/{mn}/control [post]
+func (h *HJ212Handler) ControlDevice(c *gin.Context) {
+	mn := c.Param("mn")
+	var req DeviceControlRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
+		return
+	}
+
+	if !middleware.DeviceAllowed(c, mn) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse(http.StatusForbidden, "无权操作该设备"))
+		return
+	}
+
+	control := &hj212.ControlRequest{
+		Type:       req.Type,
+		SystemTime: req.SystemTime,
+		PolID:      req.PolID,
+		Interval:   req.Interval,
+		Limits:     req.Limits,
+	}
+	if _, _, err := hj212.BuildControlCP(control, time.Now()); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "控制命令参数错误: "+err.Error()))
+		return
+	}
+	if _, _, ok := h.server.DeviceHeader(mn); !ok {
+		c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "设备未连接"))
+		return
+	}
+
+	timeout := defaultControlTimeout
+	if req.Timeout > 0 {
+		timeout = time.Duration(req.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
+	result, err := hj212.ExecuteControl(ctx, h.server, mn, control)
+	if err != nil {
+		h.logger.Warn("Device control failed",
+			zap.String("device_id", mn),
+			zap.String("type", req.Type),
+			zap.Error(err))
+		status, message := http.StatusBadGateway, "命令下发失败"
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			status, message = http.StatusGatewayTimeout, "等待设备应答超时"
+		case result != nil:
+			message = "设备拒绝执行该命令"
+		}
+		resp := models.ErrorResponse(status, message)
+		if result != nil {
+			resp.Data = result
+		}
+		c.JSON(status, resp)
+		return
+	}
+
+	h.logger.Info("Device control executed",
+		zap.String("device_id", mn),
+		zap.String("type", req.Type),
+		zap.String("exe_rtn", result.ExeRtn))
+	if !result.Succeeded() {
+		resp := models.ErrorResponse(http.StatusBadGateway, "设备执行失败: "+result.RtnInfo)
+		resp.Data = result
+		c.JSON(http.StatusBadGateway, resp)
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse(result))
+}
+
 // GetDataDetail 获取数据详情
 // @Summary 获取HJ212数据详情
 // @Description 根据ID获取HJ212数据的详细信息
@@ -317,7 +416,8 @@ func (h *HJ212Handler) GetDataDetail(c *gin.Context) {
 	}
 
 	var data models.HJ212Data
-	if err := database.DB.Where("id = ?", id).First(&data).Error; err != nil {
+	if err := database.DB.Scopes(middleware.DeviceScope(c, "device_id")).
+		Where("id = ?", id).First(&data).Error; err != nil {
 		h.logger.Error("Failed to get HJ212 data detail", zap.Error(err))
 		c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "数据不存在"))
 		return
@@ -350,21 +450,12 @@ func (h *HJ212Handler) GetAlarmData(c *gin.Context) {
 		EndTime   *time.Time `form:"end_time" time_format:"2006-01-02 15:04:05"`
 	}
 
-	if err := c.ShouldBindQuery(&query); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "查询参数错误"))
+	if !bindPageQuery(c, &query) {
 		return
 	}
 
-	// 设置默认分页参数
-	if query.Page <= 0 {
-		query.Page = 1
-	}
-	if query.PageSize <= 0 {
-		query.PageSize = 10
-	}
-
 	// 构建查询
-	db := database.DB.Model(&models.HJ212AlarmData{})
+	db := database.DB.Model(&models.HJ212AlarmData{}).Scopes(middleware.DeviceScope(c, "device_id"))
 
 	// 应用筛选条件
 	if query.DeviceID != nil && *query.DeviceID != "" {
@@ -390,7 +481,7 @@ func (h *HJ212Handler) GetAlarmData(c *gin.Context) {
 
 	// 分页查询
 	var alarms []models.HJ212AlarmData
-	offset := (query.Page - 1) * query.PageSize
+	offset := query.Offset()
 	if err := db.Offset(offset).Limit(query.PageSize).Order("received_at DESC").Find(&alarms).Error; err != nil {
 		h.logger.Error("Failed to query alarm data", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
@@ -399,5 +490,77 @@ func (h *HJ212Handler) GetAlarmData(c *gin.Context) {
 
 	result := models.NewPageResponse(alarms, total, query.Page, query.PageSize)
 
+	c.JSON(http.StatusOK, models.SuccessResponse(result))
+}
+
+// ListDeadLetters 查询解析或校验失败的原始报文
+// @Summary 查询HJ212死信报文
+// @Description 分页查询解析或校验失败的HJ212原始报文，包含失败原因和CRC对比
+// @Tags HJ212数据
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页大小" default(10)
+// @Param device_id query string false "设备ID"
+// @Param remote_addr query string false "来源地址"
+// @Param stage query string false "失败阶段" Enums(parse,validate)
+// @Param start_time query string false "开始时间" format(date-time)
+// @Param end_time query string false "结束时间" format(date-time)
+// @Success 200 {object} models.Response{data=models.PaginatedList{items=[]models.HJ212DeadLetter}} "查询成功"
+// @Router /api/v1/hj212/dead-letters [get]
+func (h *HJ212Handler) ListDeadLetters(c *gin.Context) {
+	var query struct {
+		models.PaginationQuery
+		DeviceID   *string    `form:"device_id"`
+		RemoteAddr *string    `form:"remote_addr"`
+		Stage      *string    `form:"stage"`
+		StartTime  *time.Time `form:"start_time" time_format:"2006-01-02 15:04:05"`
+		EndTime    *time.Time `form:"end_time" time_format:"2006-01-02 15:04:05"`
+	}
+
+	if !bindPageQuery(c, &query) {
+		return
+	}
+
+	// 构建查询，受设备数据权限限制的用户看不到无法识别设备的报文
+	db := database.DB.Model(&models.HJ212DeadLetter{}).Scopes(middleware.DeviceScope(c, "device_id"))
+
+	// 应用筛选条件
+	if query.DeviceID != nil && *query.DeviceID != "" {
+		db = db.Where("device_id = ?", *query.DeviceID)
+	}
+	if query.RemoteAddr != nil && *query.RemoteAddr != "" {
+		db = db.Where("remote_addr LIKE ?", *query.RemoteAddr+"%")
+	}
+	if query.Stage != nil && *query.Stage != "" {
+		db = db.Where("stage = ?", *query.Stage)
+	}
+	if query.StartTime != nil {
+		db = db.Where("received_at >= ?", *query.StartTime)
+	}
+	if query.EndTime != nil {
+		db = db.Where("received_at <= ?", *query.EndTime)
+	}
+
+	// 获取总数
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		h.logger.Error("Failed to count HJ212 dead letters", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return
+	}
+
+	// 分页查询
+	var letters []models.HJ212DeadLetter
+	offset := query.Offset()
+	if err := db.Offset(offset).Limit(query.PageSize).Order("received_at DESC").Find(&letters).Error; err != nil {
+		h.logger.Error("Failed to query HJ212 dead letters", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return
+	}
+
+	result := models.NewPageResponse(letters, total, query.Page, query.PageSize)
+
 	c.JSON(http.StatusOK, models.SuccessResponse(result))
 }
\ No newline at end of file
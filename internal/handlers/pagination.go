@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/env-data-platform/internal/models"
+)
+
+// pageQuery 嵌入models.PageRequest的列表查询参数
+type pageQuery interface {
+	Pagination() *models.PageRequest
+}
+
+// bindPageQuery 绑定列表查询参数并补全分页默认值，page/page_size不传时取第1页、每页10条。
+// 绑定失败时已响应400，调用方直接返回
+func bindPageQuery(c *gin.Context, query pageQuery) bool {
+	if err := c.ShouldBindQuery(query); err != nil {
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, query))
+		return false
+	}
+	query.Pagination().Normalize()
+	return true
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/env-data-platform/internal/models"
+)
+
+type pageTestQuery struct {
+	models.PageRequest
+	Status string `form:"status"`
+}
+
+// bindPage 以给定查询串调用bindPageQuery
+func bindPage(t *testing.T, rawQuery string) (*pageTestQuery, *httptest.ResponseRecorder, bool) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/?"+rawQuery, nil)
+	var query pageTestQuery
+	ok := bindPageQuery(c, &query)
+	return &query, w, ok
+}
+
+func TestBindPageQuery(t *testing.T) {
+	// 不传分页参数时取默认值
+	query, _, ok := bindPage(t, "status=active")
+	require.True(t, ok)
+	assert.Equal(t, models.DefaultPage, query.Page)
+	assert.Equal(t, models.DefaultPageSize, query.PageSize)
+	assert.Equal(t, 0, query.Offset())
+	assert.Equal(t, "active", query.Status)
+
+	query, _, ok = bindPage(t, "page=3&page_size=20&keyword=abc")
+	require.True(t, ok)
+	assert.Equal(t, 40, query.Offset())
+	assert.Equal(t, 20, query.PageSize)
+	assert.Equal(t, "abc", query.Keyword)
+
+	// 显式传入的非法值仍然报错，字段路径不含嵌入的结构体名
+	_, w, ok := bindPage(t, "page=0&page_size=500")
+	require.False(t, ok)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var resp struct {
+		Data ValidationErrorData `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Data.Errors, 1)
+	assert.Equal(t, "page_size", resp.Data.Errors[0].Field)
+	assert.Equal(t, "每页数量", resp.Data.Errors[0].Label)
+}
+
+func TestPageRequestNormalize(t *testing.T) {
+	p := models.PageRequest{Page: -1, PageSize: 1000}
+	p.Normalize()
+	assert.Equal(t, models.DefaultPage, p.Page)
+	assert.Equal(t, models.MaxPageSize, p.PageSize)
+}
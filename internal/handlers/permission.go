@@ -6,6 +6,7 @@ import (
 
 	"github.com/env-data-platform/internal/database"
 	"github.com/env-data-platform/internal/models"
+	"github.com/env-data-platform/internal/services"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
@@ -13,23 +14,24 @@ import (
 
 // PermissionHandler 权限处理器
 type PermissionHandler struct {
-	db     *gorm.DB
-	logger *zap.Logger
+	db                *gorm.DB
+	logger            *zap.Logger
+	permissionService *services.PermissionService
 }
 
 // NewPermissionHandler 创建权限处理器
 func NewPermissionHandler(logger *zap.Logger) *PermissionHandler {
 	return &PermissionHandler{
-		db:     database.GetDB(),
-		logger: logger,
+		db:                database.GetDB(),
+		logger:            logger,
+		permissionService: services.NewPermissionService(),
 	}
 }
 
 // ListPermissions 获取权限列表
 func (h *PermissionHandler) ListPermissions(c *gin.Context) {
 	var req struct {
-		Page     int    `form:"page"`
-		PageSize int    `form:"page_size"`
+		models.PageRequest
 		Name     string `form:"name"`
 		Code     string `form:"code"`
 		Type     string `form:"type"`
@@ -38,8 +40,7 @@ func (h *PermissionHandler) ListPermissions(c *gin.Context) {
 		Tree     bool   `form:"tree"` // 是否返回树形结构
 	}
 
-	if err := c.ShouldBindQuery(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "参数错误"))
+	if !bindPageQuery(c, &req) {
 		return
 	}
 
@@ -71,24 +72,17 @@ func (h *PermissionHandler) ListPermissions(c *gin.Context) {
 		}
 
 		// 构建树形结构
-		tree := h.buildPermissionTree(permissions, nil)
+		tree := services.BuildPermissionTree(permissions)
 		c.JSON(http.StatusOK, models.SuccessResponse(tree))
 		return
 	}
 
 	// 分页查询
-	if req.Page <= 0 {
-		req.Page = 1
-	}
-	if req.PageSize <= 0 {
-		req.PageSize = 20
-	}
-
 	var total int64
 	query.Count(&total)
 
 	var permissions []models.Permission
-	offset := (req.Page - 1) * req.PageSize
+	offset := req.Offset()
 	if err := query.Offset(offset).Limit(req.PageSize).
 		Preload("Parent").
 		Order("sort ASC, id ASC").
@@ -106,25 +100,6 @@ func (h *PermissionHandler) ListPermissions(c *gin.Context) {
 	}))
 }
 
-// buildPermissionTree 构建权限树
-func (h *PermissionHandler) buildPermissionTree(permissions []models.Permission, parentID *uint) []models.Permission {
-	var tree []models.Permission
-
-	for _, permission := range permissions {
-		if (parentID == nil && permission.ParentID == nil) ||
-			(parentID != nil && permission.ParentID != nil && *permission.ParentID == *parentID) {
-
-			children := h.buildPermissionTree(permissions, &permission.ID)
-			if len(children) > 0 {
-				permission.Children = children
-			}
-			tree = append(tree, permission)
-		}
-	}
-
-	return tree
-}
-
 // CreatePermission 创建权限
 func (h *PermissionHandler) CreatePermission(c *gin.Context) {
 	var req struct {
@@ -142,14 +117,14 @@ func (h *PermissionHandler) CreatePermission(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "参数错误"))
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
 		return
 	}
 
 	// 检查权限名称和代码是否已存在
 	var existingPermission models.Permission
 	if err := h.db.Where("name = ? OR code = ?", req.Name, req.Code).First(&existingPermission).Error; err == nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "权限名称或代码已存在"))
+		c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodePermissionExists, ""))
 		return
 	}
 
@@ -158,7 +133,7 @@ func (h *PermissionHandler) CreatePermission(c *gin.Context) {
 		var parentPermission models.Permission
 		if err := h.db.First(&parentPermission, *req.ParentID).Error; err != nil {
 			if err == gorm.ErrRecordNotFound {
-				c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "父权限不存在"))
+				c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeParentPermissionNotFound, ""))
 				return
 			}
 			h.logger.Error("Failed to check parent permission", zap.Error(err))
@@ -205,7 +180,7 @@ func (h *PermissionHandler) GetPermission(c *gin.Context) {
 	if err := h.db.Preload("Parent").Preload("Children").
 		First(&permission, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "权限不存在"))
+			c.JSON(http.StatusNotFound, models.CodeErrorResponse(models.CodePermissionNotFound, ""))
 			return
 		}
 		h.logger.Error("Failed to get permission", zap.Error(err))
@@ -239,14 +214,14 @@ func (h *PermissionHandler) UpdatePermission(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "参数错误"))
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
 		return
 	}
 
 	var permission models.Permission
 	if err := h.db.First(&permission, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "权限不存在"))
+			c.JSON(http.StatusNotFound, models.CodeErrorResponse(models.CodePermissionNotFound, ""))
 			return
 		}
 		h.logger.Error("Failed to get permission", zap.Error(err))
@@ -256,28 +231,28 @@ func (h *PermissionHandler) UpdatePermission(c *gin.Context) {
 
 	// 检查系统权限
 	if permission.IsSystem {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "系统权限不允许修改"))
+		c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeSystemPermissionReadonly, ""))
 		return
 	}
 
 	// 检查权限名称和代码是否被其他权限使用
 	var existingPermission models.Permission
 	if err := h.db.Where("(name = ? OR code = ?) AND id != ?", req.Name, req.Code, id).First(&existingPermission).Error; err == nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "权限名称或代码已存在"))
+		c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodePermissionExists, ""))
 		return
 	}
 
 	// 如果有父权限，检查父权限是否存在，并且不能是自己或自己的子权限
 	if req.ParentID != nil {
 		if *req.ParentID == uint(id) {
-			c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "不能设置自己为父权限"))
+			c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeInvalidParentPermission, "不能设置自己为父权限"))
 			return
 		}
 
 		var parentPermission models.Permission
 		if err := h.db.First(&parentPermission, *req.ParentID).Error; err != nil {
 			if err == gorm.ErrRecordNotFound {
-				c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "父权限不存在"))
+				c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeParentPermissionNotFound, ""))
 				return
 			}
 			h.logger.Error("Failed to check parent permission", zap.Error(err))
@@ -286,8 +261,14 @@ func (h *PermissionHandler) UpdatePermission(c *gin.Context) {
 		}
 
 		// 检查是否形成循环引用
-		if h.wouldCreateCycle(uint(id), *req.ParentID) {
-			c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "不能设置子权限为父权限"))
+		cycle, err := h.permissionService.WouldCreateCycle(uint(id), *req.ParentID)
+		if err != nil {
+			h.logger.Error("Failed to check permission hierarchy", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "父权限检查失败"))
+			return
+		}
+		if cycle {
+			c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeInvalidParentPermission, "不能设置子权限为父权限"))
 			return
 		}
 	}
@@ -319,35 +300,6 @@ func (h *PermissionHandler) UpdatePermission(c *gin.Context) {
 	c.JSON(http.StatusOK, models.SuccessResponse(permission))
 }
 
-// wouldCreateCycle 检查是否会形成循环引用
-func (h *PermissionHandler) wouldCreateCycle(permissionID, parentID uint) bool {
-	var permission models.Permission
-	if err := h.db.Preload("Children").First(&permission, permissionID).Error; err != nil {
-		return false
-	}
-
-	// 递归检查所有子权限
-	return h.hasChildPermission(permission.Children, parentID)
-}
-
-// hasChildPermission 检查是否包含指定的子权限
-func (h *PermissionHandler) hasChildPermission(children []models.Permission, targetID uint) bool {
-	for _, child := range children {
-		if child.ID == targetID {
-			return true
-		}
-
-		// 加载子权限的子权限
-		var childWithChildren models.Permission
-		if err := h.db.Preload("Children").First(&childWithChildren, child.ID).Error; err == nil {
-			if h.hasChildPermission(childWithChildren.Children, targetID) {
-				return true
-			}
-		}
-	}
-	return false
-}
-
 // DeletePermission 删除权限
 func (h *PermissionHandler) DeletePermission(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
@@ -359,7 +311,7 @@ func (h *PermissionHandler) DeletePermission(c *gin.Context) {
 	var permission models.Permission
 	if err := h.db.First(&permission, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "权限不存在"))
+			c.JSON(http.StatusNotFound, models.CodeErrorResponse(models.CodePermissionNotFound, ""))
 			return
 		}
 		h.logger.Error("Failed to get permission", zap.Error(err))
@@ -369,7 +321,7 @@ func (h *PermissionHandler) DeletePermission(c *gin.Context) {
 
 	// 检查系统权限
 	if permission.IsSystem {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "系统权限不允许删除"))
+		c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeSystemPermissionReadonly, "系统权限不允许删除"))
 		return
 	}
 
@@ -377,7 +329,7 @@ func (h *PermissionHandler) DeletePermission(c *gin.Context) {
 	var childCount int64
 	h.db.Model(&models.Permission{}).Where("parent_id = ?", id).Count(&childCount)
 	if childCount > 0 {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "该权限下还有子权限，请先删除子权限"))
+		c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodePermissionHasChildren, ""))
 		return
 	}
 
@@ -385,7 +337,7 @@ func (h *PermissionHandler) DeletePermission(c *gin.Context) {
 	var roleCount int64
 	h.db.Model(&models.RolePermission{}).Where("permission_id = ?", id).Count(&roleCount)
 	if roleCount > 0 {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "该权限被角色使用，无法删除"))
+		c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodePermissionInUse, ""))
 		return
 	}
 
@@ -413,29 +365,32 @@ func (h *PermissionHandler) GetPermissionTypes(c *gin.Context) {
 func (h *PermissionHandler) GetUserPermissions(c *gin.Context) {
 	userID := c.GetUint("user_id")
 	if userID == 0 {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse(http.StatusUnauthorized, "用户未登录"))
+		c.JSON(http.StatusUnauthorized, models.CodeErrorResponse(models.CodeNotLoggedIn, ""))
 		return
 	}
 
 	// 通过用户的角色获取权限
-	var permissions []models.Permission
-	if err := h.db.Table("env_permissions").
-		Joins("JOIN env_role_permissions ON env_permissions.id = env_role_permissions.permission_id").
-		Joins("JOIN env_user_roles ON env_role_permissions.role_id = env_user_roles.role_id").
-		Where("env_user_roles.user_id = ? AND env_permissions.status = 1", userID).
-		Group("env_permissions.id").
-		Order("env_permissions.sort ASC, env_permissions.id ASC").
-		Find(&permissions).Error; err != nil {
+	permissions, err := h.permissionService.GetUserPermissions(userID, "")
+	if err != nil {
 		h.logger.Error("Failed to get user permissions", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
 		return
 	}
 
+	// 权限code列表，可直接用于 auth.HasPermission 匹配（超级管理员为"*"）
+	codes, err := h.permissionService.GetUserPermissionCodes(userID)
+	if err != nil {
+		h.logger.Error("Failed to get user permission codes", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return
+	}
+
 	// 构建权限树
-	tree := h.buildPermissionTree(permissions, nil)
+	tree := services.BuildPermissionTree(permissions)
 
 	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{
 		"permissions": permissions,
+		"codes":       codes,
 		"tree":        tree,
 	}))
 }
@@ -444,26 +399,20 @@ func (h *PermissionHandler) GetUserPermissions(c *gin.Context) {
 func (h *PermissionHandler) GetUserMenus(c *gin.Context) {
 	userID := c.GetUint("user_id")
 	if userID == 0 {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse(http.StatusUnauthorized, "用户未登录"))
+		c.JSON(http.StatusUnauthorized, models.CodeErrorResponse(models.CodeNotLoggedIn, ""))
 		return
 	}
 
 	// 获取用户的菜单权限
-	var permissions []models.Permission
-	if err := h.db.Table("env_permissions").
-		Joins("JOIN env_role_permissions ON env_permissions.id = env_role_permissions.permission_id").
-		Joins("JOIN env_user_roles ON env_role_permissions.role_id = env_user_roles.role_id").
-		Where("env_user_roles.user_id = ? AND env_permissions.status = 1 AND env_permissions.type = ?", userID, "menu").
-		Group("env_permissions.id").
-		Order("env_permissions.sort ASC, env_permissions.id ASC").
-		Find(&permissions).Error; err != nil {
+	permissions, err := h.permissionService.GetUserPermissions(userID, "menu")
+	if err != nil {
 		h.logger.Error("Failed to get user menus", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
 		return
 	}
 
 	// 构建菜单树
-	menuTree := h.buildPermissionTree(permissions, nil)
+	menuTree := services.BuildPermissionTree(permissions)
 
 	c.JSON(http.StatusOK, models.SuccessResponse(menuTree))
 }
\ No newline at end of file
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/env-data-platform/internal/database"
 	"github.com/env-data-platform/internal/models"
@@ -16,25 +17,52 @@ import (
 
 // QualityHandler 数据质量处理器
 type QualityHandler struct {
-	db      *gorm.DB
-	logger  *zap.Logger
-	checker *services.QualityChecker
+	db        *gorm.DB
+	logger    *zap.Logger
+	checker   *services.QualityChecker
+	scheduler *services.QualityScheduler
+	trend     *services.QualityTrendService
+	score     *services.QualityScoreService
+	batch     *services.QualityBatchRunner
 }
 
-// NewQualityHandler 创建数据质量处理器
-func NewQualityHandler(logger *zap.Logger) *QualityHandler {
+// NewQualityHandler 创建数据质量处理器，notifier用于定时检查失败或低分时告警
+func NewQualityHandler(logger *zap.Logger, notifier services.QualityAlarmNotifier) *QualityHandler {
+	checker := services.NewQualityChecker(logger)
+	scheduler := services.NewQualityScheduler(logger, checker)
+	if notifier != nil {
+		scheduler.SetAlarmNotifier(notifier)
+	}
+
 	return &QualityHandler{
-		db:      database.GetDB(),
-		logger:  logger,
-		checker: services.NewQualityChecker(logger),
+		db:        database.GetDB(),
+		logger:    logger,
+		checker:   checker,
+		scheduler: scheduler,
+		trend:     services.NewQualityTrendService(),
+		score:     services.NewQualityScoreService(),
+		batch:     services.NewQualityBatchRunner(logger, checker),
 	}
 }
 
+// qualityRuleListQuery 质量规则列表的关键字搜索列和可排序字段
+var qualityRuleListQuery = database.ListQuery{
+	SearchColumns: []string{"name", "description", "type", "target_table", "column_name"},
+	SortColumns: map[string]string{
+		"created_at":    "created_at",
+		"updated_at":    "updated_at",
+		"last_check_at": "last_check_at",
+		"name":          "name",
+		"priority":      "priority",
+		"weight":        "weight",
+	},
+	DefaultSort: "created_at:desc",
+}
+
 // ListQualityRules 获取数据质量规则列表
 func (h *QualityHandler) ListQualityRules(c *gin.Context) {
 	var req struct {
-		Page         int    `form:"page" binding:"required,min=1"`
-		PageSize     int    `form:"page_size" binding:"required,min=1,max=100"`
+		models.PageRequest
 		Name         string `form:"name"`
 		Type         string `form:"type"`
 		DataSourceID uint   `form:"data_source_id"`
@@ -42,12 +70,17 @@ func (h *QualityHandler) ListQualityRules(c *gin.Context) {
 		IsEnabled    *bool  `form:"is_enabled"`
 	}
 
-	if err := c.ShouldBindQuery(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "参数错误"))
+	if !bindPageQuery(c, &req) {
 		return
 	}
 
-	query := h.db.Model(&models.QualityRule{})
+	sortScope, err := qualityRuleListQuery.Sort(req.Sort, req.Order)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	query := h.db.Model(&models.QualityRule{}).Scopes(qualityRuleListQuery.Search(req.Keyword))
 
 	if req.Name != "" {
 		query = query.Where("name LIKE ?", "%"+req.Name+"%")
@@ -69,10 +102,10 @@ func (h *QualityHandler) ListQualityRules(c *gin.Context) {
 	query.Count(&total)
 
 	var rules []models.QualityRule
-	offset := (req.Page - 1) * req.PageSize
+	offset := req.Offset()
 	if err := query.Offset(offset).Limit(req.PageSize).
 		Preload("DataSource").Preload("ETLJob").Preload("Creator").
-		Order("created_at DESC").
+		Scopes(sortScope).
 		Find(&rules).Error; err != nil {
 		h.logger.Error("Failed to list quality rules", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
@@ -92,7 +125,7 @@ func (h *QualityHandler) CreateQualityRule(c *gin.Context) {
 	var req struct {
 		Name         string                 `json:"name" binding:"required,min=1,max=100"`
 		Description  string                 `json:"description"`
-		Type         string                 `json:"type" binding:"required,oneof=completeness uniqueness validity consistency accuracy freshness"`
+		Type         string                 `json:"type" binding:"required,oneof=completeness uniqueness validity consistency accuracy freshness outlier referential"`
 		DataSourceID uint                   `json:"data_source_id"`
 		ETLJobID     uint                   `json:"etl_job_id"`
 		TargetTable  string                 `json:"target_table"`
@@ -101,11 +134,12 @@ func (h *QualityHandler) CreateQualityRule(c *gin.Context) {
 		Threshold    float64                `json:"threshold" binding:"min=0,max=100"`
 		IsEnabled    bool                   `json:"is_enabled"`
 		Priority     int                    `json:"priority"`
+		Weight       *float64               `json:"weight" binding:"omitempty,gt=0,max=100"`
 		AlertLevel   string                 `json:"alert_level" binding:"required,oneof=info warning critical fatal"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "参数错误"))
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
 		return
 	}
 
@@ -153,8 +187,12 @@ func (h *QualityHandler) CreateQualityRule(c *gin.Context) {
 		Threshold:    req.Threshold,
 		IsEnabled:    req.IsEnabled,
 		Priority:     req.Priority,
+		Weight:       1,
 		AlertLevel:   req.AlertLevel,
 	}
+	if req.Weight != nil {
+		rule.Weight = *req.Weight
+	}
 	rule.CreatedBy = userID
 	rule.UpdatedBy = userID
 
@@ -201,7 +239,7 @@ func (h *QualityHandler) UpdateQualityRule(c *gin.Context) {
 	var req struct {
 		Name         string                 `json:"name" binding:"required,min=1,max=100"`
 		Description  string                 `json:"description"`
-		Type         string                 `json:"type" binding:"required,oneof=completeness uniqueness validity consistency accuracy freshness"`
+		Type         string                 `json:"type" binding:"required,oneof=completeness uniqueness validity consistency accuracy freshness outlier referential"`
 		DataSourceID uint                   `json:"data_source_id"`
 		ETLJobID     uint                   `json:"etl_job_id"`
 		TargetTable  string                 `json:"target_table"`
@@ -210,11 +248,12 @@ func (h *QualityHandler) UpdateQualityRule(c *gin.Context) {
 		Threshold    float64                `json:"threshold" binding:"min=0,max=100"`
 		IsEnabled    bool                   `json:"is_enabled"`
 		Priority     int                    `json:"priority"`
+		Weight       *float64               `json:"weight" binding:"omitempty,gt=0,max=100"`
 		AlertLevel   string                 `json:"alert_level" binding:"required,oneof=info warning critical fatal"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "参数错误"))
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
 		return
 	}
 
@@ -273,6 +312,9 @@ func (h *QualityHandler) UpdateQualityRule(c *gin.Context) {
 		"alert_level":    req.AlertLevel,
 		"updated_by":     c.GetUint("user_id"),
 	}
+	if req.Weight != nil {
+		updates["weight"] = *req.Weight
+	}
 
 	if err := h.db.Model(&rule).Updates(updates).Error; err != nil {
 		h.logger.Error("Failed to update quality rule", zap.Error(err))
@@ -280,6 +322,14 @@ func (h *QualityHandler) UpdateQualityRule(c *gin.Context) {
 		return
 	}
 
+	// 规则停用时摘除定时检查，重新启用时按已有调度恢复
+	h.scheduler.UnscheduleRule(rule.ID)
+	if req.IsEnabled && rule.ScheduleEnabled && rule.CronExpr != "" {
+		if err := h.scheduler.ScheduleRule(&rule); err != nil {
+			h.logger.Warn("Failed to reschedule quality rule", zap.Error(err), zap.Uint("rule_id", rule.ID))
+		}
+	}
+
 	c.JSON(http.StatusOK, models.SuccessResponse(rule))
 }
 
@@ -310,6 +360,8 @@ func (h *QualityHandler) DeleteQualityRule(c *gin.Context) {
 		return
 	}
 
+	h.scheduler.UnscheduleRule(rule.ID)
+
 	if err := h.db.Delete(&rule).Error; err != nil {
 		h.logger.Error("Failed to delete quality rule", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "删除失败"))
@@ -319,6 +371,224 @@ func (h *QualityHandler) DeleteQualityRule(c *gin.Context) {
 	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{"message": "删除成功"}))
 }
 
+// EnableRuleSchedule 启用规则的定时检查
+func (h *QualityHandler) EnableRuleSchedule(c *gin.Context) {
+	rule, ok := h.findRule(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		CronExpr string `json:"cron_expr" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
+		return
+	}
+	if _, err := validateCronExpr(req.CronExpr); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	if err := h.db.Model(rule).Updates(map[string]interface{}{
+		"cron_expr":        req.CronExpr,
+		"schedule_enabled": true,
+		"updated_by":       c.GetUint("user_id"),
+	}).Error; err != nil {
+		h.logger.Error("Failed to enable quality rule schedule", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "更新失败"))
+		return
+	}
+
+	// 停用的规则只保存调度配置，启用规则时再挂载
+	if rule.IsEnabled {
+		if err := h.scheduler.ScheduleRule(rule); err != nil {
+			h.logger.Error("Failed to schedule quality rule", zap.Error(err), zap.Uint("rule_id", rule.ID))
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "调度失败"))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(rule))
+}
+
+// DisableRuleSchedule 停用规则的定时检查，保留cron表达式
+func (h *QualityHandler) DisableRuleSchedule(c *gin.Context) {
+	rule, ok := h.findRule(c)
+	if !ok {
+		return
+	}
+
+	h.scheduler.UnscheduleRule(rule.ID)
+
+	if err := h.db.Model(rule).Updates(map[string]interface{}{
+		"schedule_enabled": false,
+		"next_check_at":    nil,
+		"updated_by":       c.GetUint("user_id"),
+	}).Error; err != nil {
+		h.logger.Error("Failed to disable quality rule schedule", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "更新失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(rule))
+}
+
+// GetRuleSchedule 获取规则的调度状态和下次检查时间
+func (h *QualityHandler) GetRuleSchedule(c *gin.Context) {
+	rule, ok := h.findRule(c)
+	if !ok {
+		return
+	}
+
+	status := h.scheduler.GetRuleStatus(rule.ID)
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{
+		"rule_id":          rule.ID,
+		"cron_expr":        rule.CronExpr,
+		"schedule_enabled": rule.ScheduleEnabled,
+		"is_scheduled":     status.IsScheduled,
+		"next_check_at":    rule.NextCheckAt,
+		"last_check_at":    rule.LastCheckAt,
+	}))
+}
+
+// GetRuleTrend 获取单条规则的质量分数趋势
+func (h *QualityHandler) GetRuleTrend(c *gin.Context) {
+	rule, ok := h.findRule(c)
+	if !ok {
+		return
+	}
+	q, ok := parseTrendQuery(c)
+	if !ok {
+		return
+	}
+
+	series, err := h.trend.RuleTrend(rule, q)
+	if err != nil {
+		h.logger.Error("Failed to query quality rule trend", zap.Error(err), zap.Uint("rule_id", rule.ID))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(series))
+}
+
+// GetOverallTrend 获取按数据源或规则类型聚合的整体质量趋势
+func (h *QualityHandler) GetOverallTrend(c *gin.Context) {
+	q, ok := parseTrendQuery(c)
+	if !ok {
+		return
+	}
+
+	groupBy := c.Query("group_by")
+	if groupBy != "" && groupBy != services.TrendGroupDataSource && groupBy != services.TrendGroupType {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "group_by仅支持data_source或type"))
+		return
+	}
+
+	series, err := h.trend.OverallTrend(groupBy, q)
+	if err != nil {
+		h.logger.Error("Failed to query overall quality trend", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{
+		"group_by": groupBy,
+		"interval": q.Interval,
+		"series":   series,
+	}))
+}
+
+// ListDecliningRules 获取分数持续下降的规则
+func (h *QualityHandler) ListDecliningRules(c *gin.Context) {
+	q, ok := parseTrendQuery(c)
+	if !ok {
+		return
+	}
+
+	series, err := h.trend.DecliningRules(q)
+	if err != nil {
+		h.logger.Error("Failed to query declining quality rules", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{
+		"list":  series,
+		"total": len(series),
+	}))
+}
+
+// parseTrendQuery 解析趋势查询参数，默认按天统计最近30天，连续下降3个时间桶视为持续下降
+func parseTrendQuery(c *gin.Context) (services.TrendQuery, bool) {
+	var req struct {
+		StartDate string `form:"start_date"`
+		EndDate   string `form:"end_date"`
+		Interval  string `form:"interval" binding:"omitempty,oneof=hour day"`
+		MinDrops  int    `form:"min_drops" binding:"omitempty,min=1,max=30"`
+	}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
+		return services.TrendQuery{}, false
+	}
+
+	q := services.TrendQuery{Interval: req.Interval, MinDrops: req.MinDrops}
+	if q.Interval == "" {
+		q.Interval = services.TrendIntervalDay
+	}
+	if q.MinDrops == 0 {
+		q.MinDrops = 3
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	q.End = today.AddDate(0, 0, 1)
+	q.Start = today.AddDate(0, 0, -29)
+	if req.EndDate != "" {
+		end, err := time.ParseInLocation("2006-01-02", req.EndDate, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "结束日期格式错误"))
+			return q, false
+		}
+		q.End = end.AddDate(0, 0, 1)
+	}
+	if req.StartDate != "" {
+		start, err := time.ParseInLocation("2006-01-02", req.StartDate, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "开始日期格式错误"))
+			return q, false
+		}
+		q.Start = start
+	}
+	if !q.Start.Before(q.End) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "开始日期不能晚于结束日期"))
+		return q, false
+	}
+	return q, true
+}
+
+// findRule 根据路径参数查找质量规则
+func (h *QualityHandler) findRule(c *gin.Context) (*models.QualityRule, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "无效的ID"))
+		return nil, false
+	}
+
+	var rule models.QualityRule
+	if err := h.db.First(&rule, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "质量规则不存在"))
+			return nil, false
+		}
+		h.logger.Error("Failed to get quality rule", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return nil, false
+	}
+	return &rule, true
+}
+
 // ExecuteQualityCheck 执行数据质量检查
 func (h *QualityHandler) ExecuteQualityCheck(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
@@ -358,42 +628,49 @@ func (h *QualityHandler) ExecuteQualityCheck(c *gin.Context) {
 	}))
 }
 
+// QualityReportFilter 数据质量报告筛选条件，列表和导出共用
+type QualityReportFilter struct {
+	RuleID    uint   `form:"rule_id"`
+	Status    string `form:"status"`
+	StartDate string `form:"start_date"`
+	EndDate   string `form:"end_date"`
+}
+
+// apply 应用筛选条件
+func (f *QualityReportFilter) apply(query *gorm.DB) *gorm.DB {
+	if f.RuleID > 0 {
+		query = query.Where("rule_id = ?", f.RuleID)
+	}
+	if f.Status != "" {
+		query = query.Where("status = ?", f.Status)
+	}
+	if f.StartDate != "" {
+		query = query.Where("check_time >= ?", f.StartDate)
+	}
+	if f.EndDate != "" {
+		query = query.Where("check_time <= ?", f.EndDate)
+	}
+	return query
+}
+
 // ListQualityReports 获取数据质量报告列表
 func (h *QualityHandler) ListQualityReports(c *gin.Context) {
 	var req struct {
-		Page      int    `form:"page" binding:"required,min=1"`
-		PageSize  int    `form:"page_size" binding:"required,min=1,max=100"`
-		RuleID    uint   `form:"rule_id"`
-		Status    string `form:"status"`
-		StartDate string `form:"start_date"`
-		EndDate   string `form:"end_date"`
+		models.PageRequest
+		QualityReportFilter
 	}
 
-	if err := c.ShouldBindQuery(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "参数错误"))
+	if !bindPageQuery(c, &req) {
 		return
 	}
 
-	query := h.db.Model(&models.QualityReport{})
-
-	if req.RuleID > 0 {
-		query = query.Where("rule_id = ?", req.RuleID)
-	}
-	if req.Status != "" {
-		query = query.Where("status = ?", req.Status)
-	}
-	if req.StartDate != "" {
-		query = query.Where("check_time >= ?", req.StartDate)
-	}
-	if req.EndDate != "" {
-		query = query.Where("check_time <= ?", req.EndDate)
-	}
+	query := req.QualityReportFilter.apply(h.db.Model(&models.QualityReport{}))
 
 	var total int64
 	query.Count(&total)
 
 	var reports []models.QualityReport
-	offset := (req.Page - 1) * req.PageSize
+	offset := req.Offset()
 	if err := query.Offset(offset).Limit(req.PageSize).
 		Preload("Rule").
 		Order("check_time DESC").
@@ -411,6 +688,45 @@ func (h *QualityHandler) ListQualityReports(c *gin.Context) {
 	}))
 }
 
+// ExportQualityReports 按列表查询条件导出数据质量报告为CSV或Excel
+func (h *QualityHandler) ExportQualityReports(c *gin.Context) {
+	var filter QualityReportFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &filter))
+		return
+	}
+
+	var reports []models.QualityReport
+	streamListExport(c, h.logger, filter.apply(h.db.Model(&models.QualityReport{})).Preload("Rule"), listExport{
+		Name:    "quality_reports",
+		Headers: []string{"ID", "规则ID", "规则名称", "规则类型", "检查时间", "状态", "质量分数", "总记录数", "通过记录数", "失败记录数", "改进建议"},
+		Dest:    &reports,
+		Rows: func() [][]string {
+			rows := make([][]string, 0, len(reports))
+			for _, report := range reports {
+				var ruleName, ruleType string
+				if report.Rule != nil {
+					ruleName, ruleType = report.Rule.Name, report.Rule.Type
+				}
+				rows = append(rows, []string{
+					strconv.FormatUint(uint64(report.ID), 10),
+					strconv.FormatUint(uint64(report.RuleID), 10),
+					ruleName,
+					ruleType,
+					formatExportTime(&report.CheckTime),
+					report.Status,
+					strconv.FormatFloat(report.Score, 'f', 2, 64),
+					strconv.FormatInt(report.TotalCount, 10),
+					strconv.FormatInt(report.PassCount, 10),
+					strconv.FormatInt(report.FailCount, 10),
+					report.Suggestions,
+				})
+			}
+			return rows
+		},
+	})
+}
+
 // GetQualityReport 获取数据质量报告详情
 func (h *QualityHandler) GetQualityReport(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
@@ -430,10 +746,14 @@ func (h *QualityHandler) GetQualityReport(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
 		return
 	}
+	report.FailureSamples = services.ReportFailureSamples(report.Details)
 
 	c.JSON(http.StatusOK, models.SuccessResponse(report))
 }
 
+// qualityImpactRules 统计信息中列出的影响最大的规则数
+const qualityImpactRules = 10
+
 // GetQualityStats 获取数据质量统计信息
 func (h *QualityHandler) GetQualityStats(c *gin.Context) {
 	var stats struct {
@@ -451,6 +771,10 @@ func (h *QualityHandler) GetQualityStats(c *gin.Context) {
 			Range string `json:"range"`
 			Count int64  `json:"count"`
 		} `json:"score_distribution"`
+		// TableScores 各表按规则权重计算的综合分，从低到高排序
+		TableScores []services.TableQualityScore `json:"table_scores"`
+		// RuleImpacts 对所在表综合分影响最大的规则
+		RuleImpacts []services.RuleScore `json:"rule_impacts"`
 	}
 
 	// 总规则数
@@ -508,24 +832,44 @@ func (h *QualityHandler) GetQualityStats(c *gin.Context) {
 		})
 	}
 
+	// 加权综合分
+	tableScores, ruleImpacts, err := h.score.TableScores()
+	if err != nil {
+		h.logger.Error("Failed to compute weighted quality scores", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return
+	}
+	if len(ruleImpacts) > qualityImpactRules {
+		ruleImpacts = ruleImpacts[:qualityImpactRules]
+	}
+	stats.TableScores = tableScores
+	stats.RuleImpacts = ruleImpacts
+
 	c.JSON(http.StatusOK, models.SuccessResponse(stats))
 }
 
-// BatchExecuteQualityCheck 批量执行数据质量检查
+// BatchExecuteQualityCheck 批量执行数据质量检查，按rule_ids指定规则，
+// 或只传data_source_id执行该数据源下全部启用的规则；后台执行，返回批次ID用于查询进度
 func (h *QualityHandler) BatchExecuteQualityCheck(c *gin.Context) {
 	var req struct {
-		RuleIDs       []uint `json:"rule_ids" binding:"required,min=1"`
-		DataSourceID  uint   `json:"data_source_id"`
-		OnlyEnabled   bool   `json:"only_enabled"`
+		RuleIDs      []uint `json:"rule_ids"`
+		DataSourceID uint   `json:"data_source_id"`
+		OnlyEnabled  bool   `json:"only_enabled"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "参数错误"))
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
+		return
+	}
+	if len(req.RuleIDs) == 0 && req.DataSourceID == 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "rule_ids和data_source_id至少指定一个"))
 		return
 	}
 
-	query := h.db.Model(&models.QualityRule{}).Where("id IN ?", req.RuleIDs)
-
+	query := h.db.Model(&models.QualityRule{})
+	if len(req.RuleIDs) > 0 {
+		query = query.Where("id IN ?", req.RuleIDs)
+	}
 	if req.DataSourceID > 0 {
 		query = query.Where("data_source_id = ?", req.DataSourceID)
 	}
@@ -534,48 +878,77 @@ func (h *QualityHandler) BatchExecuteQualityCheck(c *gin.Context) {
 	}
 
 	var rules []models.QualityRule
-	if err := query.Preload("DataSource").Find(&rules).Error; err != nil {
+	if err := query.Preload("DataSource").Order("priority DESC, id").Find(&rules).Error; err != nil {
 		h.logger.Error("Failed to get quality rules", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询规则失败"))
 		return
 	}
 
-	if len(rules) == 0 {
+	// 已禁用的规则不参与执行
+	enabled := rules[:0]
+	for _, rule := range rules {
+		if rule.IsEnabled {
+			enabled = append(enabled, rule)
+		}
+	}
+	if len(enabled) == 0 {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "未找到符合条件的规则"))
 		return
 	}
 
 	// 异步执行批量检查
-	go h.executeBatchQualityCheck(rules)
+	batch, err := h.batch.Start(enabled, req.DataSourceID)
+	if err != nil {
+		h.logger.Error("Failed to start quality check batch", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "启动批量质量检查失败"))
+		return
+	}
 
 	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{
 		"message":    "批量质量检查已启动",
-		"rule_count": len(rules),
+		"batch_id":   batch.BatchID,
+		"rule_count": batch.TotalCount,
 	}))
 }
 
-// executeBatchQualityCheck 执行批量质量检查
-func (h *QualityHandler) executeBatchQualityCheck(rules []models.QualityRule) {
-	ctx := context.Background()
+// ListQualityCheckBatches 获取质量检查批次列表，可按数据源和状态筛选
+func (h *QualityHandler) ListQualityCheckBatches(c *gin.Context) {
+	var req struct {
+		models.PageRequest
+		services.QualityBatchFilter
+	}
 
-	for _, rule := range rules {
-		if !rule.IsEnabled {
-			continue
-		}
+	if !bindPageQuery(c, &req) {
+		return
+	}
 
-		report, err := h.checker.ExecuteQualityCheck(ctx, &rule)
-		if err != nil {
-			h.logger.Error("Failed to execute quality check in batch",
-				zap.Uint("rule_id", rule.ID),
-				zap.String("rule_name", rule.Name),
-				zap.Error(err))
-			continue
-		}
+	batches, total, err := h.batch.List(req.QualityBatchFilter, req.Offset(), req.PageSize)
+	if err != nil {
+		h.logger.Error("Failed to list quality check batches", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return
+	}
 
-		h.logger.Info("Quality check completed in batch",
-			zap.Uint("rule_id", rule.ID),
-			zap.String("rule_name", rule.Name),
-			zap.String("status", report.Status),
-			zap.Float64("score", report.Score))
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{
+		"list":      batches,
+		"total":     total,
+		"page":      req.Page,
+		"page_size": req.PageSize,
+	}))
+}
+
+// GetQualityCheckBatch 查询质量检查批次的进度和汇总得分
+func (h *QualityHandler) GetQualityCheckBatch(c *gin.Context) {
+	batch, err := h.batch.Get(c.Param("batch_id"))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "质量检查批次不存在"))
+			return
+		}
+		h.logger.Error("Failed to get quality check batch", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return
 	}
-}
\ No newline at end of file
+
+	c.JSON(http.StatusOK, models.SuccessResponse(batch))
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/database/dbtest"
+	"github.com/env-data-platform/internal/models"
+)
+
+func TestBatchExecuteQualityCheckRuleSelection(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		where   string
+		message string
+	}{
+		{name: "未指定规则和数据源", body: `{}`, message: "rule_ids和data_source_id至少指定一个"},
+		{name: "按规则ID", body: `{"rule_ids":[1,2]}`, where: "WHERE id IN (?,?) AND"},
+		{name: "按数据源", body: `{"data_source_id":5}`, where: "WHERE data_source_id = ? AND"},
+		{name: "数据源内指定规则", body: `{"rule_ids":[1],"data_source_id":5,"only_enabled":true}`, where: "WHERE id IN (?) AND data_source_id = ? AND is_enabled = ? AND"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.New(t)
+			// 查到的规则均已禁用，不会启动批次
+			db.On(dbtest.KindQuery, func(tx *gorm.DB) {
+				if rules, ok := tx.Statement.Dest.(*[]models.QualityRule); ok {
+					*rules = []models.QualityRule{{Name: "完整性"}, {Name: "唯一性"}}
+				}
+			})
+			h := &QualityHandler{db: db.DB, logger: zap.NewNop()}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/quality/rules/batch-check", strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			h.BatchExecuteQualityCheck(c)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			if tt.where == "" {
+				assert.Contains(t, w.Body.String(), tt.message)
+				assert.Empty(t, db.Statements())
+				return
+			}
+			assert.Contains(t, w.Body.String(), "未找到符合条件的规则")
+
+			queries := db.Statements(dbtest.KindQuery)
+			require.NotEmpty(t, queries)
+			assert.Equal(t, "env_quality_rules", queries[0].Table)
+			assert.Contains(t, queries[0].SQL, tt.where)
+			assert.Contains(t, queries[0].SQL, "ORDER BY priority DESC, id")
+			assert.Empty(t, db.Statements(dbtest.KindCreate))
+		})
+	}
+}
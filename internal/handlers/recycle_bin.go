@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/models"
+)
+
+// recycleResource 支持从回收站恢复的软删除资源
+type recycleResource struct {
+	name     string                 // 资源名称，用于提示信息
+	model    func() interface{}     // 模型指针
+	list     func() interface{}     // 模型切片指针
+	keywords []string               // 关键字搜索的列
+	restore  map[string]interface{} // 恢复时额外更新的字段
+}
+
+// recycleResources 回收站支持的资源，文件的恢复需检查存储对象，由 FileHandler 单独处理
+var recycleResources = map[string]recycleResource{
+	"users": {
+		name:     "用户",
+		model:    func() interface{} { return &models.User{} },
+		list:     func() interface{} { return &[]models.User{} },
+		keywords: []string{"username", "email", "real_name"},
+	},
+	"roles": {
+		name:     "角色",
+		model:    func() interface{} { return &models.Role{} },
+		list:     func() interface{} { return &[]models.Role{} },
+		keywords: []string{"name", "code"},
+	},
+	"datasources": {
+		name:     "数据源",
+		model:    func() interface{} { return &models.DataSource{} },
+		list:     func() interface{} { return &[]models.DataSource{} },
+		keywords: []string{"name"},
+	},
+	"etl_jobs": {
+		name:     "ETL作业",
+		model:    func() interface{} { return &models.ETLJob{} },
+		list:     func() interface{} { return &[]models.ETLJob{} },
+		keywords: []string{"name"},
+		// 删除时已取消调度，恢复后保持停用，由管理员确认后重新启用
+		restore: map[string]interface{}{"is_enabled": false},
+	},
+}
+
+// RecycleBinHandler 回收站处理器，列出并恢复软删除的记录
+type RecycleBinHandler struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+// NewRecycleBinHandler 创建回收站处理器
+func NewRecycleBinHandler(logger *zap.Logger) *RecycleBinHandler {
+	return &RecycleBinHandler{
+		db:     database.GetDB(),
+		logger: logger,
+	}
+}
+
+// resource 解析路径中的资源类型
+func (h *RecycleBinHandler) resource(c *gin.Context) (recycleResource, bool) {
+	resource, ok := recycleResources[c.Param("resource")]
+	if !ok {
+		c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "不支持的资源类型"))
+	}
+	return resource, ok
+}
+
+// ListDeleted 列出已删除的记录
+// @Summary 回收站列表
+// @Description 分页列出已软删除的记录，resource可选users、roles、datasources、etl_jobs
+// @Tags 回收站
+// @Produce json
+// @Security BearerAuth
+// @Param resource path string true "资源类型"
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页大小" default(10)
+// @Param keyword query string false "关键字"
+// @Success 200 {object} models.Response{data=models.PageResponse} "获取成功"
+// @Router /api/v1/recycle-bin/{resource} [get]
+func (h *RecycleBinHandler) ListDeleted(c *gin.Context) {
+	resource, ok := h.resource(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		models.PageRequest
+	}
+	if !bindPageQuery(c, &req) {
+		return
+	}
+
+	query := h.db.Unscoped().Model(resource.model()).Where("deleted_at IS NOT NULL")
+	if keyword := strings.TrimSpace(req.Keyword); keyword != "" && len(resource.keywords) > 0 {
+		conditions := make([]string, 0, len(resource.keywords))
+		args := make([]interface{}, 0, len(resource.keywords))
+		for _, column := range resource.keywords {
+			conditions = append(conditions, column+" LIKE ?")
+			args = append(args, "%"+keyword+"%")
+		}
+		query = query.Where(strings.Join(conditions, " OR "), args...)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		h.logger.Error("Failed to count deleted records", zap.String("resource", c.Param("resource")), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return
+	}
+
+	list := resource.list()
+	if err := query.Order("deleted_at DESC").
+		Offset(req.Offset()).Limit(req.PageSize).
+		Find(list).Error; err != nil {
+		h.logger.Error("Failed to list deleted records", zap.String("resource", c.Param("resource")), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(models.NewPageResponse(list, total, req.Page, req.PageSize)))
+}
+
+// Restore 恢复已删除的记录
+// @Summary 恢复已删除记录
+// @Description 将软删除的记录恢复为正常状态。角色删除时已清除的权限关联不会恢复；ETL作业恢复后为停用状态
+// @Tags 回收站
+// @Produce json
+// @Security BearerAuth
+// @Param resource path string true "资源类型"
+// @Param id path int true "记录ID"
+// @Success 200 {object} models.Response "恢复成功"
+// @Failure 404 {object} models.Response "记录不存在或未被删除"
+// @Router /api/v1/recycle-bin/{resource}/{id}/restore [post]
+func (h *RecycleBinHandler) Restore(c *gin.Context) {
+	resource, ok := h.resource(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "无效的ID"))
+		return
+	}
+
+	updates := map[string]interface{}{"deleted_at": nil}
+	for column, value := range resource.restore {
+		updates[column] = value
+	}
+
+	result := h.db.Unscoped().Model(resource.model()).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(updates)
+	if result.Error != nil {
+		h.logger.Error("Failed to restore record",
+			zap.String("resource", c.Param("resource")), zap.Uint64("id", id), zap.Error(result.Error))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "恢复失败"))
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, resource.name+"不存在或未被删除"))
+		return
+	}
+
+	record := resource.model()
+	h.db.First(record, id)
+
+	h.logger.Info("Deleted record restored",
+		zap.String("resource", c.Param("resource")),
+		zap.Uint64("id", id),
+		zap.Uint("operator", c.GetUint("user_id")))
+	c.JSON(http.StatusOK, models.SuccessResponse(record))
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/database/dbtest"
+)
+
+func TestRecycleBinRestore(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource string
+		id       string
+		deleted  bool
+		expected int
+		table    string
+		columns  string
+		message  string
+	}{
+		{name: "恢复用户", resource: "users", id: "7", deleted: true, expected: http.StatusOK, table: "env_users", columns: "SET `deleted_at`=?,`updated_at`=? WHERE"},
+		// ETL作业恢复后保持停用
+		{name: "恢复ETL作业", resource: "etl_jobs", id: "7", deleted: true, expected: http.StatusOK, table: "env_etl_jobs", columns: "SET `deleted_at`=?,`is_enabled`=?,`updated_at`=? WHERE"},
+		{name: "记录未被删除", resource: "roles", id: "7", expected: http.StatusNotFound, table: "env_roles", message: "角色不存在或未被删除"},
+		{name: "不支持的资源", resource: "files", id: "7", expected: http.StatusNotFound, message: "不支持的资源类型"},
+		{name: "无效ID", resource: "users", id: "abc", expected: http.StatusBadRequest, message: "无效的ID"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.New(t)
+			db.On(dbtest.KindUpdate, func(tx *gorm.DB) {
+				if tt.deleted {
+					tx.RowsAffected = 1
+				}
+			})
+			h := &RecycleBinHandler{db: db.DB, logger: zap.NewNop()}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/recycle-bin/"+tt.resource+"/"+tt.id+"/restore", nil)
+			c.Params = gin.Params{{Key: "resource", Value: tt.resource}, {Key: "id", Value: tt.id}}
+			h.Restore(c)
+			assert.Equal(t, tt.expected, w.Code)
+			if tt.message != "" {
+				assert.Contains(t, w.Body.String(), tt.message)
+			}
+
+			updates := db.Statements(dbtest.KindUpdate)
+			if tt.table == "" {
+				assert.Empty(t, db.Statements())
+				return
+			}
+			// 只恢复已删除的记录，不能被默认的 deleted_at IS NULL 条件过滤掉
+			require.Len(t, updates, 1)
+			assert.Equal(t, tt.table, updates[0].Table)
+			assert.True(t, strings.HasSuffix(updates[0].SQL, "WHERE id = ? AND deleted_at IS NOT NULL"), updates[0].SQL)
+			assert.Nil(t, updates[0].Vars[0])
+			assert.Equal(t, uint64(7), updates[0].Vars[len(updates[0].Vars)-1])
+			if tt.columns != "" {
+				assert.Contains(t, updates[0].SQL, tt.columns)
+			}
+			if tt.resource == "etl_jobs" {
+				assert.Contains(t, updates[0].Vars, false)
+			}
+
+			// 恢复成功后返回恢复后的记录，未恢复时不再查询
+			queries := db.Statements(dbtest.KindQuery)
+			if tt.expected != http.StatusOK {
+				assert.Empty(t, queries)
+				return
+			}
+			require.Len(t, queries, 1)
+			assert.Equal(t, tt.table, queries[0].Table)
+		})
+	}
+}
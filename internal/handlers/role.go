@@ -6,6 +6,7 @@ import (
 
 	"github.com/env-data-platform/internal/database"
 	"github.com/env-data-platform/internal/models"
+	"github.com/env-data-platform/internal/services"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
@@ -13,30 +14,31 @@ import (
 
 // RoleHandler 角色处理器
 type RoleHandler struct {
-	db     *gorm.DB
-	logger *zap.Logger
+	db        *gorm.DB
+	logger    *zap.Logger
+	hierarchy *services.RoleHierarchy
 }
 
 // NewRoleHandler 创建角色处理器
 func NewRoleHandler(logger *zap.Logger) *RoleHandler {
+	db := database.GetDB()
 	return &RoleHandler{
-		db:     database.GetDB(),
-		logger: logger,
+		db:        db,
+		logger:    logger,
+		hierarchy: services.NewRoleHierarchy(db),
 	}
 }
 
 // ListRoles 获取角色列表
 func (h *RoleHandler) ListRoles(c *gin.Context) {
 	var req struct {
-		Page     int    `form:"page" binding:"required,min=1"`
-		PageSize int    `form:"page_size" binding:"required,min=1,max=100"`
-		Name     string `form:"name"`
-		Code     string `form:"code"`
-		Status   *int   `form:"status"`
+		models.PageRequest
+		Name   string `form:"name"`
+		Code   string `form:"code"`
+		Status *int   `form:"status"`
 	}
 
-	if err := c.ShouldBindQuery(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "参数错误"))
+	if !bindPageQuery(c, &req) {
 		return
 	}
 
@@ -56,7 +58,7 @@ func (h *RoleHandler) ListRoles(c *gin.Context) {
 	query.Count(&total)
 
 	var roles []models.Role
-	offset := (req.Page - 1) * req.PageSize
+	offset := req.Offset()
 	if err := query.Offset(offset).Limit(req.PageSize).
 		Preload("Permissions").
 		Order("sort ASC, id ASC").
@@ -82,18 +84,30 @@ func (h *RoleHandler) CreateRole(c *gin.Context) {
 		Description   string `json:"description"`
 		Status        int    `json:"status"`
 		Sort          int    `json:"sort"`
+		ParentID      *uint  `json:"parent_id"`
 		PermissionIDs []uint `json:"permission_ids"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "参数错误"))
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
 		return
 	}
 
 	// 检查角色名称和代码是否已存在
 	var existingRole models.Role
 	if err := h.db.Where("name = ? OR code = ?", req.Name, req.Code).First(&existingRole).Error; err == nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "角色名称或代码已存在"))
+		c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeRoleExists, ""))
+		return
+	}
+
+	// 检查父角色
+	if err := h.hierarchy.CheckParent(0, req.ParentID); err != nil {
+		if err == services.ErrParentRoleNotFound {
+			c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeInvalidParentRole, err.Error()))
+			return
+		}
+		h.logger.Error("Failed to check role parent", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "父角色校验失败"))
 		return
 	}
 
@@ -103,6 +117,7 @@ func (h *RoleHandler) CreateRole(c *gin.Context) {
 		Description: req.Description,
 		Status:      req.Status,
 		Sort:        req.Sort,
+		ParentID:    req.ParentID,
 	}
 
 	// 开启事务
@@ -159,7 +174,7 @@ func (h *RoleHandler) GetRole(c *gin.Context) {
 	if err := h.db.Preload("Permissions").Preload("Users").
 		First(&role, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "角色不存在"))
+			c.JSON(http.StatusNotFound, models.CodeErrorResponse(models.CodeRoleNotFound, ""))
 			return
 		}
 		h.logger.Error("Failed to get role", zap.Error(err))
@@ -184,18 +199,19 @@ func (h *RoleHandler) UpdateRole(c *gin.Context) {
 		Description   string `json:"description"`
 		Status        int    `json:"status"`
 		Sort          int    `json:"sort"`
+		ParentID      *uint  `json:"parent_id"`
 		PermissionIDs []uint `json:"permission_ids"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "参数错误"))
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
 		return
 	}
 
 	var role models.Role
 	if err := h.db.First(&role, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "角色不存在"))
+			c.JSON(http.StatusNotFound, models.CodeErrorResponse(models.CodeRoleNotFound, ""))
 			return
 		}
 		h.logger.Error("Failed to get role", zap.Error(err))
@@ -205,14 +221,25 @@ func (h *RoleHandler) UpdateRole(c *gin.Context) {
 
 	// 检查系统角色
 	if role.IsSystem {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "系统角色不允许修改"))
+		c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeSystemRoleReadonly, ""))
 		return
 	}
 
 	// 检查角色名称和代码是否被其他角色使用
 	var existingRole models.Role
 	if err := h.db.Where("(name = ? OR code = ?) AND id != ?", req.Name, req.Code, id).First(&existingRole).Error; err == nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "角色名称或代码已存在"))
+		c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeRoleExists, ""))
+		return
+	}
+
+	// 检查父角色及继承关系是否成环
+	if err := h.hierarchy.CheckParent(role.ID, req.ParentID); err != nil {
+		if err == services.ErrParentRoleNotFound || err == services.ErrRoleInheritanceCycle {
+			c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeInvalidParentRole, err.Error()))
+			return
+		}
+		h.logger.Error("Failed to check role parent", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "父角色校验失败"))
 		return
 	}
 
@@ -231,6 +258,7 @@ func (h *RoleHandler) UpdateRole(c *gin.Context) {
 		"description": req.Description,
 		"status":      req.Status,
 		"sort":        req.Sort,
+		"parent_id":   req.ParentID,
 	}
 
 	if err := tx.Model(&role).Updates(updates).Error; err != nil {
@@ -274,7 +302,16 @@ func (h *RoleHandler) UpdateRole(c *gin.Context) {
 	c.JSON(http.StatusOK, models.SuccessResponse(role))
 }
 
+// 删除角色时对其用户的处理方式
+const (
+	roleUserActionTransfer = "transfer" // 转移到替代角色
+	roleUserActionUnbind   = "unbind"   // 解除关联
+)
+
 // DeleteRole 删除角色
+//
+// 角色下有用户时默认拒绝删除；user_action=transfer 把用户转移到 transfer_role_id 指定的角色，
+// user_action=unbind 解除用户与该角色的关联，与删除角色在同一事务内完成
 func (h *RoleHandler) DeleteRole(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
@@ -282,10 +319,19 @@ func (h *RoleHandler) DeleteRole(c *gin.Context) {
 		return
 	}
 
+	var req struct {
+		UserAction     string `form:"user_action" binding:"omitempty,oneof=transfer unbind" label:"用户处理方式"`
+		TransferRoleID uint   `form:"transfer_role_id" binding:"required_if=UserAction transfer" label:"替代角色"`
+	}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
+		return
+	}
+
 	var role models.Role
 	if err := h.db.First(&role, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "角色不存在"))
+			c.JSON(http.StatusNotFound, models.CodeErrorResponse(models.CodeRoleNotFound, ""))
 			return
 		}
 		h.logger.Error("Failed to get role", zap.Error(err))
@@ -295,15 +341,46 @@ func (h *RoleHandler) DeleteRole(c *gin.Context) {
 
 	// 检查系统角色
 	if role.IsSystem {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "系统角色不允许删除"))
+		c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeSystemRoleReadonly, "系统角色不允许删除"))
 		return
 	}
 
-	// 检查是否有用户关联此角色
-	var userCount int64
-	h.db.Model(&models.UserRole{}).Where("role_id = ?", id).Count(&userCount)
-	if userCount > 0 {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "该角色下还有用户，无法删除"))
+	// 未指定处理方式时，有用户关联此角色则拒绝删除
+	if req.UserAction == "" {
+		var userCount int64
+		h.db.Model(&models.UserRole{}).Where("role_id = ?", id).Count(&userCount)
+		if userCount > 0 {
+			c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeRoleHasUsers, ""))
+			return
+		}
+	}
+
+	if req.UserAction == roleUserActionTransfer {
+		var target models.Role
+		if err := h.db.First(&target, req.TransferRoleID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeInvalidTransferRole, "替代角色不存在"))
+				return
+			}
+			h.logger.Error("Failed to get transfer role", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+			return
+		}
+		if target.ID == role.ID {
+			c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeInvalidTransferRole, "替代角色不能是被删除的角色"))
+			return
+		}
+		if target.Status != 1 {
+			c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeInvalidTransferRole, "替代角色已禁用"))
+			return
+		}
+	}
+
+	// 检查是否有子角色继承此角色
+	var childCount int64
+	h.db.Model(&models.Role{}).Where("parent_id = ?", id).Count(&childCount)
+	if childCount > 0 {
+		c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeRoleHasChildren, ""))
 		return
 	}
 
@@ -315,6 +392,15 @@ func (h *RoleHandler) DeleteRole(c *gin.Context) {
 		}
 	}()
 
+	// 转移或解除用户关联
+	affectedUsers, err := moveRoleUsers(tx, role.ID, req.UserAction, req.TransferRoleID)
+	if err != nil {
+		tx.Rollback()
+		h.logger.Error("Failed to move role users", zap.Uint("role_id", role.ID), zap.String("user_action", req.UserAction), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "用户关联处理失败"))
+		return
+	}
+
 	// 清除权限关联
 	if err := tx.Model(&role).Association("Permissions").Clear(); err != nil {
 		tx.Rollback()
@@ -331,12 +417,78 @@ func (h *RoleHandler) DeleteRole(c *gin.Context) {
 		return
 	}
 
-	tx.Commit()
+	if err := tx.Commit().Error; err != nil {
+		h.logger.Error("Failed to commit role deletion", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "删除失败"))
+		return
+	}
 
-	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{"message": "删除成功"}))
+	if affectedUsers > 0 {
+		h.logger.Info("Role users moved on deletion",
+			zap.Uint("role_id", role.ID),
+			zap.String("user_action", req.UserAction),
+			zap.Uint("transfer_role_id", req.TransferRoleID),
+			zap.Int("affected_users", affectedUsers))
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(gin.H{
+		"message":        "删除成功",
+		"user_action":    req.UserAction,
+		"affected_users": affectedUsers,
+	}))
 }
 
-// GetRolePermissions 获取角色权限
+// moveRoleUsers 删除角色前处理其用户关联，返回受影响的用户数。
+// 转移时已拥有替代角色的用户只解除原关联，不重复分配
+func moveRoleUsers(tx *gorm.DB, roleID uint, action string, transferRoleID uint) (int, error) {
+	if action == "" {
+		return 0, nil
+	}
+
+	var userIDs []uint
+	if err := tx.Model(&models.UserRole{}).Where("role_id = ?", roleID).Pluck("user_id", &userIDs).Error; err != nil {
+		return 0, err
+	}
+	if len(userIDs) == 0 {
+		return 0, nil
+	}
+
+	if action == roleUserActionTransfer {
+		var existing []uint
+		if err := tx.Model(&models.UserRole{}).
+			Where("role_id = ? AND user_id IN ?", transferRoleID, userIDs).
+			Pluck("user_id", &existing).Error; err != nil {
+			return 0, err
+		}
+		if assignments := transferAssignments(userIDs, existing, transferRoleID); len(assignments) > 0 {
+			if err := tx.Create(&assignments).Error; err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if err := tx.Where("role_id = ?", roleID).Delete(&models.UserRole{}).Error; err != nil {
+		return 0, err
+	}
+	return len(userIDs), nil
+}
+
+// transferAssignments 需要新增的替代角色关联，跳过已拥有替代角色的用户
+func transferAssignments(userIDs, existing []uint, transferRoleID uint) []models.UserRole {
+	has := make(map[uint]bool, len(existing))
+	for _, id := range existing {
+		has[id] = true
+	}
+	assignments := make([]models.UserRole, 0, len(userIDs))
+	for _, id := range userIDs {
+		if !has[id] {
+			assignments = append(assignments, models.UserRole{UserID: id, RoleID: transferRoleID})
+		}
+	}
+	return assignments
+}
+
+// GetRolePermissions 获取角色的有效权限，包含从父角色继承的权限
 func (h *RoleHandler) GetRolePermissions(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
@@ -345,9 +497,9 @@ func (h *RoleHandler) GetRolePermissions(c *gin.Context) {
 	}
 
 	var role models.Role
-	if err := h.db.Preload("Permissions").First(&role, id).Error; err != nil {
+	if err := h.db.First(&role, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "角色不存在"))
+			c.JSON(http.StatusNotFound, models.CodeErrorResponse(models.CodeRoleNotFound, ""))
 			return
 		}
 		h.logger.Error("Failed to get role", zap.Error(err))
@@ -355,7 +507,14 @@ func (h *RoleHandler) GetRolePermissions(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, models.SuccessResponse(role.Permissions))
+	permissions, err := h.hierarchy.GetRolePermissions(role.ID)
+	if err != nil {
+		h.logger.Error("Failed to get role permissions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse(permissions))
 }
 
 // AssignPermissions 分配权限给角色
@@ -371,14 +530,14 @@ func (h *RoleHandler) AssignPermissions(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "参数错误"))
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
 		return
 	}
 
 	var role models.Role
 	if err := h.db.First(&role, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "角色不存在"))
+			c.JSON(http.StatusNotFound, models.CodeErrorResponse(models.CodeRoleNotFound, ""))
 			return
 		}
 		h.logger.Error("Failed to get role", zap.Error(err))
@@ -388,7 +547,7 @@ func (h *RoleHandler) AssignPermissions(c *gin.Context) {
 
 	// 检查系统角色
 	if role.IsSystem {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "系统角色不允许修改权限"))
+		c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeSystemRoleReadonly, "系统角色不允许修改权限"))
 		return
 	}
 
@@ -420,7 +579,7 @@ func (h *RoleHandler) AssignPermissions(c *gin.Context) {
 
 		if len(permissions) != len(req.PermissionIDs) {
 			tx.Rollback()
-			c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "部分权限不存在或已禁用"))
+			c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodePermissionUnavailable, ""))
 			return
 		}
 
@@ -451,13 +610,9 @@ func (h *RoleHandler) GetRoleUsers(c *gin.Context) {
 		return
 	}
 
-	var req struct {
-		Page     int `form:"page" binding:"required,min=1"`
-		PageSize int `form:"page_size" binding:"required,min=1,max=100"`
-	}
+	var req models.PageRequest
 
-	if err := c.ShouldBindQuery(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "参数错误"))
+	if !bindPageQuery(c, &req) {
 		return
 	}
 
@@ -465,7 +620,7 @@ func (h *RoleHandler) GetRoleUsers(c *gin.Context) {
 	var role models.Role
 	if err := h.db.First(&role, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "角色不存在"))
+			c.JSON(http.StatusNotFound, models.CodeErrorResponse(models.CodeRoleNotFound, ""))
 			return
 		}
 		h.logger.Error("Failed to get role", zap.Error(err))
@@ -480,7 +635,7 @@ func (h *RoleHandler) GetRoleUsers(c *gin.Context) {
 		Count(&total)
 
 	var users []models.User
-	offset := (req.Page - 1) * req.PageSize
+	offset := req.Offset()
 	if err := h.db.Model(&models.User{}).
 		Joins("JOIN env_user_roles ON env_users.id = env_user_roles.user_id").
 		Where("env_user_roles.role_id = ?", id).
@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/env-data-platform/internal/models"
+)
+
+func TestTransferAssignments(t *testing.T) {
+	// 用户2已拥有替代角色，只需解除原关联
+	assignments := transferAssignments([]uint{1, 2, 3}, []uint{2}, 9)
+	assert.Equal(t, []models.UserRole{
+		{UserID: 1, RoleID: 9},
+		{UserID: 3, RoleID: 9},
+	}, assignments)
+
+	assert.Empty(t, transferAssignments([]uint{1}, []uint{1}, 9))
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/env-data-platform/internal/config"
+)
+
+// RuntimeStats Go运行时指标，用于排查goroutine泄漏和GC压力
+type RuntimeStats struct {
+	Goroutines int   `json:"goroutines"`
+	CgoCalls   int64 `json:"cgo_calls"`
+
+	HeapAlloc    uint64 `json:"heap_alloc"`    // 堆上存活对象占用的字节数
+	HeapInuse    uint64 `json:"heap_inuse"`    // 已使用的堆span字节数
+	HeapIdle     uint64 `json:"heap_idle"`     // 空闲的堆span字节数
+	HeapReleased uint64 `json:"heap_released"` // 已归还操作系统的字节数
+	HeapObjects  uint64 `json:"heap_objects"`  // 堆上存活对象数
+	StackInuse   uint64 `json:"stack_inuse"`   // goroutine栈占用的字节数
+	Sys          uint64 `json:"sys"`           // 从操作系统获取的总字节数
+	Mallocs      uint64 `json:"mallocs"`       // 累计分配对象数
+	Frees        uint64 `json:"frees"`         // 累计释放对象数
+
+	NumGC            uint32     `json:"num_gc"`
+	NumForcedGC      uint32     `json:"num_forced_gc"`
+	NextGC           uint64     `json:"next_gc"` // 下次GC触发时的堆大小目标
+	LastGC           *time.Time `json:"last_gc"`
+	LastPauseMs      float64    `json:"last_pause_ms"`
+	MaxRecentPauseMs float64    `json:"max_recent_pause_ms"` // 最近至多256次GC中的最长暂停
+	PauseTotalMs     float64    `json:"pause_total_ms"`
+	GCCPUFraction    float64    `json:"gc_cpu_fraction"` // 启动以来GC占用的CPU时间比例
+}
+
+// collectRuntimeStats 采集当前运行时指标，ReadMemStats会短暂STW，不宜高频调用
+func collectRuntimeStats() RuntimeStats {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return runtimeStatsFrom(&m, runtime.NumGoroutine(), runtime.NumCgoCall())
+}
+
+// runtimeStatsFrom 由MemStats计算运行时指标
+func runtimeStatsFrom(m *runtime.MemStats, goroutines int, cgoCalls int64) RuntimeStats {
+	stats := RuntimeStats{
+		Goroutines:    goroutines,
+		CgoCalls:      cgoCalls,
+		HeapAlloc:     m.HeapAlloc,
+		HeapInuse:     m.HeapInuse,
+		HeapIdle:      m.HeapIdle,
+		HeapReleased:  m.HeapReleased,
+		HeapObjects:   m.HeapObjects,
+		StackInuse:    m.StackInuse,
+		Sys:           m.Sys,
+		Mallocs:       m.Mallocs,
+		Frees:         m.Frees,
+		NumGC:         m.NumGC,
+		NumForcedGC:   m.NumForcedGC,
+		NextGC:        m.NextGC,
+		PauseTotalMs:  durationMs(time.Duration(m.PauseTotalNs)),
+		GCCPUFraction: m.GCCPUFraction,
+	}
+	if m.NumGC == 0 {
+		return stats
+	}
+
+	lastGC := time.Unix(0, int64(m.LastGC))
+	stats.LastGC = &lastGC
+	// PauseNs为环形缓冲，最近一次GC位于(NumGC+255)%256
+	stats.LastPauseMs = durationMs(time.Duration(m.PauseNs[(m.NumGC+255)%256]))
+
+	recent := int(m.NumGC)
+	if recent > len(m.PauseNs) {
+		recent = len(m.PauseNs)
+	}
+	var maxPause uint64
+	for i := 0; i < recent; i++ {
+		if m.PauseNs[i] > maxPause {
+			maxPause = m.PauseNs[i]
+		}
+	}
+	stats.MaxRecentPauseMs = durationMs(time.Duration(maxPause))
+	return stats
+}
+
+// durationMs 转为毫秒，保留三位小数
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// runtimeHealth 按阈值检查goroutine数量和GC暂停，超过任一阈值为warning；堆内存由memory检查项判断
+func runtimeHealth(stats RuntimeStats, cfg config.RuntimeMonitorConfig) map[string]interface{} {
+	var problems []string
+	if cfg.MaxGoroutines > 0 && stats.Goroutines > cfg.MaxGoroutines {
+		problems = append(problems, fmt.Sprintf("goroutine count %d exceeds %d, possible goroutine leak", stats.Goroutines, cfg.MaxGoroutines))
+	}
+	if cfg.MaxGCPause > 0 && stats.MaxRecentPauseMs > durationMs(cfg.MaxGCPause) {
+		problems = append(problems, fmt.Sprintf("GC pause %.3fms exceeds %s", stats.MaxRecentPauseMs, cfg.MaxGCPause))
+	}
+
+	check := map[string]interface{}{
+		"status":              "healthy",
+		"message":             "Runtime metrics are normal",
+		"goroutines":          stats.Goroutines,
+		"heap_objects":        stats.HeapObjects,
+		"num_gc":              stats.NumGC,
+		"max_recent_pause_ms": stats.MaxRecentPauseMs,
+		"gc_cpu_fraction":     stats.GCCPUFraction,
+	}
+	if len(problems) > 0 {
+		check["status"] = "warning"
+		check["message"] = strings.Join(problems, "; ")
+	}
+	return check
+}
+
+// runtimeMonitorConfig 运行时告警阈值，未加载配置时使用默认值
+func runtimeMonitorConfig() config.RuntimeMonitorConfig {
+	if config.GlobalConfig == nil {
+		return config.RuntimeMonitorConfig{MaxGoroutines: 10000, MaxHeapMB: 1024, MaxGCPause: 100 * time.Millisecond}
+	}
+	return config.GlobalConfig.Monitor.Runtime
+}
@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
 	"net/http"
 	"runtime"
 	"strconv"
@@ -9,19 +12,23 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
+	"github.com/env-data-platform/internal/config"
 	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/hj212"
 	"github.com/env-data-platform/internal/models"
 )
 
 // SystemHandler 系统处理器
 type SystemHandler struct {
-	logger *zap.Logger
+	logger      *zap.Logger
+	hj212Server *hj212.Server
 }
 
 // NewSystemHandler 创建系统处理器
-func NewSystemHandler(logger *zap.Logger) *SystemHandler {
+func NewSystemHandler(logger *zap.Logger, hj212Server *hj212.Server) *SystemHandler {
 	return &SystemHandler{
-		logger: logger,
+		logger:      logger,
+		hj212Server: hj212Server,
 	}
 }
 
@@ -39,6 +46,7 @@ type SystemInfo struct {
 		Connected bool   `json:"connected"`
 		Version   string `json:"version"`
 	} `json:"database_info"`
+	Runtime RuntimeStats `json:"runtime"`
 }
 
 // SystemStats 系统统计信息
@@ -58,23 +66,28 @@ type SystemStats struct {
 type OperationLogQuery struct {
 	models.PaginationQuery
 	UserID     *uint      `form:"user_id"`
+	Module     *string    `form:"module"`
 	Action     *string    `form:"action"`
 	Resource   *string    `form:"resource"`
 	StartTime  *time.Time `form:"start_time" time_format:"2006-01-02 15:04:05"`
 	EndTime    *time.Time `form:"end_time" time_format:"2006-01-02 15:04:05"`
 	IPAddress  *string    `form:"ip_address"`
 	StatusCode *int       `form:"status_code"`
+	Cursor     *string    `form:"cursor"`
 }
 
 // LoginLogQuery 登录日志查询参数
 type LoginLogQuery struct {
 	models.PaginationQuery
-	UserID    *uint      `form:"user_id"`
-	Username  *string    `form:"username"`
-	StartTime *time.Time `form:"start_time" time_format:"2006-01-02 15:04:05"`
-	EndTime   *time.Time `form:"end_time" time_format:"2006-01-02 15:04:05"`
-	IPAddress *string    `form:"ip_address"`
-	Status    *string    `form:"status"`
+	UserID     *uint      `form:"user_id"`
+	Username   *string    `form:"username"`
+	StartTime  *time.Time `form:"start_time" time_format:"2006-01-02 15:04:05"`
+	EndTime    *time.Time `form:"end_time" time_format:"2006-01-02 15:04:05"`
+	IPAddress  *string    `form:"ip_address"`
+	Status     *string    `form:"status"`
+	DeviceType *string    `form:"device_type"`
+	IsRemote   *bool      `form:"is_remote"`
+	Cursor     *string    `form:"cursor"`
 }
 
 var startTime = time.Now()
@@ -88,8 +101,7 @@ var startTime = time.Now()
 // @Success 200 {object} models.Response{data=SystemInfo} "获取成功"
 // @Router /api/v1/system/info [get]
 func (h *SystemHandler) GetSystemInfo(c *gin.Context) {
-	var memStats runtime.MemStats
-	runtime.ReadMemStats(&memStats)
+	runtimeStats := collectRuntimeStats()
 
 	// 检查数据库连接
 	dbConnected := true
@@ -115,8 +127,9 @@ func (h *SystemHandler) GetSystemInfo(c *gin.Context) {
 		Platform:    runtime.GOOS + "/" + runtime.GOARCH,
 		StartTime:   startTime,
 		Uptime:      time.Since(startTime).String(),
-		MemoryUsage: int64(memStats.Alloc),
+		MemoryUsage: int64(runtimeStats.HeapAlloc),
 		CPUCount:    runtime.NumCPU(),
+		Runtime:     runtimeStats,
 	}
 
 	info.DatabaseInfo.Connected = dbConnected
@@ -168,29 +181,22 @@ func (h *SystemHandler) GetSystemStats(c *gin.Context) {
 // @Param page query int false "页码" default(1)
 // @Param page_size query int false "每页大小" default(10)
 // @Param user_id query int false "用户ID"
+// @Param module query string false "操作模块，网关管理操作为gateway"
 // @Param action query string false "操作类型"
 // @Param resource query string false "资源类型"
 // @Param start_time query string false "开始时间"
 // @Param end_time query string false "结束时间"
 // @Param ip_address query string false "IP地址"
 // @Param status_code query int false "状态码"
+// @Param cursor query string false "游标，传入时按游标分页，首页传空值，后续传上一页返回的next_cursor"
 // @Success 200 {object} models.Response{data=models.PaginatedList{items=[]models.OperationLog}} "获取成功"
 // @Router /api/v1/system/logs/operation [get]
 func (h *SystemHandler) GetOperationLogs(c *gin.Context) {
 	var query OperationLogQuery
-	if err := c.ShouldBindQuery(&query); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "查询参数错误"))
+	if !bindPageQuery(c, &query) {
 		return
 	}
 
-	// 设置默认分页参数
-	if query.Page <= 0 {
-		query.Page = 1
-	}
-	if query.PageSize <= 0 {
-		query.PageSize = 10
-	}
-
 	// 构建查询
 	db := database.DB.Model(&models.OperationLog{}).Preload("User")
 
@@ -198,6 +204,9 @@ func (h *SystemHandler) GetOperationLogs(c *gin.Context) {
 	if query.UserID != nil {
 		db = db.Where("user_id = ?", *query.UserID)
 	}
+	if query.Module != nil && *query.Module != "" {
+		db = db.Where("module = ?", *query.Module)
+	}
 	if query.Action != nil && *query.Action != "" {
 		db = db.Where("action LIKE ?", "%"+*query.Action+"%")
 	}
@@ -211,12 +220,32 @@ func (h *SystemHandler) GetOperationLogs(c *gin.Context) {
 		db = db.Where("created_at <= ?", *query.EndTime)
 	}
 	if query.IPAddress != nil && *query.IPAddress != "" {
-		db = db.Where("ip_address = ?", *query.IPAddress)
+		db = db.Where("ip = ?", *query.IPAddress)
 	}
 	if query.StatusCode != nil {
 		db = db.Where("status_code = ?", *query.StatusCode)
 	}
 
+	// 传入cursor时按created_at+id游标分页，跳过总数统计以避免大表深分页
+	if query.Cursor != nil {
+		cursor, err := database.DecodeCursor(*query.Cursor)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, err.Error()))
+			return
+		}
+		var logs []models.OperationLog
+		if err := db.Scopes(database.CursorPage(cursor, query.PageSize)).Find(&logs).Error; err != nil {
+			h.logger.Error("Failed to list operation logs", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+			return
+		}
+		logs, next := database.TrimCursorPage(logs, query.PageSize, func(l models.OperationLog) database.Cursor {
+			return database.Cursor{CreatedAt: l.CreatedAt, ID: l.ID}
+		})
+		c.JSON(http.StatusOK, models.SuccessResponse(models.NewCursorPageResponse(logs, next, query.PageSize)))
+		return
+	}
+
 	// 获取总数
 	var total int64
 	if err := db.Count(&total).Error; err != nil {
@@ -227,7 +256,7 @@ func (h *SystemHandler) GetOperationLogs(c *gin.Context) {
 
 	// 分页查询
 	var logs []models.OperationLog
-	offset := (query.Page - 1) * query.PageSize
+	offset := query.Offset()
 	if err := db.Offset(offset).Limit(query.PageSize).Order("created_at DESC").Find(&logs).Error; err != nil {
 		h.logger.Error("Failed to list operation logs", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
@@ -252,23 +281,17 @@ func (h *SystemHandler) GetOperationLogs(c *gin.Context) {
 // @Param end_time query string false "结束时间"
 // @Param ip_address query string false "IP地址"
 // @Param status query string false "登录状态"
+// @Param device_type query string false "设备类型 desktop/mobile/tablet/bot/client/unknown"
+// @Param is_remote query bool false "是否异地登录"
+// @Param cursor query string false "游标，传入时按游标分页，首页传空值，后续传上一页返回的next_cursor"
 // @Success 200 {object} models.Response{data=models.PaginatedList{items=[]models.LoginLog}} "获取成功"
 // @Router /api/v1/system/logs/login [get]
 func (h *SystemHandler) GetLoginLogs(c *gin.Context) {
 	var query LoginLogQuery
-	if err := c.ShouldBindQuery(&query); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "查询参数错误"))
+	if !bindPageQuery(c, &query) {
 		return
 	}
 
-	// 设置默认分页参数
-	if query.Page <= 0 {
-		query.Page = 1
-	}
-	if query.PageSize <= 0 {
-		query.PageSize = 10
-	}
-
 	// 构建查询
 	db := database.DB.Model(&models.LoginLog{}).Preload("User")
 
@@ -287,11 +310,37 @@ func (h *SystemHandler) GetLoginLogs(c *gin.Context) {
 		db = db.Where("created_at <= ?", *query.EndTime)
 	}
 	if query.IPAddress != nil && *query.IPAddress != "" {
-		db = db.Where("ip_address = ?", *query.IPAddress)
+		db = db.Where("ip = ?", *query.IPAddress)
 	}
 	if query.Status != nil && *query.Status != "" {
 		db = db.Where("status = ?", *query.Status)
 	}
+	if query.DeviceType != nil && *query.DeviceType != "" {
+		db = db.Where("device_type = ?", *query.DeviceType)
+	}
+	if query.IsRemote != nil {
+		db = db.Where("is_remote = ?", *query.IsRemote)
+	}
+
+	// 传入cursor时按created_at+id游标分页，跳过总数统计以避免大表深分页
+	if query.Cursor != nil {
+		cursor, err := database.DecodeCursor(*query.Cursor)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, err.Error()))
+			return
+		}
+		var logs []models.LoginLog
+		if err := db.Scopes(database.CursorPage(cursor, query.PageSize)).Find(&logs).Error; err != nil {
+			h.logger.Error("Failed to list login logs", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+			return
+		}
+		logs, next := database.TrimCursorPage(logs, query.PageSize, func(l models.LoginLog) database.Cursor {
+			return database.Cursor{CreatedAt: l.CreatedAt, ID: l.ID}
+		})
+		c.JSON(http.StatusOK, models.SuccessResponse(models.NewCursorPageResponse(logs, next, query.PageSize)))
+		return
+	}
 
 	// 获取总数
 	var total int64
@@ -303,7 +352,7 @@ func (h *SystemHandler) GetLoginLogs(c *gin.Context) {
 
 	// 分页查询
 	var logs []models.LoginLog
-	offset := (query.Page - 1) * query.PageSize
+	offset := query.Offset()
 	if err := db.Offset(offset).Limit(query.PageSize).Order("created_at DESC").Find(&logs).Error; err != nil {
 		h.logger.Error("Failed to list login logs", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
@@ -383,12 +432,13 @@ func (h *SystemHandler) GetSystemHealth(c *gin.Context) {
 
 	checks := health["checks"].(map[string]interface{})
 
-	// 检查数据库连接
+	// 检查数据库连接和连接池
 	if sqlDB, err := database.DB.DB(); err == nil {
 		if err := sqlDB.Ping(); err == nil {
-			checks["database"] = map[string]interface{}{
-				"status": "healthy",
-				"message": "Database connection is working",
+			dbCheck := poolHealth(sqlDB.Stats())
+			checks["database"] = dbCheck
+			if dbCheck["status"] == "warning" && health["status"] == "healthy" {
+				health["status"] = "warning"
 			}
 		} else {
 			checks["database"] = map[string]interface{}{
@@ -405,12 +455,25 @@ func (h *SystemHandler) GetSystemHealth(c *gin.Context) {
 		health["status"] = "unhealthy"
 	}
 
-	// 检查内存使用
-	var memStats runtime.MemStats
-	runtime.ReadMemStats(&memStats)
-	memUsageMB := memStats.Alloc / 1024 / 1024
+	// 检查Redis，Redis不可用时相关功能降级，不判定为整体不健康
+	checks["redis"] = redisHealth(c.Request.Context())
+	if checks["redis"].(map[string]interface{})["status"] != "healthy" && health["status"] == "healthy" {
+		health["status"] = "warning"
+	}
+
+	// 检查HJ212服务器是否在监听
+	hj212Check := h.hj212Health()
+	checks["hj212"] = hj212Check
+	if hj212Check["status"] == "unhealthy" {
+		health["status"] = "unhealthy"
+	}
 
-	if memUsageMB < 1024 { // 小于1GB认为正常
+	// 检查内存使用、goroutine数量和GC暂停
+	runtimeCfg := runtimeMonitorConfig()
+	runtimeStats := collectRuntimeStats()
+	memUsageMB := runtimeStats.HeapAlloc / 1024 / 1024
+
+	if runtimeCfg.MaxHeapMB <= 0 || memUsageMB < uint64(runtimeCfg.MaxHeapMB) {
 		checks["memory"] = map[string]interface{}{
 			"status": "healthy",
 			"usage_mb": memUsageMB,
@@ -427,6 +490,12 @@ func (h *SystemHandler) GetSystemHealth(c *gin.Context) {
 		}
 	}
 
+	runtimeCheck := runtimeHealth(runtimeStats, runtimeCfg)
+	checks["runtime"] = runtimeCheck
+	if runtimeCheck["status"] == "warning" && health["status"] == "healthy" {
+		health["status"] = "warning"
+	}
+
 	// 检查系统运行时间
 	uptime := time.Since(startTime)
 	checks["uptime"] = map[string]interface{}{
@@ -436,4 +505,75 @@ func (h *SystemHandler) GetSystemHealth(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, models.SuccessResponse(health))
-}
\ No newline at end of file
+}
+
+// poolHealth 数据库连接池状态，使用中连接数达到上限视为连接池耗尽
+func poolHealth(stats sql.DBStats) map[string]interface{} {
+	check := map[string]interface{}{
+		"status":           "healthy",
+		"message":          "Database connection is working",
+		"max_open":         stats.MaxOpenConnections,
+		"open_connections": stats.OpenConnections,
+		"in_use":           stats.InUse,
+		"idle":             stats.Idle,
+		"wait_count":       stats.WaitCount,
+		"wait_duration":    stats.WaitDuration.String(),
+		"max_idle_closed":  stats.MaxIdleClosed,
+	}
+	if stats.MaxOpenConnections > 0 && stats.InUse >= stats.MaxOpenConnections {
+		check["status"] = "warning"
+		check["message"] = fmt.Sprintf("Database connection pool exhausted: %d/%d in use", stats.InUse, stats.MaxOpenConnections)
+	}
+	return check
+}
+
+// redisHealth Redis连接状态
+func redisHealth(ctx context.Context) map[string]interface{} {
+	client := database.GetRedis()
+	if client == nil {
+		return map[string]interface{}{
+			"status":  "warning",
+			"message": "Redis is not connected, dependent features are degraded",
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	stats := client.PoolStats()
+	check := map[string]interface{}{
+		"status":      "healthy",
+		"message":     "Redis connection is working",
+		"total_conns": stats.TotalConns,
+		"idle_conns":  stats.IdleConns,
+		"timeouts":    stats.Timeouts,
+	}
+	if err := client.Ping(ctx).Err(); err != nil {
+		check["status"] = "warning"
+		check["message"] = "Redis ping failed: " + err.Error()
+	}
+	return check
+}
+
+// hj212Health HJ212服务器监听状态，未启用时不参与整体健康判定
+func (h *SystemHandler) hj212Health() map[string]interface{} {
+	if config.GlobalConfig == nil || !config.GlobalConfig.HJ212.Enabled || h.hj212Server == nil {
+		return map[string]interface{}{
+			"status":  "disabled",
+			"message": "HJ212 server is disabled",
+		}
+	}
+
+	addr, listening := h.hj212Server.ListenAddr()
+	if !listening {
+		return map[string]interface{}{
+			"status":  "unhealthy",
+			"message": "HJ212 server is not listening",
+		}
+	}
+	return map[string]interface{}{
+		"status":      "healthy",
+		"message":     "HJ212 server is listening",
+		"listen_addr": addr,
+		"connections": len(h.hj212Server.GetConnectedDevices()),
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"database/sql"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/env-data-platform/internal/config"
+)
+
+func TestPoolHealth(t *testing.T) {
+	check := poolHealth(sql.DBStats{MaxOpenConnections: 10, OpenConnections: 4, InUse: 3, Idle: 1})
+	assert.Equal(t, "healthy", check["status"])
+	assert.Equal(t, 3, check["in_use"])
+
+	check = poolHealth(sql.DBStats{MaxOpenConnections: 10, OpenConnections: 10, InUse: 10, WaitCount: 5, WaitDuration: 2 * time.Second})
+	assert.Equal(t, "warning", check["status"])
+	assert.Equal(t, int64(5), check["wait_count"])
+	assert.Equal(t, "2s", check["wait_duration"])
+
+	// 未限制最大连接数时不判定耗尽
+	check = poolHealth(sql.DBStats{InUse: 100})
+	assert.Equal(t, "healthy", check["status"])
+}
+
+func TestRuntimeStatsFrom(t *testing.T) {
+	m := &runtime.MemStats{HeapAlloc: 64 << 20, HeapObjects: 1000, NumGC: 3, LastGC: uint64(time.Unix(1700000000, 0).UnixNano())}
+	m.PauseNs[0] = uint64(2 * time.Millisecond)
+	m.PauseNs[1] = uint64(5 * time.Millisecond)
+	m.PauseNs[2] = uint64(1500 * time.Microsecond)
+
+	stats := runtimeStatsFrom(m, 42, 0)
+	assert.Equal(t, 42, stats.Goroutines)
+	assert.Equal(t, uint64(1000), stats.HeapObjects)
+	assert.Equal(t, 1.5, stats.LastPauseMs)
+	assert.Equal(t, 5.0, stats.MaxRecentPauseMs)
+	require.NotNil(t, stats.LastGC)
+	assert.Equal(t, int64(1700000000), stats.LastGC.Unix())
+
+	// 尚未GC时没有暂停数据
+	stats = runtimeStatsFrom(&runtime.MemStats{}, 1, 0)
+	assert.Nil(t, stats.LastGC)
+	assert.Zero(t, stats.MaxRecentPauseMs)
+}
+
+func TestRuntimeHealth(t *testing.T) {
+	cfg := config.RuntimeMonitorConfig{MaxGoroutines: 100, MaxGCPause: 10 * time.Millisecond}
+
+	check := runtimeHealth(RuntimeStats{Goroutines: 50, MaxRecentPauseMs: 1}, cfg)
+	assert.Equal(t, "healthy", check["status"])
+
+	check = runtimeHealth(RuntimeStats{Goroutines: 500, MaxRecentPauseMs: 20}, cfg)
+	assert.Equal(t, "warning", check["status"])
+	message := check["message"].(string)
+	assert.True(t, strings.Contains(message, "goroutine count 500 exceeds 100"), message)
+	assert.True(t, strings.Contains(message, "GC pause 20.000ms exceeds 10ms"), message)
+
+	// 阈值为0不检查
+	check = runtimeHealth(RuntimeStats{Goroutines: 500, MaxRecentPauseMs: 20}, config.RuntimeMonitorConfig{})
+	assert.Equal(t, "healthy", check["status"])
+}
@@ -1,29 +1,44 @@
 package handlers
 
 import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/mail"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 
 	"github.com/env-data-platform/internal/auth"
+	"github.com/env-data-platform/internal/config"
 	"github.com/env-data-platform/internal/database"
 	"github.com/env-data-platform/internal/models"
+	"github.com/env-data-platform/internal/services"
 )
 
 // UserHandler 用户处理器
 type UserHandler struct {
 	logger          *zap.Logger
 	passwordManager *auth.PasswordManager
+	passwordHistory *services.PasswordHistoryService
+	loginGuard      *auth.LoginGuard
 }
 
 // NewUserHandler 创建用户处理器
-func NewUserHandler(logger *zap.Logger) *UserHandler {
+func NewUserHandler(cfg *config.Config, logger *zap.Logger) *UserHandler {
+	passwordManager := auth.NewPasswordManagerWithConfig(cfg.Security.Password.Argon2)
 	return &UserHandler{
 		logger:          logger,
-		passwordManager: auth.NewPasswordManager(),
+		passwordManager: passwordManager,
+		passwordHistory: services.NewPasswordHistoryService(passwordManager, cfg.Security.Password.HistoryCount),
+		loginGuard:      auth.NewLoginGuard(database.GetRedis(), cfg.Security.Login),
 	}
 }
 
@@ -54,6 +69,9 @@ type UserListQuery struct {
 	RealName *string `form:"real_name"`
 	RoleID   *uint   `form:"role_id"`
 	Status   *string `form:"status"`
+	Online   *bool   `form:"online"`
+	// InactiveDays 筛选超过指定天数未登录（含从未登录）的用户，用于清理长期不活跃账号
+	InactiveDays *int `form:"inactive_days" binding:"omitempty,min=1"`
 }
 
 // ResetPasswordRequest 重置密码请求
@@ -61,6 +79,11 @@ type ResetPasswordRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// UnlockIPRequest 解除IP登录锁定请求
+type UnlockIPRequest struct {
+	IP string `json:"ip" binding:"required,ip" example:"192.168.1.10"`
+}
+
 // UpdateCurrentUserRequest 更新当前用户请求
 type UpdateCurrentUserRequest struct {
 	Email    *string `json:"email,omitempty" binding:"omitempty,email"`
@@ -73,6 +96,41 @@ type AssignRolesRequest struct {
 	RoleIDs []uint `json:"role_ids" binding:"required" example:"[1,2,3]"`
 }
 
+// 批量分配角色方式
+const (
+	RoleAssignModeReplace = "replace" // 替换为指定角色
+	RoleAssignModeAdd     = "add"     // 追加指定角色
+	RoleAssignModeRemove  = "remove"  // 移除指定角色
+)
+
+// maxBatchAssignUsers 单次批量分配角色的最大用户数
+const maxBatchAssignUsers = 500
+
+// BatchAssignRolesRequest 批量分配角色请求
+type BatchAssignRolesRequest struct {
+	UserIDs []uint `json:"user_ids" binding:"required,min=1" example:"[1,2,3]"`
+	RoleIDs []uint `json:"role_ids" binding:"required" example:"[2]"`
+	Mode    string `json:"mode" binding:"omitempty,oneof=replace add remove" example:"add"` // 默认replace
+}
+
+// BatchAssignRoleResult 单个用户的角色分配结果
+type BatchAssignRoleResult struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username,omitempty"`
+	Status   string `json:"status"` // success, failed
+	Message  string `json:"message,omitempty"`
+	Added    int    `json:"added"`
+	Removed  int    `json:"removed"`
+}
+
+// BatchAssignRolesSummary 批量分配角色汇总
+type BatchAssignRolesSummary struct {
+	Total   int                     `json:"total"`
+	Success int                     `json:"success"`
+	Failed  int                     `json:"failed"`
+	Results []BatchAssignRoleResult `json:"results"`
+}
+
 // ListUsers 获取用户列表
 // @Summary 获取用户列表
 // @Description 分页获取用户列表，支持多条件筛选
@@ -87,42 +145,18 @@ type AssignRolesRequest struct {
 // @Param real_name query string false "真实姓名"
 // @Param role_id query int false "角色ID"
 // @Param status query string false "状态"
+// @Param online query bool false "是否在线"
+// @Param inactive_days query int false "超过天数未登录"
 // @Success 200 {object} models.Response{data=models.PaginatedList{items=[]models.UserInfo}} "获取成功"
 // @Router /api/v1/users [get]
 func (h *UserHandler) ListUsers(c *gin.Context) {
 	var query UserListQuery
-	if err := c.ShouldBindQuery(&query); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "查询参数错误"))
+	if !bindPageQuery(c, &query) {
 		return
 	}
 
-	// 设置默认分页参数
-	if query.Page <= 0 {
-		query.Page = 1
-	}
-	if query.PageSize <= 0 {
-		query.PageSize = 10
-	}
-
 	// 构建查询
-	db := database.DB.Model(&models.User{}).Preload("Role")
-
-	// 应用筛选条件
-	if query.Username != nil && *query.Username != "" {
-		db = db.Where("username LIKE ?", "%"+*query.Username+"%")
-	}
-	if query.Email != nil && *query.Email != "" {
-		db = db.Where("email LIKE ?", "%"+*query.Email+"%")
-	}
-	if query.RealName != nil && *query.RealName != "" {
-		db = db.Where("real_name LIKE ?", "%"+*query.RealName+"%")
-	}
-	if query.RoleID != nil {
-		db = db.Where("role_id = ?", *query.RoleID)
-	}
-	if query.Status != nil && *query.Status != "" {
-		db = db.Where("status = ?", *query.Status)
-	}
+	db := applyUserFilters(database.DB.Model(&models.User{}).Preload("Role"), &query)
 
 	// 获取总数
 	var total int64
@@ -134,7 +168,7 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 
 	// 分页查询
 	var users []models.User
-	offset := (query.Page - 1) * query.PageSize
+	offset := query.Offset()
 	if err := db.Offset(offset).Limit(query.PageSize).Order("created_at DESC").Find(&users).Error; err != nil {
 		h.logger.Error("Failed to list users", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
@@ -152,6 +186,98 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 	c.JSON(http.StatusOK, models.SuccessResponse(result))
 }
 
+// applyUserFilters 应用用户列表的筛选条件，列表和导出共用
+func applyUserFilters(db *gorm.DB, query *UserListQuery) *gorm.DB {
+	if query.Username != nil && *query.Username != "" {
+		db = db.Where("username LIKE ?", "%"+*query.Username+"%")
+	}
+	if query.Email != nil && *query.Email != "" {
+		db = db.Where("email LIKE ?", "%"+*query.Email+"%")
+	}
+	if query.RealName != nil && *query.RealName != "" {
+		db = db.Where("real_name LIKE ?", "%"+*query.RealName+"%")
+	}
+	if query.RoleID != nil {
+		db = db.Where("id IN (?)", database.DB.Model(&models.UserRole{}).Select("user_id").Where("role_id = ?", *query.RoleID))
+	}
+	if query.Status != nil && *query.Status != "" {
+		db = db.Where("status = ?", *query.Status)
+	}
+	now := time.Now()
+	if query.Online != nil {
+		if *query.Online {
+			db = db.Where("status = ? AND session_expires_at > ?", models.UserStatusActive, now)
+		} else {
+			db = db.Where("(status <> ? OR session_expires_at IS NULL OR session_expires_at <= ?)", models.UserStatusActive, now)
+		}
+	}
+	if query.InactiveDays != nil {
+		db = db.Where("(last_login_at IS NULL OR last_login_at < ?)", now.AddDate(0, 0, -*query.InactiveDays))
+	}
+	return db
+}
+
+// ExportUsers 导出用户列表
+// @Summary 导出用户列表
+// @Description 按列表查询条件全量导出用户为CSV或Excel，超过导出行数上限时返回400
+// @Tags 用户管理
+// @Produce octet-stream
+// @Security BearerAuth
+// @Param format query string false "导出格式 csv/xlsx" default(csv)
+// @Param username query string false "用户名"
+// @Param email query string false "邮箱"
+// @Param real_name query string false "真实姓名"
+// @Param role_id query int false "角色ID"
+// @Param status query string false "状态"
+// @Param online query bool false "是否在线"
+// @Param inactive_days query int false "超过天数未登录"
+// @Success 200 {file} file "导出文件"
+// @Failure 400 {object} models.Response "参数错误或超过导出上限"
+// @Router /api/v1/users/export [get]
+func (h *UserHandler) ExportUsers(c *gin.Context) {
+	var query UserListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &query))
+		return
+	}
+
+	var users []models.User
+	streamListExport(c, h.logger, applyUserFilters(database.DB.Model(&models.User{}).Preload("Roles"), &query), listExport{
+		Name:    "users",
+		Headers: []string{"ID", "用户名", "邮箱", "手机号", "真实姓名", "部门", "职位", "角色", "状态", "认证来源", "最后登录时间", "登录次数", "创建时间"},
+		Dest:    &users,
+		Rows: func() [][]string {
+			rows := make([][]string, 0, len(users))
+			for _, user := range users {
+				roleNames := make([]string, 0, len(user.Roles))
+				for _, role := range user.Roles {
+					roleNames = append(roleNames, role.Name)
+				}
+				status := "禁用"
+				if user.Status == 1 {
+					status = "激活"
+				}
+				rows = append(rows, []string{
+					strconv.FormatUint(uint64(user.ID), 10),
+					user.Username,
+					user.Email,
+					user.Phone,
+					user.RealName,
+					user.Department,
+					user.Position,
+					strings.Join(roleNames, ","),
+					status,
+					user.AuthSource,
+					formatExportTime(user.LastLoginAt),
+					strconv.Itoa(user.LoginCount),
+					formatExportTime(&user.CreatedAt),
+				})
+			}
+			return rows
+		},
+	})
+}
+
 // GetUser 获取用户详情
 // @Summary 获取用户详情
 // @Description 根据用户ID获取详细信息
@@ -172,7 +298,7 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 	var user models.User
 	if err := database.DB.Where("id = ?", id).Preload("Role").First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "用户不存在"))
+			c.JSON(http.StatusNotFound, models.CodeErrorResponse(models.CodeUserNotFound, ""))
 		} else {
 			h.logger.Error("Failed to get user", zap.Error(err))
 			c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
@@ -199,13 +325,13 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 func (h *UserHandler) CreateUser(c *gin.Context) {
 	var req CreateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "请求参数错误"))
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
 		return
 	}
 
 	// 验证密码强度
 	if err := h.passwordManager.ValidatePasswordStrength(req.Password); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "密码强度不足"))
+		c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeWeakPassword, ""))
 		return
 	}
 
@@ -213,9 +339,9 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 	var existingUser models.User
 	if err := database.DB.Where("username = ? OR email = ?", req.Username, req.Email).First(&existingUser).Error; err == nil {
 		if existingUser.Username == req.Username {
-			c.JSON(http.StatusConflict, models.ErrorResponse(http.StatusConflict, "用户名已存在"))
+			c.JSON(http.StatusConflict, models.CodeErrorResponse(models.CodeUsernameExists, ""))
 		} else {
-			c.JSON(http.StatusConflict, models.ErrorResponse(http.StatusConflict, "邮箱已存在"))
+			c.JSON(http.StatusConflict, models.CodeErrorResponse(models.CodeEmailExists, ""))
 		}
 		return
 	}
@@ -223,7 +349,7 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 	// 验证角色是否存在
 	var role models.Role
 	if err := database.DB.Where("id = ?", req.RoleID).First(&role).Error; err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "角色不存在"))
+		c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeRoleNotFound, ""))
 		return
 	}
 
@@ -245,7 +371,12 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 		Status:   models.UserStatusActive,
 	}
 
-	if err := database.DB.Create(&user).Error; err != nil {
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return h.passwordHistory.Record(tx, user.ID, hashedPassword)
+	}); err != nil {
 		h.logger.Error("Failed to create user", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "创建用户失败"))
 		return
@@ -293,7 +424,7 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 
 	var req UpdateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "请求参数错误"))
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
 		return
 	}
 
@@ -301,7 +432,7 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	var user models.User
 	if err := database.DB.Where("id = ?", id).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "用户不存在"))
+			c.JSON(http.StatusNotFound, models.CodeErrorResponse(models.CodeUserNotFound, ""))
 		} else {
 			h.logger.Error("Failed to find user", zap.Error(err))
 			c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
@@ -314,7 +445,7 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		// 检查邮箱是否已存在
 		var existingUser models.User
 		if err := database.DB.Where("email = ? AND id != ?", *req.Email, id).First(&existingUser).Error; err == nil {
-			c.JSON(http.StatusConflict, models.ErrorResponse(http.StatusConflict, "邮箱已存在"))
+			c.JSON(http.StatusConflict, models.CodeErrorResponse(models.CodeEmailExists, ""))
 			return
 		}
 		user.Email = *req.Email
@@ -329,7 +460,7 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		// 验证角色是否存在
 		var role models.Role
 		if err := database.DB.Where("id = ?", *req.RoleID).First(&role).Error; err != nil {
-			c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "角色不存在"))
+			c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeRoleNotFound, ""))
 			return
 		}
 
@@ -353,7 +484,7 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		case "inactive":
 			status = models.UserStatusInactive
 		default:
-			c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "状态值无效"))
+			c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeInvalidUserStatus, ""))
 			return
 		}
 		user.Status = status
@@ -397,7 +528,7 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 	// 获取当前用户ID，防止删除自己
 	currentUserID, _ := c.Get("user_id")
 	if currentUserID == uint(id) {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "不能删除自己"))
+		c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeCannotDeleteSelf, ""))
 		return
 	}
 
@@ -405,7 +536,7 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 	var user models.User
 	if err := database.DB.Where("id = ?", id).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "用户不存在"))
+			c.JSON(http.StatusNotFound, models.CodeErrorResponse(models.CodeUserNotFound, ""))
 		} else {
 			h.logger.Error("Failed to find user", zap.Error(err))
 			c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
@@ -446,13 +577,13 @@ func (h *UserHandler) ResetPassword(c *gin.Context) {
 
 	var req ResetPasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "请求参数错误"))
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
 		return
 	}
 
 	// 验证密码强度
 	if err := h.passwordManager.ValidatePasswordStrength(req.Password); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "密码强度不足"))
+		c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeWeakPassword, ""))
 		return
 	}
 
@@ -460,7 +591,7 @@ func (h *UserHandler) ResetPassword(c *gin.Context) {
 	var user models.User
 	if err := database.DB.Where("id = ?", id).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "用户不存在"))
+			c.JSON(http.StatusNotFound, models.CodeErrorResponse(models.CodeUserNotFound, ""))
 		} else {
 			h.logger.Error("Failed to find user", zap.Error(err))
 			c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
@@ -468,6 +599,17 @@ func (h *UserHandler) ResetPassword(c *gin.Context) {
 		return
 	}
 
+	// 检查是否重复使用近期密码
+	if err := h.passwordHistory.CheckReuse(user.ID, user.Password, req.Password); err != nil {
+		if err == services.ErrPasswordReused {
+			c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodePasswordReused, err.Error()))
+			return
+		}
+		h.logger.Error("Failed to check password history", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "密码历史校验失败"))
+		return
+	}
+
 	// 哈希新密码
 	hashedPassword, err := h.passwordManager.HashPassword(req.Password)
 	if err != nil {
@@ -476,9 +618,14 @@ func (h *UserHandler) ResetPassword(c *gin.Context) {
 		return
 	}
 
-	// 更新密码
+	// 更新密码并记录历史
 	user.Password = hashedPassword
-	if err := database.DB.Save(&user).Error; err != nil {
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+		return h.passwordHistory.Record(tx, user.ID, hashedPassword)
+	}); err != nil {
 		h.logger.Error("Failed to reset password", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "密码重置失败"))
 		return
@@ -488,6 +635,74 @@ func (h *UserHandler) ResetPassword(c *gin.Context) {
 	c.JSON(http.StatusOK, models.SuccessResponse(nil))
 }
 
+// UnlockUser 解除用户登录锁定
+// @Summary 解除用户登录锁定
+// @Description 管理员手动解除因连续登录失败导致的账户锁定
+// @Tags 用户管理
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} models.Response "解锁成功"
+// @Failure 404 {object} models.Response "用户不存在"
+// @Router /api/v1/users/{id}/unlock [post]
+func (h *UserHandler) UnlockUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "用户ID无效"))
+		return
+	}
+
+	// 查找用户
+	var user models.User
+	if err := database.DB.Where("id = ?", id).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.CodeErrorResponse(models.CodeUserNotFound, ""))
+		} else {
+			h.logger.Error("Failed to find user", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		}
+		return
+	}
+
+	// 已存在用户的锁定按用户ID记录，无论使用用户名还是邮箱登录
+	if err := h.loginGuard.Unlock(c.Request.Context(), auth.LoginAccount(user.ID, user.Username)); err != nil {
+		h.logger.Error("Failed to unlock user", zap.Uint("user_id", user.ID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "解锁失败"))
+		return
+	}
+
+	h.logger.Info("User unlocked", zap.Uint("user_id", user.ID), zap.Uint("operator_id", c.GetUint("user_id")))
+	c.JSON(http.StatusOK, models.SuccessResponse(nil))
+}
+
+// UnlockIP 解除IP登录锁定
+// @Summary 解除IP登录锁定
+// @Description 管理员手动解除因该IP连续登录失败导致的锁定，同时清除失败计数
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body UnlockIPRequest true "解锁IP请求"
+// @Success 200 {object} models.Response "解锁成功"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Router /api/v1/users/unlock-ip [post]
+func (h *UserHandler) UnlockIP(c *gin.Context) {
+	var req UnlockIPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
+		return
+	}
+
+	if err := h.loginGuard.UnlockIP(c.Request.Context(), req.IP); err != nil {
+		h.logger.Error("Failed to unlock ip", zap.String("ip", req.IP), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "解锁失败"))
+		return
+	}
+
+	h.logger.Info("Login ip unlocked", zap.String("ip", req.IP), zap.Uint("operator_id", c.GetUint("user_id")))
+	c.JSON(http.StatusOK, models.SuccessResponse(nil))
+}
+
 // ChangeUserStatus 修改用户状态
 // @Summary 修改用户状态
 // @Description 启用或禁用用户账户
@@ -516,14 +731,14 @@ func (h *UserHandler) ChangeUserStatus(c *gin.Context) {
 	case "inactive":
 		status = models.UserStatusInactive
 	default:
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "状态值无效"))
+		c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeInvalidUserStatus, ""))
 		return
 	}
 
 	// 获取当前用户ID，防止禁用自己
 	currentUserID, _ := c.Get("user_id")
 	if currentUserID == uint(id) && status == models.UserStatusInactive {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "不能禁用自己"))
+		c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeCannotDisableSelf, ""))
 		return
 	}
 
@@ -531,7 +746,7 @@ func (h *UserHandler) ChangeUserStatus(c *gin.Context) {
 	var user models.User
 	if err := database.DB.Where("id = ?", id).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "用户不存在"))
+			c.JSON(http.StatusNotFound, models.CodeErrorResponse(models.CodeUserNotFound, ""))
 		} else {
 			h.logger.Error("Failed to find user", zap.Error(err))
 			c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
@@ -615,14 +830,14 @@ func (h *UserHandler) GetCurrentUser(c *gin.Context) {
 	// 从中间件获取当前用户ID
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse(http.StatusUnauthorized, "未授权"))
+		c.JSON(http.StatusUnauthorized, models.CodeErrorResponse(models.CodeNotLoggedIn, ""))
 		return
 	}
 
 	var user models.User
 	if err := database.DB.Where("id = ?", userID).Preload("Role").First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "用户不存在"))
+			c.JSON(http.StatusNotFound, models.CodeErrorResponse(models.CodeUserNotFound, ""))
 		} else {
 			h.logger.Error("Failed to get current user", zap.Error(err))
 			c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
@@ -648,13 +863,13 @@ func (h *UserHandler) UpdateCurrentUser(c *gin.Context) {
 	// 从中间件获取当前用户ID
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse(http.StatusUnauthorized, "未授权"))
+		c.JSON(http.StatusUnauthorized, models.CodeErrorResponse(models.CodeNotLoggedIn, ""))
 		return
 	}
 
 	var req UpdateCurrentUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "请求参数错误"))
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
 		return
 	}
 
@@ -662,7 +877,7 @@ func (h *UserHandler) UpdateCurrentUser(c *gin.Context) {
 	var user models.User
 	if err := database.DB.Where("id = ?", userID).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "用户不存在"))
+			c.JSON(http.StatusNotFound, models.CodeErrorResponse(models.CodeUserNotFound, ""))
 		} else {
 			h.logger.Error("Failed to find current user", zap.Error(err))
 			c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
@@ -675,7 +890,7 @@ func (h *UserHandler) UpdateCurrentUser(c *gin.Context) {
 		// 检查邮箱是否已存在
 		var existingUser models.User
 		if err := database.DB.Where("email = ? AND id != ?", *req.Email, userID).First(&existingUser).Error; err == nil {
-			c.JSON(http.StatusConflict, models.ErrorResponse(http.StatusConflict, "邮箱已存在"))
+			c.JSON(http.StatusConflict, models.CodeErrorResponse(models.CodeEmailExists, ""))
 			return
 		}
 		user.Email = *req.Email
@@ -724,7 +939,7 @@ func (h *UserHandler) GetUserRoles(c *gin.Context) {
 	var user models.User
 	if err := database.DB.Where("id = ?", id).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "用户不存在"))
+			c.JSON(http.StatusNotFound, models.CodeErrorResponse(models.CodeUserNotFound, ""))
 		} else {
 			h.logger.Error("Failed to find user", zap.Error(err))
 			c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
@@ -766,7 +981,7 @@ func (h *UserHandler) AssignRoles(c *gin.Context) {
 
 	var req AssignRolesRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "请求参数错误"))
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
 		return
 	}
 
@@ -774,7 +989,7 @@ func (h *UserHandler) AssignRoles(c *gin.Context) {
 	var user models.User
 	if err := database.DB.Where("id = ?", id).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, models.ErrorResponse(http.StatusNotFound, "用户不存在"))
+			c.JSON(http.StatusNotFound, models.CodeErrorResponse(models.CodeUserNotFound, ""))
 		} else {
 			h.logger.Error("Failed to find user", zap.Error(err))
 			c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
@@ -791,7 +1006,7 @@ func (h *UserHandler) AssignRoles(c *gin.Context) {
 	}
 
 	if len(existingRoles) != len(req.RoleIDs) {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "存在无效的角色ID"))
+		c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeInvalidRoleIDs, ""))
 		return
 	}
 
@@ -834,4 +1049,459 @@ func (h *UserHandler) AssignRoles(c *gin.Context) {
 
 	h.logger.Info("User roles assigned successfully", zap.Uint("user_id", uint(id)), zap.Uints("role_ids", req.RoleIDs))
 	c.JSON(http.StatusOK, models.SuccessResponse(nil))
-}
\ No newline at end of file
+}
+
+// BatchAssignRoles 批量分配用户角色
+// @Summary 批量分配用户角色
+// @Description 为多个用户统一调整角色，mode为replace（默认，替换为指定角色）、add（追加）或remove（移除）。不存在的用户标记为失败，其余用户在同一事务内处理
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body BatchAssignRolesRequest true "批量分配角色请求"
+// @Success 200 {object} models.Response{data=BatchAssignRolesSummary} "处理完成"
+// @Failure 400 {object} models.Response "请求参数错误"
+// @Router /api/v1/users/roles/batch [put]
+func (h *UserHandler) BatchAssignRoles(c *gin.Context) {
+	var req BatchAssignRolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err, &req))
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = RoleAssignModeReplace
+	}
+
+	userIDs := uniqueUints(req.UserIDs)
+	roleIDs := uniqueUints(req.RoleIDs)
+	if len(userIDs) > maxBatchAssignUsers {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, fmt.Sprintf("单次最多处理%d个用户", maxBatchAssignUsers)))
+		return
+	}
+	if len(roleIDs) == 0 && req.Mode != RoleAssignModeReplace {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "角色ID不能为空"))
+		return
+	}
+
+	// 验证所有角色是否存在
+	if len(roleIDs) > 0 {
+		var roleCount int64
+		if err := database.DB.Model(&models.Role{}).Where("id IN ?", roleIDs).Count(&roleCount).Error; err != nil {
+			h.logger.Error("Failed to verify roles", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "角色验证失败"))
+			return
+		}
+		if int(roleCount) != len(roleIDs) {
+			c.JSON(http.StatusBadRequest, models.CodeErrorResponse(models.CodeInvalidRoleIDs, ""))
+			return
+		}
+	}
+
+	var users []models.User
+	if err := database.DB.Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		h.logger.Error("Failed to find users", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询失败"))
+		return
+	}
+	usersByID := make(map[uint]models.User, len(users))
+	for _, user := range users {
+		usersByID[user.ID] = user
+	}
+
+	summary := BatchAssignRolesSummary{Total: len(userIDs), Results: make([]BatchAssignRoleResult, 0, len(userIDs))}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, userID := range userIDs {
+			user, ok := usersByID[userID]
+			if !ok {
+				summary.Results = append(summary.Results, BatchAssignRoleResult{UserID: userID, Status: "failed", Message: "用户不存在"})
+				continue
+			}
+
+			added, removed, err := applyUserRoles(tx, userID, roleIDs, req.Mode)
+			if err != nil {
+				return fmt.Errorf("用户%d: %w", userID, err)
+			}
+			summary.Results = append(summary.Results, BatchAssignRoleResult{
+				UserID:   userID,
+				Username: user.Username,
+				Status:   "success",
+				Added:    added,
+				Removed:  removed,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		h.logger.Error("Failed to batch assign roles", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "批量分配角色失败，所有变更已回滚"))
+		return
+	}
+
+	for _, result := range summary.Results {
+		if result.Status == "success" {
+			summary.Success++
+		} else {
+			summary.Failed++
+		}
+	}
+
+	h.logger.Info("User roles batch assigned",
+		zap.String("mode", req.Mode),
+		zap.Uints("role_ids", roleIDs),
+		zap.Int("success", summary.Success),
+		zap.Int("failed", summary.Failed))
+	c.JSON(http.StatusOK, models.SuccessResponse(summary))
+}
+
+// applyUserRoles 按mode调整单个用户的角色关联，返回新增和移除的关联数
+func applyUserRoles(tx *gorm.DB, userID uint, roleIDs []uint, mode string) (int, int, error) {
+	var current []uint
+	if err := tx.Model(&models.UserRole{}).Where("user_id = ?", userID).Pluck("role_id", &current).Error; err != nil {
+		return 0, 0, err
+	}
+	toAdd, toRemove := diffUserRoles(current, roleIDs, mode)
+
+	if len(toRemove) > 0 {
+		if err := tx.Where("user_id = ? AND role_id IN ?", userID, toRemove).Delete(&models.UserRole{}).Error; err != nil {
+			return 0, 0, err
+		}
+	}
+	for _, roleID := range toAdd {
+		if err := tx.Create(&models.UserRole{UserID: userID, RoleID: roleID}).Error; err != nil {
+			return 0, 0, err
+		}
+	}
+	return len(toAdd), len(toRemove), nil
+}
+
+// diffUserRoles 根据当前角色、目标角色和分配方式计算需新增和移除的角色
+func diffUserRoles(current, roleIDs []uint, mode string) (toAdd, toRemove []uint) {
+	currentSet := make(map[uint]bool, len(current))
+	for _, id := range current {
+		currentSet[id] = true
+	}
+	targetSet := make(map[uint]bool, len(roleIDs))
+	for _, id := range roleIDs {
+		targetSet[id] = true
+	}
+
+	switch mode {
+	case RoleAssignModeAdd:
+		for _, id := range roleIDs {
+			if !currentSet[id] {
+				toAdd = append(toAdd, id)
+			}
+		}
+	case RoleAssignModeRemove:
+		for _, id := range roleIDs {
+			if currentSet[id] {
+				toRemove = append(toRemove, id)
+			}
+		}
+	default:
+		for _, id := range roleIDs {
+			if !currentSet[id] {
+				toAdd = append(toAdd, id)
+			}
+		}
+		for _, id := range current {
+			if !targetSet[id] {
+				toRemove = append(toRemove, id)
+			}
+		}
+	}
+	return toAdd, toRemove
+}
+
+// uniqueUints 去重并保持原有顺序
+func uniqueUints(values []uint) []uint {
+	seen := make(map[uint]bool, len(values))
+	result := make([]uint, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// maxImportUserRows 单次导入的最大用户数
+const maxImportUserRows = 1000
+
+// userImportColumns CSV表头与字段的对应关系，支持中英文表头
+var userImportColumns = map[string]string{
+	"username":  "username",
+	"用户名":       "username",
+	"email":     "email",
+	"邮箱":        "email",
+	"real_name": "real_name",
+	"真实姓名":      "real_name",
+	"role":      "role",
+	"角色":        "role",
+	"password":  "password",
+	"初始密码":      "password",
+}
+
+// userImportRow CSV中的一行用户数据
+type userImportRow struct {
+	Line     int
+	Username string
+	Email    string
+	RealName string
+	Role     string
+	Password string
+}
+
+// UserImportResult 单行导入结果
+type UserImportResult struct {
+	Line     int    `json:"line"`
+	Username string `json:"username"`
+	Status   string `json:"status"` // success, skipped, failed
+	Message  string `json:"message,omitempty"`
+}
+
+// UserImportSummary 批量导入汇总
+type UserImportSummary struct {
+	Total   int                `json:"total"`
+	Success int                `json:"success"`
+	Skipped int                `json:"skipped"`
+	Failed  int                `json:"failed"`
+	Results []UserImportResult `json:"results"`
+}
+
+// ImportUsers 通过CSV批量导入用户
+// @Summary 批量导入用户
+// @Description 上传CSV文件批量创建用户，表头为 username,email,real_name,role,password（支持中文表头）。逐行处理，单行失败不影响其他行，重复用户名跳过
+// @Tags 用户管理
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "CSV文件"
+// @Success 200 {object} models.Response{data=UserImportSummary} "导入完成"
+// @Failure 400 {object} models.Response "文件格式错误"
+// @Router /api/v1/users/import [post]
+func (h *UserHandler) ImportUsers(c *gin.Context) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "请上传CSV文件"))
+		return
+	}
+
+	if strings.ToLower(filepath.Ext(fileHeader.Filename)) != ".csv" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, "仅支持CSV文件"))
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		h.logger.Error("Failed to open import file", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "读取文件失败"))
+		return
+	}
+	defer file.Close()
+
+	rows, err := parseUserImportCSV(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	// 预加载角色，支持按角色编码或名称匹配
+	var roles []models.Role
+	if err := database.DB.Find(&roles).Error; err != nil {
+		h.logger.Error("Failed to load roles", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "查询角色失败"))
+		return
+	}
+	roleMap := make(map[string]uint, len(roles)*2)
+	for _, role := range roles {
+		roleMap[role.Code] = role.ID
+		roleMap[role.Name] = role.ID
+	}
+
+	summary := UserImportSummary{
+		Total:   len(rows),
+		Results: make([]UserImportResult, 0, len(rows)),
+	}
+	seen := make(map[string]bool, len(rows))
+
+	for _, row := range rows {
+		result := h.importUserRow(row, roleMap, seen)
+		switch result.Status {
+		case "success":
+			summary.Success++
+		case "skipped":
+			summary.Skipped++
+		default:
+			summary.Failed++
+		}
+		summary.Results = append(summary.Results, result)
+	}
+
+	h.logger.Info("Users imported",
+		zap.Int("total", summary.Total),
+		zap.Int("success", summary.Success),
+		zap.Int("skipped", summary.Skipped),
+		zap.Int("failed", summary.Failed),
+		zap.Uint("operator_id", c.GetUint("user_id")))
+
+	c.JSON(http.StatusOK, models.SuccessResponse(summary))
+}
+
+// importUserRow 校验并创建单个用户，每行使用独立事务，失败不影响其他行
+func (h *UserHandler) importUserRow(row userImportRow, roleMap map[string]uint, seen map[string]bool) UserImportResult {
+	result := UserImportResult{Line: row.Line, Username: row.Username}
+	fail := func(status, message string) UserImportResult {
+		result.Status = status
+		result.Message = message
+		return result
+	}
+
+	if row.Username == "" {
+		return fail("failed", "用户名、邮箱、真实姓名、角色、初始密码均不能为空")
+	}
+
+	// 文件内重复或库中已存在的用户名先于其他校验判断，统一计为跳过
+	if seen[row.Username] {
+		return fail("skipped", "文件中用户名重复")
+	}
+	seen[row.Username] = true
+
+	var existingUser models.User
+	if err := database.DB.Unscoped().Where("username = ?", row.Username).First(&existingUser).Error; err == nil {
+		return fail("skipped", "用户名已存在")
+	} else if err != gorm.ErrRecordNotFound {
+		h.logger.Error("Failed to check existing user", zap.Error(err))
+		return fail("failed", "查询用户失败")
+	}
+
+	// 基础校验
+	if row.Email == "" || row.RealName == "" || row.Role == "" || row.Password == "" {
+		return fail("failed", "用户名、邮箱、真实姓名、角色、初始密码均不能为空")
+	}
+	if _, err := mail.ParseAddress(row.Email); err != nil {
+		return fail("failed", "邮箱格式错误")
+	}
+	roleID, ok := roleMap[row.Role]
+	if !ok {
+		return fail("failed", fmt.Sprintf("角色不存在: %s", row.Role))
+	}
+	if err := h.passwordManager.ValidatePasswordStrength(row.Password); err != nil {
+		return fail("failed", fmt.Sprintf("密码强度不足: %v", err))
+	}
+
+	var emailOwner models.User
+	if err := database.DB.Unscoped().Where("email = ?", row.Email).First(&emailOwner).Error; err == nil {
+		return fail("failed", "邮箱已被其他用户使用")
+	} else if err != gorm.ErrRecordNotFound {
+		h.logger.Error("Failed to check existing user", zap.Error(err))
+		return fail("failed", "查询用户失败")
+	}
+
+	hashedPassword, err := h.passwordManager.HashPassword(row.Password)
+	if err != nil {
+		h.logger.Error("Failed to hash password", zap.Error(err))
+		return fail("failed", "密码加密失败")
+	}
+
+	user := models.User{
+		Username: row.Username,
+		Email:    row.Email,
+		RealName: row.RealName,
+		Password: hashedPassword,
+		Status:   models.UserStatusActive,
+	}
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&models.UserRole{UserID: user.ID, RoleID: roleID}).Error; err != nil {
+			return err
+		}
+		return h.passwordHistory.Record(tx, user.ID, hashedPassword)
+	}); err != nil {
+		h.logger.Error("Failed to import user", zap.String("username", row.Username), zap.Error(err))
+		return fail("failed", "创建用户失败")
+	}
+
+	result.Status = "success"
+	return result
+}
+
+// parseUserImportCSV 解析用户导入CSV，首行为表头
+func parseUserImportCSV(r io.Reader) ([]userImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("CSV文件为空")
+		}
+		return nil, fmt.Errorf("CSV表头解析失败: %v", err)
+	}
+
+	// 解析表头，去除UTF-8 BOM
+	columnIndex := make(map[string]int)
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		if field, ok := userImportColumns[strings.ToLower(name)]; ok {
+			columnIndex[field] = i
+		}
+	}
+	for _, field := range []string{"username", "email", "real_name", "role", "password"} {
+		if _, ok := columnIndex[field]; !ok {
+			return nil, fmt.Errorf("CSV缺少必需的列: %s", field)
+		}
+	}
+
+	value := func(record []string, field string) string {
+		idx := columnIndex[field]
+		if idx >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[idx])
+	}
+
+	var rows []userImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return nil, fmt.Errorf("第%d行解析失败: %v", parseErr.StartLine, parseErr.Err)
+			}
+			return nil, fmt.Errorf("CSV解析失败: %v", err)
+		}
+		// 按记录起始行号报告，带引号的字段中可以包含换行
+		line, _ := reader.FieldPos(0)
+
+		// 跳过空行
+		if len(strings.TrimSpace(strings.Join(record, ""))) == 0 {
+			continue
+		}
+
+		rows = append(rows, userImportRow{
+			Line:     line,
+			Username: value(record, "username"),
+			Email:    value(record, "email"),
+			RealName: value(record, "real_name"),
+			Role:     value(record, "role"),
+			Password: value(record, "password"),
+		})
+		if len(rows) > maxImportUserRows {
+			return nil, fmt.Errorf("单次最多导入%d个用户", maxImportUserRows)
+		}
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("CSV文件中没有用户数据")
+	}
+
+	return rows, nil
+}
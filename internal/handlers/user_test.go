@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/auth"
+	"github.com/env-data-platform/internal/config"
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/database/dbtest"
+	"github.com/env-data-platform/internal/models"
+)
+
+func TestDiffUserRoles(t *testing.T) {
+	current := []uint{1, 2, 3}
+
+	toAdd, toRemove := diffUserRoles(current, []uint{3, 4}, RoleAssignModeReplace)
+	assert.Equal(t, []uint{4}, toAdd)
+	assert.Equal(t, []uint{1, 2}, toRemove)
+
+	toAdd, toRemove = diffUserRoles(current, []uint{3, 4}, RoleAssignModeAdd)
+	assert.Equal(t, []uint{4}, toAdd)
+	assert.Empty(t, toRemove)
+
+	toAdd, toRemove = diffUserRoles(current, []uint{3, 4}, RoleAssignModeRemove)
+	assert.Empty(t, toAdd)
+	assert.Equal(t, []uint{3}, toRemove)
+
+	toAdd, toRemove = diffUserRoles(current, nil, RoleAssignModeReplace)
+	assert.Empty(t, toAdd)
+	assert.Equal(t, current, toRemove)
+}
+
+func TestUniqueUints(t *testing.T) {
+	assert.Equal(t, []uint{3, 1, 2}, uniqueUints([]uint{3, 1, 3, 2, 1}))
+	assert.Empty(t, uniqueUints(nil))
+}
+
+func TestParseUserImportCSV(t *testing.T) {
+	const header = "username,email,real_name,role,password\n"
+	tests := []struct {
+		name  string
+		input string
+		lines []int
+		users []string
+		err   string
+	}{
+		{
+			name:  "中文表头和BOM",
+			input: "\ufeff用户名,邮箱,真实姓名,角色,初始密码\nalice,a@example.com,Alice,admin,Passw0rd!\n",
+			lines: []int{2},
+			users: []string{"alice"},
+		},
+		{
+			name:  "跳过空行",
+			input: header + "alice,a@example.com,Alice,admin,Passw0rd!\n\n,,,,\nbob,b@example.com,Bob,admin,Passw0rd!\n",
+			lines: []int{2, 5},
+			users: []string{"alice", "bob"},
+		},
+		{
+			name:  "字段内换行按记录起始行计",
+			input: header + "alice,a@example.com,\"Alice\nSmith\",admin,Passw0rd!\nbob,b@example.com,Bob,admin,Passw0rd!\n",
+			lines: []int{2, 4},
+			users: []string{"alice", "bob"},
+		},
+		{name: "空文件", input: "", err: "CSV文件为空"},
+		{name: "缺少列", input: "username,email\nalice,a@example.com\n", err: "CSV缺少必需的列: real_name"},
+		{name: "没有数据", input: header, err: "CSV文件中没有用户数据"},
+		{name: "格式错误", input: header + "alice,a@example.com,Alice,admin,Passw0rd!\nbob,\"b@example.com,Bob\n", err: "第3行解析失败"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := parseUserImportCSV(strings.NewReader(tt.input))
+			if tt.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+				return
+			}
+			require.NoError(t, err)
+			var lines []int
+			var users []string
+			for _, row := range rows {
+				lines = append(lines, row.Line)
+				users = append(users, row.Username)
+			}
+			assert.Equal(t, tt.lines, lines)
+			assert.Equal(t, tt.users, users)
+		})
+	}
+}
+
+func TestImportUserRowDuplicatesSkippedBeforeValidation(t *testing.T) {
+	db := dbtest.New(t)
+	db.On(dbtest.KindQuery, func(tx *gorm.DB) {
+		// 库中已存在bob
+		if user, ok := tx.Statement.Dest.(*models.User); ok && tx.Statement.Vars[0] == "bob" {
+			user.ID = 2
+			user.Username = "bob"
+			return
+		}
+		tx.AddError(gorm.ErrRecordNotFound)
+	})
+	db.Replace(t, &database.DB)
+	h := &UserHandler{logger: zap.NewNop()}
+	seen := map[string]bool{"alice": true}
+
+	tests := []struct {
+		name    string
+		row     userImportRow
+		status  string
+		message string
+	}{
+		{name: "文件内重复且字段不全", row: userImportRow{Username: "alice"}, status: "skipped", message: "文件中用户名重复"},
+		{name: "库中已存在且邮箱错误", row: userImportRow{Username: "bob", Email: "bad"}, status: "skipped", message: "用户名已存在"},
+		{name: "用户名为空", row: userImportRow{Email: "c@example.com"}, status: "failed"},
+		{name: "新用户邮箱错误", row: userImportRow{Username: "carol", Email: "bad", RealName: "Carol", Role: "admin", Password: "x"}, status: "failed", message: "邮箱格式错误"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := h.importUserRow(tt.row, nil, seen)
+			assert.Equal(t, tt.status, result.Status)
+			if tt.message != "" {
+				assert.Equal(t, tt.message, result.Message)
+			}
+		})
+	}
+}
+
+func TestUnlockIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &UserHandler{logger: zap.NewNop(), loginGuard: auth.NewLoginGuard(nil, config.LoginSecurityConfig{Enabled: true})}
+
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{name: "缺少IP", body: `{}`, expected: http.StatusBadRequest},
+		{name: "IP格式错误", body: `{"ip":"10.0.0"}`, expected: http.StatusBadRequest},
+		// 未启用Redis时无法解锁
+		{name: "Redis未启用", body: `{"ip":"10.0.0.1"}`, expected: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/users/unlock-ip", strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			h.UnlockIP(c)
+			assert.Equal(t, tt.expected, w.Code)
+		})
+	}
+}
@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/env-data-platform/internal/models"
+)
+
+// FieldError 字段级参数校验错误
+type FieldError struct {
+	Field   string `json:"field"`   // 请求中的字段路径，如 roles[0].name
+	Label   string `json:"label"`   // 字段中文名
+	Rule    string `json:"rule"`    // 未通过的校验规则，如 required、max
+	Message string `json:"message"` // 中文提示
+}
+
+// ValidationErrorData 参数校验失败时响应的data
+type ValidationErrorData struct {
+	Errors []FieldError `json:"errors"`
+}
+
+// fieldLabels 常用字段的中文名，请求结构体字段可用label标签覆盖
+var fieldLabels = map[string]string{
+	"username":         "用户名",
+	"password":         "密码",
+	"old_password":     "原密码",
+	"new_password":     "新密码",
+	"email":            "邮箱",
+	"phone":            "手机号",
+	"real_name":        "姓名",
+	"nickname":         "昵称",
+	"status":           "状态",
+	"name":             "名称",
+	"code":             "编码",
+	"type":             "类型",
+	"description":      "描述",
+	"remark":           "备注",
+	"role_ids":         "角色",
+	"role_id":          "角色",
+	"user_ids":         "用户",
+	"permission_ids":   "权限",
+	"parent_id":        "上级",
+	"sort":             "排序",
+	"order":            "排序方向",
+	"page":             "页码",
+	"page_size":        "每页数量",
+	"keyword":          "关键词",
+	"token":            "令牌",
+	"host":             "主机",
+	"port":             "端口",
+	"database":         "数据库",
+	"config":           "配置",
+	"source_id":        "源数据源",
+	"target_id":        "目标数据源",
+	"data_source_id":   "数据源",
+	"cron_expr":        "定时表达式",
+	"timeout":          "超时时间",
+	"table_name":       "表名",
+	"target_table":     "表名",
+	"column_name":      "列名",
+	"threshold":        "阈值",
+	"priority":         "优先级",
+	"weight":           "权重",
+	"start_time":       "开始时间",
+	"end_time":         "结束时间",
+	"device_id":        "设备编号",
+	"factor_code":      "因子编码",
+	"alarm_level":      "告警级别",
+	"parameters":       "参数",
+	"transformations":  "转换步骤",
+	"schedule_enabled": "启用定时",
+	"pol_id":           "污染物因子",
+	"system_time":      "设备时间",
+	"interval":         "间隔",
+	"limits":           "门限值",
+}
+
+// bindErrorResponse 把ShouldBind*返回的错误转成带字段级错误列表的响应，obj为绑定目标，用于解析字段名
+func bindErrorResponse(err error, obj interface{}) *models.Response {
+	errs := fieldErrors(err, obj)
+	if len(errs) == 0 {
+		message := "请求参数错误"
+		if errors.Is(err, io.EOF) {
+			message = "请求体不能为空"
+		}
+		return models.ErrorResponse(http.StatusBadRequest, message)
+	}
+
+	resp := models.ErrorResponse(http.StatusBadRequest, "参数校验失败: "+errs[0].Message)
+	resp.Data = ValidationErrorData{Errors: errs}
+	return resp
+}
+
+// fieldErrors 解析validator和JSON解码错误，其他错误返回nil
+func fieldErrors(err error, obj interface{}) []FieldError {
+	root := reflect.TypeOf(obj)
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		result := make([]FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			// StructNamespace形如 CreateUserRequest.Roles[0].Name，首段为类型名
+			path := strings.Split(fe.StructNamespace(), ".")[1:]
+			field, label := resolveField(root, path, goFieldName)
+			result = append(result, FieldError{
+				Field:   field,
+				Label:   label,
+				Rule:    fe.Tag(),
+				Message: label + ruleMessage(fe),
+			})
+		}
+		return result
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		field, label := resolveField(root, jsonPath(typeErr.Field), jsonFieldName)
+		return []FieldError{{
+			Field:   field,
+			Label:   label,
+			Rule:    "type",
+			Message: fmt.Sprintf("%s类型错误，应为%s", label, typeName(typeErr.Type)),
+		}}
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return []FieldError{{Rule: "json", Message: fmt.Sprintf("请求体不是合法的JSON（第%d字节附近）", syntaxErr.Offset)}}
+	}
+	return nil
+}
+
+// jsonPath 把JSON解码错误中的路径（如 items.0.value）转成与校验错误一致的分段（items[0]、value）
+func jsonPath(field string) []string {
+	var path []string
+	for _, segment := range strings.Split(field, ".") {
+		if _, err := strconv.Atoi(segment); err == nil && len(path) > 0 {
+			path[len(path)-1] += "[" + segment + "]"
+			continue
+		}
+		path = append(path, segment)
+	}
+	return path
+}
+
+// 字段匹配方式：validator给出Go字段名，JSON解码错误给出json字段名
+const (
+	goFieldName = iota
+	jsonFieldName
+)
+
+// resolveField 沿路径在结构体类型中查找字段，返回请求中的字段路径和末级字段的中文名
+func resolveField(t reflect.Type, path []string, match int) (string, string) {
+	names := make([]string, 0, len(path))
+	label := ""
+	for _, segment := range path {
+		name, index := segment, ""
+		if i := strings.IndexByte(segment, '['); i >= 0 {
+			name, index = segment[:i], segment[i:]
+		}
+
+		t = elemType(t)
+		var field reflect.StructField
+		found := false
+		if t != nil && t.Kind() == reflect.Struct {
+			if match == goFieldName {
+				field, found = t.FieldByName(name)
+			} else {
+				field, found = fieldByRequestName(t, name)
+			}
+		}
+		if !found {
+			// 类型信息不可用时按原样输出剩余路径
+			names = append(names, segment)
+			label = requestLabel(name, "")
+			t = nil
+			continue
+		}
+
+		t = field.Type
+		if field.Anonymous && requestTagName(field) == "" {
+			// 匿名嵌入的结构体（如分页参数）的字段在请求中与外层字段平级
+			continue
+		}
+		requestName := requestFieldName(field)
+		names = append(names, requestName+index)
+		label = requestLabel(requestName, field.Tag.Get("label"))
+		if index != "" {
+			t = elemType(t)
+			if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map) {
+				t = t.Elem()
+			}
+		}
+	}
+	return strings.Join(names, "."), label
+}
+
+// elemType 去掉指针
+func elemType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// fieldByRequestName 按json/form字段名查找字段，包含匿名嵌入结构体中的字段
+func fieldByRequestName(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && requestTagName(field) == "" {
+			if inner := elemType(field.Type); inner != nil && inner.Kind() == reflect.Struct {
+				if f, ok := fieldByRequestName(inner, name); ok {
+					return f, true
+				}
+			}
+			continue
+		}
+		if requestFieldName(field) == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// requestTagName json标签中的字段名，没有时取form标签
+func requestTagName(field reflect.StructField) string {
+	for _, key := range []string{"json", "form"} {
+		name := strings.Split(field.Tag.Get(key), ",")[0]
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return ""
+}
+
+// requestFieldName 请求中的字段名，未设置标签时为Go字段名
+func requestFieldName(field reflect.StructField) string {
+	if name := requestTagName(field); name != "" {
+		return name
+	}
+	return field.Name
+}
+
+// requestLabel 字段中文名：label标签优先，其次常用字段表，最后为字段名本身
+func requestLabel(name, tag string) string {
+	if tag != "" {
+		return tag
+	}
+	if label, ok := fieldLabels[name]; ok {
+		return label
+	}
+	return name
+}
+
+// ruleMessage 校验规则对应的中文原因
+func ruleMessage(fe validator.FieldError) string {
+	param := fe.Param()
+	sized := fe.Kind() == reflect.String || fe.Kind() == reflect.Slice || fe.Kind() == reflect.Array || fe.Kind() == reflect.Map
+	unit := "个字符"
+	if fe.Kind() != reflect.String {
+		unit = "项"
+	}
+
+	switch fe.Tag() {
+	case "required", "required_if", "required_with", "required_without":
+		return "不能为空"
+	case "min":
+		if sized {
+			return fmt.Sprintf("不能少于%s%s", param, unit)
+		}
+		return "不能小于" + param
+	case "max":
+		if sized {
+			return fmt.Sprintf("不能超过%s%s", param, unit)
+		}
+		return "不能大于" + param
+	case "len":
+		if sized {
+			return fmt.Sprintf("必须为%s%s", param, unit)
+		}
+		return "必须等于" + param
+	case "gt":
+		return "必须大于" + param
+	case "gte":
+		return "不能小于" + param
+	case "lt":
+		return "必须小于" + param
+	case "lte":
+		return "不能大于" + param
+	case "oneof":
+		return "必须是以下值之一: " + strings.Join(strings.Fields(param), "、")
+	case "email":
+		return "格式不正确，应为邮箱地址"
+	case "url":
+		return "格式不正确，应为URL"
+	case "ip", "ipv4", "ipv6":
+		return "格式不正确，应为IP地址"
+	case "numeric", "number":
+		return "必须为数字"
+	case "alphanum":
+		return "只能包含字母和数字"
+	case "dive":
+		return "中存在不合法的元素"
+	case "eqfield":
+		return "与" + param + "不一致"
+	case "nefield":
+		return "不能与" + param + "相同"
+	default:
+		return fmt.Sprintf("不符合校验规则(%s)", fe.Tag())
+	}
+}
+
+// typeName JSON解码期望的类型
+func typeName(t reflect.Type) string {
+	switch elemType(t).Kind() {
+	case reflect.String:
+		return "字符串"
+	case reflect.Bool:
+		return "布尔值"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "整数"
+	case reflect.Float32, reflect.Float64:
+		return "数字"
+	case reflect.Slice, reflect.Array:
+		return "数组"
+	case reflect.Map, reflect.Struct:
+		return "对象"
+	default:
+		return t.String()
+	}
+}
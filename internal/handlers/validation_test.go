@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type validationItem struct {
+	Name  string `json:"name" binding:"required"`
+	Value int    `json:"value" binding:"gte=0" label:"数值"`
+}
+
+type validationRequest struct {
+	Username string           `json:"username" binding:"required,min=3"`
+	Email    string           `json:"email" binding:"omitempty,email"`
+	Mode     string           `json:"mode" binding:"omitempty,oneof=full incremental" label:"同步模式"`
+	Items    []validationItem `json:"items" binding:"max=2,dive"`
+}
+
+// bindJSON 用gin的JSON绑定解析请求体并返回错误响应
+func bindJSON(t *testing.T, body string) (*validationRequest, error) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	var req validationRequest
+	return &req, c.ShouldBindJSON(&req)
+}
+
+func TestBindErrorResponse(t *testing.T) {
+	req, err := bindJSON(t, `{"username":"ab","email":"x","mode":"all","items":[{"name":"","value":-1}]}`)
+	require.Error(t, err)
+	resp := bindErrorResponse(err, req)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Equal(t, "参数校验失败: 用户名不能少于3个字符", resp.Message)
+
+	data, ok := resp.Data.(ValidationErrorData)
+	require.True(t, ok)
+	assert.Equal(t, []FieldError{
+		{Field: "username", Label: "用户名", Rule: "min", Message: "用户名不能少于3个字符"},
+		{Field: "email", Label: "邮箱", Rule: "email", Message: "邮箱格式不正确，应为邮箱地址"},
+		{Field: "mode", Label: "同步模式", Rule: "oneof", Message: "同步模式必须是以下值之一: full、incremental"},
+		{Field: "items[0].name", Label: "名称", Rule: "required", Message: "名称不能为空"},
+		{Field: "items[0].value", Label: "数值", Rule: "gte", Message: "数值不能小于0"},
+	}, data.Errors)
+
+	req, err = bindJSON(t, `{"username":"admin","items":[{"name":"a","value":"x"}]}`)
+	require.Error(t, err)
+	data = bindErrorResponse(err, req).Data.(ValidationErrorData)
+	assert.Equal(t, "items[0].value", data.Errors[0].Field)
+	assert.Equal(t, "数值类型错误，应为整数", data.Errors[0].Message)
+
+	req, err = bindJSON(t, `{"username":`)
+	require.Error(t, err)
+	assert.Contains(t, bindErrorResponse(err, req).Message, "请求")
+
+	req, err = bindJSON(t, ``)
+	require.Error(t, err)
+	assert.Equal(t, "请求体不能为空", bindErrorResponse(err, req).Message)
+}
@@ -0,0 +1,181 @@
+package hj212
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/env-data-platform/internal/config"
+	"go.uber.org/zap"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// 报文方向
+const (
+	archiveReceived = "RX"
+	archiveSent     = "TX"
+)
+
+// archiveUnknownMN 无法识别MN的报文（如解析失败的包）归档到该目录
+const archiveUnknownMN = "unknown"
+
+var (
+	rawMNRegex     = regexp.MustCompile(`MN=([^;&]*)`)
+	unsafeNameRune = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+)
+
+// packetArchive 原始报文归档，每个设备每天一个文件，文件超过大小上限时由lumberjack轮转
+//
+// 每行格式：时间戳 方向 对端地址 报文，报文去掉结尾的\r\n；
+// 含其他控制字符的报文以 "hex:" 前缀加十六进制写入，保证可以还原原始字节。
+type packetArchive struct {
+	cfg     config.HJ212ArchiveConfig
+	logger  *zap.Logger
+	now     func() time.Time
+	mu      sync.Mutex
+	writers map[string]*archiveWriter
+}
+
+// archiveWriter 单个设备当天的归档文件
+type archiveWriter struct {
+	day  string
+	file *lumberjack.Logger
+}
+
+// newPacketArchive 未开启归档时返回nil，nil的归档器上所有操作均为空操作
+func newPacketArchive(cfg config.HJ212ArchiveConfig, logger *zap.Logger) *packetArchive {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &packetArchive{
+		cfg:     cfg,
+		logger:  logger,
+		now:     time.Now,
+		writers: make(map[string]*archiveWriter),
+	}
+}
+
+// record 归档一个收发的原始报文，mn为空时从报文中提取
+func (a *packetArchive) record(direction, mn, remote string, data []byte) {
+	if a == nil {
+		return
+	}
+	if mn == "" {
+		mn = extractMN(data)
+	}
+
+	now := a.now()
+	line := fmt.Sprintf("%s %s %s %s\n",
+		now.Format("2006-01-02T15:04:05.000Z07:00"), direction, remote, encodeArchivePayload(data))
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, err := a.writer(mn, now).Write([]byte(line)); err != nil {
+		a.logger.Warn("Failed to archive HJ212 packet", zap.String("mn", mn), zap.Error(err))
+	}
+}
+
+// writer 返回设备当天的归档文件，跨天时关闭前一天的文件，调用方需持有锁
+func (a *packetArchive) writer(mn string, now time.Time) *lumberjack.Logger {
+	dirName := archiveDirName(mn)
+	day := now.Format("2006-01-02")
+	if w, ok := a.writers[dirName]; ok {
+		if w.day == day {
+			return w.file
+		}
+		w.file.Close()
+	}
+
+	file := &lumberjack.Logger{
+		Filename:  filepath.Join(a.cfg.Dir, dirName, day+".log"),
+		MaxSize:   a.cfg.MaxSize,
+		Compress:  a.cfg.Compress,
+		LocalTime: true,
+	}
+	a.writers[dirName] = &archiveWriter{day: day, file: file}
+	return file
+}
+
+// cleanup 关闭非当天的文件并删除超过保留天数的归档
+func (a *packetArchive) cleanup() {
+	if a == nil {
+		return
+	}
+	now := a.now()
+
+	a.mu.Lock()
+	today := now.Format("2006-01-02")
+	for mn, w := range a.writers {
+		if w.day != today {
+			w.file.Close()
+			delete(a.writers, mn)
+		}
+	}
+	a.mu.Unlock()
+
+	if a.cfg.MaxAge <= 0 {
+		return
+	}
+	cutoff := now.AddDate(0, 0, -a.cfg.MaxAge)
+	err := filepath.Walk(a.cfg.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !info.IsDir() && info.ModTime().Before(cutoff) {
+			if err := os.Remove(path); err != nil {
+				a.logger.Warn("Failed to remove expired HJ212 archive", zap.String("file", path), zap.Error(err))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		a.logger.Warn("Failed to clean up HJ212 archive", zap.Error(err))
+	}
+}
+
+// Close 关闭所有归档文件
+func (a *packetArchive) Close() {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for mn, w := range a.writers {
+		w.file.Close()
+		delete(a.writers, mn)
+	}
+}
+
+// extractMN 从原始报文中提取MN，无法提取时返回空字符串
+func extractMN(data []byte) string {
+	if m := rawMNRegex.FindSubmatch(data); m != nil {
+		return string(m[1])
+	}
+	return ""
+}
+
+// archiveDirName 设备归档目录名，过滤MN中不能用于路径的字符
+func archiveDirName(mn string) string {
+	if mn == "" {
+		return archiveUnknownMN
+	}
+	return unsafeNameRune.ReplaceAllString(mn, "_")
+}
+
+// encodeArchivePayload 去掉包尾的\r\n，含其他控制字符时改用十六进制
+func encodeArchivePayload(data []byte) string {
+	payload := bytes.TrimSuffix(data, []byte("\r\n"))
+	for _, b := range payload {
+		if b < 0x20 || b == 0x7f {
+			return "hex:" + hex.EncodeToString(data)
+		}
+	}
+	return string(payload)
+}
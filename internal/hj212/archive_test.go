@@ -0,0 +1,64 @@
+package hj212
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/env-data-platform/internal/config"
+)
+
+func TestPacketArchive(t *testing.T) {
+	assert.Nil(t, newPacketArchive(config.HJ212ArchiveConfig{}, zap.NewNop()))
+	// 未开启时调用为空操作
+	var disabled *packetArchive
+	disabled.record(archiveReceived, "MN001", "127.0.0.1:1", []byte("##"))
+	disabled.cleanup()
+	disabled.Close()
+
+	dir := t.TempDir()
+	archive := newPacketArchive(config.HJ212ArchiveConfig{Enabled: true, Dir: dir, MaxAge: 7}, zap.NewNop())
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local)
+	archive.now = func() time.Time { return now }
+
+	raw := []byte("##0101QN=20240501100000000;ST=22;CN=2011;PW=123456;MN=MN/001;Flag=5;CP=&&a34004-Rtd=35.5&&1C80\r\n")
+	archive.record(archiveReceived, "", "10.0.0.1:5000", raw)
+	archive.record(archiveSent, "MN/001", "10.0.0.1:5000", []byte("##0050ST=91;CN=9014\r\n"))
+	archive.record(archiveReceived, "", "10.0.0.2:5000", []byte("garbage\x00\r\n"))
+
+	// 跨天后写入新文件
+	now = now.Add(24 * time.Hour)
+	archive.record(archiveReceived, "MN/001", "10.0.0.1:5000", raw)
+	archive.Close()
+
+	data, err := os.ReadFile(filepath.Join(dir, "MN_001", "2024-05-01.log"))
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	assert.True(t, strings.HasPrefix(lines[0], "2024-05-01T10:00:00.000"))
+	assert.Contains(t, lines[0], " RX 10.0.0.1:5000 ##0101QN=")
+	assert.True(t, strings.HasSuffix(lines[0], "&&1C80"))
+	assert.Contains(t, lines[1], " TX 10.0.0.1:5000 ##0050ST=91;CN=9014")
+
+	data, err = os.ReadFile(filepath.Join(dir, archiveUnknownMN, "2024-05-01.log"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "hex:67617262616765000d0a")
+
+	_, err = os.Stat(filepath.Join(dir, "MN_001", "2024-05-02.log"))
+	require.NoError(t, err)
+
+	// 超过保留天数的归档被清理
+	old := filepath.Join(dir, "MN_001", "2024-05-01.log")
+	require.NoError(t, os.Chtimes(old, now.AddDate(0, 0, -8), now.AddDate(0, 0, -8)))
+	archive.cleanup()
+	_, err = os.Stat(old)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(dir, "MN_001", "2024-05-02.log"))
+	assert.NoError(t, err)
+}
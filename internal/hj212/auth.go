@@ -0,0 +1,110 @@
+package hj212
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/env-data-platform/internal/config"
+	"github.com/env-data-platform/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 白名单未命中时重新加载的最小间隔，避免非法设备频繁触发查库
+const deviceReloadMinInterval = 5 * time.Second
+
+// 非法接入原因
+const (
+	authReasonUnregistered  = "unregistered"
+	authReasonWrongPassword = "wrong_password"
+)
+
+// deviceAuthenticator 设备接入鉴权，白名单为状态为active的hj212数据源，MN对应数据源的device_id，
+// 密码取数据源配置中的password，未配置密码的设备只校验MN
+type deviceAuthenticator struct {
+	cfg    config.HJ212AuthConfig
+	db     *gorm.DB
+	logger *zap.Logger
+
+	mu       sync.Mutex
+	devices  map[string]string
+	loadedAt time.Time
+}
+
+// newDeviceAuthenticator 创建设备鉴权器
+func newDeviceAuthenticator(cfg config.HJ212AuthConfig, db *gorm.DB, logger *zap.Logger) *deviceAuthenticator {
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = time.Minute
+	}
+	return &deviceAuthenticator{cfg: cfg, db: db, logger: logger}
+}
+
+// authorize 校验数据包的MN和PW，通过时返回空字符串，否则返回ExeRtn和原因
+func (a *deviceAuthenticator) authorize(packet *Packet) (string, string) {
+	if !a.cfg.Enabled {
+		return "", ""
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	since := time.Since(a.loadedAt)
+	_, known := a.devices[packet.MN]
+	// 到期刷新；新登记的设备未命中时也提前刷新，使白名单变更尽快生效
+	if a.devices == nil || since >= a.cfg.RefreshInterval || (!known && since >= deviceReloadMinInterval) {
+		a.reload()
+	}
+
+	return checkDevice(a.devices, packet.MN, packet.PW, a.cfg.CheckPassword)
+}
+
+// reload 从数据源表重新加载白名单，失败时保留旧白名单
+func (a *deviceAuthenticator) reload() {
+	a.loadedAt = time.Now()
+	if a.db == nil {
+		if a.devices == nil {
+			a.devices = map[string]string{}
+		}
+		return
+	}
+
+	var sources []models.DataSource
+	err := a.db.Select("device_id", "config").
+		Where("type = ? AND status = ? AND device_id <> ''", models.DataSourceTypeHJ212, "active").
+		Find(&sources).Error
+	if err != nil {
+		a.logger.Error("Failed to load HJ212 device whitelist", zap.Error(err))
+		if a.devices == nil {
+			a.devices = map[string]string{}
+		}
+		return
+	}
+
+	devices := make(map[string]string, len(sources))
+	for _, source := range sources {
+		var cfg models.DataSourceConfig
+		if source.Config != "" {
+			if err := json.Unmarshal([]byte(source.Config), &cfg); err != nil {
+				a.logger.Warn("Invalid HJ212 data source config",
+					zap.String("mn", source.DeviceID),
+					zap.Error(err))
+			}
+		}
+		devices[source.DeviceID] = cfg.Password
+	}
+	a.devices = devices
+}
+
+// checkDevice 按白名单校验MN和PW
+func checkDevice(devices map[string]string, mn, pw string, checkPassword bool) (string, string) {
+	password, ok := devices[mn]
+	if !ok {
+		return ExeRtn_InvalidMN, authReasonUnregistered
+	}
+	if checkPassword && password != "" && subtle.ConstantTimeCompare([]byte(password), []byte(pw)) != 1 {
+		return ExeRtn_InvalidPassword, authReasonWrongPassword
+	}
+	return "", ""
+}
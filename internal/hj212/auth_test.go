@@ -0,0 +1,50 @@
+package hj212
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/env-data-platform/internal/config"
+)
+
+func TestCheckDevice(t *testing.T) {
+	devices := map[string]string{
+		"88888880000001": "123456",
+		"88888880000002": "",
+	}
+
+	tests := []struct {
+		name          string
+		mn, pw        string
+		checkPassword bool
+		wantRtn       string
+		wantReason    string
+	}{
+		{"registered", "88888880000001", "123456", true, "", ""},
+		{"unregistered", "99999990000001", "123456", true, ExeRtn_InvalidMN, authReasonUnregistered},
+		{"wrong password", "88888880000001", "654321", true, ExeRtn_InvalidPassword, authReasonWrongPassword},
+		{"password check disabled", "88888880000001", "654321", false, "", ""},
+		{"no password configured", "88888880000002", "anything", true, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rtn, reason := checkDevice(devices, tt.mn, tt.pw, tt.checkPassword)
+			assert.Equal(t, tt.wantRtn, rtn)
+			assert.Equal(t, tt.wantReason, reason)
+		})
+	}
+}
+
+func TestDeviceAuthenticatorDisabled(t *testing.T) {
+	auth := newDeviceAuthenticator(config.HJ212AuthConfig{}, nil, zap.NewNop())
+	rtn, _ := auth.authorize(&Packet{MN: "99999990000001"})
+	assert.Empty(t, rtn)
+
+	auth = newDeviceAuthenticator(config.HJ212AuthConfig{Enabled: true}, nil, zap.NewNop())
+	rtn, reason := auth.authorize(&Packet{MN: "99999990000001"})
+	assert.Equal(t, ExeRtn_InvalidMN, rtn)
+	assert.Equal(t, authReasonUnregistered, reason)
+}
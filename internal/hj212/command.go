@@ -0,0 +1,155 @@
+package hj212
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// CommandResult 设备对下发命令的应答结果
+type CommandResult struct {
+	QN      string `json:"qn"`
+	QnRtn   string `json:"qn_rtn"`   // 请求应答结果，1表示准备执行
+	ExeRtn  string `json:"exe_rtn"`  // 执行结果
+	RtnInfo string `json:"rtn_info"` // 返回信息
+	Packets int    `json:"packets"`  // 执行期间设备以该QN上传的数据包数
+	// Data 提取类命令（如提取现场机时间）设备以该QN上传的参数
+	Data map[string]string `json:"data,omitempty"`
+}
+
+// Succeeded 设备是否执行成功
+func (r *CommandResult) Succeeded() bool {
+	return r.ExeRtn == ExeRtn_Success
+}
+
+// pendingCommand 等待设备应答的命令，按QN匹配
+type pendingCommand struct {
+	mn     string
+	result CommandResult
+	done   chan struct{}
+	once   sync.Once
+}
+
+// finish 标记命令结束
+func (p *pendingCommand) finish() {
+	p.once.Do(func() { close(p.done) })
+}
+
+// commandTracker 跟踪已下发命令的应答
+type commandTracker struct {
+	mu      sync.Mutex
+	pending map[string]*pendingCommand
+}
+
+func newCommandTracker() *commandTracker {
+	return &commandTracker{pending: make(map[string]*pendingCommand)}
+}
+
+// register 登记等待应答的命令
+func (t *commandTracker) register(mn, qn string) *pendingCommand {
+	cmd := &pendingCommand{mn: mn, result: CommandResult{QN: qn}, done: make(chan struct{})}
+	t.mu.Lock()
+	t.pending[qn] = cmd
+	t.mu.Unlock()
+	return cmd
+}
+
+// remove 移除命令
+func (t *commandTracker) remove(qn string) {
+	t.mu.Lock()
+	delete(t.pending, qn)
+	t.mu.Unlock()
+}
+
+// match 将设备上传的包与等待中的命令匹配：9011/9012的CP中QN为请求编号，
+// 命令执行期间上传的数据包沿用请求的QN。返回数据包是否属于某条命令
+func (t *commandTracker) match(packet *Packet) bool {
+	qn := packet.QN
+	if packet.CN == CN_Response || packet.CN == CN_ExecuteResponse {
+		qn = packet.DataArea["QN"]
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	cmd, ok := t.pending[qn]
+	if !ok || cmd.mn != packet.MN {
+		return false
+	}
+
+	switch packet.CN {
+	case CN_Response:
+		cmd.result.QnRtn = packet.QnRtn
+		if packet.QnRtn != QnRtn_Ready {
+			cmd.finish()
+		}
+	case CN_ExecuteResponse:
+		cmd.result.ExeRtn = packet.ExeRtn
+		cmd.result.RtnInfo = packet.RtnInfo
+		cmd.finish()
+	default:
+		switch {
+		case IsDataCommand(packet.CN):
+			cmd.result.Packets++
+		case IsParamCommand(packet.CN) || IsControlCommand(packet.CN):
+			if cmd.result.Data == nil {
+				cmd.result.Data = make(map[string]string, len(packet.DataArea))
+			}
+			for key, value := range packet.DataArea {
+				cmd.result.Data[key] = value
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// execute 登记命令并经send下发，等待设备执行结果（CN 9012），ctx控制等待时长
+func (t *commandTracker) execute(ctx context.Context, deviceMN string, packet *Packet, send func(*Packet) error) (*CommandResult, error) {
+	if packet.QN == "" {
+		packet.QN = GenerateQN()
+	}
+	packet.MN = deviceMN
+	packet.Flag |= Flag_Confirm
+
+	cmd := t.register(deviceMN, packet.QN)
+	defer t.remove(packet.QN)
+
+	if err := send(packet); err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-cmd.done:
+	case <-ctx.Done():
+		t.mu.Lock()
+		result := cmd.result
+		t.mu.Unlock()
+		return &result, fmt.Errorf("waiting for device %s response: %w", deviceMN, ctx.Err())
+	}
+
+	t.mu.Lock()
+	result := cmd.result
+	t.mu.Unlock()
+	if result.QnRtn != "" && result.QnRtn != QnRtn_Ready {
+		return &result, fmt.Errorf("device %s rejected request, QnRtn=%s", deviceMN, result.QnRtn)
+	}
+	return &result, nil
+}
+
+// ExecuteCommand 下发命令并等待设备执行结果（CN 9012），ctx控制等待时长；
+// 设备拒绝请求时返回错误，执行结果由调用方根据ExeRtn判断
+func (s *ServerV2) ExecuteCommand(ctx context.Context, deviceMN string, packet *Packet) (*CommandResult, error) {
+	return s.commands.execute(ctx, deviceMN, packet, func(p *Packet) error {
+		return s.SendCommand(deviceMN, p)
+	})
+}
+
+// DeviceHeader 设备最近一次上报的系统编码和密码，设备不在线时返回false
+func (s *ServerV2) DeviceHeader(deviceMN string) (st, pw string, ok bool) {
+	s.mu.RLock()
+	header, ok := s.deviceHeaders[deviceMN]
+	s.mu.RUnlock()
+	return header.ST, header.PW, ok
+}
@@ -0,0 +1,78 @@
+package hj212
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildRaw 构建设备上传的报文
+func buildRaw(t *testing.T, p *Parser, packet *Packet) *Packet {
+	data, err := p.Build(packet)
+	require.NoError(t, err)
+	parsed, err := p.Parse(data)
+	require.NoError(t, err)
+	return parsed
+}
+
+func TestParseCPWithSemicolons(t *testing.T) {
+	p := NewParser("2017")
+	packet := buildRaw(t, p, &Packet{
+		QN: "20240301080000001", ST: "32", CN: CN_GetHourData, PW: "123456", MN: "MN001",
+		CP: "DataTime=20240301070000;w01018-Avg=12.5,w01018-Flag=N;w21003-Avg=0.8,w21003-Flag=N",
+	})
+
+	assert.Equal(t, "MN001", packet.MN)
+	assert.Equal(t, time.Date(2024, 3, 1, 7, 0, 0, 0, time.Local), packet.DataTime)
+	require.Contains(t, packet.Factors, "w01018")
+	assert.Equal(t, 12.5, packet.Factors["w01018"].Avg)
+	assert.Equal(t, "N", packet.Factors["w21003"].Flag)
+}
+
+func TestCommandTrackerMatch(t *testing.T) {
+	p := NewParser("2017")
+	tracker := newCommandTracker()
+	cmd := tracker.register("MN001", "20240301080000001")
+
+	ack := buildRaw(t, p, &Packet{
+		QN: "20240301080000002", ST: ST_System, CN: CN_Response, MN: "MN001",
+		CP: "QN=20240301080000001;QnRtn=1",
+	})
+	assert.True(t, tracker.match(ack))
+
+	// 其他设备同QN的包不匹配
+	assert.False(t, tracker.match(&Packet{QN: "20240301080000001", CN: CN_GetHourData, MN: "MN002"}))
+
+	for i := 0; i < 2; i++ {
+		assert.True(t, tracker.match(&Packet{QN: "20240301080000001", CN: CN_GetHourData, MN: "MN001"}))
+	}
+
+	select {
+	case <-cmd.done:
+		t.Fatal("command should still be pending")
+	default:
+	}
+
+	result := buildRaw(t, p, &Packet{
+		QN: "20240301080000003", ST: ST_System, CN: CN_ExecuteResponse, MN: "MN001",
+		CP: "QN=20240301080000001;ExeRtn=1",
+	})
+	assert.True(t, tracker.match(result))
+
+	<-cmd.done
+	assert.Equal(t, CommandResult{QN: "20240301080000001", QnRtn: QnRtn_Ready, ExeRtn: ExeRtn_Success, Packets: 2}, cmd.result)
+}
+
+func TestCommandTrackerRejected(t *testing.T) {
+	tracker := newCommandTracker()
+	cmd := tracker.register("MN001", "20240301080000001")
+
+	assert.True(t, tracker.match(&Packet{
+		CN: CN_Response, MN: "MN001", QnRtn: QnRtn_Rejected,
+		DataArea: map[string]string{"QN": "20240301080000001"},
+	}))
+	<-cmd.done
+	assert.Equal(t, QnRtn_Rejected, cmd.result.QnRtn)
+}
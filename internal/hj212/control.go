@@ -0,0 +1,202 @@
+package hj212
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 运维可下发的控制命令
+const (
+	ControlGetTime        = "get_time"         // 提取现场机时间（1011）
+	ControlSetTime        = "set_time"         // 设置现场机时间（1012）
+	ControlSetRtdInterval = "set_rtd_interval" // 设置实时数据间隔（1062）
+	ControlGetLimits      = "get_limits"       // 提取污染物报警门限值（1021）
+	ControlSetLimits      = "set_limits"       // 设置污染物报警门限值（1022）
+	ControlCalibrate      = "calibrate"        // 零点校准量程校准（3011）
+	ControlSample         = "sample"           // 即时采样（3012）
+	ControlClean          = "clean"            // 启动清洗/反吹（3013）
+	ControlCompareSample  = "compare_sample"   // 比对采样（3014）
+)
+
+// controlCommands 控制命令对应的CN
+var controlCommands = map[string]string{
+	ControlGetTime:        CN_GetTime,
+	ControlSetTime:        CN_SetTime,
+	ControlSetRtdInterval: CN_SetRtdInterval,
+	ControlGetLimits:      CN_GetAlarmLimit,
+	ControlSetLimits:      CN_SetAlarmLimit,
+	ControlCalibrate:      CN_ZeroCal,
+	ControlSample:         CN_RtdSample,
+	ControlClean:          CN_StartClearDevice,
+	ControlCompareSample:  CN_ComparisonSample,
+}
+
+// ControlTypes 支持的控制命令
+func ControlTypes() []string {
+	types := make([]string, 0, len(controlCommands))
+	for t := range controlCommands {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// LimitValue 污染物报警门限值，为空表示不设置
+type LimitValue struct {
+	Lower *float64 `json:"lower,omitempty"`
+	Upper *float64 `json:"upper,omitempty"`
+}
+
+// ControlRequest 控制命令参数，按Type使用对应字段
+type ControlRequest struct {
+	Type string `json:"type"`
+	// SystemTime set_time的目标时间，为空时使用服务器当前时间
+	SystemTime *time.Time `json:"system_time,omitempty"`
+	// PolID calibrate、sample、compare_sample、get_limits的污染物因子编码，get_limits为空时提取全部
+	PolID string `json:"pol_id,omitempty"`
+	// Interval set_rtd_interval的实时数据间隔(秒)
+	Interval int `json:"interval,omitempty"`
+	// Limits set_limits按因子编码设置的上下限
+	Limits map[string]LimitValue `json:"limits,omitempty"`
+}
+
+// CommandExecutor 可向在线设备下发命令并等待应答的服务器，Server和ServerV2均实现
+type CommandExecutor interface {
+	ExecuteCommand(ctx context.Context, deviceMN string, packet *Packet) (*CommandResult, error)
+	DeviceHeader(deviceMN string) (st, pw string, ok bool)
+}
+
+// BuildControlCP 校验控制命令参数并生成CN和CP
+func BuildControlCP(req *ControlRequest, now time.Time) (string, string, error) {
+	cn, ok := controlCommands[req.Type]
+	if !ok {
+		return "", "", fmt.Errorf("unsupported control command: %s", req.Type)
+	}
+
+	switch req.Type {
+	case ControlSetTime:
+		t := now
+		if req.SystemTime != nil {
+			t = *req.SystemTime
+		}
+		return cn, "SystemTime=" + t.Format(historyTimeFormat), nil
+
+	case ControlSetRtdInterval:
+		if req.Interval <= 0 {
+			return "", "", fmt.Errorf("interval must be positive")
+		}
+		return cn, "RtdInterval=" + strconv.Itoa(req.Interval), nil
+
+	case ControlSetLimits:
+		if len(req.Limits) == 0 {
+			return "", "", fmt.Errorf("limits are required")
+		}
+		codes := make([]string, 0, len(req.Limits))
+		for code := range req.Limits {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+
+		fields := make([]string, 0, len(codes))
+		for _, code := range codes {
+			limit := req.Limits[code]
+			if limit.Lower == nil && limit.Upper == nil {
+				return "", "", fmt.Errorf("limit of %s is empty", code)
+			}
+			if limit.Lower != nil && limit.Upper != nil && *limit.Lower > *limit.Upper {
+				return "", "", fmt.Errorf("lower limit of %s is greater than upper limit", code)
+			}
+			var values []string
+			if limit.Lower != nil {
+				values = append(values, fmt.Sprintf("%s-LowValue=%s", code, formatLimit(*limit.Lower)))
+			}
+			if limit.Upper != nil {
+				values = append(values, fmt.Sprintf("%s-UpValue=%s", code, formatLimit(*limit.Upper)))
+			}
+			fields = append(fields, strings.Join(values, ","))
+		}
+		return cn, strings.Join(fields, ";"), nil
+
+	case ControlCalibrate, ControlSample, ControlCompareSample:
+		if req.PolID == "" {
+			return "", "", fmt.Errorf("pol_id is required")
+		}
+		return cn, "PolId=" + req.PolID, nil
+
+	case ControlGetLimits:
+		if req.PolID != "" {
+			return cn, "PolId=" + req.PolID, nil
+		}
+	}
+	return cn, "", nil
+}
+
+// formatLimit 门限值按最短形式输出
+func formatLimit(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// ExecuteControl 向在线设备下发控制命令并等待执行结果，沿用设备最近一次上报的系统编码和密码
+func ExecuteControl(ctx context.Context, executor CommandExecutor, deviceMN string, req *ControlRequest) (*CommandResult, error) {
+	cn, cp, err := BuildControlCP(req, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	st, pw, ok := executor.DeviceHeader(deviceMN)
+	if !ok {
+		return nil, fmt.Errorf("device %s not connected", deviceMN)
+	}
+
+	return executor.ExecuteCommand(ctx, deviceMN, &Packet{
+		QN: GenerateQN(),
+		ST: st,
+		CN: cn,
+		PW: pw,
+		CP: cp,
+	})
+}
+
+// SetDeviceTime 设置现场机时间
+func (s *ServerV2) SetDeviceTime(ctx context.Context, deviceMN string, t time.Time) (*CommandResult, error) {
+	return ExecuteControl(ctx, s, deviceMN, &ControlRequest{Type: ControlSetTime, SystemTime: &t})
+}
+
+// Sample 对指定因子即时采样
+func (s *ServerV2) Sample(ctx context.Context, deviceMN, polID string) (*CommandResult, error) {
+	return ExecuteControl(ctx, s, deviceMN, &ControlRequest{Type: ControlSample, PolID: polID})
+}
+
+// Calibrate 对指定因子零点校准量程校准
+func (s *ServerV2) Calibrate(ctx context.Context, deviceMN, polID string) (*CommandResult, error) {
+	return ExecuteControl(ctx, s, deviceMN, &ControlRequest{Type: ControlCalibrate, PolID: polID})
+}
+
+// SetLimits 设置污染物报警门限值
+func (s *ServerV2) SetLimits(ctx context.Context, deviceMN string, limits map[string]LimitValue) (*CommandResult, error) {
+	return ExecuteControl(ctx, s, deviceMN, &ControlRequest{Type: ControlSetLimits, Limits: limits})
+}
+
+// handleControlCommand 处理设备上传的参数和控制命令：提取类命令的应答已由commandTracker
+// 记入执行结果，按需确认；平台不受理设备主动发起的控制命令
+func (s *ServerV2) handleControlCommand(conn net.Conn, packet *Packet, matched bool) {
+	s.logger.Info("Control command received",
+		zap.String("mn", packet.MN),
+		zap.String("cn", packet.CN),
+		zap.Bool("matched", matched))
+
+	if matched {
+		if packet.Flag&Flag_Confirm != 0 {
+			s.sendSuccessResponse(conn, packet)
+		}
+		return
+	}
+	s.sendExecutionResponse(conn, packet, ExeRtn_Failed, "unsupported command")
+}
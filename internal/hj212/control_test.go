@@ -0,0 +1,120 @@
+package hj212
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildControlCP(t *testing.T) {
+	now := time.Date(2024, 3, 1, 8, 0, 0, 0, time.Local)
+	lower, upper := 0.5, 10.0
+
+	tests := []struct {
+		name string
+		req  ControlRequest
+		cn   string
+		cp   string
+	}{
+		{"设置时间默认当前", ControlRequest{Type: ControlSetTime}, CN_SetTime, "SystemTime=20240301080000"},
+		{"即时采样", ControlRequest{Type: ControlSample, PolID: "w01018"}, CN_RtdSample, "PolId=w01018"},
+		{"校零校标", ControlRequest{Type: ControlCalibrate, PolID: "w01018"}, CN_ZeroCal, "PolId=w01018"},
+		{"实时数据间隔", ControlRequest{Type: ControlSetRtdInterval, Interval: 30}, CN_SetRtdInterval, "RtdInterval=30"},
+		{"提取全部门限", ControlRequest{Type: ControlGetLimits}, CN_GetAlarmLimit, ""},
+		{
+			"设置上下限",
+			ControlRequest{Type: ControlSetLimits, Limits: map[string]LimitValue{
+				"w21003": {Upper: &upper},
+				"w01018": {Lower: &lower, Upper: &upper},
+			}},
+			CN_SetAlarmLimit,
+			"w01018-LowValue=0.5,w01018-UpValue=10;w21003-UpValue=10",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cn, cp, err := BuildControlCP(&tt.req, now)
+			require.NoError(t, err)
+			assert.Equal(t, tt.cn, cn)
+			assert.Equal(t, tt.cp, cp)
+		})
+	}
+
+	invalid := []ControlRequest{
+		{Type: "reboot"},
+		{Type: ControlSample},
+		{Type: ControlSetRtdInterval},
+		{Type: ControlSetLimits},
+		{Type: ControlSetLimits, Limits: map[string]LimitValue{"w01018": {Lower: &upper, Upper: &lower}}},
+	}
+	for _, req := range invalid {
+		_, _, err := BuildControlCP(&req, now)
+		assert.Error(t, err, req.Type)
+	}
+}
+
+// fakeExecutor 模拟设备：先应答准备执行，提取类命令上传参数，再返回执行结果
+type fakeExecutor struct {
+	parser   *Parser
+	commands *commandTracker
+	exeRtn   string
+	sent     *Packet
+}
+
+func (f *fakeExecutor) DeviceHeader(mn string) (string, string, bool) {
+	return "32", "123456", mn == "MN001"
+}
+
+func (f *fakeExecutor) ExecuteCommand(ctx context.Context, mn string, packet *Packet) (*CommandResult, error) {
+	return f.commands.execute(ctx, mn, packet, func(p *Packet) error {
+		f.sent = p
+		go func() {
+			f.reply(&Packet{ST: ST_System, CN: CN_Response, MN: mn, CP: "QN=" + p.QN + ";QnRtn=1"})
+			if p.CN == CN_GetTime {
+				f.reply(&Packet{QN: p.QN, ST: "32", CN: CN_GetTime, MN: mn, CP: "PolId=w01018;SystemTime=20240301080000"})
+			}
+			f.reply(&Packet{ST: ST_System, CN: CN_ExecuteResponse, MN: mn, CP: "QN=" + p.QN + ";ExeRtn=" + f.exeRtn})
+		}()
+		return nil
+	})
+}
+
+func (f *fakeExecutor) reply(packet *Packet) {
+	if packet.QN == "" {
+		packet.QN = GenerateQN()
+	}
+	data, _ := f.parser.Build(packet)
+	parsed, _ := f.parser.Parse(data)
+	f.commands.match(parsed)
+}
+
+func TestExecuteControl(t *testing.T) {
+	executor := &fakeExecutor{parser: NewParser("2017"), commands: newCommandTracker(), exeRtn: ExeRtn_Success}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	result, err := ExecuteControl(ctx, executor, "MN001", &ControlRequest{Type: ControlSample, PolID: "w01018"})
+	require.NoError(t, err)
+	assert.True(t, result.Succeeded())
+	assert.Equal(t, CN_RtdSample, executor.sent.CN)
+	assert.Equal(t, "123456", executor.sent.PW)
+	assert.Equal(t, "32", executor.sent.ST)
+
+	// 提取类命令返回设备上传的参数
+	result, err = ExecuteControl(ctx, executor, "MN001", &ControlRequest{Type: ControlGetTime})
+	require.NoError(t, err)
+	assert.Equal(t, "20240301080000", result.Data["SystemTime"])
+
+	// 执行失败
+	executor.exeRtn = ExeRtn_Failed
+	result, err = ExecuteControl(ctx, executor, "MN001", &ControlRequest{Type: ControlClean})
+	require.NoError(t, err)
+	assert.False(t, result.Succeeded())
+
+	// 设备不在线
+	_, err = ExecuteControl(ctx, executor, "MN002", &ControlRequest{Type: ControlClean})
+	assert.Error(t, err)
+}
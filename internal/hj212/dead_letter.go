@@ -0,0 +1,131 @@
+package hj212
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/env-data-platform/internal/config"
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// packetChecksum 失败报文的长度和CRC对比
+type packetChecksum struct {
+	DeclaredLength int // 包头声明的数据段长度，无法解析时为-1
+	ActualLength   int
+	Expected       string
+	Calculated     string
+}
+
+// diagnose 按包头声明的长度定位数据段和CRC；声明长度不可用时把包尾4个字符视为CRC，
+// 其余部分视为数据段，尽量给出可对比的CRC
+func (p *Parser) diagnose(data []byte) packetChecksum {
+	str := strings.TrimSuffix(string(data), "\r\n")
+	result := packetChecksum{DeclaredLength: -1}
+	if !strings.HasPrefix(str, "##") || len(str) < 6 {
+		return result
+	}
+
+	if n, err := strconv.Atoi(str[2:6]); err == nil && n >= 0 {
+		result.DeclaredLength = n
+	}
+
+	var segment string
+	if n := result.DeclaredLength; n >= 0 && len(str) >= 6+n+4 {
+		segment = str[6 : 6+n]
+		result.Expected = str[6+n : 6+n+4]
+	} else if len(str) >= 10 {
+		segment = str[6 : len(str)-4]
+		result.Expected = str[len(str)-4:]
+	} else {
+		segment = str[6:]
+	}
+	result.ActualLength = len(segment)
+	result.Calculated = fmt.Sprintf("%04X", p.calculateCRC([]byte(segment)))
+	return result
+}
+
+// newDeadLetter 构建死信记录，packet为解析成功但校验失败的包，解析失败时为nil
+func (p *Parser) newDeadLetter(remote, stage string, data []byte, packet *Packet, cause error) *models.HJ212DeadLetter {
+	checksum := p.diagnose(data)
+	mn := extractMN(data)
+	if packet != nil && packet.MN != "" {
+		mn = packet.MN
+	}
+	if len(mn) > 50 {
+		mn = mn[:50]
+	}
+
+	return &models.HJ212DeadLetter{
+		DeviceID:       mn,
+		RemoteAddr:     remote,
+		Stage:          stage,
+		Reason:         cause.Error(),
+		RawData:        encodeArchivePayload(data),
+		CRCExpected:    checksum.Expected,
+		CRCCalculated:  checksum.Calculated,
+		CRCMatched:     checksum.Expected != "" && strings.EqualFold(checksum.Expected, checksum.Calculated),
+		DeclaredLength: checksum.DeclaredLength,
+		ActualLength:   checksum.ActualLength,
+		ReceivedAt:     time.Now(),
+	}
+}
+
+// recordDeadLetter 保存解析或校验失败的原始报文
+func (s *ServerV2) recordDeadLetter(conn net.Conn, stage string, data []byte, packet *Packet, cause error) {
+	if !s.config.DeadLetter.Enabled {
+		return
+	}
+	saveDeadLetter(s.db, s.logger, s.parser.newDeadLetter(conn.RemoteAddr().String(), stage, data, packet, cause))
+}
+
+// purgeDeadLetters 删除超过保留时长的死信
+func (s *ServerV2) purgeDeadLetters() {
+	purgeDeadLetters(s.db, s.logger, s.config.DeadLetter)
+}
+
+// recordDeadLetter 保存解析或校验失败的原始报文
+func (s *Server) recordDeadLetter(conn net.Conn, stage string, data []byte, packet *Packet, cause error) {
+	if !s.config.HJ212.DeadLetter.Enabled {
+		return
+	}
+	saveDeadLetter(database.DB, s.logger, s.parser.newDeadLetter(conn.RemoteAddr().String(), stage, data, packet, cause))
+}
+
+// purgeDeadLetters 删除超过保留时长的死信
+func (s *Server) purgeDeadLetters() {
+	purgeDeadLetters(database.DB, s.logger, s.config.HJ212.DeadLetter)
+}
+
+// saveDeadLetter 保存死信记录，Server和ServerV2共用
+func saveDeadLetter(db *gorm.DB, logger *zap.Logger, letter *models.HJ212DeadLetter) {
+	if db == nil {
+		return
+	}
+	if err := db.Create(letter).Error; err != nil {
+		logger.Error("Failed to save HJ212 dead letter",
+			zap.String("remote", letter.RemoteAddr),
+			zap.String("mn", letter.DeviceID),
+			zap.Error(err))
+	}
+}
+
+// purgeDeadLetters 按保留时长删除死信，Server和ServerV2共用
+func purgeDeadLetters(db *gorm.DB, logger *zap.Logger, cfg config.HJ212DeadLetterConfig) {
+	if !cfg.Enabled || cfg.Retention <= 0 || db == nil {
+		return
+	}
+
+	cutoff := time.Now().Add(-cfg.Retention)
+	result := db.Unscoped().Where("received_at < ?", cutoff).Delete(&models.HJ212DeadLetter{})
+	if result.Error != nil {
+		logger.Error("Failed to purge HJ212 dead letters", zap.Error(result.Error))
+	} else if result.RowsAffected > 0 {
+		logger.Info("Purged HJ212 dead letters", zap.Int64("count", result.RowsAffected))
+	}
+}
@@ -0,0 +1,50 @@
+package hj212
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/env-data-platform/internal/models"
+)
+
+func TestNewDeadLetter(t *testing.T) {
+	parser := NewParser("2017")
+	segment := "QN=20240501100000000;ST=22;CN=2011;PW=123456;MN=MN001;Flag=5;CP=&&a34004-Rtd=35.5&&"
+	crc := fmt.Sprintf("%04X", parser.calculateCRC([]byte(segment)))
+
+	// CRC不一致时保留包尾CRC和重新计算的CRC
+	raw := []byte(fmt.Sprintf("##%04d%sFFFF\r\n", len(segment), segment))
+	letter := parser.newDeadLetter("10.0.0.1:5000", models.HJ212DeadLetterStageParse, raw, nil, errors.New("CRC mismatch"))
+	assert.Equal(t, "MN001", letter.DeviceID)
+	assert.Equal(t, "10.0.0.1:5000", letter.RemoteAddr)
+	assert.Equal(t, "CRC mismatch", letter.Reason)
+	assert.Equal(t, "FFFF", letter.CRCExpected)
+	assert.Equal(t, crc, letter.CRCCalculated)
+	assert.False(t, letter.CRCMatched)
+	assert.Equal(t, len(segment), letter.DeclaredLength)
+	assert.Equal(t, len(segment), letter.ActualLength)
+	assert.Equal(t, string(raw[:len(raw)-2]), letter.RawData)
+
+	// 包头长度无法解析时按包尾4个字符对比CRC
+	raw = []byte("##XXXX" + segment + crc + "\r\n")
+	letter = parser.newDeadLetter("10.0.0.1:5000", models.HJ212DeadLetterStageParse, raw, nil, errors.New("invalid length"))
+	assert.Equal(t, -1, letter.DeclaredLength)
+	assert.Equal(t, len(segment), letter.ActualLength)
+	assert.True(t, letter.CRCMatched)
+
+	// 校验失败的包以解析结果中的MN为准
+	raw = []byte(fmt.Sprintf("##%04d%s%s\r\n", len(segment), segment, crc))
+	letter = parser.newDeadLetter("10.0.0.1:5000", models.HJ212DeadLetterStageValidate, raw, &Packet{MN: "MN002"}, errors.New("invalid ST"))
+	assert.Equal(t, "MN002", letter.DeviceID)
+	assert.Equal(t, models.HJ212DeadLetterStageValidate, letter.Stage)
+	assert.True(t, letter.CRCMatched)
+
+	// 过短的报文不产生CRC
+	letter = parser.newDeadLetter("10.0.0.1:5000", models.HJ212DeadLetterStageParse, []byte("garbage"), nil, errors.New("invalid header"))
+	assert.Empty(t, letter.DeviceID)
+	assert.Empty(t, letter.CRCExpected)
+	assert.False(t, letter.CRCMatched)
+}
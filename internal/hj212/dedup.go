@@ -0,0 +1,100 @@
+package hj212
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// mysqlDuplicateEntry MySQL唯一索引冲突错误码
+const mysqlDuplicateEntry = 1062
+
+// packetDedup 按设备MN+QN(+分包序号)识别设备重发或网络重传的数据包。
+// 近期的QN缓存在内存中，并发收到同一包时只有一个能认领入库；
+// 超出窗口或进程重启后的重传由数据库唯一索引拦截
+type packetDedup struct {
+	mu        sync.Mutex
+	window    time.Duration
+	seen      map[string]time.Time
+	lastPrune time.Time
+}
+
+func newPacketDedup(window time.Duration) *packetDedup {
+	return &packetDedup{
+		window: window,
+		seen:   make(map[string]time.Time),
+	}
+}
+
+// packetDedupKey 数据包的去重键，为空表示不去重：没有QN的包无法识别重传；
+// 命令执行期间上传的数据沿用请求的QN，多包QN相同，由数据时间去重
+func packetDedupKey(packet *Packet, matched bool) string {
+	if packet.QN == "" || packet.MN == "" || matched {
+		return ""
+	}
+	return packet.MN + "|" + packet.QN + "|" + strconv.Itoa(packetNo(packet))
+}
+
+// packetNo 分包序号PNO，未分包时为0
+func packetNo(packet *Packet) int {
+	no, _ := strconv.Atoi(packet.DataArea["PNO"])
+	return no
+}
+
+// claim 认领去重键，窗口内首次出现返回true，重复返回false；window为0时不做内存去重
+func (d *packetDedup) claim(key string, now time.Time) bool {
+	if key == "" || d.window <= 0 {
+		return true
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.prune(now)
+	if at, ok := d.seen[key]; ok && now.Sub(at) < d.window {
+		return false
+	}
+	d.seen[key] = now
+	return true
+}
+
+// release 入库失败时释放认领，设备重传的包可以再次入库
+func (d *packetDedup) release(key string) {
+	if key == "" {
+		return
+	}
+	d.mu.Lock()
+	delete(d.seen, key)
+	d.mu.Unlock()
+}
+
+// prune 每个窗口清理一次过期的键
+func (d *packetDedup) prune(now time.Time) {
+	if now.Sub(d.lastPrune) < d.window {
+		return
+	}
+	d.lastPrune = now
+	for key, at := range d.seen {
+		if now.Sub(at) >= d.window {
+			delete(d.seen, key)
+		}
+	}
+}
+
+// isDuplicateKeyError 入库时是否违反唯一索引，即数据包已由其他连接或实例入库
+func isDuplicateKeyError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry
+}
+
+// packetQN 入库的QN，不去重的包不记录，避免命令上传的多包违反唯一索引
+func packetQN(packet *Packet, key string) *string {
+	if key == "" {
+		return nil
+	}
+	qn := packet.QN
+	return &qn
+}
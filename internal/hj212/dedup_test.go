@@ -0,0 +1,78 @@
+package hj212
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPacketDedupKey(t *testing.T) {
+	packet := &Packet{MN: "MN001", QN: "20240501100000123", CN: CN_GetRtdData, DataArea: map[string]string{}}
+	assert.Equal(t, "MN001|20240501100000123|0", packetDedupKey(packet, false))
+
+	// 分包按序号区分
+	packet.DataArea["PNO"] = "2"
+	assert.Equal(t, "MN001|20240501100000123|2", packetDedupKey(packet, false))
+	assert.Equal(t, 2, packetNo(packet))
+
+	// 命令执行期间上传的数据沿用请求QN，不按QN去重
+	assert.Empty(t, packetDedupKey(packet, true))
+	assert.Nil(t, packetQN(packet, ""))
+
+	packet.QN = ""
+	assert.Empty(t, packetDedupKey(packet, false))
+}
+
+func TestPacketDedupClaim(t *testing.T) {
+	d := newPacketDedup(10 * time.Minute)
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local)
+
+	assert.True(t, d.claim("MN001|1|0", now))
+	assert.False(t, d.claim("MN001|1|0", now.Add(time.Minute)))
+	assert.True(t, d.claim("MN002|1|0", now.Add(time.Minute)))
+
+	// 入库失败释放后允许重传的包再次入库
+	d.release("MN001|1|0")
+	assert.True(t, d.claim("MN001|1|0", now.Add(2*time.Minute)))
+
+	// 超出窗口后不再视为重复，过期的键被清理
+	assert.True(t, d.claim("MN002|1|0", now.Add(20*time.Minute)))
+	assert.Len(t, d.seen, 1)
+
+	// 不去重的包总是放行
+	assert.True(t, d.claim("", now))
+	assert.True(t, newPacketDedup(0).claim("MN001|1|0", now))
+}
+
+func TestPacketDedupConcurrentClaim(t *testing.T) {
+	d := newPacketDedup(time.Minute)
+	now := time.Now()
+
+	var claimed int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if d.claim("MN001|20240501100000123|0", now) {
+				atomic.AddInt32(&claimed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), claimed)
+}
+
+func TestIsDuplicateKeyError(t *testing.T) {
+	dup := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}
+	assert.True(t, isDuplicateKeyError(dup))
+	assert.True(t, isDuplicateKeyError(fmt.Errorf("insert: %w", dup)))
+	assert.False(t, isDuplicateKeyError(&mysql.MySQLError{Number: 1045}))
+	assert.False(t, isDuplicateKeyError(errors.New("connection refused")))
+}
@@ -0,0 +1,234 @@
+package hj212
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/models"
+)
+
+// DeviceStatusNotifier 设备上下线通知接口，由告警检测器实现
+type DeviceStatusNotifier interface {
+	DeviceStatusChanged(event *models.DeviceStatusEvent)
+}
+
+// deviceStatusRecorder 按数据源的is_connected维护设备在线状态，状态变化时记录上下线事件并通知，Server和ServerV2共用
+type deviceStatusRecorder struct {
+	db       *gorm.DB
+	logger   *zap.Logger
+	notifier DeviceStatusNotifier
+}
+
+// SetStatusNotifier 设置设备上下线通知，为空时只记录事件
+func (s *ServerV2) SetStatusNotifier(notifier DeviceStatusNotifier) {
+	s.status.notifier = notifier
+}
+
+// touchDevice 记录设备最后收包时间和包头，设备由离线转为在线时产生上线事件
+func (s *ServerV2) touchDevice(packet *Packet) {
+	mn := packet.MN
+	if mn == "" {
+		return
+	}
+
+	s.mu.Lock()
+	_, online := s.lastSeen[mn]
+	s.lastSeen[mn] = time.Now()
+	if packet.ST != ST_System {
+		s.deviceHeaders[mn] = deviceHeader{ST: packet.ST, PW: packet.PW}
+	}
+	s.mu.Unlock()
+
+	if !online {
+		s.status.setOnline(mn)
+	}
+}
+
+// setOnline 标记设备在线，数据源原为未连接时记录上线事件
+func (r *deviceStatusRecorder) setOnline(mn string) {
+	if r.db == nil {
+		return
+	}
+
+	var dataSource models.DataSource
+	if err := r.db.Where("device_id = ? AND type = ?", mn, models.DataSourceTypeHJ212).
+		First(&dataSource).Error; err != nil {
+		return
+	}
+
+	now := time.Now()
+	result := r.db.Model(&models.DataSource{}).
+		Where("id = ? AND is_connected = ?", dataSource.ID, false).
+		Updates(map[string]interface{}{"is_connected": true, "last_active_at": now})
+	if result.Error != nil {
+		r.logger.Error("Failed to mark device online", zap.String("mn", mn), zap.Error(result.Error))
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+
+	r.recordEvent(&models.DeviceStatusEvent{
+		DataSourceID: dataSource.ID,
+		DeviceID:     mn,
+		Event:        models.DeviceEventOnline,
+		LastActiveAt: dataSource.LastActiveAt,
+		OccurredAt:   now,
+	})
+}
+
+// setOffline 标记设备离线，数据源原为已连接时记录离线事件
+func (r *deviceStatusRecorder) setOffline(dataSource *models.DataSource, lastActiveAt *time.Time) {
+	updates := map[string]interface{}{"is_connected": false}
+	if lastActiveAt != nil {
+		updates["last_active_at"] = *lastActiveAt
+	}
+
+	result := r.db.Model(&models.DataSource{}).
+		Where("id = ? AND is_connected = ?", dataSource.ID, true).
+		Updates(updates)
+	if result.Error != nil {
+		r.logger.Error("Failed to mark device offline",
+			zap.String("mn", dataSource.DeviceID),
+			zap.Error(result.Error))
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+
+	r.recordEvent(&models.DeviceStatusEvent{
+		DataSourceID: dataSource.ID,
+		DeviceID:     dataSource.DeviceID,
+		Event:        models.DeviceEventOffline,
+		LastActiveAt: lastActiveAt,
+		OccurredAt:   time.Now(),
+	})
+}
+
+// recordEvent 保存上下线事件并触发通知
+func (r *deviceStatusRecorder) recordEvent(event *models.DeviceStatusEvent) {
+	r.logger.Info("Device status changed",
+		zap.String("mn", event.DeviceID),
+		zap.String("event", event.Event))
+
+	if err := r.db.Create(event).Error; err != nil {
+		r.logger.Error("Failed to save device status event",
+			zap.String("mn", event.DeviceID),
+			zap.Error(err))
+	}
+
+	if r.notifier != nil {
+		r.notifier.DeviceStatusChanged(event)
+	}
+}
+
+// markOffline 将offline中超时的设备标记为离线；服务启动后未再上报、但数据源仍为已连接的设备
+// 按last_active_at判断，active为仍在线的设备
+func (r *deviceStatusRecorder) markOffline(offline map[string]time.Time, active []string, cutoff time.Time) {
+	if r.db == nil {
+		return
+	}
+
+	query := r.db.Where("type = ? AND is_connected = ?", models.DataSourceTypeHJ212, true)
+	if len(active) > 0 {
+		query = query.Where("device_id NOT IN ?", active)
+	}
+	var sources []models.DataSource
+	if err := query.Find(&sources).Error; err != nil {
+		r.logger.Error("Failed to query connected devices", zap.Error(err))
+		return
+	}
+
+	for i := range sources {
+		source := &sources[i]
+		if seen, ok := offline[source.DeviceID]; ok {
+			r.setOffline(source, &seen)
+			continue
+		}
+		if source.LastActiveAt == nil || source.LastActiveAt.Before(cutoff) {
+			r.setOffline(source, source.LastActiveAt)
+		}
+	}
+}
+
+// detectOfflineDevices 将超过离线阈值未收包的设备标记为离线并断开其连接，UDP设备只清除来源地址
+func (s *ServerV2) detectOfflineDevices() {
+	timeout := s.config.OfflineTimeout
+	if timeout <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-timeout)
+
+	offline := make(map[string]time.Time)
+	var active []string
+	s.mu.Lock()
+	for mn, seen := range s.lastSeen {
+		if seen.Before(cutoff) {
+			offline[mn] = seen
+			delete(s.lastSeen, mn)
+			delete(s.deviceHeaders, mn)
+			delete(s.udpPeers, mn)
+			if conn, ok := s.connections[mn]; ok {
+				conn.Close()
+			}
+			continue
+		}
+		active = append(active, mn)
+	}
+	s.mu.Unlock()
+
+	s.status.markOffline(offline, active, cutoff)
+}
+
+// SetStatusNotifier 设置设备上下线通知，为空时只记录事件
+func (s *Server) SetStatusNotifier(notifier DeviceStatusNotifier) {
+	s.status.notifier = notifier
+}
+
+// touchDevice 记录设备最后收包时间，设备由离线转为在线时产生上线事件
+func (s *Server) touchDevice(mn string) {
+	if mn == "" {
+		return
+	}
+
+	s.lastSeenMu.Lock()
+	_, online := s.lastSeen[mn]
+	s.lastSeen[mn] = time.Now()
+	s.lastSeenMu.Unlock()
+
+	if !online {
+		s.status.setOnline(mn)
+	}
+}
+
+// detectOfflineDevices 将超过离线阈值未收包的设备标记为离线并断开其连接
+func (s *Server) detectOfflineDevices() {
+	timeout := s.config.HJ212.OfflineTimeout
+	if timeout <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-timeout)
+
+	offline := make(map[string]time.Time)
+	var active []string
+	s.lastSeenMu.Lock()
+	for mn, seen := range s.lastSeen {
+		if seen.Before(cutoff) {
+			offline[mn] = seen
+			delete(s.lastSeen, mn)
+			if value, ok := s.clients.LoadAndDelete(mn); ok {
+				if client, ok := value.(*Client); ok {
+					client.Conn.Close()
+				}
+			}
+			continue
+		}
+		active = append(active, mn)
+	}
+	s.lastSeenMu.Unlock()
+
+	s.status.markOffline(offline, active, cutoff)
+}
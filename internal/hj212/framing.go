@@ -0,0 +1,71 @@
+package hj212
+
+import "bytes"
+
+// 数据包结构：##(2) + 数据段长度(4位十进制) + 数据段 + CRC(4位十六进制) + \r\n
+const (
+	packetHeaderLen  = 6
+	packetTrailerLen = 6
+)
+
+var (
+	packetStart = []byte("##")
+	packetEnd   = []byte("\r\n")
+)
+
+// splitPacket 从data开头切出一帧，返回帧的字节数，0表示数据不完整需继续读取。
+// 以##开头时按包头的数据段长度精确切分并校验包尾，数据段内出现\r\n也不会截断；
+// 包头前的噪声、长度字段非数字或包尾不符的字节切到下一个\r\n或##为止单独成帧，
+// 交由解析环节记为无效包，随后从下一个包头重新同步
+func splitPacket(data []byte) int {
+	if len(data) == 0 {
+		return 0
+	}
+	if !bytes.HasPrefix(data, packetStart) {
+		if len(data) == 1 && data[0] == '#' {
+			return 0 // 可能是被拆开的包头
+		}
+		return malformedFrameLen(data, 0)
+	}
+
+	// 包头未收全时，已收到的长度字段必须是数字
+	header := data[2:]
+	if len(header) > 4 {
+		header = header[:4]
+	}
+	dataLen := 0
+	for _, c := range header {
+		if c < '0' || c > '9' {
+			return malformedFrameLen(data, 2)
+		}
+		dataLen = dataLen*10 + int(c-'0')
+	}
+	if len(data) < packetHeaderLen {
+		return 0
+	}
+
+	total := packetHeaderLen + dataLen + packetTrailerLen
+	if len(data) < total {
+		return 0
+	}
+	if !bytes.Equal(data[total-len(packetEnd):total], packetEnd) {
+		return malformedFrameLen(data, 2)
+	}
+	return total
+}
+
+// malformedFrameLen 非法数据的帧长度：到from之后的第一个\r\n（含）或##（不含）为止，都没有时为全部数据；
+// 末尾单独的#留给下一次切分，可能是下一个包头的一半
+func malformedFrameLen(data []byte, from int) int {
+	end := len(data)
+	if i := bytes.Index(data[from:], packetEnd); i >= 0 {
+		end = from + i + len(packetEnd)
+	}
+	if i := bytes.Index(data[from:], packetStart); i >= 0 && from+i < end {
+		end = from + i
+	}
+	if end == len(data) && end > 1 && data[end-1] == '#' {
+		end--
+	}
+	return end
+}
@@ -0,0 +1,98 @@
+package hj212
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func framingPacket(p *Parser, segment string) string {
+	return fmt.Sprintf("##%04d%s%04X\r\n", len(segment), segment, p.calculateCRC([]byte(segment)))
+}
+
+// splitAll 模拟连接读取循环：逐块追加数据并切出所有完整帧
+func splitAll(chunks ...string) (frames []string, rest string) {
+	var buf []byte
+	for _, chunk := range chunks {
+		buf = append(buf, chunk...)
+		for len(buf) > 0 {
+			end := splitPacket(buf)
+			if end == 0 {
+				break
+			}
+			frames = append(frames, string(buf[:end]))
+			buf = buf[end:]
+		}
+	}
+	return frames, string(buf)
+}
+
+func TestSplitPacket(t *testing.T) {
+	p := NewParser("2017")
+	first := framingPacket(p, "QN=20240501100000000;ST=22;CN=2011;PW=123456;MN=MN001;Flag=5;CP=&&a34004-Rtd=35.5&&")
+	second := framingPacket(p, "QN=20240501100000001;ST=22;CN=2011;PW=123456;MN=MN002;Flag=5;CP=&&a34004-Rtd=36.1&&")
+	// 数据段内出现\r\n时不能在此处截断
+	multiline := framingPacket(p, "QN=20240501100000002;ST=22;CN=2011;PW=123456;MN=MN003;Flag=5;CP=&&Info=a\r\nb&&")
+
+	t.Run("粘包", func(t *testing.T) {
+		frames, rest := splitAll(first + second + multiline)
+		assert.Equal(t, []string{first, second, multiline}, frames)
+		assert.Empty(t, rest)
+	})
+
+	t.Run("半包逐字节到达", func(t *testing.T) {
+		var chunks []string
+		for _, b := range []byte(first + multiline) {
+			chunks = append(chunks, string(b))
+		}
+		frames, rest := splitAll(chunks...)
+		assert.Equal(t, []string{first, multiline}, frames)
+		assert.Empty(t, rest)
+	})
+
+	t.Run("不完整的包等待后续数据", func(t *testing.T) {
+		frames, rest := splitAll(first + second[:20])
+		assert.Equal(t, []string{first}, frames)
+		assert.Equal(t, second[:20], rest)
+
+		frames, rest = splitAll("#")
+		assert.Empty(t, frames)
+		assert.Equal(t, "#", rest)
+	})
+
+	t.Run("包头前的噪声单独成帧", func(t *testing.T) {
+		frames, rest := splitAll("noise" + first + "junk\r\n" + second + "tail#")
+		assert.Equal(t, []string{"noise", first, "junk\r\n", second, "tail"}, frames)
+		assert.Equal(t, "#", rest)
+	})
+
+	t.Run("长度字段非法", func(t *testing.T) {
+		frames, rest := splitAll("##12AB" + first)
+		assert.Equal(t, []string{"##12AB", first}, frames)
+		assert.Empty(t, rest)
+	})
+
+	t.Run("长度与包尾不符", func(t *testing.T) {
+		// 声明长度比实际短，按长度截取后包尾不是\r\n，丢弃到下一个包头重新同步
+		bad := "##0010" + first[6:]
+		frames, rest := splitAll(bad + second)
+		assert.Equal(t, []string{bad, second}, frames)
+		assert.Empty(t, rest)
+	})
+}
+
+func TestParseRequiresTerminatorAfterCRC(t *testing.T) {
+	p := NewParser("2017")
+	segment := "QN=20240501100000000;ST=22;CN=2011;PW=123456;MN=MN001;Flag=5;CP=&&Info=a\r\nb&&"
+	raw := framingPacket(p, segment)
+
+	packet, err := p.Parse([]byte(raw))
+	require.NoError(t, err)
+	assert.Equal(t, "MN001", packet.MN)
+
+	// 数据段内有\r\n但CRC后缺少结束符
+	_, err = p.Parse([]byte(raw[:len(raw)-2] + "XX"))
+	assert.EqualError(t, err, "packet end not found")
+}
@@ -0,0 +1,83 @@
+package hj212
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/models"
+)
+
+// historyTimeFormat 历史数据请求的时间格式
+const historyTimeFormat = "20060102150405"
+
+// historyCommands 支持补传的历史数据命令及数据类型
+var historyCommands = map[string]string{
+	CN_GetDayData:     "day",
+	CN_GetRunTimeData: "runtime",
+	CN_GetMinuteData:  "minute",
+	CN_GetHourData:    "hour",
+}
+
+// IsHistoryCommand 判断是否为历史数据命令
+func IsHistoryCommand(cn string) bool {
+	_, ok := historyCommands[cn]
+	return ok
+}
+
+// deviceHeader 设备最近一次上报的系统编码和密码，主动下发命令时沿用
+type deviceHeader struct {
+	ST string
+	PW string
+}
+
+// RequestHistoryData 请求设备补传[begin, end]时间段的历史数据并等待执行完成。
+// 设备以请求的QN分多包上传数据，按设备、命令和数据时间去重后入库
+func RequestHistoryData(ctx context.Context, executor CommandExecutor, deviceMN, cn string, begin, end time.Time) (*CommandResult, error) {
+	if !IsHistoryCommand(cn) {
+		return nil, fmt.Errorf("unsupported history command: %s", cn)
+	}
+	if !end.After(begin) {
+		return nil, fmt.Errorf("end time must be after begin time")
+	}
+
+	st, pw, ok := executor.DeviceHeader(deviceMN)
+	if !ok {
+		return nil, fmt.Errorf("device %s not connected", deviceMN)
+	}
+
+	return executor.ExecuteCommand(ctx, deviceMN, &Packet{
+		QN: GenerateQN(),
+		ST: st,
+		CN: cn,
+		PW: pw,
+		CP: fmt.Sprintf("BeginTime=%s;EndTime=%s", begin.Format(historyTimeFormat), end.Format(historyTimeFormat)),
+	})
+}
+
+// RequestHistoryData 请求设备补传历史数据
+func (s *Server) RequestHistoryData(ctx context.Context, deviceMN, cn string, begin, end time.Time) (*CommandResult, error) {
+	return RequestHistoryData(ctx, s, deviceMN, cn, begin, end)
+}
+
+// RequestHistoryData 请求设备补传历史数据
+func (s *ServerV2) RequestHistoryData(ctx context.Context, deviceMN, cn string, begin, end time.Time) (*CommandResult, error) {
+	return RequestHistoryData(ctx, s, deviceMN, cn, begin, end)
+}
+
+// isDuplicateHistoryData 判断历史数据是否已入库，相同设备、命令和数据时间视为重复
+func isDuplicateHistoryData(db *gorm.DB, packet *Packet) bool {
+	if !IsHistoryCommand(packet.CN) || packet.DataTime.IsZero() {
+		return false
+	}
+
+	var count int64
+	if err := db.Model(&models.HJ212Data{}).
+		Where("device_id = ? AND command_code = ? AND data_time = ?", packet.MN, packet.CN, packet.DataTime).
+		Count(&count).Error; err != nil {
+		return false
+	}
+	return count > 0
+}
@@ -0,0 +1,84 @@
+package hj212
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sort"
+	"time"
+)
+
+// 连接断开原因，用于日志和hj212_disconnects_total指标
+const (
+	disconnectHeartbeatTimeout = "heartbeat_timeout" // 超过心跳超时未收到有效数据包，服务端主动断开
+	disconnectReadTimeout      = "read_timeout"      // 单次读取超时
+	disconnectClosed           = "closed"            // 设备关闭连接
+	disconnectError            = "error"             // 读取出错
+	disconnectShutdown         = "shutdown"          // 服务停止
+)
+
+// idleTracker 跟踪连接最后一次收到有效数据包的时间，据此设置读超时。
+// readTimeout限制单次读取的等待时长，heartbeatTimeout限制两个有效数据包的间隔，
+// 设备只发噪声或半包时读操作能成功但不会刷新有效时间，到期后同样断开；为0表示不限制
+type idleTracker struct {
+	readTimeout      time.Duration
+	heartbeatTimeout time.Duration
+	lastValid        time.Time
+}
+
+func newIdleTracker(readTimeout, heartbeatTimeout time.Duration, now time.Time) *idleTracker {
+	return &idleTracker{
+		readTimeout:      readTimeout,
+		heartbeatTimeout: heartbeatTimeout,
+		lastValid:        now,
+	}
+}
+
+// touch 收到有效数据包
+func (t *idleTracker) touch(now time.Time) {
+	t.lastValid = now
+}
+
+// deadline 下一次读取的截止时间，取单次读超时和心跳到期时间中较早者，零值表示不限制
+func (t *idleTracker) deadline(now time.Time) time.Time {
+	var deadline time.Time
+	if t.readTimeout > 0 {
+		deadline = now.Add(t.readTimeout)
+	}
+	if t.heartbeatTimeout > 0 {
+		expiry := t.lastValid.Add(t.heartbeatTimeout)
+		if deadline.IsZero() || expiry.Before(deadline) {
+			deadline = expiry
+		}
+	}
+	return deadline
+}
+
+// expired 是否已超过心跳超时
+func (t *idleTracker) expired(now time.Time) bool {
+	return t.heartbeatTimeout > 0 && !now.Before(t.lastValid.Add(t.heartbeatTimeout))
+}
+
+// disconnectReason 根据读取错误判断连接断开原因
+func (t *idleTracker) disconnectReason(err error, now time.Time) string {
+	if isTimeout(err) {
+		if t.expired(now) {
+			return disconnectHeartbeatTimeout
+		}
+		return disconnectReadTimeout
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+		return disconnectClosed
+	}
+	return disconnectError
+}
+
+// connDevices 连接上报过的设备MN，按字母排序
+func connDevices(devices map[string]bool) []string {
+	list := make([]string, 0, len(devices))
+	for mn := range devices {
+		list = append(list, mn)
+	}
+	sort.Strings(list)
+	return list
+}
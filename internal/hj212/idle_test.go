@@ -0,0 +1,100 @@
+package hj212
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/env-data-platform/internal/config"
+)
+
+func TestIdleTracker(t *testing.T) {
+	start := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	idle := newIdleTracker(30*time.Second, time.Minute, start)
+
+	// 单次读超时早于心跳到期
+	assert.Equal(t, start.Add(30*time.Second), idle.deadline(start))
+	// 临近心跳到期时以心跳到期时间为准
+	assert.Equal(t, start.Add(time.Minute), idle.deadline(start.Add(50*time.Second)))
+	assert.False(t, idle.expired(start.Add(59*time.Second)))
+	assert.True(t, idle.expired(start.Add(time.Minute)))
+
+	idle.touch(start.Add(50 * time.Second))
+	assert.False(t, idle.expired(start.Add(time.Minute)))
+
+	// 0表示不限制
+	assert.True(t, newIdleTracker(0, 0, start).deadline(start).IsZero())
+	assert.Equal(t, start.Add(time.Minute), newIdleTracker(0, time.Minute, start).deadline(start))
+
+	assert.Equal(t, disconnectClosed, idle.disconnectReason(io.EOF, start))
+	assert.Equal(t, disconnectError, idle.disconnectReason(io.ErrUnexpectedEOF, start))
+}
+
+func TestServerV2HeartbeatTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := &ServerV2{
+		config: &config.HJ212Config{
+			BufferSize:       1024,
+			Timeout:          time.Second,
+			HeartbeatTimeout: 200 * time.Millisecond,
+		},
+		logger:      zap.NewNop(),
+		parser:      NewParser("2017"),
+		connections: make(map[string]net.Conn),
+		ctx:         ctx,
+		cancel:      cancel,
+		stats:       &ServerStats{StartTime: time.Now()},
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			s.handleConnection(conn)
+		}
+	}()
+
+	device, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer device.Close()
+	require.Eventually(t, func() bool {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		return len(s.connections) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// 只发无效数据，读操作能成功但不刷新心跳，到期后被服务端断开
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(50 * time.Millisecond)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := device.Write([]byte("noise\r\n")); err != nil {
+				return
+			}
+		}
+	}()
+
+	device.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, err = io.Copy(io.Discard, device)
+	require.False(t, isTimeout(err), "server should close the connection before the client read deadline")
+
+	require.Eventually(t, func() bool {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		s.stats.mu.RLock()
+		defer s.stats.mu.RUnlock()
+		return len(s.connections) == 0 && s.stats.Connections == 0
+	}, time.Second, 10*time.Millisecond)
+	device.Close()
+	<-done
+}
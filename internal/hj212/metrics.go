@@ -0,0 +1,133 @@
+package hj212
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// HJ212数据包总数，result为valid/invalid
+	hj212PacketsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "hj212_packets_total",
+			Help: "Total number of HJ212 packets received",
+		},
+		[]string{"result"},
+	)
+
+	// HJ212接收字节数
+	hj212ReceivedBytesTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "hj212_received_bytes_total",
+			Help: "Total bytes received by the HJ212 server",
+		},
+	)
+
+	// 收到的UDP数据报数
+	hj212UDPDatagramsTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "hj212_udp_datagrams_total",
+			Help: "Total number of UDP datagrams received by the HJ212 server",
+		},
+	)
+
+	// 当前连接数
+	hj212Connections = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "hj212_connections",
+			Help: "Number of current HJ212 device connections",
+		},
+	)
+
+	// 断开的连接数，reason为heartbeat_timeout/read_timeout/closed/error/shutdown
+	hj212DisconnectsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "hj212_disconnects_total",
+			Help: "Total number of HJ212 device connections closed, by reason",
+		},
+		[]string{"reason"},
+	)
+
+	// 连接数上限，与hj212_connections对比评估是否需要扩容
+	hj212MaxConnections = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "hj212_max_connections",
+			Help: "Configured maximum number of HJ212 device connections",
+		},
+	)
+
+	// 被拒绝的连接数，reason为max_connections
+	hj212RejectedConnectionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "hj212_rejected_connections_total",
+			Help: "Total number of HJ212 connections rejected by the server",
+		},
+		[]string{"reason"},
+	)
+
+	// 按设备统计的有效数据包数，用rate()计算各设备接收速率
+	hj212DevicePacketsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "hj212_device_packets_total",
+			Help: "Total number of valid HJ212 packets received per device",
+		},
+		[]string{"mn", "cn"},
+	)
+
+	// 按设备统计的接收字节数
+	hj212DeviceBytesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "hj212_device_received_bytes_total",
+			Help: "Total bytes of valid HJ212 packets received per device",
+		},
+		[]string{"mn"},
+	)
+
+	// 设备最后一次收包时间，用于判断设备在线
+	hj212DeviceLastPacket = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "hj212_device_last_packet_timestamp_seconds",
+			Help: "Unix time of the last valid packet received from each device",
+		},
+		[]string{"mn"},
+	)
+
+	// 鉴权未通过的数据包数，reason为unregistered/wrong_password
+	hj212UnauthorizedPacketsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "hj212_unauthorized_packets_total",
+			Help: "Total number of HJ212 packets rejected by device authentication",
+		},
+		[]string{"reason"},
+	)
+
+	// 实时质量校验发现的异常数，check为range/change_rate/flag，level为suspect/invalid
+	hj212QualityIssuesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "hj212_quality_issues_total",
+			Help: "Total number of factor anomalies found by HJ212 real-time quality checks",
+		},
+		[]string{"check", "level"},
+	)
+
+	// 重复的数据包数，source为cache(近期QN缓存)/database(唯一索引)
+	hj212DuplicatePacketsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "hj212_duplicate_packets_total",
+			Help: "Total number of retransmitted HJ212 data packets skipped by QN deduplication",
+		},
+		[]string{"source"},
+	)
+)
+
+// observeValidPacket 记录有效数据包指标，只对通过校验和鉴权的包打设备标签，避免非法MN造成标签膨胀
+func observeValidPacket(packet *Packet, size int) {
+	hj212PacketsTotal.WithLabelValues("valid").Inc()
+
+	if packet.MN == "" {
+		return
+	}
+	hj212DevicePacketsTotal.WithLabelValues(packet.MN, packet.CN).Inc()
+	hj212DeviceBytesTotal.WithLabelValues(packet.MN).Add(float64(size))
+	hj212DeviceLastPacket.WithLabelValues(packet.MN).SetToCurrentTime()
+}
@@ -0,0 +1,42 @@
+package hj212
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObserveValidPacket(t *testing.T) {
+	tests := []struct {
+		name   string
+		packet *Packet
+		size   int
+	}{
+		{name: "按设备和命令计数", packet: &Packet{MN: "88888880000101", CN: CN_GetRtdData}, size: 120},
+		{name: "同设备其他命令", packet: &Packet{MN: "88888880000101", CN: CN_GetMinuteData}, size: 80},
+		// 没有MN的包只计入总数，不产生设备标签
+		{name: "缺少MN", packet: &Packet{CN: CN_GetRtdData}, size: 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valid := testutil.ToFloat64(hj212PacketsTotal.WithLabelValues("valid"))
+			series := testutil.CollectAndCount(hj212DeviceBytesTotal)
+			if tt.packet.MN == "" {
+				observeValidPacket(tt.packet, tt.size)
+				assert.Equal(t, valid+1, testutil.ToFloat64(hj212PacketsTotal.WithLabelValues("valid")))
+				assert.Equal(t, series, testutil.CollectAndCount(hj212DeviceBytesTotal))
+				return
+			}
+
+			packets := testutil.ToFloat64(hj212DevicePacketsTotal.WithLabelValues(tt.packet.MN, tt.packet.CN))
+			bytes := testutil.ToFloat64(hj212DeviceBytesTotal.WithLabelValues(tt.packet.MN))
+			observeValidPacket(tt.packet, tt.size)
+
+			assert.Equal(t, valid+1, testutil.ToFloat64(hj212PacketsTotal.WithLabelValues("valid")))
+			assert.Equal(t, packets+1, testutil.ToFloat64(hj212DevicePacketsTotal.WithLabelValues(tt.packet.MN, tt.packet.CN)))
+			assert.Equal(t, bytes+float64(tt.size), testutil.ToFloat64(hj212DeviceBytesTotal.WithLabelValues(tt.packet.MN)))
+			assert.Positive(t, testutil.ToFloat64(hj212DeviceLastPacket.WithLabelValues(tt.packet.MN)))
+		})
+	}
+}
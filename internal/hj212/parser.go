@@ -3,9 +3,12 @@ package hj212
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/env-data-platform/internal/factor"
 )
 
 // CRC16计算表 (CRC-16-CCITT)
@@ -89,6 +92,10 @@ func (p *Parser) Parse(data []byte) (*Packet, error) {
 	if len(str) < expectedLen {
 		return nil, errors.New("incomplete packet")
 	}
+	// 结束符必须紧跟CRC，数据段内的\r\n不能当作包尾
+	if str[expectedLen-2:expectedLen] != "\r\n" {
+		return nil, errors.New("packet end not found")
+	}
 
 	// 提取数据段
 	dataSegment := str[6 : 6+dataLen]
@@ -125,6 +132,17 @@ func (p *Parser) parseDataSegment(data string) (*Packet, error) {
 		DataArea: make(map[string]string),
 	}
 
+	// CP数据区内部同样以分号分隔，先整体取出再分割其余字段
+	cpIndex := strings.Index(data, "CP=&&")
+	var cpData string
+	if cpIndex >= 0 {
+		cpData = data[cpIndex+len("CP=&&"):]
+		if end := strings.LastIndex(cpData, "&&"); end >= 0 {
+			cpData = cpData[:end]
+		}
+		data = data[:cpIndex]
+	}
+
 	// 分割数据段为字段
 	fields := strings.Split(data, ";")
 
@@ -174,6 +192,12 @@ func (p *Parser) parseDataSegment(data string) (*Packet, error) {
 		}
 	}
 
+	// 头部字段解析完成后再按CN解析CP
+	if cpIndex >= 0 {
+		packet.CP = cpData
+		p.parseCP(cpData, packet)
+	}
+
 	return packet, nil
 }
 
@@ -193,9 +217,11 @@ func (p *Parser) parseCP(cpData string, packet *Packet) {
 		p.parseHourData(cpData, packet)
 	case "2061": // 日数据
 		p.parseDayData(cpData, packet)
+	case "2041": // 设备运行时间日历史数据
+		p.parseDayData(cpData, packet)
 	case "2021": // 超标告警
 		p.parseAlarmData(cpData, packet)
-	case "9011", "9012": // 响应消息
+	case "9011", "9012", "9014": // 响应消息
 		p.parseResponse(cpData, packet)
 	default:
 		// 通用解析
@@ -205,7 +231,7 @@ func (p *Parser) parseCP(cpData string, packet *Packet) {
 
 // parseRealtimeData 解析实时数据
 func (p *Parser) parseRealtimeData(cpData string, packet *Packet) {
-	fields := strings.Split(cpData, ",")
+	fields := splitCPFields(cpData)
 
 	for _, field := range fields {
 		if field == "" {
@@ -236,6 +262,13 @@ func (p *Parser) parseRealtimeData(cpData string, packet *Packet) {
 	}
 }
 
+// splitCPFields 分割CP数据区字段，同一数据项内以逗号分隔，数据项之间以分号分隔
+func splitCPFields(cpData string) []string {
+	return strings.FieldsFunc(cpData, func(r rune) bool {
+		return r == ';' || r == ','
+	})
+}
+
 // parseFactorData 解析监测因子数据
 func (p *Parser) parseFactorData(key, value string, packet *Packet) {
 	// 分离因子编码和数据类型
@@ -255,8 +288,10 @@ func (p *Parser) parseFactorData(key, value string, packet *Packet) {
 	factor, exists := packet.Factors[factorCode]
 	if !exists {
 		factor = &FactorData{
-			Code: factorCode,
-			Name: getFactorName(factorCode),
+			Code:     factorCode,
+			Name:     getFactorName(factorCode),
+			Unit:     getFactorUnit(factorCode),
+			Category: factorCategory(factorCode, packet.ST),
 		}
 		packet.Factors[factorCode] = factor
 	}
@@ -315,7 +350,7 @@ func (p *Parser) parseDayData(cpData string, packet *Packet) {
 
 // parseAlarmData 解析告警数据
 func (p *Parser) parseAlarmData(cpData string, packet *Packet) {
-	fields := strings.Split(cpData, ",")
+	fields := splitCPFields(cpData)
 
 	packet.AlarmData = &AlarmData{
 		Factors: make(map[string]*AlarmFactor),
@@ -384,7 +419,7 @@ func (p *Parser) parseAlarmFactor(key, value string, alarmData *AlarmData) {
 
 // parseResponse 解析响应消息
 func (p *Parser) parseResponse(cpData string, packet *Packet) {
-	fields := strings.Split(cpData, ",")
+	fields := splitCPFields(cpData)
 
 	for _, field := range fields {
 		parts := strings.SplitN(field, "=", 2)
@@ -396,6 +431,8 @@ func (p *Parser) parseResponse(cpData string, packet *Packet) {
 		value := parts[1]
 
 		switch key {
+		case "QnRtn":
+			packet.QnRtn = value
 		case "ExeRtn":
 			packet.ExeRtn = value
 		case "RtnInfo":
@@ -408,7 +445,7 @@ func (p *Parser) parseResponse(cpData string, packet *Packet) {
 
 // parseGenericData 通用数据解析
 func (p *Parser) parseGenericData(cpData string, packet *Packet) {
-	fields := strings.Split(cpData, ",")
+	fields := splitCPFields(cpData)
 
 	for _, field := range fields {
 		if field == "" {
@@ -449,9 +486,13 @@ func (p *Parser) Build(packet *Packet) ([]byte, error) {
 	dataSegment.WriteString(fmt.Sprintf("MN=%s;", packet.MN))
 	dataSegment.WriteString(fmt.Sprintf("Flag=%d;", packet.Flag))
 
-	// 构建CP数据区
-	if packet.CP != "" {
-		dataSegment.WriteString(fmt.Sprintf("CP=&&%s&&", packet.CP))
+	// 构建CP数据区，未提供CP时由因子数据生成
+	cp := packet.CP
+	if cp == "" && len(packet.Factors) > 0 {
+		cp = p.buildFactorCP(packet)
+	}
+	if cp != "" {
+		dataSegment.WriteString(fmt.Sprintf("CP=&&%s&&", cp))
 	}
 
 	// 获取数据段字符串
@@ -466,6 +507,55 @@ func (p *Parser) Build(packet *Packet) ([]byte, error) {
 	return []byte(result), nil
 }
 
+// buildFactorCP 由因子数据生成CP数据区：DataTime在前，各因子按编码排序；
+// 实时数据输出Rtd，其余数据输出Avg/Max/Min及非零的Cou，Flag缺省为N
+func (p *Parser) buildFactorCP(packet *Packet) string {
+	dataTime := packet.DataTime
+	if dataTime.IsZero() {
+		dataTime = time.Now()
+	}
+	items := []string{"DataTime=" + dataTime.Format("20060102150405")}
+
+	codes := make([]string, 0, len(packet.Factors))
+	for code := range packet.Factors {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	for _, code := range codes {
+		factor := packet.Factors[code]
+		var fields []string
+		if packet.CN == CN_GetRtdData {
+			fields = append(fields, code+"-Rtd="+formatFactorValue(factor.Rtd))
+		} else {
+			fields = append(fields,
+				code+"-Avg="+formatFactorValue(factor.Avg),
+				code+"-Max="+formatFactorValue(factor.Max),
+				code+"-Min="+formatFactorValue(factor.Min))
+			if factor.Cou != 0 {
+				fields = append(fields, code+"-Cou="+formatFactorValue(factor.Cou))
+			}
+		}
+
+		flag := factor.Flag
+		if flag == "" {
+			flag = "N"
+		}
+		fields = append(fields, code+"-Flag="+flag)
+		if factor.EFlag != "" {
+			fields = append(fields, code+"-EFlag="+factor.EFlag)
+		}
+		items = append(items, strings.Join(fields, ","))
+	}
+
+	return strings.Join(items, ";")
+}
+
+// formatFactorValue 格式化因子数值，不输出多余的零
+func formatFactorValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
 // ValidatePacket 验证数据包
 func (p *Parser) ValidatePacket(packet *Packet) error {
 	// 验证必需字段
@@ -488,8 +578,8 @@ func (p *Parser) ValidatePacket(packet *Packet) error {
 	}
 
 	// 验证ST编码
-	if len(packet.ST) != 2 {
-		return errors.New("invalid ST format")
+	if err := validateSystemCode(packet.ST); err != nil {
+		return err
 	}
 
 	// 验证CN编码
@@ -500,38 +590,15 @@ func (p *Parser) ValidatePacket(packet *Packet) error {
 	return nil
 }
 
-// getFactorName 获取监测因子名称
+// getFactorName 从因子字典获取监测因子名称
 func getFactorName(code string) string {
-	factorNames := map[string]string{
-		"a01001": "温度",
-		"a01002": "湿度",
-		"a01006": "气压",
-		"a01007": "风速",
-		"a01008": "风向",
-		"a21001": "SO2",
-		"a21002": "NO",
-		"a21003": "NO2",
-		"a21004": "NOx",
-		"a21005": "CO",
-		"a21026": "O3",
-		"a34001": "TSP",
-		"a34002": "PM10",
-		"a34004": "PM2.5",
-		"w01001": "pH值",
-		"w01003": "溶解氧",
-		"w01009": "化学需氧量",
-		"w01010": "五日生化需氧量",
-		"w01018": "总磷",
-		"w01019": "总氮",
-		"w21001": "氨氮",
-		"w21003": "总氮",
-		"w21011": "总磷",
-	}
-
-	if name, ok := factorNames[code]; ok {
-		return name
-	}
-	return code
+	return factor.Default().Name(code)
+}
+
+// getFactorUnit 从因子字典获取监测因子单位
+func getFactorUnit(code string) string {
+	f, _ := factor.Default().Lookup(code)
+	return f.Unit
 }
 
 // GenerateQN 生成请求编号
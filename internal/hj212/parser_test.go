@@ -0,0 +1,89 @@
+package hj212
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildCPFromFactors(t *testing.T) {
+	p := NewParser("2017")
+	dataTime := time.Date(2024, 3, 1, 8, 0, 0, 0, time.Local)
+
+	data, err := p.Build(&Packet{
+		QN: "20240301080000001", ST: "22", CN: CN_GetRtdData, PW: "123456", MN: "MN001",
+		DataTime: dataTime,
+		Factors: map[string]*FactorData{
+			"a34004": {Rtd: 35.5},
+			"a21026": {Rtd: 120, Flag: "D"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "CP=&&DataTime=20240301080000;a21026-Rtd=120,a21026-Flag=D;a34004-Rtd=35.5,a34004-Flag=N&&")
+
+	packet, err := p.Parse(data)
+	require.NoError(t, err)
+	assert.Equal(t, dataTime, packet.DataTime)
+	assert.Equal(t, 35.5, packet.Factors["a34004"].Rtd)
+	assert.Equal(t, "D", packet.Factors["a21026"].Flag)
+
+	// 历史数据输出统计值，显式CP优先
+	data, err = p.Build(&Packet{
+		QN: "20240301080000002", ST: "32", CN: CN_GetHourData, MN: "MN001", DataTime: dataTime,
+		Factors: map[string]*FactorData{"w01018": {Avg: 12.5, Max: 15, Min: 10, Cou: 3.2}},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "CP=&&DataTime=20240301080000;w01018-Avg=12.5,w01018-Max=15,w01018-Min=10,w01018-Cou=3.2,w01018-Flag=N&&")
+
+	data, err = p.Build(&Packet{QN: "20240301080000003", CN: CN_GetRtdData, CP: "DataTime=20240301080000",
+		Factors: map[string]*FactorData{"a34004": {Rtd: 1}}})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "CP=&&DataTime=20240301080000&&")
+}
+
+func TestValidatePacketSystemCode(t *testing.T) {
+	p := NewParser("2017")
+	packet := &Packet{QN: "20240301080000001", CN: CN_GetRtdData, MN: "MN001"}
+
+	for _, st := range []string{ST_Water, ST_Air, ST_WasteWater, ST_Dust, ST_System} {
+		packet.ST = st
+		assert.NoError(t, p.ValidatePacket(packet), st)
+	}
+
+	packet.ST = "2A"
+	assert.EqualError(t, p.ValidatePacket(packet), "invalid ST format")
+
+	packet.ST = "99"
+	err := p.ValidatePacket(packet)
+	assert.True(t, errors.Is(err, ErrUnknownSystemCode))
+	assert.EqualError(t, err, "unknown ST code: 99")
+}
+
+func TestParseFactorCategory(t *testing.T) {
+	p := NewParser("2017")
+
+	// 噪声因子编码没有a/w前缀，按系统编码归类
+	data, err := p.Build(&Packet{
+		QN: "20240301080000001", ST: ST_Noise, CN: CN_GetRtdData, MN: "MN001",
+		DataTime: time.Date(2024, 3, 1, 8, 0, 0, 0, time.Local),
+		Factors: map[string]*FactorData{
+			"LA":     {Rtd: 55.2},
+			"a01001": {Rtd: 21},
+		},
+	})
+	require.NoError(t, err)
+
+	packet, err := p.Parse(data)
+	require.NoError(t, err)
+	assert.Equal(t, FactorCategoryNoise, packet.Factors["LA"].Category)
+	assert.Equal(t, FactorCategoryAir, packet.Factors["a01001"].Category)
+
+	code, ok := LookupSystemCode(ST_WasteWater)
+	require.True(t, ok)
+	assert.Equal(t, FactorCategoryWater, code.Category)
+	_, ok = LookupSystemCode("99")
+	assert.False(t, ok)
+}
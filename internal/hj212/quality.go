@@ -0,0 +1,292 @@
+package hj212
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/env-data-platform/internal/config"
+	"github.com/env-data-platform/internal/factor"
+	"github.com/env-data-platform/internal/models"
+)
+
+// DataQualityNotifier 实时质量校验发现异常数据时的告警接口，由告警检测器实现
+type DataQualityNotifier interface {
+	DataQualityAnomaly(data *models.HJ212Data, issues []models.HJ212QualityIssue)
+}
+
+// valueRange 因子值的合理区间，为空的一端不限制
+type valueRange struct {
+	min *float64
+	max *float64
+}
+
+func bound(v float64) *float64 {
+	return &v
+}
+
+// builtinRanges 常用因子的物理合理区间，浓度类因子不应为负
+var builtinRanges = map[string]valueRange{
+	"a01001": {min: bound(-60), max: bound(70)}, // 温度
+	"a01002": {min: bound(0), max: bound(100)},  // 湿度
+	"a01006": {min: bound(50), max: bound(110)}, // 气压
+	"a01007": {min: bound(0), max: bound(75)},   // 风速
+	"a01008": {min: bound(0), max: bound(360)},  // 风向
+	"a21001": {min: bound(0)},
+	"a21002": {min: bound(0)},
+	"a21003": {min: bound(0)},
+	"a21004": {min: bound(0)},
+	"a21005": {min: bound(0)},
+	"a21026": {min: bound(0)},
+	"a34001": {min: bound(0)},
+	"a34002": {min: bound(0)},
+	"a34004": {min: bound(0)},
+	"w01001": {min: bound(0), max: bound(14)}, // pH
+	"w01003": {min: bound(0), max: bound(20)}, // 溶解氧
+	"w01009": {min: bound(0)},
+	"w01010": {min: bound(0)},
+	"w01018": {min: bound(0)},
+	"w01019": {min: bound(0)},
+	"w21001": {min: bound(0)},
+	"w21003": {min: bound(0)},
+	"w21011": {min: bound(0)},
+}
+
+// flagQuality HJ212-2017数据标记对应的质量等级，N为正常
+var flagQuality = map[string]struct {
+	level string
+	desc  string
+}{
+	"F": {models.HJ212QualityInvalid, "停运"},
+	"M": {models.HJ212QualityInvalid, "维护"},
+	"D": {models.HJ212QualityInvalid, "故障"},
+	"C": {models.HJ212QualityInvalid, "校准"},
+	"B": {models.HJ212QualityInvalid, "通讯异常"},
+	"S": {models.HJ212QualitySuspect, "手工输入"},
+	"T": {models.HJ212QualitySuspect, "超测定上限"},
+}
+
+// lastFactorValue 变化率检查的基准值
+type lastFactorValue struct {
+	value float64
+	at    time.Time
+}
+
+// qualityChecker 收包时的实时质量校验：合理区间、与上一条相比的变化率和数据标记
+type qualityChecker struct {
+	cfg    config.HJ212QualityConfig
+	ranges map[string]valueRange
+
+	mu      sync.Mutex
+	last    map[string]lastFactorValue // 设备|命令|因子 -> 最近一条正常值
+	alarmed map[string]time.Time       // 设备|因子|检查项 -> 最近告警时间
+}
+
+// newQualityChecker 创建质量校验器，配置的区间覆盖同因子的内置区间
+func newQualityChecker(cfg config.HJ212QualityConfig) *qualityChecker {
+	ranges := make(map[string]valueRange, len(builtinRanges)+len(cfg.Ranges))
+	for code, r := range builtinRanges {
+		ranges[code] = r
+	}
+	for code, r := range cfg.Ranges {
+		ranges[code] = valueRange{min: r.Min, max: r.Max}
+	}
+
+	return &qualityChecker{
+		cfg:     cfg,
+		ranges:  ranges,
+		last:    make(map[string]lastFactorValue),
+		alarmed: make(map[string]time.Time),
+	}
+}
+
+// check 校验数据包中的各因子，返回发现的异常；未启用时返回nil
+func (q *qualityChecker) check(packet *Packet) []models.HJ212QualityIssue {
+	if q == nil || !q.cfg.Enabled || len(packet.Factors) == 0 {
+		return nil
+	}
+
+	at := packet.DataTime
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	codes := make([]string, 0, len(packet.Factors))
+	for code := range packet.Factors {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	var issues []models.HJ212QualityIssue
+	for _, code := range codes {
+		f := packet.Factors[code]
+		value := factorValue(packet.CN, f)
+		found := len(issues)
+
+		if issue, ok := checkFlag(code, f, value); ok {
+			issues = append(issues, issue)
+		}
+		if issue, ok := q.checkRange(code, value); ok {
+			issues = append(issues, issue)
+		}
+		// 已判定异常的值不参与变化率比较，也不作为下一条的基准
+		if len(issues) > found {
+			continue
+		}
+		if issue, ok := q.checkChange(packet.MN, packet.CN, code, value, at); ok {
+			issues = append(issues, issue)
+		}
+	}
+
+	for _, issue := range issues {
+		hj212QualityIssuesTotal.WithLabelValues(issue.Check, issue.Level).Inc()
+	}
+	return issues
+}
+
+// factorValue 参与校验的因子值，实时数据取Rtd，分钟/小时/日数据取Avg
+func factorValue(cn string, f *FactorData) float64 {
+	if cn == CN_GetRtdData {
+		return f.Rtd
+	}
+	return f.Avg
+}
+
+// checkFlag 数据标记为非N时按标记判定质量等级，设备上报EFlag异常标记时判为可疑
+func checkFlag(code string, f *FactorData, value float64) (models.HJ212QualityIssue, bool) {
+	flag := strings.ToUpper(strings.TrimSpace(f.Flag))
+	if flag == "" || flag == "N" {
+		if f.EFlag == "" {
+			return models.HJ212QualityIssue{}, false
+		}
+		return models.HJ212QualityIssue{
+			FactorCode: code,
+			Check:      models.HJ212QualityCheckFlag,
+			Level:      models.HJ212QualitySuspect,
+			Value:      value,
+			Message:    fmt.Sprintf("%s异常标记为%s", factor.Default().Name(code), f.EFlag),
+		}, true
+	}
+
+	level, desc := models.HJ212QualitySuspect, "未知标记"
+	if q, ok := flagQuality[flag]; ok {
+		level, desc = q.level, q.desc
+	}
+	return models.HJ212QualityIssue{
+		FactorCode: code,
+		Check:      models.HJ212QualityCheckFlag,
+		Level:      level,
+		Value:      value,
+		Message:    fmt.Sprintf("%s数据标记为%s(%s)", factor.Default().Name(code), flag, desc),
+	}, true
+}
+
+// checkRange 超出合理区间的值判为无效
+func (q *qualityChecker) checkRange(code string, value float64) (models.HJ212QualityIssue, bool) {
+	r, ok := q.ranges[code]
+	if !ok {
+		return models.HJ212QualityIssue{}, false
+	}
+	if (r.min == nil || value >= *r.min) && (r.max == nil || value <= *r.max) {
+		return models.HJ212QualityIssue{}, false
+	}
+	return models.HJ212QualityIssue{
+		FactorCode: code,
+		Check:      models.HJ212QualityCheckRange,
+		Level:      models.HJ212QualityInvalid,
+		Value:      value,
+		Message:    fmt.Sprintf("%s值%g超出合理区间[%s, %s]", factor.Default().Name(code), value, formatBound(r.min, "-∞"), formatBound(r.max, "+∞")),
+	}, true
+}
+
+// checkChange 与窗口内上一条正常值相比变化量超过上一条值的MaxChangeRatio倍时判为可疑，
+// 突变值不更新基准，避免一次尖峰导致回落的正常值也被判为突变
+func (q *qualityChecker) checkChange(mn, cn, code string, value float64, at time.Time) (models.HJ212QualityIssue, bool) {
+	if q.cfg.MaxChangeRatio <= 0 {
+		return models.HJ212QualityIssue{}, false
+	}
+
+	key := mn + "|" + cn + "|" + code
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	prev, ok := q.last[key]
+	// 补传的历史数据早于基准时不比较
+	if ok && at.Before(prev.at) {
+		return models.HJ212QualityIssue{}, false
+	}
+	if !ok || prev.value == 0 || (q.cfg.ChangeWindow > 0 && at.Sub(prev.at) > q.cfg.ChangeWindow) {
+		q.last[key] = lastFactorValue{value: value, at: at}
+		return models.HJ212QualityIssue{}, false
+	}
+
+	if math.Abs(value-prev.value) <= math.Abs(prev.value)*q.cfg.MaxChangeRatio {
+		q.last[key] = lastFactorValue{value: value, at: at}
+		return models.HJ212QualityIssue{}, false
+	}
+	return models.HJ212QualityIssue{
+		FactorCode: code,
+		Check:      models.HJ212QualityCheckChange,
+		Level:      models.HJ212QualitySuspect,
+		Value:      value,
+		Message: fmt.Sprintf("%s值%g与上一条%g相比变化超过%g倍",
+			factor.Default().Name(code), value, prev.value, q.cfg.MaxChangeRatio),
+	}, true
+}
+
+// alarmIssues 需要告警的异常，同一设备同一因子同类异常在AlarmCooldown内只告警一次
+func (q *qualityChecker) alarmIssues(mn string, issues []models.HJ212QualityIssue, now time.Time) []models.HJ212QualityIssue {
+	if q == nil || !q.cfg.Alarm || len(issues) == 0 {
+		return nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var result []models.HJ212QualityIssue
+	for _, issue := range issues {
+		key := mn + "|" + issue.FactorCode + "|" + issue.Check
+		if last, ok := q.alarmed[key]; ok && now.Sub(last) < q.cfg.AlarmCooldown {
+			continue
+		}
+		q.alarmed[key] = now
+		result = append(result, issue)
+	}
+	return result
+}
+
+// applyQualityIssues 按最严重的异常设置数据的质量等级，无效数据标记为不可用，
+// 异常明细写入解析数据的quality_issues
+func applyQualityIssues(data *models.HJ212Data, issues []models.HJ212QualityIssue) {
+	if len(issues) == 0 {
+		return
+	}
+
+	level := models.HJ212QualitySuspect
+	messages := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		if issue.Level == models.HJ212QualityInvalid {
+			level = models.HJ212QualityInvalid
+		}
+		messages = append(messages, issue.Message)
+	}
+
+	data.QualityLevel = level
+	data.IsValid = level != models.HJ212QualityInvalid
+	data.ErrorMessage = strings.Join(messages, "; ")
+	if data.ParsedData == nil {
+		data.ParsedData = make(models.JSONMap)
+	}
+	data.ParsedData["quality_issues"] = issues
+}
+
+// formatBound 区间端点，不限制时显示为infinite
+func formatBound(v *float64, infinite string) string {
+	if v == nil {
+		return infinite
+	}
+	return fmt.Sprintf("%g", *v)
+}
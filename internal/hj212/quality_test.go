@@ -0,0 +1,136 @@
+package hj212
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/env-data-platform/internal/config"
+	"github.com/env-data-platform/internal/models"
+)
+
+func testQualityConfig() config.HJ212QualityConfig {
+	return config.HJ212QualityConfig{
+		Enabled:        true,
+		MaxChangeRatio: 5,
+		ChangeWindow:   30 * time.Minute,
+		Alarm:          true,
+		AlarmCooldown:  30 * time.Minute,
+	}
+}
+
+func rtdPacket(at time.Time, factors map[string]*FactorData) *Packet {
+	return &Packet{MN: "MN001", CN: CN_GetRtdData, DataTime: at, Factors: factors}
+}
+
+func TestQualityCheckerRange(t *testing.T) {
+	max := 500.0
+	cfg := testQualityConfig()
+	cfg.Ranges = map[string]config.HJ212FactorRange{"a34004": {Max: &max}}
+	q := newQualityChecker(cfg)
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local)
+
+	issues := q.check(rtdPacket(now, map[string]*FactorData{
+		"a34004": {Rtd: 800, Flag: "N"},
+		"w01001": {Rtd: 15, Flag: "N"},
+		"a01001": {Rtd: -10, Flag: "N"},
+	}))
+	require.Len(t, issues, 2)
+	// 配置的区间覆盖内置的非负下限
+	assert.Equal(t, "a34004", issues[0].FactorCode)
+	assert.Equal(t, models.HJ212QualityCheckRange, issues[0].Check)
+	assert.Equal(t, models.HJ212QualityInvalid, issues[0].Level)
+	assert.Contains(t, issues[0].Message, "[-∞, 500]")
+	assert.Equal(t, "w01001", issues[1].FactorCode)
+}
+
+func TestQualityCheckerFlag(t *testing.T) {
+	q := newQualityChecker(testQualityConfig())
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local)
+
+	issues := q.check(rtdPacket(now, map[string]*FactorData{
+		"a21001": {Rtd: 10, Flag: "D"},
+		"a21002": {Rtd: 10, Flag: "T"},
+		"a21003": {Rtd: 10, Flag: "N"},
+		"a21004": {Rtd: 10, Flag: "N", EFlag: "E1"},
+	}))
+	require.Len(t, issues, 3)
+	assert.Equal(t, models.HJ212QualityInvalid, issues[0].Level)
+	assert.Equal(t, models.HJ212QualitySuspect, issues[1].Level)
+	assert.Equal(t, "a21004", issues[2].FactorCode)
+	assert.Equal(t, models.HJ212QualitySuspect, issues[2].Level)
+}
+
+func TestQualityCheckerChangeRate(t *testing.T) {
+	q := newQualityChecker(testQualityConfig())
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local)
+	check := func(at time.Time, v float64) []models.HJ212QualityIssue {
+		return q.check(rtdPacket(at, map[string]*FactorData{"a34004": {Rtd: v, Flag: "N"}}))
+	}
+
+	assert.Empty(t, check(now, 20))
+	assert.Empty(t, check(now.Add(time.Minute), 60))
+
+	// 变化量超过上一条的5倍判为突变，突变值不作为基准
+	issues := check(now.Add(2*time.Minute), 500)
+	require.Len(t, issues, 1)
+	assert.Equal(t, models.HJ212QualityCheckChange, issues[0].Check)
+	assert.Equal(t, models.HJ212QualitySuspect, issues[0].Level)
+	assert.Empty(t, check(now.Add(3*time.Minute), 70))
+
+	// 早于基准的补传数据不比较
+	assert.Empty(t, check(now, 1000))
+
+	// 超过窗口后重新建立基准
+	assert.Empty(t, check(now.Add(time.Hour), 1000))
+	assert.Empty(t, check(now.Add(61*time.Minute), 1200))
+
+	// 不同设备分别比较
+	other := rtdPacket(now.Add(62*time.Minute), map[string]*FactorData{"a34004": {Rtd: 10, Flag: "N"}})
+	other.MN = "MN002"
+	assert.Empty(t, q.check(other))
+}
+
+func TestQualityCheckerDisabled(t *testing.T) {
+	cfg := testQualityConfig()
+	cfg.Enabled = false
+	q := newQualityChecker(cfg)
+
+	assert.Nil(t, q.check(rtdPacket(time.Now(), map[string]*FactorData{"w01001": {Rtd: 20, Flag: "D"}})))
+}
+
+func TestQualityCheckerAlarmCooldown(t *testing.T) {
+	q := newQualityChecker(testQualityConfig())
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local)
+	issues := []models.HJ212QualityIssue{
+		{FactorCode: "a34004", Check: models.HJ212QualityCheckRange},
+		{FactorCode: "a34004", Check: models.HJ212QualityCheckFlag},
+	}
+
+	assert.Len(t, q.alarmIssues("MN001", issues, now), 2)
+	assert.Empty(t, q.alarmIssues("MN001", issues, now.Add(10*time.Minute)))
+	assert.Len(t, q.alarmIssues("MN002", issues[:1], now.Add(10*time.Minute)), 1)
+	assert.Len(t, q.alarmIssues("MN001", issues, now.Add(30*time.Minute)), 2)
+}
+
+func TestApplyQualityIssues(t *testing.T) {
+	data := &models.HJ212Data{QualityLevel: models.HJ212QualityNormal, IsValid: true}
+	applyQualityIssues(data, nil)
+	assert.Equal(t, models.HJ212QualityNormal, data.QualityLevel)
+	assert.True(t, data.IsValid)
+
+	applyQualityIssues(data, []models.HJ212QualityIssue{{Level: models.HJ212QualitySuspect, Message: "a"}})
+	assert.Equal(t, models.HJ212QualitySuspect, data.QualityLevel)
+	assert.True(t, data.IsValid)
+
+	applyQualityIssues(data, []models.HJ212QualityIssue{
+		{Level: models.HJ212QualitySuspect, Message: "a"},
+		{Level: models.HJ212QualityInvalid, Message: "b"},
+	})
+	assert.Equal(t, models.HJ212QualityInvalid, data.QualityLevel)
+	assert.False(t, data.IsValid)
+	assert.Equal(t, "a; b", data.ErrorMessage)
+	assert.Len(t, data.ParsedData["quality_issues"], 2)
+}
@@ -0,0 +1,98 @@
+package hj212
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// rejectSampleInterval 同一来源IP被拒绝连接时的日志采样间隔
+const rejectSampleInterval = time.Minute
+
+// rejectSampler 按来源IP对被拒绝连接的日志采样，连接风暴时每个IP每个间隔只记一条
+type rejectSampler struct {
+	interval time.Duration
+	mu       sync.Mutex
+	sources  map[string]*rejectSource
+}
+
+// rejectSource 单个来源IP的采样状态
+type rejectSource struct {
+	lastLogged time.Time
+	suppressed int
+}
+
+func newRejectSampler(interval time.Duration) *rejectSampler {
+	return &rejectSampler{
+		interval: interval,
+		sources:  make(map[string]*rejectSource),
+	}
+}
+
+// sample 判断本次拒绝是否需要记录日志，同时返回上次记录后被抑制的次数
+func (r *rejectSampler) sample(ip string, now time.Time) (bool, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	source, ok := r.sources[ip]
+	if !ok {
+		r.sources[ip] = &rejectSource{lastLogged: now}
+		return true, 0
+	}
+	if now.Sub(source.lastLogged) < r.interval {
+		source.suppressed++
+		return false, 0
+	}
+	suppressed := source.suppressed
+	source.lastLogged = now
+	source.suppressed = 0
+	return true, suppressed
+}
+
+// prune 删除超过采样间隔未再被拒绝的来源
+func (r *rejectSampler) prune(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for ip, source := range r.sources {
+		if now.Sub(source.lastLogged) >= r.interval {
+			delete(r.sources, ip)
+		}
+	}
+}
+
+// rejectConnection 达到最大连接数时拒绝新连接，累计统计和指标，并按来源IP采样记录日志
+func (s *ServerV2) rejectConnection(conn net.Conn, connCount int) {
+	conn.Close()
+
+	s.stats.mu.Lock()
+	s.stats.RejectedConnections++
+	s.stats.LastRejectedTime = time.Now()
+	s.stats.mu.Unlock()
+	s.rejects.reject(s.logger, conn, connCount, s.config.MaxConnections)
+}
+
+// rejectConnection 达到最大连接数时拒绝新连接，记录指标并按来源IP采样记录日志
+func (s *Server) rejectConnection(conn net.Conn, connCount int) {
+	conn.Close()
+	s.rejects.reject(s.logger, conn, connCount, s.config.HJ212.MaxConnections)
+}
+
+// reject 记录被拒绝连接的指标，按来源IP采样记录日志
+func (r *rejectSampler) reject(logger *zap.Logger, conn net.Conn, connCount, limit int) {
+	hj212RejectedConnectionsTotal.WithLabelValues("max_connections").Inc()
+
+	ip := conn.RemoteAddr().String()
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	if ok, suppressed := r.sample(ip, time.Now()); ok {
+		logger.Warn("Max connections reached, rejecting new connection",
+			zap.String("remote_ip", ip),
+			zap.Int("connections", connCount),
+			zap.Int("max", limit),
+			zap.Int("suppressed", suppressed))
+	}
+}
@@ -0,0 +1,35 @@
+package hj212
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRejectSampler(t *testing.T) {
+	sampler := newRejectSampler(time.Minute)
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local)
+
+	logged, suppressed := sampler.sample("10.0.0.1", now)
+	assert.True(t, logged)
+	assert.Zero(t, suppressed)
+
+	// 采样间隔内同一IP只计数不记录，其他IP不受影响
+	for i := 0; i < 3; i++ {
+		logged, _ = sampler.sample("10.0.0.1", now.Add(time.Second))
+		assert.False(t, logged)
+	}
+	logged, _ = sampler.sample("10.0.0.2", now.Add(time.Second))
+	assert.True(t, logged)
+
+	// 超过间隔后再次记录，并带上被抑制的次数
+	logged, suppressed = sampler.sample("10.0.0.1", now.Add(time.Minute))
+	assert.True(t, logged)
+	assert.Equal(t, 3, suppressed)
+
+	sampler.prune(now.Add(90 * time.Second))
+	assert.Len(t, sampler.sources, 1)
+	sampler.prune(now.Add(2 * time.Minute))
+	assert.Empty(t, sampler.sources)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
@@ -31,13 +32,35 @@ type AlarmDetector interface {
 type Server struct {
 	config        *config.Config
 	logger        *zap.Logger
-	listener      net.Listener
+	listeners     []net.Listener // 明文和TLS监听
+	udpConn       net.PacketConn // UDP监听，未启用时为nil
+	listenerMu    sync.RWMutex
 	clients       sync.Map // 存储客户端连接
 	ctx           context.Context
 	cancel        context.CancelFunc
 	parser        *Parser        // 使用新的解析器
 	wsHub         WSHub          // WebSocket集线器接口
 	alarmDetector AlarmDetector  // 告警检测器接口
+	commands      *commandTracker // 已下发等待应答的命令
+
+	dedup           *packetDedup        // 按设备MN+QN识别重传的数据包
+	quality         *qualityChecker     // 收包实时质量校验
+	qualityNotifier DataQualityNotifier // 异常数据告警，为空时只标记质量等级
+
+	// 设备白名单与密码校验
+	auth *deviceAuthenticator
+
+	// 原始报文归档，未开启时为nil
+	archive *packetArchive
+
+	// 当前TCP连接数，达到MaxConnections时拒绝新连接并按来源IP采样记录日志
+	connections atomic.Int64
+	rejects     *rejectSampler
+
+	// 设备MN到最后收包时间，用于离线检测；设备断开连接后仍保留到判定离线
+	lastSeen   map[string]time.Time
+	lastSeenMu sync.Mutex
+	status     *deviceStatusRecorder
 }
 
 // Client 客户端连接信息
@@ -45,6 +68,8 @@ type Client struct {
 	Conn       net.Conn
 	MN         string    // 设备唯一标识
 	LastActive time.Time // 最后活跃时间
+	ST         string    // 最近一次上报的系统编码，下发命令时沿用
+	PW         string    // 最近一次上报的访问密码
 }
 
 // NewServer 创建HJ212服务器
@@ -60,46 +85,87 @@ func NewServer(cfg *config.Config, logger *zap.Logger, wsHub WSHub, alarmDetecto
 		parser:        parser,
 		wsHub:         wsHub,
 		alarmDetector: alarmDetector,
+		commands:      newCommandTracker(),
+		auth:          newDeviceAuthenticator(cfg.HJ212.Auth, database.GetDB(), logger),
+		lastSeen:      make(map[string]time.Time),
+		archive:       newPacketArchive(cfg.HJ212.Archive, logger),
+		rejects:       newRejectSampler(rejectSampleInterval),
+		status:        &deviceStatusRecorder{db: database.GetDB(), logger: logger},
+		dedup:         newPacketDedup(cfg.HJ212.DedupWindow),
+		quality:       newQualityChecker(cfg.HJ212.Quality),
 	}
 }
 
-// Start 启动服务器
+// SetQualityNotifier 设置异常数据告警，为空时只标记质量等级
+func (s *Server) SetQualityNotifier(notifier DataQualityNotifier) {
+	s.qualityNotifier = notifier
+}
+
+// Start 启动服务器，按配置监听明文和TLS端口，阻塞直到服务器停止
 func (s *Server) Start() error {
 	if !s.config.HJ212.Enabled {
 		s.logger.Info("HJ212 server is disabled")
 		return nil
 	}
 
-	addr := fmt.Sprintf(":%d", s.config.HJ212.TCPPort)
-	listener, err := net.Listen("tcp", addr)
+	listeners, err := listenTCP(&s.config.HJ212)
 	if err != nil {
-		return fmt.Errorf("failed to listen on %s: %v", addr, err)
+		return err
+	}
+	udpConn, err := listenUDP(&s.config.HJ212)
+	if err != nil {
+		for _, listener := range listeners {
+			listener.Close()
+		}
+		return err
 	}
 
-	s.listener = listener
-	s.logger.Info("HJ212 server started", zap.String("address", addr))
+	s.listenerMu.Lock()
+	s.listeners = listeners
+	s.udpConn = udpConn
+	s.listenerMu.Unlock()
+
+	hj212MaxConnections.Set(float64(s.config.HJ212.MaxConnections))
 
 	// 启动客户端清理协程
 	go s.cleanupClients()
 
 	// 接受连接
+	for _, listener := range listeners {
+		s.logger.Info("HJ212 server started", zap.String("address", listener.Addr().String()))
+		go s.acceptConnections(listener)
+	}
+	if udpConn != nil {
+		s.logger.Info("HJ212 UDP server started", zap.String("address", udpConn.LocalAddr().String()))
+		go s.serveUDP(udpConn)
+	}
+
+	<-s.ctx.Done()
+	return nil
+}
+
+// acceptConnections 接受新连接，服务器关闭时退出
+func (s *Server) acceptConnections(listener net.Listener) {
 	for {
-		select {
-		case <-s.ctx.Done():
-			return s.ctx.Err()
-		default:
-			conn, err := listener.Accept()
-			if err != nil {
-				if s.ctx.Err() != nil {
-					return nil // 服务器正在关闭
-				}
-				s.logger.Error("Failed to accept connection", zap.Error(err))
-				continue
+		conn, err := listener.Accept()
+		if err != nil {
+			if s.ctx.Err() != nil {
+				return // 服务器正在关闭
 			}
+			s.logger.Error("Failed to accept connection", zap.Error(err))
+			continue
+		}
 
-			// 处理新连接
-			go s.handleConnection(conn)
+		// 限制最大连接数，未配置时不限制
+		if limit := s.config.HJ212.MaxConnections; limit > 0 {
+			if count := int(s.connections.Load()); count >= limit {
+				s.rejectConnection(conn, count)
+				continue
+			}
 		}
+
+		// 处理新连接
+		go s.handleConnection(conn)
 	}
 }
 
@@ -107,11 +173,17 @@ func (s *Server) Start() error {
 func (s *Server) Stop() error {
 	s.cancel()
 
-	if s.listener != nil {
-		if err := s.listener.Close(); err != nil {
+	s.listenerMu.RLock()
+	listeners, udpConn := s.listeners, s.udpConn
+	s.listenerMu.RUnlock()
+	for _, listener := range listeners {
+		if err := listener.Close(); err != nil {
 			s.logger.Error("Failed to close listener", zap.Error(err))
 		}
 	}
+	if udpConn != nil {
+		udpConn.Close()
+	}
 
 	// 关闭所有客户端连接
 	s.clients.Range(func(key, value interface{}) bool {
@@ -121,89 +193,186 @@ func (s *Server) Stop() error {
 		return true
 	})
 
+	s.archive.Close()
+
 	s.logger.Info("HJ212 server stopped")
 	return nil
 }
 
-// handleConnection 处理客户端连接
+// ListenAddr 返回正在监听的地址（仅TLS模式下为TLS地址），未启动或已停止时返回false
+func (s *Server) ListenAddr() (string, bool) {
+	s.listenerMu.RLock()
+	defer s.listenerMu.RUnlock()
+
+	if len(s.listeners) == 0 || s.ctx.Err() != nil {
+		return "", false
+	}
+	return s.listeners[0].Addr().String(), true
+}
+
+// handleConnection 处理客户端连接，超过心跳超时未收到有效数据包时主动断开，
+// 退出时清理本连接登记的设备映射
 func (s *Server) handleConnection(conn net.Conn) {
 	defer conn.Close()
 
 	clientAddr := conn.RemoteAddr().String()
 	s.logger.Info("New HJ212 client connected", zap.String("address", clientAddr))
-
-	// 设置读取超时
-	conn.SetReadDeadline(time.Now().Add(s.config.HJ212.Timeout))
+	s.connections.Add(1)
+	hj212Connections.Inc()
+
+	idle := newIdleTracker(s.config.HJ212.Timeout, s.config.HJ212.HeartbeatTimeout, time.Now())
+	devices := make(map[string]bool) // 本连接上报过的设备MN
+	defer func() {
+		for mn := range devices {
+			s.releaseClient(mn, conn)
+		}
+		s.connections.Add(-1)
+		hj212Connections.Dec()
+	}()
 
 	buffer := make([]byte, s.config.HJ212.BufferSize)
+	var dataBuffer []byte
 
 	for {
 		select {
 		case <-s.ctx.Done():
+			hj212DisconnectsTotal.WithLabelValues(disconnectShutdown).Inc()
 			return
 		default:
-			// 读取数据
+			// 每次读取前按最后一次有效数据包的时间重新计算超时
+			conn.SetReadDeadline(idle.deadline(time.Now()))
+
 			n, err := conn.Read(buffer)
 			if err != nil {
-				if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
-					s.logger.Debug("Client connection timeout", zap.String("address", clientAddr))
+				reason := idle.disconnectReason(err, time.Now())
+				if s.ctx.Err() != nil {
+					reason = disconnectShutdown
+				}
+				hj212DisconnectsTotal.WithLabelValues(reason).Inc()
+				if reason == disconnectHeartbeatTimeout {
+					s.logger.Warn("Closing HJ212 client without valid packets within heartbeat timeout",
+						zap.String("address", clientAddr),
+						zap.Duration("heartbeat_timeout", s.config.HJ212.HeartbeatTimeout),
+						zap.Strings("devices", connDevices(devices)))
 				} else {
-					s.logger.Debug("Client disconnected", zap.String("address", clientAddr), zap.Error(err))
+					s.logger.Debug("Client disconnected",
+						zap.String("address", clientAddr),
+						zap.String("reason", reason),
+						zap.Error(err))
 				}
 				return
 			}
 
 			if n > 0 {
-				// 重置读取超时
-				conn.SetReadDeadline(time.Now().Add(s.config.HJ212.Timeout))
-
-				// 处理接收到的数据
-				data := string(buffer[:n])
-				s.handleMessage(conn, clientAddr, data)
+				hj212ReceivedBytesTotal.Add(float64(n))
+				// 累积数据并按包头长度切包，一次读取可能包含多个包或半个包
+				dataBuffer = append(dataBuffer, buffer[:n]...)
+				for len(dataBuffer) > 0 {
+					end := splitPacket(dataBuffer)
+					if end == 0 {
+						break
+					}
+					data := string(dataBuffer[:end])
+					dataBuffer = dataBuffer[end:]
+					if mn, ok := s.handleMessage(conn, clientAddr, data); ok {
+						idle.touch(time.Now())
+						if mn != "" {
+							devices[mn] = true
+						}
+					}
+				}
 			}
 		}
 	}
 }
 
-// handleMessage 处理HJ212消息
-func (s *Server) handleMessage(conn net.Conn, clientAddr, data string) {
+// releaseClient 连接断开时移除设备映射，设备已在新连接上登记时保留
+func (s *Server) releaseClient(mn string, conn net.Conn) {
+	value, ok := s.clients.Load(mn)
+	if !ok {
+		return
+	}
+	if client, ok := value.(*Client); ok && client.Conn == conn {
+		s.clients.CompareAndDelete(mn, value)
+	}
+}
+
+// handleMessage 处理HJ212消息，返回设备MN及数据包是否有效
+func (s *Server) handleMessage(conn net.Conn, clientAddr, data string) (string, bool) {
 	s.logger.Debug("Received HJ212 data",
 		zap.String("address", clientAddr),
 		zap.String("data", data))
+	s.archive.record(archiveReceived, "", conn.RemoteAddr().String(), []byte(data))
 
 	// 使用新解析器解析HJ212消息
 	packet, err := s.parser.Parse([]byte(data))
 	if err != nil {
+		hj212PacketsTotal.WithLabelValues("invalid").Inc()
 		s.logger.Warn("Failed to parse HJ212 packet",
 			zap.String("address", clientAddr),
 			zap.Error(err),
 			zap.String("data", data))
-		return
+		s.recordDeadLetter(conn, models.HJ212DeadLetterStageParse, []byte(data), nil, err)
+		return "", false
 	}
 
 	// 验证消息有效性
 	if err := s.parser.ValidatePacket(packet); err != nil {
+		hj212PacketsTotal.WithLabelValues("invalid").Inc()
 		s.logger.Warn("Invalid HJ212 packet",
 			zap.String("address", clientAddr),
 			zap.Error(err),
 			zap.Any("packet", packet))
-		return
+		s.recordDeadLetter(conn, models.HJ212DeadLetterStageValidate, []byte(data), packet, err)
+		return "", false
 	}
 
+	// 设备白名单与密码校验，未通过的包不登记设备也不入库
+	if rtn, reason := s.auth.authorize(packet); rtn != "" {
+		s.rejectPacket(conn, clientAddr, packet, rtn, reason)
+		return "", false
+	}
+	observeValidPacket(packet, len(data))
+	s.touchDevice(packet.MN)
+
 	// 更新客户端信息
 	if packet.MN != "" {
 		client := &Client{
 			Conn:       conn,
 			MN:         packet.MN,
 			LastActive: time.Now(),
+			ST:         packet.ST,
+			PW:         packet.PW,
+		}
+		if previous, ok := s.clients.Load(packet.MN); ok {
+			if prev, ok := previous.(*Client); ok {
+				// 应答包的ST为系统交互，沿用设备上次上报的包头
+				if packet.ST == ST_System {
+					client.ST, client.PW = prev.ST, prev.PW
+				}
+				// 设备已在新连接上报，旧连接多半已悬挂，主动关闭；UDP每个数据报的来源都是新的udpPeer，无需关闭
+				if _, udp := prev.Conn.(*udpPeer); !udp && prev.Conn != conn {
+					s.logger.Info("HJ212 device reconnected, closing previous connection",
+						zap.String("mn", packet.MN),
+						zap.String("previous", prev.Conn.RemoteAddr().String()),
+						zap.String("address", clientAddr))
+					prev.Conn.Close()
+				}
+			}
 		}
 		s.clients.Store(packet.MN, client)
 	}
 
+	// 匹配已下发命令的应答，应答只用于命令结果，不按心跳或设备信息处理
+	matched := s.commands.match(packet)
+	if matched && (packet.CN == CN_Response || packet.CN == CN_ExecuteResponse) {
+		return packet.MN, true
+	}
+
 	// 处理不同类型的消息
 	switch packet.CN {
-	case "2011", "2051", "2061", "2031": // 监测数据
-		s.handleMonitoringData(conn, clientAddr, packet)
+	case "2011", "2051", "2061", "2031", "2041": // 监测数据及运行时间数据
+		s.handleMonitoringData(conn, clientAddr, packet, matched)
 	case "2021": // 报警数据
 		s.handleAlarmData(conn, clientAddr, packet)
 	case "9011": // 心跳包
@@ -215,16 +384,54 @@ func (s *Server) handleMessage(conn net.Conn, clientAddr, data string) {
 			zap.String("address", clientAddr),
 			zap.String("cn", packet.CN))
 	}
+	return packet.MN, true
 }
 
-// handleMonitoringData 处理监测数据
-func (s *Server) handleMonitoringData(conn net.Conn, clientAddr string, packet *Packet) {
+// rejectPacket 拒绝未通过鉴权的数据包，记录非法接入并应答鉴权结果
+func (s *Server) rejectPacket(conn net.Conn, clientAddr string, packet *Packet, rtn, reason string) {
+	hj212UnauthorizedPacketsTotal.WithLabelValues(reason).Inc()
+	s.logger.Warn("Unauthorized HJ212 access",
+		zap.String("address", clientAddr),
+		zap.String("mn", packet.MN),
+		zap.String("cn", packet.CN),
+		zap.String("reason", reason))
+
+	if err := s.writePacket(conn, packet.MN, s.buildResponse(packet, rtn)); err != nil {
+		s.logger.Error("Failed to send response",
+			zap.Error(err),
+			zap.String("address", clientAddr))
+	}
+}
+
+// handleMonitoringData 处理监测数据，matched表示数据包属于已下发的命令，沿用请求的QN，不按QN去重
+func (s *Server) handleMonitoringData(conn net.Conn, clientAddr string, packet *Packet, matched bool) {
 	s.logger.Info("Received monitoring data",
 		zap.String("address", clientAddr),
 		zap.String("mn", packet.MN),
 		zap.String("cn", packet.CN),
 		zap.Time("data_time", packet.DataTime))
 
+	// 设备重发或网络重传的包QN相同，只应答不重复入库
+	key := packetDedupKey(packet, matched)
+	if !s.dedup.claim(key, time.Now()) {
+		hj212DuplicatePacketsTotal.WithLabelValues("cache").Inc()
+		s.logger.Debug("Duplicate packet skipped",
+			zap.String("mn", packet.MN),
+			zap.String("qn", packet.QN))
+		s.sendSuccessResponse(conn, clientAddr, packet)
+		return
+	}
+
+	// 补传的历史数据可能与已入库数据重复
+	if isDuplicateHistoryData(database.DB, packet) {
+		s.logger.Debug("Duplicate history data skipped",
+			zap.String("mn", packet.MN),
+			zap.String("cn", packet.CN),
+			zap.Time("data_time", packet.DataTime))
+		s.sendSuccessResponse(conn, clientAddr, packet)
+		return
+	}
+
 	// 准备解析后的数据
 	parsedData := make(models.JSONMap)
 
@@ -238,14 +445,15 @@ func (s *Server) handleMonitoringData(conn net.Conn, clientAddr string, packet *
 		factors := make(map[string]interface{})
 		for code, factor := range packet.Factors {
 			factors[code] = map[string]interface{}{
-				"name": factor.Name,
-				"rtd":  factor.Rtd,
-				"avg":  factor.Avg,
-				"max":  factor.Max,
-				"min":  factor.Min,
-				"cou":  factor.Cou,
-				"flag": factor.Flag,
-				"unit": factor.Unit,
+				"name":     factor.Name,
+				"rtd":      factor.Rtd,
+				"avg":      factor.Avg,
+				"max":      factor.Max,
+				"min":      factor.Min,
+				"cou":      factor.Cou,
+				"flag":     factor.Flag,
+				"unit":     factor.Unit,
+				"category": factor.Category,
 			}
 		}
 		parsedData["factors"] = factors
@@ -256,21 +464,37 @@ func (s *Server) handleMonitoringData(conn net.Conn, clientAddr string, packet *
 	hj212Data := models.HJ212Data{
 		DeviceID:     packet.MN,
 		CommandCode:  packet.CN,
+		QN:           packetQN(packet, key),
+		PacketNo:     packetNo(packet),
 		DataType:     s.getDataTypeByCN(packet.CN),
 		RawData:      string(packet.RawData),
 		ParsedData:   parsedData,
 		ReceivedFrom: clientAddr,
 		ReceivedAt:   currentTime,
-		QualityLevel: "normal",
+		DataTime:     dataTimePtr(packet.DataTime),
+		QualityLevel: models.HJ212QualityNormal,
 		IsValid:      true,
 		CreatedDate:  currentTime.Format("2006-01-02"),
 		CreatedHour:  currentTime.Hour(),
 	}
 
+	// 实时质量校验，异常数据标记质量等级
+	issues := s.quality.check(packet)
+	applyQualityIssues(&hj212Data, issues)
+
 	if err := database.DB.Create(&hj212Data).Error; err != nil {
-		s.logger.Error("Failed to save HJ212 data",
-			zap.Error(err),
-			zap.String("mn", packet.MN))
+		if isDuplicateKeyError(err) {
+			// 其他连接或实例已入库
+			hj212DuplicatePacketsTotal.WithLabelValues("database").Inc()
+			s.logger.Debug("Duplicate packet skipped",
+				zap.String("mn", packet.MN),
+				zap.String("qn", packet.QN))
+		} else {
+			s.dedup.release(key)
+			s.logger.Error("Failed to save HJ212 data",
+				zap.Error(err),
+				zap.String("mn", packet.MN))
+		}
 	} else {
 		// 广播新数据到WebSocket客户端
 		if s.wsHub != nil {
@@ -281,6 +505,9 @@ func (s *Server) handleMonitoringData(conn net.Conn, clientAddr string, packet *
 		if s.alarmDetector != nil {
 			s.alarmDetector.CheckData(&hj212Data)
 		}
+		if alarms := s.quality.alarmIssues(packet.MN, issues, currentTime); len(alarms) > 0 && s.qualityNotifier != nil {
+			s.qualityNotifier.DataQualityAnomaly(&hj212Data, alarms)
+		}
 
 		s.logger.Debug("HJ212 data saved and broadcasted",
 			zap.String("device_id", hj212Data.DeviceID),
@@ -288,8 +515,13 @@ func (s *Server) handleMonitoringData(conn net.Conn, clientAddr string, packet *
 	}
 
 	// 发送响应确认
+	s.sendSuccessResponse(conn, clientAddr, packet)
+}
+
+// sendSuccessResponse 应答执行成功
+func (s *Server) sendSuccessResponse(conn net.Conn, clientAddr string, packet *Packet) {
 	response := s.buildResponse(packet, ExeRtn_Success)
-	if _, err := conn.Write(response); err != nil {
+	if err := s.writePacket(conn, packet.MN, response); err != nil {
 		s.logger.Error("Failed to send response",
 			zap.Error(err),
 			zap.String("address", clientAddr))
@@ -323,7 +555,7 @@ func (s *Server) handleAlarmData(conn net.Conn, clientAddr string, packet *Packe
 
 	// 发送响应确认
 	response := s.buildResponse(packet, ExeRtn_Success)
-	if _, err := conn.Write(response); err != nil {
+	if err := s.writePacket(conn, packet.MN, response); err != nil {
 		s.logger.Error("Failed to send response",
 			zap.Error(err),
 			zap.String("address", clientAddr))
@@ -355,7 +587,7 @@ func (s *Server) handleHeartbeat(conn net.Conn, clientAddr string, packet *Packe
 
 	// 发送心跳响应
 	response := s.buildResponse(packet, ExeRtn_Success)
-	if _, err := conn.Write(response); err != nil {
+	if err := s.writePacket(conn, packet.MN, response); err != nil {
 		s.logger.Error("Failed to send heartbeat response",
 			zap.Error(err),
 			zap.String("address", clientAddr))
@@ -408,23 +640,37 @@ func (s *Server) handleDeviceInfo(conn net.Conn, clientAddr string, packet *Pack
 
 	// 发送响应确认
 	response := s.buildResponse(packet, ExeRtn_Success)
-	if _, err := conn.Write(response); err != nil {
+	if err := s.writePacket(conn, packet.MN, response); err != nil {
 		s.logger.Error("Failed to send response",
 			zap.Error(err),
 			zap.String("address", clientAddr))
 	}
 }
 
-// cleanupClients 清理不活跃的客户端
+// cleanupClients 清理不活跃的客户端，按离线检测间隔将超时未收包的设备判定为离线
 func (s *Server) cleanupClients() {
 	ticker := time.NewTicker(5 * time.Minute)
 	defer ticker.Stop()
 
+	checkInterval := s.config.HJ212.OfflineCheckInterval
+	if checkInterval <= 0 {
+		checkInterval = time.Minute
+	}
+	offlineTicker := time.NewTicker(checkInterval)
+	defer offlineTicker.Stop()
+
 	for {
 		select {
 		case <-s.ctx.Done():
 			return
+		case <-offlineTicker.C:
+			s.detectOfflineDevices()
 		case <-ticker.C:
+			// 关闭过期的归档文件并清理超过保留天数的归档
+			s.archive.cleanup()
+			s.purgeDeadLetters()
+			s.rejects.prune(time.Now())
+
 			now := time.Now()
 			s.clients.Range(func(key, value interface{}) bool {
 				if client, ok := value.(*Client); ok {
@@ -457,13 +703,50 @@ func (s *Server) GetConnectedDevices() []string {
 func (s *Server) SendCommand(deviceID, command string) error {
 	if client, ok := s.clients.Load(deviceID); ok {
 		if c, ok := client.(*Client); ok {
-			_, err := c.Conn.Write([]byte(command))
-			return err
+			return s.writePacket(c.Conn, deviceID, []byte(command))
 		}
 	}
 	return fmt.Errorf("device %s not connected", deviceID)
 }
 
+// writePacket 向设备发送数据并归档
+func (s *Server) writePacket(conn net.Conn, mn string, data []byte) error {
+	if _, err := conn.Write(data); err != nil {
+		return err
+	}
+	s.archive.record(archiveSent, mn, conn.RemoteAddr().String(), data)
+	return nil
+}
+
+// SendPacket 向设备发送数据包
+func (s *Server) SendPacket(deviceID string, packet *Packet) error {
+	data, err := s.parser.Build(packet)
+	if err != nil {
+		return fmt.Errorf("failed to build packet: %w", err)
+	}
+	return s.SendCommand(deviceID, string(data))
+}
+
+// ExecuteCommand 下发命令并等待设备执行结果（CN 9012），ctx控制等待时长
+func (s *Server) ExecuteCommand(ctx context.Context, deviceID string, packet *Packet) (*CommandResult, error) {
+	return s.commands.execute(ctx, deviceID, packet, func(p *Packet) error {
+		return s.SendPacket(deviceID, p)
+	})
+}
+
+// DeviceHeader 设备最近一次上报的系统编码和密码，设备未连接时返回false
+func (s *Server) DeviceHeader(deviceID string) (st, pw string, ok bool) {
+	value, ok := s.clients.Load(deviceID)
+	if !ok {
+		return "", "", false
+	}
+	client, ok := value.(*Client)
+	if !ok {
+		return "", "", false
+	}
+	return client.ST, client.PW, true
+}
+
 // getDataTypeByCN 根据命令编码获取数据类型
 func (s *Server) getDataTypeByCN(cn string) string {
 	switch cn {
@@ -475,6 +758,8 @@ func (s *Server) getDataTypeByCN(cn string) string {
 		return "小时数据"
 	case CN_GetDayData:
 		return "日数据"
+	case CN_GetRunTimeData:
+		return "运行时间数据"
 	case CN_GetDeviceStatus:
 		return "设备状态"
 	default:
@@ -491,7 +776,7 @@ func (s *Server) buildResponse(originalPacket *Packet, execResult string) []byte
 		PW:   originalPacket.PW,
 		MN:   originalPacket.MN,
 		Flag: Flag_Confirm,
-		CP:   fmt.Sprintf("&&ExeRtn=%s&&", execResult),
+		CP:   fmt.Sprintf("ExeRtn=%s", execResult), // Build会在CP两侧加上&&
 	}
 
 	response, err := s.parser.Build(responsePacket)
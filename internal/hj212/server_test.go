@@ -0,0 +1,447 @@
+package hj212
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/config"
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/database/dbtest"
+	"github.com/env-data-platform/internal/models"
+)
+
+// serverDB 记录服务器执行语句的DryRun数据库
+type serverDB struct {
+	*dbtest.DB
+
+	mu      sync.Mutex
+	devices []string
+}
+
+// saved 服务器写入监测数据的设备，按写入顺序
+func (d *serverDB) saved() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.devices...)
+}
+
+// withServerDB 将 database.DB 替换为DryRun数据库，查询数据源时返回devices登记的设备（MN到密码），并记录写入的监测数据
+func withServerDB(t *testing.T, devices map[string]string) *serverDB {
+	db := &serverDB{DB: dbtest.New(t)}
+	db.On(dbtest.KindQuery, func(tx *gorm.DB) {
+		if sources, ok := tx.Statement.Dest.(*[]models.DataSource); ok {
+			for mn, pw := range devices {
+				*sources = append(*sources, models.DataSource{DeviceID: mn, Config: `{"password":"` + pw + `"}`})
+			}
+		}
+	})
+	db.On(dbtest.KindCreate, func(tx *gorm.DB) {
+		if data, ok := tx.Statement.Dest.(*models.HJ212Data); ok {
+			db.mu.Lock()
+			db.devices = append(db.devices, data.DeviceID)
+			db.mu.Unlock()
+		}
+	})
+	db.Replace(t, &database.DB)
+	return db
+}
+
+// startTestServer 以随机端口启动生产环境使用的Server，返回监听地址
+func startTestServer(t *testing.T, cfg config.HJ212Config) (*Server, string) {
+	cfg.Enabled = true
+	if cfg.BufferSize == 0 {
+		cfg.BufferSize = 1024
+	}
+	s := NewServer(&config.Config{HJ212: cfg}, zap.NewNop(), nil, nil)
+	go s.Start()
+	t.Cleanup(func() { s.Stop() })
+
+	var addr string
+	require.Eventually(t, func() bool {
+		var ok bool
+		addr, ok = s.ListenAddr()
+		return ok
+	}, time.Second, 10*time.Millisecond)
+	return s, addr
+}
+
+// exchange 发送一个数据包并读取服务器的应答
+func exchange(t *testing.T, conn net.Conn, packet *Packet) *Packet {
+	parser := NewParser("2017")
+	data, err := parser.Build(packet)
+	require.NoError(t, err)
+	_, err = conn.Write(data)
+	require.NoError(t, err)
+
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	response, err := parser.Parse(buf[:n])
+	require.NoError(t, err)
+	return response
+}
+
+func TestServerDeviceAuth(t *testing.T) {
+	db := withServerDB(t, map[string]string{"88888880000001": "123456"})
+	_, addr := startTestServer(t, config.HJ212Config{
+		Auth: config.HJ212AuthConfig{Enabled: true, CheckPassword: true},
+	})
+
+	tests := []struct {
+		name    string
+		mn, pw  string
+		wantRtn string
+	}{
+		{"registered", "88888880000001", "123456", ExeRtn_Success},
+		{"wrong password", "88888880000001", "654321", ExeRtn_InvalidPassword},
+		{"unregistered", "99999990000001", "123456", ExeRtn_InvalidMN},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := net.Dial("tcp", addr)
+			require.NoError(t, err)
+			defer conn.Close()
+
+			response := exchange(t, conn, &Packet{
+				QN: "2024030108000000" + string(rune('1'+i)),
+				ST: "32",
+				CN: CN_GetRtdData,
+				PW: tt.pw,
+				MN: tt.mn,
+				CP: "DataTime=20240301080000;w01018-Rtd=12.5,w01018-Flag=N",
+			})
+			assert.Equal(t, tt.wantRtn, response.ExeRtn)
+		})
+	}
+
+	// 只有通过鉴权的设备数据入库
+	assert.Equal(t, []string{"88888880000001"}, db.saved())
+}
+
+func TestServerTLSOnly(t *testing.T) {
+	withServerDB(t, nil)
+	certFile, keyFile := writeSelfSignedCert(t)
+	_, addr := startTestServer(t, config.HJ212Config{
+		TLS: config.HJ212TLSConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile, Only: true},
+	})
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	response := exchange(t, conn, &Packet{
+		QN: "20240301080000001",
+		ST: "32",
+		CN: CN_GetRtdData,
+		MN: "88888880000001",
+		CP: "DataTime=20240301080000;w01018-Rtd=12.5,w01018-Flag=N",
+	})
+	assert.Equal(t, ExeRtn_Success, response.ExeRtn)
+
+	// 仅TLS模式下明文连接无法完成握手
+	plain, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer plain.Close()
+	_, err = plain.Write([]byte("##0000\r\n"))
+	require.NoError(t, err)
+	plain.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 64)
+	n, _ := plain.Read(buf)
+	assert.NotContains(t, string(buf[:n]), "ExeRtn")
+}
+
+func TestServerUDP(t *testing.T) {
+	db := withServerDB(t, nil)
+	s, _ := startTestServer(t, config.HJ212Config{UDP: config.HJ212UDPConfig{Enabled: true}})
+
+	s.listenerMu.RLock()
+	udpAddr := s.udpConn.LocalAddr().String()
+	s.listenerMu.RUnlock()
+	device, err := net.Dial("udp", udpAddr)
+	require.NoError(t, err)
+	defer device.Close()
+
+	// 应答发往数据报的来源地址
+	response := exchange(t, device, &Packet{
+		QN: "20240301080000001",
+		ST: "32",
+		CN: CN_GetRtdData,
+		MN: "88888880000001",
+		CP: "DataTime=20240301080000;w01018-Rtd=12.5,w01018-Flag=N",
+	})
+	assert.Equal(t, ExeRtn_Success, response.ExeRtn)
+	assert.Equal(t, []string{"88888880000001"}, db.saved())
+	assert.Contains(t, s.GetConnectedDevices(), "88888880000001")
+
+	// 命令发往设备最后的来源地址
+	require.NoError(t, s.SendPacket("88888880000001", &Packet{
+		QN: "20240301080000002", ST: ST_System, CN: CN_Response, MN: "88888880000001",
+	}))
+	buf := make([]byte, 1024)
+	device.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := device.Read(buf)
+	require.NoError(t, err)
+	packet, err := NewParser("2017").Parse(buf[:n])
+	require.NoError(t, err)
+	assert.Equal(t, "20240301080000002", packet.QN)
+}
+
+func TestServerDeviceMetrics(t *testing.T) {
+	withServerDB(t, nil)
+	_, addr := startTestServer(t, config.HJ212Config{})
+
+	const mn = "88888880000009"
+	packets := testutil.ToFloat64(hj212DevicePacketsTotal.WithLabelValues(mn, CN_GetRtdData))
+	invalid := testutil.ToFloat64(hj212PacketsTotal.WithLabelValues("invalid"))
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("noise\r\n"))
+	require.NoError(t, err)
+	exchange(t, conn, &Packet{
+		QN: "20240301080000001",
+		ST: "32",
+		CN: CN_GetRtdData,
+		MN: mn,
+		CP: "DataTime=20240301080000;w01018-Rtd=12.5,w01018-Flag=N",
+	})
+
+	// 有效包按设备计数，无效包只计入总数
+	assert.Equal(t, packets+1, testutil.ToFloat64(hj212DevicePacketsTotal.WithLabelValues(mn, CN_GetRtdData)))
+	assert.Positive(t, testutil.ToFloat64(hj212DeviceBytesTotal.WithLabelValues(mn)))
+	assert.Equal(t, invalid+1, testutil.ToFloat64(hj212PacketsTotal.WithLabelValues("invalid")))
+}
+
+// statusEvents 记录上下线通知
+type statusEvents struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (e *statusEvents) DeviceStatusChanged(event *models.DeviceStatusEvent) {
+	e.mu.Lock()
+	e.events = append(e.events, event.DeviceID+":"+event.Event)
+	e.mu.Unlock()
+}
+
+func TestServerDeviceStatus(t *testing.T) {
+	db := dbtest.New(t)
+	db.On(dbtest.KindQuery, func(tx *gorm.DB) {
+		switch dest := tx.Statement.Dest.(type) {
+		case *models.DataSource:
+			dest.ID = 4
+			dest.DeviceID = "MN4"
+		case *[]models.DataSource:
+			// 数据源表中仍为已连接的设备：MN1超时未收包，MN3服务启动后未上报且last_active_at已过期
+			stale := time.Now().Add(-time.Hour)
+			*dest = []models.DataSource{{DeviceID: "MN1", IsConnected: true}, {DeviceID: "MN3", IsConnected: true, LastActiveAt: &stale}}
+		}
+	})
+	// 条件更新视为命中一行，状态发生变化
+	db.On(dbtest.KindUpdate, func(tx *gorm.DB) {
+		tx.RowsAffected = 1
+	})
+	db.Replace(t, &database.DB)
+
+	s := NewServer(&config.Config{HJ212: config.HJ212Config{OfflineTimeout: time.Minute}}, zap.NewNop(), nil, nil)
+	events := &statusEvents{}
+	s.SetStatusNotifier(events)
+
+	// 首次收包时上线，已在线的设备不重复查库
+	s.touchDevice("MN4")
+	s.touchDevice("MN4")
+	assert.Equal(t, []string{"MN4:online"}, events.events)
+	assert.Len(t, db.Statements(dbtest.KindQuery), 1)
+
+	device, server := net.Pipe()
+	defer device.Close()
+	s.clients.Store("MN1", &Client{Conn: server, MN: "MN1"})
+	s.lastSeen["MN1"] = time.Now().Add(-2 * time.Minute)
+	s.lastSeen["MN2"] = time.Now()
+
+	s.detectOfflineDevices()
+	assert.Equal(t, []string{"MN4:online", "MN1:offline", "MN3:offline"}, events.events)
+	queries := db.Statements(dbtest.KindQuery)
+	assert.Contains(t, queries[len(queries)-1].SQL, "device_id NOT IN")
+	assert.Contains(t, queries[len(queries)-1].Vars, "MN2")
+	assert.NotContains(t, s.GetConnectedDevices(), "MN1")
+	_, err := device.Read(make([]byte, 1))
+	assert.Error(t, err, "offline device connection should be closed")
+}
+
+// readPacket 读取服务器下发的一个数据包
+func readPacket(t *testing.T, conn net.Conn) *Packet {
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	packet, err := NewParser("2017").Parse(buf[:n])
+	require.NoError(t, err)
+	return packet
+}
+
+// send 设备上传一个数据包
+func send(t *testing.T, conn net.Conn, packet *Packet) {
+	data, err := NewParser("2017").Build(packet)
+	require.NoError(t, err)
+	_, err = conn.Write(data)
+	require.NoError(t, err)
+}
+
+func TestServerRequestHistoryData(t *testing.T) {
+	const mn = "88888880000001"
+	db := withServerDB(t, nil)
+	// 07:01的分钟数据已入库
+	existing := time.Date(2024, 3, 1, 7, 1, 0, 0, time.Local)
+	db.On(dbtest.KindQuery, func(tx *gorm.DB) {
+		if count, ok := tx.Statement.Dest.(*int64); ok {
+			vars := tx.Statement.Vars
+			if dataTime, ok := vars[len(vars)-1].(time.Time); ok && dataTime.Equal(existing) {
+				*count = 1
+				tx.RowsAffected = 1
+			}
+		}
+	})
+	s, addr := startTestServer(t, config.HJ212Config{})
+
+	device, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer device.Close()
+	exchange(t, device, &Packet{QN: "20240301080000001", ST: "32", CN: CN_GetRtdData, PW: "123456", MN: mn,
+		CP: "DataTime=20240301080000;w01018-Rtd=12.5,w01018-Flag=N"})
+
+	_, err = s.RequestHistoryData(context.Background(), mn, "2999", time.Now(), time.Now().Add(time.Hour))
+	assert.Error(t, err)
+
+	type outcome struct {
+		result *CommandResult
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		result, err := s.RequestHistoryData(ctx, mn, CN_GetMinuteData,
+			time.Date(2024, 3, 1, 7, 0, 0, 0, time.Local), time.Date(2024, 3, 1, 7, 2, 0, 0, time.Local))
+		done <- outcome{result, err}
+	}()
+
+	// 请求沿用设备上报的包头
+	request := readPacket(t, device)
+	assert.Equal(t, CN_GetMinuteData, request.CN)
+	assert.Equal(t, "32", request.ST)
+	assert.Equal(t, "123456", request.PW)
+	assert.Contains(t, request.CP, "BeginTime=20240301070000;EndTime=20240301070200")
+
+	// 设备分多包补传，沿用请求的QN
+	send(t, device, &Packet{QN: request.QN, ST: ST_System, CN: CN_Response, MN: mn, CP: "QN=" + request.QN + ";QnRtn=1"})
+	for _, dataTime := range []string{"20240301070000", "20240301070100"} {
+		exchange(t, device, &Packet{QN: request.QN, ST: "32", CN: CN_GetMinuteData, PW: "123456", MN: mn,
+			CP: "DataTime=" + dataTime + ";w01018-Avg=12.5,w01018-Flag=N"})
+	}
+	send(t, device, &Packet{QN: request.QN, ST: ST_System, CN: CN_ExecuteResponse, MN: mn, CP: "QN=" + request.QN + ";ExeRtn=1"})
+
+	result := <-done
+	require.NoError(t, result.err)
+	assert.True(t, result.result.Succeeded())
+	assert.Equal(t, 2, result.result.Packets)
+	// 已入库的07:01数据不重复入库
+	assert.Equal(t, []string{mn, mn}, db.saved())
+	check, ok := db.Find("device_id = ? AND command_code = ? AND data_time = ?")
+	require.True(t, ok)
+	assert.Equal(t, []interface{}{mn, CN_GetMinuteData}, check.Vars[:2])
+}
+
+func TestServerArchive(t *testing.T) {
+	withServerDB(t, nil)
+	dir := t.TempDir()
+	_, addr := startTestServer(t, config.HJ212Config{Archive: config.HJ212ArchiveConfig{Enabled: true, Dir: dir}})
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	exchange(t, conn, &Packet{QN: "20240301080000001", ST: "32", CN: CN_GetRtdData, MN: "88888880000001",
+		CP: "DataTime=20240301080000;w01018-Rtd=12.5,w01018-Flag=N"})
+
+	// 收到的数据包和应答按设备归档
+	file := filepath.Join(dir, "88888880000001", time.Now().Format("2006-01-02")+".log")
+	require.Eventually(t, func() bool {
+		content, err := os.ReadFile(file)
+		return err == nil && strings.Contains(string(content), " RX ") && strings.Contains(string(content), " TX ")
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestServerDeadLetter(t *testing.T) {
+	db := withServerDB(t, nil)
+	letters := make(chan *models.HJ212DeadLetter, 1)
+	db.On(dbtest.KindCreate, func(tx *gorm.DB) {
+		if letter, ok := tx.Statement.Dest.(*models.HJ212DeadLetter); ok {
+			letters <- letter
+		}
+	})
+	_, addr := startTestServer(t, config.HJ212Config{DeadLetter: config.HJ212DeadLetterConfig{Enabled: true}})
+
+	data, err := NewParser("2017").Build(&Packet{QN: "20240301080000001", ST: "32", CN: CN_GetRtdData, MN: "88888880000001",
+		CP: "DataTime=20240301080000;w01018-Rtd=12.5,w01018-Flag=N"})
+	require.NoError(t, err)
+	// 篡改CRC
+	corrupted := append([]byte(nil), data...)
+	copy(corrupted[len(corrupted)-6:], "0000")
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write(corrupted)
+	require.NoError(t, err)
+
+	select {
+	case letter := <-letters:
+		assert.Equal(t, "88888880000001", letter.DeviceID)
+		assert.Equal(t, models.HJ212DeadLetterStageParse, letter.Stage)
+		assert.Equal(t, "0000", letter.CRCExpected)
+		assert.False(t, letter.CRCMatched)
+	case <-time.After(time.Second):
+		t.Fatal("dead letter not recorded")
+	}
+}
+
+func TestServerMaxConnections(t *testing.T) {
+	withServerDB(t, nil)
+	s, addr := startTestServer(t, config.HJ212Config{MaxConnections: 1})
+	rejected := testutil.ToFloat64(hj212RejectedConnectionsTotal.WithLabelValues("max_connections"))
+
+	first, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer first.Close()
+	require.Eventually(t, func() bool { return s.connections.Load() == 1 }, time.Second, 10*time.Millisecond)
+
+	// 超过上限的连接被直接关闭
+	second, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer second.Close()
+	second.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = second.Read(make([]byte, 1))
+	assert.False(t, isTimeout(err), "rejected connection should be closed by the server")
+	assert.Equal(t, rejected+1, testutil.ToFloat64(hj212RejectedConnectionsTotal.WithLabelValues("max_connections")))
+
+	// 已建立的连接不受影响
+	exchange(t, first, &Packet{QN: "20240301080000001", ST: "32", CN: CN_GetRtdData, MN: "88888880000001",
+		CP: "DataTime=20240301080000;w01018-Rtd=12.5,w01018-Flag=N"})
+}
@@ -3,7 +3,6 @@ package hj212
 import (
 	"context"
 	"fmt"
-	"io"
 	"net"
 	"sync"
 	"time"
@@ -19,13 +18,39 @@ import (
 type ServerV2 struct {
 	config      *config.HJ212Config
 	logger      *zap.Logger
-	listener    net.Listener
+	listeners   []net.Listener
+	udpConn     net.PacketConn
 	parser      *Parser
 	connections map[string]net.Conn
 	mu          sync.RWMutex
 	ctx         context.Context
 	cancel      context.CancelFunc
 	db          *gorm.DB
+	auth        *deviceAuthenticator
+
+	// 设备MN到最后收包时间，用于离线检测
+	lastSeen      map[string]time.Time
+	deviceHeaders map[string]deviceHeader
+	status        *deviceStatusRecorder
+
+	// UDP设备MN到最后的来源地址，UDP无连接，不计入connections
+	udpPeers map[string]*udpPeer
+
+	// 已下发等待应答的命令
+	commands *commandTracker
+
+	// 原始报文归档，未开启时为nil
+	archive *packetArchive
+
+	// 被拒绝连接的日志采样
+	rejects *rejectSampler
+
+	// 按设备MN+QN识别重传的数据包
+	dedup *packetDedup
+
+	// 收包实时质量校验
+	quality         *qualityChecker
+	qualityNotifier DataQualityNotifier
 
 	// 数据处理通道
 	dataChannel  chan *Packet
@@ -45,20 +70,38 @@ type ServerStats struct {
 	Connections     uint32
 	LastPacketTime  time.Time
 	StartTime       time.Time
+
+	// 达到最大连接数被拒绝的连接
+	RejectedConnections uint64
+	LastRejectedTime    time.Time
+
+	// 收到的UDP数据报数
+	UDPDatagrams uint64
 }
 
 // NewServerV2 创建增强版服务器
 func NewServerV2(cfg *config.HJ212Config, logger *zap.Logger) *ServerV2 {
 	ctx, cancel := context.WithCancel(context.Background())
+	db := database.GetDB()
 
 	return &ServerV2{
 		config:       cfg,
 		logger:       logger,
 		parser:       NewParser("2017"),
 		connections:  make(map[string]net.Conn),
+		lastSeen:      make(map[string]time.Time),
+		deviceHeaders: make(map[string]deviceHeader),
+		status:        &deviceStatusRecorder{db: db, logger: logger},
+		udpPeers:      make(map[string]*udpPeer),
+		commands:      newCommandTracker(),
+		archive:       newPacketArchive(cfg.Archive, logger),
+		rejects:       newRejectSampler(rejectSampleInterval),
+		dedup:         newPacketDedup(cfg.DedupWindow),
+		quality:       newQualityChecker(cfg.Quality),
 		ctx:          ctx,
 		cancel:       cancel,
-		db:           database.GetDB(),
+		db:           db,
+		auth:         newDeviceAuthenticator(cfg.Auth, db, logger),
 		dataChannel:  make(chan *Packet, 1000),
 		alarmChannel: make(chan *AlarmData, 100),
 		stats: &ServerStats{
@@ -78,18 +121,33 @@ func (s *ServerV2) Start() error {
 	go s.dataProcessor()
 	go s.alarmProcessor()
 
-	// 监听端口
-	addr := fmt.Sprintf(":%d", s.config.TCPPort)
-	listener, err := net.Listen("tcp", addr)
+	// 监听端口，TLS启用时另开TLS端口
+	listeners, err := s.listen()
+	if err != nil {
+		return err
+	}
+	s.listeners = listeners
+
+	udpConn, err := s.listenUDP()
 	if err != nil {
-		return fmt.Errorf("failed to listen: %w", err)
+		for _, listener := range listeners {
+			listener.Close()
+		}
+		return err
 	}
+	s.udpConn = udpConn
 
-	s.listener = listener
-	s.logger.Info("HJ212 server v2 started", zap.String("address", addr))
+	hj212MaxConnections.Set(float64(s.config.MaxConnections))
 
 	// 接受连接
-	go s.acceptConnections()
+	for _, listener := range listeners {
+		s.logger.Info("HJ212 server v2 started", zap.String("address", listener.Addr().String()))
+		go s.acceptConnections(listener)
+	}
+	if udpConn != nil {
+		s.logger.Info("HJ212 UDP server started", zap.String("address", udpConn.LocalAddr().String()))
+		go s.serveUDP(udpConn)
+	}
 
 	// 启动定时任务
 	go s.periodicTasks()
@@ -98,13 +156,13 @@ func (s *ServerV2) Start() error {
 }
 
 // acceptConnections 接受新连接
-func (s *ServerV2) acceptConnections() {
+func (s *ServerV2) acceptConnections(listener net.Listener) {
 	for {
 		select {
 		case <-s.ctx.Done():
 			return
 		default:
-			conn, err := s.listener.Accept()
+			conn, err := listener.Accept()
 			if err != nil {
 				if s.ctx.Err() != nil {
 					return
@@ -119,9 +177,7 @@ func (s *ServerV2) acceptConnections() {
 			s.mu.RUnlock()
 
 			if connCount >= s.config.MaxConnections {
-				s.logger.Warn("Max connections reached, rejecting new connection",
-					zap.Int("max", s.config.MaxConnections))
-				conn.Close()
+				s.rejectConnection(conn, connCount)
 				continue
 			}
 
@@ -130,7 +186,8 @@ func (s *ServerV2) acceptConnections() {
 	}
 }
 
-// handleConnection 处理连接
+// handleConnection 处理连接，超过心跳超时未收到有效数据包时主动断开，
+// 退出时清理按地址和设备MN登记的连接映射
 func (s *ServerV2) handleConnection(conn net.Conn) {
 	defer conn.Close()
 
@@ -140,15 +197,27 @@ func (s *ServerV2) handleConnection(conn net.Conn) {
 	// 注册连接
 	s.mu.Lock()
 	s.connections[deviceID] = conn
-	s.stats.Connections++
 	s.mu.Unlock()
+	s.stats.mu.Lock()
+	s.stats.Connections++
+	s.stats.mu.Unlock()
+	hj212Connections.Inc()
+
+	idle := newIdleTracker(s.config.Timeout, s.config.HeartbeatTimeout, time.Now())
+	devices := make(map[string]bool) // 本连接上报过的设备MN
 
 	// 清理连接
 	defer func() {
 		s.mu.Lock()
-		delete(s.connections, deviceID)
-		s.stats.Connections--
+		s.releaseConnection(deviceID, conn)
+		for mn := range devices {
+			s.releaseConnection(mn, conn)
+		}
 		s.mu.Unlock()
+		s.stats.mu.Lock()
+		s.stats.Connections--
+		s.stats.mu.Unlock()
+		hj212Connections.Dec()
 		s.logger.Info("Connection closed", zap.String("device", deviceID))
 	}()
 
@@ -159,14 +228,26 @@ func (s *ServerV2) handleConnection(conn net.Conn) {
 	for {
 		select {
 		case <-s.ctx.Done():
+			hj212DisconnectsTotal.WithLabelValues(disconnectShutdown).Inc()
 			return
 		default:
-			// 设置读取超时
-			conn.SetReadDeadline(time.Now().Add(s.config.Timeout))
+			// 每次读取前按最后一次有效数据包的时间重新计算超时
+			conn.SetReadDeadline(idle.deadline(time.Now()))
 
 			n, err := conn.Read(buffer)
 			if err != nil {
-				if err != io.EOF && !isTimeout(err) {
+				reason := idle.disconnectReason(err, time.Now())
+				if s.ctx.Err() != nil {
+					reason = disconnectShutdown
+				}
+				hj212DisconnectsTotal.WithLabelValues(reason).Inc()
+				switch reason {
+				case disconnectHeartbeatTimeout:
+					s.logger.Warn("Closing connection without valid packets within heartbeat timeout",
+						zap.String("device", deviceID),
+						zap.Duration("heartbeat_timeout", s.config.HeartbeatTimeout),
+						zap.Strings("devices", connDevices(devices)))
+				case disconnectError:
 					s.logger.Error("Read error", zap.String("device", deviceID), zap.Error(err))
 				}
 				return
@@ -174,77 +255,120 @@ func (s *ServerV2) handleConnection(conn net.Conn) {
 
 			// 累积数据
 			dataBuffer = append(dataBuffer, buffer[:n]...)
+			s.stats.mu.Lock()
 			s.stats.TotalBytes += uint64(n)
+			s.stats.mu.Unlock()
+			hj212ReceivedBytesTotal.Add(float64(n))
 
 			// 尝试解析数据包
 			for len(dataBuffer) > 0 {
-				// 查找包结束标记
-				endIndex := findPacketEnd(dataBuffer)
-				if endIndex == -1 {
-					break // 不完整的包，等待更多数据
+				// 按包头长度切包，粘包时逐个切出，半包时等待更多数据
+				endIndex := splitPacket(dataBuffer)
+				if endIndex == 0 {
+					break
 				}
 
 				// 提取完整的数据包
 				packetData := dataBuffer[:endIndex]
 				dataBuffer = dataBuffer[endIndex:]
 
-				// 处理数据包
-				s.processPacket(conn, deviceID, packetData)
+				// 处理数据包，只有有效数据包刷新心跳
+				if mn, ok := s.processPacket(conn, deviceID, packetData); ok {
+					idle.touch(time.Now())
+					if mn != "" {
+						devices[mn] = true
+					}
+				}
 			}
 		}
 	}
 }
 
-// processPacket 处理数据包
-func (s *ServerV2) processPacket(conn net.Conn, deviceID string, data []byte) {
+// releaseConnection 移除映射到conn的连接登记，设备已在新连接上登记时保留，调用方持有s.mu
+func (s *ServerV2) releaseConnection(key string, conn net.Conn) {
+	if current, ok := s.connections[key]; ok && current == conn {
+		delete(s.connections, key)
+	}
+}
+
+// processPacket 处理数据包，返回设备MN及数据包是否有效（解析、校验和鉴权均通过）
+func (s *ServerV2) processPacket(conn net.Conn, deviceID string, data []byte) (string, bool) {
+	s.stats.mu.Lock()
 	s.stats.TotalPackets++
 	s.stats.LastPacketTime = time.Now()
+	s.stats.mu.Unlock()
 
 	// 记录原始数据
 	s.logger.Debug("Received packet",
 		zap.String("device", deviceID),
 		zap.Int("size", len(data)),
 		zap.String("data", string(data)))
+	s.archive.record(archiveReceived, "", conn.RemoteAddr().String(), data)
 
 	// 解析数据包
 	packet, err := s.parser.Parse(data)
 	if err != nil {
-		s.stats.InvalidPackets++
+		s.recordInvalidPacket()
 		s.logger.Error("Parse error",
 			zap.String("device", deviceID),
 			zap.Error(err),
 			zap.String("raw", string(data)))
+		s.recordDeadLetter(conn, models.HJ212DeadLetterStageParse, data, nil, err)
 
 		// 发送错误响应
 		s.sendErrorResponse(conn, packet, err)
-		return
+		return "", false
 	}
 
 	// 验证数据包
 	if err := s.parser.ValidatePacket(packet); err != nil {
-		s.stats.InvalidPackets++
+		s.recordInvalidPacket()
 		s.logger.Warn("Invalid packet",
 			zap.String("device", deviceID),
 			zap.Error(err))
-		return
+		s.recordDeadLetter(conn, models.HJ212DeadLetterStageValidate, data, packet, err)
+		return "", false
 	}
 
-	s.stats.ValidPackets++
+	// 设备白名单与密码校验
+	if rtn, reason := s.auth.authorize(packet); rtn != "" {
+		s.rejectPacket(conn, deviceID, packet, rtn, reason)
+		return "", false
+	}
 
-	// 更新设备连接映射
-	if packet.MN != "" {
+	s.recordValidPacket(packet, len(data))
+	s.touchDevice(packet)
+
+	// 更新设备连接映射，UDP设备只记录来源地址
+	if peer, ok := conn.(*udpPeer); ok {
+		if packet.MN != "" {
+			s.rememberUDPPeer(packet.MN, peer)
+		}
+	} else if packet.MN != "" {
 		s.mu.Lock()
-		delete(s.connections, deviceID)
+		s.releaseConnection(deviceID, conn)
+		previous, exists := s.connections[packet.MN]
 		s.connections[packet.MN] = conn
 		s.mu.Unlock()
+		// 设备已在新连接上报，旧连接多半已悬挂，主动关闭
+		if exists && previous != conn {
+			s.logger.Info("Device reconnected, closing previous connection",
+				zap.String("mn", packet.MN),
+				zap.String("previous", previous.RemoteAddr().String()),
+				zap.String("device", deviceID))
+			previous.Close()
+		}
 	}
 
+	// 匹配已下发命令的应答
+	matched := s.commands.match(packet)
+
 	// 根据命令类型处理
 	switch {
 	case IsDataCommand(packet.CN):
-		s.handleDataCommand(conn, packet)
-	case IsControlCommand(packet.CN):
-		s.handleControlCommand(conn, packet)
+		s.handleDataCommand(conn, packet, matched)
+	case IsParamCommand(packet.CN), IsControlCommand(packet.CN):
+		s.handleControlCommand(conn, packet, matched)
 	case IsResponseCommand(packet.CN):
 		s.handleResponseCommand(conn, packet)
 	default:
@@ -252,17 +376,56 @@ func (s *ServerV2) processPacket(conn net.Conn, deviceID string, data []byte) {
 			zap.String("device", deviceID),
 			zap.String("cn", packet.CN))
 	}
+	return packet.MN, true
+}
+
+// recordInvalidPacket 记录无效数据包
+func (s *ServerV2) recordInvalidPacket() {
+	s.stats.mu.Lock()
+	s.stats.InvalidPackets++
+	s.stats.mu.Unlock()
+	hj212PacketsTotal.WithLabelValues("invalid").Inc()
+}
+
+// recordValidPacket 记录有效数据包，按设备MN统计；只对通过校验的包打设备标签，避免非法MN造成标签膨胀
+func (s *ServerV2) recordValidPacket(packet *Packet, size int) {
+	s.stats.mu.Lock()
+	s.stats.ValidPackets++
+	s.stats.mu.Unlock()
+	observeValidPacket(packet, size)
+}
+
+// rejectPacket 拒绝未通过鉴权的数据包，记录非法接入并返回执行结果
+func (s *ServerV2) rejectPacket(conn net.Conn, deviceID string, packet *Packet, rtn, reason string) {
+	hj212UnauthorizedPacketsTotal.WithLabelValues(reason).Inc()
+	s.logger.Warn("Unauthorized HJ212 access",
+		zap.String("device", deviceID),
+		zap.String("remote", conn.RemoteAddr().String()),
+		zap.String("mn", packet.MN),
+		zap.String("cn", packet.CN),
+		zap.String("reason", reason))
+
+	info := "设备未登记"
+	if rtn == ExeRtn_InvalidPassword {
+		info = "密码错误"
+	}
+	s.sendExecutionResponse(conn, packet, rtn, info)
 }
 
-// handleDataCommand 处理数据命令
-func (s *ServerV2) handleDataCommand(conn net.Conn, packet *Packet) {
+// handleDataCommand 处理数据命令，重传的数据包只应答不重复处理
+func (s *ServerV2) handleDataCommand(conn net.Conn, packet *Packet, matched bool) {
 	s.logger.Info("Data command received",
 		zap.String("mn", packet.MN),
 		zap.String("cn", packet.CN),
 		zap.String("st", packet.ST))
 
 	// 保存数据
-	s.savePacketData(packet)
+	if !s.savePacketData(packet, matched) {
+		if packet.Flag&Flag_Confirm != 0 {
+			s.sendSuccessResponse(conn, packet)
+		}
+		return
+	}
 
 	// 发送到数据处理通道
 	select {
@@ -286,19 +449,6 @@ func (s *ServerV2) handleDataCommand(conn net.Conn, packet *Packet) {
 	}
 }
 
-// handleControlCommand 处理控制命令
-func (s *ServerV2) handleControlCommand(conn net.Conn, packet *Packet) {
-	s.logger.Info("Control command received",
-		zap.String("mn", packet.MN),
-		zap.String("cn", packet.CN))
-
-	// TODO: 实现控制命令处理逻辑
-	// 例如：设置参数、校准、采样等
-
-	// 发送执行结果
-	s.sendExecutionResponse(conn, packet, ExeRtn_Success, "Command executed")
-}
-
 // handleResponseCommand 处理响应命令
 func (s *ServerV2) handleResponseCommand(conn net.Conn, packet *Packet) {
 	s.logger.Debug("Response received",
@@ -310,16 +460,39 @@ func (s *ServerV2) handleResponseCommand(conn net.Conn, packet *Packet) {
 	s.updateDeviceStatus(packet)
 }
 
-// savePacketData 保存数据包数据
-func (s *ServerV2) savePacketData(packet *Packet) {
+// savePacketData 保存数据包数据，数据包重复时返回false。matched表示数据包属于已下发的命令，
+// 沿用请求的QN，不按QN去重
+func (s *ServerV2) savePacketData(packet *Packet, matched bool) bool {
+	// 设备重发或网络重传的包QN相同，认领失败说明已在处理
+	key := packetDedupKey(packet, matched)
+	if !s.dedup.claim(key, time.Now()) {
+		hj212DuplicatePacketsTotal.WithLabelValues("cache").Inc()
+		s.logger.Debug("Duplicate packet skipped",
+			zap.String("mn", packet.MN),
+			zap.String("qn", packet.QN))
+		return false
+	}
+
+	// 补传的历史数据可能与已入库数据重复
+	if isDuplicateHistoryData(s.db, packet) {
+		s.logger.Debug("Duplicate history data skipped",
+			zap.String("mn", packet.MN),
+			zap.String("cn", packet.CN),
+			zap.Time("data_time", packet.DataTime))
+		return false
+	}
+
 	// 构建数据模型
 	hj212Data := models.HJ212Data{
 		DeviceID:     packet.MN,
 		CommandCode:  packet.CN,
+		QN:           packetQN(packet, key),
+		PacketNo:     packetNo(packet),
 		DataType:     s.getDataType(packet.CN),
 		RawData:      string(packet.RawData),
 		ReceivedAt:   time.Now(),
-		QualityLevel: "normal",
+		DataTime:     dataTimePtr(packet.DataTime),
+		QualityLevel: models.HJ212QualityNormal,
 		IsValid:      true,
 	}
 
@@ -328,14 +501,15 @@ func (s *ServerV2) savePacketData(packet *Packet) {
 		factorData := make(map[string]interface{})
 		for code, factor := range packet.Factors {
 			factorData[code] = map[string]interface{}{
-				"name": factor.Name,
-				"rtd":  factor.Rtd,
-				"avg":  factor.Avg,
-				"max":  factor.Max,
-				"min":  factor.Min,
-				"cou":  factor.Cou,
-				"flag": factor.Flag,
-				"unit": factor.Unit,
+				"name":     factor.Name,
+				"rtd":      factor.Rtd,
+				"avg":      factor.Avg,
+				"max":      factor.Max,
+				"min":      factor.Min,
+				"cou":      factor.Cou,
+				"flag":     factor.Flag,
+				"unit":     factor.Unit,
+				"category": factor.Category,
 			}
 		}
 		// 添加系统编码信息
@@ -344,16 +518,33 @@ func (s *ServerV2) savePacketData(packet *Packet) {
 		hj212Data.ParsedData = factorData
 	}
 
-	// 保存到数据库
+	// 入库前完成质量校验，质量等级随数据保存
+	issues := s.checkDataQuality(packet)
+	applyQualityIssues(&hj212Data, issues)
+
+	// 保存到数据库，唯一索引冲突说明其他连接或实例已入库
 	if err := s.db.Create(&hj212Data).Error; err != nil {
+		if isDuplicateKeyError(err) {
+			hj212DuplicatePacketsTotal.WithLabelValues("database").Inc()
+			s.logger.Debug("Duplicate packet skipped",
+				zap.String("mn", packet.MN),
+				zap.String("qn", packet.QN))
+			return false
+		}
+		s.dedup.release(key)
 		s.logger.Error("Failed to save data",
 			zap.String("mn", packet.MN),
 			zap.Error(err))
-	} else {
-		s.logger.Debug("Data saved",
-			zap.String("mn", packet.MN),
-			zap.String("cn", packet.CN))
+		return true
 	}
+	s.logger.Debug("Data saved",
+		zap.String("mn", packet.MN),
+		zap.String("cn", packet.CN))
+
+	if alarms := s.quality.alarmIssues(packet.MN, issues, hj212Data.ReceivedAt); len(alarms) > 0 && s.qualityNotifier != nil {
+		s.qualityNotifier.DataQualityAnomaly(&hj212Data, alarms)
+	}
+	return true
 }
 
 // dataProcessor 数据处理协程
@@ -363,8 +554,7 @@ func (s *ServerV2) dataProcessor() {
 		case <-s.ctx.Done():
 			return
 		case packet := <-s.dataChannel:
-			// 数据质量检查
-			s.checkDataQuality(packet)
+			// 数据质量检查在入库时完成
 
 			// 数据聚合
 			s.aggregateData(packet)
@@ -399,12 +589,21 @@ func (s *ServerV2) processAlarm(alarm *AlarmData) {
 	// 3. 触发告警响应流程
 }
 
-// checkDataQuality 数据质量检查
-func (s *ServerV2) checkDataQuality(packet *Packet) {
-	// TODO: 实现数据质量检查
-	// 1. 范围检查
-	// 2. 变化率检查
-	// 3. 完整性检查
+// SetQualityNotifier 设置异常数据告警，为空时只标记质量等级
+func (s *ServerV2) SetQualityNotifier(notifier DataQualityNotifier) {
+	s.qualityNotifier = notifier
+}
+
+// checkDataQuality 数据质量检查：合理区间、与上一条相比的变化率和数据标记
+func (s *ServerV2) checkDataQuality(packet *Packet) []models.HJ212QualityIssue {
+	issues := s.quality.check(packet)
+	if len(issues) > 0 {
+		s.logger.Warn("Data quality issues found",
+			zap.String("mn", packet.MN),
+			zap.String("cn", packet.CN),
+			zap.Int("issues", len(issues)))
+	}
+	return issues
 }
 
 // aggregateData 数据聚合
@@ -496,6 +695,7 @@ func (s *ServerV2) sendResponse(conn net.Conn, response *Packet) {
 	if _, err := conn.Write(data); err != nil {
 		s.logger.Error("Failed to send response", zap.Error(err))
 	} else {
+		s.archive.record(archiveSent, response.MN, conn.RemoteAddr().String(), data)
 		s.logger.Debug("Response sent",
 			zap.String("cn", response.CN),
 			zap.String("data", string(data)))
@@ -507,23 +707,34 @@ func (s *ServerV2) periodicTasks() {
 	ticker := time.NewTicker(5 * time.Minute)
 	defer ticker.Stop()
 
+	checkInterval := s.config.OfflineCheckInterval
+	if checkInterval <= 0 {
+		checkInterval = time.Minute
+	}
+	offlineTicker := time.NewTicker(checkInterval)
+	defer offlineTicker.Stop()
+
 	for {
 		select {
 		case <-s.ctx.Done():
 			return
-		case <-ticker.C:
+		case <-offlineTicker.C:
 			// 清理不活跃连接
 			s.cleanupInactiveConnections()
-
+		case <-ticker.C:
 			// 更新统计信息
 			s.logStats()
+			// 关闭过期的归档文件并清理超过保留天数的归档
+			s.archive.cleanup()
+			s.purgeDeadLetters()
+			s.rejects.prune(time.Now())
 		}
 	}
 }
 
-// cleanupInactiveConnections 清理不活跃连接
+// cleanupInactiveConnections 清理不活跃连接，超时未收包的设备判定为离线
 func (s *ServerV2) cleanupInactiveConnections() {
-	// TODO: 实现连接清理逻辑
+	s.detectOfflineDevices()
 }
 
 // logStats 记录统计信息
@@ -537,6 +748,7 @@ func (s *ServerV2) logStats() {
 		zap.Uint64("invalid_packets", s.stats.InvalidPackets),
 		zap.Uint64("total_bytes", s.stats.TotalBytes),
 		zap.Uint32("connections", s.stats.Connections),
+		zap.Uint64("rejected_connections", s.stats.RejectedConnections),
 		zap.Duration("uptime", time.Since(s.stats.StartTime)))
 }
 
@@ -544,8 +756,11 @@ func (s *ServerV2) logStats() {
 func (s *ServerV2) Stop() error {
 	s.cancel()
 
-	if s.listener != nil {
-		s.listener.Close()
+	for _, listener := range s.listeners {
+		listener.Close()
+	}
+	if s.udpConn != nil {
+		s.udpConn.Close()
 	}
 
 	// 关闭所有连接
@@ -559,6 +774,8 @@ func (s *ServerV2) Stop() error {
 	close(s.dataChannel)
 	close(s.alarmChannel)
 
+	s.archive.Close()
+
 	s.logger.Info("HJ212 server v2 stopped")
 	return nil
 }
@@ -576,25 +793,51 @@ func (s *ServerV2) GetStats() map[string]interface{} {
 		"connections":     s.stats.Connections,
 		"uptime":          time.Since(s.stats.StartTime).String(),
 		"last_packet":     s.stats.LastPacketTime,
+
+		"max_connections":      s.config.MaxConnections,
+		"rejected_connections": s.stats.RejectedConnections,
+		"last_rejected":        s.stats.LastRejectedTime,
+
+		"udp_enabled":   s.config.UDP.Enabled,
+		"udp_datagrams": s.stats.UDPDatagrams,
+		"udp_devices":   s.udpDeviceCount(),
 	}
 }
 
-// GetConnectedDevices 获取连接的设备列表
+// GetConnectedDevices 获取连接的设备列表，包含未超过离线阈值的UDP设备
 func (s *ServerV2) GetConnectedDevices() []string {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	devices := make([]string, 0, len(s.connections))
+	devices := make([]string, 0, len(s.connections)+len(s.udpPeers))
 	for mn := range s.connections {
 		devices = append(devices, mn)
 	}
+	for mn := range s.udpPeers {
+		if _, ok := s.connections[mn]; !ok {
+			devices = append(devices, mn)
+		}
+	}
 	return devices
 }
 
+// udpDeviceCount 当前在线的UDP设备数
+func (s *ServerV2) udpDeviceCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.udpPeers)
+}
+
 // SendCommand 向设备发送命令
 func (s *ServerV2) SendCommand(deviceMN string, packet *Packet) error {
 	s.mu.RLock()
 	conn, exists := s.connections[deviceMN]
+	if !exists {
+		// UDP设备发往最后收包的来源地址
+		if peer, ok := s.udpPeers[deviceMN]; ok {
+			conn, exists = peer, true
+		}
+	}
 	s.mu.RUnlock()
 
 	if !exists {
@@ -606,8 +849,11 @@ func (s *ServerV2) SendCommand(deviceMN string, packet *Packet) error {
 		return fmt.Errorf("failed to build packet: %w", err)
 	}
 
-	_, err = conn.Write(data)
-	return err
+	if _, err = conn.Write(data); err != nil {
+		return err
+	}
+	s.archive.record(archiveSent, deviceMN, conn.RemoteAddr().String(), data)
+	return nil
 }
 
 // 辅助函数
@@ -622,19 +868,19 @@ func (s *ServerV2) getDataType(cn string) string {
 		return "hour"
 	case CN_GetDayData:
 		return "day"
+	case CN_GetRunTimeData:
+		return "runtime"
 	default:
 		return "unknown"
 	}
 }
 
-func findPacketEnd(data []byte) int {
-	// 查找 \r\n 结束标记
-	for i := 0; i < len(data)-1; i++ {
-		if data[i] == '\r' && data[i+1] == '\n' {
-			return i + 2
-		}
+// dataTimePtr 数据时间为空时返回nil
+func dataTimePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
 	}
-	return -1
+	return &t
 }
 
 func isTimeout(err error) bool {
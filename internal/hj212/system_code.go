@@ -0,0 +1,80 @@
+package hj212
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 因子类别，由因子编码前缀或系统编码推断
+const (
+	FactorCategoryAir       = "air"       // 空气、废气
+	FactorCategoryWater     = "water"     // 地表水、地下水、废水
+	FactorCategoryNoise     = "noise"     // 声环境、振动
+	FactorCategorySoil      = "soil"      // 土壤
+	FactorCategoryOcean     = "ocean"     // 海水
+	FactorCategoryRadiation = "radiation" // 放射性、电磁
+	FactorCategoryUnknown   = ""
+)
+
+// ErrUnknownSystemCode 系统编码不在HJ 212-2017编码表中
+var ErrUnknownSystemCode = errors.New("unknown ST code")
+
+// SystemCode 系统编码信息
+type SystemCode struct {
+	Code     string
+	Name     string
+	Category string // 该系统上报因子的默认类别，系统交互为空
+}
+
+// systemCodes 已知的系统编码
+var systemCodes = map[string]SystemCode{
+	ST_Water:         {ST_Water, "地表水质量监测", FactorCategoryWater},
+	ST_Air:           {ST_Air, "空气质量监测", FactorCategoryAir},
+	ST_Noise:         {ST_Noise, "声环境质量监测", FactorCategoryNoise},
+	ST_GroundWater:   {ST_GroundWater, "地下水质量监测", FactorCategoryWater},
+	ST_Soil:          {ST_Soil, "土壤质量监测", FactorCategorySoil},
+	ST_Ocean:         {ST_Ocean, "海水质量监测", FactorCategoryOcean},
+	ST_VOC:           {ST_VOC, "挥发性有机物监测", FactorCategoryAir},
+	ST_WasteGas:      {ST_WasteGas, "大气环境污染源", FactorCategoryAir},
+	ST_WasteWater:    {ST_WasteWater, "地表水体环境污染源", FactorCategoryWater},
+	ST_GroundPollute: {ST_GroundPollute, "地下水体环境污染源", FactorCategoryWater},
+	ST_OceanPollute:  {ST_OceanPollute, "海洋环境污染源", FactorCategoryOcean},
+	ST_SoilPollute:   {ST_SoilPollute, "土壤环境污染源", FactorCategorySoil},
+	ST_NoisePollute:  {ST_NoisePollute, "声环境污染源", FactorCategoryNoise},
+	ST_Vibration:     {ST_Vibration, "振动环境污染源", FactorCategoryNoise},
+	ST_Radioactive:   {ST_Radioactive, "放射性环境污染源", FactorCategoryRadiation},
+	ST_Dust:          {ST_Dust, "工地扬尘污染源", FactorCategoryAir},
+	ST_Electromag:    {ST_Electromag, "电磁环境污染源", FactorCategoryRadiation},
+	ST_System:        {ST_System, "系统交互", FactorCategoryUnknown},
+}
+
+// LookupSystemCode 查询系统编码，未知编码返回false
+func LookupSystemCode(st string) (SystemCode, bool) {
+	code, ok := systemCodes[st]
+	return code, ok
+}
+
+// validateSystemCode 校验系统编码为两位数字且在编码表中
+func validateSystemCode(st string) error {
+	if len(st) != 2 || st[0] < '0' || st[0] > '9' || st[1] < '0' || st[1] > '9' {
+		return errors.New("invalid ST format")
+	}
+	if _, ok := systemCodes[st]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownSystemCode, st)
+	}
+	return nil
+}
+
+// factorCategory 推断因子类别：a开头为大气类、w开头为水类（HJ 212-2017 附录B），
+// 其他编码（如噪声、自定义因子）按系统编码归类
+func factorCategory(code, st string) string {
+	if code != "" {
+		switch code[0] {
+		case 'a':
+			return FactorCategoryAir
+		case 'w':
+			return FactorCategoryWater
+		}
+	}
+	return systemCodes[st].Category
+}
@@ -0,0 +1,87 @@
+package hj212
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/env-data-platform/internal/config"
+)
+
+// newTLSConfig 根据配置加载服务端证书，配置了客户端CA时要求并校验设备证书
+func newTLSConfig(cfg config.HJ212TLSConfig) (*tls.Config, error) {
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, fmt.Errorf("TLS requires cert_file and key_file")
+	}
+
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificate in client CA file %s", cfg.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConfig, nil
+}
+
+// listen 按配置打开明文和TLS监听，仅TLS模式下不监听明文端口
+func (s *ServerV2) listen() ([]net.Listener, error) {
+	return listenTCP(s.config)
+}
+
+// listenTCP 按配置打开明文和TLS监听，Server和ServerV2共用
+func listenTCP(cfg *config.HJ212Config) ([]net.Listener, error) {
+	tlsCfg := cfg.TLS
+	if tlsCfg.Enabled && !tlsCfg.Only && tlsCfg.Port != 0 && tlsCfg.Port == cfg.TCPPort {
+		return nil, fmt.Errorf("TLS port %d conflicts with plain TCP port", tlsCfg.Port)
+	}
+
+	var listeners []net.Listener
+	closeAll := func() {
+		for _, l := range listeners {
+			l.Close()
+		}
+	}
+
+	if !tlsCfg.Enabled || !tlsCfg.Only {
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.TCPPort))
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen: %w", err)
+		}
+		listeners = append(listeners, listener)
+	}
+
+	if tlsCfg.Enabled {
+		tlsConfig, err := newTLSConfig(tlsCfg)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		listener, err := tls.Listen("tcp", fmt.Sprintf(":%d", tlsCfg.Port), tlsConfig)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("failed to listen TLS: %w", err)
+		}
+		listeners = append(listeners, listener)
+	}
+
+	return listeners, nil
+}
@@ -0,0 +1,86 @@
+package hj212
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/env-data-platform/internal/config"
+)
+
+// writeSelfSignedCert 生成自签名证书和私钥文件
+func writeSelfSignedCert(t *testing.T) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "hj212-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "server.crt")
+	keyFile = filepath.Join(dir, "server.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certFile, keyFile
+}
+
+func TestServerV2ListenTLS(t *testing.T) {
+	certFile, keyFile := writeSelfSignedCert(t)
+
+	// 仅TLS
+	s := &ServerV2{config: &config.HJ212Config{TLS: config.HJ212TLSConfig{
+		Enabled: true, CertFile: certFile, KeyFile: keyFile, Only: true,
+	}}}
+	listeners, err := s.listen()
+	require.NoError(t, err)
+	require.Len(t, listeners, 1)
+	defer listeners[0].Close()
+
+	go func() {
+		conn, err := listeners[0].Accept()
+		if err == nil {
+			conn.Write([]byte("ok"))
+			conn.Close()
+		}
+	}()
+
+	conn, err := tls.Dial("tcp", listeners[0].Addr().String(), &tls.Config{InsecureSkipVerify: true})
+	require.NoError(t, err)
+	buf := make([]byte, 2)
+	_, err = conn.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", string(buf))
+	conn.Close()
+
+	// 明文与TLS端口冲突
+	s.config.TLS.Only = false
+	s.config.TLS.Port = 9213
+	s.config.TCPPort = 9213
+	_, err = s.listen()
+	assert.Error(t, err)
+
+	// 证书缺失
+	_, err = newTLSConfig(config.HJ212TLSConfig{Enabled: true})
+	assert.Error(t, err)
+}
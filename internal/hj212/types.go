@@ -33,22 +33,24 @@ type Packet struct {
 	AlarmData *AlarmData
 
 	// 响应数据
+	QnRtn   string // 请求应答结果
 	ExeRtn  string // 执行结果
 	RtnInfo string // 返回信息
 }
 
 // FactorData 监测因子数据
 type FactorData struct {
-	Code  string  // 因子编码
-	Name  string  // 因子名称
-	Rtd   float64 // 实时数据
-	Avg   float64 // 平均值
-	Max   float64 // 最大值
-	Min   float64 // 最小值
-	Cou   float64 // 累计值
-	Flag  string  // 数据标记
-	EFlag string  // 异常标记
-	Unit  string  // 单位
+	Code     string  // 因子编码
+	Name     string  // 因子名称
+	Rtd      float64 // 实时数据
+	Avg      float64 // 平均值
+	Max      float64 // 最大值
+	Min      float64 // 最小值
+	Cou      float64 // 累计值
+	Flag     string  // 数据标记
+	EFlag    string  // 异常标记
+	Unit     string  // 单位
+	Category string  // 因子类别，见FactorCategory常量
 }
 
 // AlarmData 告警数据
@@ -90,6 +92,8 @@ const (
 	CN_GetMinInterval     = "1063" // 提取分钟数据间隔
 	CN_SetMinInterval     = "1064" // 设置分钟数据间隔
 	CN_SetPassword        = "1072" // 设置现场机密码
+	CN_GetAlarmLimit      = "1021" // 提取污染物报警门限值（HJ212-2005，多数数采仪仍支持）
+	CN_SetAlarmLimit      = "1022" // 设置污染物报警门限值（HJ212-2005，多数数采仪仍支持）
 
 	// 数据命令
 	CN_GetRtdData         = "2011" // 取污染物实时数据
@@ -123,16 +127,26 @@ const (
 	CN_DataResponse       = "9014" // 数据应答
 )
 
-// 系统编码常量
+// 系统编码常量，见HJ 212-2017 附录B 表5
 const (
-	ST_Water = "21" // 地表水质量监测
-	ST_Air   = "22" // 空气质量监测
-	ST_Noise = "23" // 声环境质量监测
-	ST_Soil  = "24" // 土壤质量监测
-	ST_Ocean = "25" // 海水质量监测
-	ST_WasteWater = "32" // 废水污染源
-	ST_WasteGas   = "31" // 废气污染源
-	ST_System     = "91" // 系统交互
+	ST_Water         = "21" // 地表水质量监测
+	ST_Air           = "22" // 空气质量监测
+	ST_Noise         = "23" // 声环境质量监测
+	ST_GroundWater   = "24" // 地下水质量监测
+	ST_Soil          = "25" // 土壤质量监测
+	ST_Ocean         = "26" // 海水质量监测
+	ST_VOC           = "27" // 挥发性有机物监测
+	ST_WasteGas      = "31" // 大气环境污染源
+	ST_WasteWater    = "32" // 地表水体环境污染源
+	ST_GroundPollute = "33" // 地下水体环境污染源
+	ST_OceanPollute  = "34" // 海洋环境污染源
+	ST_SoilPollute   = "35" // 土壤环境污染源
+	ST_NoisePollute  = "36" // 声环境污染源
+	ST_Vibration     = "37" // 振动环境污染源
+	ST_Radioactive   = "38" // 放射性环境污染源
+	ST_Dust          = "39" // 工地扬尘污染源
+	ST_Electromag    = "41" // 电磁环境污染源
+	ST_System        = "91" // 系统交互
 )
 
 // 标志位定义
@@ -153,6 +167,12 @@ const (
 	ExeRtn_NoData          = "100" // 无数据
 )
 
+// 请求应答结果定义
+const (
+	QnRtn_Ready    = "1" // 准备执行请求
+	QnRtn_Rejected = "2" // 请求被拒绝
+)
+
 // CommandInfo 命令信息
 type CommandInfo struct {
 	CN          string // 命令编码
@@ -221,6 +241,11 @@ func IsDataCommand(cn string) bool {
 	return cn >= "2000" && cn < "3000"
 }
 
+// IsParamCommand 判断是否为初始化或参数命令
+func IsParamCommand(cn string) bool {
+	return cn >= "1000" && cn < "2000"
+}
+
 // IsControlCommand 判断是否为控制命令
 func IsControlCommand(cn string) bool {
 	return cn >= "3000" && cn < "4000"
@@ -0,0 +1,139 @@
+package hj212
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/env-data-platform/internal/config"
+)
+
+// udpMaxDatagram UDP数据报最大长度，读缓冲区不小于该值以免截断
+const udpMaxDatagram = 65535
+
+// errUDPRead UDP来源不支持按连接读取，数据报统一由serveUDP读取
+var errUDPRead = errors.New("read not supported on UDP peer")
+
+// udpPeer 把UDP数据报的来源地址包装成net.Conn，应答经Write回写到来源地址，
+// 使TCP的报文处理和应答逻辑可以直接复用
+type udpPeer struct {
+	conn net.PacketConn
+	addr net.Addr
+}
+
+func (p *udpPeer) Read(b []byte) (int, error)         { return 0, errUDPRead }
+func (p *udpPeer) Write(b []byte) (int, error)        { return p.conn.WriteTo(b, p.addr) }
+func (p *udpPeer) Close() error                       { return nil }
+func (p *udpPeer) LocalAddr() net.Addr                { return p.conn.LocalAddr() }
+func (p *udpPeer) RemoteAddr() net.Addr               { return p.addr }
+func (p *udpPeer) SetDeadline(t time.Time) error      { return nil }
+func (p *udpPeer) SetReadDeadline(t time.Time) error  { return nil }
+func (p *udpPeer) SetWriteDeadline(t time.Time) error { return nil }
+
+// listenUDP 打开UDP监听，未启用时返回nil
+func (s *ServerV2) listenUDP() (net.PacketConn, error) {
+	return listenUDP(s.config)
+}
+
+// listenUDP 按配置打开UDP监听，未启用时返回nil，Server和ServerV2共用
+func listenUDP(cfg *config.HJ212Config) (net.PacketConn, error) {
+	if !cfg.UDP.Enabled {
+		return nil, nil
+	}
+	conn, err := net.ListenPacket("udp", fmt.Sprintf(":%d", cfg.UDP.Port))
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen UDP: %w", err)
+	}
+	return conn, nil
+}
+
+// serveUDP 读取UDP数据报，一个数据报可包含多个以\r\n结尾的数据包，末尾不完整的部分按一个包处理
+func (s *ServerV2) serveUDP(conn net.PacketConn) {
+	buffer := make([]byte, udpBufferSize(s.config.BufferSize))
+
+	for {
+		n, addr, err := conn.ReadFrom(buffer)
+		if err != nil {
+			if s.ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			s.logger.Error("UDP read error", zap.Error(err))
+			continue
+		}
+		if n == 0 {
+			continue
+		}
+
+		s.stats.mu.Lock()
+		s.stats.TotalBytes += uint64(n)
+		s.stats.UDPDatagrams++
+		s.stats.mu.Unlock()
+		hj212ReceivedBytesTotal.Add(float64(n))
+		hj212UDPDatagramsTotal.Inc()
+
+		// 数据报之间不共享缓冲区，解析结果会被异步处理
+		data := append([]byte(nil), buffer[:n]...)
+		peer := &udpPeer{conn: conn, addr: addr}
+		for len(data) > 0 {
+			// 数据报内的半包不会再有后续数据，整体作为无效包处理
+			end := splitPacket(data)
+			if end == 0 {
+				end = len(data)
+			}
+			s.processPacket(peer, addr.String(), data[:end])
+			data = data[end:]
+		}
+	}
+}
+
+// rememberUDPPeer 记录UDP设备最后的来源地址，下发命令时发往该地址；
+// UDP设备不占用连接数，在线状态完全由最后收包时间判断
+func (s *ServerV2) rememberUDPPeer(mn string, peer *udpPeer) {
+	s.mu.Lock()
+	s.udpPeers[mn] = peer
+	s.mu.Unlock()
+}
+
+// udpBufferSize UDP读缓冲区大小，不小于最大数据报长度
+func udpBufferSize(size int) int {
+	if size < udpMaxDatagram {
+		return udpMaxDatagram
+	}
+	return size
+}
+
+// serveUDP 读取UDP数据报并按包交给handleMessage处理，应答和下发命令经udpPeer发往来源地址
+func (s *Server) serveUDP(conn net.PacketConn) {
+	buffer := make([]byte, udpBufferSize(s.config.HJ212.BufferSize))
+
+	for {
+		n, addr, err := conn.ReadFrom(buffer)
+		if err != nil {
+			if s.ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			s.logger.Error("UDP read error", zap.Error(err))
+			continue
+		}
+		if n == 0 {
+			continue
+		}
+		hj212ReceivedBytesTotal.Add(float64(n))
+		hj212UDPDatagramsTotal.Inc()
+
+		data := buffer[:n]
+		peer := &udpPeer{conn: conn, addr: addr}
+		for len(data) > 0 {
+			// 数据报内的半包不会再有后续数据，整体作为无效包处理
+			end := splitPacket(data)
+			if end == 0 {
+				end = len(data)
+			}
+			s.handleMessage(peer, addr.String(), string(data[:end]))
+			data = data[end:]
+		}
+	}
+}
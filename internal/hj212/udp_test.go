@@ -0,0 +1,70 @@
+package hj212
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/env-data-platform/internal/config"
+)
+
+func TestServerV2UDP(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := &ServerV2{
+		config:      &config.HJ212Config{UDP: config.HJ212UDPConfig{Enabled: true}},
+		logger:      zap.NewNop(),
+		parser:      NewParser("2017"),
+		connections: make(map[string]net.Conn),
+		udpPeers:    make(map[string]*udpPeer),
+		ctx:         ctx,
+		cancel:      cancel,
+		stats:       &ServerStats{StartTime: time.Now()},
+	}
+
+	conn, err := s.listenUDP()
+	require.NoError(t, err)
+	require.NotNil(t, conn)
+	go s.serveUDP(conn)
+	defer conn.Close()
+
+	device, err := net.Dial("udp", conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer device.Close()
+
+	// 一个数据报中的多个包分别处理，不完整的末尾按一个包处理
+	_, err = device.Write([]byte("bad1\r\nbad2"))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		s.stats.mu.RLock()
+		defer s.stats.mu.RUnlock()
+		return s.stats.UDPDatagrams == 1 && s.stats.InvalidPackets == 2
+	}, time.Second, 10*time.Millisecond)
+
+	// 命令发往设备最后的来源地址
+	s.rememberUDPPeer("MN001", &udpPeer{conn: conn, addr: device.LocalAddr()})
+	assert.Contains(t, s.GetConnectedDevices(), "MN001")
+	require.NoError(t, s.SendCommand("MN001", &Packet{
+		QN: "20240301080000001", ST: ST_System, CN: CN_Response, MN: "MN001",
+	}))
+
+	buf := make([]byte, 1024)
+	device.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := device.Read(buf)
+	require.NoError(t, err)
+	packet, err := s.parser.Parse(buf[:n])
+	require.NoError(t, err)
+	assert.Equal(t, "MN001", packet.MN)
+	assert.Equal(t, CN_Response, packet.CN)
+
+	// 未启用时不监听
+	s.config.UDP.Enabled = false
+	disabled, err := s.listenUDP()
+	require.NoError(t, err)
+	assert.Nil(t, disabled)
+}
@@ -0,0 +1,192 @@
+// Package kafka 基于segmentio/kafka-go封装ETL所需的生产、按分区拉取和消费组offset提交。
+// 消费组仅用于在broker上保存offset（不参与组成员协调），同一消费组不应再被其他订阅式消费者使用
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// 默认超时
+const (
+	DefaultDialTimeout    = 10 * time.Second
+	DefaultRequestTimeout = 30 * time.Second
+
+	defaultClientID = "env-data-platform"
+	metadataRetries = 3
+	retryBackoff    = 500 * time.Millisecond
+)
+
+// Config 客户端配置
+type Config struct {
+	Brokers        []string
+	ClientID       string
+	DialTimeout    time.Duration
+	RequestTimeout time.Duration
+}
+
+// Partition 分区元数据
+type Partition struct {
+	ID     int32
+	Leader int32
+}
+
+// Client Kafka客户端，请求按分区leader或消费组协调者自动路由，连接由transport复用，可并发使用
+type Client struct {
+	cfg       Config
+	client    *kafkago.Client
+	transport *kafkago.Transport
+
+	mu        sync.Mutex
+	producers []*Producer
+}
+
+// NewClient 创建客户端，连接在首次请求时建立
+func NewClient(cfg Config) (*Client, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, errors.New("kafka: no brokers configured")
+	}
+	if cfg.ClientID == "" {
+		cfg.ClientID = defaultClientID
+	}
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = DefaultDialTimeout
+	}
+	if cfg.RequestTimeout <= 0 {
+		cfg.RequestTimeout = DefaultRequestTimeout
+	}
+
+	transport := &kafkago.Transport{
+		ClientID:    cfg.ClientID,
+		DialTimeout: cfg.DialTimeout,
+	}
+	return &Client{
+		cfg:       cfg,
+		transport: transport,
+		client: &kafkago.Client{
+			Addr:      kafkago.TCP(cfg.Brokers...),
+			Timeout:   cfg.RequestTimeout,
+			Transport: transport,
+		},
+	}, nil
+}
+
+// Close 关闭由该客户端创建的生产者及全部空闲连接
+func (c *Client) Close() error {
+	c.mu.Lock()
+	producers := c.producers
+	c.producers = nil
+	c.mu.Unlock()
+
+	var firstErr error
+	for _, p := range producers {
+		if err := p.writer.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	c.transport.CloseIdleConnections()
+	return firstErr
+}
+
+// Partitions 查询topic的分区及leader，topic刚自动创建时leader可能暂未选出，会短暂重试
+func (c *Client) Partitions(ctx context.Context, topic string) ([]Partition, error) {
+	var lastErr error
+	for attempt := 0; attempt < metadataRetries; attempt++ {
+		if attempt > 0 {
+			if err := sleep(ctx, retryBackoff); err != nil {
+				return nil, err
+			}
+		}
+
+		partitions, err := c.metadata(ctx, topic)
+		if err == nil {
+			return partitions, nil
+		}
+		lastErr = err
+		if !retriable(err) {
+			break
+		}
+	}
+	return nil, fmt.Errorf("kafka: load metadata of topic %s: %w", topic, lastErr)
+}
+
+// metadata 发送Metadata请求，任一分区leader未就绪时返回可重试的错误
+func (c *Client) metadata(ctx context.Context, topic string) ([]Partition, error) {
+	resp, err := c.client.Metadata(ctx, &kafkago.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range resp.Topics {
+		if t.Name != topic {
+			continue
+		}
+		if t.Error != nil {
+			return nil, t.Error
+		}
+		if len(t.Partitions) == 0 {
+			return nil, kafkago.LeaderNotAvailable
+		}
+		partitions := make([]Partition, 0, len(t.Partitions))
+		for _, p := range t.Partitions {
+			if p.Error != nil {
+				return nil, p.Error
+			}
+			partitions = append(partitions, Partition{ID: int32(p.ID), Leader: int32(p.Leader.ID)})
+		}
+		return partitions, nil
+	}
+	return nil, kafkago.UnknownTopicOrPartition
+}
+
+// listOffsets 查询各分区的最早和最新offset
+func (c *Client) listOffsets(ctx context.Context, topic string, partitions []int32) (first, last map[int32]int64, err error) {
+	requests := make([]kafkago.OffsetRequest, 0, 2*len(partitions))
+	for _, id := range partitions {
+		requests = append(requests, kafkago.FirstOffsetOf(int(id)), kafkago.LastOffsetOf(int(id)))
+	}
+	resp, err := c.client.ListOffsets(ctx, &kafkago.ListOffsetsRequest{
+		Topics: map[string][]kafkago.OffsetRequest{topic: requests},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	first = make(map[int32]int64, len(partitions))
+	last = make(map[int32]int64, len(partitions))
+	for _, p := range resp.Topics[topic] {
+		if p.Error != nil {
+			return nil, nil, fmt.Errorf("kafka: list offsets of %s/%d: %w", topic, p.Partition, p.Error)
+		}
+		first[int32(p.Partition)] = p.FirstOffset
+		last[int32(p.Partition)] = p.LastOffset
+	}
+	for _, id := range partitions {
+		if _, ok := last[id]; !ok {
+			return nil, nil, fmt.Errorf("kafka: list offsets of %s/%d: no response", topic, id)
+		}
+	}
+	return first, last, nil
+}
+
+// retriable leader切换、协调者迁移等临时错误可重试
+func retriable(err error) bool {
+	var kerr kafkago.Error
+	return errors.As(err, &kerr) && kerr.Temporary()
+}
+
+// sleep 可被上下文中断的等待
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
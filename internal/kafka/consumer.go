@@ -0,0 +1,276 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// 无已提交offset时的起始位置
+const (
+	OffsetEarliest int64 = -2
+	OffsetLatest   int64 = -1
+)
+
+const defaultFetchMaxBytes = 1 << 20
+
+// Consumer 消费topic的全部分区，offset保存在消费组中。
+// 创建时记录各分区的最新位置，Done表示已消费到该位置，便于批量ETL按批次处理
+type Consumer struct {
+	client     *Client
+	topic      string
+	group      string
+	reset      int64
+	partitions []*cursor
+	// MaxBytes 单个分区每次拉取的最大字节数
+	MaxBytes int32
+}
+
+// cursor 分区的消费位置
+type cursor struct {
+	Partition
+	position  int64 // 下一次拉取的offset
+	marked    int64 // 已处理完成、待提交的offset
+	committed int64 // 已提交的offset
+	end       int64 // 创建消费者时的最新offset
+}
+
+// NewConsumer 创建消费者，从消费组已提交的offset继续消费，没有已提交offset时按reset决定起始位置
+func (c *Client) NewConsumer(ctx context.Context, topic, group string, reset int64) (*Consumer, error) {
+	if group == "" {
+		return nil, errors.New("kafka: consumer group is required")
+	}
+	if reset != OffsetEarliest && reset != OffsetLatest {
+		return nil, fmt.Errorf("kafka: invalid offset reset %d", reset)
+	}
+
+	partitions, err := c.Partitions(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+	consumer := &Consumer{client: c, topic: topic, group: group, reset: reset, MaxBytes: defaultFetchMaxBytes}
+	for _, p := range partitions {
+		consumer.partitions = append(consumer.partitions, &cursor{Partition: p})
+	}
+
+	committed, err := consumer.fetchCommitted(ctx)
+	if err != nil {
+		return nil, err
+	}
+	starts, ends, err := c.listOffsets(ctx, topic, consumer.partitionIDs())
+	if err != nil {
+		return nil, err
+	}
+	if reset == OffsetLatest {
+		starts = ends
+	}
+
+	for _, p := range consumer.partitions {
+		p.end = ends[p.ID]
+		p.committed = committed[p.ID]
+		if p.committed >= 0 {
+			p.position = p.committed
+		} else {
+			p.position = starts[p.ID]
+		}
+		p.marked = p.position
+	}
+	return consumer, nil
+}
+
+// Done 是否已消费到创建消费者时各分区的最新位置
+func (c *Consumer) Done() bool {
+	for _, p := range c.partitions {
+		if p.position < p.end {
+			return false
+		}
+	}
+	return true
+}
+
+// Lag 距创建消费者时最新位置尚未拉取的消息数
+func (c *Consumer) Lag() int64 {
+	var lag int64
+	for _, p := range c.partitions {
+		if p.end > p.position {
+			lag += p.end - p.position
+		}
+	}
+	return lag
+}
+
+// Fetch 从尚未消费到最新位置的分区拉取一批消息，maxWait为broker等待新消息的最长时间。
+// leader切换等临时错误跳过该分区，稍后由下一次Fetch重试
+func (c *Consumer) Fetch(ctx context.Context, maxWait time.Duration) ([]Message, error) {
+	var msgs []Message
+	var retryLater bool
+	for _, p := range c.partitions {
+		if p.position >= p.end {
+			continue
+		}
+
+		resp, err := c.client.client.Fetch(ctx, &kafkago.FetchRequest{
+			Topic:     c.topic,
+			Partition: int(p.ID),
+			Offset:    p.position,
+			MinBytes:  1,
+			MaxBytes:  int64(c.MaxBytes),
+			MaxWait:   maxWait,
+		})
+		if err == nil {
+			err = resp.Error
+		}
+		switch {
+		case err == nil:
+		case errors.Is(err, kafkago.OffsetOutOfRange):
+			// 已提交的offset已被清理，按reset重新定位
+			starts, ends, lerr := c.client.listOffsets(ctx, c.topic, []int32{p.ID})
+			if lerr != nil {
+				return msgs, lerr
+			}
+			if c.reset == OffsetLatest {
+				p.position = ends[p.ID]
+			} else {
+				p.position = starts[p.ID]
+			}
+			continue
+		case retriable(err):
+			retryLater = true
+			continue
+		default:
+			return msgs, fmt.Errorf("kafka: fetch %s/%d: %w", c.topic, p.ID, err)
+		}
+
+		batch, next, err := readRecords(c.topic, p.ID, resp.Records, p.position)
+		if err != nil {
+			return msgs, fmt.Errorf("kafka: fetch %s/%d: %w", c.topic, p.ID, err)
+		}
+		msgs = append(msgs, batch...)
+		if next > p.position {
+			p.position = next
+		}
+	}
+
+	// 全部分区都在等待元数据刷新时稍作等待，避免空转
+	if retryLater && len(msgs) == 0 {
+		if err := sleep(ctx, retryBackoff); err != nil {
+			return nil, err
+		}
+	}
+	return msgs, nil
+}
+
+// Mark 标记消息已处理完成，下次Commit时提交
+func (c *Consumer) Mark(msg Message) {
+	for _, p := range c.partitions {
+		if p.ID == msg.Partition && msg.Offset+1 > p.marked {
+			p.marked = msg.Offset + 1
+		}
+	}
+}
+
+// Commit 提交已标记的offset，没有新进度时不发送请求
+func (c *Consumer) Commit(ctx context.Context) error {
+	pending := c.pending()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	err := c.commit(ctx, pending)
+	if retriable(err) {
+		// 协调者迁移后transport会重新查找，重试一次
+		if err := sleep(ctx, retryBackoff); err != nil {
+			return err
+		}
+		err = c.commit(ctx, c.pending())
+	}
+	return err
+}
+
+// pending 有未提交进度的分区
+func (c *Consumer) pending() []*cursor {
+	var pending []*cursor
+	for _, p := range c.partitions {
+		if p.marked > p.committed {
+			pending = append(pending, p)
+		}
+	}
+	return pending
+}
+
+// commit 发送OffsetCommit请求，generation为-1表示不参与组成员协调，仅保存offset
+func (c *Consumer) commit(ctx context.Context, pending []*cursor) error {
+	offsets := make([]kafkago.OffsetCommit, 0, len(pending))
+	byID := make(map[int32]*cursor, len(pending))
+	for _, p := range pending {
+		offsets = append(offsets, kafkago.OffsetCommit{Partition: int(p.ID), Offset: p.marked})
+		byID[p.ID] = p
+	}
+
+	resp, err := c.client.client.OffsetCommit(ctx, &kafkago.OffsetCommitRequest{
+		GroupID:      c.group,
+		GenerationID: -1,
+		Topics:       map[string][]kafkago.OffsetCommit{c.topic: offsets},
+	})
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	for _, result := range resp.Topics[c.topic] {
+		p, ok := byID[int32(result.Partition)]
+		if !ok {
+			continue
+		}
+		if result.Error != nil {
+			if firstErr == nil {
+				firstErr = result.Error
+			}
+			continue
+		}
+		p.committed = p.marked
+	}
+	return firstErr
+}
+
+// fetchCommitted 查询消费组已提交的offset，未提交的分区为-1
+func (c *Consumer) fetchCommitted(ctx context.Context) (map[int32]int64, error) {
+	ids := make([]int, 0, len(c.partitions))
+	for _, p := range c.partitions {
+		ids = append(ids, int(p.ID))
+	}
+	resp, err := c.client.client.OffsetFetch(ctx, &kafkago.OffsetFetchRequest{
+		GroupID: c.group,
+		Topics:  map[string][]int{c.topic: ids},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("kafka: fetch committed offsets of group %s: %w", c.group, resp.Error)
+	}
+
+	offsets := make(map[int32]int64, len(c.partitions))
+	for _, p := range c.partitions {
+		offsets[p.ID] = -1
+	}
+	for _, p := range resp.Topics[c.topic] {
+		if p.Error != nil {
+			return nil, fmt.Errorf("kafka: fetch committed offset of %s/%d: %w", c.topic, p.Partition, p.Error)
+		}
+		offsets[int32(p.Partition)] = p.CommittedOffset
+	}
+	return offsets, nil
+}
+
+// partitionIDs 消费的全部分区ID
+func (c *Consumer) partitionIDs() []int32 {
+	ids := make([]int32, 0, len(c.partitions))
+	for _, p := range c.partitions {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
@@ -0,0 +1,87 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClientValidatesConfig(t *testing.T) {
+	_, err := NewClient(Config{})
+	assert.Error(t, err)
+
+	client, err := NewClient(Config{Brokers: []string{"127.0.0.1:9092"}})
+	require.NoError(t, err)
+	assert.Equal(t, defaultClientID, client.cfg.ClientID)
+	assert.Equal(t, DefaultRequestTimeout, client.cfg.RequestTimeout)
+	assert.NoError(t, client.Close())
+
+	_, err = client.NewConsumer(context.Background(), "t", "", OffsetEarliest)
+	assert.Error(t, err)
+	_, err = client.NewConsumer(context.Background(), "t", "g", 0)
+	assert.Error(t, err)
+}
+
+func TestReadRecords(t *testing.T) {
+	now := time.UnixMilli(1700000000000)
+	records := &protocol.RecordStream{Records: []protocol.RecordReader{
+		&protocol.RecordBatch{Records: protocol.NewRecordReader(
+			protocol.Record{Offset: 99, Time: now, Value: protocol.NewBytes([]byte("consumed"))},
+			protocol.Record{Offset: 100, Time: now, Key: protocol.NewBytes([]byte("k1")), Value: protocol.NewBytes([]byte(`{"a":1}`))},
+			protocol.Record{Offset: 101, Time: now.Add(time.Second), Value: protocol.NewBytes([]byte("plain"))},
+		)},
+		// 事务提交标记，不应作为消息返回
+		protocol.NewControlBatch(protocol.ControlRecord{Offset: 102, Time: now}),
+	}}
+
+	msgs, next, err := readRecords("t", 2, records, 100)
+	require.NoError(t, err)
+	assert.Equal(t, int64(103), next)
+	require.Len(t, msgs, 2)
+
+	assert.Equal(t, int64(100), msgs[0].Offset)
+	assert.Equal(t, int32(2), msgs[0].Partition)
+	assert.Equal(t, "t", msgs[0].Topic)
+	assert.Equal(t, "k1", string(msgs[0].Key))
+	assert.Equal(t, `{"a":1}`, string(msgs[0].Value))
+	assert.Nil(t, msgs[1].Key)
+	assert.Equal(t, now.Add(time.Second).UnixMilli(), msgs[1].Time.UnixMilli())
+
+	msgs, next, err = readRecords("t", 2, protocol.NewRecordReader(), 100)
+	require.NoError(t, err)
+	assert.Empty(t, msgs)
+	assert.Equal(t, int64(-1), next)
+}
+
+func TestConsumerProgress(t *testing.T) {
+	c := &Consumer{partitions: []*cursor{
+		{Partition: Partition{ID: 0}, position: 5, marked: 5, committed: 5, end: 8},
+		{Partition: Partition{ID: 1}, position: 3, marked: 3, committed: 3, end: 3},
+	}}
+	assert.False(t, c.Done())
+	assert.Equal(t, int64(3), c.Lag())
+	// 没有新进度的分区不提交
+	assert.Empty(t, c.pending())
+
+	c.partitions[0].position = 8
+	c.Mark(Message{Partition: 0, Offset: 6})
+	// 乱序标记不会回退进度
+	c.Mark(Message{Partition: 0, Offset: 5})
+	assert.True(t, c.Done())
+	assert.Zero(t, c.Lag())
+	assert.Equal(t, int64(7), c.partitions[0].marked)
+	assert.Equal(t, []int32{0, 1}, c.partitionIDs())
+
+	pending := c.pending()
+	require.Len(t, pending, 1)
+	assert.Equal(t, int32(0), pending[0].ID)
+}
+
+func TestProducerSendEmpty(t *testing.T) {
+	p := &Producer{topic: "t"}
+	assert.NoError(t, p.Send(context.Background(), nil))
+}
@@ -0,0 +1,81 @@
+package kafka
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol"
+)
+
+// Message 一条Kafka消息
+type Message struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Time      time.Time
+}
+
+// readRecords 读取Fetch响应中的记录，返回不小于from的消息及下一次拉取的位置（无记录时为-1）。
+// 事务控制批次不包含业务消息，但其offset同样需要跳过，否则分区会停在控制批次处
+func readRecords(topic string, partition int32, records protocol.RecordReader, from int64) ([]Message, int64, error) {
+	var msgs []Message
+	next := int64(-1)
+
+	batches := []protocol.RecordReader{records}
+	if stream, ok := records.(*protocol.RecordStream); ok {
+		batches = stream.Records
+	}
+	for _, batch := range batches {
+		_, control := batch.(*protocol.ControlBatch)
+		for {
+			record, err := batch.ReadRecord()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return msgs, next, err
+			}
+			if record.Offset+1 > next {
+				next = record.Offset + 1
+			}
+			// 压缩批次会从批次起始位置返回，已消费过的消息跳过
+			if control || record.Offset < from {
+				closeRecord(record)
+				continue
+			}
+
+			key, err := protocol.ReadAll(record.Key)
+			if err == nil {
+				var value []byte
+				if value, err = protocol.ReadAll(record.Value); err == nil {
+					msgs = append(msgs, Message{
+						Topic:     topic,
+						Partition: partition,
+						Offset:    record.Offset,
+						Key:       key,
+						Value:     value,
+						Time:      record.Time,
+					})
+				}
+			}
+			closeRecord(record)
+			if err != nil {
+				return msgs, next, err
+			}
+		}
+	}
+	return msgs, next, nil
+}
+
+// closeRecord 释放记录的key和value占用的缓冲区
+func closeRecord(record *protocol.Record) {
+	if record.Key != nil {
+		record.Key.Close()
+	}
+	if record.Value != nil {
+		record.Value.Close()
+	}
+}
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/env-data-platform/internal/config"
@@ -9,24 +10,43 @@ import (
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-// New 创建新的日志器
-func New(cfg *config.Config) (*zap.Logger, error) {
-	// 设置日志级别
-	level := zapcore.InfoLevel
-	switch cfg.Log.Level {
+// atomicLevel 日志级别，配置热加载时通过SetLevel调整
+var atomicLevel = zap.NewAtomicLevelAt(zapcore.InfoLevel)
+
+// parseLevel 解析日志级别，未识别的级别按info处理
+func parseLevel(name string) (zapcore.Level, bool) {
+	switch name {
 	case "debug":
-		level = zapcore.DebugLevel
+		return zapcore.DebugLevel, true
 	case "info":
-		level = zapcore.InfoLevel
+		return zapcore.InfoLevel, true
 	case "warn":
-		level = zapcore.WarnLevel
+		return zapcore.WarnLevel, true
 	case "error":
-		level = zapcore.ErrorLevel
+		return zapcore.ErrorLevel, true
 	case "panic":
-		level = zapcore.PanicLevel
+		return zapcore.PanicLevel, true
 	case "fatal":
-		level = zapcore.FatalLevel
+		return zapcore.FatalLevel, true
+	}
+	return zapcore.InfoLevel, false
+}
+
+// SetLevel 运行时调整日志级别
+func SetLevel(name string) error {
+	level, ok := parseLevel(name)
+	if !ok {
+		return fmt.Errorf("不支持的日志级别: %s", name)
 	}
+	atomicLevel.SetLevel(level)
+	return nil
+}
+
+// New 创建新的日志器
+func New(cfg *config.Config) (*zap.Logger, error) {
+	// 设置日志级别
+	level, _ := parseLevel(cfg.Log.Level)
+	atomicLevel.SetLevel(level)
 
 	// 设置编码器配置
 	var encoderConfig zapcore.EncoderConfig
@@ -101,7 +121,7 @@ func New(cfg *config.Config) (*zap.Logger, error) {
 	}
 
 	// 创建核心
-	core := zapcore.NewCore(encoder, writeSyncer, level)
+	core := zapcore.NewCore(encoder, writeSyncer, atomicLevel)
 
 	// 创建日志器选项
 	options := []zap.Option{
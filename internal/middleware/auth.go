@@ -3,14 +3,15 @@ package middleware
 import (
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
 	"github.com/env-data-platform/internal/auth"
 	"github.com/env-data-platform/internal/config"
-	"github.com/env-data-platform/internal/database"
 	"github.com/env-data-platform/internal/models"
+	"github.com/env-data-platform/internal/services"
 )
 
 // AuthMiddleware JWT认证中间件
@@ -22,7 +23,7 @@ func AuthMiddleware(cfg *config.Config, logger *zap.Logger) gin.HandlerFunc {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			logger.Warn("Missing authorization header")
-			c.JSON(http.StatusUnauthorized, models.ErrorResponse(http.StatusUnauthorized, "缺少认证令牌"))
+			c.JSON(http.StatusUnauthorized, models.CodeErrorResponse(models.CodeTokenMissing, ""))
 			c.Abort()
 			return
 		}
@@ -31,7 +32,7 @@ func AuthMiddleware(cfg *config.Config, logger *zap.Logger) gin.HandlerFunc {
 		tokenParts := strings.SplitN(authHeader, " ", 2)
 		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
 			logger.Warn("Invalid authorization header format")
-			c.JSON(http.StatusUnauthorized, models.ErrorResponse(http.StatusUnauthorized, "认证令牌格式错误"))
+			c.JSON(http.StatusUnauthorized, models.CodeErrorResponse(models.CodeTokenMalformed, ""))
 			c.Abort()
 			return
 		}
@@ -40,7 +41,22 @@ func AuthMiddleware(cfg *config.Config, logger *zap.Logger) gin.HandlerFunc {
 		claims, err := jwtManager.ParseToken(tokenParts[1])
 		if err != nil {
 			logger.Warn("Invalid token", zap.Error(err))
-			c.JSON(http.StatusUnauthorized, models.ErrorResponse(http.StatusUnauthorized, "认证令牌无效"))
+			c.JSON(http.StatusUnauthorized, models.CodeErrorResponse(models.CodeTokenInvalid, ""))
+			c.Abort()
+			return
+		}
+
+		// 用户重置密码后，之前签发的令牌失效
+		revoked, err := services.IsSessionRevoked(claims.UserID, claims.IssuedAt.Time)
+		if err != nil {
+			logger.Error("Failed to check session revocation", zap.Uint("user_id", claims.UserID), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "认证状态校验失败"))
+			c.Abort()
+			return
+		}
+		if revoked {
+			logger.Warn("Revoked token rejected", zap.Uint("user_id", claims.UserID))
+			c.JSON(http.StatusUnauthorized, models.CodeErrorResponse(models.CodeSessionRevoked, ""))
 			c.Abort()
 			return
 		}
@@ -50,39 +66,39 @@ func AuthMiddleware(cfg *config.Config, logger *zap.Logger) gin.HandlerFunc {
 		c.Set("username", claims.Username)
 		c.Set("role_id", claims.RoleID)
 		c.Set("role_name", claims.RoleName)
+		onlineUsers.touch(claims.UserID, time.Now())
 
 		c.Next()
 	}
 }
 
-// RequirePermission 权限检查中间件（基于角色权限）
+// RequirePermission 权限检查中间件，permission为"资源:动作"形式的权限code
+// 用户角色授予的权限支持通配符，如"etl:*"可满足"etl:job:execute"
 func RequirePermission(permission string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 获取用户角色ID
-		roleID, exists := c.Get("role_id")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, models.ErrorResponse(http.StatusUnauthorized, "用户未登录"))
+		// 获取用户ID
+		userID := c.GetUint("user_id")
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, models.CodeErrorResponse(models.CodeNotLoggedIn, ""))
 			c.Abort()
 			return
 		}
 
 		// 超级管理员拥有所有权限
-		roleName, _ := c.Get("role_name")
-		if roleName == "超级管理员" || roleName == "admin" {
+		if IsSuperAdmin(c) {
 			c.Next()
 			return
 		}
 
-		// 查询角色是否拥有指定权限
-		hasPermission, err := checkRolePermission(roleID.(uint), permission)
+		codes, err := getUserPermissionCodes(c, userID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "权限检查失败"))
 			c.Abort()
 			return
 		}
 
-		if !hasPermission {
-			c.JSON(http.StatusForbidden, models.ErrorResponse(http.StatusForbidden, "权限不足"))
+		if !auth.HasPermission(codes, permission) {
+			c.JSON(http.StatusForbidden, models.CodeErrorResponse(models.CodePermissionDenied, ""))
 			c.Abort()
 			return
 		}
@@ -91,40 +107,15 @@ func RequirePermission(permission string) gin.HandlerFunc {
 	}
 }
 
-// RequireUserPermission 用户权限检查中间件（检查用户直接权限和角色权限）
+// RequireUserPermission 用户权限检查中间件，与 RequirePermission 相同，保留以兼容旧调用
 func RequireUserPermission(permission string) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// 获取用户ID
-		userID, exists := c.Get("user_id")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, models.ErrorResponse(http.StatusUnauthorized, "用户未登录"))
-			c.Abort()
-			return
-		}
-
-		// 超级管理员拥有所有权限
-		roleName, _ := c.Get("role_name")
-		if roleName == "超级管理员" || roleName == "admin" {
-			c.Next()
-			return
-		}
-
-		// 查询用户是否拥有指定权限（直接权限或通过角色）
-		hasPermission, err := checkUserPermission(userID.(uint), permission)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "权限检查失败"))
-			c.Abort()
-			return
-		}
-
-		if !hasPermission {
-			c.JSON(http.StatusForbidden, models.ErrorResponse(http.StatusForbidden, "权限不足"))
-			c.Abort()
-			return
-		}
+	return RequirePermission(permission)
+}
 
-		c.Next()
-	}
+// IsSuperAdmin 判断当前用户是否为超级管理员
+func IsSuperAdmin(c *gin.Context) bool {
+	roleName := c.GetString("role_name")
+	return roleName == "超级管理员" || roleName == "admin"
 }
 
 // RequireRole 角色检查中间件
@@ -133,7 +124,7 @@ func RequireRole(roles ...string) gin.HandlerFunc {
 		// 获取用户角色
 		roleName, exists := c.Get("role_name")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, models.ErrorResponse(http.StatusUnauthorized, "用户未登录"))
+			c.JSON(http.StatusUnauthorized, models.CodeErrorResponse(models.CodeNotLoggedIn, ""))
 			c.Abort()
 			return
 		}
@@ -147,7 +138,7 @@ func RequireRole(roles ...string) gin.HandlerFunc {
 			}
 		}
 
-		c.JSON(http.StatusForbidden, models.ErrorResponse(http.StatusForbidden, "权限不足"))
+		c.JSON(http.StatusForbidden, models.CodeErrorResponse(models.CodePermissionDenied, ""))
 		c.Abort()
 	}
 }
@@ -178,6 +169,21 @@ func OptionalAuth(cfg *config.Config, logger *zap.Logger) gin.HandlerFunc {
 			return
 		}
 
+		// 用户重置密码后，之前签发的令牌失效
+		revoked, err := services.IsSessionRevoked(claims.UserID, claims.IssuedAt.Time)
+		if err != nil {
+			logger.Error("Failed to check session revocation", zap.Uint("user_id", claims.UserID), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse(http.StatusInternalServerError, "认证状态校验失败"))
+			c.Abort()
+			return
+		}
+		if revoked {
+			logger.Warn("Revoked token rejected", zap.Uint("user_id", claims.UserID))
+			c.JSON(http.StatusUnauthorized, models.CodeErrorResponse(models.CodeSessionRevoked, ""))
+			c.Abort()
+			return
+		}
+
 		// 将用户信息存储到上下文
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
@@ -188,53 +194,19 @@ func OptionalAuth(cfg *config.Config, logger *zap.Logger) gin.HandlerFunc {
 	}
 }
 
-// checkRolePermission 检查角色是否拥有指定权限
-func checkRolePermission(roleID uint, permissionCode string) (bool, error) {
-	var count int64
-
-	// 查询角色权限关联表，检查是否存在指定权限
-	err := database.DB.Table("role_permissions rp").
-		Joins("JOIN permissions p ON rp.permission_id = p.id").
-		Where("rp.role_id = ? AND p.code = ? AND p.is_enabled = ?", roleID, permissionCode, true).
-		Count(&count).Error
-
-	if err != nil {
-		return false, err
-	}
-
-	return count > 0, nil
-}
-
-// checkUserPermission 检查用户是否拥有指定权限（直接权限或通过角色）
-func checkUserPermission(userID uint, permissionCode string) (bool, error) {
-	var count int64
-
-	// 先检查用户直接权限
-	err := database.DB.Table("user_permissions up").
-		Joins("JOIN permissions p ON up.permission_id = p.id").
-		Where("up.user_id = ? AND p.code = ? AND p.is_enabled = ?", userID, permissionCode, true).
-		Count(&count).Error
-
-	if err != nil {
-		return false, err
-	}
-
-	if count > 0 {
-		return true, nil
+// getUserPermissionCodes 获取用户权限code列表，同一请求内缓存在上下文中
+func getUserPermissionCodes(c *gin.Context, userID uint) ([]string, error) {
+	if cached, exists := c.Get("permission_codes"); exists {
+		if codes, ok := cached.([]string); ok {
+			return codes, nil
+		}
 	}
 
-	// 再检查角色权限
-	err = database.DB.Table("user_roles ur").
-		Joins("JOIN role_permissions rp ON ur.role_id = rp.role_id").
-		Joins("JOIN permissions p ON rp.permission_id = p.id").
-		Joins("JOIN roles r ON ur.role_id = r.id").
-		Where("ur.user_id = ? AND p.code = ? AND p.is_enabled = ? AND r.is_enabled = ?",
-			userID, permissionCode, true, true).
-		Count(&count).Error
-
+	codes, err := services.NewPermissionService().GetUserPermissionCodes(userID)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	return count > 0, nil
-}
\ No newline at end of file
+	c.Set("permission_codes", codes)
+	return codes, nil
+}
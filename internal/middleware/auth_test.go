@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequirePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		userID   uint
+		roleName string
+		codes    []string
+		expected int
+	}{
+		{name: "未登录", expected: http.StatusUnauthorized},
+		{name: "超级管理员", userID: 1, roleName: "admin", expected: http.StatusOK},
+		{name: "精确匹配", userID: 2, codes: []string{"hj212:control"}, expected: http.StatusOK},
+		{name: "通配符匹配", userID: 2, codes: []string{"hj212:*"}, expected: http.StatusOK},
+		{name: "无匹配权限", userID: 2, codes: []string{"datasource:preview", "hj212"}, expected: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				if tt.userID > 0 {
+					c.Set("user_id", tt.userID)
+					c.Set("role_name", tt.roleName)
+				}
+				// 预置本次请求的权限code，避免查询数据库
+				c.Set("permission_codes", tt.codes)
+			})
+			router.POST("/control", RequirePermission("hj212:control"), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/control", nil))
+			assert.Equal(t, tt.expected, w.Code)
+		})
+	}
+}
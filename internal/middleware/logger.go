@@ -3,23 +3,61 @@ package middleware
 import (
 	"bytes"
 	"io"
+	"mime"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
-// responseBodyWriter 响应体写入器
+// responseBodyWriter 响应体写入器，最多保留 maxLoggedBodySize+1 字节用于判断是否截断，
+// 文件下载等二进制响应不保留
 type responseBodyWriter struct {
 	gin.ResponseWriter
 	body *bytes.Buffer
 }
 
 func (r responseBodyWriter) Write(b []byte) (int, error) {
-	r.body.Write(b)
+	if remaining := maxLoggedBodySize + 1 - r.body.Len(); remaining > 0 && isTextualBody(r.Header()) {
+		if len(b) < remaining {
+			remaining = len(b)
+		}
+		r.body.Write(b[:remaining])
+	}
 	return r.ResponseWriter.Write(b)
 }
 
+// isTextualBody 判断响应是否为可记录的文本内容：附件及非文本类型（zip、图片等）不记录
+func isTextualBody(header http.Header) bool {
+	if strings.HasPrefix(strings.ToLower(header.Get("Content-Disposition")), "attachment") {
+		return false
+	}
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") ||
+		mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") ||
+		mediaType == "application/xml" || strings.HasSuffix(mediaType, "+xml") ||
+		mediaType == "application/x-www-form-urlencoded"
+}
+
+// readLoggedBody 读取最多 maxLoggedBodySize+1 字节的请求体用于记录，并将已读部分与剩余部分重新拼接为请求体
+func readLoggedBody(req *http.Request) []byte {
+	body, _ := io.ReadAll(io.LimitReader(req.Body, maxLoggedBodySize+1))
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), req.Body), req.Body}
+	return body
+}
+
 // Logger 日志中间件
 func Logger(logger *zap.Logger) gin.HandlerFunc {
 	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
@@ -61,8 +99,7 @@ func DetailedLogger(logger *zap.Logger) gin.HandlerFunc {
 		// 读取请求体
 		var requestBody []byte
 		if c.Request.Body != nil {
-			requestBody, _ = io.ReadAll(c.Request.Body)
-			c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
+			requestBody = readLoggedBody(c.Request)
 		}
 
 		// 创建响应体写入器
@@ -92,7 +129,7 @@ func DetailedLogger(logger *zap.Logger) gin.HandlerFunc {
 
 		// 添加请求体（排除敏感信息）
 		if len(requestBody) > 0 && !containsSensitiveData(c.Request.URL.Path) {
-			fields = append(fields, zap.String("request_body", string(requestBody)))
+			fields = append(fields, zap.String("request_body", truncateBody(string(requestBody))))
 		}
 
 		// 添加响应体（限制长度）
@@ -123,6 +160,9 @@ func containsSensitiveData(path string) bool {
 		"/api/auth/login",
 		"/api/auth/register",
 		"/api/users/password",
+		"/api/v1/auth/login",
+		"/api/v1/auth/refresh",
+		"/api/v1/auth/password",
 	}
 
 	for _, sensitivePath := range sensitivePaths {
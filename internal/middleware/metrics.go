@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"net/http"
 	"strconv"
 	"time"
 
@@ -30,6 +31,15 @@ var (
 		[]string{"method", "path", "status"},
 	)
 
+	// 服务端错误（5xx）请求数，与http_requests_total相除得到错误率
+	httpRequestErrorsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_request_errors_total",
+			Help: "Total number of HTTP requests that ended with a 5xx status",
+		},
+		[]string{"method", "path"},
+	)
+
 	// 当前正在处理的请求数
 	httpRequestsInFlight = promauto.NewGauge(
 		prometheus.GaugeOpts{
@@ -67,10 +77,19 @@ func Metrics() gin.HandlerFunc {
 		status := strconv.Itoa(c.Writer.Status())
 		responseSize := float64(c.Writer.Size())
 
+		// 未匹配路由统一记为unmatched，避免任意路径撑爆标签
+		path := c.FullPath()
+		if path == "" {
+			path = "unmatched"
+		}
+
 		// 记录指标
-		httpRequestsTotal.WithLabelValues(c.Request.Method, c.FullPath(), status).Inc()
-		httpRequestDuration.WithLabelValues(c.Request.Method, c.FullPath(), status).Observe(duration)
-		httpResponseSize.WithLabelValues(c.Request.Method, c.FullPath(), status).Observe(responseSize)
+		httpRequestsTotal.WithLabelValues(c.Request.Method, path, status).Inc()
+		httpRequestDuration.WithLabelValues(c.Request.Method, path, status).Observe(duration)
+		httpResponseSize.WithLabelValues(c.Request.Method, path, status).Observe(responseSize)
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			httpRequestErrorsTotal.WithLabelValues(c.Request.Method, path).Inc()
+		}
 	}
 }
 
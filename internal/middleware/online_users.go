@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// onlineUserWindow 用户在该时间窗口内有已认证的请求即视为在线
+const onlineUserWindow = 5 * time.Minute
+
+// onlineTracker 记录用户最近一次已认证请求的时间
+type onlineTracker struct {
+	window   time.Duration
+	mu       sync.Mutex
+	lastSeen map[uint]time.Time
+}
+
+func newOnlineTracker(window time.Duration) *onlineTracker {
+	return &onlineTracker{
+		window:   window,
+		lastSeen: make(map[uint]time.Time),
+	}
+}
+
+// touch 记录用户活动
+func (t *onlineTracker) touch(userID uint, now time.Time) {
+	t.mu.Lock()
+	t.lastSeen[userID] = now
+	t.mu.Unlock()
+}
+
+// count 统计窗口内活跃的用户数，同时清理过期记录
+func (t *onlineTracker) count(now time.Time) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for userID, seen := range t.lastSeen {
+		if now.Sub(seen) > t.window {
+			delete(t.lastSeen, userID)
+		}
+	}
+	return len(t.lastSeen)
+}
+
+// onlineUsers 全局在线用户统计，由认证中间件更新
+var onlineUsers = newOnlineTracker(onlineUserWindow)
+
+// 在线用户数，采集时按窗口统计
+var onlineUsersGauge = promauto.NewGaugeFunc(
+	prometheus.GaugeOpts{
+		Name: "online_users",
+		Help: "Number of users with an authenticated request in the last 5 minutes",
+	},
+	func() float64 { return float64(onlineUsers.count(time.Now())) },
+)
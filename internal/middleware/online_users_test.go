@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOnlineTracker(t *testing.T) {
+	tracker := newOnlineTracker(5 * time.Minute)
+	now := time.Now()
+
+	tracker.touch(1, now.Add(-10*time.Minute))
+	tracker.touch(2, now.Add(-time.Minute))
+	tracker.touch(3, now)
+	tracker.touch(3, now)
+	assert.Equal(t, 2, tracker.count(now))
+
+	// 过期用户被清理，再次活动后重新计入
+	assert.Len(t, tracker.lastSeen, 2)
+	tracker.touch(1, now)
+	assert.Equal(t, 3, tracker.count(now))
+	assert.Equal(t, 0, tracker.count(now.Add(6*time.Minute)))
+}
@@ -2,10 +2,13 @@ package middleware
 
 import (
 	"bytes"
-	"io"
+	"context"
+	"encoding/json"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 	"github.com/env-data-platform/internal/database"
@@ -13,8 +16,136 @@ import (
 	"go.uber.org/zap"
 )
 
+const (
+	// operationLogBufferSize 操作日志缓冲队列长度
+	operationLogBufferSize = 1024
+	// operationLogBatchSize 单次批量写入条数
+	operationLogBatchSize = 100
+	// operationLogFlushInterval 定时刷新间隔
+	operationLogFlushInterval = 2 * time.Second
+	// maxLoggedBodySize 请求体/响应体最大记录长度
+	maxLoggedBodySize = 5000
+	// maskedValue 脱敏占位符
+	maskedValue = "******"
+	// ExportRowsKey 列表导出handler写入上下文的导出行数
+	ExportRowsKey = "export_rows"
+)
+
+// sensitiveFields 需要脱敏的请求体字段（小写，包含匹配）
+var sensitiveFields = []string{
+	"password",
+	"passwd",
+	"secret",
+	"token",
+	"captcha",
+	"api_key",
+	"apikey",
+	"access_key",
+	"private_key",
+}
+
+// operationLogWriter 操作日志异步写入器
+type operationLogWriter struct {
+	logger  *zap.Logger
+	entries chan *models.OperationLog
+	done    chan struct{}
+}
+
+var (
+	opLogWriter     *operationLogWriter
+	opLogWriterOnce sync.Once
+	opLogStopOnce   sync.Once
+)
+
+// getOperationLogWriter 获取（并按需启动）全局操作日志写入器
+func getOperationLogWriter(logger *zap.Logger) *operationLogWriter {
+	opLogWriterOnce.Do(func() {
+		opLogWriter = &operationLogWriter{
+			logger:  logger,
+			entries: make(chan *models.OperationLog, operationLogBufferSize),
+			done:    make(chan struct{}),
+		}
+		go opLogWriter.run()
+	})
+	return opLogWriter
+}
+
+// enqueue 将日志放入缓冲队列，队列已满时丢弃，保证不阻塞请求
+func (w *operationLogWriter) enqueue(entry *models.OperationLog) {
+	select {
+	case w.entries <- entry:
+	default:
+		w.logger.Warn("Operation log buffer full, dropping entry",
+			zap.String("method", entry.Method),
+			zap.String("url", entry.URL))
+	}
+}
+
+// run 后台批量写入，达到批量大小或定时刷新
+func (w *operationLogWriter) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(operationLogFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*models.OperationLog, 0, operationLogBatchSize)
+	for {
+		select {
+		case entry, ok := <-w.entries:
+			if !ok {
+				w.flush(batch)
+				return
+			}
+			batch = append(batch, entry)
+			if len(batch) >= operationLogBatchSize {
+				w.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				w.flush(batch)
+				batch = batch[:0]
+			}
+		}
+	}
+}
+
+// flush 批量写入数据库
+func (w *operationLogWriter) flush(batch []*models.OperationLog) {
+	if len(batch) == 0 {
+		return
+	}
+
+	db := database.GetDB()
+	if db == nil {
+		return
+	}
+
+	if err := db.CreateInBatches(batch, operationLogBatchSize).Error; err != nil {
+		w.logger.Error("Failed to record operation logs", zap.Int("count", len(batch)), zap.Error(err))
+	}
+}
+
+// StopOperationLog 停止操作日志写入器并刷新缓冲区中剩余的日志
+func StopOperationLog(ctx context.Context) {
+	if opLogWriter == nil {
+		return
+	}
+
+	opLogStopOnce.Do(func() {
+		close(opLogWriter.entries)
+	})
+
+	select {
+	case <-opLogWriter.done:
+	case <-ctx.Done():
+	}
+}
+
 // OperationLog 操作日志中间件
 func OperationLog(logger *zap.Logger) gin.HandlerFunc {
+	writer := getOperationLogWriter(logger)
+
 	return func(c *gin.Context) {
 		// 跳过GET请求和健康检查等路径
 		if shouldSkipLogging(c.Request.Method, c.Request.URL.Path) {
@@ -25,36 +156,56 @@ func OperationLog(logger *zap.Logger) gin.HandlerFunc {
 		// 记录开始时间
 		start := time.Now()
 
-		// 读取请求体
+		// 读取请求体（文件上传等multipart请求不记录请求体）
 		var requestBody []byte
-		if c.Request.Body != nil {
-			requestBody, _ = io.ReadAll(c.Request.Body)
-			c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
+		if c.Request.Body != nil && !strings.HasPrefix(c.ContentType(), "multipart/") {
+			requestBody = readLoggedBody(c.Request)
+		}
+
+		// 导出请求流式输出文件，不缓存响应体，仅记录导出行数
+		if isExportRequest(c.Request.Method, c.Request.URL.Path) {
+			c.Next()
+			writer.enqueue(buildOperationLog(c, start, requestBody, exportSummary(c)))
+			return
 		}
 
 		// 创建响应体写入器
 		responseWriter := &responseBodyWriter{
 			ResponseWriter: c.Writer,
-			body:          bytes.NewBufferString(""),
+			body:           bytes.NewBufferString(""),
 		}
 		c.Writer = responseWriter
 
 		// 处理请求
 		c.Next()
 
-		// 异步记录操作日志
-		go func() {
-			if err := recordOperationLog(c, start, requestBody, responseWriter.body.String()); err != nil {
-				logger.Error("Failed to record operation log", zap.Error(err))
-			}
-		}()
+		// gin.Context 会被复用，必须在请求结束前同步构建日志，再异步写入
+		writer.enqueue(buildOperationLog(c, start, requestBody, responseWriter.body.String()))
 	}
 }
 
+// isExportRequest 判断是否为列表导出请求（GET .../export）
+func isExportRequest(method, path string) bool {
+	return method == http.MethodGet && strings.HasSuffix(strings.TrimSuffix(path, "/"), "/export")
+}
+
+// exportSummary 导出请求的响应摘要
+func exportSummary(c *gin.Context) string {
+	if c.Writer.Status() >= http.StatusBadRequest {
+		return ""
+	}
+	rows, _ := c.Get(ExportRowsKey)
+	summary, _ := json.Marshal(map[string]interface{}{
+		"export_rows": rows,
+		"format":      c.DefaultQuery("format", "csv"),
+	})
+	return string(summary)
+}
+
 // shouldSkipLogging 判断是否跳过日志记录
 func shouldSkipLogging(method, path string) bool {
-	// 跳过GET请求
-	if method == http.MethodGet {
+	// 跳过GET请求，列表导出除外
+	if method == http.MethodGet && !isExportRequest(method, path) {
 		return true
 	}
 
@@ -75,56 +226,51 @@ func shouldSkipLogging(method, path string) bool {
 	return false
 }
 
-// recordOperationLog 记录操作日志
-func recordOperationLog(c *gin.Context, startTime time.Time, requestBody []byte, responseBody string) error {
-	db := database.GetDB()
-	if db == nil {
-		return nil
+// buildOperationLog 构建操作日志记录
+func buildOperationLog(c *gin.Context, startTime time.Time, requestBody []byte, responseBody string) *models.OperationLog {
+	// 获取用户信息（由认证中间件写入上下文，未登录请求为空）
+	var userID *uint
+	if id := c.GetUint("user_id"); id > 0 {
+		userID = &id
 	}
+	username := c.GetString("username")
 
-	// 获取用户信息
-	var userID uint
-	var username string
-	if user, exists := c.Get("user"); exists {
-		if u, ok := user.(*models.User); ok {
-			userID = u.ID
-			username = u.Username
-		}
-	}
+	// 确定操作模块
+	module, _ := parseModuleAndAction(c.Request.URL.Path, c.Request.Method)
 
-	// 确定操作模块和动作
-	module, action := parseModuleAndAction(c.Request.URL.Path, c.Request.Method)
+	// 操作动作：方法 + 路由模板，未匹配路由时使用原始路径
+	route := c.FullPath()
+	if route == "" {
+		route = c.Request.URL.Path
+	}
+	action := c.Request.Method + " " + route
 
 	// 计算执行时长
 	duration := time.Since(startTime).Milliseconds()
 
 	// 处理敏感信息
-	requestBodyStr := string(requestBody)
-	if containsSensitiveData(c.Request.URL.Path) {
-		requestBodyStr = "[SENSITIVE DATA HIDDEN]"
-	}
+	requestBodyStr := maskSensitiveBody(c.Request.URL.Path, requestBody)
 
-	// 限制响应体长度
-	if len(responseBody) > 5000 {
-		responseBody = responseBody[:5000] + "...[TRUNCATED]"
-	}
+	// 响应体同样脱敏（如登录返回的token）并限制长度
+	responseBody = maskSensitiveBody(c.Request.URL.Path, []byte(responseBody))
 
 	// 确定操作状态
+	statusCode := c.Writer.Status()
 	status := 1 // 成功
 	var errorMsg string
-	if c.Writer.Status() >= 400 {
+	if statusCode >= 400 {
 		status = 0 // 失败
 		if len(c.Errors) > 0 {
 			errorMsg = c.Errors.String()
 		}
 	}
 
-	// 创建操作日志记录
-	operationLog := models.OperationLog{
+	return &models.OperationLog{
 		UserID:      userID,
 		Username:    username,
 		Module:      module,
 		Action:      action,
+		Resource:    parseResource(c.Request.URL.Path),
 		Method:      c.Request.Method,
 		URL:         c.Request.URL.String(),
 		IP:          c.ClientIP(),
@@ -132,12 +278,87 @@ func recordOperationLog(c *gin.Context, startTime time.Time, requestBody []byte,
 		RequestBody: requestBodyStr,
 		Response:    responseBody,
 		Status:      status,
+		StatusCode:  statusCode,
 		ErrorMsg:    errorMsg,
 		Duration:    duration,
 	}
+}
+
+// parseResource 解析操作资源（去除API前缀后的路径，如 users/12/roles）
+func parseResource(path string) string {
+	path = strings.TrimPrefix(path, "/api/v1/")
+	path = strings.TrimPrefix(path, "/api/")
+	path = strings.Trim(path, "/")
+	if len(path) > 200 {
+		path = path[:200]
+	}
+	return path
+}
+
+// maskSensitiveBody 请求体脱敏：JSON请求体按字段脱敏，无法解析的敏感路径请求体整体隐藏
+func maskSensitiveBody(path string, body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
 
-	// 保存到数据库
-	return db.Create(&operationLog).Error
+	var payload interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		if containsSensitiveData(path) {
+			return "[SENSITIVE DATA HIDDEN]"
+		}
+		return truncateBody(string(body))
+	}
+
+	masked, err := json.Marshal(maskSensitiveValue(payload))
+	if err != nil {
+		return "[SENSITIVE DATA HIDDEN]"
+	}
+	return truncateBody(string(masked))
+}
+
+// maskSensitiveValue 递归替换敏感字段的值
+func maskSensitiveValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			if isSensitiveField(k) {
+				val[k] = maskedValue
+				continue
+			}
+			val[k] = maskSensitiveValue(item)
+		}
+		return val
+	case []interface{}:
+		for i, item := range val {
+			val[i] = maskSensitiveValue(item)
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+// isSensitiveField 判断字段名是否敏感
+func isSensitiveField(name string) bool {
+	name = strings.ToLower(name)
+	for _, field := range sensitiveFields {
+		if strings.Contains(name, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// truncateBody 限制记录内容长度，截断位置回退到字符边界，避免写入半个多字节字符
+func truncateBody(body string) string {
+	if len(body) > maxLoggedBodySize {
+		end := maxLoggedBodySize
+		for end > 0 && !utf8.RuneStart(body[end]) {
+			end--
+		}
+		return body[:end] + "...[TRUNCATED]"
+	}
+	return body
 }
 
 // parseModuleAndAction 解析模块和操作
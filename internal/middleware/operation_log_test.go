@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaskSensitiveBody(t *testing.T) {
+	t.Run("JSON字段脱敏", func(t *testing.T) {
+		body := []byte(`{"username":"admin","password":"123456","profile":{"access_token":"abc"},"items":[{"secret":"x","name":"n"}]}`)
+		masked := maskSensitiveBody("/api/v1/users", body)
+
+		var result map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(masked), &result))
+		assert.Equal(t, "admin", result["username"], "普通字段不应被脱敏")
+		assert.Equal(t, maskedValue, result["password"], "密码应被脱敏")
+		assert.Equal(t, maskedValue, result["profile"].(map[string]interface{})["access_token"], "嵌套token应被脱敏")
+		item := result["items"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, maskedValue, item["secret"], "数组内敏感字段应被脱敏")
+		assert.Equal(t, "n", item["name"])
+	})
+
+	t.Run("敏感路径非JSON请求体整体隐藏", func(t *testing.T) {
+		masked := maskSensitiveBody("/api/v1/auth/login", []byte("username=admin&password=123"))
+		assert.Equal(t, "[SENSITIVE DATA HIDDEN]", masked)
+	})
+
+	t.Run("空请求体", func(t *testing.T) {
+		assert.Equal(t, "", maskSensitiveBody("/api/v1/users", nil))
+	})
+}
+
+func TestParseResource(t *testing.T) {
+	assert.Equal(t, "users/12/roles", parseResource("/api/v1/users/12/roles"))
+	assert.Equal(t, "etl/jobs", parseResource("/api/v1/etl/jobs/"))
+}
+
+func TestShouldSkipLogging(t *testing.T) {
+	assert.True(t, shouldSkipLogging("GET", "/api/v1/users"))
+	assert.False(t, shouldSkipLogging("GET", "/api/v1/users/export"), "列表导出应记录操作日志")
+	assert.False(t, shouldSkipLogging("GET", "/api/v1/quality/reports/export/"))
+	assert.False(t, shouldSkipLogging("POST", "/api/v1/users"))
+	assert.True(t, shouldSkipLogging("POST", "/health"))
+
+	assert.True(t, isExportRequest("GET", "/api/v1/etl/executions/export"))
+	assert.False(t, isExportRequest("POST", "/api/v1/etl/executions/export"))
+	assert.False(t, isExportRequest("GET", "/api/v1/files/exporter"))
+}
+
+func TestTruncateBody(t *testing.T) {
+	assert.Equal(t, "短内容", truncateBody("短内容"))
+
+	// 截断位置落在多字节字符中间时回退到字符边界
+	body := "a" + strings.Repeat("中", maxLoggedBodySize)
+	truncated := truncateBody(body)
+	assert.True(t, utf8.ValidString(truncated))
+	assert.True(t, strings.HasSuffix(truncated, "...[TRUNCATED]"))
+	assert.Equal(t, body[:maxLoggedBodySize-1], strings.TrimSuffix(truncated, "...[TRUNCATED]"))
+}
+
+func newTestBodyWriter() (*responseBodyWriter, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	return &responseBodyWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}}, w
+}
+
+func TestResponseBodyWriterLimit(t *testing.T) {
+	writer, w := newTestBodyWriter()
+	writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+	chunk := []byte(strings.Repeat("a", 3000))
+	for i := 0; i < 3; i++ {
+		_, err := writer.Write(chunk)
+		require.NoError(t, err)
+	}
+
+	// 客户端收到完整响应，记录的内容只多保留1字节用于判断截断
+	assert.Equal(t, 9000, w.Body.Len())
+	assert.Equal(t, maxLoggedBodySize+1, writer.body.Len())
+	assert.True(t, strings.HasSuffix(truncateBody(writer.body.String()), "...[TRUNCATED]"))
+}
+
+func TestResponseBodyWriterSkipsBinary(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		disposition string
+		captured    bool
+	}{
+		{name: "zip下载", contentType: "application/zip", disposition: `attachment; filename="files.zip"`},
+		{name: "附件", contentType: "text/csv", disposition: "attachment"},
+		{name: "图片", contentType: "image/png"},
+		{name: "JSON", contentType: "application/json", captured: true},
+		{name: "未设置类型", captured: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer, w := newTestBodyWriter()
+			if tt.contentType != "" {
+				writer.Header().Set("Content-Type", tt.contentType)
+			}
+			if tt.disposition != "" {
+				writer.Header().Set("Content-Disposition", tt.disposition)
+			}
+			_, err := writer.Write([]byte("PK\x03\x04"))
+			require.NoError(t, err)
+
+			assert.Equal(t, "PK\x03\x04", w.Body.String())
+			assert.Equal(t, tt.captured, writer.body.Len() > 0)
+		})
+	}
+}
+
+func TestReadLoggedBody(t *testing.T) {
+	payload := strings.Repeat("x", maxLoggedBodySize*3)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/data", strings.NewReader(payload))
+
+	// 只读取记录所需的长度，handler仍能读到完整请求体
+	logged := readLoggedBody(req)
+	assert.Len(t, logged, maxLoggedBodySize+1)
+	body, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	assert.Equal(t, payload, string(body))
+	assert.NoError(t, req.Body.Close())
+}
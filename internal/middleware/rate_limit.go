@@ -3,8 +3,10 @@ package middleware
 import (
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/env-data-platform/internal/config"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/time/rate"
 )
@@ -48,6 +50,19 @@ func (rl *RateLimiter) getLimiter(ip string) *rate.Limiter {
 	return limiter
 }
 
+// SetLimit 调整限流速率，已创建的各IP限流器同步生效
+func (rl *RateLimiter) SetLimit(rateLimit rate.Limit, burst int) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	rl.rate = rateLimit
+	rl.burst = burst
+	for _, limiter := range rl.limiters {
+		limiter.SetLimit(rateLimit)
+		limiter.SetBurst(burst)
+	}
+}
+
 // Allow 检查是否允许请求
 func (rl *RateLimiter) Allow(ip string) bool {
 	limiter := rl.getLimiter(ip)
@@ -57,9 +72,29 @@ func (rl *RateLimiter) Allow(ip string) bool {
 // 全局限流器实例
 var globalRateLimiter = NewRateLimiter(100, 200) // 每秒100个请求，突发200个
 
+// globalRateLimitEnabled 全局限流开关
+var globalRateLimitEnabled atomic.Bool
+
+func init() {
+	globalRateLimitEnabled.Store(true)
+}
+
+// SetGlobalRateLimit 按配置调整全局限流，支持运行时热加载
+func SetGlobalRateLimit(cfg config.RateLimitConfig) {
+	globalRateLimitEnabled.Store(cfg.Enabled)
+	if cfg.RequestsPerMinute > 0 {
+		globalRateLimiter.SetLimit(rate.Limit(float64(cfg.RequestsPerMinute)/60), cfg.Burst)
+	}
+}
+
 // RateLimit 限流中间件
 func RateLimit() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if !globalRateLimitEnabled.Load() {
+			c.Next()
+			return
+		}
+
 		ip := c.ClientIP()
 
 		if !globalRateLimiter.Allow(ip) {
@@ -0,0 +1,171 @@
+package models
+
+import "time"
+
+// 告警级别常量
+const (
+	AlarmLevelInfo     = "info"     // 信息
+	AlarmLevelWarning  = "warning"  // 警告
+	AlarmLevelCritical = "critical" // 严重
+	AlarmLevelFatal    = "fatal"    // 致命
+)
+
+// 告警类型常量
+const (
+	AlarmTypeOverLimit     = "over_limit"     // 超上限
+	AlarmTypeUnderLimit    = "under_limit"    // 低于下限
+	AlarmTypeDeviceOffline = "device_offline" // 设备离线
+	AlarmTypeDeviceOnline  = "device_online"  // 设备恢复上线
+	AlarmTypeQualityCheck  = "quality_check"  // 数据质量检查失败或低分
+
+	AlarmTypeDataSourceUnreachable = "datasource_unreachable" // 数据源巡检连续失败
+	AlarmTypeDataSourceRecovered   = "datasource_recovered"   // 数据源巡检恢复正常
+
+	AlarmTypeDataAnomaly = "data_anomaly" // 收包实时质量校验发现异常数据
+)
+
+// AlarmRule 告警阈值规则模型，按系统编码ST+因子编码配置上下限
+type AlarmRule struct {
+	BaseModelWithOperator
+	Name        string   `gorm:"not null;size:100;comment:规则名称" json:"name"`
+	Description string   `gorm:"size:255;comment:规则描述" json:"description"`
+	SystemCode  string   `gorm:"size:10;index;comment:系统编码ST，空表示所有系统" json:"system_code"`
+	FactorCode  string   `gorm:"not null;size:20;index;comment:监测因子编码" json:"factor_code"`
+	DeviceID    string   `gorm:"size:50;comment:设备MN，空表示所有设备" json:"device_id"`
+	UpperLimit  *float64 `gorm:"comment:上限，为空表示不检查" json:"upper_limit"`
+	LowerLimit  *float64 `gorm:"comment:下限，为空表示不检查" json:"lower_limit"`
+	Level       string   `gorm:"not null;size:20;default:warning;comment:告警级别" json:"level"`
+	Enabled     bool     `gorm:"not null;comment:是否启用" json:"enabled"`
+	CooldownMin int      `gorm:"comment:同设备重复告警冷却时间(分钟)，0表示不冷却" json:"cooldown_min"`
+}
+
+// TableName 指定表名
+func (AlarmRule) TableName() string {
+	return GetTableName("alarm_rules")
+}
+
+// Matches 判断规则是否适用于指定系统编码、因子和设备
+func (r *AlarmRule) Matches(systemCode, factorCode, deviceID string) bool {
+	if r.FactorCode != factorCode {
+		return false
+	}
+	if r.SystemCode != "" && r.SystemCode != systemCode {
+		return false
+	}
+	if r.DeviceID != "" && r.DeviceID != deviceID {
+		return false
+	}
+	return true
+}
+
+// Evaluate 判断监测值是否越限，返回越限类型和被突破的限值
+func (r *AlarmRule) Evaluate(value float64) (alarmType string, limit float64, violated bool) {
+	if r.UpperLimit != nil && value > *r.UpperLimit {
+		return AlarmTypeOverLimit, *r.UpperLimit, true
+	}
+	if r.LowerLimit != nil && value < *r.LowerLimit {
+		return AlarmTypeUnderLimit, *r.LowerLimit, true
+	}
+	return "", 0, false
+}
+
+// AlarmRuleRequest 告警规则创建/更新请求
+type AlarmRuleRequest struct {
+	Name        string   `json:"name" binding:"required,max=100"`
+	Description string   `json:"description" binding:"max=255"`
+	SystemCode  string   `json:"system_code" binding:"max=10"`
+	FactorCode  string   `json:"factor_code" binding:"required,max=20"`
+	DeviceID    string   `json:"device_id" binding:"max=50"`
+	UpperLimit  *float64 `json:"upper_limit"`
+	LowerLimit  *float64 `json:"lower_limit"`
+	Level       string   `json:"level" binding:"required,oneof=info warning critical fatal"`
+	Enabled     *bool    `json:"enabled"`
+	CooldownMin int      `json:"cooldown_min" binding:"min=0"`
+}
+
+// 告警通知发送状态常量
+const (
+	NotificationStatusSuccess = "success" // 发送成功
+	NotificationStatusFailed  = "failed"  // 重试后仍失败
+)
+
+// AlarmNotification 告警通知发送记录，用于审计通知是否送达
+type AlarmNotification struct {
+	BaseModel
+	AlarmID   uint       `gorm:"index;comment:告警记录ID" json:"alarm_id"`
+	RuleID    uint       `gorm:"index;comment:告警规则ID" json:"rule_id"`
+	DeviceID  string     `gorm:"size:50;index;comment:设备ID" json:"device_id"`
+	Level     string     `gorm:"size:20;comment:告警级别" json:"level"`
+	Channel   string     `gorm:"not null;size:20;index;comment:通知通道" json:"channel"`
+	Status    string     `gorm:"not null;size:20;index;comment:发送状态" json:"status"`
+	Attempts  int        `gorm:"comment:发送次数" json:"attempts"`
+	Content   string     `gorm:"type:text;comment:通知内容" json:"content"`
+	LastError string     `gorm:"type:text;comment:最后一次失败原因" json:"last_error"`
+	SentAt    *time.Time `gorm:"comment:发送成功时间" json:"sent_at"`
+}
+
+// TableName 指定表名
+func (AlarmNotification) TableName() string {
+	return GetTableName("alarm_notifications")
+}
+
+// 告警处理状态常量，设备上报告警的初始状态为active，与pending同为待处理
+const (
+	AlarmStatusPending      = "pending"      // 待处理
+	AlarmStatusActive       = "active"       // 待处理（设备上报）
+	AlarmStatusAcknowledged = "acknowledged" // 已确认
+	AlarmStatusAssigned     = "assigned"     // 已指派
+	AlarmStatusClosed       = "closed"       // 已关闭
+)
+
+// 告警处理动作常量
+const (
+	AlarmActionAcknowledge = "acknowledge" // 确认
+	AlarmActionAssign      = "assign"      // 指派
+	AlarmActionClose       = "close"       // 关闭
+)
+
+// alarmTransitions 各处理动作允许的起始状态
+var alarmTransitions = map[string][]string{
+	AlarmActionAcknowledge: {AlarmStatusPending, AlarmStatusActive},
+	AlarmActionAssign:      {AlarmStatusPending, AlarmStatusActive, AlarmStatusAcknowledged, AlarmStatusAssigned},
+	AlarmActionClose:       {AlarmStatusPending, AlarmStatusActive, AlarmStatusAcknowledged, AlarmStatusAssigned},
+}
+
+// CanTransitAlarm 判断处于status状态的告警能否执行action
+func CanTransitAlarm(status, action string) bool {
+	for _, from := range alarmTransitions[action] {
+		if from == status {
+			return true
+		}
+	}
+	return false
+}
+
+// AlarmAction 告警处理记录
+type AlarmAction struct {
+	BaseModel
+	AlarmID    uint   `gorm:"not null;index;comment:告警记录ID" json:"alarm_id"`
+	Action     string `gorm:"not null;size:20;comment:处理动作" json:"action"`
+	FromStatus string `gorm:"size:20;comment:原状态" json:"from_status"`
+	ToStatus   string `gorm:"size:20;comment:新状态" json:"to_status"`
+	OperatorID uint   `gorm:"comment:处理人ID" json:"operator_id"`
+	AssigneeID *uint  `gorm:"comment:指派处理人ID" json:"assignee_id"`
+	Remark     string `gorm:"type:text;comment:处理备注" json:"remark"`
+}
+
+// TableName 指定表名
+func (AlarmAction) TableName() string {
+	return GetTableName("alarm_actions")
+}
+
+// AlarmHandleRequest 告警确认/关闭请求
+type AlarmHandleRequest struct {
+	Remark string `json:"remark" binding:"max=1000"`
+}
+
+// AlarmAssignRequest 告警指派请求
+type AlarmAssignRequest struct {
+	AssigneeID uint   `json:"assignee_id" binding:"required"`
+	Remark     string `json:"remark" binding:"max=1000"`
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlarmRuleMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     AlarmRule
+		system   string
+		factor   string
+		device   string
+		expected bool
+	}{
+		{name: "因子匹配，系统和设备不限", rule: AlarmRule{FactorCode: "a34004"}, system: "22", factor: "a34004", device: "MN1", expected: true},
+		{name: "因子不同", rule: AlarmRule{FactorCode: "a34004"}, system: "22", factor: "a34002", device: "MN1"},
+		{name: "系统编码匹配", rule: AlarmRule{SystemCode: "32", FactorCode: "w01018"}, system: "32", factor: "w01018", device: "MN1", expected: true},
+		{name: "系统编码不同", rule: AlarmRule{SystemCode: "32", FactorCode: "w01018"}, system: "22", factor: "w01018", device: "MN1"},
+		{name: "指定设备匹配", rule: AlarmRule{FactorCode: "w01018", DeviceID: "MN1"}, system: "32", factor: "w01018", device: "MN1", expected: true},
+		{name: "指定设备不同", rule: AlarmRule{FactorCode: "w01018", DeviceID: "MN1"}, system: "32", factor: "w01018", device: "MN2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.rule.Matches(tt.system, tt.factor, tt.device))
+		})
+	}
+}
+
+func TestAlarmRuleEvaluate(t *testing.T) {
+	upper, lower := 150.0, 10.0
+	tests := []struct {
+		name      string
+		rule      AlarmRule
+		value     float64
+		alarmType string
+		limit     float64
+		violated  bool
+	}{
+		{name: "超上限", rule: AlarmRule{UpperLimit: &upper, LowerLimit: &lower}, value: 150.1, alarmType: AlarmTypeOverLimit, limit: upper, violated: true},
+		{name: "等于上限不告警", rule: AlarmRule{UpperLimit: &upper, LowerLimit: &lower}, value: 150},
+		{name: "低于下限", rule: AlarmRule{UpperLimit: &upper, LowerLimit: &lower}, value: 9.9, alarmType: AlarmTypeUnderLimit, limit: lower, violated: true},
+		{name: "等于下限不告警", rule: AlarmRule{UpperLimit: &upper, LowerLimit: &lower}, value: 10},
+		{name: "只配置上限", rule: AlarmRule{UpperLimit: &upper}, value: -1000},
+		{name: "只配置下限", rule: AlarmRule{LowerLimit: &lower}, value: 1000},
+		{name: "未配置限值", rule: AlarmRule{}, value: 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alarmType, limit, violated := tt.rule.Evaluate(tt.value)
+			assert.Equal(t, tt.violated, violated)
+			assert.Equal(t, tt.alarmType, alarmType)
+			assert.Equal(t, tt.limit, limit)
+		})
+	}
+}
+
+func TestCanTransitAlarm(t *testing.T) {
+	tests := []struct {
+		status   string
+		action   string
+		expected bool
+	}{
+		{AlarmStatusPending, AlarmActionAcknowledge, true},
+		{AlarmStatusActive, AlarmActionAcknowledge, true},
+		{AlarmStatusAcknowledged, AlarmActionAcknowledge, false},
+		{AlarmStatusAssigned, AlarmActionAcknowledge, false},
+		{AlarmStatusActive, AlarmActionAssign, true},
+		{AlarmStatusAcknowledged, AlarmActionAssign, true},
+		// 已指派的告警可以改派
+		{AlarmStatusAssigned, AlarmActionAssign, true},
+		{AlarmStatusAssigned, AlarmActionClose, true},
+		{AlarmStatusPending, AlarmActionClose, true},
+		// 已关闭的告警不能再处理
+		{AlarmStatusClosed, AlarmActionAcknowledge, false},
+		{AlarmStatusClosed, AlarmActionAssign, false},
+		{AlarmStatusClosed, AlarmActionClose, false},
+		{AlarmStatusPending, "reopen", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, CanTransitAlarm(tt.status, tt.action), tt.status+" -> "+tt.action)
+	}
+}
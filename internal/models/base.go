@@ -47,6 +47,24 @@ const (
 	DataSourceTypeFile     = "file"     // 文件
 	DataSourceTypeAPI      = "api"      // API接口
 	DataSourceTypeWebhook  = "webhook"  // Webhook
+	DataSourceTypeKafka    = "kafka"    // Kafka消息队列
+
+	DataSourceTypeFileExport = "file_export" // 文件导出，仅作为ETL目标
+)
+
+// 数据源状态常量
+const (
+	DataSourceStatusActive   = "active"   // 连接正常
+	DataSourceStatusInactive = "inactive" // 连接失败
+	DataSourceStatusDisabled = "disabled" // 已停用，不参与健康巡检
+)
+
+// 数据源健康巡检状态常量
+const (
+	DataSourceHealthUnknown   = "unknown"   // 未巡检
+	DataSourceHealthHealthy   = "healthy"   // 最近一次巡检连接正常
+	DataSourceHealthDegraded  = "degraded"  // 连续失败但未达到告警阈值
+	DataSourceHealthUnhealthy = "unhealthy" // 连续失败达到告警阈值
 )
 
 // ETL任务状态常量
@@ -83,15 +101,45 @@ type Response struct {
 	Data    interface{} `json:"data,omitempty"`
 }
 
-// 分页请求结构
+// 分页参数默认值
+const (
+	DefaultPage     = 1
+	DefaultPageSize = 10
+	MaxPageSize     = 100
+)
+
+// 分页请求结构，page/page_size非必填，未传时由Normalize补默认值
 type PageRequest struct {
-	Page     int    `json:"page" form:"page" binding:"min=1"`
-	PageSize int    `json:"page_size" form:"page_size" binding:"min=1,max=100"`
+	Page     int    `json:"page" form:"page" binding:"omitempty,min=1"`
+	PageSize int    `json:"page_size" form:"page_size" binding:"omitempty,min=1,max=100"`
 	Sort     string `json:"sort" form:"sort"`
 	Order    string `json:"order" form:"order"`
 	Keyword  string `json:"keyword" form:"keyword"`
 }
 
+// Pagination 返回分页参数本身，嵌入PageRequest的查询结构体借此暴露分页参数
+func (p *PageRequest) Pagination() *PageRequest {
+	return p
+}
+
+// Normalize 补全分页默认值，并把超出上限的每页数量截断为MaxPageSize
+func (p *PageRequest) Normalize() {
+	if p.Page <= 0 {
+		p.Page = DefaultPage
+	}
+	if p.PageSize <= 0 {
+		p.PageSize = DefaultPageSize
+	}
+	if p.PageSize > MaxPageSize {
+		p.PageSize = MaxPageSize
+	}
+}
+
+// Offset 当前页第一条记录的偏移量
+func (p *PageRequest) Offset() int {
+	return (p.Page - 1) * p.PageSize
+}
+
 // PaginationQuery 分页查询参数（别名）
 type PaginationQuery = PageRequest
 
@@ -105,6 +153,8 @@ type PageResponse struct {
 	Page      int         `json:"page"`
 	PageSize  int         `json:"page_size"`
 	TotalPage int         `json:"total_page"`
+	// NextCursor 游标分页时下一页的游标，为空表示没有下一页
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 // NewPageResponse 创建分页响应
@@ -123,6 +173,15 @@ func NewPageResponse(list interface{}, total int64, page, pageSize int) *PageRes
 	}
 }
 
+// NewCursorPageResponse 创建游标分页响应，游标分页不统计总数，Total和TotalPage为0
+func NewCursorPageResponse(list interface{}, nextCursor string, pageSize int) *PageResponse {
+	return &PageResponse{
+		List:       list,
+		PageSize:   pageSize,
+		NextCursor: nextCursor,
+	}
+}
+
 // 成功响应
 func SuccessResponse(data interface{}) *Response {
 	return &Response{
@@ -12,7 +12,8 @@ type DataSource struct {
 	Name         string          `gorm:"not null;size:100;comment:数据源名称" json:"name"`
 	Type         string          `gorm:"not null;size:20;comment:数据源类型" json:"type"`
 	Description  string          `gorm:"size:500;comment:描述" json:"description"`
-	DeviceID     string          `gorm:"size:50;comment:设备ID" json:"device_id"`
+	DeviceID     string          `gorm:"size:50;index;comment:设备ID" json:"device_id"`
+	Region       string          `gorm:"size:100;index;comment:所属区域" json:"region"`
 	Config       string          `gorm:"type:text;comment:配置信息JSON" json:"-"`
 	ConfigData   json.RawMessage `gorm:"-" json:"config"`
 	Status       string          `gorm:"size:20;default:active;comment:状态" json:"status"`
@@ -24,6 +25,8 @@ type DataSource struct {
 	Priority     int             `gorm:"default:0;comment:优先级" json:"priority"`
 	ErrorCount   int             `gorm:"default:0;comment:错误次数" json:"error_count"`
 	LastError    string          `gorm:"type:text;comment:最后错误信息" json:"last_error"`
+	HealthStatus string          `gorm:"size:20;default:unknown;comment:巡检健康状态" json:"health_status"`
+	ImportTable  string          `gorm:"size:100;comment:文件导入的数据表，非空时连接平台数据库且只能访问该表" json:"import_table,omitempty"`
 
 	// 关联
 	Creator      *User           `gorm:"foreignKey:CreatedBy" json:"creator,omitempty"`
@@ -38,10 +41,16 @@ func (DataSource) TableName() string {
 	return GetTableName("data_sources")
 }
 
+// IsImported 是否为文件导入的数据源。导入数据存放在平台数据库中，连接参数不保存在配置里，
+// 预览、ETL和质量检查只能访问 ImportTable
+func (ds *DataSource) IsImported() bool {
+	return ds.ImportTable != ""
+}
+
 // DataTable 数据表模型
 type DataTable struct {
 	BaseModel
-	DataSourceID uint   `gorm:"not null;comment:数据源ID" json:"data_source_id"`
+	DataSourceID uint   `gorm:"not null;index;comment:数据源ID" json:"data_source_id"`
 	Name         string `gorm:"not null;size:100;comment:表名" json:"table_name"`
 	TableComment string `gorm:"size:500;comment:表注释" json:"table_comment"`
 	Schema       string `gorm:"size:100;comment:数据库schema" json:"schema"`
@@ -49,10 +58,12 @@ type DataTable struct {
 	SizeBytes    int64  `gorm:"default:0;comment:大小(字节)" json:"size_bytes"`
 	LastSyncAt   *time.Time `gorm:"comment:最后同步时间" json:"last_sync_at"`
 	IsActive     bool   `gorm:"default:true;comment:是否活跃" json:"is_active"`
+	Version      int    `gorm:"comment:表结构最后变更的元数据版本" json:"version"`
 
 	// 关联
 	DataSource *DataSource   `gorm:"foreignKey:DataSourceID" json:"data_source,omitempty"`
 	Columns    []DataColumn  `gorm:"foreignKey:TableID" json:"columns,omitempty"`
+	Indexes    []DataIndex   `gorm:"foreignKey:TableID" json:"indexes,omitempty"`
 }
 
 // TableName 指定表名
@@ -63,7 +74,7 @@ func (DataTable) TableName() string {
 // DataColumn 数据列模型
 type DataColumn struct {
 	BaseModel
-	TableID      uint   `gorm:"not null;comment:表ID" json:"table_id"`
+	TableID      uint   `gorm:"not null;index;comment:表ID" json:"table_id"`
 	ColumnName   string `gorm:"not null;size:100;comment:列名" json:"column_name"`
 	DataType     string `gorm:"not null;size:50;comment:数据类型" json:"data_type"`
 	Length       int    `gorm:"comment:长度" json:"length"`
@@ -71,6 +82,7 @@ type DataColumn struct {
 	Scale        int    `gorm:"comment:小数位数" json:"scale"`
 	IsNullable   bool   `gorm:"comment:是否可空" json:"is_nullable"`
 	IsPrimaryKey bool   `gorm:"comment:是否主键" json:"is_primary_key"`
+	IsAutoIncr   bool   `gorm:"comment:是否自增" json:"is_auto_increment"`
 	DefaultValue string `gorm:"size:255;comment:默认值" json:"default_value"`
 	Comment      string `gorm:"size:500;comment:列注释" json:"comment"`
 	Position     int    `gorm:"comment:列位置" json:"position"`
@@ -84,12 +96,49 @@ func (DataColumn) TableName() string {
 	return GetTableName("data_columns")
 }
 
+// DataIndex 数据表索引模型
+type DataIndex struct {
+	BaseModel
+	TableID   uint   `gorm:"not null;index;comment:表ID" json:"table_id"`
+	IndexName string `gorm:"not null;size:100;comment:索引名" json:"index_name"`
+	IndexType string `gorm:"size:50;comment:索引类型" json:"index_type"`
+	Columns   string `gorm:"size:500;comment:索引列，逗号分隔" json:"columns"`
+	IsUnique  bool   `gorm:"comment:是否唯一索引" json:"is_unique"`
+}
+
+// TableName 指定表名
+func (DataIndex) TableName() string {
+	return GetTableName("data_indexes")
+}
+
+// MetadataSnapshot 数据源元数据快照，每次同步结果与上一版本不同时生成新版本
+type MetadataSnapshot struct {
+	BaseModel
+	DataSourceID   uint      `gorm:"not null;uniqueIndex:idx_metadata_snapshot_version;comment:数据源ID" json:"data_source_id"`
+	Version        int       `gorm:"not null;uniqueIndex:idx_metadata_snapshot_version;comment:版本号" json:"version"`
+	TableCount     int       `gorm:"comment:表数量" json:"table_count"`
+	Checksum       string    `gorm:"size:64;comment:元数据内容SHA256" json:"checksum"`
+	Content        string    `gorm:"type:longtext;comment:表元数据JSON" json:"-"`
+	TablesAdded    int       `gorm:"comment:相比上一版本新增表数" json:"tables_added"`
+	TablesRemoved  int       `gorm:"comment:相比上一版本删除表数" json:"tables_removed"`
+	TablesModified int       `gorm:"comment:相比上一版本变更表数" json:"tables_modified"`
+	LastSyncedAt   time.Time `gorm:"comment:最后一次同步得到该版本的时间" json:"last_synced_at"`
+}
+
+// TableName 指定表名
+func (MetadataSnapshot) TableName() string {
+	return GetTableName("metadata_snapshots")
+}
+
 // HJ212Data HJ212协议数据模型
 type HJ212Data struct {
 	BaseModel
-	DeviceID     string    `gorm:"not null;size:50;comment:设备ID" json:"device_id"`
-	CommandCode  string    `gorm:"not null;size:10;comment:命令编码" json:"command_code"`
+	DeviceID     string    `gorm:"not null;size:50;index:idx_hj212_data_device_time,priority:1;uniqueIndex:uk_hj212_data_qn,priority:1;comment:设备ID" json:"device_id"`
+	CommandCode  string    `gorm:"not null;size:10;index:idx_hj212_data_device_time,priority:2;comment:命令编码" json:"command_code"`
+	QN           *string   `gorm:"size:32;uniqueIndex:uk_hj212_data_qn,priority:2;comment:请求编号，设备重传的数据包QN相同" json:"qn,omitempty"`
+	PacketNo     int       `gorm:"default:0;uniqueIndex:uk_hj212_data_qn,priority:3;comment:分包序号PNO" json:"packet_no,omitempty"`
 	DataType     string    `gorm:"size:50;comment:数据类型" json:"data_type"`
+	DataTime     *time.Time `gorm:"index:idx_hj212_data_device_time,priority:3;comment:数据时间" json:"data_time"`
 	RawData      string    `gorm:"type:text;comment:原始数据" json:"raw_data"`
 	ParsedData   JSONMap   `gorm:"type:json;comment:解析后数据" json:"parsed_data"`
 	ReceivedFrom string    `gorm:"size:100;comment:接收来源IP" json:"received_from"`
@@ -108,6 +157,29 @@ func (HJ212Data) TableName() string {
 	return GetTableName("hj212_data")
 }
 
+// HJ212数据质量等级
+const (
+	HJ212QualityNormal  = "normal"  // 正常
+	HJ212QualitySuspect = "suspect" // 可疑，数据保留但需复核
+	HJ212QualityInvalid = "invalid" // 无效，不参与统计
+)
+
+// HJ212 实时质量校验项
+const (
+	HJ212QualityCheckRange  = "range"       // 超出合理区间
+	HJ212QualityCheckChange = "change_rate" // 与上一条相比突变
+	HJ212QualityCheckFlag   = "flag"        // 数据标记为非正常状态
+)
+
+// HJ212QualityIssue 收包实时质量校验发现的因子异常，记录在解析数据的quality_issues中
+type HJ212QualityIssue struct {
+	FactorCode string  `json:"factor_code"`
+	Check      string  `json:"check"`
+	Level      string  `json:"level"`
+	Value      float64 `json:"value"`
+	Message    string  `json:"message"`
+}
+
 // FileUploadRecord 文件上传记录模型
 type FileUploadRecord struct {
 	BaseModelWithOperator
@@ -136,11 +208,15 @@ func (FileUploadRecord) TableName() string {
 // DataSourceConfig 数据源配置结构体
 type DataSourceConfig struct {
 	// 数据库配置
-	Host     string `json:"host,omitempty"`
-	Port     int    `json:"port,omitempty"`
-	Database string `json:"database,omitempty"`
-	Username string `json:"username,omitempty"`
-	Password string `json:"password,omitempty"`
+	Host        string `json:"host,omitempty"`
+	Port        int    `json:"port,omitempty"`
+	Database    string `json:"database,omitempty"`
+	Username    string `json:"username,omitempty"`
+	Password    string `json:"password,omitempty"`
+	Instance    string `json:"instance,omitempty"`     // SQL Server命名实例
+	Encrypt     string `json:"encrypt,omitempty"`      // SQL Server连接加密：disable/false/true
+	ServiceName string `json:"service_name,omitempty"` // Oracle服务名，未配置时使用database
+	SID         string `json:"sid,omitempty"`          // Oracle SID，配置后优先于服务名
 
 	// HJ212配置
 	Protocol   string `json:"protocol,omitempty"`   // TCP/UDP
@@ -155,6 +231,12 @@ type DataSourceConfig struct {
 	FilePath   string `json:"file_path,omitempty"`
 	FileFormat string `json:"file_format,omitempty"`
 
+	// Kafka配置
+	Brokers       []string `json:"brokers,omitempty"`
+	Topic         string   `json:"topic,omitempty"`
+	ConsumerGroup string   `json:"consumer_group,omitempty"`
+	Serialization string   `json:"serialization,omitempty"` // 消息序列化格式：json/csv/string
+
 	// 通用配置
 	Timeout       int               `json:"timeout,omitempty"`
 	RetryTimes    int               `json:"retry_times,omitempty"`
@@ -165,8 +247,10 @@ type DataSourceConfig struct {
 // 数据源请求结构
 type DataSourceRequest struct {
 	Name        string            `json:"name" binding:"required,min=1,max=100"`
-	Type        string            `json:"type" binding:"required,oneof=hj212 database file api webhook"`
+	Type        string            `json:"type" binding:"required,oneof=hj212 database mysql postgresql sqlserver oracle file api webhook file_export kafka"`
 	Description string            `json:"description"`
+	DeviceID    string            `json:"device_id" binding:"max=50"`
+	Region      string            `json:"region" binding:"max=100"`
 	Config      DataSourceConfig  `json:"config" binding:"required"`
 	Tags        []string          `json:"tags"`
 	Priority    int               `json:"priority"`
@@ -290,17 +374,74 @@ func (j *JSONMap) Scan(value interface{}) error {
 type HJ212AlarmData struct {
 	BaseModel
 	DeviceID     string    `gorm:"not null;size:50;comment:设备ID" json:"device_id"`
+	RuleID       uint      `gorm:"index;default:0;comment:触发的告警规则ID，设备上报告警为0" json:"rule_id"`
+	FactorCode   string    `gorm:"size:20;comment:监测因子编码" json:"factor_code"`
+	Value        float64   `gorm:"comment:触发告警的监测值" json:"value"`
 	AlarmType    string    `gorm:"size:50;comment:报警类型" json:"alarm_type"`
 	AlarmLevel   string    `gorm:"size:20;comment:报警级别" json:"alarm_level"`
 	AlarmDesc    string    `gorm:"type:text;comment:报警描述" json:"alarm_desc"`
 	RawData      string    `gorm:"type:text;comment:原始数据" json:"raw_data"`
 	ReceivedFrom string    `gorm:"size:100;comment:接收来源IP" json:"received_from"`
 	ReceivedAt   time.Time `gorm:"not null;comment:接收时间" json:"received_at"`
-	Status       string    `gorm:"size:20;comment:处理状态" json:"status"`
+	Status       string    `gorm:"size:20;index;comment:处理状态" json:"status"`
 	ProcessedAt  *time.Time `gorm:"comment:处理时间" json:"processed_at"`
+	AcknowledgedBy *uint      `gorm:"comment:确认人ID" json:"acknowledged_by"`
+	AcknowledgedAt *time.Time `gorm:"comment:确认时间" json:"acknowledged_at"`
+	AssigneeID     *uint      `gorm:"comment:指派处理人ID" json:"assignee_id"`
+	AssignedAt     *time.Time `gorm:"comment:指派时间" json:"assigned_at"`
+	ClosedBy       *uint      `gorm:"comment:关闭人ID" json:"closed_by"`
+	HandleRemark   string     `gorm:"type:text;comment:处理备注" json:"handle_remark"`
 }
 
 // TableName 指定表名
 func (HJ212AlarmData) TableName() string {
 	return GetTableName("hj212_alarm_data")
-}
\ No newline at end of file
+}
+
+// 设备上下线事件常量
+const (
+	DeviceEventOnline  = "online"  // 上线
+	DeviceEventOffline = "offline" // 离线
+)
+
+// DeviceStatusEvent 设备上下线事件记录
+type DeviceStatusEvent struct {
+	BaseModel
+	DataSourceID uint       `gorm:"index;comment:数据源ID" json:"data_source_id"`
+	DeviceID     string     `gorm:"not null;size:50;index;comment:设备ID" json:"device_id"`
+	Event        string     `gorm:"not null;size:20;index;comment:事件类型" json:"event"`
+	LastActiveAt *time.Time `gorm:"comment:事件发生前最后一次收包时间" json:"last_active_at"`
+	OccurredAt   time.Time  `gorm:"not null;index;comment:事件时间" json:"occurred_at"`
+}
+
+// TableName 指定表名
+func (DeviceStatusEvent) TableName() string {
+	return GetTableName("device_status_events")
+}
+
+// HJ212 死信阶段
+const (
+	HJ212DeadLetterStageParse    = "parse"    // 报文格式、长度或CRC错误
+	HJ212DeadLetterStageValidate = "validate" // 解析成功但字段校验失败
+)
+
+// HJ212DeadLetter 解析或校验失败的HJ212原始报文，供人工分析
+type HJ212DeadLetter struct {
+	BaseModel
+	DeviceID       string    `gorm:"size:50;index;comment:设备MN，无法识别时为空" json:"device_id"`
+	RemoteAddr     string    `gorm:"size:100;comment:来源地址" json:"remote_addr"`
+	Stage          string    `gorm:"size:20;index;comment:失败阶段" json:"stage"`
+	Reason         string    `gorm:"type:text;comment:失败原因" json:"reason"`
+	RawData        string    `gorm:"type:text;comment:原始报文，含控制字符时为hex:前缀的十六进制" json:"raw_data"`
+	CRCExpected    string    `gorm:"size:8;comment:报文携带的CRC" json:"crc_expected"`
+	CRCCalculated  string    `gorm:"size:8;comment:按数据段计算的CRC" json:"crc_calculated"`
+	CRCMatched     bool      `gorm:"comment:CRC是否一致" json:"crc_matched"`
+	DeclaredLength int       `gorm:"comment:包头声明的数据段长度，无法解析时为-1" json:"declared_length"`
+	ActualLength   int       `gorm:"comment:实际数据段长度" json:"actual_length"`
+	ReceivedAt     time.Time `gorm:"not null;index;comment:接收时间" json:"received_at"`
+}
+
+// TableName 指定表名
+func (HJ212DeadLetter) TableName() string {
+	return GetTableName("hj212_dead_letters")
+}
@@ -0,0 +1,132 @@
+package models
+
+// ErrorCode 业务错误码，放在响应的code字段中供前端区分具体错误并做本地化。
+// 五位数字，前三位为错误类别：400用户、401认证、403授权、410角色、411权限。
+// 尚未细分的错误仍以HTTP状态码作为code
+type ErrorCode int
+
+// 用户
+const (
+	CodeUsernameExists    ErrorCode = 40001 // 用户名已存在
+	CodeEmailExists       ErrorCode = 40002 // 邮箱已存在
+	CodeUserNotFound      ErrorCode = 40003 // 用户不存在
+	CodeWeakPassword      ErrorCode = 40004 // 密码强度不足
+	CodePasswordReused    ErrorCode = 40005 // 新密码与近期密码重复
+	CodeCannotDeleteSelf  ErrorCode = 40006 // 不能删除自己
+	CodeCannotDisableSelf ErrorCode = 40007 // 不能禁用自己
+	CodeInvalidUserStatus ErrorCode = 40008 // 用户状态值无效
+)
+
+// 认证
+const (
+	CodeTokenMissing       ErrorCode = 40101 // 缺少认证令牌
+	CodeTokenMalformed     ErrorCode = 40102 // 认证令牌格式错误
+	CodeTokenInvalid       ErrorCode = 40103 // 认证令牌无效
+	CodeSessionRevoked     ErrorCode = 40104 // 登录已失效
+	CodeNotLoggedIn        ErrorCode = 40105 // 用户未登录
+	CodeInvalidCredentials ErrorCode = 40106 // 用户名或密码错误
+	CodeAccountDisabled    ErrorCode = 40107 // 账户已被禁用
+	CodeAccountLocked      ErrorCode = 40108 // 登录失败次数过多，账户已锁定
+	CodeWrongOldPassword   ErrorCode = 40109 // 原密码错误
+	CodeTokenRefreshFailed ErrorCode = 40110 // 令牌刷新失败
+	CodeResetDisabled      ErrorCode = 40111 // 未启用自助密码重置
+	CodeResetTokenInvalid  ErrorCode = 40112 // 重置链接无效或已过期
+	CodeLDAPUserConflict   ErrorCode = 40113 // LDAP账户与本地账户重名
+)
+
+// 授权
+const (
+	CodePermissionDenied ErrorCode = 40301 // 权限不足
+)
+
+// 角色
+const (
+	CodeRoleExists          ErrorCode = 41001 // 角色名称或代码已存在
+	CodeRoleNotFound        ErrorCode = 41002 // 角色不存在
+	CodeSystemRoleReadonly  ErrorCode = 41003 // 系统角色不允许修改或删除
+	CodeRoleHasUsers        ErrorCode = 41004 // 角色下还有用户
+	CodeRoleHasChildren     ErrorCode = 41005 // 角色存在子角色
+	CodeInvalidRoleIDs      ErrorCode = 41006 // 存在无效的角色ID
+	CodeInvalidParentRole   ErrorCode = 41007 // 父角色不合法
+	CodeInvalidTransferRole ErrorCode = 41008 // 删除角色时指定的替代角色不合法
+)
+
+// 权限
+const (
+	CodePermissionExists         ErrorCode = 41101 // 权限名称或代码已存在
+	CodePermissionNotFound       ErrorCode = 41102 // 权限不存在
+	CodeParentPermissionNotFound ErrorCode = 41103 // 父权限不存在
+	CodeSystemPermissionReadonly ErrorCode = 41104 // 系统权限不允许修改或删除
+	CodePermissionHasChildren    ErrorCode = 41105 // 权限下还有子权限
+	CodePermissionInUse          ErrorCode = 41106 // 权限被角色使用
+	CodeInvalidParentPermission  ErrorCode = 41107 // 父权限不能是自己或子权限
+	CodePermissionUnavailable    ErrorCode = 41108 // 部分权限不存在或已禁用
+)
+
+// errorMessages 错误码的默认提示
+var errorMessages = map[ErrorCode]string{
+	CodeUsernameExists:    "用户名已存在",
+	CodeEmailExists:       "邮箱已存在",
+	CodeUserNotFound:      "用户不存在",
+	CodeWeakPassword:      "密码强度不足",
+	CodePasswordReused:    "新密码不能与最近使用过的密码相同",
+	CodeCannotDeleteSelf:  "不能删除自己",
+	CodeCannotDisableSelf: "不能禁用自己",
+	CodeInvalidUserStatus: "状态值无效",
+
+	CodeTokenMissing:       "缺少认证令牌",
+	CodeTokenMalformed:     "认证令牌格式错误",
+	CodeTokenInvalid:       "认证令牌无效",
+	CodeSessionRevoked:     "登录已失效，请重新登录",
+	CodeNotLoggedIn:        "用户未登录",
+	CodeInvalidCredentials: "用户名或密码错误",
+	CodeAccountDisabled:    "账户已被禁用",
+	CodeAccountLocked:      "登录失败次数过多，请稍后重试",
+	CodeWrongOldPassword:   "原密码错误",
+	CodeTokenRefreshFailed: "令牌刷新失败",
+	CodeResetDisabled:      "未启用自助密码重置，请联系管理员",
+	CodeResetTokenInvalid:  "重置链接无效或已过期",
+	CodeLDAPUserConflict:   "该用户名已被本地账户占用，请联系管理员",
+
+	CodePermissionDenied: "权限不足",
+
+	CodeRoleExists:          "角色名称或代码已存在",
+	CodeRoleNotFound:        "角色不存在",
+	CodeSystemRoleReadonly:  "系统角色不允许修改",
+	CodeRoleHasUsers:        "该角色下还有用户，无法删除",
+	CodeRoleHasChildren:     "该角色存在子角色，无法删除",
+	CodeInvalidRoleIDs:      "存在无效的角色ID",
+	CodeInvalidParentRole:   "父角色不合法",
+	CodeInvalidTransferRole: "替代角色不合法",
+
+	CodePermissionExists:         "权限名称或代码已存在",
+	CodePermissionNotFound:       "权限不存在",
+	CodeParentPermissionNotFound: "父权限不存在",
+	CodeSystemPermissionReadonly: "系统权限不允许修改",
+	CodePermissionHasChildren:    "该权限下还有子权限，请先删除子权限",
+	CodePermissionInUse:          "该权限被角色使用，无法删除",
+	CodeInvalidParentPermission:  "不能设置自己或子权限为父权限",
+	CodePermissionUnavailable:    "部分权限不存在或已禁用",
+}
+
+// Message 错误码的默认提示
+func (c ErrorCode) Message() string {
+	return errorMessages[c]
+}
+
+// ErrorCodes 全部业务错误码及默认提示，供前端生成本地化表
+func ErrorCodes() map[ErrorCode]string {
+	codes := make(map[ErrorCode]string, len(errorMessages))
+	for code, message := range errorMessages {
+		codes[code] = message
+	}
+	return codes
+}
+
+// CodeErrorResponse 业务错误响应，message为空时使用错误码的默认提示
+func CodeErrorResponse(code ErrorCode, message string) *Response {
+	if message == "" {
+		message = code.Message()
+	}
+	return ErrorResponse(int(code), message)
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodeErrorResponse(t *testing.T) {
+	resp := CodeErrorResponse(CodeUsernameExists, "")
+	assert.Equal(t, 40001, resp.Code)
+	assert.Equal(t, "用户名已存在", resp.Message)
+
+	resp = CodeErrorResponse(CodeSystemRoleReadonly, "系统角色不允许删除")
+	assert.Equal(t, 41003, resp.Code)
+	assert.Equal(t, "系统角色不允许删除", resp.Message)
+
+	// 错误码按类别分段，且都有默认提示
+	for code, message := range ErrorCodes() {
+		assert.NotEmpty(t, message, code)
+		assert.Contains(t, []int{400, 401, 403, 410, 411}, int(code)/100, code)
+	}
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"encoding/json"
+	"math"
 	"time"
 )
 
@@ -18,6 +19,8 @@ type ETLJob struct {
 	CronExpr     string          `gorm:"size:100;comment:定时表达式" json:"cron_expr"`
 	Status       string          `gorm:"not null;size:20;comment:作业状态" json:"status"`
 	IsEnabled    bool            `gorm:"default:true;comment:是否启用" json:"is_enabled"`
+	IsPaused     bool            `gorm:"default:false;comment:是否暂停" json:"is_paused"`
+	PausedAt     *time.Time      `gorm:"comment:暂停时间" json:"paused_at"`
 	Priority     int             `gorm:"default:0;comment:优先级" json:"priority"`
 	MaxRetries   int             `gorm:"default:3;comment:最大重试次数" json:"max_retries"`
 	Timeout      int             `gorm:"default:3600;comment:超时时间(秒)" json:"timeout"`
@@ -26,6 +29,8 @@ type ETLJob struct {
 	RunCount     int             `gorm:"default:0;comment:运行次数" json:"run_count"`
 	SuccessCount int             `gorm:"default:0;comment:成功次数" json:"success_count"`
 	FailureCount int             `gorm:"default:0;comment:失败次数" json:"failure_count"`
+	RunOwner     string          `gorm:"size:100;comment:执行锁持有实例" json:"-"`
+	RunHeartbeat *time.Time      `gorm:"comment:执行锁心跳时间" json:"-"`
 
 	// 关联
 	Source      *DataSource      `gorm:"foreignKey:SourceID" json:"source,omitempty"`
@@ -43,7 +48,7 @@ func (ETLJob) TableName() string {
 // ETLExecution ETL执行记录模型
 type ETLExecution struct {
 	BaseModel
-	JobID        uint       `gorm:"not null;comment:作业ID" json:"job_id"`
+	JobID        uint       `gorm:"not null;index;comment:作业ID" json:"job_id"`
 	ExecutionID  string     `gorm:"not null;size:100;comment:执行ID" json:"execution_id"`
 	Status       string     `gorm:"not null;size:20;comment:执行状态" json:"status"`
 	StartTime    time.Time  `gorm:"comment:开始时间" json:"start_time"`
@@ -53,15 +58,21 @@ type ETLExecution struct {
 	OutputRows   int64      `gorm:"default:0;comment:输出行数" json:"output_rows"`
 	ErrorRows    int64      `gorm:"default:0;comment:错误行数" json:"error_rows"`
 	SkippedRows  int64      `gorm:"default:0;comment:跳过行数" json:"skipped_rows"`
+	RowsPerSecond float64   `gorm:"default:0;comment:吞吐率(行/秒)" json:"rows_per_second"`
+	Progress     int        `gorm:"default:0;comment:处理进度(%)" json:"progress"`
+	OutputFileID *uint      `gorm:"comment:导出文件ID" json:"output_file_id"`
 	ErrorMessage string     `gorm:"type:text;comment:错误信息" json:"error_message"`
 	LogContent   string     `gorm:"type:longtext;comment:日志内容" json:"log_content"`
 	TriggerType  string     `gorm:"size:20;comment:触发类型 manual/schedule/api" json:"trigger_type"`
-	TriggerBy    uint       `gorm:"comment:触发人ID" json:"trigger_by"`
+	TriggerBy    *uint      `gorm:"comment:触发人ID，定时触发时为空" json:"trigger_by"`
+	Attempt      int        `gorm:"default:0;comment:重试次数，0为首次执行" json:"attempt"`
+	RetryOf      string     `gorm:"size:100;comment:重试的首次执行ID" json:"retry_of,omitempty"`
 
 	// 关联
 	Job     *ETLJob `gorm:"foreignKey:JobID" json:"job,omitempty"`
 	Trigger *User   `gorm:"foreignKey:TriggerBy" json:"trigger,omitempty"`
-	// Steps   []ETLExecutionStep `gorm:"foreignKey:ExecutionID" json:"steps,omitempty"` // 暂时注释
+	OutputFile *FileRecord `gorm:"foreignKey:OutputFileID" json:"output_file,omitempty"`
+	Steps   []ETLExecutionStep `gorm:"foreignKey:ExecutionID" json:"steps,omitempty"`
 }
 
 // TableName 指定表名
@@ -69,11 +80,16 @@ func (ETLExecution) TableName() string {
 	return GetTableName("etl_executions")
 }
 
-// ETLExecutionStep ETL执行步骤模型 (暂时完全注释掉)
-/*
+// ETL执行步骤类型，转换步骤使用 TransformationConfig.Type
+const (
+	ETLStepExtract = "extract" // 读取源数据
+	ETLStepLoad    = "load"    // 写入目标
+)
+
+// ETLExecutionStep ETL执行步骤模型，记录每个步骤的行数、耗时和错误，用于定位慢步骤和失败原因
 type ETLExecutionStep struct {
 	BaseModel
-	ExecutionID  uint       `gorm:"not null;comment:执行记录ID" json:"execution_id"`
+	ExecutionID  uint       `gorm:"not null;index;comment:执行记录ID" json:"execution_id"`
 	StepName     string     `gorm:"not null;size:100;comment:步骤名称" json:"step_name"`
 	StepType     string     `gorm:"not null;size:50;comment:步骤类型" json:"step_type"`
 	Status       string     `gorm:"not null;size:20;comment:步骤状态" json:"status"`
@@ -83,18 +99,15 @@ type ETLExecutionStep struct {
 	InputRows    int64      `gorm:"default:0;comment:输入行数" json:"input_rows"`
 	OutputRows   int64      `gorm:"default:0;comment:输出行数" json:"output_rows"`
 	ErrorRows    int64      `gorm:"default:0;comment:错误行数" json:"error_rows"`
+	SkippedRows  int64      `gorm:"default:0;comment:跳过行数" json:"skipped_rows"`
 	ErrorMessage string     `gorm:"type:text;comment:错误信息" json:"error_message"`
 	StepOrder    int        `gorm:"comment:步骤顺序" json:"step_order"`
-
-	// 关联 (暂时移除外键约束)
-	// Execution *ETLExecution `gorm:"foreignKey:ExecutionID" json:"execution,omitempty"`
 }
 
 // TableName 指定表名
 func (ETLExecutionStep) TableName() string {
 	return GetTableName("etl_execution_steps")
 }
-*/
 
 // ETLTemplate ETL模板模型
 type ETLTemplate struct {
@@ -134,7 +147,12 @@ type QualityRule struct {
 	Threshold    float64         `gorm:"comment:阈值" json:"threshold"`
 	IsEnabled    bool            `gorm:"default:true;comment:是否启用" json:"is_enabled"`
 	Priority     int             `gorm:"default:0;comment:优先级" json:"priority"`
+	Weight       float64         `gorm:"default:1;comment:综合评分权重" json:"weight"`
 	AlertLevel   string          `gorm:"size:20;comment:告警级别" json:"alert_level"`
+	CronExpr     string          `gorm:"size:100;comment:定时检查表达式" json:"cron_expr"`
+	ScheduleEnabled bool         `gorm:"default:false;comment:是否启用定时检查" json:"schedule_enabled"`
+	LastCheckAt  *time.Time      `gorm:"comment:最后检查时间" json:"last_check_at"`
+	NextCheckAt  *time.Time      `gorm:"comment:下次检查时间" json:"next_check_at"`
 
 	// 关联
 	DataSource *DataSource       `gorm:"foreignKey:DataSourceID" json:"data_source,omitempty"`
@@ -161,6 +179,9 @@ type QualityReport struct {
 	Details      string    `gorm:"type:text;comment:检查详情JSON" json:"details"`
 	Suggestions  string    `gorm:"type:text;comment:改进建议" json:"suggestions"`
 
+	// 失败样例，来自检查详情，不单独入库
+	FailureSamples []map[string]interface{} `gorm:"-" json:"failure_samples,omitempty"`
+
 	// 关联
 	Rule *QualityRule `gorm:"foreignKey:RuleID" json:"rule,omitempty"`
 }
@@ -170,6 +191,45 @@ func (QualityReport) TableName() string {
 	return GetTableName("quality_reports")
 }
 
+// 质量检查批次状态常量
+const (
+	QualityBatchRunning   = "running"
+	QualityBatchCompleted = "completed"
+	// QualityBatchInterrupted 执行实例在批次完成前停止，剩余规则未执行
+	QualityBatchInterrupted = "interrupted"
+)
+
+// QualityCheckBatch 质量检查批次，批量执行时记录整体进度和汇总得分
+type QualityCheckBatch struct {
+	BaseModel
+	BatchID        string     `gorm:"not null;size:36;uniqueIndex;comment:批次ID" json:"batch_id"`
+	DataSourceID   uint       `gorm:"index;comment:数据源ID，按数据源执行时有值" json:"data_source_id"`
+	Status         string     `gorm:"not null;size:20;index;comment:批次状态" json:"status"`
+	TotalCount     int        `gorm:"comment:规则总数" json:"total_count"`
+	CompletedCount int        `gorm:"comment:已执行规则数" json:"completed_count"`
+	FailedCount    int        `gorm:"comment:执行出错或未达阈值的规则数" json:"failed_count"`
+	AvgScore       float64    `gorm:"comment:已完成检查的平均分" json:"avg_score"`
+	StartedAt      time.Time  `gorm:"comment:开始时间" json:"started_at"`
+	FinishedAt     *time.Time `gorm:"comment:结束时间" json:"finished_at"`
+	RunOwner       string     `gorm:"size:100;comment:执行实例" json:"-"`
+	RunHeartbeat   *time.Time `gorm:"comment:执行心跳时间" json:"-"`
+	Progress       float64    `gorm:"-" json:"progress"` // 完成百分比，查询时计算
+}
+
+// 方法：计算完成百分比
+func (b *QualityCheckBatch) CalculateProgress() {
+	if b.TotalCount <= 0 {
+		b.Progress = 0
+		return
+	}
+	b.Progress = math.Round(float64(b.CompletedCount)*10000/float64(b.TotalCount)) / 100
+}
+
+// TableName 指定表名
+func (QualityCheckBatch) TableName() string {
+	return GetTableName("quality_check_batches")
+}
+
 // ETL配置结构
 type ETLJobConfig struct {
 	// 数据源配置
@@ -264,10 +324,12 @@ type ETLExecutionRequest struct {
 type ETLExecutionStats struct {
 	TotalJobs      int64 `json:"total_jobs"`
 	ActiveJobs     int64 `json:"active_jobs"`
+	PausedJobs     int64 `json:"paused_jobs"`
 	TotalExecutions int64 `json:"total_executions"`
 	RunningExecutions int64 `json:"running_executions"`
 	TodayExecutions int64 `json:"today_executions"`
 	SuccessRate    float64 `json:"success_rate"`
+	AvgRowsPerSecond float64 `json:"avg_rows_per_second"`
 }
 
 // 方法：设置配置数据
@@ -308,15 +370,33 @@ func (exec *ETLExecution) CalculateDuration() {
 	}
 }
 
+// 方法：计算吞吐率，运行中的执行按已处理行数和已用时长估算
+func (exec *ETLExecution) CalculateThroughput() {
+	duration := exec.Duration
+	if exec.EndTime == nil && !exec.StartTime.IsZero() {
+		duration = time.Since(exec.StartTime).Milliseconds()
+	}
+	if duration <= 0 {
+		exec.RowsPerSecond = 0
+		return
+	}
+	exec.RowsPerSecond = math.Round(float64(exec.InputRows)*1000/float64(duration)*100) / 100
+}
+
 // ETL状态常量 - 新增的状态
 const (
 	ETLStatusCreated  = "created"
 	ETLStatusStopped  = "stopped"
 	ETLStatusIdle     = "idle"
 	ETLStatusError    = "error"
+	// ETLStatusRetrying 本次执行失败，已安排重试，最终结果见重试产生的执行记录
+	ETLStatusRetrying = "retrying"
+	// ETLStatusTimeout 超过作业的超时时间被取消
+	ETLStatusTimeout = "timeout"
 )
 
 // 方法：检查执行是否完成
 func (exec *ETLExecution) IsCompleted() bool {
-	return exec.Status == ETLStatusSuccess || exec.Status == ETLStatusFailed || exec.Status == ETLStatusCanceled
+	return exec.Status == ETLStatusSuccess || exec.Status == ETLStatusFailed || exec.Status == ETLStatusCanceled ||
+		exec.Status == ETLStatusRetrying || exec.Status == ETLStatusTimeout
 }
\ No newline at end of file
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestETLExecutionCalculateThroughput(t *testing.T) {
+	start := time.Date(2024, 3, 1, 8, 0, 0, 0, time.Local)
+	end := start.Add(4 * time.Second)
+
+	tests := []struct {
+		name      string
+		execution ETLExecution
+		expected  float64
+	}{
+		{name: "已结束", execution: ETLExecution{StartTime: start, EndTime: &end, Duration: 4000, InputRows: 1000}, expected: 250},
+		{name: "保留两位小数", execution: ETLExecution{StartTime: start, EndTime: &end, Duration: 3000, InputRows: 1000}, expected: 333.33},
+		{name: "无数据", execution: ETLExecution{StartTime: start, EndTime: &end, Duration: 4000}, expected: 0},
+		{name: "时长为零", execution: ETLExecution{StartTime: start, EndTime: &start, InputRows: 1000}, expected: 0},
+		{name: "未开始", execution: ETLExecution{InputRows: 1000}, expected: 0},
+		// 上次计算的结果不能残留
+		{name: "清除旧值", execution: ETLExecution{StartTime: start, EndTime: &start, InputRows: 1000, RowsPerSecond: 99}, expected: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.execution.CalculateThroughput()
+			assert.Equal(t, tt.expected, tt.execution.RowsPerSecond)
+		})
+	}
+
+	// 运行中的执行按已用时长估算
+	running := ETLExecution{StartTime: time.Now().Add(-10 * time.Second), InputRows: 1000, Duration: 1}
+	running.CalculateThroughput()
+	assert.InDelta(t, 100, running.RowsPerSecond, 1)
+}
@@ -0,0 +1,27 @@
+package models
+
+// MonitorFactor 监测因子字典，配置因子名称、单位和默认上下限
+type MonitorFactor struct {
+	BaseModelWithOperator
+	Code        string   `gorm:"not null;size:20;uniqueIndex;comment:因子编码" json:"code"`
+	Name        string   `gorm:"not null;size:100;comment:因子名称" json:"name"`
+	Unit        string   `gorm:"size:20;comment:单位" json:"unit"`
+	UpperLimit  *float64 `gorm:"comment:默认上限，为空表示不检查" json:"upper_limit"`
+	LowerLimit  *float64 `gorm:"comment:默认下限，为空表示不检查" json:"lower_limit"`
+	Description string   `gorm:"size:255;comment:描述" json:"description"`
+}
+
+// TableName 指定表名
+func (MonitorFactor) TableName() string {
+	return GetTableName("monitor_factors")
+}
+
+// MonitorFactorRequest 监测因子创建/更新请求
+type MonitorFactorRequest struct {
+	Code        string   `json:"code" binding:"required,max=20"`
+	Name        string   `json:"name" binding:"required,max=100"`
+	Unit        string   `json:"unit" binding:"max=20"`
+	UpperLimit  *float64 `json:"upper_limit"`
+	LowerLimit  *float64 `json:"lower_limit"`
+	Description string   `json:"description" binding:"max=255"`
+}
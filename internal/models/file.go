@@ -10,15 +10,19 @@ type FileRecord struct {
 	OriginalName string    `gorm:"not null;size:255;comment:原始文件名" json:"original_name"`
 	StoredName   string    `gorm:"not null;size:255;comment:存储文件名" json:"stored_name"`
 	FilePath     string    `gorm:"not null;size:500;comment:文件路径" json:"file_path"`
+	StorageType  string    `gorm:"size:20;default:local;comment:存储后端类型" json:"storage_type"`
 	FileSize     int64     `gorm:"not null;comment:文件大小(字节)" json:"file_size"`
 	FileType     string    `gorm:"size:100;comment:文件类型" json:"file_type"`
 	MimeType     string    `gorm:"size:100;comment:MIME类型" json:"mime_type"`
+	Category     string    `gorm:"size:50;index;comment:文件分类" json:"category"`
 	MD5Hash      string    `gorm:"size:32;comment:MD5哈希值" json:"md5_hash"`
+	SHA256Hash   string    `gorm:"size:64;index;comment:内容SHA256，相同内容的记录共用存储对象" json:"sha256_hash"`
 	Status       string    `gorm:"not null;size:20;comment:文件状态" json:"status"`
 	Description  string    `gorm:"size:500;comment:文件描述" json:"description"`
 	Tags         string    `gorm:"size:500;comment:文件标签" json:"tags"`
 	AccessCount  int       `gorm:"default:0;comment:访问次数" json:"access_count"`
 	LastAccess   *time.Time `gorm:"comment:最后访问时间" json:"last_access"`
+	Deduplicated bool      `gorm:"-" json:"deduplicated,omitempty"` // 上传时复用了已有的存储对象
 
 	// 关联
 	Uploader *User `gorm:"foreignKey:CreatedBy" json:"uploader,omitempty"`
@@ -29,6 +33,53 @@ func (FileRecord) TableName() string {
 	return GetTableName("file_records")
 }
 
+// FileUploadSession 分片上传会话
+type FileUploadSession struct {
+	BaseModelWithOperator
+	UploadID    string    `gorm:"not null;size:36;uniqueIndex;comment:上传ID" json:"upload_id"`
+	FileName    string    `gorm:"not null;size:255;comment:原始文件名" json:"file_name"`
+	FileSize    int64     `gorm:"not null;comment:文件大小(字节)" json:"file_size"`
+	FileMD5     string    `gorm:"size:32;index;comment:整个文件的MD5，用于合并后校验和断点续传匹配" json:"file_md5"`
+	MimeType    string    `gorm:"size:100;comment:MIME类型" json:"mime_type"`
+	ChunkSize   int64     `gorm:"not null;comment:分片大小(字节)" json:"chunk_size"`
+	TotalChunks int       `gorm:"not null;comment:分片总数" json:"total_chunks"`
+	Description string    `gorm:"size:500;comment:文件描述" json:"description"`
+	Tags        string    `gorm:"size:500;comment:文件标签" json:"tags"`
+	Status      string    `gorm:"not null;size:20;index;comment:上传状态" json:"status"`
+	FileID      *uint     `gorm:"comment:合并后的文件记录ID" json:"file_id"`
+	ExpiresAt   time.Time `gorm:"not null;index;comment:过期时间" json:"expires_at"`
+}
+
+// TableName 指定表名
+func (FileUploadSession) TableName() string {
+	return GetTableName("file_upload_sessions")
+}
+
+// 分片上传状态常量
+const (
+	UploadSessionUploading = "uploading" // 上传中
+	UploadSessionCompleted = "completed" // 已合并
+	UploadSessionAborted   = "aborted"   // 已取消
+	UploadSessionExpired   = "expired"   // 已过期
+)
+
+// ChunkUploadInitRequest 分片上传初始化请求
+type ChunkUploadInitRequest struct {
+	FileName    string `json:"file_name" binding:"required,max=255"`
+	FileSize    int64  `json:"file_size" binding:"required,min=1"`
+	ChunkSize   int64  `json:"chunk_size" binding:"required,min=1"`
+	FileMD5     string `json:"file_md5" binding:"omitempty,len=32,hexadecimal"`
+	MimeType    string `json:"mime_type" binding:"max=100"`
+	Description string `json:"description" binding:"max=500"`
+	Tags        string `json:"tags" binding:"max=500"`
+}
+
+// FileZipDownloadRequest 多文件打包下载请求
+type FileZipDownloadRequest struct {
+	FileIDs []uint `json:"file_ids" binding:"required,min=1,dive,min=1"`
+	Name    string `json:"name" binding:"max=100"` // 压缩包文件名，不含扩展名
+}
+
 // FileStatus 文件状态常量
 const (
 	FileStatusActive   = "active"   // 活跃状态
@@ -20,6 +20,12 @@ type User struct {
 	Department  string     `gorm:"size:100;comment:部门" json:"department"`
 	Position    string     `gorm:"size:100;comment:职位" json:"position"`
 	Remark      string     `gorm:"type:text;comment:备注" json:"remark"`
+	AuthSource  string     `gorm:"size:20;default:local;comment:认证来源 local/ldap" json:"auth_source"`
+
+	// PasswordChangedAt 密码重置时间，早于该时间签发的令牌均失效
+	PasswordChangedAt *time.Time `gorm:"comment:密码重置时间" json:"-"`
+	// SessionExpiresAt 最近一次登录或刷新签发的令牌过期时间，登出或重置密码时清空，用于判断在线状态
+	SessionExpiresAt *time.Time `gorm:"comment:会话过期时间" json:"-"`
 
 	// 关联
 	Roles       []Role       `gorm:"many2many:env_user_roles;" json:"roles,omitempty"`
@@ -42,6 +48,9 @@ type UserInfo struct {
 	Avatar      string     `json:"avatar"`
 	Status      int        `json:"status"`
 	LastLoginAt *time.Time `json:"last_login_at"`
+	LastLoginIP string     `json:"last_login_ip"`
+	LoginCount  int        `json:"login_count"`
+	Online      bool       `json:"online"`
 	Department  string     `json:"department"`
 	Position    string     `json:"position"`
 	Roles       []Role     `json:"roles"`
@@ -49,6 +58,11 @@ type UserInfo struct {
 	UpdatedAt   time.Time  `json:"updated_at"`
 }
 
+// IsOnline 用户处于激活状态且会话未过期时视为在线。令牌无状态，多端登录时任一端登出即视为离线
+func (u *User) IsOnline(now time.Time) bool {
+	return u.Status == UserStatusActive && u.SessionExpiresAt != nil && u.SessionExpiresAt.After(now)
+}
+
 // GetPrimaryRole 获取用户的主要角色
 func (u *User) GetPrimaryRole() *Role {
 	if len(u.Roles) > 0 {
@@ -84,6 +98,9 @@ func (u *User) ToUserInfo() *UserInfo {
 		Avatar:      u.Avatar,
 		Status:      u.Status,
 		LastLoginAt: u.LastLoginAt,
+		LastLoginIP: u.LoginIP,
+		LoginCount:  u.LoginCount,
+		Online:      u.IsOnline(time.Now()),
 		Department:  u.Department,
 		Position:    u.Position,
 		Roles:       u.Roles,
@@ -101,6 +118,7 @@ type Role struct {
 	Status      int    `gorm:"default:1;comment:状态 1激活 0禁用" json:"status"`
 	Sort        int    `gorm:"default:0;comment:排序" json:"sort"`
 	IsSystem    bool   `gorm:"default:false;comment:是否系统角色" json:"is_system"`
+	ParentID    *uint  `gorm:"index;comment:父角色ID，子角色继承父角色的全部权限" json:"parent_id"`
 
 	// 关联
 	Users       []User       `gorm:"many2many:env_user_roles;" json:"users,omitempty"`
@@ -173,7 +191,7 @@ func (RolePermission) TableName() string {
 // LoginLog 登录日志模型
 type LoginLog struct {
 	BaseModel
-	UserID    uint   `gorm:"not null;comment:用户ID" json:"user_id"`
+	UserID    *uint  `gorm:"index;comment:用户ID，用户不存在时为空" json:"user_id"`
 	Username  string `gorm:"not null;size:50;comment:用户名" json:"username"`
 	IP        string `gorm:"not null;size:45;comment:登录IP" json:"ip"`
 	UserAgent string `gorm:"size:500;comment:用户代理" json:"user_agent"`
@@ -181,22 +199,95 @@ type LoginLog struct {
 	Message   string `gorm:"size:255;comment:登录信息" json:"message"`
 	Location  string `gorm:"size:100;comment:登录地点" json:"location"`
 
+	// 终端信息，由User-Agent解析
+	DeviceType string `gorm:"size:20;comment:设备类型 desktop/mobile/tablet/bot/client/unknown" json:"device_type"`
+	OS         string `gorm:"size:50;comment:操作系统" json:"os"`
+	Browser    string `gorm:"size:50;comment:浏览器或客户端" json:"browser"`
+
+	// 地理信息，由IP地址库解析，未配置地址库或未命中时为空
+	Country  string `gorm:"size:50;comment:国家" json:"country"`
+	Province string `gorm:"size:50;comment:省份" json:"province"`
+	City     string `gorm:"size:50;comment:城市" json:"city"`
+	IsRemote bool   `gorm:"index;default:false;comment:是否异地登录" json:"is_remote"`
+
+	// Extra 扩展审计信息，如运营商、上次登录地点等
+	Extra JSONMap `gorm:"type:json;comment:扩展信息" json:"extra,omitempty"`
+
 	// 关联
 	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
 }
 
+// 登录设备类型
+const (
+	LoginDeviceDesktop = "desktop"
+	LoginDeviceMobile  = "mobile"
+	LoginDeviceTablet  = "tablet"
+	LoginDeviceBot     = "bot"    // 爬虫
+	LoginDeviceClient  = "client" // curl、脚本等非浏览器客户端
+	LoginDeviceUnknown = "unknown"
+)
+
 // TableName 指定表名
 func (LoginLog) TableName() string {
 	return GetTableName("login_logs")
 }
 
+// PasswordHistory 密码历史模型，用于防止重复使用近期密码
+type PasswordHistory struct {
+	BaseModel
+	UserID       uint   `gorm:"not null;index;comment:用户ID" json:"user_id"`
+	PasswordHash string `gorm:"not null;size:255;comment:密码散列" json:"-"`
+}
+
+// TableName 指定表名
+func (PasswordHistory) TableName() string {
+	return GetTableName("password_histories")
+}
+
+// PasswordResetToken 自助密码重置令牌，仅保存令牌散列，一次性使用
+type PasswordResetToken struct {
+	BaseModel
+	UserID    uint       `gorm:"not null;index;comment:用户ID" json:"user_id"`
+	TokenHash string     `gorm:"not null;uniqueIndex;size:64;comment:令牌SHA-256散列" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null;comment:过期时间" json:"expires_at"`
+	UsedAt    *time.Time `gorm:"comment:使用时间" json:"used_at"`
+	RequestIP string     `gorm:"size:45;comment:申请IP" json:"request_ip"`
+}
+
+// TableName 指定表名
+func (PasswordResetToken) TableName() string {
+	return GetTableName("password_reset_tokens")
+}
+
+// 数据权限主体类型常量
+const (
+	DataScopeSubjectRole = "role" // 角色
+	DataScopeSubjectUser = "user" // 用户
+)
+
+// DataScope 数据权限范围模型，限定角色或用户可见的设备MN和区域
+// 用户级配置优先于角色级配置；未配置任何范围时不做数据限制
+type DataScope struct {
+	BaseModel
+	SubjectType string `gorm:"not null;size:20;uniqueIndex:idx_data_scope_subject;comment:主体类型 role/user" json:"subject_type"`
+	SubjectID   uint   `gorm:"not null;uniqueIndex:idx_data_scope_subject;comment:主体ID" json:"subject_id"`
+	DeviceIDs   string `gorm:"type:text;comment:设备MN，逗号分隔" json:"device_ids"`
+	Regions     string `gorm:"size:500;comment:区域，逗号分隔" json:"regions"`
+}
+
+// TableName 指定表名
+func (DataScope) TableName() string {
+	return GetTableName("data_scopes")
+}
+
 // OperationLog 操作日志模型
 type OperationLog struct {
 	BaseModel
-	UserID      uint   `gorm:"comment:操作用户ID" json:"user_id"`
+	UserID      *uint  `gorm:"index;comment:操作用户ID，未登录请求为空" json:"user_id"`
 	Username    string `gorm:"size:50;comment:操作用户名" json:"username"`
 	Module      string `gorm:"not null;size:50;comment:操作模块" json:"module"`
-	Action      string `gorm:"not null;size:50;comment:操作动作" json:"action"`
+	Action      string `gorm:"not null;size:255;comment:操作动作(方法+路径)" json:"action"`
+	Resource    string `gorm:"size:255;index;comment:操作资源" json:"resource"`
 	Method      string `gorm:"not null;size:10;comment:请求方法" json:"method"`
 	URL         string `gorm:"not null;size:500;comment:请求URL" json:"url"`
 	IP          string `gorm:"not null;size:45;comment:操作IP" json:"ip"`
@@ -204,8 +295,11 @@ type OperationLog struct {
 	RequestBody string `gorm:"type:text;comment:请求参数" json:"request_body"`
 	Response    string `gorm:"type:text;comment:响应结果" json:"response"`
 	Status      int    `gorm:"not null;comment:操作状态 1成功 0失败" json:"status"`
+	StatusCode  int    `gorm:"comment:HTTP状态码" json:"status_code"`
 	ErrorMsg    string `gorm:"type:text;comment:错误信息" json:"error_msg"`
 	Duration    int64  `gorm:"comment:执行时长(毫秒)" json:"duration"`
+	OldValue    string `gorm:"type:text;comment:变更前的值" json:"old_value,omitempty"`
+	NewValue    string `gorm:"type:text;comment:变更后的值" json:"new_value,omitempty"`
 
 	// 关联
 	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
@@ -216,6 +310,12 @@ func (OperationLog) TableName() string {
 	return GetTableName("operation_logs")
 }
 
+// 用户认证来源
+const (
+	AuthSourceLocal = "local"
+	AuthSourceLDAP  = "ldap"
+)
+
 // UserRequest 用户请求结构
 type UserRequest struct {
 	Username   string `json:"username" binding:"required,min=3,max=50"`
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserIsOnline(t *testing.T) {
+	now := time.Now()
+	future := now.Add(time.Hour)
+	past := now.Add(-time.Minute)
+
+	user := &User{Status: UserStatusActive, LoginIP: "10.0.0.1", SessionExpiresAt: &future}
+	assert.True(t, user.IsOnline(now))
+	info := user.ToUserInfo()
+	assert.True(t, info.Online)
+	assert.Equal(t, "10.0.0.1", info.LastLoginIP)
+
+	user.SessionExpiresAt = &past
+	assert.False(t, user.IsOnline(now))
+
+	user.SessionExpiresAt = nil
+	assert.False(t, user.IsOnline(now))
+
+	user.SessionExpiresAt = &future
+	user.Status = UserStatusInactive
+	assert.False(t, user.IsOnline(now))
+}
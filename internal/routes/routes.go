@@ -2,6 +2,7 @@ package routes
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/env-data-platform/internal/alarm"
 	"github.com/env-data-platform/internal/config"
 	"github.com/env-data-platform/internal/handlers"
 	"github.com/env-data-platform/internal/hj212"
@@ -10,7 +11,7 @@ import (
 )
 
 // SetupAPIRoutes 设置API路由
-func SetupAPIRoutes(router *gin.Engine, cfg *config.Config, logger *zap.Logger, hj212Server *hj212.Server) {
+func SetupAPIRoutes(router *gin.Engine, cfg *config.Config, logger *zap.Logger, hj212Server *hj212.Server, alarmDetector *alarm.Detector) {
 	// API版本1
 	v1 := router.Group("/api/v1")
 	{
@@ -22,10 +23,10 @@ func SetupAPIRoutes(router *gin.Engine, cfg *config.Config, logger *zap.Logger,
 		authenticated.Use(middleware.AuthMiddleware(cfg, logger))
 		{
 			// 仪表板
-			setupDashboardRoutes(authenticated, logger)
+			setupDashboardRoutes(authenticated, cfg, logger)
 
 			// 用户管理
-			setupUserRoutes(authenticated, logger)
+			setupUserRoutes(authenticated, cfg, logger)
 
 			// 角色管理
 			setupRoleRoutes(authenticated, logger)
@@ -33,20 +34,32 @@ func SetupAPIRoutes(router *gin.Engine, cfg *config.Config, logger *zap.Logger,
 			// 权限管理
 			setupPermissionRoutes(authenticated, logger)
 
+			// 数据权限范围
+			setupDataScopeRoutes(authenticated, logger)
+
 			// 数据源管理
 			setupDataSourceRoutes(authenticated, logger, hj212Server)
 
+			// 告警管理
+			setupAlarmRoutes(authenticated, logger, alarmDetector)
+
+			// 监测因子字典
+			setupFactorRoutes(authenticated, logger)
+
 			// ETL管理
 			setupETLRoutes(authenticated, logger)
 
 			// 数据质量管理
-			setupQualityRoutes(authenticated, logger)
+			setupQualityRoutes(authenticated, logger, alarmDetector)
 
 			// 文件管理
-			setupFileRoutes(authenticated, logger)
+			setupFileRoutes(authenticated, cfg, logger)
 
 			// 系统管理
-			setupSystemRoutes(authenticated, logger)
+			setupSystemRoutes(authenticated, logger, hj212Server)
+
+			// 回收站
+			setupRecycleBinRoutes(authenticated, logger)
 		}
 	}
 
@@ -68,6 +81,10 @@ func setupAuthRoutes(rg *gin.RouterGroup, cfg *config.Config, logger *zap.Logger
 		// 刷新token
 		auth.POST("/refresh", authHandler.RefreshToken)
 
+		// 忘记密码与凭邮件令牌重置密码
+		auth.POST("/password/forgot", authHandler.ForgotPassword)
+		auth.POST("/password/reset", authHandler.ResetPassword)
+
 		// 需要认证的路由
 		authRequired := auth.Group("")
 		authRequired.Use(middleware.AuthMiddleware(cfg, logger))
@@ -85,12 +102,15 @@ func setupAuthRoutes(rg *gin.RouterGroup, cfg *config.Config, logger *zap.Logger
 }
 
 // setupUserRoutes 设置用户路由
-func setupUserRoutes(rg *gin.RouterGroup, logger *zap.Logger) {
-	userHandler := handlers.NewUserHandler(logger)
+func setupUserRoutes(rg *gin.RouterGroup, cfg *config.Config, logger *zap.Logger) {
+	userHandler := handlers.NewUserHandler(cfg, logger)
 	users := rg.Group("/users")
 	{
 		users.GET("", userHandler.ListUsers)
 		users.POST("", userHandler.CreateUser)
+		users.POST("/import", userHandler.ImportUsers)
+		users.GET("/export", userHandler.ExportUsers)
+		users.PUT("/roles/batch", userHandler.BatchAssignRoles)
 		users.GET("/stats", userHandler.GetUserStats)
 		users.GET("/current", userHandler.GetCurrentUser)
 		users.PUT("/current", userHandler.UpdateCurrentUser)
@@ -98,6 +118,8 @@ func setupUserRoutes(rg *gin.RouterGroup, logger *zap.Logger) {
 		users.PUT("/:id", userHandler.UpdateUser)
 		users.DELETE("/:id", userHandler.DeleteUser)
 		users.PUT("/:id/password", userHandler.ResetPassword)
+		users.POST("/unlock-ip", middleware.RequireRole("超级管理员", "admin"), userHandler.UnlockIP)
+		users.POST("/:id/unlock", middleware.RequireRole("超级管理员", "admin"), userHandler.UnlockUser)
 		users.GET("/:id/roles", userHandler.GetUserRoles)
 		users.PUT("/:id/roles", userHandler.AssignRoles)
 	}
@@ -135,10 +157,28 @@ func setupPermissionRoutes(rg *gin.RouterGroup, logger *zap.Logger) {
 	}
 }
 
+// setupDataScopeRoutes 设置数据权限范围路由
+func setupDataScopeRoutes(rg *gin.RouterGroup, logger *zap.Logger) {
+	dataScopeHandler := handlers.NewDataScopeHandler(logger)
+	rg.GET("/data-scope/current", middleware.DataScope(logger), dataScopeHandler.GetCurrentDataScope)
+
+	admin := rg.Group("")
+	admin.Use(middleware.RequireRole("超级管理员", "admin"))
+	{
+		admin.GET("/roles/:id/data-scope", dataScopeHandler.GetRoleDataScope)
+		admin.PUT("/roles/:id/data-scope", dataScopeHandler.UpdateRoleDataScope)
+		admin.DELETE("/roles/:id/data-scope", dataScopeHandler.DeleteRoleDataScope)
+		admin.GET("/users/:id/data-scope", dataScopeHandler.GetUserDataScope)
+		admin.PUT("/users/:id/data-scope", dataScopeHandler.UpdateUserDataScope)
+		admin.DELETE("/users/:id/data-scope", dataScopeHandler.DeleteUserDataScope)
+	}
+}
+
 // setupDataSourceRoutes 设置数据源路由
 func setupDataSourceRoutes(rg *gin.RouterGroup, logger *zap.Logger, hj212Server *hj212.Server) {
 	dataSourceHandler := handlers.NewDataSourceHandler(logger)
 	dataSources := rg.Group("/datasources")
+	dataSources.Use(middleware.DataScope(logger))
 	{
 		dataSources.GET("", dataSourceHandler.ListDataSources)
 		dataSources.POST("", dataSourceHandler.CreateDataSource)
@@ -148,18 +188,78 @@ func setupDataSourceRoutes(rg *gin.RouterGroup, logger *zap.Logger, hj212Server
 		dataSources.POST("/:id/test", dataSourceHandler.TestDataSource)
 		dataSources.POST("/:id/sync", dataSourceHandler.SyncDataSource)
 		dataSources.GET("/:id/tables", dataSourceHandler.GetDataSourceTables)
+		dataSources.POST("/:id/preview", middleware.RequirePermission("datasource:preview"), dataSourceHandler.PreviewData)
+		dataSources.GET("/:id/metadata", dataSourceHandler.GetMetadata)
+		dataSources.GET("/:id/metadata/versions", dataSourceHandler.ListMetadataVersions)
+		dataSources.GET("/:id/metadata/diff", dataSourceHandler.GetMetadataDiff)
 	}
 
 	// HJ212数据查询
 	hj212Handler := handlers.NewHJ212Handler(logger, hj212Server)
 	hj212 := rg.Group("/hj212")
+	hj212.Use(middleware.DataScope(logger))
 	{
 		hj212.GET("/data", hj212Handler.QueryData)
 		hj212.GET("/data/:id", hj212Handler.GetDataDetail)
 		hj212.GET("/stats", hj212Handler.GetStats)
 		hj212.GET("/devices", hj212Handler.GetConnectedDevices)
 		hj212.GET("/alarms", hj212Handler.GetAlarmData)
-		hj212.POST("/command", hj212Handler.SendCommand)
+		hj212.GET("/dead-letters", hj212Handler.ListDeadLetters)
+		hj212.POST("/command", middleware.RequirePermission("hj212:control"), hj212Handler.SendCommand)
+		hj212.POST("/devices/:mn/control", middleware.RequirePermission("hj212:control"), hj212Handler.ControlDevice)
+	}
+}
+
+// setupAlarmRoutes 设置告警路由
+func setupAlarmRoutes(rg *gin.RouterGroup, logger *zap.Logger, alarmDetector *alarm.Detector) {
+	alarmHandler := handlers.NewAlarmHandler(logger, alarmDetector)
+	alarms := rg.Group("/alarms")
+	{
+		// 告警规则
+		rules := alarms.Group("/rules")
+		{
+			rules.GET("", alarmHandler.ListAlarmRules)
+			rules.GET("/:id", alarmHandler.GetAlarmRule)
+
+			adminRules := rules.Group("")
+			adminRules.Use(middleware.RequireRole("超级管理员", "admin"))
+			adminRules.POST("", alarmHandler.CreateAlarmRule)
+			adminRules.PUT("/:id", alarmHandler.UpdateAlarmRule)
+			adminRules.DELETE("/:id", alarmHandler.DeleteAlarmRule)
+			adminRules.POST("/:id/enable", alarmHandler.EnableAlarmRule)
+			adminRules.POST("/:id/disable", alarmHandler.DisableAlarmRule)
+		}
+
+		// 告警通知发送记录
+		alarms.GET("/notifications", alarmHandler.ListAlarmNotifications)
+
+		// 告警处理工作流
+		events := alarms.Group("")
+		events.Use(middleware.DataScope(logger))
+		{
+			events.GET("", alarmHandler.ListAlarms)
+			events.GET("/:id", alarmHandler.GetAlarm)
+			events.POST("/:id/ack", alarmHandler.AcknowledgeAlarm)
+			events.POST("/:id/assign", alarmHandler.AssignAlarm)
+			events.POST("/:id/close", alarmHandler.CloseAlarm)
+		}
+	}
+}
+
+// setupFactorRoutes 设置监测因子字典路由
+func setupFactorRoutes(rg *gin.RouterGroup, logger *zap.Logger) {
+	factorHandler := handlers.NewFactorHandler(logger)
+	factors := rg.Group("/factors")
+	{
+		factors.GET("", factorHandler.ListFactors)
+		factors.GET("/code/:code", factorHandler.LookupFactor)
+
+		adminFactors := factors.Group("")
+		adminFactors.Use(middleware.RequireRole("超级管理员", "admin"))
+		adminFactors.POST("", factorHandler.CreateFactor)
+		adminFactors.PUT("/:id", factorHandler.UpdateFactor)
+		adminFactors.DELETE("/:id", factorHandler.DeleteFactor)
+		adminFactors.POST("/reload", factorHandler.ReloadFactors)
 	}
 }
 
@@ -170,6 +270,7 @@ func setupETLRoutes(rg *gin.RouterGroup, logger *zap.Logger) {
 	{
 		// ETL统计信息
 		etl.GET("/stats", etlHandler.GetETLStats)
+		etl.GET("/cron/preview", etlHandler.PreviewCronExpr)
 
 		// ETL作业管理
 		jobs := etl.Group("/jobs")
@@ -181,12 +282,16 @@ func setupETLRoutes(rg *gin.RouterGroup, logger *zap.Logger) {
 			jobs.DELETE("/:id", etlHandler.DeleteETLJob)
 			jobs.POST("/:id/execute", etlHandler.ExecuteETLJob)
 			jobs.POST("/:id/stop", etlHandler.StopETLJob)
+			jobs.POST("/:id/pause", etlHandler.PauseETLJob)
+			jobs.POST("/:id/resume", etlHandler.ResumeETLJob)
+			jobs.POST("/:id/clone", etlHandler.CloneETLJob)
 		}
 
 		// ETL执行记录
 		executions := etl.Group("/executions")
 		{
 			executions.GET("", etlHandler.ListETLExecutions)
+			executions.GET("/export", etlHandler.ExportETLExecutions)
 			executions.GET("/:id", etlHandler.GetETLExecution)
 			executions.GET("/:id/logs", etlHandler.GetETLExecutionLogs)
 		}
@@ -205,13 +310,17 @@ func setupETLRoutes(rg *gin.RouterGroup, logger *zap.Logger) {
 }
 
 // setupQualityRoutes 设置数据质量路由
-func setupQualityRoutes(rg *gin.RouterGroup, logger *zap.Logger) {
-	qualityHandler := handlers.NewQualityHandler(logger)
+func setupQualityRoutes(rg *gin.RouterGroup, logger *zap.Logger, alarmDetector *alarm.Detector) {
+	qualityHandler := handlers.NewQualityHandler(logger, alarmDetector)
 	quality := rg.Group("/quality")
 	{
 		// 质量统计信息
 		quality.GET("/stats", qualityHandler.GetQualityStats)
 
+		// 质量趋势
+		quality.GET("/trend", qualityHandler.GetOverallTrend)
+		quality.GET("/trend/declining", qualityHandler.ListDecliningRules)
+
 		// 质量规则
 		rules := quality.Group("/rules")
 		{
@@ -221,6 +330,10 @@ func setupQualityRoutes(rg *gin.RouterGroup, logger *zap.Logger) {
 			rules.PUT("/:id", qualityHandler.UpdateQualityRule)
 			rules.DELETE("/:id", qualityHandler.DeleteQualityRule)
 			rules.POST("/:id/check", qualityHandler.ExecuteQualityCheck)
+			rules.GET("/:id/schedule", qualityHandler.GetRuleSchedule)
+			rules.GET("/:id/trend", qualityHandler.GetRuleTrend)
+			rules.POST("/:id/schedule", qualityHandler.EnableRuleSchedule)
+			rules.DELETE("/:id/schedule", qualityHandler.DisableRuleSchedule)
 			rules.POST("/batch-check", qualityHandler.BatchExecuteQualityCheck)
 		}
 
@@ -228,14 +341,19 @@ func setupQualityRoutes(rg *gin.RouterGroup, logger *zap.Logger) {
 		reports := quality.Group("/reports")
 		{
 			reports.GET("", qualityHandler.ListQualityReports)
+			reports.GET("/export", qualityHandler.ExportQualityReports)
 			reports.GET("/:id", qualityHandler.GetQualityReport)
 		}
+
+		// 质量检查批次
+		quality.GET("/batches", qualityHandler.ListQualityCheckBatches)
+		quality.GET("/batches/:batch_id", qualityHandler.GetQualityCheckBatch)
 	}
 }
 
 // setupFileRoutes 设置文件路由
-func setupFileRoutes(rg *gin.RouterGroup, logger *zap.Logger) {
-	fileHandler := handlers.NewFileHandler(logger)
+func setupFileRoutes(rg *gin.RouterGroup, cfg *config.Config, logger *zap.Logger) {
+	fileHandler := handlers.NewFileHandler(cfg, logger)
 	files := rg.Group("/files")
 	{
 		files.POST("/upload", fileHandler.UploadFile)
@@ -244,12 +362,42 @@ func setupFileRoutes(rg *gin.RouterGroup, logger *zap.Logger) {
 		files.DELETE("/:id", fileHandler.DeleteFile)
 		files.GET("/:id/info", fileHandler.GetFileInfo)
 		files.GET("/stats", fileHandler.GetFileStats)
+		files.POST("/zip", fileHandler.DownloadFilesZip)
+		files.POST("/:id/import-datasource", middleware.RequirePermission("datasource:import"), fileHandler.ImportAsDataSource)
+
+		// 回收站：恢复或彻底删除已删除的文件
+		adminFiles := files.Group("")
+		adminFiles.Use(middleware.RequireRole("超级管理员", "admin"))
+		adminFiles.GET("/deleted", fileHandler.ListDeletedFiles)
+		adminFiles.POST("/:id/restore", fileHandler.RestoreFile)
+		adminFiles.DELETE("/:id/purge", fileHandler.PurgeFile)
+
+		// 分片上传
+		chunks := files.Group("/chunks")
+		{
+			chunks.POST("/init", fileHandler.InitChunkUpload)
+			chunks.GET("/:upload_id", fileHandler.GetChunkUpload)
+			chunks.PUT("/:upload_id/:index", fileHandler.UploadChunk)
+			chunks.POST("/:upload_id/complete", fileHandler.CompleteChunkUpload)
+			chunks.DELETE("/:upload_id", fileHandler.AbortChunkUpload)
+		}
+	}
+}
+
+// setupRecycleBinRoutes 设置回收站路由
+func setupRecycleBinRoutes(rg *gin.RouterGroup, logger *zap.Logger) {
+	recycleBinHandler := handlers.NewRecycleBinHandler(logger)
+	recycleBin := rg.Group("/recycle-bin")
+	recycleBin.Use(middleware.RequireRole("超级管理员", "admin"))
+	{
+		recycleBin.GET("/:resource", recycleBinHandler.ListDeleted)
+		recycleBin.POST("/:resource/:id/restore", recycleBinHandler.Restore)
 	}
 }
 
 // setupSystemRoutes 设置系统路由
-func setupSystemRoutes(rg *gin.RouterGroup, logger *zap.Logger) {
-	systemHandler := handlers.NewSystemHandler(logger)
+func setupSystemRoutes(rg *gin.RouterGroup, logger *zap.Logger, hj212Server *hj212.Server) {
+	systemHandler := handlers.NewSystemHandler(logger, hj212Server)
 	system := rg.Group("/system")
 	{
 		system.GET("/info", systemHandler.GetSystemInfo)
@@ -267,12 +415,17 @@ func setupSystemRoutes(rg *gin.RouterGroup, logger *zap.Logger) {
 }
 
 // setupDashboardRoutes 设置仪表板路由
-func setupDashboardRoutes(rg *gin.RouterGroup, logger *zap.Logger) {
-	dashboardHandler := handlers.NewDashboardHandler(logger)
+func setupDashboardRoutes(rg *gin.RouterGroup, cfg *config.Config, logger *zap.Logger) {
+	dashboardHandler := handlers.NewDashboardHandler(cfg, logger)
 	dashboard := rg.Group("/dashboard")
+	dashboard.Use(middleware.DataScope(logger))
 	{
 		dashboard.GET("/overview", dashboardHandler.GetDashboardOverview)
 		dashboard.GET("/realtime", dashboardHandler.GetRealTimeData)
 		dashboard.GET("/charts", dashboardHandler.GetChartData)
+
+		// 在概览上直接确认最新告警
+		alarmHandler := handlers.NewAlarmHandler(logger, nil)
+		dashboard.POST("/alerts/:id/ack", alarmHandler.AcknowledgeAlarm)
 	}
 }
\ No newline at end of file
@@ -9,22 +9,30 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/env-data-platform/internal/alarm"
 	"github.com/env-data-platform/internal/config"
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/factor"
+	"github.com/env-data-platform/internal/handlers"
 	"github.com/env-data-platform/internal/hj212"
 	"github.com/env-data-platform/internal/middleware"
 	"github.com/env-data-platform/internal/routes"
+	"github.com/env-data-platform/internal/services"
 	"github.com/env-data-platform/internal/websocket"
 	"go.uber.org/zap"
 )
 
 // Server HTTP服务器
 type Server struct {
-	config       *config.Config
-	logger       *zap.Logger
-	httpServer   *http.Server
-	router       *gin.Engine
-	hj212Server  *hj212.Server
-	wsHub        *websocket.Hub
-	wsHandler    *websocket.Handler
+	config        *config.Config
+	logger        *zap.Logger
+	httpServer    *http.Server
+	router        *gin.Engine
+	hj212Server   *hj212.Server
+	alarmDetector *alarm.Detector
+	wsHub         *websocket.Hub
+	wsHandler     *websocket.Handler
+	dashboardPush *websocket.DashboardPusher
+	logCleaner    *services.LogCleaner
+	dsInspector   *services.DataSourceInspector
 }
 
 // NewServer 创建新的服务器实例
@@ -41,19 +49,44 @@ func NewServer(cfg *config.Config, logger *zap.Logger) *Server {
 	wsHub := websocket.NewHub(logger)
 	wsHandler := websocket.NewHandler(wsHub, logger)
 
+	// 仪表板推送：订阅dashboard频道的客户端按频率接收统计和最新告警，数据变化时立即推送
+	// 推送内容按连接用户的数据范围过滤，未登录的连接不能订阅
+	var dashboardPush *websocket.DashboardPusher
+	if cfg.Dashboard.Push.Enabled {
+		dashboardHandler := handlers.NewDashboardHandler(cfg, logger)
+		dashboardPush = websocket.NewDashboardPusher(wsHub, dashboardHandler.DashboardSnapshot, cfg.Dashboard.Push, logger)
+		wsHandler.SetDashboardScopeResolver(dashboardHandler.DashboardScope)
+		services.SetDashboardNotifier(dashboardPush.Notify)
+	}
+
+	// 加载监测因子字典，解析和告警共用
+	if err := factor.Default().Load(database.GetDB()); err != nil {
+		logger.Warn("Failed to load factor dictionary, using builtin factors", zap.Error(err))
+	}
+
 	// 创建告警检测器
-	alarmDetector := alarm.NewDetector(logger, wsHub)
+	alarmDetector := alarm.NewDetector(logger, wsHub, alarm.NewNotifier(cfg.Alarm.Notify, logger), cfg.Alarm.SuppressWindow)
 
 	// 创建HJ212服务器
 	hj212Server := hj212.NewServer(cfg, logger, wsHub, alarmDetector)
+	hj212Server.SetQualityNotifier(alarmDetector)
+	hj212Server.SetStatusNotifier(alarmDetector)
+
+	// 创建数据源健康巡检，连续失败时通过告警检测器告警
+	dsInspector := services.NewDataSourceInspector(logger, cfg.DataSource.HealthCheck)
+	dsInspector.SetAlarmNotifier(alarmDetector)
 
 	return &Server{
-		config:       cfg,
-		logger:       logger,
-		router:       router,
-		hj212Server:  hj212Server,
-		wsHub:        wsHub,
-		wsHandler:    wsHandler,
+		config:        cfg,
+		logger:        logger,
+		router:        router,
+		hj212Server:   hj212Server,
+		alarmDetector: alarmDetector,
+		wsHub:         wsHub,
+		wsHandler:     wsHandler,
+		dashboardPush: dashboardPush,
+		logCleaner:    services.NewLogCleaner(logger, cfg.AuditLog.Cleanup),
+		dsInspector:   dsInspector,
 	}
 }
 
@@ -79,6 +112,7 @@ func (s *Server) SetupMiddleware() {
 	s.router.Use(middleware.RequestID())
 
 	// 限流中间件
+	middleware.SetGlobalRateLimit(s.config.RateLimit)
 	s.router.Use(middleware.RateLimit())
 
 	// 监控中间件
@@ -93,10 +127,11 @@ func (s *Server) SetupMiddleware() {
 // SetupRoutes 设置路由
 func (s *Server) SetupRoutes() {
 	// 设置API路由
-	routes.SetupAPIRoutes(s.router, s.config, s.logger, s.hj212Server)
+	routes.SetupAPIRoutes(s.router, s.config, s.logger, s.hj212Server, s.alarmDetector)
 
 	// 设置WebSocket路由
-	s.router.GET("/ws", s.wsHandler.HandleWebSocket)
+	// 携带令牌的连接解析登录用户，用于仪表板推送的数据范围
+	s.router.GET("/ws", middleware.OptionalAuth(s.config, s.logger), s.wsHandler.HandleWebSocket)
 
 	// 设置健康检查路由
 	s.router.GET("/health", s.healthCheck)
@@ -141,6 +176,15 @@ func (s *Server) Start() error {
 
 	// 启动WebSocket Hub
 	go s.wsHub.Run()
+	if s.dashboardPush != nil {
+		s.dashboardPush.Start()
+	}
+
+	// 启动过期日志自动清理
+	s.logCleaner.Start()
+
+	// 启动数据源健康巡检
+	s.dsInspector.Start()
 
 	// 启动HJ212服务器
 	if s.config.HJ212.Enabled {
@@ -185,12 +229,35 @@ func (s *Server) Stop(ctx context.Context) error {
 		}
 	}
 
+	// 停止日志清理
+	s.logCleaner.Stop()
+
+	// 停止数据源健康巡检
+	s.dsInspector.Stop()
+
+	// 停止仪表板推送
+	if s.dashboardPush != nil {
+		s.dashboardPush.Stop()
+	}
+
 	// 停止HTTP服务器
+	var err error
 	if s.httpServer != nil {
-		return s.httpServer.Shutdown(ctx)
+		err = s.httpServer.Shutdown(ctx)
 	}
 
-	return nil
+	// 刷新缓冲中的操作日志
+	middleware.StopOperationLog(ctx)
+
+	return err
+}
+
+// ApplyConfig 将热加载的配置应用到运行中的组件（限流、告警抑制窗口和通知通道）
+func (s *Server) ApplyConfig(cfg *config.Config) {
+	middleware.SetGlobalRateLimit(cfg.RateLimit)
+
+	s.alarmDetector.SetSuppressWindow(cfg.Alarm.SuppressWindow)
+	s.alarmDetector.SetNotifier(alarm.NewNotifier(cfg.Alarm.Notify, s.logger))
 }
 
 // GetRouter 获取路由器
@@ -211,7 +278,7 @@ func (s *Server) healthCheck(c *gin.Context) {
 // ping 简单ping处理器
 func (s *Server) ping(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
-		"message": "pong",
+		"message":   "pong",
 		"timestamp": time.Now().Unix(),
 	})
-}
\ No newline at end of file
+}
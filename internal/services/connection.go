@@ -40,15 +40,21 @@ func (s *ConnectionTestService) TestConnection(ctx context.Context, dataSource *
 		Details:  make(map[string]interface{}),
 	}
 
-	switch dataSource.Type {
-	case "mysql":
+	switch {
+	case dataSource.IsImported():
+		result = s.testImportConnection(ctx, dataSource, result)
+	case dataSource.Type == "mysql":
 		result = s.testMySQLConnection(ctx, dataSource, result)
-	case "postgresql":
+	case dataSource.Type == "postgresql":
 		result = s.testPostgreSQLConnection(ctx, dataSource, result)
-	case "hj212":
+	case dataSource.Type == "sqlserver":
+		result = s.testSQLServerConnection(ctx, dataSource, result)
+	case dataSource.Type == "hj212":
 		result = s.testHJ212Connection(ctx, dataSource, result)
-	case "api":
+	case dataSource.Type == "api":
 		result = s.testAPIConnection(ctx, dataSource, result)
+	case dataSource.Type == models.DataSourceTypeKafka:
+		result = s.testKafkaConnection(ctx, dataSource, result)
 	default:
 		result.Success = false
 		result.Message = fmt.Sprintf("不支持的数据源类型: %s", dataSource.Type)
@@ -58,6 +64,68 @@ func (s *ConnectionTestService) TestConnection(ctx context.Context, dataSource *
 	return result
 }
 
+// testImportConnection 测试文件导入的数据源，确认平台数据库中的导入表仍可查询
+func (s *ConnectionTestService) testImportConnection(ctx context.Context, dataSource *models.DataSource, result *ConnectionTestResult) *ConnectionTestResult {
+	db, err := openImportDB()
+	if err != nil {
+		result.Success = false
+		result.Message = fmt.Sprintf("MySQL连接失败: %v", err)
+		return result
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var rows int64
+	if err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM `%s`", dataSource.ImportTable)).Scan(&rows); err != nil {
+		result.Success = false
+		result.Message = fmt.Sprintf("查询导入表失败: %v", err)
+		return result
+	}
+
+	result.Details["table"] = dataSource.ImportTable
+	result.Details["row_count"] = rows
+	result.Success = true
+	result.Message = "导入表可用"
+	return result
+}
+
+// testKafkaConnection 测试Kafka连接，查询topic的分区元数据
+func (s *ConnectionTestService) testKafkaConnection(ctx context.Context, dataSource *models.DataSource, result *ConnectionTestResult) *ConnectionTestResult {
+	opts, err := parseKafkaOptions(dataSource, nil)
+	if err != nil {
+		result.Success = false
+		result.Message = err.Error()
+		return result
+	}
+
+	client, err := newKafkaClient(opts)
+	if err != nil {
+		result.Success = false
+		result.Message = fmt.Sprintf("Kafka连接失败: %v", err)
+		return result
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	partitions, err := client.Partitions(ctx, opts.Topic)
+	if err != nil {
+		result.Success = false
+		result.Message = fmt.Sprintf("Kafka连接失败: %v", err)
+		return result
+	}
+
+	result.Details["topic"] = opts.Topic
+	result.Details["partitions"] = len(partitions)
+	result.Details["serialization"] = opts.Format
+	result.Success = true
+	result.Message = "Kafka连接成功"
+	return result
+}
+
 // testMySQLConnection 测试MySQL连接
 func (s *ConnectionTestService) testMySQLConnection(ctx context.Context, dataSource *models.DataSource, result *ConnectionTestResult) *ConnectionTestResult {
 	// 解析配置
@@ -69,14 +137,13 @@ func (s *ConnectionTestService) testMySQLConnection(ctx context.Context, dataSou
 	}
 
 	// 构建MySQL连接字符串
-	host := config["host"].(string)
-	port := int(config["port"].(float64))
-	username := config["username"].(string)
-	password := config["password"].(string)
-	database := config["database"].(string)
-
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true&charset=utf8mb4",
-		username, password, host, port, database)
+	connConfig, err := parseDBConnConfig(config)
+	if err != nil {
+		result.Success = false
+		result.Message = fmt.Sprintf("解析配置失败: %v", err)
+		return result
+	}
+	dsn := connConfig.mysqlDSN()
 
 	// 测试连接
 	db, err := sql.Open("mysql", dsn)
@@ -107,7 +174,7 @@ func (s *ConnectionTestService) testMySQLConnection(ctx context.Context, dataSou
 	// 获取数据库状态
 	var dbSize int64
 	query := "SELECT ROUND(SUM(data_length + index_length) / 1024 / 1024, 1) as db_size FROM information_schema.tables WHERE table_schema = ?"
-	if err := db.QueryRowContext(ctx, query, database).Scan(&dbSize); err == nil {
+	if err := db.QueryRowContext(ctx, query, connConfig.Database).Scan(&dbSize); err == nil {
 		result.Details["database_size_mb"] = dbSize
 	}
 
@@ -127,14 +194,13 @@ func (s *ConnectionTestService) testPostgreSQLConnection(ctx context.Context, da
 	}
 
 	// 构建PostgreSQL连接字符串
-	host := config["host"].(string)
-	port := int(config["port"].(float64))
-	username := config["username"].(string)
-	password := config["password"].(string)
-	database := config["database"].(string)
-
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		host, port, username, password, database)
+	connConfig, err := parseDBConnConfig(config)
+	if err != nil {
+		result.Success = false
+		result.Message = fmt.Sprintf("解析配置失败: %v", err)
+		return result
+	}
+	dsn := connConfig.postgresDSN()
 
 	// 测试连接
 	db, err := sql.Open("postgres", dsn)
@@ -165,7 +231,7 @@ func (s *ConnectionTestService) testPostgreSQLConnection(ctx context.Context, da
 	// 获取数据库大小
 	var dbSize int64
 	query := "SELECT pg_database_size($1)"
-	if err := db.QueryRowContext(ctx, query, database).Scan(&dbSize); err == nil {
+	if err := db.QueryRowContext(ctx, query, connConfig.Database).Scan(&dbSize); err == nil {
 		result.Details["database_size_bytes"] = dbSize
 		result.Details["database_size_mb"] = dbSize / 1024 / 1024
 	}
@@ -175,6 +241,55 @@ func (s *ConnectionTestService) testPostgreSQLConnection(ctx context.Context, da
 	return result
 }
 
+// testSQLServerConnection 测试SQL Server连接
+func (s *ConnectionTestService) testSQLServerConnection(ctx context.Context, dataSource *models.DataSource, result *ConnectionTestResult) *ConnectionTestResult {
+	// 解析配置
+	var config map[string]interface{}
+	if err := json.Unmarshal(dataSource.ConfigData, &config); err != nil {
+		result.Success = false
+		result.Message = fmt.Sprintf("解析配置失败: %v", err)
+		return result
+	}
+
+	// 测试连接
+	db, err := openSQLServer(config)
+	if err != nil {
+		result.Success = false
+		result.Message = fmt.Sprintf("SQL Server连接失败: %v", err)
+		return result
+	}
+	defer db.Close()
+
+	// 设置连接超时
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	// 验证连接
+	if err := db.PingContext(ctx); err != nil {
+		result.Success = false
+		result.Message = fmt.Sprintf("SQL Server ping失败: %v", err)
+		return result
+	}
+
+	// 获取数据库版本信息
+	var version string
+	if err := db.QueryRowContext(ctx, "SELECT @@VERSION").Scan(&version); err == nil {
+		result.Details["version"] = version
+	}
+
+	// 获取数据库大小（数据文件页数，每页8KB）
+	var dbSize int64
+	query := "SELECT CAST(SUM(size) AS BIGINT) * 8 * 1024 FROM sys.database_files"
+	if err := db.QueryRowContext(ctx, query).Scan(&dbSize); err == nil {
+		result.Details["database_size_bytes"] = dbSize
+		result.Details["database_size_mb"] = dbSize / 1024 / 1024
+	}
+
+	result.Success = true
+	result.Message = "SQL Server连接成功"
+	return result
+}
+
 // testHJ212Connection 测试HJ212设备连接
 func (s *ConnectionTestService) testHJ212Connection(ctx context.Context, dataSource *models.DataSource, result *ConnectionTestResult) *ConnectionTestResult {
 	// 解析配置
@@ -185,8 +300,18 @@ func (s *ConnectionTestService) testHJ212Connection(ctx context.Context, dataSou
 		return result
 	}
 
-	host := config["host"].(string)
-	port := int(config["port"].(float64))
+	host, err := configString(config, "host", true)
+	if err != nil {
+		result.Success = false
+		result.Message = fmt.Sprintf("解析配置失败: %v", err)
+		return result
+	}
+	port, err := configPort(config, "port", true)
+	if err != nil {
+		result.Success = false
+		result.Message = fmt.Sprintf("解析配置失败: %v", err)
+		return result
+	}
 
 	// 测试TCP连接
 	address := fmt.Sprintf("%s:%d", host, port)
@@ -227,7 +352,12 @@ func (s *ConnectionTestService) testAPIConnection(ctx context.Context, dataSourc
 		return result
 	}
 
-	url := config["url"].(string)
+	url, err := configString(config, "url", true)
+	if err != nil {
+		result.Success = false
+		result.Message = fmt.Sprintf("解析配置失败: %v", err)
+		return result
+	}
 	method := "GET"
 	if m, ok := config["method"].(string); ok {
 		method = m
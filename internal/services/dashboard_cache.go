@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/env-data-platform/internal/database"
+)
+
+// dashboardCacheKeyPrefix 仪表板缓存key前缀
+const dashboardCacheKeyPrefix = "dashboard:"
+
+// dashboardCacheRequests 仪表板缓存命中统计
+var dashboardCacheRequests = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "dashboard_cache_requests_total",
+		Help: "Total number of dashboard cache lookups by result",
+	},
+	[]string{"result"}, // hit, miss, error
+)
+
+// 仪表板数据变化来源
+const (
+	DashboardTriggerUpdate = "update" // 数据源、作业、告警等通过接口变更
+	DashboardTriggerETL    = "etl"    // ETL执行开始或结束
+
+	DashboardTriggerHealthCheck = "health_check" // 数据源巡检发现连接状态变化
+)
+
+// dashboardNotifier 仪表板数据变化时的推送回调
+var dashboardNotifier atomic.Value // func(trigger string)
+
+// SetDashboardNotifier 设置仪表板数据变化时的推送回调，由WebSocket推送在启动时注册
+func SetDashboardNotifier(notify func(trigger string)) {
+	dashboardNotifier.Store(notify)
+}
+
+// NotifyDashboardChange 通知仪表板数据已变化，trigger说明变化来源，未注册回调时忽略
+func NotifyDashboardChange(trigger string) {
+	if notify, ok := dashboardNotifier.Load().(func(string)); ok && notify != nil {
+		notify(trigger)
+	}
+}
+
+// DashboardCache 仪表板数据Redis缓存
+type DashboardCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewDashboardCache 创建仪表板缓存，client为nil或ttl<=0时缓存不生效
+func NewDashboardCache(client *redis.Client, ttl time.Duration) *DashboardCache {
+	return &DashboardCache{
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+// Enabled 缓存是否生效
+func (c *DashboardCache) Enabled() bool {
+	return c.client != nil && c.ttl > 0
+}
+
+// Get 读取缓存到dest，返回是否命中
+func (c *DashboardCache) Get(ctx context.Context, key string, dest interface{}) (bool, error) {
+	if !c.Enabled() {
+		return false, nil
+	}
+
+	data, err := c.client.Get(ctx, dashboardCacheKeyPrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		dashboardCacheRequests.WithLabelValues("miss").Inc()
+		return false, nil
+	}
+	if err != nil {
+		dashboardCacheRequests.WithLabelValues("error").Inc()
+		return false, fmt.Errorf("读取仪表板缓存失败: %w", err)
+	}
+
+	if err := json.Unmarshal(data, dest); err != nil {
+		dashboardCacheRequests.WithLabelValues("error").Inc()
+		return false, fmt.Errorf("解析仪表板缓存失败: %w", err)
+	}
+
+	dashboardCacheRequests.WithLabelValues("hit").Inc()
+	return true, nil
+}
+
+// Set 写入缓存
+func (c *DashboardCache) Set(ctx context.Context, key string, value interface{}) error {
+	if !c.Enabled() {
+		return nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("序列化仪表板缓存失败: %w", err)
+	}
+	return c.client.Set(ctx, dashboardCacheKeyPrefix+key, data, c.ttl).Err()
+}
+
+// InvalidateDashboardCache 底层数据变更时清除全部仪表板缓存，Redis未启用时直接返回
+func InvalidateDashboardCache(ctx context.Context) error {
+	client := database.GetRedis()
+	if client == nil {
+		return nil
+	}
+
+	iter := client.Scan(ctx, 0, dashboardCacheKeyPrefix+"*", 100).Iterator()
+	var keys []string
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("查找仪表板缓存失败: %w", err)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return client.Del(ctx, keys...).Err()
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/env-data-platform/internal/database"
+)
+
+// newTestRedis 启动内存Redis并替换全局客户端，测试结束后恢复
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	previous := database.Redis
+	database.Redis = client
+	t.Cleanup(func() { database.Redis = previous })
+	return mr, client
+}
+
+type cachedOverview struct {
+	Total int `json:"total"`
+}
+
+func TestDashboardCacheHitMissAndExpiry(t *testing.T) {
+	mr, client := newTestRedis(t)
+	ctx := context.Background()
+	cache := NewDashboardCache(client, time.Minute)
+
+	hits := testutil.ToFloat64(dashboardCacheRequests.WithLabelValues("hit"))
+	misses := testutil.ToFloat64(dashboardCacheRequests.WithLabelValues("miss"))
+
+	var got cachedOverview
+	found, err := cache.Get(ctx, "overview:today", &got)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	require.NoError(t, cache.Set(ctx, "overview:today", cachedOverview{Total: 3}))
+	assert.True(t, mr.Exists(dashboardCacheKeyPrefix+"overview:today"))
+	assert.Equal(t, time.Minute, mr.TTL(dashboardCacheKeyPrefix+"overview:today"))
+
+	found, err = cache.Get(ctx, "overview:today", &got)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 3, got.Total)
+
+	// 超过TTL后重新计算
+	mr.FastForward(time.Minute + time.Second)
+	found, err = cache.Get(ctx, "overview:today", &got)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	assert.Equal(t, hits+1, testutil.ToFloat64(dashboardCacheRequests.WithLabelValues("hit")))
+	assert.Equal(t, misses+2, testutil.ToFloat64(dashboardCacheRequests.WithLabelValues("miss")))
+}
+
+func TestDashboardCacheErrors(t *testing.T) {
+	mr, client := newTestRedis(t)
+	ctx := context.Background()
+	cache := NewDashboardCache(client, time.Minute)
+
+	// 缓存内容损坏时视为未命中并返回错误
+	require.NoError(t, mr.Set(dashboardCacheKeyPrefix+"overview:today", "{"))
+	var got cachedOverview
+	found, err := cache.Get(ctx, "overview:today", &got)
+	assert.Error(t, err)
+	assert.False(t, found)
+
+	// 未配置Redis或TTL时缓存不生效
+	for _, disabled := range []*DashboardCache{NewDashboardCache(nil, time.Minute), NewDashboardCache(client, 0)} {
+		assert.False(t, disabled.Enabled())
+		require.NoError(t, disabled.Set(ctx, "overview:week", cachedOverview{Total: 1}))
+		found, err = disabled.Get(ctx, "overview:week", &got)
+		require.NoError(t, err)
+		assert.False(t, found)
+	}
+	assert.False(t, mr.Exists(dashboardCacheKeyPrefix+"overview:week"))
+}
+
+func TestInvalidateDashboardCache(t *testing.T) {
+	mr, client := newTestRedis(t)
+	ctx := context.Background()
+	cache := NewDashboardCache(client, time.Minute)
+
+	// 超过单次SCAN数量的缓存也要全部清除
+	for i := 0; i < 250; i++ {
+		require.NoError(t, cache.Set(ctx, fmt.Sprintf("overview:%d", i), cachedOverview{Total: i}))
+	}
+	require.NoError(t, mr.Set("session:1", "keep"))
+
+	require.NoError(t, InvalidateDashboardCache(ctx))
+	assert.Equal(t, []string{"session:1"}, mr.Keys())
+
+	// 没有缓存时不报错
+	require.NoError(t, InvalidateDashboardCache(ctx))
+
+	database.Redis = nil
+	assert.NoError(t, InvalidateDashboardCache(ctx))
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/models"
+	"gorm.io/gorm"
+)
+
+// ResolvedDataScope 用户最终生效的数据权限范围
+type ResolvedDataScope struct {
+	Unrestricted bool     `json:"unrestricted"` // 不限制数据范围
+	DeviceIDs    []string `json:"device_ids"`   // 可见设备MN（已包含区域下的设备）
+	Regions      []string `json:"regions"`      // 可见区域
+}
+
+// DataScopeService 数据权限范围服务
+type DataScopeService struct {
+	db *gorm.DB
+}
+
+// NewDataScopeService 创建数据权限范围服务
+func NewDataScopeService() *DataScopeService {
+	return &DataScopeService{
+		db: database.GetDB(),
+	}
+}
+
+// Resolve 计算用户生效的数据范围
+// 超级管理员不受限；用户级配置优先，否则合并用户所有启用角色的配置；均未配置时不受限
+func (s *DataScopeService) Resolve(userID uint) (*ResolvedDataScope, error) {
+	var roles []models.Role
+	if err := s.db.Table("env_roles").
+		Joins("JOIN env_user_roles ON env_roles.id = env_user_roles.role_id").
+		Where("env_user_roles.user_id = ? AND env_roles.status = 1 AND env_roles.deleted_at IS NULL", userID).
+		Select("env_roles.id, env_roles.code").
+		Find(&roles).Error; err != nil {
+		return nil, fmt.Errorf("查询用户角色失败: %w", err)
+	}
+
+	roleIDs := make([]uint, 0, len(roles))
+	for _, role := range roles {
+		if role.Code == models.RoleAdmin {
+			return &ResolvedDataScope{Unrestricted: true}, nil
+		}
+		roleIDs = append(roleIDs, role.ID)
+	}
+
+	var scopes []models.DataScope
+	if err := s.db.Where("subject_type = ? AND subject_id = ?", models.DataScopeSubjectUser, userID).
+		Find(&scopes).Error; err != nil {
+		return nil, fmt.Errorf("查询用户数据权限失败: %w", err)
+	}
+	if len(scopes) == 0 && len(roleIDs) > 0 {
+		if err := s.db.Where("subject_type = ? AND subject_id IN ?", models.DataScopeSubjectRole, roleIDs).
+			Find(&scopes).Error; err != nil {
+			return nil, fmt.Errorf("查询角色数据权限失败: %w", err)
+		}
+	}
+	if len(scopes) == 0 {
+		return &ResolvedDataScope{Unrestricted: true}, nil
+	}
+
+	resolved := &ResolvedDataScope{}
+	for _, scope := range scopes {
+		resolved.DeviceIDs = appendUnique(resolved.DeviceIDs, SplitScopeValues(scope.DeviceIDs)...)
+		resolved.Regions = appendUnique(resolved.Regions, SplitScopeValues(scope.Regions)...)
+	}
+
+	// 区域通过数据源的所属区域展开为设备MN，便于直接过滤监测数据
+	if len(resolved.Regions) > 0 {
+		var regionDevices []string
+		if err := s.db.Model(&models.DataSource{}).
+			Where("region IN ? AND device_id <> ''", resolved.Regions).
+			Distinct().
+			Pluck("device_id", &regionDevices).Error; err != nil {
+			return nil, fmt.Errorf("查询区域设备失败: %w", err)
+		}
+		resolved.DeviceIDs = appendUnique(resolved.DeviceIDs, regionDevices...)
+	}
+
+	return resolved, nil
+}
+
+// GetScope 获取角色或用户的数据范围配置，未配置时返回nil
+func (s *DataScopeService) GetScope(subjectType string, subjectID uint) (*models.DataScope, error) {
+	var scope models.DataScope
+	err := s.db.Where("subject_type = ? AND subject_id = ?", subjectType, subjectID).First(&scope).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &scope, nil
+}
+
+// SaveScope 保存角色或用户的数据范围配置
+func (s *DataScopeService) SaveScope(subjectType string, subjectID uint, deviceIDs, regions []string) (*models.DataScope, error) {
+	scope, err := s.GetScope(subjectType, subjectID)
+	if err != nil {
+		return nil, err
+	}
+	if scope == nil {
+		scope = &models.DataScope{SubjectType: subjectType, SubjectID: subjectID}
+	}
+	scope.DeviceIDs = JoinScopeValues(deviceIDs)
+	scope.Regions = JoinScopeValues(regions)
+
+	if err := s.db.Save(scope).Error; err != nil {
+		return nil, err
+	}
+	return scope, nil
+}
+
+// DeleteScope 删除角色或用户的数据范围配置，删除后回退到上级配置
+func (s *DataScopeService) DeleteScope(subjectType string, subjectID uint) error {
+	return s.db.Unscoped().
+		Where("subject_type = ? AND subject_id = ?", subjectType, subjectID).
+		Delete(&models.DataScope{}).Error
+}
+
+// SplitScopeValues 拆分逗号分隔的范围值，去除空白和重复项
+func SplitScopeValues(value string) []string {
+	var values []string
+	for _, item := range strings.Split(value, ",") {
+		values = appendUnique(values, strings.TrimSpace(item))
+	}
+	return values
+}
+
+// JoinScopeValues 将范围值合并为逗号分隔字符串
+func JoinScopeValues(values []string) string {
+	var cleaned []string
+	for _, value := range values {
+		cleaned = appendUnique(cleaned, strings.TrimSpace(value))
+	}
+	return strings.Join(cleaned, ",")
+}
+
+// appendUnique 追加非空且未出现过的值
+func appendUnique(values []string, items ...string) []string {
+	for _, item := range items {
+		if item == "" {
+			continue
+		}
+		exists := false
+		for _, value := range values {
+			if value == item {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			values = append(values, item)
+		}
+	}
+	return values
+}
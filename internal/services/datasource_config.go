@@ -0,0 +1,191 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/env-data-platform/internal/config"
+	"github.com/env-data-platform/internal/models"
+)
+
+// dbConnConfig 数据库类数据源的连接参数
+type dbConnConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	Database string
+}
+
+// parseDBConnConfig 从数据源配置中读取连接参数。host、port、username、database必填，password可为空；
+// 配置由前端或接口写入，port可能是数字也可能是字符串，统一转换而不是直接断言
+func parseDBConnConfig(config map[string]interface{}) (*dbConnConfig, error) {
+	var c dbConnConfig
+	var err error
+	if c.Host, err = configString(config, "host", true); err != nil {
+		return nil, err
+	}
+	if c.Port, err = configPort(config, "port", true); err != nil {
+		return nil, err
+	}
+	if c.Username, err = configString(config, "username", true); err != nil {
+		return nil, err
+	}
+	if c.Password, err = configString(config, "password", false); err != nil {
+		return nil, err
+	}
+	if c.Database, err = configString(config, "database", true); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// ErrImportTableOnly 文件导入的数据源访问了导入表以外的表或使用了自定义查询
+var ErrImportTableOnly = errors.New("文件导入的数据源只能访问导入的数据表")
+
+// CheckImportTable 文件导入的数据源只能访问导入时创建的表，其他数据源不限制
+func CheckImportTable(dataSource *models.DataSource, table string) error {
+	if dataSource == nil || !dataSource.IsImported() || table == dataSource.ImportTable {
+		return nil
+	}
+	return fmt.Errorf("%w %s", ErrImportTableOnly, dataSource.ImportTable)
+}
+
+// openImportDB 打开文件导入数据源的连接。导入表存放在平台数据库中，连接参数取自平台配置，不保存在数据源配置里，
+// 调用方须先用 CheckImportTable 限制可访问的表
+func openImportDB() (*sql.DB, error) {
+	if config.GlobalConfig == nil {
+		return nil, errors.New("平台数据库未配置")
+	}
+	return sql.Open("mysql", config.GlobalConfig.Database.GetDSN())
+}
+
+// openDataSourceDB 按数据源配置打开关系型数据库连接（MySQL、PostgreSQL、SQL Server、Oracle），调用方负责关闭
+func openDataSourceDB(dataSource *models.DataSource) (*sql.DB, error) {
+	if dataSource == nil {
+		return nil, fmt.Errorf("数据源不存在")
+	}
+	if dataSource.IsImported() {
+		return openImportDB()
+	}
+
+	var config map[string]interface{}
+	if err := json.Unmarshal(dataSource.ConfigData, &config); err != nil {
+		return nil, fmt.Errorf("解析数据源配置失败: %v", err)
+	}
+
+	switch dataSource.Type {
+	case "mysql":
+		connConfig, err := parseDBConnConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		return sql.Open("mysql", connConfig.mysqlDSN())
+	case "postgresql":
+		connConfig, err := parseDBConnConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		return sql.Open("postgres", connConfig.postgresDSN())
+	case "sqlserver":
+		return openSQLServer(config)
+	case "oracle":
+		return openOracle(config)
+	default:
+		return nil, fmt.Errorf("不支持的数据源类型: %s", dataSource.Type)
+	}
+}
+
+// mysqlDSN MySQL连接字符串
+func (c *dbConnConfig) mysqlDSN() string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true&charset=utf8mb4",
+		c.Username, c.Password, c.Host, c.Port, c.Database)
+}
+
+// postgresDSN PostgreSQL连接字符串
+func (c *dbConnConfig) postgresDSN() string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+		c.Host, c.Port, c.Username, c.Password, c.Database)
+}
+
+// configString 读取字符串配置，数字按原样转为字符串（如纯数字的密码）；required为true时缺失或为空返回错误
+func configString(config map[string]interface{}, key string, required bool) (string, error) {
+	var s string
+	switch v := config[key].(type) {
+	case nil:
+	case string:
+		s = v
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		s = v.String()
+	case int:
+		s = strconv.Itoa(v)
+	default:
+		return "", fmt.Errorf("数据源配置%s类型无效: %T", key, v)
+	}
+	if required && strings.TrimSpace(s) == "" {
+		return "", fmt.Errorf("数据源配置缺少%s", key)
+	}
+	return s, nil
+}
+
+// configInt 读取整数配置，支持数字和数字字符串；缺失时返回0，required为true时返回错误
+func configInt(config map[string]interface{}, key string, required bool) (int, error) {
+	var n int
+	switch v := config[key].(type) {
+	case nil:
+		if required {
+			return 0, fmt.Errorf("数据源配置缺少%s", key)
+		}
+		return 0, nil
+	case float64:
+		if v != float64(int(v)) {
+			return 0, fmt.Errorf("数据源配置%s必须为整数: %v", key, v)
+		}
+		n = int(v)
+	case int:
+		n = v
+	case json.Number:
+		i, err := strconv.Atoi(v.String())
+		if err != nil {
+			return 0, fmt.Errorf("数据源配置%s必须为整数: %s", key, v)
+		}
+		n = i
+	case string:
+		s := strings.TrimSpace(v)
+		if s == "" {
+			if required {
+				return 0, fmt.Errorf("数据源配置缺少%s", key)
+			}
+			return 0, nil
+		}
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, fmt.Errorf("数据源配置%s必须为整数: %q", key, v)
+		}
+		n = i
+	default:
+		return 0, fmt.Errorf("数据源配置%s类型无效: %T", key, v)
+	}
+	return n, nil
+}
+
+// configPort 读取端口配置并校验范围，未配置且非必填时返回0
+func configPort(config map[string]interface{}, key string, required bool) (int, error) {
+	port, err := configInt(config, key, required)
+	if err != nil {
+		return 0, err
+	}
+	if port == 0 && !required {
+		return 0, nil
+	}
+	if port < 1 || port > 65535 {
+		return 0, fmt.Errorf("数据源配置%s必须在1-65535之间，当前为%d", key, port)
+	}
+	return port, nil
+}
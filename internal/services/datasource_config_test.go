@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/env-data-platform/internal/models"
+)
+
+func TestParseDBConnConfig(t *testing.T) {
+	// port写成字符串、密码为纯数字时都能正确解析
+	var config map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{"host":"10.0.0.5","port":"3306","username":"root","password":123456,"database":"env"}`), &config))
+	conn, err := parseDBConnConfig(config)
+	require.NoError(t, err)
+	assert.Equal(t, 3306, conn.Port)
+	assert.Equal(t, "123456", conn.Password)
+	assert.Equal(t, "root:123456@tcp(10.0.0.5:3306)/env?parseTime=true&charset=utf8mb4", conn.mysqlDSN())
+	assert.Equal(t, "host=10.0.0.5 port=3306 user=root password=123456 dbname=env sslmode=disable", conn.postgresDSN())
+
+	config["port"] = float64(5432)
+	delete(config, "password")
+	conn, err = parseDBConnConfig(config)
+	require.NoError(t, err)
+	assert.Equal(t, 5432, conn.Port)
+	assert.Empty(t, conn.Password)
+
+	for _, tc := range []struct {
+		name   string
+		modify func(map[string]interface{})
+		err    string
+	}{
+		{"缺少host", func(c map[string]interface{}) { delete(c, "host") }, "数据源配置缺少host"},
+		{"空用户名", func(c map[string]interface{}) { c["username"] = " " }, "数据源配置缺少username"},
+		{"端口非数字", func(c map[string]interface{}) { c["port"] = "abc" }, `数据源配置port必须为整数: "abc"`},
+		{"端口为小数", func(c map[string]interface{}) { c["port"] = 3306.5 }, "数据源配置port必须为整数: 3306.5"},
+		{"端口越界", func(c map[string]interface{}) { c["port"] = float64(70000) }, "数据源配置port必须在1-65535之间，当前为70000"},
+		{"类型无效", func(c map[string]interface{}) { c["database"] = []interface{}{"env"} }, "数据源配置database类型无效: []interface {}"},
+	} {
+		c := map[string]interface{}{"host": "h", "port": float64(3306), "username": "u", "database": "d"}
+		tc.modify(c)
+		_, err := parseDBConnConfig(c)
+		assert.EqualError(t, err, tc.err, tc.name)
+	}
+}
+
+func TestImportDataSourceRestrictedToImportTable(t *testing.T) {
+	imported := &models.DataSource{Type: "mysql", ImportTable: "import_sensor_12", ConfigData: []byte(`{}`)}
+	previews := NewDataPreviewService()
+
+	// 预览：平台数据库中的其他表和自定义查询都被拒绝，且在建立连接之前拒绝
+	for _, req := range []DataPreviewRequest{
+		{Table: "env_users"},
+		{Table: "env.import_sensor_12"},
+		{Query: "SELECT password FROM env_users"},
+		{Query: "SELECT * FROM import_sensor_12"},
+	} {
+		_, err := previews.Preview(context.Background(), imported, &req)
+		assert.ErrorIs(t, err, ErrInvalidPreviewRequest, "%+v", req)
+	}
+	_, err := previews.Preview(context.Background(), imported, &DataPreviewRequest{Table: "import_sensor_12"})
+	assert.NotErrorIs(t, err, ErrInvalidPreviewRequest)
+
+	// ETL导出与写入
+	assert.ErrorIs(t, checkImportSourceConfig(imported, map[string]interface{}{"table": "env_users"}), ErrImportTableOnly)
+	assert.ErrorIs(t, checkImportSourceConfig(imported, map[string]interface{}{"query": "SELECT * FROM env_users"}), ErrImportTableOnly)
+	assert.NoError(t, checkImportSourceConfig(imported, map[string]interface{}{"table": "import_sensor_12"}))
+	assert.ErrorIs(t, CheckImportTable(imported, "env_roles"), ErrImportTableOnly)
+
+	// 质量检查：其他表、拼入SQL的列名和关联其他表的检查
+	rules := []models.QualityRule{
+		{Type: "completeness", TargetTable: "env_users", ColumnName: "password", DataSource: imported},
+		{Type: "completeness", TargetTable: "import_sensor_12", ColumnName: "(SELECT password FROM env_users LIMIT 1)", DataSource: imported},
+		{Type: "freshness", TargetTable: "import_sensor_12", RuleConfig: `{"time_column":"(SELECT 1 FROM env_users)"}`, DataSource: imported},
+		{Type: "referential", TargetTable: "import_sensor_12", ColumnName: "user_id", DataSource: imported},
+	}
+	for _, rule := range rules {
+		assert.ErrorIs(t, checkImportRule(&rule), ErrImportTableOnly, "%+v", rule)
+	}
+	assert.NoError(t, checkImportRule(&models.QualityRule{Type: "completeness", TargetTable: "import_sensor_12", ColumnName: "pm25", DataSource: imported}))
+
+	// 普通数据源不受限制
+	plain := &models.DataSource{Type: "mysql"}
+	assert.NoError(t, CheckImportTable(plain, "env_users"))
+	assert.NoError(t, checkImportSourceConfig(plain, map[string]interface{}{"query": "SELECT 1"}))
+}
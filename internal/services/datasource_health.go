@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/config"
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/models"
+)
+
+// inspectableDataSourceTypes 参与健康巡检的数据源类型；HJ212设备主动上报，
+// 在线状态由设备心跳维护，文件类数据源没有可检查的连接
+var inspectableDataSourceTypes = []string{"mysql", "postgresql", "sqlserver", models.DataSourceTypeAPI, models.DataSourceTypeKafka}
+
+// DataSourceHealthNotifier 数据源连续巡检失败和恢复时的告警接口，由告警检测器实现
+type DataSourceHealthNotifier interface {
+	// failures 告警时为连续失败次数，恢复时为恢复前的连续失败次数
+	DataSourceHealthChanged(dataSource *models.DataSource, healthy bool, failures int, message string)
+}
+
+// connectionTester 数据源连通性检查，便于测试替换
+type connectionTester interface {
+	TestConnection(ctx context.Context, dataSource *models.DataSource) *ConnectionTestResult
+}
+
+// healthTransition 一次巡检后需要发出的告警
+type healthTransition int
+
+const (
+	healthUnchanged  healthTransition = iota
+	healthBecameDown                  // 连续失败刚达到阈值
+	healthRecovered                   // 达到阈值后恢复
+)
+
+// evaluateHealth 根据上次的连续失败次数和本次结果计算新的失败次数、健康状态和告警，
+// 连续失败达到阈值时只告警一次，恢复后再发恢复通知
+func evaluateHealth(previousFailures int, success bool, threshold int) (int, string, healthTransition) {
+	if success {
+		if previousFailures >= threshold {
+			return 0, models.DataSourceHealthHealthy, healthRecovered
+		}
+		return 0, models.DataSourceHealthHealthy, healthUnchanged
+	}
+
+	failures := previousFailures + 1
+	switch {
+	case failures == threshold:
+		return failures, models.DataSourceHealthUnhealthy, healthBecameDown
+	case failures > threshold:
+		return failures, models.DataSourceHealthUnhealthy, healthUnchanged
+	default:
+		return failures, models.DataSourceHealthDegraded, healthUnchanged
+	}
+}
+
+// DataSourceInspector 定期检查数据源连通性，更新连接状态和连续失败次数，连续失败达到阈值时告警
+type DataSourceInspector struct {
+	logger   *zap.Logger
+	db       *gorm.DB
+	cfg      config.DataSourceHealthCheckConfig
+	tester   connectionTester
+	notifier DataSourceHealthNotifier
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewDataSourceInspector 创建数据源健康巡检器
+func NewDataSourceInspector(logger *zap.Logger, cfg config.DataSourceHealthCheckConfig) *DataSourceInspector {
+	return &DataSourceInspector{
+		logger: logger,
+		db:     database.GetDB(),
+		cfg:    cfg,
+		tester: NewConnectionTestService(),
+		stop:   make(chan struct{}),
+	}
+}
+
+// SetAlarmNotifier 设置告警通知，为空时只记录日志
+func (i *DataSourceInspector) SetAlarmNotifier(notifier DataSourceHealthNotifier) {
+	i.notifier = notifier
+}
+
+// Start 启动后台巡检，未启用时直接返回
+func (i *DataSourceInspector) Start() {
+	if !i.cfg.Enabled || i.cfg.Interval <= 0 {
+		i.logger.Info("Data source health check is disabled")
+		return
+	}
+
+	go i.run()
+	i.logger.Info("Data source health check started",
+		zap.Duration("interval", i.cfg.Interval),
+		zap.Duration("timeout", i.cfg.Timeout),
+		zap.Int("failure_threshold", i.cfg.FailureThreshold))
+}
+
+// Stop 停止后台巡检
+func (i *DataSourceInspector) Stop() {
+	i.stopOnce.Do(func() {
+		close(i.stop)
+	})
+}
+
+// run 启动后先巡检一次，之后按周期巡检
+func (i *DataSourceInspector) run() {
+	ticker := time.NewTicker(i.cfg.Interval)
+	defer ticker.Stop()
+
+	i.InspectAll()
+	for {
+		select {
+		case <-ticker.C:
+			i.InspectAll()
+		case <-i.stop:
+			return
+		}
+	}
+}
+
+// InspectAll 并发检查所有未停用的数据源，返回检查的数据源数
+func (i *DataSourceInspector) InspectAll() int {
+	if i.db == nil {
+		return 0
+	}
+
+	var dataSources []models.DataSource
+	if err := i.db.Where("type IN ? AND status <> ?", inspectableDataSourceTypes, models.DataSourceStatusDisabled).
+		Find(&dataSources).Error; err != nil {
+		i.logger.Error("Failed to load data sources for health check", zap.Error(err))
+		return 0
+	}
+
+	concurrency := i.cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var changed int32
+	var wg sync.WaitGroup
+	for idx := range dataSources {
+		select {
+		case <-i.stop:
+			wg.Wait()
+			return idx
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(ds *models.DataSource) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			wasConnected := ds.IsConnected
+			if result := i.Inspect(ds); result.Success != wasConnected {
+				atomic.AddInt32(&changed, 1)
+			}
+		}(&dataSources[idx])
+	}
+	wg.Wait()
+
+	if atomic.LoadInt32(&changed) > 0 {
+		NotifyDashboardChange(DashboardTriggerHealthCheck)
+	}
+	return len(dataSources)
+}
+
+// Inspect 检查单个数据源并写回健康状态
+func (i *DataSourceInspector) Inspect(dataSource *models.DataSource) *ConnectionTestResult {
+	timeout := i.cfg.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	result := i.tester.TestConnection(ctx, dataSource)
+	dataSourceHealthChecksTotal.WithLabelValues(dataSource.Type, healthCheckResult(result.Success)).Inc()
+
+	previousFailures := dataSource.ErrorCount
+	failures, health, transition := evaluateHealth(previousFailures, result.Success, i.cfg.FailureThreshold)
+	updates := map[string]interface{}{
+		"last_test_at":  result.TestedAt,
+		"is_connected":  result.Success,
+		"error_count":   failures,
+		"health_status": health,
+	}
+	if result.Success {
+		updates["status"] = models.DataSourceStatusActive
+	} else {
+		updates["status"] = models.DataSourceStatusInactive
+		updates["last_error"] = result.Message
+		i.logger.Warn("Data source health check failed",
+			zap.Uint("datasource_id", dataSource.ID),
+			zap.String("name", dataSource.Name),
+			zap.Int("consecutive_failures", failures),
+			zap.String("error", result.Message))
+	}
+	if i.db != nil {
+		if err := i.db.Model(&models.DataSource{}).Where("id = ?", dataSource.ID).Updates(updates).Error; err != nil {
+			i.logger.Error("Failed to update data source health",
+				zap.Uint("datasource_id", dataSource.ID),
+				zap.Error(err))
+		}
+	}
+
+	dataSource.ErrorCount = failures
+	dataSource.HealthStatus = health
+	dataSource.IsConnected = result.Success
+	dataSource.LastTestAt = &result.TestedAt
+	if !result.Success {
+		dataSource.LastError = result.Message
+	}
+
+	if transition != healthUnchanged && i.notifier != nil {
+		if transition == healthRecovered {
+			i.notifier.DataSourceHealthChanged(dataSource, true, previousFailures, result.Message)
+		} else {
+			i.notifier.DataSourceHealthChanged(dataSource, false, failures, result.Message)
+		}
+	}
+	return result
+}
+
+// healthCheckResult 巡检结果的指标标签
+func healthCheckResult(success bool) string {
+	if success {
+		return "success"
+	}
+	return "failed"
+}
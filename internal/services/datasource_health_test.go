@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/env-data-platform/internal/config"
+	"github.com/env-data-platform/internal/models"
+)
+
+func TestEvaluateHealth(t *testing.T) {
+	tests := []struct {
+		name       string
+		previous   int
+		success    bool
+		failures   int
+		health     string
+		transition healthTransition
+	}{
+		{"正常", 0, true, 0, models.DataSourceHealthHealthy, healthUnchanged},
+		{"首次失败", 0, false, 1, models.DataSourceHealthDegraded, healthUnchanged},
+		{"达到阈值告警", 2, false, 3, models.DataSourceHealthUnhealthy, healthBecameDown},
+		{"超过阈值不重复告警", 3, false, 4, models.DataSourceHealthUnhealthy, healthUnchanged},
+		{"未达阈值时恢复不通知", 2, true, 0, models.DataSourceHealthHealthy, healthUnchanged},
+		{"告警后恢复", 5, true, 0, models.DataSourceHealthHealthy, healthRecovered},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failures, health, transition := evaluateHealth(tt.previous, tt.success, 3)
+			assert.Equal(t, tt.failures, failures)
+			assert.Equal(t, tt.health, health)
+			assert.Equal(t, tt.transition, transition)
+		})
+	}
+}
+
+type fakeConnectionTester struct {
+	success bool
+}
+
+func (f *fakeConnectionTester) TestConnection(ctx context.Context, dataSource *models.DataSource) *ConnectionTestResult {
+	return &ConnectionTestResult{Success: f.success, Message: "connection refused", TestedAt: time.Now()}
+}
+
+type healthEvent struct {
+	healthy  bool
+	failures int
+}
+
+type fakeHealthNotifier struct {
+	events []healthEvent
+}
+
+func (f *fakeHealthNotifier) DataSourceHealthChanged(dataSource *models.DataSource, healthy bool, failures int, message string) {
+	f.events = append(f.events, healthEvent{healthy, failures})
+}
+
+func TestDataSourceInspectorInspect(t *testing.T) {
+	tester := &fakeConnectionTester{}
+	notifier := &fakeHealthNotifier{}
+	inspector := &DataSourceInspector{
+		logger:   zap.NewNop(),
+		cfg:      config.DataSourceHealthCheckConfig{Timeout: time.Second, FailureThreshold: 2},
+		tester:   tester,
+		notifier: notifier,
+	}
+	ds := &models.DataSource{Name: "src", Type: "mysql", IsConnected: true}
+
+	inspector.Inspect(ds)
+	assert.Empty(t, notifier.events)
+	assert.Equal(t, models.DataSourceHealthDegraded, ds.HealthStatus)
+	assert.Equal(t, "connection refused", ds.LastError)
+	assert.False(t, ds.IsConnected)
+	assert.NotNil(t, ds.LastTestAt)
+
+	// 连续失败达到阈值只告警一次
+	inspector.Inspect(ds)
+	inspector.Inspect(ds)
+	assert.Equal(t, []healthEvent{{false, 2}}, notifier.events)
+	assert.Equal(t, 3, ds.ErrorCount)
+
+	tester.success = true
+	inspector.Inspect(ds)
+	assert.Equal(t, []healthEvent{{false, 2}, {true, 3}}, notifier.events)
+	assert.Zero(t, ds.ErrorCount)
+	assert.Equal(t, models.DataSourceHealthHealthy, ds.HealthStatus)
+}
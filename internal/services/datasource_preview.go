@@ -0,0 +1,275 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/env-data-platform/internal/config"
+	"github.com/env-data-platform/internal/models"
+)
+
+// 预览默认值，未配置datasource.preview时使用
+const (
+	defaultPreviewRows    = 20
+	defaultPreviewMaxRows = 200
+	defaultPreviewTimeout = 10 * time.Second
+
+	// previewSubqueryAlias 自定义查询作为子查询时的别名
+	previewSubqueryAlias = "preview_source"
+	// previewMaskPlaceholder 脱敏时替换中间部分的占位符
+	previewMaskPlaceholder = "****"
+)
+
+// defaultPreviewMaskColumns 列名包含这些关键字时脱敏
+var defaultPreviewMaskColumns = []string{"password", "passwd", "secret", "token", "phone", "mobile", "email", "id_card", "idcard"}
+
+// previewableDataSourceTypes 支持预览的数据源类型
+var previewableDataSourceTypes = map[string]bool{"mysql": true, "postgresql": true, "sqlserver": true}
+
+var (
+	// previewTableRegex 表名，可带库名或schema前缀
+	previewTableRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+	// previewQueryStartRegex 预览查询只允许SELECT或WITH开头
+	previewQueryStartRegex = regexp.MustCompile(`(?i)^(select|with)\b`)
+	// previewForbiddenRegex 预览查询中不允许出现的写操作和过程调用关键字
+	previewForbiddenRegex = regexp.MustCompile(`(?i)\b(insert|update|delete|merge|upsert|drop|alter|create|truncate|rename|grant|revoke|exec|execute|call|into|lock|set)\b`)
+)
+
+// ErrInvalidPreviewRequest 预览参数不合法
+var ErrInvalidPreviewRequest = errors.New("预览参数不合法")
+
+// DataPreviewRequest 数据预览请求，table与query二选一
+type DataPreviewRequest struct {
+	Table       string   `json:"table"`        // 表名，可带库名或schema前缀
+	Query       string   `json:"query"`        // 只读查询，仅支持单条SELECT/WITH语句，仅限超级管理员（脱敏按结果列名匹配）
+	Limit       int      `json:"limit"`        // 返回行数，超过上限时按上限返回
+	MaskColumns []string `json:"mask_columns"` // 额外需要脱敏的列名关键字
+}
+
+// PreviewColumn 预览结果的列，顺序与查询结果一致
+type PreviewColumn struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Masked bool   `json:"masked"`
+}
+
+// DataPreview 数据预览结果
+type DataPreview struct {
+	Columns   []PreviewColumn          `json:"columns"`
+	Rows      []map[string]interface{} `json:"rows"`
+	Limit     int                      `json:"limit"`
+	HasMore   bool                     `json:"has_more"` // 源数据超过limit行
+	ElapsedMs int64                    `json:"elapsed_ms"`
+}
+
+// DataPreviewService 数据预览服务，按表或只读查询返回前N行样例数据并对敏感列脱敏
+type DataPreviewService struct {
+	defaultRows int
+	maxRows     int
+	timeout     time.Duration
+	maskColumns []string
+}
+
+// NewDataPreviewService 创建数据预览服务，读取datasource.preview配置
+func NewDataPreviewService() *DataPreviewService {
+	s := &DataPreviewService{
+		defaultRows: defaultPreviewRows,
+		maxRows:     defaultPreviewMaxRows,
+		timeout:     defaultPreviewTimeout,
+		maskColumns: defaultPreviewMaskColumns,
+	}
+	if cfg := config.GlobalConfig; cfg != nil {
+		preview := cfg.DataSource.Preview
+		if preview.DefaultRows > 0 {
+			s.defaultRows = preview.DefaultRows
+		}
+		if preview.MaxRows > 0 {
+			s.maxRows = preview.MaxRows
+		}
+		if preview.Timeout > 0 {
+			s.timeout = preview.Timeout
+		}
+		if preview.MaskColumns != nil {
+			s.maskColumns = preview.MaskColumns
+		}
+	}
+	return s
+}
+
+// Preview 查询数据源的样例数据。MySQL和PostgreSQL在只读事务中执行，查询超时由配置控制
+func (s *DataPreviewService) Preview(ctx context.Context, dataSource *models.DataSource, req *DataPreviewRequest) (*DataPreview, error) {
+	if !previewableDataSourceTypes[dataSource.Type] {
+		return nil, fmt.Errorf("%w: 数据源类型%s不支持预览", ErrInvalidPreviewRequest, dataSource.Type)
+	}
+	if dataSource.IsImported() {
+		if strings.TrimSpace(req.Query) != "" {
+			return nil, fmt.Errorf("%w: %v，不支持自定义查询", ErrInvalidPreviewRequest, ErrImportTableOnly)
+		}
+		if err := CheckImportTable(dataSource, strings.TrimSpace(req.Table)); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPreviewRequest, err)
+		}
+	}
+	source, err := previewSource(req)
+	if err != nil {
+		return nil, err
+	}
+	limit := s.limit(req.Limit)
+
+	db, err := openDataSourceDB(dataSource)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	start := time.Now()
+	// 多取一行判断是否还有更多数据
+	columns, rows, err := queryPreview(ctx, db, dataSource.Type, previewQuery(dataSource.Type, source, limit+1))
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("预览查询超过%s: %w", s.timeout, context.DeadlineExceeded)
+		}
+		return nil, err
+	}
+
+	preview := &DataPreview{
+		Columns:   columns,
+		Rows:      rows,
+		Limit:     limit,
+		ElapsedMs: time.Since(start).Milliseconds(),
+	}
+	if len(rows) > limit {
+		preview.Rows = rows[:limit]
+		preview.HasMore = true
+	}
+	maskPreview(preview, append(append([]string(nil), s.maskColumns...), req.MaskColumns...))
+	return preview, nil
+}
+
+// limit 请求行数，未指定时用默认行数，超过上限时按上限
+func (s *DataPreviewService) limit(requested int) int {
+	if requested <= 0 {
+		return s.defaultRows
+	}
+	if requested > s.maxRows {
+		return s.maxRows
+	}
+	return requested
+}
+
+// previewSource 预览的数据来源：校验后的表名，或包装为子查询的只读查询
+func previewSource(req *DataPreviewRequest) (string, error) {
+	table := strings.TrimSpace(req.Table)
+	query := strings.TrimRight(strings.TrimSpace(req.Query), "; \t\r\n")
+
+	switch {
+	case table != "" && query != "":
+		return "", fmt.Errorf("%w: table与query只能指定一个", ErrInvalidPreviewRequest)
+	case table != "":
+		if !previewTableRegex.MatchString(table) {
+			return "", fmt.Errorf("%w: 表名 %s 不合法", ErrInvalidPreviewRequest, table)
+		}
+		return table, nil
+	case query != "":
+		if strings.Contains(query, ";") || strings.Contains(query, "--") || strings.Contains(query, "/*") {
+			return "", fmt.Errorf("%w: 查询只能是单条语句且不能包含注释", ErrInvalidPreviewRequest)
+		}
+		if !previewQueryStartRegex.MatchString(query) {
+			return "", fmt.Errorf("%w: 查询必须以SELECT或WITH开头", ErrInvalidPreviewRequest)
+		}
+		if keyword := previewForbiddenRegex.FindString(query); keyword != "" {
+			return "", fmt.Errorf("%w: 查询不能包含%s", ErrInvalidPreviewRequest, strings.ToUpper(keyword))
+		}
+		return fmt.Sprintf("(%s) AS %s", query, previewSubqueryAlias), nil
+	default:
+		return "", fmt.Errorf("%w: 需要指定table或query", ErrInvalidPreviewRequest)
+	}
+}
+
+// previewQuery 取前limit行的查询语句
+func previewQuery(dbType, source string, limit int) string {
+	if dbType == "sqlserver" {
+		return fmt.Sprintf("SELECT TOP %d * FROM %s", limit, source)
+	}
+	return fmt.Sprintf("SELECT * FROM %s LIMIT %d", source, limit)
+}
+
+// queryPreview 执行预览查询，MySQL和PostgreSQL驱动支持只读事务，由数据库拦截写操作
+func queryPreview(ctx context.Context, db *sql.DB, dbType, query string) ([]PreviewColumn, []map[string]interface{}, error) {
+	var rows *sql.Rows
+	var err error
+	if dbType == "sqlserver" {
+		rows, err = db.QueryContext(ctx, query)
+	} else {
+		tx, txErr := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+		if txErr != nil {
+			return nil, nil, txErr
+		}
+		defer tx.Rollback()
+		rows, err = tx.QueryContext(ctx, query)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, nil, err
+	}
+	columns := make([]PreviewColumn, len(columnTypes))
+	for i, columnType := range columnTypes {
+		columns[i] = PreviewColumn{Name: columnType.Name(), Type: columnType.DatabaseTypeName()}
+	}
+
+	data, err := scanRowMaps(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	return columns, data, nil
+}
+
+// maskPreview 列名包含任一关键字（不区分大小写）的列脱敏
+func maskPreview(preview *DataPreview, keywords []string) {
+	for i := range preview.Columns {
+		column := &preview.Columns[i]
+		if !matchesMaskKeyword(column.Name, keywords) {
+			continue
+		}
+		column.Masked = true
+		for _, row := range preview.Rows {
+			row[column.Name] = maskPreviewValue(row[column.Name])
+		}
+	}
+}
+
+func matchesMaskKeyword(column string, keywords []string) bool {
+	column = strings.ToLower(column)
+	for _, keyword := range keywords {
+		keyword = strings.ToLower(strings.TrimSpace(keyword))
+		if keyword != "" && strings.Contains(column, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskPreviewValue 保留首尾各四分之一字符便于识别格式，中间替换为****；过短的值整体替换，空值保持为空
+func maskPreviewValue(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	runes := []rune(fmt.Sprint(value))
+	keep := len(runes) / 4
+	if keep == 0 {
+		return previewMaskPlaceholder
+	}
+	return string(runes[:keep]) + previewMaskPlaceholder + string(runes[len(runes)-keep:])
+}
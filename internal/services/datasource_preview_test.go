@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/env-data-platform/internal/models"
+)
+
+func TestPreviewSource(t *testing.T) {
+	source, err := previewSource(&DataPreviewRequest{Table: " env.station_data "})
+	require.NoError(t, err)
+	assert.Equal(t, "env.station_data", source)
+	assert.Equal(t, "SELECT * FROM env.station_data LIMIT 21", previewQuery("mysql", source, 21))
+	assert.Equal(t, "SELECT TOP 21 * FROM env.station_data", previewQuery("sqlserver", source, 21))
+
+	source, err = previewSource(&DataPreviewRequest{Query: "select id, update_time from t where status = 1;"})
+	require.NoError(t, err)
+	assert.Equal(t, "(select id, update_time from t where status = 1) AS preview_source", source)
+
+	for _, req := range []DataPreviewRequest{
+		{},
+		{Table: "t", Query: "SELECT 1"},
+		{Table: "t; DROP TABLE t"},
+		{Query: "DELETE FROM t"},
+		{Query: "SELECT 1; DROP TABLE t"},
+		{Query: "SELECT * FROM t -- comment"},
+		{Query: "WITH x AS (SELECT 1) UPDATE t SET a = 1"},
+		{Query: "SELECT * INTO backup FROM t"},
+	} {
+		_, err := previewSource(&req)
+		assert.ErrorIs(t, err, ErrInvalidPreviewRequest, "%+v", req)
+	}
+}
+
+func TestPreviewLimitAndMask(t *testing.T) {
+	s := &DataPreviewService{defaultRows: 20, maxRows: 200}
+	assert.Equal(t, 20, s.limit(0))
+	assert.Equal(t, 50, s.limit(50))
+	assert.Equal(t, 200, s.limit(1000))
+
+	preview := &DataPreview{
+		Columns: []PreviewColumn{{Name: "id"}, {Name: "Contact_Phone"}, {Name: "remark"}},
+		Rows: []map[string]interface{}{
+			{"id": 1, "Contact_Phone": "13812345678", "remark": "ok"},
+			{"id": 2, "Contact_Phone": nil, "remark": "abc"},
+		},
+	}
+	maskPreview(preview, []string{"phone", " REMARK "})
+	assert.False(t, preview.Columns[0].Masked)
+	assert.True(t, preview.Columns[1].Masked)
+	assert.True(t, preview.Columns[2].Masked)
+	assert.Equal(t, 1, preview.Rows[0]["id"])
+	assert.Equal(t, "13****78", preview.Rows[0]["Contact_Phone"])
+	assert.Nil(t, preview.Rows[1]["Contact_Phone"])
+	assert.Equal(t, "****", preview.Rows[0]["remark"])
+
+	_, err := NewDataPreviewService().Preview(context.Background(), &models.DataSource{Type: models.DataSourceTypeKafka}, &DataPreviewRequest{Table: "t"})
+	assert.ErrorIs(t, err, ErrInvalidPreviewRequest)
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -40,6 +41,25 @@ type ETLExecutionResult struct {
 	SkippedRows  int64  `json:"skipped_rows"`
 	ErrorMessage string `json:"error_message"`
 	LogContent   string `json:"log_content"`
+	OutputFileID *uint  `json:"output_file_id,omitempty"`
+	// Retryable 失败原因为连接超时等临时故障，可按作业的MaxRetries重试
+	Retryable bool `json:"retryable"`
+	// Steps 读取、各转换步骤和写入的步骤级统计，执行结束后随执行记录保存
+	Steps []models.ETLExecutionStep `json:"steps,omitempty"`
+
+	// pipeline 本次执行的转换管道，用于生成步骤统计
+	pipeline *transformRows
+}
+
+// defaultETLJobTimeout 作业未设置超时时间时的执行超时
+const defaultETLJobTimeout = time.Hour
+
+// jobTimeout 作业的执行超时
+func jobTimeout(job *models.ETLJob) time.Duration {
+	if job.Timeout <= 0 {
+		return defaultETLJobTimeout
+	}
+	return time.Duration(job.Timeout) * time.Second
 }
 
 // NewETLExecutor 创建ETL执行器
@@ -57,8 +77,9 @@ func (e *ETLExecutor) ExecuteJob(ctx context.Context, job *models.ETLJob, execut
 		ctx = context.Background()
 	}
 
-	// 创建可取消的上下文
-	jobCtx, cancel := context.WithTimeout(ctx, time.Duration(job.Timeout)*time.Second)
+	// 创建带超时的上下文，超时或手动停止时取消
+	timeout := jobTimeout(job)
+	jobCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	// 记录运行中的作业
@@ -71,12 +92,14 @@ func (e *ETLExecutor) ExecuteJob(ctx context.Context, job *models.ETLJob, execut
 		StartTime:   time.Now(),
 	}
 	e.mutex.Unlock()
+	etlRunningJobs.Inc()
 
 	// 执行完成后清理
 	defer func() {
 		e.mutex.Lock()
 		delete(e.runningJobs, job.ID)
 		e.mutex.Unlock()
+		etlRunningJobs.Dec()
 	}()
 
 	e.logger.Info("Starting ETL job execution",
@@ -84,9 +107,13 @@ func (e *ETLExecutor) ExecuteJob(ctx context.Context, job *models.ETLJob, execut
 		zap.String("execution_id", execution.ExecutionID),
 		zap.String("job_name", job.Name))
 
+	start := time.Now()
 	result := &ETLExecutionResult{
 		Status: "running",
 	}
+	defer func() {
+		observeETLExecution(etlSourceType(job), result, time.Since(start).Seconds())
+	}()
 
 	var logBuilder strings.Builder
 	logBuilder.WriteString(fmt.Sprintf("[%s] ETL作业开始执行\n", time.Now().Format("2006-01-02 15:04:05")))
@@ -100,22 +127,20 @@ func (e *ETLExecutor) ExecuteJob(ctx context.Context, job *models.ETLJob, execut
 		return result
 	}
 
-	// 执行ETL步骤
-	switch job.Source.Type {
-	case "mysql", "postgresql":
-		err = e.executeDatabaseETL(jobCtx, job, config, result, &logBuilder)
-	case "hj212":
-		err = e.executeHJ212ETL(jobCtx, job, config, result, &logBuilder)
-	case "api":
-		err = e.executeAPIETL(jobCtx, job, config, result, &logBuilder)
-	default:
-		err = fmt.Errorf("不支持的数据源类型: %s", job.Source.Type)
+	err = e.runSteps(jobCtx, job, execution, config, result, &logBuilder)
+	if result.pipeline != nil {
+		result.Steps = result.pipeline.executionSteps(result, err)
 	}
 
 	// 设置最终状态
-	if err != nil {
+	if errors.Is(err, context.DeadlineExceeded) && errors.Is(jobCtx.Err(), context.DeadlineExceeded) {
+		result.Status = models.ETLStatusTimeout
+		result.ErrorMessage = fmt.Sprintf("执行超时（超过%s），已取消", timeout)
+		logBuilder.WriteString(fmt.Sprintf("[%s] %s\n", time.Now().Format("2006-01-02 15:04:05"), result.ErrorMessage))
+	} else if err != nil {
 		result.Status = "failed"
 		result.ErrorMessage = err.Error()
+		result.Retryable = IsRetryableETLError(err)
 		logBuilder.WriteString(fmt.Sprintf("[%s] ETL作业执行失败: %s\n", time.Now().Format("2006-01-02 15:04:05"), err.Error()))
 	} else {
 		result.Status = "success"
@@ -132,11 +157,82 @@ func (e *ETLExecutor) ExecuteJob(ctx context.Context, job *models.ETLJob, execut
 		zap.Int64("output_rows", result.OutputRows),
 		zap.Error(err))
 
+	e.saveSteps(execution, result.Steps)
 	return result
 }
 
+// runSteps 在独立协程中执行ETL步骤，目标为文件导出时直接将源数据写成文件，源或目标为Kafka时按消息流处理。
+// 上下文取消（超时或手动停止）后立即返回，不等待未响应取消的步骤，以便释放作业；
+// 步骤在自己的结果和日志上运行，正常结束时才合并，避免与返回后的写入并发
+func (e *ETLExecutor) runSteps(ctx context.Context, job *models.ETLJob, execution *models.ETLExecution, config *models.ETLJobConfig, result *ETLExecutionResult, logBuilder *strings.Builder) error {
+	type stepOutcome struct {
+		result *ETLExecutionResult
+		log    string
+		err    error
+	}
+	done := make(chan stepOutcome, 1)
+
+	go func() {
+		stepResult := &ETLExecutionResult{Status: result.Status}
+		var stepLog strings.Builder
+		var err error
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("执行异常: %v", r)
+			}
+			done <- stepOutcome{result: stepResult, log: stepLog.String(), err: err}
+		}()
+
+		switch {
+		case job.Target != nil && job.Target.Type == models.DataSourceTypeFileExport:
+			err = e.executeFileExport(ctx, job, execution, config, stepResult, &stepLog)
+		case job.Source.Type == models.DataSourceTypeKafka:
+			err = e.executeKafkaSourceETL(ctx, job, execution, config, stepResult, &stepLog)
+		case job.Target != nil && job.Target.Type == models.DataSourceTypeKafka:
+			err = e.executeKafkaSinkETL(ctx, job, execution, config, stepResult, &stepLog)
+		default:
+			err = e.executeSourceETL(ctx, job, execution, config, stepResult, &stepLog)
+		}
+	}()
+
+	select {
+	case outcome := <-done:
+		*result = *outcome.result
+		logBuilder.WriteString(outcome.log)
+		return outcome.err
+	case <-ctx.Done():
+		// 步骤与取消同时结束时以步骤结果为准
+		select {
+		case outcome := <-done:
+			*result = *outcome.result
+			logBuilder.WriteString(outcome.log)
+			return outcome.err
+		default:
+		}
+		e.logger.Warn("ETL job cancelled before steps returned",
+			zap.Uint("job_id", job.ID),
+			zap.String("execution_id", execution.ExecutionID),
+			zap.Error(ctx.Err()))
+		return ctx.Err()
+	}
+}
+
+// executeSourceETL 按源数据源类型执行ETL
+func (e *ETLExecutor) executeSourceETL(ctx context.Context, job *models.ETLJob, execution *models.ETLExecution, config *models.ETLJobConfig, result *ETLExecutionResult, logBuilder *strings.Builder) error {
+	switch job.Source.Type {
+	case "mysql", "postgresql":
+		return e.executeDatabaseETL(ctx, job, execution, config, result, logBuilder)
+	case "hj212":
+		return e.executeHJ212ETL(ctx, job, execution, config, result, logBuilder)
+	case "api":
+		return e.executeAPIETL(ctx, job, execution, config, result, logBuilder)
+	default:
+		return fmt.Errorf("不支持的数据源类型: %s", job.Source.Type)
+	}
+}
+
 // executeDatabaseETL 执行数据库ETL
-func (e *ETLExecutor) executeDatabaseETL(ctx context.Context, job *models.ETLJob, config *models.ETLJobConfig, result *ETLExecutionResult, logBuilder *strings.Builder) error {
+func (e *ETLExecutor) executeDatabaseETL(ctx context.Context, job *models.ETLJob, execution *models.ETLExecution, config *models.ETLJobConfig, result *ETLExecutionResult, logBuilder *strings.Builder) error {
 	logBuilder.WriteString(fmt.Sprintf("[%s] 开始执行数据库ETL\n", time.Now().Format("2006-01-02 15:04:05")))
 
 	// 解析源数据源配置
@@ -166,6 +262,7 @@ func (e *ETLExecutor) executeDatabaseETL(ctx context.Context, job *models.ETLJob
 	// 模拟抽取1000条数据
 	result.InputRows = 1000
 	logBuilder.WriteString(fmt.Sprintf("[%s] 数据抽取完成，共抽取 %d 条记录\n", time.Now().Format("2006-01-02 15:04:05"), result.InputRows))
+	e.reportProgress(execution, 30, result.InputRows)
 
 	// 模拟数据转换
 	logBuilder.WriteString(fmt.Sprintf("[%s] 开始数据转换\n", time.Now().Format("2006-01-02 15:04:05")))
@@ -189,6 +286,7 @@ func (e *ETLExecutor) executeDatabaseETL(ctx context.Context, job *models.ETLJob
 	result.ErrorRows = 50
 	logBuilder.WriteString(fmt.Sprintf("[%s] 数据转换完成，输出 %d 条记录，错误 %d 条\n",
 		time.Now().Format("2006-01-02 15:04:05"), result.OutputRows, result.ErrorRows))
+	e.reportProgress(execution, 70, result.InputRows)
 
 	// 模拟数据加载
 	if job.Target != nil {
@@ -210,51 +308,8 @@ func (e *ETLExecutor) executeDatabaseETL(ctx context.Context, job *models.ETLJob
 	return nil
 }
 
-// executeHJ212ETL 执行HJ212数据ETL
-func (e *ETLExecutor) executeHJ212ETL(ctx context.Context, job *models.ETLJob, config *models.ETLJobConfig, result *ETLExecutionResult, logBuilder *strings.Builder) error {
-	logBuilder.WriteString(fmt.Sprintf("[%s] 开始执行HJ212数据ETL\n", time.Now().Format("2006-01-02 15:04:05")))
-
-	// 查询HJ212原始数据
-	var dataCount int64
-	startTime := time.Now().Add(-1 * time.Hour) // 处理最近1小时的数据
-
-	err := e.db.Model(&models.HJ212Data{}).
-		Where("created_at >= ?", startTime).
-		Count(&dataCount).Error
-
-	if err != nil {
-		return fmt.Errorf("查询HJ212数据失败: %v", err)
-	}
-
-	result.InputRows = dataCount
-	logBuilder.WriteString(fmt.Sprintf("[%s] 查询到 %d 条HJ212数据\n", time.Now().Format("2006-01-02 15:04:05"), dataCount))
-
-	// 检查上下文是否取消
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	default:
-	}
-
-	// 模拟数据处理
-	if dataCount > 0 {
-		logBuilder.WriteString(fmt.Sprintf("[%s] 开始处理HJ212数据\n", time.Now().Format("2006-01-02 15:04:05")))
-
-		// 模拟处理时间
-		time.Sleep(time.Duration(dataCount/100) * time.Millisecond)
-
-		result.OutputRows = dataCount * 95 / 100 // 95%的数据处理成功
-		result.ErrorRows = dataCount - result.OutputRows
-
-		logBuilder.WriteString(fmt.Sprintf("[%s] HJ212数据处理完成，成功 %d 条，失败 %d 条\n",
-			time.Now().Format("2006-01-02 15:04:05"), result.OutputRows, result.ErrorRows))
-	}
-
-	return nil
-}
-
 // executeAPIETL 执行API数据ETL
-func (e *ETLExecutor) executeAPIETL(ctx context.Context, job *models.ETLJob, config *models.ETLJobConfig, result *ETLExecutionResult, logBuilder *strings.Builder) error {
+func (e *ETLExecutor) executeAPIETL(ctx context.Context, job *models.ETLJob, execution *models.ETLExecution, config *models.ETLJobConfig, result *ETLExecutionResult, logBuilder *strings.Builder) error {
 	logBuilder.WriteString(fmt.Sprintf("[%s] 开始执行API数据ETL\n", time.Now().Format("2006-01-02 15:04:05")))
 
 	// 解析API配置
@@ -269,6 +324,7 @@ func (e *ETLExecutor) executeAPIETL(ctx context.Context, job *models.ETLJob, con
 	}
 
 	logBuilder.WriteString(fmt.Sprintf("[%s] 调用API: %s\n", time.Now().Format("2006-01-02 15:04:05"), url))
+	e.reportProgress(execution, 10, 0)
 
 	// 检查上下文是否取消
 	select {
@@ -282,6 +338,7 @@ func (e *ETLExecutor) executeAPIETL(ctx context.Context, job *models.ETLJob, con
 
 	// 模拟获取数据
 	result.InputRows = 500
+	e.reportProgress(execution, 60, result.InputRows)
 	result.OutputRows = 480
 	result.ErrorRows = 20
 
@@ -291,6 +348,21 @@ func (e *ETLExecutor) executeAPIETL(ctx context.Context, job *models.ETLJob, con
 	return nil
 }
 
+// reportProgress 更新执行进度和已处理行数，供执行详情查看实时进度
+func (e *ETLExecutor) reportProgress(execution *models.ETLExecution, progress int, inputRows int64) {
+	if e.db == nil || execution == nil || execution.ID == 0 {
+		return
+	}
+	if err := e.db.Model(&models.ETLExecution{}).Where("id = ?", execution.ID).Updates(map[string]interface{}{
+		"progress":   progress,
+		"input_rows": inputRows,
+	}).Error; err != nil {
+		e.logger.Warn("Failed to update execution progress",
+			zap.String("execution_id", execution.ExecutionID),
+			zap.Error(err))
+	}
+}
+
 // StopJob 停止作业执行
 func (e *ETLExecutor) StopJob(jobID uint) error {
 	e.mutex.RLock()
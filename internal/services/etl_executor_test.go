@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/env-data-platform/internal/database/dbtest"
+	"github.com/env-data-platform/internal/models"
+)
+
+func TestExecuteJobTimeout(t *testing.T) {
+	e := &ETLExecutor{logger: zap.NewNop(), runningJobs: make(map[uint]*JobExecution)}
+	// API抽取步骤不检查取消，超时后执行器也应立即返回
+	job := &models.ETLJob{
+		Source:  &models.DataSource{Type: "api", ConfigData: json.RawMessage(`{"url":"http://example.com"}`)},
+		Timeout: 1,
+	}
+	job.ID = 1
+	execution := &models.ETLExecution{JobID: 1, ExecutionID: "exec_timeout"}
+
+	start := time.Now()
+	result := e.ExecuteJob(context.Background(), job, execution, nil)
+	assert.Less(t, time.Since(start), 2500*time.Millisecond)
+	assert.Equal(t, models.ETLStatusTimeout, result.Status)
+	assert.Contains(t, result.ErrorMessage, "执行超时")
+	assert.False(t, result.Retryable)
+	assert.False(t, e.IsJobRunning(job.ID), "超时后释放运行中的作业")
+}
+
+func TestJobTimeout(t *testing.T) {
+	assert.Equal(t, defaultETLJobTimeout, jobTimeout(&models.ETLJob{}))
+	assert.Equal(t, 90*time.Second, jobTimeout(&models.ETLJob{Timeout: 90}))
+}
+
+func TestETLExecutorReportProgress(t *testing.T) {
+	tests := []struct {
+		name      string
+		execution *models.ETLExecution
+		updated   bool
+	}{
+		{name: "已保存的执行", execution: &models.ETLExecution{BaseModel: models.BaseModel{ID: 9}, ExecutionID: "exec_9"}, updated: true},
+		// 执行记录尚未保存时没有可更新的行
+		{name: "未保存的执行", execution: &models.ETLExecution{ExecutionID: "exec_new"}},
+		{name: "无执行记录", execution: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.New(t)
+			e := &ETLExecutor{db: db.DB, logger: zap.NewNop()}
+
+			e.reportProgress(tt.execution, 30, 1000)
+
+			statements := db.Statements()
+			if !tt.updated {
+				assert.Empty(t, statements)
+				return
+			}
+			require.Len(t, statements, 1)
+			assert.Equal(t, dbtest.KindUpdate, statements[0].Kind)
+			assert.Equal(t, "env_etl_executions", statements[0].Table)
+			assert.Contains(t, statements[0].SQL, "`input_rows`=?,`progress`=?")
+			assert.Equal(t, []interface{}{int64(1000), 30}, statements[0].Vars[:2])
+			assert.Contains(t, statements[0].Vars, uint(9))
+		})
+	}
+}
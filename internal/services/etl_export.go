@@ -0,0 +1,498 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/md5"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/env-data-platform/internal/config"
+	"github.com/env-data-platform/internal/models"
+	"github.com/env-data-platform/internal/storage"
+)
+
+// 导出文件格式
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatXLSX = "xlsx"
+)
+
+const (
+	exportCategory = "data"
+	exportKeyDir   = "etl_exports"
+	// xlsxMaxRows 单个工作表的最大行数
+	xlsxMaxRows = 1048576
+)
+
+var (
+	utf8BOM          = []byte{0xEF, 0xBB, 0xBF}
+	exportTableRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+)
+
+// ExportOptions 文件导出选项，目标数据源配置为默认值，作业的target_config可覆盖
+type ExportOptions struct {
+	Format     string `json:"format"`
+	FileName   string `json:"file_name"`
+	WithHeader bool   `json:"with_header"`
+	UTF8BOM    bool   `json:"utf8_bom"`
+}
+
+// contentType 导出文件的MIME类型
+func (o *ExportOptions) contentType() string {
+	if o.Format == ExportFormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv"
+}
+
+// parseExportOptions 合并目标数据源和作业配置中的导出选项
+func parseExportOptions(job *models.ETLJob, jobConfig *models.ETLJobConfig) (*ExportOptions, error) {
+	opts := &ExportOptions{Format: ExportFormatCSV, WithHeader: true}
+
+	settings := map[string]interface{}{}
+	if job.Target != nil {
+		targetConfig, err := job.Target.GetConfig()
+		if err != nil {
+			return nil, fmt.Errorf("解析目标数据源配置失败: %v", err)
+		}
+		if targetConfig.FileFormat != "" {
+			settings["format"] = targetConfig.FileFormat
+		}
+		if targetConfig.FilePath != "" {
+			settings["file_name"] = targetConfig.FilePath
+		}
+		for k, v := range targetConfig.CustomConfig {
+			settings[k] = v
+		}
+	}
+	for k, v := range jobConfig.TargetConfig {
+		settings[k] = v
+	}
+
+	if v, ok := settings["format"].(string); ok && v != "" {
+		opts.Format = strings.ToLower(v)
+	}
+	if opts.Format == "excel" {
+		opts.Format = ExportFormatXLSX
+	}
+	if opts.Format != ExportFormatCSV && opts.Format != ExportFormatXLSX {
+		return nil, fmt.Errorf("不支持的导出格式: %s", opts.Format)
+	}
+	if v, ok := settings["file_name"].(string); ok {
+		opts.FileName = path.Base(strings.ReplaceAll(v, "\\", "/"))
+	}
+	if v, ok := settings["with_header"].(bool); ok {
+		opts.WithHeader = v
+	}
+	if v, ok := settings["utf8_bom"].(bool); ok {
+		opts.UTF8BOM = v
+	}
+
+	ext := "." + opts.Format
+	if opts.FileName == "" || opts.FileName == "." || opts.FileName == "/" {
+		opts.FileName = fmt.Sprintf("%s_%s", job.Name, time.Now().Format("20060102150405"))
+	}
+	if !strings.EqualFold(path.Ext(opts.FileName), ext) {
+		opts.FileName += ext
+	}
+	return opts, nil
+}
+
+// RowWriter 按行流式写出导出文件
+type RowWriter interface {
+	WriteRow(values []string) error
+	Close() error
+}
+
+// NewRowWriter 创建CSV或XLSX格式的行写入器，withBOM仅对CSV生效
+func NewRowWriter(w io.Writer, format string, withBOM bool) (RowWriter, error) {
+	return newRowWriter(w, &ExportOptions{Format: format, UTF8BOM: withBOM})
+}
+
+// newRowWriter 按导出格式创建行写入器
+func newRowWriter(w io.Writer, opts *ExportOptions) (RowWriter, error) {
+	if opts.Format == ExportFormatXLSX {
+		return newXLSXWriter(w)
+	}
+	if opts.UTF8BOM {
+		if _, err := w.Write(utf8BOM); err != nil {
+			return nil, err
+		}
+	}
+	return &csvRowWriter{w: csv.NewWriter(w)}, nil
+}
+
+// csvRowWriter CSV写入器
+type csvRowWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvRowWriter) WriteRow(values []string) error {
+	return c.w.Write(values)
+}
+
+func (c *csvRowWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// xlsxWriter 单工作表的XLSX写入器，单元格以内联字符串写出，无需在内存中保存共享字符串表
+type xlsxWriter struct {
+	zw    *zip.Writer
+	sheet io.Writer
+	rows  int
+}
+
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+	xlsxSheetHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetFooter = `</sheetData></worksheet>`
+)
+
+// newXLSXWriter 写入工作簿的固定部分，随后逐行写出工作表
+func newXLSXWriter(w io.Writer) (*xlsxWriter, error) {
+	zw := zip.NewWriter(w)
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+	for _, part := range parts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(sheet, xlsxSheetHeader); err != nil {
+		return nil, err
+	}
+	return &xlsxWriter{zw: zw, sheet: sheet}, nil
+}
+
+func (x *xlsxWriter) WriteRow(values []string) error {
+	if x.rows >= xlsxMaxRows {
+		return fmt.Errorf("导出行数超过Excel单表上限 %d，请改用CSV格式", xlsxMaxRows)
+	}
+	x.rows++
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<row r="%d">`, x.rows)
+	for _, v := range values {
+		if isExportNumber(v) {
+			fmt.Fprintf(&b, `<c><v>%s</v></c>`, v)
+			continue
+		}
+		b.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+		xml.EscapeText(&b, []byte(v))
+		b.WriteString(`</t></is></c>`)
+	}
+	b.WriteString(`</row>`)
+
+	_, err := io.WriteString(x.sheet, b.String())
+	return err
+}
+
+func (x *xlsxWriter) Close() error {
+	if _, err := io.WriteString(x.sheet, xlsxSheetFooter); err != nil {
+		return err
+	}
+	return x.zw.Close()
+}
+
+// isExportNumber 判断是否按数值单元格写出，带前导零的编码（如设备MN）保持文本
+func isExportNumber(v string) bool {
+	if v == "" || len(v) > 15 {
+		return false
+	}
+	digits := strings.TrimPrefix(v, "-")
+	if len(digits) > 1 && digits[0] == '0' && digits[1] != '.' {
+		return false
+	}
+	if strings.Trim(digits, "0123456789.") != "" {
+		return false
+	}
+	_, err := strconv.ParseFloat(v, 64)
+	return err == nil
+}
+
+// exportCounter 统计写出的字节数并计算MD5
+type exportCounter struct {
+	w    io.Writer
+	hash hash.Hash
+	size int64
+}
+
+func (c *exportCounter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.hash.Write(p[:n])
+	c.size += int64(n)
+	return n, err
+}
+
+// writeExportRows 将查询结果逐行写出，返回写出的数据行数
+func writeExportRows(ctx context.Context, rows sourceRows, w io.Writer, opts *ExportOptions) (int64, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+
+	rw, err := newRowWriter(w, opts)
+	if err != nil {
+		return 0, err
+	}
+	if opts.WithHeader {
+		if err := rw.WriteRow(columns); err != nil {
+			return 0, err
+		}
+	}
+
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	record := make([]string, len(columns))
+
+	var count int64
+	for rows.Next() {
+		if count%1000 == 0 {
+			if err := ctx.Err(); err != nil {
+				return count, err
+			}
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return count, err
+		}
+		for i, v := range values {
+			record[i] = v.String
+		}
+		if err := rw.WriteRow(record); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return count, err
+	}
+	return count, rw.Close()
+}
+
+// exportStorage 按全局配置创建文件存储，与文件上传使用同一存储后端
+func exportStorage() (storage.Storage, error) {
+	cfg := config.GlobalConfig
+	if cfg == nil {
+		return nil, fmt.Errorf("配置未加载")
+	}
+	uploadDir := cfg.Upload.UploadPath
+	if dir := os.Getenv("UPLOAD_DIR"); dir != "" {
+		uploadDir = dir
+	}
+	return storage.New(cfg.Storage, uploadDir)
+}
+
+// queryExportRows 查询源数据，HJ212数据源读取平台内的报文数据并展开因子，数据库数据源执行配置的查询
+func (e *ETLExecutor) queryExportRows(ctx context.Context, job *models.ETLJob, jobConfig *models.ETLJobConfig) (sourceRows, func(), error) {
+	switch job.Source.Type {
+	case models.DataSourceTypeHJ212:
+		rows, err := e.openHJ212Rows(ctx, job, jobConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+		return rows, func() {}, nil
+	case "mysql", "postgresql", "sqlserver":
+		if err := checkImportSourceConfig(job.Source, jobConfig.SourceConfig); err != nil {
+			return nil, nil, err
+		}
+		statement, err := exportStatement(jobConfig.SourceConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+		db, err := openSourceDB(job.Source)
+		if err != nil {
+			return nil, nil, err
+		}
+		rows, err := db.QueryContext(ctx, statement)
+		if err != nil {
+			db.Close()
+			return nil, nil, fmt.Errorf("查询源数据失败: %v", err)
+		}
+		return rows, func() { rows.Close(); db.Close() }, nil
+	default:
+		return nil, nil, fmt.Errorf("数据源类型 %s 不支持导出", job.Source.Type)
+	}
+}
+
+// exportStatement 源数据查询语句，优先使用source_config.query，否则导出source_config.table整表
+func exportStatement(sourceConfig map[string]interface{}) (string, error) {
+	if query, ok := sourceConfig["query"].(string); ok && strings.TrimSpace(query) != "" {
+		return query, nil
+	}
+	table, _ := sourceConfig["table"].(string)
+	if !exportTableRegex.MatchString(table) {
+		return "", fmt.Errorf("导出作业需要在source_config中配置query或合法的table")
+	}
+	return "SELECT * FROM " + table, nil
+}
+
+// checkImportSourceConfig 文件导入的数据源不允许自定义查询，table必须是导入表
+func checkImportSourceConfig(source *models.DataSource, sourceConfig map[string]interface{}) error {
+	if !source.IsImported() {
+		return nil
+	}
+	if query, _ := sourceConfig["query"].(string); strings.TrimSpace(query) != "" {
+		return fmt.Errorf("%w，不支持自定义查询", ErrImportTableOnly)
+	}
+	table, _ := sourceConfig["table"].(string)
+	return CheckImportTable(source, table)
+}
+
+// openSourceDB 打开数据库类型的源数据源连接
+func openSourceDB(source *models.DataSource) (*sql.DB, error) {
+	if source.IsImported() {
+		return openImportDB()
+	}
+	cfg, err := source.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("解析源数据源配置失败: %v", err)
+	}
+
+	switch source.Type {
+	case "mysql":
+		dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true&charset=utf8mb4",
+			cfg.Username, cfg.Password, cfg.Host, cfg.Port, cfg.Database)
+		return sql.Open("mysql", dsn)
+	case "postgresql":
+		dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+			cfg.Host, cfg.Port, cfg.Username, cfg.Password, cfg.Database)
+		return sql.Open("postgres", dsn)
+	default:
+		var raw map[string]interface{}
+		if err := json.Unmarshal([]byte(source.Config), &raw); err != nil {
+			return nil, fmt.Errorf("解析源数据源配置失败: %v", err)
+		}
+		return openSQLServer(raw)
+	}
+}
+
+// executeFileExport 将源数据流式写成CSV或Excel存入文件存储，并创建文件记录
+func (e *ETLExecutor) executeFileExport(ctx context.Context, job *models.ETLJob, execution *models.ETLExecution, jobConfig *models.ETLJobConfig, result *ETLExecutionResult, logBuilder *strings.Builder) error {
+	logBuilder.WriteString(fmt.Sprintf("[%s] 开始执行文件导出\n", time.Now().Format("2006-01-02 15:04:05")))
+
+	opts, err := parseExportOptions(job, jobConfig)
+	if err != nil {
+		return err
+	}
+	store, err := exportStorage()
+	if err != nil {
+		return fmt.Errorf("初始化文件存储失败: %v", err)
+	}
+
+	source, closeRows, err := e.queryExportRows(ctx, job, jobConfig)
+	if err != nil {
+		return err
+	}
+	defer closeRows()
+	rows, err := newTransformRows(source, jobConfig.Transformations)
+	if err != nil {
+		return err
+	}
+	result.pipeline = rows
+	e.reportProgress(execution, 10, 0)
+
+	storedName := fmt.Sprintf("%s/%d/%d_%s", exportKeyDir, job.ID, time.Now().Unix(), opts.FileName)
+	counter := &exportCounter{hash: md5.New()}
+
+	// 边查询边写出，避免大结果集占用内存
+	pr, pw := io.Pipe()
+	counter.w = pw
+	type writeResult struct {
+		rows int64
+		err  error
+	}
+	done := make(chan writeResult, 1)
+	go func() {
+		n, err := writeExportRows(ctx, rows, counter, opts)
+		pw.CloseWithError(err)
+		done <- writeResult{rows: n, err: err}
+	}()
+
+	putErr := store.Put(ctx, storedName, pr, -1, opts.contentType())
+	pr.CloseWithError(putErr)
+	written := <-done
+
+	result.InputRows = written.rows
+	if written.err != nil || putErr != nil {
+		store.Delete(context.Background(), storedName)
+		if written.err != nil {
+			return fmt.Errorf("写出导出文件失败: %v", written.err)
+		}
+		return fmt.Errorf("保存导出文件失败: %v", putErr)
+	}
+	result.OutputRows = written.rows
+	rows.report(result, logBuilder)
+
+	record := models.FileRecord{
+		OriginalName: opts.FileName,
+		StoredName:   storedName,
+		FilePath:     store.Locate(storedName),
+		StorageType:  store.Type(),
+		FileSize:     counter.size,
+		FileType:     models.GetFileTypeByMime(opts.contentType()),
+		MimeType:     opts.contentType(),
+		Category:     exportCategory,
+		MD5Hash:      hex.EncodeToString(counter.hash.Sum(nil)),
+		Status:       models.FileStatusActive,
+		Description:  fmt.Sprintf("ETL作业[%s]导出 %s", job.Name, execution.ExecutionID),
+	}
+	record.CreatedBy = job.CreatedBy
+	if execution.TriggerBy != nil {
+		record.CreatedBy = *execution.TriggerBy
+	}
+	if err := e.db.Create(&record).Error; err != nil {
+		store.Delete(context.Background(), storedName)
+		return fmt.Errorf("创建导出文件记录失败: %v", err)
+	}
+	result.OutputFileID = &record.ID
+
+	logBuilder.WriteString(fmt.Sprintf("[%s] 文件导出完成，共 %d 行，文件 %s (%d 字节)\n",
+		time.Now().Format("2006-01-02 15:04:05"), written.rows, opts.FileName, counter.size))
+	e.logger.Info("ETL export file saved",
+		zap.Uint("job_id", job.ID),
+		zap.Uint("file_id", record.ID),
+		zap.String("format", opts.Format),
+		zap.Int64("rows", written.rows))
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/env-data-platform/internal/models"
+)
+
+func TestParseExportOptions(t *testing.T) {
+	target := &models.DataSource{Type: models.DataSourceTypeFileExport}
+	require.NoError(t, target.SetConfig(models.DataSourceConfig{
+		FileFormat:   "excel",
+		CustomConfig: map[string]interface{}{"with_header": false},
+	}))
+	job := &models.ETLJob{Name: "daily", Target: target}
+
+	opts, err := parseExportOptions(job, &models.ETLJobConfig{
+		TargetConfig: map[string]interface{}{"file_name": "../out/report", "utf8_bom": true},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ExportFormatXLSX, opts.Format)
+	assert.Equal(t, "report.xlsx", opts.FileName)
+	assert.False(t, opts.WithHeader)
+	assert.True(t, opts.UTF8BOM)
+
+	_, err = parseExportOptions(job, &models.ETLJobConfig{TargetConfig: map[string]interface{}{"format": "pdf"}})
+	assert.Error(t, err)
+}
+
+func TestCSVRowWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := newRowWriter(&buf, &ExportOptions{Format: ExportFormatCSV, UTF8BOM: true})
+	require.NoError(t, err)
+	require.NoError(t, w.WriteRow([]string{"设备", "备注"}))
+	require.NoError(t, w.WriteRow([]string{"MN001", "a,b"}))
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, "\xEF\xBB\xBF设备,备注\nMN001,\"a,b\"\n", buf.String())
+}
+
+func TestXLSXRowWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := newRowWriter(&buf, &ExportOptions{Format: ExportFormatXLSX})
+	require.NoError(t, err)
+	require.NoError(t, w.WriteRow([]string{"device_id", "value"}))
+	require.NoError(t, w.WriteRow([]string{"0100", "12.5"}))
+	require.NoError(t, w.WriteRow([]string{"<a&b>", "-3"}))
+	require.NoError(t, w.Close())
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	var sheet string
+	names := make([]string, 0, len(zr.File))
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		if f.Name == "xl/worksheets/sheet1.xml" {
+			rc, err := f.Open()
+			require.NoError(t, err)
+			data, err := io.ReadAll(rc)
+			rc.Close()
+			require.NoError(t, err)
+			sheet = string(data)
+		}
+	}
+	assert.Contains(t, names, "[Content_Types].xml")
+	assert.Contains(t, names, "xl/workbook.xml")
+	assert.Contains(t, sheet, `<row r="2"><c t="inlineStr"><is><t xml:space="preserve">0100</t></is></c><c><v>12.5</v></c></row>`)
+	assert.Contains(t, sheet, `&lt;a&amp;b&gt;`)
+	assert.Contains(t, sheet, `<c><v>-3</v></c>`)
+	assert.Contains(t, sheet, `</sheetData></worksheet>`)
+}
+
+func TestIsExportNumber(t *testing.T) {
+	for v, want := range map[string]bool{
+		"0":                true,
+		"0.25":             true,
+		"-12.5":            true,
+		"007":              false,
+		"1e5":              false,
+		"NaN":              false,
+		"":                 false,
+		"1.2.3":            false,
+		"1234567890123456": false,
+	} {
+		assert.Equal(t, want, isExportNumber(v), v)
+	}
+}
@@ -0,0 +1,459 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// filterStep 按表达式过滤行
+//
+// 表达式只在内存中对已读取的行求值，不会拼接进任何SQL，如：
+//
+//	status = '有效' and (a34004_rtd > 10 or device_id in ('MN001', 'MN002'))
+//
+// 支持 =, !=, <>, >, <, >=, <=, in, not in, like, not like，以及 and、or 和括号。
+// 字符串用单引号或双引号括起，引号自身用两个引号转义；列名含特殊字符时用反引号括起。
+// 与数值比较时列值按数值比较，列值不是数值时该行计为错误行；与字符串比较时按字符串比较。
+// like 中 % 匹配任意多个字符，_ 匹配单个字符。
+// 列值为NULL时比较结果为假，可用 "= null"、"!= null" 判断是否为NULL。
+type filterStep struct {
+	expr filterExpr
+}
+
+func newFilterStep(params map[string]interface{}) (*filterStep, error) {
+	expression := paramString(params, "expression")
+	if expression == "" {
+		return nil, fmt.Errorf("未配置expression")
+	}
+	expr, err := parseFilterExpression(expression)
+	if err != nil {
+		return nil, fmt.Errorf("过滤表达式错误: %v", err)
+	}
+	return &filterStep{expr: expr}, nil
+}
+
+func (s *filterStep) bind(columns []string) ([]string, error) {
+	if err := s.expr.bind(columns); err != nil {
+		return nil, err
+	}
+	return columns, nil
+}
+
+func (s *filterStep) apply(row []sql.NullString) ([]sql.NullString, error) {
+	ok, err := s.expr.eval(row)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errSkipRow
+	}
+	return row, nil
+}
+
+// filterExpr 过滤表达式的语法树节点
+type filterExpr interface {
+	bind(columns []string) error
+	eval(row []sql.NullString) (bool, error)
+}
+
+// filterLogic and/or 组合
+type filterLogic struct {
+	and         bool
+	left, right filterExpr
+}
+
+func (e *filterLogic) bind(columns []string) error {
+	if err := e.left.bind(columns); err != nil {
+		return err
+	}
+	return e.right.bind(columns)
+}
+
+func (e *filterLogic) eval(row []sql.NullString) (bool, error) {
+	ok, err := e.left.eval(row)
+	if err != nil || ok != e.and {
+		return ok, err
+	}
+	return e.right.eval(row)
+}
+
+// filterLiteral 表达式中的常量
+type filterLiteral struct {
+	text     string
+	number   float64
+	isNumber bool
+	null     bool
+}
+
+// filterCompare 列与常量的比较
+type filterCompare struct {
+	column string
+	index  int
+	op     string
+	negate bool
+	values []filterLiteral
+	like   *regexp.Regexp
+}
+
+func (e *filterCompare) bind(columns []string) error {
+	index, err := columnIndex(columns, e.column)
+	if err != nil {
+		return err
+	}
+	e.index = index
+	return nil
+}
+
+func (e *filterCompare) eval(row []sql.NullString) (bool, error) {
+	value := row[e.index]
+	if len(e.values) == 1 && e.values[0].null {
+		return value.Valid == (e.op == "!="), nil
+	}
+	if !value.Valid {
+		return false, nil
+	}
+
+	var matched bool
+	switch e.op {
+	case "like":
+		matched = e.like.MatchString(value.String)
+	case "in":
+		for _, literal := range e.values {
+			cmp, err := e.compare(value.String, literal)
+			if err != nil {
+				return false, err
+			}
+			if cmp == 0 {
+				matched = true
+				break
+			}
+		}
+	default:
+		cmp, err := e.compare(value.String, e.values[0])
+		if err != nil {
+			return false, err
+		}
+		switch e.op {
+		case "=":
+			matched = cmp == 0
+		case "!=":
+			matched = cmp != 0
+		case ">":
+			matched = cmp > 0
+		case "<":
+			matched = cmp < 0
+		case ">=":
+			matched = cmp >= 0
+		case "<=":
+			matched = cmp <= 0
+		}
+	}
+	return matched != e.negate, nil
+}
+
+// compare 比较列值与常量，常量为数值时按数值比较
+func (e *filterCompare) compare(value string, literal filterLiteral) (int, error) {
+	if !literal.isNumber {
+		return strings.Compare(value, literal.text), nil
+	}
+	number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0, fmt.Errorf("列 %s 的值 %q 不是数值，无法与 %s 比较", e.column, value, literal.text)
+	}
+	switch {
+	case number < literal.number:
+		return -1, nil
+	case number > literal.number:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// likePattern 将like模式转为正则，其余字符按字面匹配
+func likePattern(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile("(?s)" + b.String())
+}
+
+// 表达式词法单元类型
+const (
+	filterTokenEOF = iota
+	filterTokenIdent
+	filterTokenString
+	filterTokenNumber
+	filterTokenOp
+	filterTokenPunct
+)
+
+type filterToken struct {
+	kind int
+	text string
+	// quoted 反引号括起的标识符，不作为关键字
+	quoted bool
+}
+
+// keyword 是否为指定关键字，大小写不敏感
+func (t filterToken) keyword(word string) bool {
+	return t.kind == filterTokenIdent && !t.quoted && strings.EqualFold(t.text, word)
+}
+
+// tokenizeFilter 将表达式拆分为词法单元
+func tokenizeFilter(s string) ([]filterToken, error) {
+	var tokens []filterToken
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')' || r == ',':
+			tokens = append(tokens, filterToken{kind: filterTokenPunct, text: string(r)})
+			i++
+		case r == '\'' || r == '"' || r == '`':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(runes); j++ {
+				if runes[j] == r {
+					if j+1 < len(runes) && runes[j+1] == r {
+						b.WriteRune(r)
+						j++
+						continue
+					}
+					break
+				}
+				b.WriteRune(runes[j])
+			}
+			if j >= len(runes) {
+				return nil, fmt.Errorf("位置 %d 的引号未闭合", i+1)
+			}
+			if r == '`' {
+				tokens = append(tokens, filterToken{kind: filterTokenIdent, text: b.String(), quoted: true})
+			} else {
+				tokens = append(tokens, filterToken{kind: filterTokenString, text: b.String()})
+			}
+			i = j + 1
+		case strings.ContainsRune("=!<>", r):
+			j := i + 1
+			if j < len(runes) && (runes[j] == '=' || (r == '<' && runes[j] == '>')) {
+				j++
+			}
+			op := string(runes[i:j])
+			switch op {
+			case "==":
+				op = "="
+			case "<>":
+				op = "!="
+			case "!":
+				return nil, fmt.Errorf("位置 %d 的运算符 ! 无效", i+1)
+			}
+			tokens = append(tokens, filterToken{kind: filterTokenOp, text: op})
+			i = j
+		case unicode.IsDigit(r) || ((r == '-' || r == '.') && i+1 < len(runes) && (unicode.IsDigit(runes[i+1]) || runes[i+1] == '.')):
+			j := i + 1
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.' || runes[j] == 'e' || runes[j] == 'E' ||
+				((runes[j] == '+' || runes[j] == '-') && (runes[j-1] == 'e' || runes[j-1] == 'E'))) {
+				j++
+			}
+			text := string(runes[i:j])
+			if _, err := strconv.ParseFloat(text, 64); err != nil {
+				return nil, fmt.Errorf("位置 %d 的数值 %s 无效", i+1, text)
+			}
+			tokens = append(tokens, filterToken{kind: filterTokenNumber, text: text})
+			i = j
+		case r == '_' || unicode.IsLetter(r):
+			j := i + 1
+			for j < len(runes) && (runes[j] == '_' || unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j])) {
+				j++
+			}
+			tokens = append(tokens, filterToken{kind: filterTokenIdent, text: string(runes[i:j])})
+			i = j
+		default:
+			return nil, fmt.Errorf("位置 %d 的字符 %q 无效", i+1, r)
+		}
+	}
+	return append(tokens, filterToken{kind: filterTokenEOF}), nil
+}
+
+// filterParser 递归下降解析过滤表达式，and 优先于 or
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+// parseFilterExpression 解析过滤表达式
+func parseFilterExpression(s string) (filterExpr, error) {
+	tokens, err := tokenizeFilter(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != filterTokenEOF {
+		return nil, fmt.Errorf("多余的内容: %s", tok.text)
+	}
+	return expr, nil
+}
+
+func (p *filterParser) peek() filterToken {
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) next() filterToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != filterTokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// expect 读取指定的标点
+func (p *filterParser) expect(punct string) error {
+	if tok := p.next(); tok.kind != filterTokenPunct || tok.text != punct {
+		return fmt.Errorf("缺少 %s", punct)
+	}
+	return nil
+}
+
+func (p *filterParser) parseOr() (filterExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().keyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &filterLogic{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterExpr, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().keyword("and") {
+		p.next()
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		left = &filterLogic{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parsePrimary() (filterExpr, error) {
+	if tok := p.peek(); tok.kind == filterTokenPunct && tok.text == "(" {
+		p.next()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *filterParser) parseCompare() (filterExpr, error) {
+	tok := p.next()
+	if tok.kind != filterTokenIdent {
+		return nil, fmt.Errorf("应为列名: %s", tok.text)
+	}
+	expr := &filterCompare{column: tok.text}
+
+	op := p.next()
+	if op.keyword("not") {
+		expr.negate = true
+		op = p.next()
+	}
+	switch {
+	case op.keyword("in"):
+		expr.op = "in"
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		for {
+			literal, err := p.parseLiteral()
+			if err != nil {
+				return nil, err
+			}
+			if literal.null {
+				return nil, fmt.Errorf("in 列表中不能使用null")
+			}
+			expr.values = append(expr.values, literal)
+			if p.peek().kind == filterTokenPunct && p.peek().text == "," {
+				p.next()
+				continue
+			}
+			break
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	case op.keyword("like"):
+		expr.op = "like"
+		pattern := p.next()
+		if pattern.kind != filterTokenString {
+			return nil, fmt.Errorf("like 后应为字符串")
+		}
+		like, err := likePattern(pattern.text)
+		if err != nil {
+			return nil, err
+		}
+		expr.like = like
+	case op.kind == filterTokenOp && !expr.negate:
+		expr.op = op.text
+		literal, err := p.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+		if literal.null && expr.op != "=" && expr.op != "!=" {
+			return nil, fmt.Errorf("null 只能用 = 或 != 比较")
+		}
+		expr.values = []filterLiteral{literal}
+	default:
+		return nil, fmt.Errorf("列 %s 后应为比较运算符", expr.column)
+	}
+	return expr, nil
+}
+
+func (p *filterParser) parseLiteral() (filterLiteral, error) {
+	tok := p.next()
+	switch {
+	case tok.kind == filterTokenString:
+		return filterLiteral{text: tok.text}, nil
+	case tok.kind == filterTokenNumber:
+		number, _ := strconv.ParseFloat(tok.text, 64)
+		return filterLiteral{text: tok.text, number: number, isNumber: true}, nil
+	case tok.keyword("null"):
+		return filterLiteral{text: "null", null: true}, nil
+	default:
+		return filterLiteral{}, fmt.Errorf("应为字符串或数值: %s", tok.text)
+	}
+}
@@ -0,0 +1,484 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/models"
+)
+
+const (
+	// hj212DefaultWindow 未配置时间范围时读取最近1小时的数据
+	hj212DefaultWindow = time.Hour
+	hj212BatchSize     = 500
+	hj212TimeLayout    = "2006-01-02 15:04:05"
+	// sqlMaxParams 单条INSERT语句的参数上限，SQL Server限制为2100
+	sqlMaxParams     = 2000
+	sqlMaxInsertRows = 200
+)
+
+var (
+	// hj212DefaultFields 因子展开的默认字段
+	hj212DefaultFields = []string{"rtd", "avg", "min", "max", "cou", "flag"}
+	// hj212BaseColumns 每行固定输出的报文列，其后为"因子编码_字段"形式的因子列
+	hj212BaseColumns   = []string{"device_id", "command_code", "data_type", "data_time", "received_at", "quality_level", "is_valid"}
+	sqlIdentifierRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// sourceRows ETL源数据的逐行读取，*sql.Rows和HJ212展开行均实现该接口，Scan的目标为*sql.NullString
+type sourceRows interface {
+	Columns() ([]string, error)
+	Next() bool
+	Scan(dest ...interface{}) error
+	Err() error
+}
+
+// HJ212SourceOptions HJ212源的读取条件，来自作业的source_config
+type HJ212SourceOptions struct {
+	DeviceIDs    []string
+	CommandCodes []string
+	// Factors 需要展开的因子编码，为空时展开时间范围内出现过的全部因子
+	Factors []string
+	// Fields 每个因子展开的字段，如rtd、avg、flag
+	Fields    []string
+	Start     time.Time
+	End       time.Time
+	ValidOnly bool
+}
+
+// parseHJ212SourceOptions 解析HJ212源的读取条件，未配置设备时使用源数据源绑定的设备
+func parseHJ212SourceOptions(source *models.DataSource, sourceConfig map[string]interface{}) (*HJ212SourceOptions, error) {
+	opts := &HJ212SourceOptions{
+		DeviceIDs:    configStrings(sourceConfig["device_ids"]),
+		CommandCodes: configStrings(sourceConfig["command_codes"]),
+		Factors:      configStrings(sourceConfig["factors"]),
+		Fields:       configStrings(sourceConfig["fields"]),
+		End:          time.Now(),
+	}
+	if len(opts.DeviceIDs) == 0 && source != nil && source.DeviceID != "" {
+		opts.DeviceIDs = []string{source.DeviceID}
+	}
+	if len(opts.Fields) == 0 {
+		opts.Fields = append([]string{}, hj212DefaultFields...)
+	}
+	for i, field := range opts.Fields {
+		opts.Fields[i] = strings.ToLower(field)
+	}
+	if v, ok := sourceConfig["valid_only"].(bool); ok {
+		opts.ValidOnly = v
+	}
+
+	var err error
+	if v, ok := sourceConfig["end_time"].(string); ok && v != "" {
+		if opts.End, err = parseHJ212Time(v); err != nil {
+			return nil, fmt.Errorf("end_time格式错误: %v", err)
+		}
+	}
+	opts.Start = opts.End.Add(-hj212DefaultWindow)
+	if v, ok := sourceConfig["window_hours"].(float64); ok && v > 0 {
+		opts.Start = opts.End.Add(-time.Duration(v * float64(time.Hour)))
+	}
+	if v, ok := sourceConfig["start_time"].(string); ok && v != "" {
+		if opts.Start, err = parseHJ212Time(v); err != nil {
+			return nil, fmt.Errorf("start_time格式错误: %v", err)
+		}
+	}
+	if !opts.Start.Before(opts.End) {
+		return nil, fmt.Errorf("start_time必须早于end_time")
+	}
+	return opts, nil
+}
+
+// parseHJ212Time 解析RFC3339或"2006-01-02 15:04:05"格式的时间
+func parseHJ212Time(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation(hj212TimeLayout, v, time.Local)
+}
+
+// hj212Factors 取报文中的因子数据，兼容因子位于factors下和直接位于顶层两种存储格式
+func hj212Factors(parsed models.JSONMap) map[string]map[string]interface{} {
+	source := map[string]interface{}(parsed)
+	if nested, ok := parsed["factors"].(map[string]interface{}); ok {
+		source = nested
+	}
+
+	factors := make(map[string]map[string]interface{}, len(source))
+	for code, raw := range source {
+		if factor, ok := raw.(map[string]interface{}); ok {
+			factors[code] = factor
+		}
+	}
+	return factors
+}
+
+// hj212FactorColumn 因子展开后的列名
+func hj212FactorColumn(code, field string) string {
+	return code + "_" + field
+}
+
+// hj212Query 按读取条件过滤HJ212数据，数据时间为空的旧数据按接收时间过滤
+func (e *ETLExecutor) hj212Query(ctx context.Context, opts *HJ212SourceOptions) *gorm.DB {
+	query := e.db.WithContext(ctx).Model(&models.HJ212Data{}).
+		Where("(data_time >= ? AND data_time < ?) OR (data_time IS NULL AND received_at >= ? AND received_at < ?)",
+			opts.Start, opts.End, opts.Start, opts.End)
+	if len(opts.DeviceIDs) > 0 {
+		query = query.Where("device_id IN ?", opts.DeviceIDs)
+	}
+	if len(opts.CommandCodes) > 0 {
+		query = query.Where("command_code IN ?", opts.CommandCodes)
+	}
+	if opts.ValidOnly {
+		query = query.Where("is_valid = ?", true)
+	}
+	return query
+}
+
+// discoverHJ212Factors 扫描时间范围内的数据，收集出现过的因子编码
+func (e *ETLExecutor) discoverHJ212Factors(ctx context.Context, opts *HJ212SourceOptions) ([]string, error) {
+	codes := make(map[string]bool)
+	var batch []models.HJ212Data
+	err := e.hj212Query(ctx, opts).Select("id", "parsed_data").
+		FindInBatches(&batch, hj212BatchSize, func(tx *gorm.DB, _ int) error {
+			for _, record := range batch {
+				for code := range hj212Factors(record.ParsedData) {
+					codes[code] = true
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	factors := make([]string, 0, len(codes))
+	for code := range codes {
+		factors = append(factors, code)
+	}
+	sort.Strings(factors)
+	return factors, nil
+}
+
+// openHJ212Rows 按作业配置读取HJ212数据，每条报文输出一行，因子按"因子编码_字段"展开为列
+func (e *ETLExecutor) openHJ212Rows(ctx context.Context, job *models.ETLJob, jobConfig *models.ETLJobConfig) (*hj212Rows, error) {
+	opts, err := parseHJ212SourceOptions(job.Source, jobConfig.SourceConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := &hj212Rows{opts: opts, query: e.hj212Query(ctx, opts), filterFactors: len(opts.Factors) > 0}
+	if !rows.filterFactors {
+		if opts.Factors, err = e.discoverHJ212Factors(ctx, opts); err != nil {
+			return nil, fmt.Errorf("查询HJ212因子失败: %v", err)
+		}
+	}
+
+	rows.columns = append([]string{}, hj212BaseColumns...)
+	for _, code := range opts.Factors {
+		for _, field := range opts.Fields {
+			rows.columns = append(rows.columns, hj212FactorColumn(code, field))
+		}
+	}
+	return rows, nil
+}
+
+// hj212Rows 按主键分批读取HJ212数据并展开因子
+type hj212Rows struct {
+	opts  *HJ212SourceOptions
+	query *gorm.DB
+	// filterFactors 配置了因子时，不包含任一所需因子的报文被跳过
+	filterFactors bool
+	columns       []string
+	batch         []models.HJ212Data
+	index         int
+	lastID        uint
+	done          bool
+	current       []sql.NullString
+	skipped       int64
+	err           error
+}
+
+func (r *hj212Rows) Columns() ([]string, error) {
+	return r.columns, nil
+}
+
+func (r *hj212Rows) Next() bool {
+	for {
+		if r.index < len(r.batch) {
+			record := &r.batch[r.index]
+			r.index++
+			if values, ok := r.flatten(record); ok {
+				r.current = values
+				return true
+			}
+			r.skipped++
+			continue
+		}
+		if r.done || r.err != nil {
+			return false
+		}
+		r.fetch()
+	}
+}
+
+// fetch 读取下一批数据
+func (r *hj212Rows) fetch() {
+	r.batch = r.batch[:0]
+	r.index = 0
+	if err := r.query.Session(&gorm.Session{}).Where("id > ?", r.lastID).
+		Order("id").Limit(hj212BatchSize).Find(&r.batch).Error; err != nil {
+		r.err = fmt.Errorf("查询HJ212数据失败: %v", err)
+		return
+	}
+	if len(r.batch) < hj212BatchSize {
+		r.done = true
+	}
+	if len(r.batch) > 0 {
+		r.lastID = r.batch[len(r.batch)-1].ID
+	}
+}
+
+// flatten 将一条报文展开为输出行，配置了因子但报文不含其中任一因子时返回false
+func (r *hj212Rows) flatten(record *models.HJ212Data) ([]sql.NullString, bool) {
+	values := make([]sql.NullString, 0, len(r.columns))
+	dataTime := record.ReceivedAt
+	if record.DataTime != nil {
+		dataTime = *record.DataTime
+	}
+	values = append(values,
+		sql.NullString{String: record.DeviceID, Valid: true},
+		sql.NullString{String: record.CommandCode, Valid: true},
+		sql.NullString{String: record.DataType, Valid: true},
+		sql.NullString{String: dataTime.Format(hj212TimeLayout), Valid: true},
+		sql.NullString{String: record.ReceivedAt.Format(hj212TimeLayout), Valid: true},
+		sql.NullString{String: record.QualityLevel, Valid: true},
+		sql.NullString{String: strconv.FormatBool(record.IsValid), Valid: true},
+	)
+
+	factors := hj212Factors(record.ParsedData)
+	matched := false
+	for _, code := range r.opts.Factors {
+		factor, ok := factors[code]
+		matched = matched || ok
+		for _, field := range r.opts.Fields {
+			v, ok := factor[field]
+			if !ok || v == nil {
+				values = append(values, sql.NullString{})
+				continue
+			}
+			values = append(values, sql.NullString{String: formatRecordValue(v), Valid: true})
+		}
+	}
+	return values, matched || !r.filterFactors
+}
+
+func (r *hj212Rows) Scan(dest ...interface{}) error {
+	if len(dest) != len(r.current) {
+		return fmt.Errorf("expected %d destination arguments in Scan, not %d", len(r.current), len(dest))
+	}
+	for i, d := range dest {
+		p, ok := d.(*sql.NullString)
+		if !ok {
+			return fmt.Errorf("unsupported Scan destination %T", d)
+		}
+		*p = r.current[i]
+	}
+	return nil
+}
+
+func (r *hj212Rows) Err() error {
+	return r.err
+}
+
+// executeHJ212ETL 读取HJ212数据并展开因子，目标为数据库时写入target_config.table指定的表
+func (e *ETLExecutor) executeHJ212ETL(ctx context.Context, job *models.ETLJob, execution *models.ETLExecution, config *models.ETLJobConfig, result *ETLExecutionResult, logBuilder *strings.Builder) error {
+	logBuilder.WriteString(fmt.Sprintf("[%s] 开始执行HJ212数据ETL\n", time.Now().Format("2006-01-02 15:04:05")))
+
+	rows, err := e.openHJ212Rows(ctx, job, config)
+	if err != nil {
+		return err
+	}
+	transformed, err := newTransformRows(rows, config.Transformations)
+	if err != nil {
+		return err
+	}
+	result.pipeline = transformed
+	logBuilder.WriteString(fmt.Sprintf("[%s] 读取 %s 至 %s 的HJ212数据，展开 %d 个因子\n",
+		time.Now().Format("2006-01-02 15:04:05"),
+		rows.opts.Start.Format(hj212TimeLayout), rows.opts.End.Format(hj212TimeLayout), len(rows.opts.Factors)))
+	e.reportProgress(execution, 10, 0)
+
+	switch {
+	case job.Target == nil:
+		logBuilder.WriteString(fmt.Sprintf("[%s] 未配置目标数据源，仅读取并展开数据\n", time.Now().Format("2006-01-02 15:04:05")))
+		for transformed.Next() {
+			result.InputRows++
+			if result.InputRows%1000 == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+		}
+		if err := transformed.Err(); err != nil {
+			return err
+		}
+		result.OutputRows = result.InputRows
+	case isDatabaseTarget(job.Target.Type):
+		table, _ := config.TargetConfig["table"].(string)
+		if err := e.loadRowsToDatabase(ctx, job.Target, table, transformed, execution, result); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("HJ212源暂不支持写入%s类型的目标数据源", job.Target.Type)
+	}
+
+	transformed.report(result, logBuilder)
+	result.SkippedRows += rows.skipped
+	logBuilder.WriteString(fmt.Sprintf("[%s] HJ212数据处理完成，输出 %d 行，跳过 %d 条不含所需因子的报文\n",
+		time.Now().Format("2006-01-02 15:04:05"), result.OutputRows, rows.skipped))
+	e.reportProgress(execution, 90, result.InputRows)
+	return nil
+}
+
+// isDatabaseTarget 是否为可写入的数据库类型数据源
+func isDatabaseTarget(dsType string) bool {
+	switch dsType {
+	case "mysql", "postgresql", "sqlserver":
+		return true
+	}
+	return false
+}
+
+// loadRowsToDatabase 将源数据按批插入目标数据库的表中，表需预先创建且包含全部输出列
+func (e *ETLExecutor) loadRowsToDatabase(ctx context.Context, target *models.DataSource, table string, rows sourceRows, execution *models.ETLExecution, result *ETLExecutionResult) error {
+	if !exportTableRegex.MatchString(table) {
+		return fmt.Errorf("写入数据库需要在target_config中配置合法的table")
+	}
+	if err := CheckImportTable(target, table); err != nil {
+		return err
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	for _, column := range columns {
+		if !sqlIdentifierRegex.MatchString(column) {
+			return fmt.Errorf("列名 %s 不是合法的标识符，无法写入数据库", column)
+		}
+	}
+
+	db, err := openSourceDB(target)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	perInsert := sqlMaxParams / len(columns)
+	if perInsert > sqlMaxInsertRows {
+		perInsert = sqlMaxInsertRows
+	}
+	if perInsert < 1 {
+		return fmt.Errorf("输出列数 %d 超过单条语句的参数上限", len(columns))
+	}
+
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	args := make([]interface{}, 0, perInsert*len(columns))
+	pending := 0
+
+	flush := func() error {
+		if pending == 0 {
+			return nil
+		}
+		statement := buildInsertStatement(target.Type, table, columns, pending)
+		if _, err := db.ExecContext(ctx, statement, args...); err != nil {
+			return fmt.Errorf("写入目标表 %s 失败: %v", table, err)
+		}
+		result.OutputRows += int64(pending)
+		args = args[:0]
+		pending = 0
+		e.reportProgress(execution, 50, result.InputRows)
+		return nil
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		result.InputRows++
+		for _, v := range values {
+			if v.Valid {
+				args = append(args, v.String)
+			} else {
+				args = append(args, nil)
+			}
+		}
+		pending++
+		if pending >= perInsert {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
+
+// buildInsertStatement 按数据库方言生成多行INSERT语句
+func buildInsertStatement(dbType, table string, columns []string, rowCount int) string {
+	quote := func(name string) string {
+		switch dbType {
+		case "postgresql":
+			return `"` + name + `"`
+		case "sqlserver":
+			return "[" + name + "]"
+		default:
+			return "`" + name + "`"
+		}
+	}
+
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = quote(column)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "INSERT INTO %s (%s) VALUES ", table, strings.Join(quoted, ", "))
+	param := 0
+	for i := 0; i < rowCount; i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString("(")
+		for j := range columns {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			param++
+			switch dbType {
+			case "postgresql":
+				fmt.Fprintf(&b, "$%d", param)
+			case "sqlserver":
+				fmt.Fprintf(&b, "@p%d", param)
+			default:
+				b.WriteString("?")
+			}
+		}
+		b.WriteString(")")
+	}
+	return b.String()
+}
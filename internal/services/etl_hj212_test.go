@@ -0,0 +1,105 @@
+package services
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/env-data-platform/internal/models"
+)
+
+func TestParseHJ212SourceOptions(t *testing.T) {
+	source := &models.DataSource{Type: models.DataSourceTypeHJ212, DeviceID: "MN001"}
+
+	opts, err := parseHJ212SourceOptions(source, map[string]interface{}{
+		"factors":    "a34004, a34002",
+		"fields":     []interface{}{"Rtd", "flag"},
+		"start_time": "2024-05-01 00:00:00",
+		"end_time":   "2024-05-02T00:00:00+08:00",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"MN001"}, opts.DeviceIDs)
+	assert.Equal(t, []string{"a34004", "a34002"}, opts.Factors)
+	assert.Equal(t, []string{"rtd", "flag"}, opts.Fields)
+	assert.True(t, opts.Start.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)))
+	assert.True(t, opts.End.Equal(time.Date(2024, 5, 1, 16, 0, 0, 0, time.UTC)))
+
+	opts, err = parseHJ212SourceOptions(source, map[string]interface{}{
+		"device_ids":   []interface{}{"MN002"},
+		"window_hours": float64(6),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"MN002"}, opts.DeviceIDs)
+	assert.Equal(t, hj212DefaultFields, opts.Fields)
+	assert.Equal(t, 6*time.Hour, opts.End.Sub(opts.Start))
+
+	_, err = parseHJ212SourceOptions(source, map[string]interface{}{
+		"start_time": "2024-05-02 00:00:00",
+		"end_time":   "2024-05-01 00:00:00",
+	})
+	assert.Error(t, err)
+}
+
+func TestHJ212RowsFlatten(t *testing.T) {
+	dataTime := time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local)
+	nested := &models.HJ212Data{
+		DeviceID:    "MN001",
+		CommandCode: "2011",
+		DataTime:    &dataTime,
+		ReceivedAt:  dataTime.Add(time.Second),
+		IsValid:     true,
+		ParsedData: models.JSONMap{
+			"system_code": "22",
+			"factors": map[string]interface{}{
+				"a34004": map[string]interface{}{"rtd": 35.5, "flag": "N"},
+			},
+		},
+	}
+	// 因子直接位于顶层的存储格式
+	flat := &models.HJ212Data{
+		DeviceID:   "MN002",
+		ReceivedAt: dataTime,
+		ParsedData: models.JSONMap{
+			"system_code": "32",
+			"w01018":      map[string]interface{}{"avg": 12.0},
+		},
+	}
+
+	rows := &hj212Rows{
+		opts:          &HJ212SourceOptions{Factors: []string{"a34004", "w01018"}, Fields: []string{"rtd", "avg", "flag"}},
+		filterFactors: true,
+	}
+	values, ok := rows.flatten(nested)
+	require.True(t, ok)
+	require.Len(t, values, len(hj212BaseColumns)+6)
+	assert.Equal(t, "2024-05-01 10:00:00", values[3].String)
+	factorValues := values[len(hj212BaseColumns):]
+	assert.Equal(t, []sql.NullString{
+		{String: "35.5", Valid: true}, {}, {String: "N", Valid: true},
+		{}, {}, {},
+	}, factorValues)
+
+	values, ok = rows.flatten(flat)
+	require.True(t, ok)
+	assert.Equal(t, sql.NullString{String: "12", Valid: true}, values[len(hj212BaseColumns)+4])
+
+	rows.opts.Factors = []string{"a21026"}
+	_, ok = rows.flatten(nested)
+	assert.False(t, ok)
+	rows.filterFactors = false
+	_, ok = rows.flatten(nested)
+	assert.True(t, ok)
+}
+
+func TestBuildInsertStatement(t *testing.T) {
+	columns := []string{"device_id", "a34004_rtd"}
+	assert.Equal(t, "INSERT INTO dw.hj212 (`device_id`, `a34004_rtd`) VALUES (?, ?), (?, ?)",
+		buildInsertStatement("mysql", "dw.hj212", columns, 2))
+	assert.Equal(t, `INSERT INTO hj212 ("device_id", "a34004_rtd") VALUES ($1, $2), ($3, $4)`,
+		buildInsertStatement("postgresql", "hj212", columns, 2))
+	assert.Equal(t, "INSERT INTO hj212 ([device_id], [a34004_rtd]) VALUES (@p1, @p2)",
+		buildInsertStatement("sqlserver", "hj212", columns, 1))
+}
@@ -0,0 +1,480 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/env-data-platform/internal/kafka"
+	"github.com/env-data-platform/internal/models"
+)
+
+// Kafka消息的序列化格式
+const (
+	KafkaFormatJSON   = "json"
+	KafkaFormatCSV    = "csv"
+	KafkaFormatString = "string"
+)
+
+const (
+	kafkaDefaultBatchSize = 500
+	kafkaPollWait         = time.Second
+	// kafkaCommitTimeout 作业停止后提交offset的超时，使用独立的上下文保证取消后仍能提交
+	kafkaCommitTimeout = 10 * time.Second
+)
+
+// KafkaOptions Kafka源或目标的选项，数据源配置为默认值，作业的source_config/target_config可覆盖
+type KafkaOptions struct {
+	Brokers []string
+	Topic   string
+	// Group 消费组，作为源时必填，offset保存在该消费组中
+	Group  string
+	Format string
+	// Fields CSV格式的字段顺序，解析时作为字段名，生成时决定列顺序
+	Fields []string
+	// KeyField 作为目标时用作消息key的字段，相同key写入同一分区
+	KeyField string
+	// StartOffset 消费组没有已提交offset时的起始位置：earliest/latest
+	StartOffset string
+	// MaxMessages 单次执行最多消费的消息数，0表示消费到执行开始时的最新位置
+	MaxMessages int64
+	BatchSize   int
+}
+
+// parseKafkaOptions 合并数据源配置和作业配置中的Kafka选项
+func parseKafkaOptions(ds *models.DataSource, overrides map[string]interface{}) (*KafkaOptions, error) {
+	cfg, err := ds.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("解析Kafka数据源配置失败: %v", err)
+	}
+
+	opts := &KafkaOptions{
+		Brokers:     cfg.Brokers,
+		Topic:       cfg.Topic,
+		Group:       cfg.ConsumerGroup,
+		Format:      cfg.Serialization,
+		StartOffset: "earliest",
+		BatchSize:   kafkaDefaultBatchSize,
+	}
+
+	settings := map[string]interface{}{}
+	for k, v := range cfg.CustomConfig {
+		settings[k] = v
+	}
+	for k, v := range overrides {
+		settings[k] = v
+	}
+
+	if v := configStrings(settings["brokers"]); len(v) > 0 {
+		opts.Brokers = v
+	}
+	if v, ok := settings["topic"].(string); ok && v != "" {
+		opts.Topic = v
+	}
+	if v, ok := settings["consumer_group"].(string); ok && v != "" {
+		opts.Group = v
+	}
+	if v, ok := settings["serialization"].(string); ok && v != "" {
+		opts.Format = v
+	}
+	if v := configStrings(settings["fields"]); len(v) > 0 {
+		opts.Fields = v
+	}
+	if v, ok := settings["key_field"].(string); ok {
+		opts.KeyField = v
+	}
+	if v, ok := settings["start_offset"].(string); ok && v != "" {
+		opts.StartOffset = strings.ToLower(v)
+	}
+	if v, ok := settings["max_messages"].(float64); ok && v > 0 {
+		opts.MaxMessages = int64(v)
+	}
+	if v, ok := settings["batch_size"].(float64); ok && v > 0 {
+		opts.BatchSize = int(v)
+	}
+
+	opts.Format = strings.ToLower(opts.Format)
+	if opts.Format == "" {
+		opts.Format = KafkaFormatJSON
+	}
+	switch {
+	case len(opts.Brokers) == 0:
+		return nil, fmt.Errorf("Kafka数据源未配置brokers")
+	case opts.Topic == "":
+		return nil, fmt.Errorf("Kafka数据源未配置topic")
+	case opts.Format != KafkaFormatJSON && opts.Format != KafkaFormatCSV && opts.Format != KafkaFormatString:
+		return nil, fmt.Errorf("不支持的Kafka序列化格式: %s，可选值: json, csv, string", opts.Format)
+	case opts.StartOffset != "earliest" && opts.StartOffset != "latest":
+		return nil, fmt.Errorf("start_offset只能为earliest或latest")
+	}
+	return opts, nil
+}
+
+// configStrings 解析配置中的字符串数组或逗号分隔的字符串
+func configStrings(v interface{}) []string {
+	var values []string
+	switch t := v.(type) {
+	case string:
+		values = strings.Split(t, ",")
+	case []string:
+		values = t
+	case []interface{}:
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	result := make([]string, 0, len(values))
+	for _, s := range values {
+		if s = strings.TrimSpace(s); s != "" {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// newKafkaClient 按选项创建Kafka客户端
+func newKafkaClient(opts *KafkaOptions) (*kafka.Client, error) {
+	return kafka.NewClient(kafka.Config{Brokers: opts.Brokers})
+}
+
+// decodeKafkaRecord 按序列化格式将消息解析为记录
+func decodeKafkaRecord(opts *KafkaOptions, value []byte) (map[string]interface{}, error) {
+	switch opts.Format {
+	case KafkaFormatCSV:
+		values, err := csv.NewReader(bytes.NewReader(value)).Read()
+		if err != nil {
+			return nil, fmt.Errorf("解析CSV消息失败: %v", err)
+		}
+		record := make(map[string]interface{}, len(values))
+		for i, v := range values {
+			name := fmt.Sprintf("field_%d", i+1)
+			if i < len(opts.Fields) {
+				name = opts.Fields[i]
+			}
+			record[name] = v
+		}
+		return record, nil
+	case KafkaFormatString:
+		return map[string]interface{}{"value": string(value)}, nil
+	default:
+		decoder := json.NewDecoder(bytes.NewReader(value))
+		decoder.UseNumber()
+		var record map[string]interface{}
+		if err := decoder.Decode(&record); err != nil {
+			return nil, fmt.Errorf("解析JSON消息失败: %v", err)
+		}
+		if record == nil {
+			return nil, fmt.Errorf("JSON消息不是对象")
+		}
+		return record, nil
+	}
+}
+
+// encodeKafkaRecord 按序列化格式将记录生成消息，key_field非空时取该字段作为key
+func encodeKafkaRecord(opts *KafkaOptions, record map[string]interface{}) (kafka.Message, error) {
+	var msg kafka.Message
+	if opts.KeyField != "" {
+		if v, ok := record[opts.KeyField]; ok && v != nil {
+			msg.Key = []byte(formatRecordValue(v))
+		}
+	}
+
+	switch opts.Format {
+	case KafkaFormatCSV:
+		columns := opts.Fields
+		if len(columns) == 0 {
+			for name := range record {
+				columns = append(columns, name)
+			}
+			sort.Strings(columns)
+		}
+		values := make([]string, len(columns))
+		for i, name := range columns {
+			values[i] = formatRecordValue(record[name])
+		}
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Write(values)
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return msg, err
+		}
+		msg.Value = bytes.TrimRight(buf.Bytes(), "\r\n")
+	case KafkaFormatString:
+		v, ok := record["value"]
+		if !ok {
+			return msg, fmt.Errorf("string格式需要记录包含value字段")
+		}
+		msg.Value = []byte(formatRecordValue(v))
+	default:
+		value, err := json.Marshal(record)
+		if err != nil {
+			return msg, err
+		}
+		msg.Value = value
+	}
+	return msg, nil
+}
+
+// formatRecordValue 记录字段值的文本形式
+func formatRecordValue(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case []byte:
+		return string(t)
+	case json.Number:
+		return t.String()
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case time.Time:
+		return t.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+// executeKafkaSourceETL 从Kafka消费消息，目标为Kafka时按目标格式转发。
+// 只提交已处理完成的消息offset，作业被停止或超时时也会提交后再退出，下次执行从断点继续
+func (e *ETLExecutor) executeKafkaSourceETL(ctx context.Context, job *models.ETLJob, execution *models.ETLExecution, config *models.ETLJobConfig, result *ETLExecutionResult, logBuilder *strings.Builder) error {
+	logBuilder.WriteString(fmt.Sprintf("[%s] 开始执行Kafka数据ETL\n", time.Now().Format("2006-01-02 15:04:05")))
+
+	opts, err := parseKafkaOptions(job.Source, config.SourceConfig)
+	if err != nil {
+		return err
+	}
+	if opts.Group == "" {
+		return fmt.Errorf("Kafka源需要配置consumer_group")
+	}
+
+	var targetOpts *KafkaOptions
+	if job.Target != nil {
+		if job.Target.Type != models.DataSourceTypeKafka {
+			return fmt.Errorf("Kafka源暂不支持写入%s类型的目标数据源", job.Target.Type)
+		}
+		if targetOpts, err = parseKafkaOptions(job.Target, config.TargetConfig); err != nil {
+			return err
+		}
+	}
+
+	client, err := newKafkaClient(opts)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	reset := kafka.OffsetEarliest
+	if opts.StartOffset == "latest" {
+		reset = kafka.OffsetLatest
+	}
+	consumer, err := client.NewConsumer(ctx, opts.Topic, opts.Group, reset)
+	if err != nil {
+		return fmt.Errorf("创建Kafka消费者失败: %v", err)
+	}
+
+	var producer *kafka.Producer
+	var targetClient *kafka.Client
+	if targetOpts != nil {
+		if targetClient, err = newKafkaClient(targetOpts); err != nil {
+			return err
+		}
+		defer targetClient.Close()
+		if producer, err = targetClient.NewProducer(ctx, targetOpts.Topic); err != nil {
+			return fmt.Errorf("创建Kafka生产者失败: %v", err)
+		}
+	}
+
+	pending := consumer.Lag()
+	if opts.MaxMessages > 0 && opts.MaxMessages < pending {
+		pending = opts.MaxMessages
+	}
+	logBuilder.WriteString(fmt.Sprintf("[%s] 消费topic %s，消费组 %s，待消费 %d 条消息\n",
+		time.Now().Format("2006-01-02 15:04:05"), opts.Topic, opts.Group, pending))
+	if producer == nil {
+		logBuilder.WriteString(fmt.Sprintf("[%s] 未配置目标数据源，仅消费并校验消息\n", time.Now().Format("2006-01-02 15:04:05")))
+	}
+
+	runErr := e.consumeKafka(ctx, consumer, producer, opts, targetOpts, pending, execution, result)
+
+	// 无论是否被停止，都提交已处理消息的offset
+	commitCtx, cancel := context.WithTimeout(context.Background(), kafkaCommitTimeout)
+	defer cancel()
+	if err := consumer.Commit(commitCtx); err != nil {
+		e.logger.Error("Failed to commit kafka offsets",
+			zap.Uint("job_id", job.ID), zap.String("topic", opts.Topic), zap.Error(err))
+		if runErr == nil {
+			runErr = fmt.Errorf("提交Kafka offset失败: %v", err)
+		}
+	} else {
+		logBuilder.WriteString(fmt.Sprintf("[%s] 已提交消费组 %s 的offset\n", time.Now().Format("2006-01-02 15:04:05"), opts.Group))
+	}
+
+	logBuilder.WriteString(fmt.Sprintf("[%s] Kafka消费结束，消费 %d 条，输出 %d 条，错误 %d 条\n",
+		time.Now().Format("2006-01-02 15:04:05"), result.InputRows, result.OutputRows, result.ErrorRows))
+	return runErr
+}
+
+// consumeKafka 拉取并处理消息直至消费到执行开始时的最新位置或达到max_messages
+func (e *ETLExecutor) consumeKafka(ctx context.Context, consumer *kafka.Consumer, producer *kafka.Producer, opts, targetOpts *KafkaOptions, pending int64, execution *models.ETLExecution, result *ETLExecutionResult) error {
+	for !consumer.Done() {
+		if opts.MaxMessages > 0 && result.InputRows >= opts.MaxMessages {
+			break
+		}
+		msgs, err := consumer.Fetch(ctx, kafkaPollWait)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("拉取Kafka消息失败: %v", err)
+		}
+		// 超出max_messages的消息不标记，下次执行重新拉取
+		if opts.MaxMessages > 0 && int64(len(msgs)) > opts.MaxMessages-result.InputRows {
+			msgs = msgs[:opts.MaxMessages-result.InputRows]
+		}
+
+		var output []kafka.Message
+		var errorRows int64
+		for _, msg := range msgs {
+			record, err := decodeKafkaRecord(opts, msg.Value)
+			if err == nil && producer != nil {
+				var out kafka.Message
+				if out, err = encodeKafkaRecord(targetOpts, record); err == nil {
+					output = append(output, out)
+				}
+			}
+			if err != nil {
+				errorRows++
+				e.logger.Debug("Skip invalid kafka message",
+					zap.String("topic", msg.Topic), zap.Int32("partition", msg.Partition),
+					zap.Int64("offset", msg.Offset), zap.Error(err))
+			}
+		}
+
+		if producer != nil {
+			if err := producer.Send(ctx, output); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				return fmt.Errorf("写入Kafka失败: %v", err)
+			}
+		}
+		for _, msg := range msgs {
+			consumer.Mark(msg)
+		}
+
+		result.InputRows += int64(len(msgs))
+		result.ErrorRows += errorRows
+		result.OutputRows += int64(len(msgs)) - errorRows
+		if pending > 0 {
+			e.reportProgress(execution, int(90*result.InputRows/pending), result.InputRows)
+		}
+	}
+	return nil
+}
+
+// executeKafkaSinkETL 将数据库或HJ212数据源的数据逐行写入Kafka
+func (e *ETLExecutor) executeKafkaSinkETL(ctx context.Context, job *models.ETLJob, execution *models.ETLExecution, config *models.ETLJobConfig, result *ETLExecutionResult, logBuilder *strings.Builder) error {
+	logBuilder.WriteString(fmt.Sprintf("[%s] 开始执行数据写入Kafka\n", time.Now().Format("2006-01-02 15:04:05")))
+
+	opts, err := parseKafkaOptions(job.Target, config.TargetConfig)
+	if err != nil {
+		return err
+	}
+	client, err := newKafkaClient(opts)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	producer, err := client.NewProducer(ctx, opts.Topic)
+	if err != nil {
+		return fmt.Errorf("创建Kafka生产者失败: %v", err)
+	}
+
+	source, closeRows, err := e.queryExportRows(ctx, job, config)
+	if err != nil {
+		return err
+	}
+	defer closeRows()
+	rows, err := newTransformRows(source, config.Transformations)
+	if err != nil {
+		return err
+	}
+	result.pipeline = rows
+	e.reportProgress(execution, 10, 0)
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	batch := make([]kafka.Message, 0, opts.BatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := producer.Send(ctx, batch); err != nil {
+			return fmt.Errorf("写入Kafka失败: %v", err)
+		}
+		result.OutputRows += int64(len(batch))
+		batch = batch[:0]
+		e.reportProgress(execution, 50, result.InputRows)
+		return nil
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		result.InputRows++
+
+		record := make(map[string]interface{}, len(columns))
+		for i, name := range columns {
+			if values[i].Valid {
+				record[name] = values[i].String
+			} else {
+				record[name] = nil
+			}
+		}
+		msg, err := encodeKafkaRecord(opts, record)
+		if err != nil {
+			result.ErrorRows++
+			continue
+		}
+		batch = append(batch, msg)
+		if len(batch) >= opts.BatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	rows.report(result, logBuilder)
+
+	logBuilder.WriteString(fmt.Sprintf("[%s] 写入Kafka完成，topic %s，读取 %d 行，写入 %d 条，错误 %d 行\n",
+		time.Now().Format("2006-01-02 15:04:05"), opts.Topic, result.InputRows, result.OutputRows, result.ErrorRows))
+	return nil
+}
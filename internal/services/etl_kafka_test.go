@@ -0,0 +1,72 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/env-data-platform/internal/models"
+)
+
+func TestParseKafkaOptions(t *testing.T) {
+	ds := &models.DataSource{Type: models.DataSourceTypeKafka}
+	require.NoError(t, ds.SetConfig(models.DataSourceConfig{
+		Brokers:       []string{"kafka-1:9092"},
+		Topic:         "hj212-raw",
+		ConsumerGroup: "etl",
+		CustomConfig:  map[string]interface{}{"start_offset": "latest"},
+	}))
+
+	opts, err := parseKafkaOptions(ds, map[string]interface{}{
+		"brokers":      "kafka-1:9092, kafka-2:9092",
+		"fields":       []interface{}{"mn", "value"},
+		"max_messages": float64(1000),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"kafka-1:9092", "kafka-2:9092"}, opts.Brokers)
+	assert.Equal(t, "hj212-raw", opts.Topic)
+	assert.Equal(t, "etl", opts.Group)
+	assert.Equal(t, KafkaFormatJSON, opts.Format)
+	assert.Equal(t, "latest", opts.StartOffset)
+	assert.Equal(t, []string{"mn", "value"}, opts.Fields)
+	assert.Equal(t, int64(1000), opts.MaxMessages)
+	assert.Equal(t, kafkaDefaultBatchSize, opts.BatchSize)
+
+	_, err = parseKafkaOptions(ds, map[string]interface{}{"serialization": "avro"})
+	assert.Error(t, err)
+	_, err = parseKafkaOptions(&models.DataSource{Type: models.DataSourceTypeKafka}, nil)
+	assert.Error(t, err)
+}
+
+func TestKafkaRecordCodec(t *testing.T) {
+	jsonOpts := &KafkaOptions{Format: KafkaFormatJSON, KeyField: "mn"}
+	record, err := decodeKafkaRecord(jsonOpts, []byte(`{"mn":"MN001","value":12345678901234567}`))
+	require.NoError(t, err)
+	msg, err := encodeKafkaRecord(jsonOpts, record)
+	require.NoError(t, err)
+	assert.Equal(t, "MN001", string(msg.Key))
+	// 大整数保持原始精度
+	assert.JSONEq(t, `{"mn":"MN001","value":12345678901234567}`, string(msg.Value))
+
+	_, err = decodeKafkaRecord(jsonOpts, []byte(`[1,2]`))
+	assert.Error(t, err)
+
+	csvOpts := &KafkaOptions{Format: KafkaFormatCSV, Fields: []string{"mn", "value"}}
+	record, err = decodeKafkaRecord(csvOpts, []byte(`MN001,"1,5",extra`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"mn": "MN001", "value": "1,5", "field_3": "extra"}, record)
+	msg, err = encodeKafkaRecord(csvOpts, record)
+	require.NoError(t, err)
+	assert.Equal(t, `MN001,"1,5"`, string(msg.Value))
+	assert.Nil(t, msg.Key)
+
+	stringOpts := &KafkaOptions{Format: KafkaFormatString}
+	record, err = decodeKafkaRecord(stringOpts, []byte("QN=20240101"))
+	require.NoError(t, err)
+	msg, err = encodeKafkaRecord(stringOpts, record)
+	require.NoError(t, err)
+	assert.Equal(t, "QN=20240101", string(msg.Value))
+	_, err = encodeKafkaRecord(stringOpts, map[string]interface{}{"mn": "MN001"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/config"
+	"github.com/env-data-platform/internal/models"
+)
+
+// ErrETLJobRunning 作业已有执行在进行中，重复触发被拒绝
+var ErrETLJobRunning = errors.New("作业正在运行，请等待当前执行结束")
+
+// defaultETLRunStaleTimeout 未配置时执行锁心跳的过期时长
+const defaultETLRunStaleTimeout = 5 * time.Minute
+
+// etlInstanceID 当前进程的实例标识，作为执行锁的持有者；重启后标识不同，旧的锁只能等心跳过期后接管
+var etlInstanceID = newETLInstanceID()
+
+func newETLInstanceID() string {
+	host, _ := os.Hostname()
+	buf := make([]byte, 4)
+	rand.Read(buf)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(buf))
+}
+
+// etlRunStaleTimeout 执行锁心跳超过该时长未刷新即视为持有实例已停止
+func etlRunStaleTimeout() time.Duration {
+	if config.GlobalConfig != nil && config.GlobalConfig.ETL.Scheduler.StaleRunTimeout > 0 {
+		return config.GlobalConfig.ETL.Scheduler.StaleRunTimeout
+	}
+	return defaultETLRunStaleTimeout
+}
+
+// ClaimETLJobRun 以条件更新把作业原子地置为运行中并记录持有实例和心跳，作为同一作业的执行锁。
+// 手动执行与定时调度并发触发时只有一个能更新成功，其余返回ErrETLJobRunning。
+// 锁在执行期间由 StartETLRunHeartbeat 续期，只有心跳超过stale_run_timeout未刷新
+// （持有实例已停止）的锁才能被接管。执行结束后把status改回idle即释放
+func ClaimETLJobRun(db *gorm.DB, jobID uint) error {
+	now := time.Now()
+	result := db.Model(&models.ETLJob{}).
+		Where("id = ? AND (status <> ? OR run_heartbeat IS NULL OR run_heartbeat < ?)",
+			jobID, models.ETLStatusRunning, now.Add(-etlRunStaleTimeout())).
+		Updates(map[string]interface{}{
+			"status":        models.ETLStatusRunning,
+			"run_owner":     etlInstanceID,
+			"run_heartbeat": now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrETLJobRunning
+	}
+	return nil
+}
+
+// ReleaseETLJobRun 已取得执行锁但未能开始执行（如创建执行记录失败）时释放，只释放本实例持有的锁
+func ReleaseETLJobRun(db *gorm.DB, jobID uint) error {
+	return db.Model(&models.ETLJob{}).
+		Where("id = ? AND status = ? AND run_owner = ?", jobID, models.ETLStatusRunning, etlInstanceID).
+		Update("status", "idle").Error
+}
+
+// StartETLRunHeartbeat 在执行期间定期刷新本实例持有的执行锁心跳，返回的函数停止续期
+func StartETLRunHeartbeat(db *gorm.DB, jobID uint) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(etlRunStaleTimeout() / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				db.Model(&models.ETLJob{}).
+					Where("id = ? AND status = ? AND run_owner = ?", jobID, models.ETLStatusRunning, etlInstanceID).
+					UpdateColumn("run_heartbeat", now)
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// RecoverStaleETLRuns 回收心跳已过期的执行锁：作业恢复为空闲，其下仍在运行的执行记录标记为失败。
+// 只处理持有实例已停止的作业，其他实例正在执行的作业心跳持续刷新，不受影响。返回被标记失败的执行数
+func RecoverStaleETLRuns(db *gorm.DB) (int64, error) {
+	now := time.Now()
+	staleBefore := now.Add(-etlRunStaleTimeout())
+
+	var jobIDs []uint
+	if err := db.Model(&models.ETLJob{}).
+		Where("status = ? AND (run_heartbeat IS NULL OR run_heartbeat < ?)", models.ETLStatusRunning, staleBefore).
+		Pluck("id", &jobIDs).Error; err != nil {
+		return 0, err
+	}
+
+	var recovered int64
+	for _, jobID := range jobIDs {
+		// 条件更新避免与接管同一作业的其他实例冲突，只有重置成功的作业才收尾其执行记录
+		result := db.Model(&models.ETLJob{}).
+			Where("id = ? AND status = ? AND (run_heartbeat IS NULL OR run_heartbeat < ?)", jobID, models.ETLStatusRunning, staleBefore).
+			Updates(map[string]interface{}{"status": "idle", "run_owner": ""})
+		if result.Error != nil {
+			return recovered, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		// 重置之后才开始的执行属于新取得锁的实例，不能误标
+		result = db.Model(&models.ETLExecution{}).
+			Where("job_id = ? AND status = ? AND start_time < ?", jobID, models.ETLStatusRunning, now).
+			Updates(map[string]interface{}{
+				"status":        models.ETLStatusFailed,
+				"end_time":      now,
+				"error_message": "执行实例已停止，执行被中断",
+			})
+		if result.Error != nil {
+			return recovered, result.Error
+		}
+		recovered += result.RowsAffected
+	}
+	return recovered, nil
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/env-data-platform/internal/database/dbtest"
+	"github.com/env-data-platform/internal/models"
+)
+
+func TestClaimETLJobRunTakesOverOnlyStaleClaims(t *testing.T) {
+	db := dbtest.New(t)
+
+	before := time.Now()
+	// DryRun不影响任何行，视为作业已被其他实例持有
+	assert.ErrorIs(t, ClaimETLJobRun(db.DB, 7), ErrETLJobRunning)
+	statements := db.Statements()
+	require.Len(t, statements, 1)
+
+	stmt := statements[0]
+	assert.Contains(t, stmt.SQL, "WHERE (id = ? AND (status <> ? OR run_heartbeat IS NULL OR run_heartbeat < ?))")
+	assert.Contains(t, stmt.Vars, etlInstanceID)
+	staleBefore := stmt.Vars[len(stmt.Vars)-1].(time.Time)
+	assert.WithinDuration(t, before.Add(-defaultETLRunStaleTimeout), staleBefore, time.Second)
+
+	db.Reset()
+	require.NoError(t, ReleaseETLJobRun(db.DB, 7))
+	statements = db.Statements()
+	require.Len(t, statements, 1)
+	assert.Contains(t, statements[0].SQL, "run_owner = ?")
+	assert.Contains(t, statements[0].Vars, etlInstanceID)
+}
+
+func TestRecoverStaleETLRunsSkipsLiveClaims(t *testing.T) {
+	db := dbtest.New(t)
+
+	recovered, err := RecoverStaleETLRuns(db.DB)
+	require.NoError(t, err)
+	assert.Zero(t, recovered)
+
+	// 只查询心跳过期的运行中作业，不再一律重置所有运行中的作业和执行记录
+	statements := db.Statements()
+	require.Len(t, statements, 1)
+	stmt := statements[0]
+	assert.Contains(t, stmt.SQL, "status = ? AND (run_heartbeat IS NULL OR run_heartbeat < ?)")
+	assert.Equal(t, models.ETLStatusRunning, stmt.Vars[0])
+}
+
+func TestNewETLInstanceID(t *testing.T) {
+	assert.NotEqual(t, newETLInstanceID(), newETLInstanceID())
+	assert.LessOrEqual(t, len(etlInstanceID), 100)
+}
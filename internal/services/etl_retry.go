@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/models"
+)
+
+// ETL失败重试的退避间隔，第n次重试等待 base×2^(n-1)，不超过max
+var (
+	etlRetryBaseDelay = 10 * time.Second
+	etlRetryMaxDelay  = 5 * time.Minute
+)
+
+// retryableMessages 错误链被%v展开后只剩文本时，按这些连接类错误的关键字判断可重试
+var retryableMessages = []string{
+	"connection refused",
+	"connection reset",
+	"broken pipe",
+	"i/o timeout",
+	"no route to host",
+	"network is unreachable",
+	"too many connections",
+	"server has gone away",
+	"bad connection",
+	"tls handshake timeout",
+	"temporary failure in name resolution",
+}
+
+// IsRetryableETLError 判断ETL失败是否可重试：连接超时、连接被拒绝或重置等临时故障可重试；
+// 配置错误、数据错误、手动停止和作业超时不重试
+func IsRetryableETLError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	// 主机名不存在属于配置错误，不能因外层包装的 *net.OpError 被当作临时故障
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return !dnsErr.IsNotFound
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	message := strings.ToLower(err.Error())
+	for _, keyword := range retryableMessages {
+		if strings.Contains(message, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// etlRetryDelay 第attempt次重试前的等待时长
+func etlRetryDelay(attempt int) time.Duration {
+	delay := etlRetryBaseDelay
+	for i := 1; i < attempt && delay < etlRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > etlRetryMaxDelay {
+		delay = etlRetryMaxDelay
+	}
+	return delay
+}
+
+// ExecuteJobWithRetry 执行作业，失败且错误可重试时按作业的MaxRetries带退避重试。
+// 每次重试新建一条执行记录，失败的那次标记为retrying；等待期间作业被暂停、禁用或删除时不再重试。
+// 返回最后一次执行的记录和结果，由调用方按最终结果更新执行记录和作业统计
+func (e *ETLExecutor) ExecuteJobWithRetry(ctx context.Context, job *models.ETLJob, execution *models.ETLExecution, parameters map[string]interface{}) (*models.ETLExecution, *ETLExecutionResult) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	for {
+		result := e.ExecuteJob(ctx, job, execution, parameters)
+		if result.Status == models.ETLStatusSuccess || !result.Retryable || execution.Attempt >= job.MaxRetries {
+			return execution, result
+		}
+
+		attempt := execution.Attempt + 1
+		delay := etlRetryDelay(attempt)
+		e.finishRetriedAttempt(execution, result, attempt, delay)
+		etlRetriesTotal.Inc()
+
+		if !e.waitRetry(ctx, job.ID, execution.ExecutionID, delay) {
+			// 与手动停止运行中作业时写入的状态一致
+			result.Status = "cancelled"
+			result.Retryable = false
+			result.ErrorMessage = fmt.Sprintf("等待重试时被手动停止，上次失败原因: %s", result.ErrorMessage)
+			return execution, result
+		}
+		if reason := e.retryStopReason(job.ID); reason != "" {
+			e.logger.Info("ETL job retry skipped",
+				zap.Uint("job_id", job.ID),
+				zap.String("execution_id", execution.ExecutionID),
+				zap.String("reason", reason))
+			result.Status = "cancelled"
+			result.Retryable = false
+			result.ErrorMessage = fmt.Sprintf("%s，不再重试，上次失败原因: %s", reason, result.ErrorMessage)
+			return execution, result
+		}
+
+		next, err := e.createRetryExecution(execution, attempt)
+		if err != nil {
+			e.logger.Error("Failed to create retry execution record",
+				zap.Uint("job_id", job.ID),
+				zap.String("execution_id", execution.ExecutionID),
+				zap.Error(err))
+			result.Status = models.ETLStatusFailed
+			return execution, result
+		}
+		execution = next
+	}
+}
+
+// finishRetriedAttempt 将失败但会重试的执行记录标记为retrying
+func (e *ETLExecutor) finishRetriedAttempt(execution *models.ETLExecution, result *ETLExecutionResult, attempt int, delay time.Duration) {
+	e.logger.Warn("ETL job failed, retrying",
+		zap.Uint("job_id", execution.JobID),
+		zap.String("execution_id", execution.ExecutionID),
+		zap.Int("attempt", attempt),
+		zap.Duration("delay", delay),
+		zap.String("error", result.ErrorMessage))
+
+	if e.db == nil {
+		return
+	}
+	endTime := time.Now()
+	execution.EndTime = &endTime
+	execution.Duration = endTime.Sub(execution.StartTime).Milliseconds()
+	logContent := result.LogContent + fmt.Sprintf("[%s] 错误可重试，%s后进行第%d次重试\n",
+		endTime.Format("2006-01-02 15:04:05"), delay, attempt)
+	e.db.Model(execution).Updates(map[string]interface{}{
+		"status":        models.ETLStatusRetrying,
+		"end_time":      endTime,
+		"duration":      execution.Duration,
+		"input_rows":    result.InputRows,
+		"output_rows":   result.OutputRows,
+		"error_rows":    result.ErrorRows,
+		"skipped_rows":  result.SkippedRows,
+		"error_message": result.ErrorMessage,
+		"log_content":   logContent,
+	})
+}
+
+// waitRetry 等待退避时间，期间作业视为运行中，可被StopJob取消；被取消时返回false
+func (e *ETLExecutor) waitRetry(ctx context.Context, jobID uint, executionID string, delay time.Duration) bool {
+	waitCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	e.mutex.Lock()
+	e.runningJobs[jobID] = &JobExecution{
+		JobID:       jobID,
+		ExecutionID: executionID,
+		Context:     waitCtx,
+		Cancel:      cancel,
+		StartTime:   time.Now(),
+	}
+	e.mutex.Unlock()
+	defer func() {
+		e.mutex.Lock()
+		delete(e.runningJobs, jobID)
+		e.mutex.Unlock()
+	}()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-waitCtx.Done():
+		return false
+	}
+}
+
+// retryStopReason 重新读取作业状态，等待重试期间作业被暂停、禁用或删除时返回不再重试的原因
+func (e *ETLExecutor) retryStopReason(jobID uint) string {
+	if e.db == nil {
+		return ""
+	}
+	var job models.ETLJob
+	if err := e.db.Select("id", "is_enabled", "is_paused").First(&job, jobID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "作业已删除"
+		}
+		return fmt.Sprintf("查询作业失败: %v", err)
+	}
+	switch {
+	case job.IsPaused:
+		return "作业已暂停"
+	case !job.IsEnabled:
+		return "作业已禁用"
+	}
+	return ""
+}
+
+// createRetryExecution 为重试新建执行记录，触发方式和触发人沿用首次执行
+func (e *ETLExecutor) createRetryExecution(previous *models.ETLExecution, attempt int) (*models.ETLExecution, error) {
+	retryOf := previous.RetryOf
+	if retryOf == "" {
+		retryOf = previous.ExecutionID
+	}
+	execution := &models.ETLExecution{
+		JobID:       previous.JobID,
+		ExecutionID: generateExecutionID(),
+		Status:      models.ETLStatusRunning,
+		StartTime:   time.Now(),
+		TriggerType: previous.TriggerType,
+		TriggerBy:   previous.TriggerBy,
+		Attempt:     attempt,
+		RetryOf:     retryOf,
+	}
+	if e.db == nil {
+		return execution, nil
+	}
+	if err := e.db.Create(execution).Error; err != nil {
+		return nil, err
+	}
+	NotifyDashboardChange(DashboardTriggerETL)
+	return execution, nil
+}
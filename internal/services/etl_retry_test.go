@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/database/dbtest"
+	"github.com/env-data-platform/internal/models"
+)
+
+func TestIsRetryableETLError(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	lookupErr := func(dnsErr *net.DNSError) error {
+		return fmt.Errorf("连接源数据库失败: %w", &net.OpError{Op: "dial", Net: "tcp", Err: dnsErr})
+	}
+
+	retryable := []error{
+		dialErr,
+		fmt.Errorf("连接源数据库失败: %w", dialErr),
+		fmt.Errorf("查询失败: %w", driver.ErrBadConn),
+		// 错误链被%v展开为文本
+		fmt.Errorf("连接源数据库失败: %v", dialErr),
+		errors.New("dial tcp 10.0.0.1:3306: i/o timeout"),
+		errors.New("Error 1040: Too many connections"),
+		// DNS服务器暂时不可用
+		lookupErr(&net.DNSError{Err: "server misbehaving", Name: "db.internal", IsTemporary: true}),
+	}
+	for _, err := range retryable {
+		assert.True(t, IsRetryableETLError(err), err.Error())
+	}
+
+	nonRetryable := []error{
+		nil,
+		errors.New("解析作业配置失败: invalid character"),
+		errors.New("不支持的数据源类型: ftp"),
+		context.Canceled,
+		fmt.Errorf("执行超时: %w", context.DeadlineExceeded),
+		// 主机名拼写错误等，重试也不会成功
+		lookupErr(&net.DNSError{Err: "no such host", Name: "db.invalid", IsNotFound: true}),
+	}
+	for _, err := range nonRetryable {
+		assert.False(t, IsRetryableETLError(err), fmt.Sprint(err))
+	}
+}
+
+func TestETLRetryDelay(t *testing.T) {
+	assert.Equal(t, 10*time.Second, etlRetryDelay(1))
+	assert.Equal(t, 20*time.Second, etlRetryDelay(2))
+	assert.Equal(t, 40*time.Second, etlRetryDelay(3))
+	assert.Equal(t, 5*time.Minute, etlRetryDelay(10))
+}
+
+func TestExecuteJobWithRetryNonRetryable(t *testing.T) {
+	e := &ETLExecutor{logger: zap.NewNop(), runningJobs: make(map[uint]*JobExecution)}
+	job := &models.ETLJob{
+		Source:     &models.DataSource{Type: "ftp"},
+		MaxRetries: 3,
+		Timeout:    10,
+	}
+	job.ID = 1
+	execution := &models.ETLExecution{JobID: 1, ExecutionID: "exec_1"}
+
+	// 配置类错误不重试，直接返回首次执行的记录
+	last, result := e.ExecuteJobWithRetry(context.Background(), job, execution, nil)
+	assert.Same(t, execution, last)
+	assert.Equal(t, models.ETLStatusFailed, result.Status)
+	assert.False(t, result.Retryable)
+	assert.Zero(t, last.Attempt)
+}
+
+func TestETLRetryStopReason(t *testing.T) {
+	tests := []struct {
+		name     string
+		job      models.ETLJob
+		notFound bool
+		expected string
+	}{
+		{name: "正常", job: models.ETLJob{IsEnabled: true}, expected: ""},
+		{name: "已暂停", job: models.ETLJob{IsEnabled: true, IsPaused: true}, expected: "作业已暂停"},
+		{name: "已禁用", job: models.ETLJob{}, expected: "作业已禁用"},
+		{name: "已删除", notFound: true, expected: "作业已删除"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.New(t)
+			db.On(dbtest.KindQuery, func(tx *gorm.DB) {
+				if tt.notFound {
+					tx.AddError(gorm.ErrRecordNotFound)
+				} else if job, ok := tx.Statement.Dest.(*models.ETLJob); ok {
+					*job = tt.job
+				}
+			})
+			e := &ETLExecutor{db: db.DB, logger: zap.NewNop()}
+
+			assert.Equal(t, tt.expected, e.retryStopReason(7))
+
+			// 每次重试前按ID重新读取，已软删除的作业查不到
+			statements := db.Statements()
+			require.Len(t, statements, 1)
+			assert.Contains(t, statements[0].SQL, "`env_etl_jobs`.`id` = ?")
+			assert.Contains(t, statements[0].SQL, "`deleted_at` IS NULL")
+			assert.Equal(t, uint(7), statements[0].Vars[0])
+		})
+	}
+}
@@ -1,10 +1,13 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
+	"github.com/env-data-platform/internal/config"
 	"github.com/env-data-platform/internal/database"
 	"github.com/env-data-platform/internal/models"
 	"github.com/robfig/cron/v3"
@@ -22,10 +25,34 @@ type ETLScheduler struct {
 	executor *ETLExecutor
 }
 
+// cronParser 带秒级精度的cron解析器，调度和表达式校验共用
+var cronParser = cron.NewParser(
+	cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// NextCronRuns 校验cron表达式并返回from之后的count次执行时间
+func NextCronRuns(expr string, from time.Time, count int) ([]time.Time, error) {
+	schedule, err := cronParser.Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	runs := make([]time.Time, 0, count)
+	next := from
+	for i := 0; i < count; i++ {
+		next = schedule.Next(next)
+		if next.IsZero() {
+			break
+		}
+		runs = append(runs, next)
+	}
+	return runs, nil
+}
+
 // NewETLScheduler 创建ETL调度器
 func NewETLScheduler(logger *zap.Logger) *ETLScheduler {
 	// 创建带秒级精度的cron调度器
-	c := cron.New(cron.WithSeconds())
+	c := cron.New(cron.WithParser(cronParser))
 
 	scheduler := &ETLScheduler{
 		cron:     c,
@@ -38,31 +65,102 @@ func NewETLScheduler(logger *zap.Logger) *ETLScheduler {
 	// 启动调度器
 	c.Start()
 
+	// 回收已停止实例遗留的执行锁，之后定期巡检，其他实例正在执行的作业心跳持续刷新不受影响
+	scheduler.recoverInterruptedExecutions()
+	if _, err := c.AddFunc(fmt.Sprintf("@every %s", etlRunStaleTimeout()), scheduler.recoverInterruptedExecutions); err != nil {
+		logger.Error("Failed to schedule stale ETL run recovery", zap.Error(err))
+	}
+
 	// 从数据库加载已启用的作业
 	scheduler.LoadJobsFromDB()
 
 	return scheduler
 }
 
-// LoadJobsFromDB 从数据库加载已启用的作业
+// recoverInterruptedExecutions 回收心跳已过期的执行锁，将对应的执行记录标记为失败并把作业恢复为空闲，
+// 否则持有实例停止后作业一直处于running，之后的调度都会被跳过
+func (s *ETLScheduler) recoverInterruptedExecutions() {
+	count, err := RecoverStaleETLRuns(s.db)
+	if err != nil {
+		s.logger.Error("Failed to recover interrupted ETL executions", zap.Error(err))
+	} else if count > 0 {
+		s.logger.Warn("Marked interrupted ETL executions as failed", zap.Int64("count", count))
+	}
+}
+
+// LoadJobsFromDB 从数据库加载已启用且未暂停的作业，按配置补跑停机期间错过的调度
 func (s *ETLScheduler) LoadJobsFromDB() {
 	var jobs []models.ETLJob
-	err := s.db.Where("is_enabled = ? AND cron_expr != ''", true).Find(&jobs).Error
+	err := s.db.Where("is_enabled = ? AND is_paused = ? AND cron_expr != ''", true, false).Find(&jobs).Error
 	if err != nil {
 		s.logger.Error("Failed to load jobs from database", zap.Error(err))
 		return
 	}
 
+	var schedulerConfig config.ETLSchedulerConfig
+	if config.GlobalConfig != nil {
+		schedulerConfig = config.GlobalConfig.ETL.Scheduler
+	}
+
+	now := time.Now()
+	var missed []uint
 	for _, job := range jobs {
+		// ScheduleJob会覆盖下次运行时间，先记下停机前计划的时间
+		plannedRun := job.NextRunAt
 		if err := s.ScheduleJob(&job); err != nil {
 			s.logger.Error("Failed to schedule job from database",
 				zap.Uint("job_id", job.ID),
 				zap.String("job_name", job.Name),
 				zap.Error(err))
+			continue
+		}
+		if missedRun(plannedRun, now, schedulerConfig.MaxCatchUpDelay) {
+			s.logger.Warn("ETL job missed its schedule while the service was down",
+				zap.Uint("job_id", job.ID),
+				zap.String("job_name", job.Name),
+				zap.Time("planned_run", *plannedRun),
+				zap.Bool("catch_up", schedulerConfig.CatchUp))
+			missed = append(missed, job.ID)
 		}
 	}
 
-	s.logger.Info("Loaded ETL jobs from database", zap.Int("count", len(jobs)))
+	s.logger.Info("Loaded ETL jobs from database",
+		zap.Int("count", len(jobs)),
+		zap.Int("missed", len(missed)))
+
+	// 补跑在后台进行，不阻塞调度器创建和服务启动
+	if schedulerConfig.CatchUp && len(missed) > 0 {
+		go func() {
+			for _, jobID := range missed {
+				s.executeScheduledJob(jobID)
+			}
+		}()
+	}
+}
+
+// missedRun 停机前计划的运行时间已过且未超过补跑时限，maxDelay为0表示不限制
+func missedRun(plannedRun *time.Time, now time.Time, maxDelay time.Duration) bool {
+	if plannedRun == nil || !plannedRun.Before(now) {
+		return false
+	}
+	return maxDelay <= 0 || now.Sub(*plannedRun) <= maxDelay
+}
+
+// nextRun 作业在调度器中的下次运行时间，未调度时返回nil
+func (s *ETLScheduler) nextRun(jobID uint) *time.Time {
+	s.mutex.RLock()
+	entryID, exists := s.jobs[jobID]
+	s.mutex.RUnlock()
+	if !exists {
+		return nil
+	}
+
+	entry := s.cron.Entry(entryID)
+	if entry.Schedule == nil {
+		return nil
+	}
+	next := entry.Schedule.Next(time.Now())
+	return &next
 }
 
 // ScheduleJob 调度作业
@@ -80,9 +178,10 @@ func (s *ETLScheduler) ScheduleJob(job *models.ETLJob) error {
 		delete(s.jobs, job.ID)
 	}
 
-	// 添加新的调度
+	// 添加新的调度，按值捕获作业ID，调用方可能传入循环变量的地址
+	jobID := job.ID
 	entryID, err := s.cron.AddFunc(job.CronExpr, func() {
-		s.executeScheduledJob(job.ID)
+		s.executeScheduledJob(jobID)
 	})
 
 	if err != nil {
@@ -134,9 +233,21 @@ func (s *ETLScheduler) executeScheduledJob(jobID uint) {
 		return
 	}
 
-	// 检查作业是否已在运行
-	if job.Status == "running" {
-		s.logger.Warn("Job is already running, skipping", zap.Uint("job_id", jobID))
+	// 暂停中的作业不执行
+	if job.IsPaused {
+		s.logger.Info("Skipping paused job", zap.Uint("job_id", jobID))
+		return
+	}
+
+	// 取得作业执行锁，作业已在运行（含并发的手动执行）时跳过本次调度
+	if err := ClaimETLJobRun(s.db, jobID); err != nil {
+		if errors.Is(err, ErrETLJobRunning) {
+			s.logger.Warn("Job is already running, skipping", zap.Uint("job_id", jobID))
+		} else {
+			s.logger.Error("Failed to lock job for scheduled execution",
+				zap.Uint("job_id", jobID),
+				zap.Error(err))
+		}
 		return
 	}
 
@@ -153,15 +264,22 @@ func (s *ETLScheduler) executeScheduledJob(jobID uint) {
 		s.logger.Error("Failed to create execution record for scheduled job",
 			zap.Uint("job_id", jobID),
 			zap.Error(err))
+		if err := ReleaseETLJobRun(s.db, jobID); err != nil {
+			s.logger.Error("Failed to release job lock", zap.Uint("job_id", jobID), zap.Error(err))
+		}
 		return
 	}
 
-	// 更新作业状态
-	s.db.Model(&job).Updates(map[string]interface{}{
-		"status":      "running",
+	// 更新作业运行信息，同时刷新下次运行时间，重启时据此判断是否错过调度
+	jobUpdates := map[string]interface{}{
 		"last_run_at": gorm.Expr("NOW()"),
 		"run_count":   gorm.Expr("run_count + 1"),
-	})
+	}
+	if next := s.nextRun(jobID); next != nil {
+		jobUpdates["next_run_at"] = *next
+	}
+	s.db.Model(&job).Updates(jobUpdates)
+	NotifyDashboardChange(DashboardTriggerETL)
 
 	s.logger.Info("Starting scheduled ETL job execution",
 		zap.Uint("job_id", jobID),
@@ -194,24 +312,41 @@ func (s *ETLScheduler) executeJobAsync(job *models.ETLJob, execution *models.ETL
 				"status":        "idle",
 				"failure_count": gorm.Expr("failure_count + 1"),
 			})
+			NotifyDashboardChange(DashboardTriggerETL)
 		}
 	}()
 
-	// 执行ETL作业
-	result := s.executor.ExecuteJob(nil, job, execution, nil)
+	// 执行期间续期执行锁，避免被其他实例视为已停止而接管
+	stopHeartbeat := StartETLRunHeartbeat(s.db, job.ID)
+	defer stopHeartbeat()
+
+	// 执行ETL作业，可重试的失败按MaxRetries重试，execution为最后一次执行的记录
+	execution, result := s.executor.ExecuteJobWithRetry(context.Background(), job, execution, nil)
 
 	// 更新执行记录
 	endTime := time.Now()
+	execution.EndTime = &endTime
+	execution.Duration = endTime.Sub(execution.StartTime).Milliseconds()
+	execution.InputRows = result.InputRows
+	execution.CalculateThroughput()
+
 	updates := map[string]interface{}{
-		"status":        result.Status,
-		"end_time":      endTime,
-		"duration":      endTime.Sub(execution.StartTime).Milliseconds(),
-		"input_rows":    result.InputRows,
-		"output_rows":   result.OutputRows,
-		"error_rows":    result.ErrorRows,
-		"skipped_rows":  result.SkippedRows,
-		"error_message": result.ErrorMessage,
-		"log_content":   result.LogContent,
+		"status":          result.Status,
+		"end_time":        endTime,
+		"duration":        execution.Duration,
+		"input_rows":      result.InputRows,
+		"output_rows":     result.OutputRows,
+		"error_rows":      result.ErrorRows,
+		"skipped_rows":    result.SkippedRows,
+		"error_message":   result.ErrorMessage,
+		"log_content":     result.LogContent,
+		"rows_per_second": execution.RowsPerSecond,
+	}
+	if result.Status == "success" {
+		updates["progress"] = 100
+	}
+	if result.OutputFileID != nil {
+		updates["output_file_id"] = *result.OutputFileID
 	}
 
 	s.db.Model(execution).Updates(updates)
@@ -228,14 +363,16 @@ func (s *ETLScheduler) executeJobAsync(job *models.ETLJob, execution *models.ETL
 	}
 
 	s.db.Model(job).Updates(jobUpdates)
+	NotifyDashboardChange(DashboardTriggerETL)
 
 	s.logger.Info("Scheduled ETL job execution completed",
 		zap.Uint("job_id", job.ID),
 		zap.String("execution_id", execution.ExecutionID),
 		zap.String("status", result.Status),
-		zap.Int64("duration_ms", endTime.Sub(execution.StartTime).Milliseconds()),
+		zap.Int64("duration_ms", execution.Duration),
 		zap.Int64("input_rows", result.InputRows),
-		zap.Int64("output_rows", result.OutputRows))
+		zap.Int64("output_rows", result.OutputRows),
+		zap.Float64("rows_per_second", execution.RowsPerSecond))
 }
 
 // GetJobStatus 获取作业调度状态
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/database/dbtest"
+	"github.com/env-data-platform/internal/models"
+)
+
+func TestNextCronRuns(t *testing.T) {
+	from := time.Date(2024, 3, 1, 8, 0, 0, 0, time.Local)
+
+	runs, err := NextCronRuns("0 30 * * * *", from, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []time.Time{
+		time.Date(2024, 3, 1, 8, 30, 0, 0, time.Local),
+		time.Date(2024, 3, 1, 9, 30, 0, 0, time.Local),
+		time.Date(2024, 3, 1, 10, 30, 0, 0, time.Local),
+	}, runs)
+
+	runs, err = NextCronRuns("@daily", from, 1)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 2, 0, 0, 0, 0, time.Local), runs[0])
+
+	// 调度器要求秒级字段，五段式表达式无效
+	_, err = NextCronRuns("*/5 * * * *", from, 1)
+	assert.Error(t, err)
+	_, err = NextCronRuns("0 61 * * * *", from, 1)
+	assert.Error(t, err)
+}
+
+func TestMissedRun(t *testing.T) {
+	now := time.Date(2024, 3, 1, 8, 0, 0, 0, time.Local)
+	at := func(d time.Duration) *time.Time {
+		v := now.Add(d)
+		return &v
+	}
+
+	assert.False(t, missedRun(nil, now, time.Hour))
+	assert.False(t, missedRun(at(time.Minute), now, time.Hour), "尚未到计划时间")
+	assert.True(t, missedRun(at(-30*time.Minute), now, time.Hour))
+	assert.False(t, missedRun(at(-2*time.Hour), now, time.Hour), "超过补跑时限")
+	assert.True(t, missedRun(at(-48*time.Hour), now, 0), "0表示不限制")
+}
+
+func TestExecuteScheduledJobSkipsPausedJobs(t *testing.T) {
+	tests := []struct {
+		name    string
+		job     models.ETLJob
+		claimed bool
+	}{
+		{name: "启用", job: models.ETLJob{IsEnabled: true}, claimed: true},
+		{name: "已暂停", job: models.ETLJob{IsEnabled: true, IsPaused: true}},
+		{name: "已禁用", job: models.ETLJob{}},
+		{name: "已禁用且暂停", job: models.ETLJob{IsPaused: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.New(t)
+			db.On(dbtest.KindQuery, func(tx *gorm.DB) {
+				if job, ok := tx.Statement.Dest.(*models.ETLJob); ok {
+					*job = tt.job
+					job.ID = 7
+				}
+			})
+			s := &ETLScheduler{db: db.DB, logger: zap.NewNop()}
+
+			s.executeScheduledJob(7)
+
+			// 暂停或禁用的作业不争抢执行锁，也不创建执行记录；
+			// DryRun下争抢影响0行，启用的作业视为正在运行而跳过
+			updates := db.Statements(dbtest.KindUpdate)
+			if !tt.claimed {
+				assert.Empty(t, updates)
+			} else {
+				require.Len(t, updates, 1)
+				assert.Contains(t, updates[0].SQL, "`run_owner`=?")
+				assert.Contains(t, updates[0].SQL, "WHERE (id = ? AND")
+				assert.Equal(t, uint(7), updates[0].Vars[4])
+			}
+			assert.Empty(t, db.Statements(dbtest.KindCreate))
+		})
+	}
+}
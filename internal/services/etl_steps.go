@@ -0,0 +1,90 @@
+package services
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/env-data-platform/internal/models"
+)
+
+// 步骤状态
+const (
+	etlStepSuccess = "success"
+	etlStepFailed  = "failed"
+)
+
+// executionSteps 按读取、各转换步骤、写入的顺序生成步骤记录，err为执行返回的错误。
+// 数据逐行流经各步骤，步骤耗时为处理全部行的累计耗时，写入耗时为总耗时扣除读取和转换的部分
+func (r *transformRows) executionSteps(result *ETLExecutionResult, err error) []models.ETLExecutionStep {
+	end := time.Now()
+	steps := make([]models.ETLExecutionStep, 0, len(r.steps)+2)
+	newStep := func(name, stepType string, duration time.Duration) models.ETLExecutionStep {
+		return models.ETLExecutionStep{
+			StepName:  name,
+			StepType:  stepType,
+			Status:    etlStepSuccess,
+			StartTime: r.started,
+			EndTime:   &end,
+			Duration:  duration.Milliseconds(),
+			StepOrder: len(steps) + 1,
+		}
+	}
+
+	// 读取源数据或扫描行失败时归为读取步骤的错误，否则归为写入步骤
+	extract := newStep("读取源数据", models.ETLStepExtract, r.sourceTime)
+	extract.InputRows = r.line
+	extract.OutputRows = r.line
+	sourceErr := r.Err()
+	if sourceErr != nil {
+		extract.Status = etlStepFailed
+		extract.ErrorMessage = sourceErr.Error()
+	}
+	steps = append(steps, extract)
+
+	used := r.sourceTime
+	for i := range r.steps {
+		step := newStep(r.names[i], r.types[i], r.stepTime[i])
+		step.InputRows = r.stepIn[i]
+		step.OutputRows = r.stepOut[i]
+		step.ErrorRows = r.stepErrors[i]
+		step.SkippedRows = r.skipped[i]
+		step.ErrorMessage = r.stepError[i]
+		steps = append(steps, step)
+		used += r.stepTime[i]
+	}
+
+	loadTime := end.Sub(r.started) - used
+	if loadTime < 0 {
+		loadTime = 0
+	}
+	load := newStep("写入目标", models.ETLStepLoad, loadTime)
+	load.InputRows = r.emitted
+	load.OutputRows = result.OutputRows
+	// 转换丢弃的行已计入结果时扣除，剩余为写入时失败的行
+	load.ErrorRows = result.ErrorRows
+	if r.reported {
+		load.ErrorRows -= r.errorRows
+	}
+	if err != nil && sourceErr == nil {
+		load.Status = etlStepFailed
+		load.ErrorMessage = err.Error()
+	}
+	return append(steps, load)
+}
+
+// saveSteps 保存执行的步骤记录，失败只记录日志，不影响执行结果
+func (e *ETLExecutor) saveSteps(execution *models.ETLExecution, steps []models.ETLExecutionStep) {
+	if e.db == nil || execution == nil || execution.ID == 0 || len(steps) == 0 {
+		return
+	}
+	for i := range steps {
+		steps[i].ExecutionID = execution.ID
+	}
+	if err := e.db.Create(&steps).Error; err != nil {
+		e.logger.Error("Failed to save ETL execution steps",
+			zap.Uint("job_id", execution.JobID),
+			zap.String("execution_id", execution.ExecutionID),
+			zap.Error(err))
+	}
+}
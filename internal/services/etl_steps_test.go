@@ -0,0 +1,66 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/env-data-platform/internal/models"
+)
+
+func TestTransformRowsExecutionSteps(t *testing.T) {
+	source := &sliceRows{columns: []string{"mn", "value"}, rows: [][]interface{}{
+		{"MN001", "1.5"},
+		{"MN001", "abc"},
+		{"MN002", "3"},
+		{"MN001", "4"},
+		{"MN003", "5"},
+	}}
+	rows, err := newTransformRows(source, []models.TransformationConfig{
+		{Type: TransformConvert, Name: "数值转换", Order: 1, Parameters: map[string]interface{}{"columns": []interface{}{"value"}, "to": "number"}},
+		{Type: TransformDedup, Name: "按设备去重", Order: 2, Parameters: map[string]interface{}{"columns": []interface{}{"mn"}, "keep": "last"}},
+	})
+	require.NoError(t, err)
+	assert.Len(t, readAll(t, rows), 3)
+
+	result := &ETLExecutionResult{OutputRows: 2, ErrorRows: 1}
+	var log strings.Builder
+	rows.report(result, &log)
+
+	steps := rows.executionSteps(result, nil)
+	require.Len(t, steps, 4)
+	for i, step := range steps {
+		assert.Equal(t, i+1, step.StepOrder)
+		assert.NotNil(t, step.EndTime)
+	}
+
+	assert.Equal(t, models.ETLStepExtract, steps[0].StepType)
+	assert.Equal(t, int64(5), steps[0].OutputRows)
+
+	convert := steps[1]
+	assert.Equal(t, "数值转换", convert.StepName)
+	assert.Equal(t, TransformConvert, convert.StepType)
+	assert.Equal(t, []int64{5, 4, 1}, []int64{convert.InputRows, convert.OutputRows, convert.ErrorRows})
+	assert.Contains(t, convert.ErrorMessage, "第2行 [数值转换]")
+	assert.Equal(t, etlStepSuccess, convert.Status)
+
+	// 保留末条时暂存的行在源数据读完后输出
+	dedup := steps[2]
+	assert.Equal(t, []int64{4, 3, 1}, []int64{dedup.InputRows, dedup.OutputRows, dedup.SkippedRows})
+
+	load := steps[3]
+	assert.Equal(t, models.ETLStepLoad, load.StepType)
+	assert.Equal(t, int64(3), load.InputRows)
+	assert.Equal(t, int64(2), load.OutputRows)
+	assert.Equal(t, int64(1), load.ErrorRows)
+	assert.Equal(t, etlStepSuccess, load.Status)
+
+	// 写入失败时错误归到写入步骤
+	steps = rows.executionSteps(result, errors.New("写入Kafka失败"))
+	assert.Equal(t, etlStepSuccess, steps[0].Status)
+	assert.Equal(t, etlStepFailed, steps[3].Status)
+	assert.Equal(t, "写入Kafka失败", steps[3].ErrorMessage)
+}
@@ -0,0 +1,725 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/env-data-platform/internal/models"
+)
+
+// 转换步骤类型，对应 TransformationConfig.Type
+const (
+	// TransformRename 字段重命名，parameters.mapping 为 {"原列名": "新列名"}
+	TransformRename = "rename"
+	// TransformConvert 类型转换，parameters.columns 为要转换的列，to 为目标类型
+	TransformConvert = "convert"
+	// TransformConstant 常量填充，parameters.column 列的值全部置为 value，列不存在时追加
+	TransformConstant = "constant"
+	// TransformDefault 空值默认，parameters.values 为 {"列名": 默认值}，NULL替换为默认值
+	TransformDefault = "default"
+	// TransformDedup 去重，parameters.columns 为键列，keep 为 first/last
+	TransformDedup = "dedup"
+	// TransformFilter 行过滤，parameters.expression 为过滤表达式，不满足条件的行被跳过
+	TransformFilter = "filter"
+)
+
+// 类型转换的值类型
+const (
+	ValueTypeString = "string"
+	ValueTypeNumber = "number"
+	ValueTypeTime   = "time"
+)
+
+// transformErrorSamples 执行日志中最多记录的转换错误行数
+const transformErrorSamples = 10
+
+// errSkipRow 转换步骤返回该错误表示丢弃一行，计入跳过行而非错误行
+var errSkipRow = errors.New("skip row")
+
+// rowStep 一个转换步骤
+type rowStep interface {
+	// bind 根据输入列确定输出列，配置引用了不存在的列时返回错误
+	bind(columns []string) ([]string, error)
+	// apply 转换一行。返回errSkipRow时丢弃一行并计为跳过，返回其他错误时该行计入错误行；
+	// 返回nil行表示该行被步骤暂存，稍后输出
+	apply(row []sql.NullString) ([]sql.NullString, error)
+}
+
+// rowFlusher 暂存行的转换步骤，源数据读完后输出暂存的行
+type rowFlusher interface {
+	flush() [][]sql.NullString
+}
+
+// transformRows 按 Order 顺序对源数据逐行执行转换步骤，转换失败的行被丢弃并记录原因
+type transformRows struct {
+	source  sourceRows
+	steps   []rowStep
+	names   []string
+	types   []string
+	columns []string
+	values  []sql.NullString
+	dest    []interface{}
+	current []sql.NullString
+	// pending 已完成全部转换、等待输出的行
+	pending [][]sql.NullString
+	// drained 源数据已读完；flushing 为下一个需要输出暂存行的步骤
+	drained  bool
+	flushing int
+	// line 已读取的源数据行号，用于错误定位
+	line      int64
+	errorRows int64
+	samples   []string
+	// skipped 每个步骤丢弃的行数
+	skipped []int64
+	err     error
+
+	// 步骤级统计：各步骤的输入、输出、失败行数、累计耗时和首个错误，用于执行详情
+	started    time.Time
+	sourceTime time.Duration
+	stepIn     []int64
+	stepOut    []int64
+	stepErrors []int64
+	stepTime   []time.Duration
+	stepError  []string
+	// emitted 交给下游写入的行数；reported 转换丢弃的行已计入执行结果
+	emitted  int64
+	reported bool
+}
+
+// newTransformRows 按作业的转换配置包装源数据，未配置转换时各行原样输出
+func newTransformRows(source sourceRows, transforms []models.TransformationConfig) (*transformRows, error) {
+	sourceColumns, err := source.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	ordered := append([]models.TransformationConfig{}, transforms...)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Order < ordered[j].Order })
+
+	r := &transformRows{source: source}
+	columns := sourceColumns
+	for i, transform := range ordered {
+		name := transform.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d %s", i+1, transform.Type)
+		}
+		step, err := newRowStep(transform)
+		if err == nil {
+			columns, err = step.bind(columns)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("转换步骤[%s]配置错误: %v", name, err)
+		}
+		r.steps = append(r.steps, step)
+		r.names = append(r.names, name)
+		r.types = append(r.types, transform.Type)
+	}
+	r.columns = columns
+	r.skipped = make([]int64, len(r.steps))
+	r.stepIn = make([]int64, len(r.steps))
+	r.stepOut = make([]int64, len(r.steps))
+	r.stepErrors = make([]int64, len(r.steps))
+	r.stepTime = make([]time.Duration, len(r.steps))
+	r.stepError = make([]string, len(r.steps))
+	r.started = time.Now()
+
+	r.values = make([]sql.NullString, len(sourceColumns))
+	r.dest = make([]interface{}, len(sourceColumns))
+	for i := range r.values {
+		r.dest[i] = &r.values[i]
+	}
+	return r, nil
+}
+
+// newRowStep 按类型创建转换步骤
+func newRowStep(transform models.TransformationConfig) (rowStep, error) {
+	params := transform.Parameters
+	switch transform.Type {
+	case TransformRename:
+		return newRenameStep(params)
+	case TransformConvert:
+		return newConvertStep(params)
+	case TransformConstant:
+		return newConstantStep(params)
+	case TransformDefault:
+		return newDefaultStep(params)
+	case TransformDedup:
+		return newDedupStep(params)
+	case TransformFilter:
+		return newFilterStep(params)
+	default:
+		return nil, fmt.Errorf("不支持的转换类型: %s", transform.Type)
+	}
+}
+
+func (r *transformRows) Columns() ([]string, error) {
+	return r.columns, nil
+}
+
+func (r *transformRows) Next() bool {
+	for {
+		if len(r.pending) > 0 {
+			r.current = r.pending[0]
+			r.pending = r.pending[1:]
+			r.emitted++
+			return true
+		}
+		if r.err != nil {
+			return false
+		}
+
+		if !r.drained {
+			start := time.Now()
+			if r.source.Next() {
+				err := r.source.Scan(r.dest...)
+				r.sourceTime += time.Since(start)
+				if err != nil {
+					r.err = err
+					return false
+				}
+				r.line++
+				r.run(0, append([]sql.NullString(nil), r.values...), r.line)
+				continue
+			}
+			r.sourceTime += time.Since(start)
+			if r.source.Err() != nil {
+				return false
+			}
+			r.drained = true
+		}
+
+		// 源数据读完后依次输出各步骤暂存的行，暂存的行继续经过后续步骤
+		if r.flushing >= len(r.steps) {
+			return false
+		}
+		if flusher, ok := r.steps[r.flushing].(rowFlusher); ok {
+			start := time.Now()
+			rows := flusher.flush()
+			r.stepTime[r.flushing] += time.Since(start)
+			r.stepOut[r.flushing] += int64(len(rows))
+			for _, row := range rows {
+				r.run(r.flushing+1, row, 0)
+			}
+		}
+		r.flushing++
+	}
+}
+
+// run 从第from个步骤开始转换一行，line为源数据行号，暂存后输出的行为0
+func (r *transformRows) run(from int, row []sql.NullString, line int64) {
+	for i := from; i < len(r.steps) && row != nil; i++ {
+		var err error
+		r.stepIn[i]++
+		start := time.Now()
+		row, err = r.steps[i].apply(row)
+		r.stepTime[i] += time.Since(start)
+		switch {
+		case err == errSkipRow:
+			r.skipped[i]++
+			return
+		case err != nil:
+			r.errorRows++
+			r.stepErrors[i]++
+			sample := fmt.Sprintf("[%s]: %v", r.names[i], err)
+			if line > 0 {
+				sample = fmt.Sprintf("第%d行 [%s]: %v", line, r.names[i], err)
+			}
+			if r.stepError[i] == "" {
+				r.stepError[i] = sample
+			}
+			if len(r.samples) < transformErrorSamples {
+				r.samples = append(r.samples, sample)
+			}
+			return
+		case row != nil:
+			r.stepOut[i]++
+		}
+	}
+	if row != nil {
+		r.pending = append(r.pending, row)
+	}
+}
+
+func (r *transformRows) Scan(dest ...interface{}) error {
+	if len(dest) != len(r.current) {
+		return fmt.Errorf("expected %d destination arguments in Scan, not %d", len(r.current), len(dest))
+	}
+	for i, d := range dest {
+		p, ok := d.(*sql.NullString)
+		if !ok {
+			return fmt.Errorf("unsupported Scan destination %T", d)
+		}
+		*p = r.current[i]
+	}
+	return nil
+}
+
+func (r *transformRows) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.source.Err()
+}
+
+// report 将转换中丢弃的行计入执行结果：失败的行计入错误行并在日志中记录原因，
+// 步骤丢弃的行计入跳过行。这些行不会交给下游，因此同时补计到读取行数中
+func (r *transformRows) report(result *ETLExecutionResult, logBuilder *strings.Builder) {
+	r.reported = true
+	for i, n := range r.skipped {
+		if n == 0 {
+			continue
+		}
+		result.InputRows += n
+		result.SkippedRows += n
+		logBuilder.WriteString(fmt.Sprintf("[%s] 转换步骤[%s]跳过 %d 行\n",
+			time.Now().Format("2006-01-02 15:04:05"), r.names[i], n))
+	}
+
+	if r.errorRows == 0 {
+		return
+	}
+	result.InputRows += r.errorRows
+	result.ErrorRows += r.errorRows
+
+	logBuilder.WriteString(fmt.Sprintf("[%s] 数据转换失败 %d 行\n", time.Now().Format("2006-01-02 15:04:05"), r.errorRows))
+	for _, sample := range r.samples {
+		logBuilder.WriteString("  " + sample + "\n")
+	}
+	if r.errorRows > int64(len(r.samples)) {
+		logBuilder.WriteString(fmt.Sprintf("  ……其余 %d 行省略\n", r.errorRows-int64(len(r.samples))))
+	}
+}
+
+// columnIndex 查找列的位置，不存在时返回错误
+func columnIndex(columns []string, name string) (int, error) {
+	for i, column := range columns {
+		if column == name {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("列 %s 不存在", name)
+}
+
+// paramString 取字符串类型的转换参数
+func paramString(params map[string]interface{}, key string) string {
+	s, _ := params[key].(string)
+	return strings.TrimSpace(s)
+}
+
+// paramValue 将常量或默认值参数转为列值，null表示NULL
+func paramValue(v interface{}) sql.NullString {
+	if v == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: formatRecordValue(v), Valid: true}
+}
+
+// renameStep 字段重命名
+type renameStep struct {
+	mapping map[string]string
+}
+
+func newRenameStep(params map[string]interface{}) (*renameStep, error) {
+	raw, _ := params["mapping"].(map[string]interface{})
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("未配置mapping")
+	}
+	step := &renameStep{mapping: make(map[string]string, len(raw))}
+	for from, v := range raw {
+		to, _ := v.(string)
+		if to = strings.TrimSpace(to); to == "" {
+			return nil, fmt.Errorf("列 %s 的新列名为空", from)
+		}
+		step.mapping[from] = to
+	}
+	return step, nil
+}
+
+func (s *renameStep) bind(columns []string) ([]string, error) {
+	for from := range s.mapping {
+		if _, err := columnIndex(columns, from); err != nil {
+			return nil, err
+		}
+	}
+	renamed := make([]string, len(columns))
+	seen := make(map[string]bool, len(columns))
+	for i, column := range columns {
+		if to, ok := s.mapping[column]; ok {
+			column = to
+		}
+		if seen[column] {
+			return nil, fmt.Errorf("重命名后列 %s 重复", column)
+		}
+		seen[column] = true
+		renamed[i] = column
+	}
+	return renamed, nil
+}
+
+func (s *renameStep) apply(row []sql.NullString) ([]sql.NullString, error) {
+	return row, nil
+}
+
+// convertStep 类型转换，支持字符串、数值和时间之间互相转换
+//
+// 参数：columns 要转换的列；to 目标类型 string/number/time；from 源类型，默认按字符串解析；
+// input_format、output_format 为Go时间格式，如 "2006-01-02 15:04:05"，
+// 未配置input_format时按RFC3339或"2006-01-02 15:04:05"解析，output_format默认为后者；
+// precision 数值保留的小数位数；unit 时间与数值互转时时间戳的单位 s/ms，默认s。
+// NULL和空字符串转为数值或时间时结果为NULL。
+type convertStep struct {
+	columns      []string
+	indexes      []int
+	to           string
+	from         string
+	inputFormat  string
+	outputFormat string
+	precision    int
+	millis       bool
+}
+
+func newConvertStep(params map[string]interface{}) (*convertStep, error) {
+	step := &convertStep{
+		columns:      configStrings(params["columns"]),
+		to:           strings.ToLower(paramString(params, "to")),
+		from:         strings.ToLower(paramString(params, "from")),
+		inputFormat:  paramString(params, "input_format"),
+		outputFormat: paramString(params, "output_format"),
+		precision:    -1,
+	}
+	if len(step.columns) == 0 {
+		return nil, fmt.Errorf("未配置columns")
+	}
+	if step.to == "" {
+		return nil, fmt.Errorf("未配置目标类型to")
+	}
+	if step.from == "" {
+		step.from = ValueTypeString
+	}
+	for _, t := range []string{step.to, step.from} {
+		if t != ValueTypeString && t != ValueTypeNumber && t != ValueTypeTime {
+			return nil, fmt.Errorf("不支持的类型: %s，可选值: string, number, time", t)
+		}
+	}
+	if step.outputFormat == "" {
+		step.outputFormat = hj212TimeLayout
+	}
+	if v, ok := params["precision"].(float64); ok {
+		if v < 0 || v != math.Trunc(v) {
+			return nil, fmt.Errorf("precision必须为非负整数")
+		}
+		step.precision = int(v)
+	}
+	switch unit := strings.ToLower(paramString(params, "unit")); unit {
+	case "", "s":
+	case "ms":
+		step.millis = true
+	default:
+		return nil, fmt.Errorf("不支持的时间戳单位: %s，可选值: s, ms", unit)
+	}
+	return step, nil
+}
+
+func (s *convertStep) bind(columns []string) ([]string, error) {
+	s.indexes = make([]int, len(s.columns))
+	for i, column := range s.columns {
+		index, err := columnIndex(columns, column)
+		if err != nil {
+			return nil, err
+		}
+		s.indexes[i] = index
+	}
+	return columns, nil
+}
+
+func (s *convertStep) apply(row []sql.NullString) ([]sql.NullString, error) {
+	for i, index := range s.indexes {
+		value := row[index]
+		if !value.Valid {
+			continue
+		}
+		converted, err := s.convert(value.String)
+		if err != nil {
+			return nil, fmt.Errorf("列 %s 的值 %q 无法转换为%s: %v", s.columns[i], value.String, s.to, err)
+		}
+		row[index] = converted
+	}
+	return row, nil
+}
+
+// convert 按源类型解析值，再按目标类型输出
+func (s *convertStep) convert(v string) (sql.NullString, error) {
+	if s.from == ValueTypeString && s.to == ValueTypeString {
+		return sql.NullString{String: v, Valid: true}, nil
+	}
+	v = strings.TrimSpace(v)
+	if v == "" {
+		if s.to == ValueTypeString {
+			return sql.NullString{String: v, Valid: true}, nil
+		}
+		return sql.NullString{}, nil
+	}
+
+	var (
+		number float64
+		t      time.Time
+		err    error
+	)
+	switch {
+	case s.from == ValueTypeTime || (s.from == ValueTypeString && s.to == ValueTypeTime):
+		t, err = s.parseTime(v)
+	case s.from == ValueTypeNumber || s.to == ValueTypeNumber:
+		number, err = strconv.ParseFloat(v, 64)
+		if err == nil && (math.IsNaN(number) || math.IsInf(number, 0)) {
+			err = fmt.Errorf("不是有效的数值")
+		}
+	}
+	if err != nil {
+		return sql.NullString{}, err
+	}
+
+	switch s.to {
+	case ValueTypeNumber:
+		if s.from == ValueTypeTime {
+			number = float64(t.Unix())
+			if s.millis {
+				number = float64(t.UnixMilli())
+			}
+		}
+		return sql.NullString{String: strconv.FormatFloat(number, 'f', s.precision, 64), Valid: true}, nil
+	case ValueTypeTime:
+		if s.from == ValueTypeNumber {
+			t = s.fromTimestamp(number)
+		}
+		return sql.NullString{String: t.Format(s.outputFormat), Valid: true}, nil
+	default:
+		if s.from == ValueTypeTime {
+			return sql.NullString{String: t.Format(s.outputFormat), Valid: true}, nil
+		}
+		return sql.NullString{String: strconv.FormatFloat(number, 'f', s.precision, 64), Valid: true}, nil
+	}
+}
+
+// parseTime 按input_format解析时间，未配置时按RFC3339或"2006-01-02 15:04:05"解析
+func (s *convertStep) parseTime(v string) (time.Time, error) {
+	if s.inputFormat != "" {
+		return time.ParseInLocation(s.inputFormat, v, time.Local)
+	}
+	return parseHJ212Time(v)
+}
+
+// fromTimestamp 将秒或毫秒时间戳转为本地时间
+func (s *convertStep) fromTimestamp(ts float64) time.Time {
+	if s.millis {
+		return time.UnixMilli(int64(ts)).Local()
+	}
+	sec, frac := math.Modf(ts)
+	return time.Unix(int64(sec), int64(frac*1e9)).Local()
+}
+
+// constantStep 常量填充，列已存在时覆盖原值，不存在时追加到末尾
+type constantStep struct {
+	column string
+	value  sql.NullString
+	index  int
+}
+
+func newConstantStep(params map[string]interface{}) (*constantStep, error) {
+	step := &constantStep{column: paramString(params, "column"), value: paramValue(params["value"])}
+	if step.column == "" {
+		return nil, fmt.Errorf("未配置column")
+	}
+	return step, nil
+}
+
+func (s *constantStep) bind(columns []string) ([]string, error) {
+	if index, err := columnIndex(columns, s.column); err == nil {
+		s.index = index
+		return columns, nil
+	}
+	s.index = len(columns)
+	return append(append([]string{}, columns...), s.column), nil
+}
+
+func (s *constantStep) apply(row []sql.NullString) ([]sql.NullString, error) {
+	if s.index == len(row) {
+		return append(row, s.value), nil
+	}
+	row[s.index] = s.value
+	return row, nil
+}
+
+// defaultStep 空值默认，empty_as_null为true时空字符串也视为空值
+type defaultStep struct {
+	values      map[string]sql.NullString
+	emptyAsNull bool
+	indexes     []int
+	defaults    []sql.NullString
+}
+
+func newDefaultStep(params map[string]interface{}) (*defaultStep, error) {
+	raw, _ := params["values"].(map[string]interface{})
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("未配置values")
+	}
+	step := &defaultStep{values: make(map[string]sql.NullString, len(raw))}
+	for column, v := range raw {
+		value := paramValue(v)
+		if !value.Valid {
+			return nil, fmt.Errorf("列 %s 的默认值不能为null", column)
+		}
+		step.values[column] = value
+	}
+	step.emptyAsNull, _ = params["empty_as_null"].(bool)
+	return step, nil
+}
+
+func (s *defaultStep) bind(columns []string) ([]string, error) {
+	s.indexes, s.defaults = s.indexes[:0], s.defaults[:0]
+	for column, value := range s.values {
+		index, err := columnIndex(columns, column)
+		if err != nil {
+			return nil, err
+		}
+		s.indexes = append(s.indexes, index)
+		s.defaults = append(s.defaults, value)
+	}
+	return columns, nil
+}
+
+func (s *defaultStep) apply(row []sql.NullString) ([]sql.NullString, error) {
+	for i, index := range s.indexes {
+		if !row[index].Valid || (s.emptyAsNull && row[index].String == "") {
+			row[index] = s.defaults[i]
+		}
+	}
+	return row, nil
+}
+
+// dedupStep 按键列去重，被去掉的重复行计为跳过
+//
+// 参数：columns 键列；keep 为 first 保留首条、last 保留末条，默认first；
+// sorted 为true时表示源数据已按键列排序（如查询中带ORDER BY），只比较相邻行，内存占用固定。
+// 未排序时保留首条需记住所有出现过的键，保留末条需暂存每个键的最新一行到源数据读完，
+// 数据量大时应在源查询中排序并开启sorted。
+type dedupStep struct {
+	columns  []string
+	indexes  []int
+	keepLast bool
+	sorted   bool
+
+	// seen 未排序保留首条时出现过的键
+	seen map[string]struct{}
+	// latest、held 未排序保留末条时每个键在held中的位置和暂存的行
+	latest map[string]int
+	held   [][]sql.NullString
+	// lastKey、lastRow 排序模式下上一行的键和保留末条时暂存的行
+	lastKey string
+	lastRow []sql.NullString
+	started bool
+}
+
+func newDedupStep(params map[string]interface{}) (*dedupStep, error) {
+	step := &dedupStep{columns: configStrings(params["columns"])}
+	if len(step.columns) == 0 {
+		return nil, fmt.Errorf("未配置键列columns")
+	}
+	switch keep := strings.ToLower(paramString(params, "keep")); keep {
+	case "", "first":
+	case "last":
+		step.keepLast = true
+	default:
+		return nil, fmt.Errorf("keep只能为first或last: %s", keep)
+	}
+	step.sorted, _ = params["sorted"].(bool)
+	return step, nil
+}
+
+func (s *dedupStep) bind(columns []string) ([]string, error) {
+	s.indexes = make([]int, len(s.columns))
+	for i, column := range s.columns {
+		index, err := columnIndex(columns, column)
+		if err != nil {
+			return nil, err
+		}
+		s.indexes[i] = index
+	}
+	s.seen = make(map[string]struct{})
+	s.latest = make(map[string]int)
+	s.held = nil
+	s.lastRow, s.started = nil, false
+	return columns, nil
+}
+
+// key 键列的值编码为一个字符串，带长度前缀以免拼接产生歧义，NULL与空字符串不同
+func (s *dedupStep) key(row []sql.NullString) string {
+	var b strings.Builder
+	for _, index := range s.indexes {
+		if !row[index].Valid {
+			b.WriteString("N;")
+			continue
+		}
+		b.WriteString(strconv.Itoa(len(row[index].String)))
+		b.WriteByte(':')
+		b.WriteString(row[index].String)
+	}
+	return b.String()
+}
+
+func (s *dedupStep) apply(row []sql.NullString) ([]sql.NullString, error) {
+	key := s.key(row)
+	switch {
+	case s.sorted && !s.keepLast:
+		if s.started && key == s.lastKey {
+			return nil, errSkipRow
+		}
+		s.lastKey, s.started = key, true
+		return row, nil
+	case s.sorted:
+		if s.started && key == s.lastKey {
+			s.lastRow = row
+			return nil, errSkipRow
+		}
+		previous := s.lastRow
+		s.lastKey, s.lastRow, s.started = key, row, true
+		return previous, nil
+	case !s.keepLast:
+		if _, ok := s.seen[key]; ok {
+			return nil, errSkipRow
+		}
+		s.seen[key] = struct{}{}
+		return row, nil
+	default:
+		if i, ok := s.latest[key]; ok {
+			s.held[i] = row
+			return nil, errSkipRow
+		}
+		s.latest[key] = len(s.held)
+		s.held = append(s.held, row)
+		return nil, nil
+	}
+}
+
+// flush 输出保留末条时暂存的行，未排序时按键首次出现的顺序输出
+func (s *dedupStep) flush() [][]sql.NullString {
+	if !s.keepLast {
+		return nil
+	}
+	if s.sorted {
+		if s.lastRow == nil {
+			return nil
+		}
+		row := s.lastRow
+		s.lastRow = nil
+		return [][]sql.NullString{row}
+	}
+	held := s.held
+	s.held, s.latest = nil, nil
+	return held
+}
@@ -0,0 +1,296 @@
+package services
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/env-data-platform/internal/models"
+)
+
+// sliceRows 内存中的源数据，nil表示NULL
+type sliceRows struct {
+	columns []string
+	rows    [][]interface{}
+	index   int
+}
+
+func (r *sliceRows) Columns() ([]string, error) { return r.columns, nil }
+
+func (r *sliceRows) Next() bool {
+	r.index++
+	return r.index <= len(r.rows)
+}
+
+func (r *sliceRows) Scan(dest ...interface{}) error {
+	for i, v := range r.rows[r.index-1] {
+		*dest[i].(*sql.NullString) = paramValue(v)
+	}
+	return nil
+}
+
+func (r *sliceRows) Err() error { return nil }
+
+// readAll 读出全部转换结果，NULL记为"<nil>"
+func readAll(t *testing.T, rows sourceRows) [][]string {
+	columns, err := rows.Columns()
+	require.NoError(t, err)
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	var result [][]string
+	for rows.Next() {
+		require.NoError(t, rows.Scan(dest...))
+		record := make([]string, len(values))
+		for i, v := range values {
+			record[i] = v.String
+			if !v.Valid {
+				record[i] = "<nil>"
+			}
+		}
+		result = append(result, record)
+	}
+	require.NoError(t, rows.Err())
+	return result
+}
+
+func TestTransformRows(t *testing.T) {
+	source := &sliceRows{
+		columns: []string{"mn", "ts", "value", "unit"},
+		rows: [][]interface{}{
+			{"MN001", "2024-05-01 10:00:00", "35.456", nil},
+			{"MN002", "1714528800", "", "mg/m3"},
+			{"MN003", "2024-05-01 10:00:00", "abc", nil},
+		},
+	}
+	// 按Order执行：先把value转成数值，再重命名，最后填充
+	rows, err := newTransformRows(source, []models.TransformationConfig{
+		{Type: TransformConstant, Order: 4, Parameters: map[string]interface{}{"column": "source", "value": "hj212"}},
+		{Type: TransformRename, Order: 2, Parameters: map[string]interface{}{"mapping": map[string]interface{}{"mn": "device_id"}}},
+		{Type: TransformConvert, Name: "数值", Order: 1, Parameters: map[string]interface{}{
+			"columns": "value", "to": "number", "precision": float64(1),
+		}},
+		{Type: TransformDefault, Order: 3, Parameters: map[string]interface{}{
+			"values": map[string]interface{}{"unit": "mg/m3", "value": float64(0)}, "empty_as_null": true,
+		}},
+	})
+	require.NoError(t, err)
+
+	columns, _ := rows.Columns()
+	assert.Equal(t, []string{"device_id", "ts", "value", "unit", "source"}, columns)
+	assert.Equal(t, [][]string{
+		{"MN001", "2024-05-01 10:00:00", "35.5", "mg/m3", "hj212"},
+		{"MN002", "1714528800", "0", "mg/m3", "hj212"},
+	}, readAll(t, rows))
+
+	result := &ETLExecutionResult{InputRows: 2}
+	var log strings.Builder
+	rows.report(result, &log)
+	assert.Equal(t, int64(3), result.InputRows)
+	assert.Equal(t, int64(1), result.ErrorRows)
+	assert.Contains(t, log.String(), `第3行 [数值]: 列 value 的值 "abc" 无法转换为number`)
+}
+
+func TestConvertStep(t *testing.T) {
+	convert := func(params map[string]interface{}, v string) (sql.NullString, error) {
+		params["columns"] = "v"
+		step, err := newConvertStep(params)
+		require.NoError(t, err)
+		_, err = step.bind([]string{"v"})
+		require.NoError(t, err)
+		row, err := step.apply([]sql.NullString{{String: v, Valid: true}})
+		if err != nil {
+			return sql.NullString{}, err
+		}
+		return row[0], nil
+	}
+
+	v, err := convert(map[string]interface{}{"to": "time", "input_format": "20060102150405"}, "20240501100000")
+	require.NoError(t, err)
+	assert.Equal(t, "2024-05-01 10:00:00", v.String)
+
+	v, err = convert(map[string]interface{}{"from": "time", "to": "number", "unit": "ms"}, "2024-05-01 10:00:00")
+	require.NoError(t, err)
+	ts := v.String
+
+	v, err = convert(map[string]interface{}{"from": "number", "to": "time", "unit": "ms", "output_format": "2006/01/02 15:04"}, ts)
+	require.NoError(t, err)
+	assert.Equal(t, "2024/05/01 10:00", v.String)
+
+	v, err = convert(map[string]interface{}{"from": "number", "to": "string", "precision": float64(2)}, " 1.005e1 ")
+	require.NoError(t, err)
+	assert.Equal(t, "10.05", v.String)
+
+	v, err = convert(map[string]interface{}{"to": "number"}, " ")
+	require.NoError(t, err)
+	assert.False(t, v.Valid)
+
+	_, err = convert(map[string]interface{}{"to": "number"}, "NaN")
+	assert.Error(t, err)
+	_, err = convert(map[string]interface{}{"to": "time"}, "yesterday")
+	assert.Error(t, err)
+
+	_, err = newConvertStep(map[string]interface{}{"columns": "v", "to": "bool"})
+	assert.Error(t, err)
+	_, err = newConvertStep(map[string]interface{}{"columns": "v", "to": "number", "precision": float64(1.5)})
+	assert.Error(t, err)
+}
+
+func TestTransformRowsConfigErrors(t *testing.T) {
+	source := &sliceRows{columns: []string{"a", "b"}}
+	cases := []models.TransformationConfig{
+		{Type: "lookup"},
+		{Type: TransformRename, Parameters: map[string]interface{}{"mapping": map[string]interface{}{"c": "d"}}},
+		{Type: TransformRename, Parameters: map[string]interface{}{"mapping": map[string]interface{}{"a": "b"}}},
+		{Type: TransformConvert, Parameters: map[string]interface{}{"columns": "c", "to": "number"}},
+		{Type: TransformDefault, Parameters: map[string]interface{}{"values": map[string]interface{}{"a": nil}}},
+		{Type: TransformConstant, Parameters: map[string]interface{}{"value": "x"}},
+	}
+	for _, transform := range cases {
+		_, err := newTransformRows(source, []models.TransformationConfig{transform})
+		assert.Error(t, err, transform.Type)
+	}
+
+	rows, err := newTransformRows(source, nil)
+	require.NoError(t, err)
+	columns, _ := rows.Columns()
+	assert.Equal(t, []string{"a", "b"}, columns)
+}
+
+func TestDedupTransform(t *testing.T) {
+	data := [][]interface{}{
+		{"MN001", "10:00", "1"},
+		{"MN001", "10:00", "2"},
+		{"MN002", "10:00", "3"},
+		{"MN001", "10:00", "4"},
+		{"MN002", nil, "5"},
+		{"MN002", "", "6"},
+	}
+	sortedData := [][]interface{}{
+		{"MN001", "10:00", "1"},
+		{"MN001", "10:00", "2"},
+		{"MN002", "10:00", "3"},
+		{"MN002", "10:00", "4"},
+		{"MN003", "10:00", "5"},
+	}
+
+	cases := []struct {
+		name    string
+		data    [][]interface{}
+		params  map[string]interface{}
+		values  []string
+		skipped int64
+	}{
+		{"保留首条", data, map[string]interface{}{}, []string{"1", "3", "5", "6"}, 2},
+		{"保留末条", data, map[string]interface{}{"keep": "last"}, []string{"4", "3", "5", "6"}, 2},
+		{"排序保留首条", sortedData, map[string]interface{}{"sorted": true}, []string{"1", "3", "5"}, 2},
+		{"排序保留末条", sortedData, map[string]interface{}{"sorted": true, "keep": "last"}, []string{"2", "4", "5"}, 2},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.params["columns"] = []interface{}{"mn", "ts"}
+			source := &sliceRows{columns: []string{"mn", "ts", "value"}, rows: tc.data}
+			// 去重后的行继续经过后续步骤
+			rows, err := newTransformRows(source, []models.TransformationConfig{
+				{Type: TransformDedup, Name: "去重", Order: 1, Parameters: tc.params},
+				{Type: TransformConstant, Order: 2, Parameters: map[string]interface{}{"column": "source", "value": "x"}},
+			})
+			require.NoError(t, err)
+
+			var values []string
+			for _, record := range readAll(t, rows) {
+				require.Len(t, record, 4)
+				values = append(values, record[2])
+			}
+			assert.Equal(t, tc.values, values)
+
+			result := &ETLExecutionResult{InputRows: int64(len(values))}
+			var log strings.Builder
+			rows.report(result, &log)
+			assert.Equal(t, tc.skipped, result.SkippedRows)
+			assert.Equal(t, int64(len(tc.data)), result.InputRows)
+			assert.Contains(t, log.String(), "转换步骤[去重]跳过")
+		})
+	}
+
+	_, err := newDedupStep(map[string]interface{}{"columns": "mn", "keep": "newest"})
+	assert.Error(t, err)
+}
+
+func TestFilterTransform(t *testing.T) {
+	source := &sliceRows{
+		columns: []string{"mn", "status", "rtd", "remark"},
+		rows: [][]interface{}{
+			{"MN001", "有效", "12.5", "ok"},
+			{"MN002", "有效", "8", nil},
+			{"MN003", "无效", "20", "100%"},
+			{"MN004", "有效", "n/a", "x"},
+			{"MN005", "有效", nil, "it's"},
+		},
+	}
+	rows, err := newTransformRows(source, []models.TransformationConfig{{
+		Type: TransformFilter, Name: "过滤", Parameters: map[string]interface{}{
+			"expression": "status = '有效' AND (rtd > 10 or mn in ('MN002', \"MN005\"))",
+		},
+	}})
+	require.NoError(t, err)
+	var devices []string
+	for _, record := range readAll(t, rows) {
+		devices = append(devices, record[0])
+	}
+	assert.Equal(t, []string{"MN001", "MN002", "MN005"}, devices)
+
+	result := &ETLExecutionResult{InputRows: 3}
+	var log strings.Builder
+	rows.report(result, &log)
+	assert.Equal(t, int64(1), result.SkippedRows)
+	assert.Equal(t, int64(1), result.ErrorRows)
+	assert.Contains(t, log.String(), `第4行 [过滤]: 列 rtd 的值 "n/a" 不是数值`)
+
+	match := func(expression string, row ...interface{}) bool {
+		step, err := newFilterStep(map[string]interface{}{"expression": expression})
+		require.NoError(t, err, expression)
+		_, err = step.bind(source.columns)
+		require.NoError(t, err)
+		values := make([]sql.NullString, len(row))
+		for i, v := range row {
+			values[i] = paramValue(v)
+		}
+		out, err := step.apply(values)
+		if err == errSkipRow {
+			return false
+		}
+		require.NoError(t, err)
+		return out != nil
+	}
+	assert.True(t, match("remark like '1_0%'", "MN", "有效", "1", "100% done"))
+	assert.False(t, match("remark not like '%%'", "MN", "有效", "1", "x"))
+	assert.True(t, match("remark = 'it''s'", "MN", "有效", "1", "it's"))
+	assert.True(t, match("remark = null and rtd >= -1.5e0", "MN", "有效", "-1", nil))
+	assert.False(t, match("remark != 'x'", "MN", "有效", "1", nil))
+	assert.True(t, match("`rtd` <> 2 and mn not in ('A')", "MN", "有效", "1", "x"))
+	assert.True(t, match("mn <= 'MN002'", "MN001", "有效", "1", "x"))
+
+	// 表达式只做求值，SQL片段按语法错误拒绝
+	for _, expression := range []string{
+		"status = '有效'; DROP TABLE users",
+		"status = 1 OR 1 = 1",
+		"status = '有效' --",
+		"status",
+		"rtd > null",
+		"(status = 'a'",
+		"status = 'a",
+		"missing = 1",
+	} {
+		_, err := newTransformRows(source, []models.TransformationConfig{{
+			Type: TransformFilter, Parameters: map[string]interface{}{"expression": expression},
+		}})
+		assert.Error(t, err, expression)
+	}
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+)
+
+// GeoLocation IP地址对应的大致地理位置
+type GeoLocation struct {
+	Country  string `json:"country"`
+	Province string `json:"province"`
+	City     string `json:"city"`
+	ISP      string `json:"isp,omitempty"`
+}
+
+// String 可读的地点，如 中国 广东 深圳；直辖市省份与城市相同时只输出一次
+func (l GeoLocation) String() string {
+	parts := make([]string, 0, 3)
+	for _, part := range []string{l.Country, l.Province, l.City} {
+		if part != "" && (len(parts) == 0 || parts[len(parts)-1] != part) {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// SameRegion 两个地点是否属于同一地区：都有城市时比较到城市，否则比较到省份
+func (l GeoLocation) SameRegion(other GeoLocation) bool {
+	if l.Country != other.Country || l.Province != other.Province {
+		return false
+	}
+	if l.City != "" && other.City != "" {
+		return l.City == other.City
+	}
+	return true
+}
+
+// GeoLocator IP地理位置查询，可替换为其他IP库实现
+type GeoLocator interface {
+	// Lookup 查询IP所在地，未命中时返回false
+	Lookup(ip netip.Addr) (GeoLocation, bool)
+}
+
+// ipRange IP地址库中的一段地址
+type ipRange struct {
+	start    netip.Addr
+	end      netip.Addr
+	location GeoLocation
+}
+
+// IPRangeLocator 基于IP段的地址库，按起始地址排序后二分查找
+type IPRangeLocator struct {
+	ranges []ipRange
+}
+
+// LoadIPRangeLocator 从CSV文件加载地址库
+func LoadIPRangeLocator(path string) (*IPRangeLocator, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开IP地址库失败: %w", err)
+	}
+	defer f.Close()
+	return ParseIPRanges(f)
+}
+
+// ParseIPRanges 解析IP地址库，每行 起始IP,结束IP,国家,省份,城市[,运营商]，空行和#开头的行忽略。
+// IPv4与IPv6可混合出现，地址段不应重叠
+func ParseIPRanges(r io.Reader) (*IPRangeLocator, error) {
+	var ranges []ipRange
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Split(text, ",")
+		if len(fields) < 5 {
+			return nil, fmt.Errorf("IP地址库第%d行字段不足", line)
+		}
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		start, err := netip.ParseAddr(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("IP地址库第%d行起始IP无效: %w", line, err)
+		}
+		end, err := netip.ParseAddr(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("IP地址库第%d行结束IP无效: %w", line, err)
+		}
+		start, end = start.Unmap(), end.Unmap()
+		if start.Is4() != end.Is4() || end.Less(start) {
+			return nil, fmt.Errorf("IP地址库第%d行地址段无效", line)
+		}
+
+		location := GeoLocation{Country: fields[2], Province: fields[3], City: fields[4]}
+		if len(fields) > 5 {
+			location.ISP = fields[5]
+		}
+		ranges = append(ranges, ipRange{start: start, end: end, location: location})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取IP地址库失败: %w", err)
+	}
+
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].start.Less(ranges[j].start) })
+	return &IPRangeLocator{ranges: ranges}, nil
+}
+
+// Len 地址段数量
+func (l *IPRangeLocator) Len() int {
+	return len(l.ranges)
+}
+
+// Lookup 查询IP所在地
+func (l *IPRangeLocator) Lookup(ip netip.Addr) (GeoLocation, bool) {
+	ip = ip.Unmap()
+	// 最后一个起始地址不大于ip的地址段
+	i := sort.Search(len(l.ranges), func(i int) bool { return ip.Less(l.ranges[i].start) }) - 1
+	if i < 0 || l.ranges[i].end.Less(ip) {
+		return GeoLocation{}, false
+	}
+	return l.ranges[i].location, true
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/config"
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/models"
+)
+
+// LogCleanupResult 一次日志清理的结果
+type LogCleanupResult struct {
+	OperationLogs  int64     `json:"deleted_operation_logs"`
+	LoginLogs      int64     `json:"deleted_login_logs"`
+	OperationSince time.Time `json:"operation_cutoff"`
+	LoginSince     time.Time `json:"login_cutoff"`
+}
+
+// LogCleaner 按保留天数定期清理操作日志和登录日志
+type LogCleaner struct {
+	logger   *zap.Logger
+	db       *gorm.DB
+	cfg      config.AuditLogCleanupConfig
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewLogCleaner 创建日志清理器
+func NewLogCleaner(logger *zap.Logger, cfg config.AuditLogCleanupConfig) *LogCleaner {
+	return &LogCleaner{
+		logger: logger,
+		db:     database.GetDB(),
+		cfg:    cfg,
+		stop:   make(chan struct{}),
+	}
+}
+
+// Start 启动后台定时清理，未启用时直接返回
+func (c *LogCleaner) Start() {
+	if !c.cfg.Enabled || c.cfg.Interval <= 0 {
+		c.logger.Info("Audit log cleanup is disabled")
+		return
+	}
+
+	go c.run()
+	c.logger.Info("Audit log cleanup started",
+		zap.Duration("interval", c.cfg.Interval),
+		zap.Int("operation_retention_days", c.cfg.OperationRetentionDays),
+		zap.Int("login_retention_days", c.cfg.LoginRetentionDays))
+}
+
+// Stop 停止后台清理
+func (c *LogCleaner) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.stop)
+	})
+}
+
+// run 启动后先清理一次，之后按周期清理
+func (c *LogCleaner) run() {
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+
+	c.Cleanup()
+	for {
+		select {
+		case <-ticker.C:
+			c.Cleanup()
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// Cleanup 删除超过保留天数的日志
+func (c *LogCleaner) Cleanup() *LogCleanupResult {
+	if c.db == nil {
+		return nil
+	}
+
+	now := time.Now()
+	result := &LogCleanupResult{
+		OperationSince: now.AddDate(0, 0, -c.cfg.OperationRetentionDays),
+		LoginSince:     now.AddDate(0, 0, -c.cfg.LoginRetentionDays),
+	}
+
+	var err error
+	result.OperationLogs, err = purgeBefore(c.db, &models.OperationLog{}, result.OperationSince, c.cfg.BatchSize)
+	if err != nil {
+		c.logger.Error("Failed to clean up operation logs", zap.Error(err), zap.Int64("deleted", result.OperationLogs))
+	}
+	result.LoginLogs, err = purgeBefore(c.db, &models.LoginLog{}, result.LoginSince, c.cfg.BatchSize)
+	if err != nil {
+		c.logger.Error("Failed to clean up login logs", zap.Error(err), zap.Int64("deleted", result.LoginLogs))
+	}
+
+	c.logger.Info("Audit log cleanup completed",
+		zap.Int64("deleted_operation_logs", result.OperationLogs),
+		zap.Int64("deleted_login_logs", result.LoginLogs),
+		zap.Time("operation_cutoff", result.OperationSince),
+		zap.Time("login_cutoff", result.LoginSince),
+		zap.Duration("elapsed", time.Since(now)))
+
+	return result
+}
+
+// purgeBefore 分批物理删除创建时间早于cutoff的记录，返回已删除条数
+func purgeBefore(db *gorm.DB, model interface{}, cutoff time.Time, batchSize int) (int64, error) {
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	var total int64
+	for {
+		var ids []uint
+		if err := db.Unscoped().Model(model).Where("created_at < ?", cutoff).
+			Order("id").Limit(batchSize).Pluck("id", &ids).Error; err != nil {
+			return total, err
+		}
+		if len(ids) == 0 {
+			return total, nil
+		}
+
+		result := db.Unscoped().Where("id IN ?", ids).Delete(model)
+		if result.Error != nil {
+			return total, result.Error
+		}
+		total += result.RowsAffected
+
+		if len(ids) < batchSize {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/database/dbtest"
+	"github.com/env-data-platform/internal/models"
+)
+
+func TestPurgeBefore(t *testing.T) {
+	cutoff := time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name      string
+		expired   int
+		batchSize int
+		queryErr  bool
+		deleted   int64
+		batches   []int
+		limit     int
+		queries   int
+	}{
+		{name: "无过期日志", expired: 0, batchSize: 2, deleted: 0, batches: nil, limit: 2, queries: 1},
+		{name: "不足一批", expired: 1, batchSize: 2, deleted: 1, batches: []int{1}, limit: 2, queries: 1},
+		{name: "多批且最后一批不满", expired: 5, batchSize: 2, deleted: 5, batches: []int{2, 2, 1}, limit: 2, queries: 3},
+		// 恰好整批时再查询一次确认没有剩余
+		{name: "恰好整批", expired: 4, batchSize: 2, deleted: 4, batches: []int{2, 2}, limit: 2, queries: 3},
+		{name: "默认批大小", expired: 3, batchSize: 0, deleted: 3, batches: []int{3}, limit: 1000, queries: 1},
+		{name: "查询失败", expired: 3, batchSize: 2, queryErr: true, deleted: 0, batches: nil, limit: 2, queries: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.New(t)
+			nextID, remaining := uint(1), tt.expired
+			db.On(dbtest.KindQuery, func(tx *gorm.DB) {
+				if tt.queryErr {
+					tx.AddError(errors.New("connection refused"))
+					return
+				}
+				ids, ok := tx.Statement.Dest.(*[]uint)
+				if !ok {
+					return
+				}
+				for ; remaining > 0 && len(*ids) < tt.limit; remaining-- {
+					*ids = append(*ids, nextID)
+					nextID++
+				}
+			})
+			db.On(dbtest.KindDelete, func(tx *gorm.DB) {
+				tx.RowsAffected = int64(len(tx.Statement.Vars))
+			})
+
+			deleted, err := purgeBefore(db.DB, &models.OperationLog{}, cutoff, tt.batchSize)
+			assert.Equal(t, tt.deleted, deleted)
+			if tt.queryErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			// 每批先按ID顺序取出过期记录，再按ID物理删除
+			queries := db.Statements(dbtest.KindQuery)
+			require.Len(t, queries, tt.queries)
+			for _, query := range queries {
+				assert.Contains(t, query.SQL, "WHERE created_at < ? ORDER BY id LIMIT")
+				assert.NotContains(t, query.SQL, "deleted_at")
+				assert.Equal(t, cutoff, query.Vars[0])
+			}
+			assert.Contains(t, queries[0].SQL, fmt.Sprintf("LIMIT %d", tt.limit))
+
+			deletes := db.Statements(dbtest.KindDelete)
+			require.Len(t, deletes, len(tt.batches))
+			for i, statement := range deletes {
+				assert.Equal(t, "env_operation_logs", statement.Table)
+				assert.Contains(t, statement.SQL, "DELETE FROM `env_operation_logs` WHERE id IN (")
+				assert.Len(t, statement.Vars, tt.batches[i])
+			}
+		})
+	}
+}
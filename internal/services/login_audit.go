@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/alarm"
+	"github.com/env-data-platform/internal/config"
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/models"
+)
+
+// intranetLocation 内网、回环地址的登录地点
+const intranetLocation = "内网"
+
+// remoteLoginMailTimeout 发送异地登录提醒邮件的超时时间
+const remoteLoginMailTimeout = 30 * time.Second
+
+// LoginAuditService 登录审计：为登录日志补充设备、地理位置信息并识别异地登录
+type LoginAuditService struct {
+	db      *gorm.DB
+	logger  *zap.Logger
+	cfg     config.LoginAuditConfig
+	email   config.EmailNotifyConfig
+	locator GeoLocator
+}
+
+// NewLoginAuditService 创建登录审计服务，IP地址库加载失败时仅记录日志，不解析地理位置
+func NewLoginAuditService(cfg *config.Config, logger *zap.Logger) *LoginAuditService {
+	s := &LoginAuditService{
+		db:     database.GetDB(),
+		logger: logger,
+		cfg:    cfg.Security.LoginAudit,
+		email:  cfg.Alarm.Notify.Email,
+	}
+	if path := s.cfg.GeoIPFile; path != "" {
+		locator, err := LoadIPRangeLocator(path)
+		if err != nil {
+			logger.Error("Failed to load GeoIP database, login location disabled", zap.String("file", path), zap.Error(err))
+		} else {
+			logger.Info("GeoIP database loaded", zap.String("file", path), zap.Int("ranges", locator.Len()))
+			s.locator = locator
+		}
+	}
+	return s
+}
+
+// Enrich 根据User-Agent和IP补充登录日志的设备和地理信息
+func (s *LoginAuditService) Enrich(log *models.LoginLog) {
+	ua := ParseUserAgent(log.UserAgent)
+	log.DeviceType = ua.DeviceType
+	log.OS = ua.OS
+	log.Browser = ua.Browser
+
+	addr, err := netip.ParseAddr(log.IP)
+	if err != nil {
+		return
+	}
+	addr = addr.Unmap()
+	if addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast() {
+		// 内网地址只记录地点，不参与异地判断
+		log.Location = intranetLocation
+		return
+	}
+	if s.locator == nil {
+		return
+	}
+	location, ok := s.locator.Lookup(addr)
+	if !ok {
+		return
+	}
+	log.Country = location.Country
+	log.Province = location.Province
+	log.City = location.City
+	log.Location = location.String()
+	if location.ISP != "" {
+		setLoginExtra(log, "isp", location.ISP)
+	}
+}
+
+// MarkRemoteLogin 成功登录的地点与该用户上一次可定位的成功登录不在同一地区时标记为异地登录，
+// 首次登录或任一方无法定位时不标记。应在Enrich之后、保存日志之前调用
+func (s *LoginAuditService) MarkRemoteLogin(log *models.LoginLog) bool {
+	if log.Status != 1 || log.UserID == nil || log.Country == "" {
+		return false
+	}
+
+	var last models.LoginLog
+	err := s.db.Select("id", "location", "country", "province", "city", "ip", "created_at").
+		Where("user_id = ? AND status = 1 AND country <> ''", *log.UserID).
+		Order("id DESC").First(&last).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false
+	}
+	if err != nil {
+		s.logger.Error("Failed to query last login location", zap.Uint("user_id", *log.UserID), zap.Error(err))
+		return false
+	}
+
+	current := GeoLocation{Country: log.Country, Province: log.Province, City: log.City}
+	previous := GeoLocation{Country: last.Country, Province: last.Province, City: last.City}
+	if current.SameRegion(previous) {
+		return false
+	}
+
+	log.IsRemote = true
+	setLoginExtra(log, "previous_location", previous.String())
+	setLoginExtra(log, "previous_ip", last.IP)
+	setLoginExtra(log, "previous_login_at", last.CreatedAt)
+	return true
+}
+
+// NotifyRemoteLogin 异步邮件提醒用户发生了异地登录，未开启提醒或用户没有邮箱时只记录日志
+func (s *LoginAuditService) NotifyRemoteLogin(log models.LoginLog) {
+	s.logger.Warn("Remote login detected",
+		zap.Uint("user_id", *log.UserID),
+		zap.String("username", log.Username),
+		zap.String("ip", log.IP),
+		zap.String("location", log.Location),
+		zap.Any("previous_location", log.Extra["previous_location"]))
+
+	if !s.cfg.RemoteLoginAlert {
+		return
+	}
+	go s.sendRemoteLoginMail(log)
+}
+
+// sendRemoteLoginMail 发送异地登录提醒邮件，失败仅记录日志
+func (s *LoginAuditService) sendRemoteLoginMail(log models.LoginLog) {
+	var user models.User
+	if err := s.db.Select("id", "username", "real_name", "email").First(&user, *log.UserID).Error; err != nil {
+		s.logger.Error("Failed to load user for remote login alert", zap.Uint("user_id", *log.UserID), zap.Error(err))
+		return
+	}
+	if user.Email == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), remoteLoginMailTimeout)
+	defer cancel()
+
+	name := user.RealName
+	if name == "" {
+		name = user.Username
+	}
+	device := strings.TrimSpace(log.OS + " " + log.Browser)
+	body := fmt.Sprintf("%s，您好：\n\n您的账号(%s)于%s在%s（IP %s）登录，与上次登录地点%v不同。\n登录设备：%s\n\n"+
+		"如果这不是您本人的操作，请立即修改密码并联系管理员。",
+		name, user.Username, log.CreatedAt.Format("2006-01-02 15:04:05"), log.Location, log.IP,
+		log.Extra["previous_location"], device)
+
+	if err := alarm.SendEmail(ctx, s.email, []string{user.Email}, "环境数据平台异地登录提醒", body); err != nil {
+		s.logger.Error("Failed to send remote login alert", zap.Uint("user_id", user.ID), zap.Error(err))
+	}
+}
+
+// setLoginExtra 写入登录日志的扩展信息
+func setLoginExtra(log *models.LoginLog, key string, value interface{}) {
+	if log.Extra == nil {
+		log.Extra = models.JSONMap{}
+	}
+	log.Extra[key] = value
+}
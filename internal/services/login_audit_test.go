@@ -0,0 +1,119 @@
+package services
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/env-data-platform/internal/models"
+)
+
+func TestParseUserAgent(t *testing.T) {
+	cases := []struct {
+		ua   string
+		want UserAgentInfo
+	}{
+		{
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			UserAgentInfo{models.LoginDeviceDesktop, "Windows 10", "Chrome 120"},
+		},
+		{
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.2210.91",
+			UserAgentInfo{models.LoginDeviceDesktop, "Windows 10", "Edge 120"},
+		},
+		{
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Safari/605.1.15",
+			UserAgentInfo{models.LoginDeviceDesktop, "macOS 10.15", "Safari 17"},
+		},
+		{
+			"Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1",
+			UserAgentInfo{models.LoginDeviceMobile, "iOS 17.1", "Safari 17"},
+		},
+		{
+			"Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 MicroMessenger/8.0.42",
+			UserAgentInfo{models.LoginDeviceTablet, "iOS 16.6", "WeChat 8"},
+		},
+		{
+			"Mozilla/5.0 (Linux; Android 13; Pixel 7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Mobile Safari/537.36",
+			UserAgentInfo{models.LoginDeviceMobile, "Android 13", "Chrome 119"},
+		},
+		{
+			"Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0",
+			UserAgentInfo{models.LoginDeviceDesktop, "Linux", "Firefox 121"},
+		},
+		{"curl/8.4.0", UserAgentInfo{DeviceType: models.LoginDeviceClient, Browser: "curl"}},
+		{"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", UserAgentInfo{DeviceType: models.LoginDeviceBot}},
+		{"", UserAgentInfo{DeviceType: models.LoginDeviceUnknown}},
+	}
+
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, ParseUserAgent(tc.ua), tc.ua)
+	}
+}
+
+const testIPRanges = `
+# 起始IP,结束IP,国家,省份,城市,运营商
+1.0.1.0,1.0.3.255,中国,福建,福州,电信
+14.16.0.0,14.31.255.255,中国,广东,深圳,电信
+36.110.0.0,36.110.255.255,中国,北京,北京,电信
+2400:da00::,2400:da00:ffff:ffff:ffff:ffff:ffff:ffff,中国,北京,北京,
+`
+
+func TestIPRangeLocator(t *testing.T) {
+	locator, err := ParseIPRanges(strings.NewReader(testIPRanges))
+	require.NoError(t, err)
+	assert.Equal(t, 4, locator.Len())
+
+	loc, ok := locator.Lookup(netip.MustParseAddr("14.20.1.1"))
+	require.True(t, ok)
+	assert.Equal(t, GeoLocation{Country: "中国", Province: "广东", City: "深圳", ISP: "电信"}, loc)
+	assert.Equal(t, "中国 广东 深圳", loc.String())
+
+	loc, ok = locator.Lookup(netip.MustParseAddr("::ffff:36.110.1.1"))
+	require.True(t, ok)
+	assert.Equal(t, "中国 北京", loc.String())
+
+	_, ok = locator.Lookup(netip.MustParseAddr("2400:da00::1"))
+	assert.True(t, ok)
+	_, ok = locator.Lookup(netip.MustParseAddr("1.0.4.0"))
+	assert.False(t, ok)
+	_, ok = locator.Lookup(netip.MustParseAddr("0.0.0.1"))
+	assert.False(t, ok)
+
+	_, err = ParseIPRanges(strings.NewReader("1.0.3.255,1.0.1.0,中国,福建,福州"))
+	assert.Error(t, err)
+	_, err = ParseIPRanges(strings.NewReader("1.0.1.0,1.0.3.255,中国"))
+	assert.Error(t, err)
+}
+
+func TestGeoLocationSameRegion(t *testing.T) {
+	shenzhen := GeoLocation{Country: "中国", Province: "广东", City: "深圳"}
+	assert.True(t, shenzhen.SameRegion(GeoLocation{Country: "中国", Province: "广东", City: "深圳", ISP: "联通"}))
+	assert.False(t, shenzhen.SameRegion(GeoLocation{Country: "中国", Province: "广东", City: "广州"}))
+	// 城市未知时比较到省份
+	assert.True(t, shenzhen.SameRegion(GeoLocation{Country: "中国", Province: "广东"}))
+	assert.False(t, shenzhen.SameRegion(GeoLocation{Country: "中国", Province: "北京"}))
+}
+
+func TestLoginAuditEnrich(t *testing.T) {
+	locator, err := ParseIPRanges(strings.NewReader(testIPRanges))
+	require.NoError(t, err)
+	s := &LoginAuditService{locator: locator}
+
+	log := models.LoginLog{IP: "14.20.1.1", UserAgent: "curl/8.4.0"}
+	s.Enrich(&log)
+	assert.Equal(t, models.LoginDeviceClient, log.DeviceType)
+	assert.Equal(t, "中国 广东 深圳", log.Location)
+	assert.Equal(t, "深圳", log.City)
+	assert.Equal(t, "电信", log.Extra["isp"])
+
+	// 内网地址不解析地理位置，不参与异地判断
+	log = models.LoginLog{IP: "192.168.1.10"}
+	s.Enrich(&log)
+	assert.Equal(t, intranetLocation, log.Location)
+	assert.Empty(t, log.Country)
+	assert.False(t, s.MarkRemoteLogin(&log))
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MetadataDiff 两个版本元数据之间的差异
+type MetadataDiff struct {
+	FromVersion    int         `json:"from_version"`
+	ToVersion      int         `json:"to_version"`
+	HasChanges     bool        `json:"has_changes"`
+	AddedTables    []string    `json:"added_tables"`
+	RemovedTables  []string    `json:"removed_tables"`
+	ModifiedTables []TableDiff `json:"modified_tables"`
+}
+
+// TableDiff 单个表的差异
+type TableDiff struct {
+	Table           string           `json:"table"`
+	Changes         []FieldChange    `json:"changes,omitempty"` // 表注释等表级属性变更
+	AddedColumns    []ColumnMetadata `json:"added_columns,omitempty"`
+	RemovedColumns  []ColumnMetadata `json:"removed_columns,omitempty"`
+	ModifiedColumns []ColumnDiff     `json:"modified_columns,omitempty"`
+	AddedIndexes    []IndexMetadata  `json:"added_indexes,omitempty"`
+	RemovedIndexes  []IndexMetadata  `json:"removed_indexes,omitempty"`
+	ModifiedIndexes []IndexDiff      `json:"modified_indexes,omitempty"`
+}
+
+// ColumnDiff 列属性差异
+type ColumnDiff struct {
+	Column  string        `json:"column"`
+	Changes []FieldChange `json:"changes"`
+}
+
+// IndexDiff 索引定义差异
+type IndexDiff struct {
+	Index   string        `json:"index"`
+	Changes []FieldChange `json:"changes"`
+}
+
+// FieldChange 属性变更前后的值
+type FieldChange struct {
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// DiffMetadata 比较新旧两组表元数据。行数、大小等统计信息不视为结构变更
+func DiffMetadata(oldTables, newTables []TableMetadata) *MetadataDiff {
+	diff := &MetadataDiff{
+		AddedTables:    []string{},
+		RemovedTables:  []string{},
+		ModifiedTables: []TableDiff{},
+	}
+
+	oldMap := indexTables(oldTables)
+	newMap := indexTables(newTables)
+
+	for _, key := range sortedKeys(newMap) {
+		newTable := newMap[key]
+		oldTable, ok := oldMap[key]
+		if !ok {
+			diff.AddedTables = append(diff.AddedTables, key)
+			continue
+		}
+		if tableDiff := diffTable(key, oldTable, newTable); tableDiff != nil {
+			diff.ModifiedTables = append(diff.ModifiedTables, *tableDiff)
+		}
+	}
+	for _, key := range sortedKeys(oldMap) {
+		if _, ok := newMap[key]; !ok {
+			diff.RemovedTables = append(diff.RemovedTables, key)
+		}
+	}
+
+	diff.HasChanges = len(diff.AddedTables) > 0 || len(diff.RemovedTables) > 0 || len(diff.ModifiedTables) > 0
+	return diff
+}
+
+// diffTable 比较同名表，无差异时返回nil
+func diffTable(name string, oldTable, newTable *TableMetadata) *TableDiff {
+	diff := &TableDiff{Table: name}
+	diff.Changes = appendChange(diff.Changes, "comment", oldTable.Comment, newTable.Comment)
+
+	oldColumns := make(map[string]ColumnMetadata, len(oldTable.Columns))
+	for _, col := range oldTable.Columns {
+		oldColumns[col.Name] = col
+	}
+	newColumns := make(map[string]bool, len(newTable.Columns))
+	for _, col := range newTable.Columns {
+		newColumns[col.Name] = true
+		oldCol, ok := oldColumns[col.Name]
+		if !ok {
+			diff.AddedColumns = append(diff.AddedColumns, col)
+			continue
+		}
+		if changes := diffColumn(oldCol, col); len(changes) > 0 {
+			diff.ModifiedColumns = append(diff.ModifiedColumns, ColumnDiff{Column: col.Name, Changes: changes})
+		}
+	}
+	for _, col := range oldTable.Columns {
+		if !newColumns[col.Name] {
+			diff.RemovedColumns = append(diff.RemovedColumns, col)
+		}
+	}
+
+	oldIndexes := make(map[string]IndexMetadata, len(oldTable.Indexes))
+	for _, idx := range oldTable.Indexes {
+		oldIndexes[idx.Name] = idx
+	}
+	newIndexes := make(map[string]bool, len(newTable.Indexes))
+	for _, idx := range sortedIndexes(newTable.Indexes) {
+		newIndexes[idx.Name] = true
+		oldIdx, ok := oldIndexes[idx.Name]
+		if !ok {
+			diff.AddedIndexes = append(diff.AddedIndexes, idx)
+			continue
+		}
+		if changes := diffIndex(oldIdx, idx); len(changes) > 0 {
+			diff.ModifiedIndexes = append(diff.ModifiedIndexes, IndexDiff{Index: idx.Name, Changes: changes})
+		}
+	}
+	for _, idx := range sortedIndexes(oldTable.Indexes) {
+		if !newIndexes[idx.Name] {
+			diff.RemovedIndexes = append(diff.RemovedIndexes, idx)
+		}
+	}
+
+	if len(diff.Changes) == 0 && len(diff.AddedColumns) == 0 && len(diff.RemovedColumns) == 0 &&
+		len(diff.ModifiedColumns) == 0 && len(diff.AddedIndexes) == 0 && len(diff.RemovedIndexes) == 0 &&
+		len(diff.ModifiedIndexes) == 0 {
+		return nil
+	}
+	return diff
+}
+
+// diffColumn 比较列定义
+func diffColumn(oldCol, newCol ColumnMetadata) []FieldChange {
+	var changes []FieldChange
+	changes = appendChange(changes, "type", oldCol.Type, newCol.Type)
+	changes = appendChange(changes, "length", formatIntPtr(oldCol.Length), formatIntPtr(newCol.Length))
+	changes = appendChange(changes, "precision", formatIntPtr(oldCol.Precision), formatIntPtr(newCol.Precision))
+	changes = appendChange(changes, "scale", formatIntPtr(oldCol.Scale), formatIntPtr(newCol.Scale))
+	changes = appendChange(changes, "is_nullable", fmt.Sprint(oldCol.IsNullable), fmt.Sprint(newCol.IsNullable))
+	changes = appendChange(changes, "is_primary_key", fmt.Sprint(oldCol.IsPrimaryKey), fmt.Sprint(newCol.IsPrimaryKey))
+	changes = appendChange(changes, "is_auto_increment", fmt.Sprint(oldCol.IsAutoIncr), fmt.Sprint(newCol.IsAutoIncr))
+	changes = appendChange(changes, "default_value", oldCol.DefaultValue, newCol.DefaultValue)
+	changes = appendChange(changes, "comment", oldCol.Comment, newCol.Comment)
+	return changes
+}
+
+// diffIndex 比较索引定义
+func diffIndex(oldIdx, newIdx IndexMetadata) []FieldChange {
+	var changes []FieldChange
+	changes = appendChange(changes, "type", oldIdx.Type, newIdx.Type)
+	changes = appendChange(changes, "columns", strings.Join(oldIdx.Columns, ","), strings.Join(newIdx.Columns, ","))
+	changes = appendChange(changes, "is_unique", fmt.Sprint(oldIdx.IsUnique), fmt.Sprint(newIdx.IsUnique))
+	return changes
+}
+
+func appendChange(changes []FieldChange, field, before, after string) []FieldChange {
+	if before == after {
+		return changes
+	}
+	return append(changes, FieldChange{Field: field, Before: before, After: after})
+}
+
+func formatIntPtr(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(*v)
+}
+
+// tableKey 表的唯一标识，有schema时为schema.name
+func tableKey(table *TableMetadata) string {
+	if table.Schema == "" {
+		return table.Name
+	}
+	return table.Schema + "." + table.Name
+}
+
+func indexTables(tables []TableMetadata) map[string]*TableMetadata {
+	result := make(map[string]*TableMetadata, len(tables))
+	for i := range tables {
+		result[tableKey(&tables[i])] = &tables[i]
+	}
+	return result
+}
+
+func sortedKeys(tables map[string]*TableMetadata) []string {
+	keys := make([]string, 0, len(tables))
+	for key := range tables {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedIndexes 按索引名排序，MySQL索引从map中取出时顺序不固定
+func sortedIndexes(indexes []IndexMetadata) []IndexMetadata {
+	sorted := make([]IndexMetadata, len(indexes))
+	copy(sorted, indexes)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return sorted
+}
+
+// normalizeTables 返回用于比较的表元数据：按表名、索引名排序，去掉行数、大小、列统计等统计信息
+func normalizeTables(tables []TableMetadata) []TableMetadata {
+	normalized := make([]TableMetadata, len(tables))
+	for i, table := range tables {
+		table.RowCount = 0
+		table.Size = 0
+		table.UpdatedAt = nil
+		table.Indexes = sortedIndexes(table.Indexes)
+		table.Columns = make([]ColumnMetadata, len(tables[i].Columns))
+		for j, col := range tables[i].Columns {
+			col.Stats = nil
+			table.Columns[j] = col
+		}
+		normalized[i] = table
+	}
+	sort.Slice(normalized, func(i, j int) bool {
+		return tableKey(&normalized[i]) < tableKey(&normalized[j])
+	})
+	return normalized
+}
+
+// MetadataChecksum 计算表结构的校验和，统计信息变化不影响结果
+func MetadataChecksum(tables []TableMetadata) (string, error) {
+	data, err := json.Marshal(normalizeTables(tables))
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffMetadata(t *testing.T) {
+	length := 50
+	newLength := 100
+	oldTables := []TableMetadata{
+		{
+			Name: "station", Schema: "env", RowCount: 10,
+			Columns: []ColumnMetadata{
+				{Name: "id", Type: "int", IsPrimaryKey: true},
+				{Name: "name", Type: "varchar", Length: &length},
+				{Name: "legacy", Type: "varchar"},
+			},
+			Indexes: []IndexMetadata{{Name: "idx_name", Type: "BTREE", Columns: []string{"name"}}},
+		},
+		{Name: "obsolete", Schema: "env"},
+	}
+	newTables := []TableMetadata{
+		{Name: "monitor_data", Schema: "env"},
+		{
+			Name: "station", Schema: "env", RowCount: 99,
+			Columns: []ColumnMetadata{
+				{Name: "id", Type: "bigint", IsPrimaryKey: true},
+				{Name: "name", Type: "varchar", Length: &newLength},
+				{Name: "region", Type: "varchar", IsNullable: true},
+			},
+			Indexes: []IndexMetadata{{Name: "idx_name", Type: "BTREE", Columns: []string{"name"}, IsUnique: true}},
+		},
+	}
+
+	diff := DiffMetadata(oldTables, newTables)
+	assert.True(t, diff.HasChanges)
+	assert.Equal(t, []string{"env.monitor_data"}, diff.AddedTables)
+	assert.Equal(t, []string{"env.obsolete"}, diff.RemovedTables)
+	require.Len(t, diff.ModifiedTables, 1)
+
+	table := diff.ModifiedTables[0]
+	assert.Equal(t, "env.station", table.Table)
+	require.Len(t, table.AddedColumns, 1)
+	assert.Equal(t, "region", table.AddedColumns[0].Name)
+	require.Len(t, table.RemovedColumns, 1)
+	assert.Equal(t, "legacy", table.RemovedColumns[0].Name)
+	require.Len(t, table.ModifiedColumns, 2)
+	assert.Equal(t, ColumnDiff{Column: "id", Changes: []FieldChange{{Field: "type", Before: "int", After: "bigint"}}}, table.ModifiedColumns[0])
+	assert.Equal(t, ColumnDiff{Column: "name", Changes: []FieldChange{{Field: "length", Before: "50", After: "100"}}}, table.ModifiedColumns[1])
+	require.Len(t, table.ModifiedIndexes, 1)
+	assert.Equal(t, "is_unique", table.ModifiedIndexes[0].Changes[0].Field)
+}
+
+func TestMetadataChecksum_IgnoresStatistics(t *testing.T) {
+	a := []TableMetadata{
+		{Name: "b", RowCount: 1, Indexes: []IndexMetadata{{Name: "y"}, {Name: "x"}}},
+		{Name: "a", Size: 1024},
+	}
+	b := []TableMetadata{
+		{Name: "a", Size: 2048},
+		{Name: "b", RowCount: 500, Indexes: []IndexMetadata{{Name: "x"}, {Name: "y"}}},
+	}
+
+	sumA, err := MetadataChecksum(a)
+	require.NoError(t, err)
+	sumB, err := MetadataChecksum(b)
+	require.NoError(t, err)
+	assert.Equal(t, sumA, sumB)
+	assert.False(t, DiffMetadata(a, b).HasChanges)
+
+	b[0].Comment = "changed"
+	sumB, _ = MetadataChecksum(b)
+	assert.NotEqual(t, sumA, sumB)
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrSnapshotNotFound 元数据版本不存在
+var ErrSnapshotNotFound = errors.New("元数据版本不存在")
+
+// MetadataSnapshotService 元数据快照服务，保存每次同步的结果并计算版本间差异
+type MetadataSnapshotService struct {
+	db *gorm.DB
+}
+
+// NewMetadataSnapshotService 创建元数据快照服务
+func NewMetadataSnapshotService() *MetadataSnapshotService {
+	return &MetadataSnapshotService{db: database.GetDB()}
+}
+
+// MetadataSaveResult 保存同步结果的返回
+type MetadataSaveResult struct {
+	Snapshot *models.MetadataSnapshot
+	Diff     *MetadataDiff       // 相对上一版本的差异
+	Applied  *MetadataApplyStats // 表/列/索引元数据的写入统计
+}
+
+// Save 保存同步结果。表结构与最新版本相同时只刷新同步时间，否则生成新版本；
+// 同时将表/列/索引元数据增量写入对应的表
+func (s *MetadataSnapshotService) Save(dataSourceID uint, result *MetadataSyncResult) (*MetadataSaveResult, error) {
+	checksum, err := MetadataChecksum(result.Tables)
+	if err != nil {
+		return nil, fmt.Errorf("计算元数据校验和失败: %w", err)
+	}
+	content, err := json.Marshal(result.Tables)
+	if err != nil {
+		return nil, fmt.Errorf("序列化元数据失败: %w", err)
+	}
+
+	saved := &MetadataSaveResult{}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		snapshot, diff, err := s.saveSnapshot(tx, dataSourceID, result, checksum, content)
+		if err != nil {
+			return err
+		}
+		applied, err := applyTables(tx, dataSourceID, snapshot.Version, result.Tables, result.SyncedAt)
+		if err != nil {
+			return fmt.Errorf("保存表元数据失败: %w", err)
+		}
+		saved.Snapshot, saved.Diff, saved.Applied = snapshot, diff, applied
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// saveSnapshot 保存快照，结构未变化时复用最新版本
+func (s *MetadataSnapshotService) saveSnapshot(tx *gorm.DB, dataSourceID uint, result *MetadataSyncResult, checksum string, content []byte) (*models.MetadataSnapshot, *MetadataDiff, error) {
+	latest, err := s.latest(tx, dataSourceID)
+	if err != nil && !errors.Is(err, ErrSnapshotNotFound) {
+		return nil, nil, err
+	}
+
+	var previousTables []TableMetadata
+	if latest != nil {
+		if latest.Checksum == checksum {
+			diff := DiffMetadata(nil, nil)
+			diff.FromVersion, diff.ToVersion = latest.Version, latest.Version
+			if err := tx.Model(latest).Update("last_synced_at", result.SyncedAt).Error; err != nil {
+				return nil, nil, err
+			}
+			return latest, diff, nil
+		}
+		if previousTables, err = decodeSnapshot(latest); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	diff := DiffMetadata(previousTables, result.Tables)
+	snapshot := &models.MetadataSnapshot{
+		DataSourceID:   dataSourceID,
+		Version:        1,
+		TableCount:     len(result.Tables),
+		Checksum:       checksum,
+		Content:        string(content),
+		TablesAdded:    len(diff.AddedTables),
+		TablesRemoved:  len(diff.RemovedTables),
+		TablesModified: len(diff.ModifiedTables),
+		LastSyncedAt:   result.SyncedAt,
+	}
+	if latest != nil {
+		snapshot.Version = latest.Version + 1
+		diff.FromVersion = latest.Version
+	}
+	diff.ToVersion = snapshot.Version
+	if err := tx.Create(snapshot).Error; err != nil {
+		return nil, nil, err
+	}
+	return snapshot, diff, nil
+}
+
+// List 分页获取数据源的元数据版本列表，按版本号倒序
+func (s *MetadataSnapshotService) List(dataSourceID uint, page, pageSize int) ([]models.MetadataSnapshot, int64, error) {
+	var total int64
+	query := s.db.Model(&models.MetadataSnapshot{}).Where("data_source_id = ?", dataSourceID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var snapshots []models.MetadataSnapshot
+	err := query.Omit("content").Order("version DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&snapshots).Error
+	return snapshots, total, err
+}
+
+// Tables 获取指定版本的表元数据，version<=0时取最新版本
+func (s *MetadataSnapshotService) Tables(dataSourceID uint, version int) (*models.MetadataSnapshot, []TableMetadata, error) {
+	var snapshot *models.MetadataSnapshot
+	var err error
+	if version > 0 {
+		snapshot, err = s.Get(dataSourceID, version)
+	} else {
+		snapshot, err = s.latest(s.db, dataSourceID)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	tables, err := decodeSnapshot(snapshot)
+	if err != nil {
+		return nil, nil, err
+	}
+	return snapshot, tables, nil
+}
+
+// Diff 计算两个版本之间的差异，fromVersion<=0时取toVersion的上一版本，toVersion<=0时取最新版本
+func (s *MetadataSnapshotService) Diff(dataSourceID uint, fromVersion, toVersion int) (*MetadataDiff, error) {
+	var to *models.MetadataSnapshot
+	var err error
+	if toVersion > 0 {
+		to, err = s.Get(dataSourceID, toVersion)
+	} else {
+		to, err = s.latest(s.db, dataSourceID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if fromVersion <= 0 {
+		fromVersion = to.Version - 1
+	}
+
+	var fromTables []TableMetadata
+	if fromVersion > 0 {
+		from, err := s.Get(dataSourceID, fromVersion)
+		if err != nil {
+			return nil, err
+		}
+		if fromTables, err = decodeSnapshot(from); err != nil {
+			return nil, err
+		}
+	}
+	toTables, err := decodeSnapshot(to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := DiffMetadata(fromTables, toTables)
+	diff.FromVersion = fromVersion
+	diff.ToVersion = to.Version
+	return diff, nil
+}
+
+// Get 获取指定版本的快照
+func (s *MetadataSnapshotService) Get(dataSourceID uint, version int) (*models.MetadataSnapshot, error) {
+	var snapshot models.MetadataSnapshot
+	err := s.db.Where("data_source_id = ? AND version = ?", dataSourceID, version).First(&snapshot).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSnapshotNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// latest 获取最新版本的快照
+func (s *MetadataSnapshotService) latest(db *gorm.DB, dataSourceID uint) (*models.MetadataSnapshot, error) {
+	var snapshot models.MetadataSnapshot
+	err := db.Where("data_source_id = ?", dataSourceID).Order("version DESC").First(&snapshot).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSnapshotNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// decodeSnapshot 解析快照中的表元数据
+func decodeSnapshot(snapshot *models.MetadataSnapshot) ([]TableMetadata, error) {
+	var tables []TableMetadata
+	if snapshot.Content == "" {
+		return tables, nil
+	}
+	if err := json.Unmarshal([]byte(snapshot.Content), &tables); err != nil {
+		return nil, fmt.Errorf("解析元数据版本%d失败: %w", snapshot.Version, err)
+	}
+	return tables, nil
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 列统计默认参数
+const (
+	defaultStatsSampleThreshold = 100000
+	defaultStatsSampleSize      = 10000
+	defaultStatsSampleValues    = 5
+	statsValueMaxLength         = 200
+)
+
+// MetadataSyncOptions 元数据同步选项
+type MetadataSyncOptions struct {
+	ColumnStats     bool  // 是否采集列统计信息，代价较高，默认关闭
+	SampleThreshold int64 // 表行数超过该值时只统计前SampleSize行
+	SampleSize      int64 // 采样行数
+	SampleValues    int   // 每列返回的样例值个数
+}
+
+// timeout 同步超时时间，采集列统计时放宽
+func (o MetadataSyncOptions) timeout() time.Duration {
+	if o.ColumnStats {
+		return 10 * time.Minute
+	}
+	return 30 * time.Second
+}
+
+func (o MetadataSyncOptions) sampleThreshold() int64 {
+	if o.SampleThreshold > 0 {
+		return o.SampleThreshold
+	}
+	return defaultStatsSampleThreshold
+}
+
+func (o MetadataSyncOptions) sampleSize() int64 {
+	if o.SampleSize > 0 {
+		return o.SampleSize
+	}
+	return defaultStatsSampleSize
+}
+
+func (o MetadataSyncOptions) sampleValues() int {
+	if o.SampleValues > 0 {
+		return o.SampleValues
+	}
+	return defaultStatsSampleValues
+}
+
+// ColumnStats 列统计信息
+type ColumnStats struct {
+	Min           string   `json:"min,omitempty"`
+	Max           string   `json:"max,omitempty"`
+	DistinctCount int64    `json:"distinct_count"`
+	NullCount     int64    `json:"null_count"`
+	NullRatio     float64  `json:"null_ratio"` // 0-1
+	SampleValues  []string `json:"sample_values,omitempty"`
+	RowCount      int64    `json:"row_count"` // 参与统计的行数
+	Sampled       bool     `json:"sampled"`   // 是否为采样统计
+}
+
+// statsDialect 列统计SQL的方言差异
+type statsDialect struct {
+	name string
+	// skipTypes 无法比较或去重的类型，不采集统计
+	skipTypes map[string]bool
+}
+
+var (
+	mysqlStatsDialect = statsDialect{name: "mysql", skipTypes: map[string]bool{
+		"blob": true, "tinyblob": true, "mediumblob": true, "longblob": true,
+		"binary": true, "varbinary": true, "json": true, "geometry": true, "point": true,
+		"linestring": true, "polygon": true,
+	}}
+	postgresStatsDialect = statsDialect{name: "postgresql", skipTypes: map[string]bool{
+		"bytea": true, "json": true, "jsonb": true, "xml": true, "point": true, "line": true, "box": true,
+		"polygon": true, "circle": true, "path": true, "lseg": true, "tsvector": true, "array": true,
+		"user-defined": true,
+	}}
+	sqlServerStatsDialect = statsDialect{name: "sqlserver", skipTypes: map[string]bool{
+		"text": true, "ntext": true, "image": true, "xml": true, "bit": true, "binary": true,
+		"varbinary": true, "geometry": true, "geography": true, "hierarchyid": true, "sql_variant": true,
+	}}
+)
+
+// quote 引用标识符
+func (d statsDialect) quote(name string) string {
+	switch d.name {
+	case "mysql":
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	case "sqlserver":
+		return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+	default:
+		return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+	}
+}
+
+// tableName 带schema的表名
+func (d statsDialect) tableName(table *TableMetadata) string {
+	if table.Schema == "" {
+		return d.quote(table.Name)
+	}
+	return d.quote(table.Schema) + "." + d.quote(table.Name)
+}
+
+// source 统计的数据来源，limit>0时只取前limit行
+func (d statsDialect) source(table, column string, limit int64) string {
+	if limit <= 0 {
+		return table
+	}
+	if d.name == "sqlserver" {
+		return fmt.Sprintf("(SELECT TOP %d %s FROM %s) AS s", limit, column, table)
+	}
+	return fmt.Sprintf("(SELECT %s FROM %s LIMIT %d) AS s", column, table, limit)
+}
+
+// sampleQuery 查询最多n个不同的非空样例值
+func (d statsDialect) sampleQuery(column, source string, n int) string {
+	if d.name == "sqlserver" {
+		return fmt.Sprintf("SELECT DISTINCT TOP %d %s FROM %s WHERE %s IS NOT NULL", n, column, source, column)
+	}
+	return fmt.Sprintf("SELECT DISTINCT %s FROM %s WHERE %s IS NOT NULL LIMIT %d", column, source, column, n)
+}
+
+// supports 判断列类型是否支持统计
+func (d statsDialect) supports(columnType string) bool {
+	columnType = strings.ToLower(columnType)
+	if i := strings.IndexAny(columnType, "( "); i > 0 && d.name != "postgresql" {
+		columnType = columnType[:i]
+	}
+	return !d.skipTypes[columnType]
+}
+
+// collectColumnStats 采集表中各列的统计信息，行数超过阈值的表只统计前SampleSize行。
+// 单列统计失败不影响其他列和元数据同步结果
+func (s *MetadataSyncService) collectColumnStats(ctx context.Context, db *sql.DB, dialect statsDialect, table *TableMetadata, opts MetadataSyncOptions) {
+	var limit int64
+	if table.RowCount > opts.sampleThreshold() {
+		limit = opts.sampleSize()
+	}
+	tableName := dialect.tableName(table)
+
+	for i := range table.Columns {
+		col := &table.Columns[i]
+		if !dialect.supports(col.Type) {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		stats, err := queryColumnStats(ctx, db, dialect, tableName, dialect.quote(col.Name), limit, opts.sampleValues())
+		if err != nil {
+			continue
+		}
+		col.Stats = stats
+	}
+}
+
+// queryColumnStats 查询单列的统计信息
+func queryColumnStats(ctx context.Context, db *sql.DB, dialect statsDialect, table, column string, limit int64, sampleValues int) (*ColumnStats, error) {
+	source := dialect.source(table, column, limit)
+	query := fmt.Sprintf(
+		"SELECT MIN(%[1]s), MAX(%[1]s), COUNT(DISTINCT %[1]s), SUM(CASE WHEN %[1]s IS NULL THEN 1 ELSE 0 END), COUNT(*) FROM %[2]s",
+		column, source)
+
+	var minValue, maxValue sql.NullString
+	var nullCount sql.NullInt64
+	stats := &ColumnStats{Sampled: limit > 0}
+	if err := db.QueryRowContext(ctx, query).Scan(&minValue, &maxValue, &stats.DistinctCount, &nullCount, &stats.RowCount); err != nil {
+		return nil, err
+	}
+	stats.Min = truncateString(minValue.String, statsValueMaxLength)
+	stats.Max = truncateString(maxValue.String, statsValueMaxLength)
+	stats.NullCount = nullCount.Int64
+	if stats.RowCount > 0 {
+		stats.NullRatio = float64(stats.NullCount) / float64(stats.RowCount)
+	}
+
+	rows, err := db.QueryContext(ctx, dialect.sampleQuery(column, source, sampleValues))
+	if err != nil {
+		return stats, nil
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var value sql.NullString
+		if err := rows.Scan(&value); err == nil && value.Valid {
+			stats.SampleValues = append(stats.SampleValues, truncateString(value.String, statsValueMaxLength))
+		}
+	}
+
+	return stats, nil
+}
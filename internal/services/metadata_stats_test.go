@@ -0,0 +1,34 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatsDialect(t *testing.T) {
+	table := &TableMetadata{Name: "monitor`data", Schema: "env"}
+	assert.Equal(t, "`env`.`monitor``data`", mysqlStatsDialect.tableName(table))
+	assert.Equal(t, `"env"."monitor`+"`"+`data"`, postgresStatsDialect.tableName(table))
+	assert.Equal(t, "[dbo].[a]]b]", sqlServerStatsDialect.tableName(&TableMetadata{Name: "a]b", Schema: "dbo"}))
+
+	assert.Equal(t, "(SELECT `v` FROM `t` LIMIT 100) AS s", mysqlStatsDialect.source("`t`", "`v`", 100))
+	assert.Equal(t, "(SELECT TOP 100 [v] FROM [t]) AS s", sqlServerStatsDialect.source("[t]", "[v]", 100))
+	assert.Equal(t, "`t`", mysqlStatsDialect.source("`t`", "`v`", 0))
+
+	assert.True(t, mysqlStatsDialect.supports("varchar"))
+	assert.False(t, mysqlStatsDialect.supports("longblob"))
+	assert.True(t, postgresStatsDialect.supports("timestamp without time zone"))
+	assert.False(t, postgresStatsDialect.supports("jsonb"))
+	assert.False(t, sqlServerStatsDialect.supports("ntext"))
+}
+
+func TestMetadataChecksum_IgnoresColumnStats(t *testing.T) {
+	tables := []TableMetadata{{Name: "t", Columns: []ColumnMetadata{{Name: "v", Type: "int"}}}}
+	before, _ := MetadataChecksum(tables)
+
+	tables[0].Columns[0].Stats = &ColumnStats{Min: "1", Max: "9", DistinctCount: 9}
+	after, _ := MetadataChecksum(tables)
+	assert.Equal(t, before, after)
+	assert.NotNil(t, tables[0].Columns[0].Stats, "计算校验和不应修改原数据")
+}
@@ -0,0 +1,318 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	"github.com/env-data-platform/internal/models"
+	"gorm.io/gorm"
+)
+
+// MetadataApplyStats 元数据落库的写入统计
+type MetadataApplyStats struct {
+	TablesCreated  int `json:"tables_created"`
+	TablesUpdated  int `json:"tables_updated"`
+	TablesDeleted  int `json:"tables_deleted"`
+	ColumnsWritten int `json:"columns_written"`
+	IndexesWritten int `json:"indexes_written"`
+}
+
+// applyTables 将同步结果增量写入表/列/索引元数据表，只写入有变化的记录。
+// version为本次结构所属的元数据版本，结构变化的表记录该版本号
+func applyTables(tx *gorm.DB, dataSourceID uint, version int, tables []TableMetadata, syncedAt time.Time) (*MetadataApplyStats, error) {
+	stats := &MetadataApplyStats{}
+
+	var existing []models.DataTable
+	if err := tx.Preload("Columns").Preload("Indexes").
+		Where("data_source_id = ?", dataSourceID).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	existingMap := make(map[string]*models.DataTable, len(existing))
+	for i := range existing {
+		existingMap[dataTableKey(&existing[i])] = &existing[i]
+	}
+
+	seen := make(map[string]bool, len(tables))
+	for i := range tables {
+		table := &tables[i]
+		key := tableKey(table)
+		seen[key] = true
+
+		current, ok := existingMap[key]
+		if !ok {
+			record := &models.DataTable{
+				DataSourceID: dataSourceID,
+				Name:         table.Name,
+				TableComment: truncateString(table.Comment, 500),
+				Schema:       table.Schema,
+				RowCount:     table.RowCount,
+				SizeBytes:    table.Size,
+				LastSyncAt:   &syncedAt,
+				IsActive:     true,
+				Version:      version,
+			}
+			if err := tx.Create(record).Error; err != nil {
+				return nil, err
+			}
+			stats.TablesCreated++
+			written, err := applyColumns(tx, record.ID, nil, table.Columns)
+			if err != nil {
+				return nil, err
+			}
+			stats.ColumnsWritten += written
+			if written, err = applyIndexes(tx, record.ID, nil, table.Indexes); err != nil {
+				return nil, err
+			}
+			stats.IndexesWritten += written
+			continue
+		}
+
+		columnsWritten, err := applyColumns(tx, current.ID, current.Columns, table.Columns)
+		if err != nil {
+			return nil, err
+		}
+		indexesWritten, err := applyIndexes(tx, current.ID, current.Indexes, table.Indexes)
+		if err != nil {
+			return nil, err
+		}
+		stats.ColumnsWritten += columnsWritten
+		stats.IndexesWritten += indexesWritten
+
+		updates := map[string]interface{}{}
+		comment := truncateString(table.Comment, 500)
+		if current.TableComment != comment {
+			updates["table_comment"] = comment
+		}
+		if current.RowCount != table.RowCount {
+			updates["row_count"] = table.RowCount
+		}
+		if current.SizeBytes != table.Size {
+			updates["size_bytes"] = table.Size
+		}
+		if columnsWritten > 0 || indexesWritten > 0 || updates["table_comment"] != nil {
+			updates["version"] = version
+		}
+		if len(updates) == 0 {
+			continue
+		}
+		updates["last_sync_at"] = syncedAt
+		if err := tx.Model(current).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+		stats.TablesUpdated++
+	}
+
+	for key, table := range existingMap {
+		if seen[key] {
+			continue
+		}
+		if err := deleteDataTable(tx, table.ID); err != nil {
+			return nil, err
+		}
+		stats.TablesDeleted++
+	}
+
+	return stats, nil
+}
+
+// applyColumns 增量更新表的列，返回写入（新增、修改、删除）的列数
+func applyColumns(tx *gorm.DB, tableID uint, existing []models.DataColumn, columns []ColumnMetadata) (int, error) {
+	existingMap := make(map[string]*models.DataColumn, len(existing))
+	for i := range existing {
+		existingMap[existing[i].ColumnName] = &existing[i]
+	}
+
+	written := 0
+	var creates []models.DataColumn
+	seen := make(map[string]bool, len(columns))
+	for i, col := range columns {
+		seen[col.Name] = true
+		record := toDataColumn(tableID, i+1, col)
+
+		current, ok := existingMap[col.Name]
+		if !ok {
+			creates = append(creates, record)
+			continue
+		}
+		updates := dataColumnUpdates(current, &record)
+		if len(updates) == 0 {
+			continue
+		}
+		if err := tx.Model(current).Updates(updates).Error; err != nil {
+			return written, err
+		}
+		written++
+	}
+
+	if len(creates) > 0 {
+		if err := tx.Create(&creates).Error; err != nil {
+			return written, err
+		}
+		written += len(creates)
+	}
+
+	var removed []uint
+	for name, col := range existingMap {
+		if !seen[name] {
+			removed = append(removed, col.ID)
+		}
+	}
+	if len(removed) > 0 {
+		if err := tx.Delete(&models.DataColumn{}, removed).Error; err != nil {
+			return written, err
+		}
+		written += len(removed)
+	}
+
+	return written, nil
+}
+
+// applyIndexes 增量更新表的索引，返回写入的索引数
+func applyIndexes(tx *gorm.DB, tableID uint, existing []models.DataIndex, indexes []IndexMetadata) (int, error) {
+	existingMap := make(map[string]*models.DataIndex, len(existing))
+	for i := range existing {
+		existingMap[existing[i].IndexName] = &existing[i]
+	}
+
+	written := 0
+	var creates []models.DataIndex
+	seen := make(map[string]bool, len(indexes))
+	for _, idx := range indexes {
+		seen[idx.Name] = true
+		record := models.DataIndex{
+			TableID:   tableID,
+			IndexName: idx.Name,
+			IndexType: idx.Type,
+			Columns:   truncateString(strings.Join(idx.Columns, ","), 500),
+			IsUnique:  idx.IsUnique,
+		}
+
+		current, ok := existingMap[idx.Name]
+		if !ok {
+			creates = append(creates, record)
+			continue
+		}
+		if current.IndexType == record.IndexType && current.Columns == record.Columns && current.IsUnique == record.IsUnique {
+			continue
+		}
+		if err := tx.Model(current).Updates(map[string]interface{}{
+			"index_type": record.IndexType,
+			"columns":    record.Columns,
+			"is_unique":  record.IsUnique,
+		}).Error; err != nil {
+			return written, err
+		}
+		written++
+	}
+
+	if len(creates) > 0 {
+		if err := tx.Create(&creates).Error; err != nil {
+			return written, err
+		}
+		written += len(creates)
+	}
+
+	var removed []uint
+	for name, idx := range existingMap {
+		if !seen[name] {
+			removed = append(removed, idx.ID)
+		}
+	}
+	if len(removed) > 0 {
+		if err := tx.Delete(&models.DataIndex{}, removed).Error; err != nil {
+			return written, err
+		}
+		written += len(removed)
+	}
+
+	return written, nil
+}
+
+// deleteDataTable 删除表及其列、索引记录
+func deleteDataTable(tx *gorm.DB, tableID uint) error {
+	if err := tx.Where("table_id = ?", tableID).Delete(&models.DataColumn{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("table_id = ?", tableID).Delete(&models.DataIndex{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&models.DataTable{}, tableID).Error
+}
+
+// toDataColumn 将同步得到的列元数据转换为列模型
+func toDataColumn(tableID uint, position int, col ColumnMetadata) models.DataColumn {
+	record := models.DataColumn{
+		TableID:      tableID,
+		ColumnName:   col.Name,
+		DataType:     truncateString(col.Type, 50),
+		IsNullable:   col.IsNullable,
+		IsPrimaryKey: col.IsPrimaryKey,
+		IsAutoIncr:   col.IsAutoIncr,
+		DefaultValue: truncateString(col.DefaultValue, 255),
+		Comment:      truncateString(col.Comment, 500),
+		Position:     position,
+	}
+	if col.Length != nil {
+		record.Length = *col.Length
+	}
+	if col.Precision != nil {
+		record.Precision = *col.Precision
+	}
+	if col.Scale != nil {
+		record.Scale = *col.Scale
+	}
+	return record
+}
+
+// dataColumnUpdates 比较列记录，返回需要更新的字段
+func dataColumnUpdates(current, target *models.DataColumn) map[string]interface{} {
+	updates := map[string]interface{}{}
+	if current.DataType != target.DataType {
+		updates["data_type"] = target.DataType
+	}
+	if current.Length != target.Length {
+		updates["length"] = target.Length
+	}
+	if current.Precision != target.Precision {
+		updates["precision"] = target.Precision
+	}
+	if current.Scale != target.Scale {
+		updates["scale"] = target.Scale
+	}
+	if current.IsNullable != target.IsNullable {
+		updates["is_nullable"] = target.IsNullable
+	}
+	if current.IsPrimaryKey != target.IsPrimaryKey {
+		updates["is_primary_key"] = target.IsPrimaryKey
+	}
+	if current.IsAutoIncr != target.IsAutoIncr {
+		updates["is_auto_incr"] = target.IsAutoIncr
+	}
+	if current.DefaultValue != target.DefaultValue {
+		updates["default_value"] = target.DefaultValue
+	}
+	if current.Comment != target.Comment {
+		updates["comment"] = target.Comment
+	}
+	if current.Position != target.Position {
+		updates["position"] = target.Position
+	}
+	return updates
+}
+
+// dataTableKey 与tableKey一致的表标识
+func dataTableKey(table *models.DataTable) string {
+	if table.Schema == "" {
+		return table.Name
+	}
+	return table.Schema + "." + table.Name
+}
+
+// truncateString 按字符截断字符串，避免超出列长度
+func truncateString(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDataColumnUpdates(t *testing.T) {
+	length := 64
+	current := toDataColumn(1, 2, ColumnMetadata{Name: "name", Type: "varchar", Length: &length, Comment: "名称"})
+
+	same := toDataColumn(1, 2, ColumnMetadata{Name: "name", Type: "varchar", Length: &length, Comment: "名称"})
+	assert.Empty(t, dataColumnUpdates(&current, &same))
+
+	longer := 128
+	changed := toDataColumn(1, 3, ColumnMetadata{Name: "name", Type: "varchar", Length: &longer, IsNullable: true, Comment: "名称"})
+	assert.Equal(t, map[string]interface{}{
+		"length":      128,
+		"is_nullable": true,
+		"position":    3,
+	}, dataColumnUpdates(&current, &changed))
+}
+
+func TestTruncateString(t *testing.T) {
+	assert.Equal(t, "监测", truncateString("监测数据", 2))
+	assert.Equal(t, "abc", truncateString("abc", 5))
+}
@@ -53,6 +53,8 @@ type ColumnMetadata struct {
 	IsAutoIncr   bool   `json:"is_auto_increment,omitempty"`
 	DefaultValue string `json:"default_value,omitempty"`
 	Comment      string `json:"comment,omitempty"`
+
+	Stats *ColumnStats `json:"stats,omitempty"` // 列统计信息，开启列统计时采集
 }
 
 // IndexMetadata 索引元数据
@@ -65,6 +67,11 @@ type IndexMetadata struct {
 
 // SyncMetadata 同步数据源元数据
 func (s *MetadataSyncService) SyncMetadata(ctx context.Context, dataSource *models.DataSource) *MetadataSyncResult {
+	return s.SyncMetadataWithOptions(ctx, dataSource, MetadataSyncOptions{})
+}
+
+// SyncMetadataWithOptions 按选项同步数据源元数据
+func (s *MetadataSyncService) SyncMetadataWithOptions(ctx context.Context, dataSource *models.DataSource, opts MetadataSyncOptions) *MetadataSyncResult {
 	startTime := time.Now()
 
 	result := &MetadataSyncResult{
@@ -72,11 +79,20 @@ func (s *MetadataSyncService) SyncMetadata(ctx context.Context, dataSource *mode
 		Details:  make(map[string]interface{}),
 	}
 
+	// 文件导入的数据源连接平台数据库，不能枚举库中的其他表，元数据在导入时已登记
+	if dataSource.IsImported() {
+		result.Success = false
+		result.Message = "文件导入的数据源无需同步元数据"
+		return result
+	}
+
 	switch dataSource.Type {
 	case "mysql":
-		result = s.syncMySQLMetadata(ctx, dataSource, result)
+		result = s.syncMySQLMetadata(ctx, dataSource, result, opts)
 	case "postgresql":
-		result = s.syncPostgreSQLMetadata(ctx, dataSource, result)
+		result = s.syncPostgreSQLMetadata(ctx, dataSource, result, opts)
+	case "sqlserver":
+		result = s.syncSQLServerMetadata(ctx, dataSource, result, opts)
 	case "hj212":
 		result = s.syncHJ212Metadata(ctx, dataSource, result)
 	default:
@@ -88,7 +104,7 @@ func (s *MetadataSyncService) SyncMetadata(ctx context.Context, dataSource *mode
 }
 
 // syncMySQLMetadata 同步MySQL元数据
-func (s *MetadataSyncService) syncMySQLMetadata(ctx context.Context, dataSource *models.DataSource, result *MetadataSyncResult) *MetadataSyncResult {
+func (s *MetadataSyncService) syncMySQLMetadata(ctx context.Context, dataSource *models.DataSource, result *MetadataSyncResult, opts MetadataSyncOptions) *MetadataSyncResult {
 	// 解析配置
 	var config map[string]interface{}
 	if err := json.Unmarshal(dataSource.ConfigData, &config); err != nil {
@@ -98,14 +114,13 @@ func (s *MetadataSyncService) syncMySQLMetadata(ctx context.Context, dataSource
 	}
 
 	// 构建MySQL连接字符串
-	host := config["host"].(string)
-	port := int(config["port"].(float64))
-	username := config["username"].(string)
-	password := config["password"].(string)
-	database := config["database"].(string)
-
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true&charset=utf8mb4",
-		username, password, host, port, database)
+	connConfig, err := parseDBConnConfig(config)
+	if err != nil {
+		result.Success = false
+		result.Message = fmt.Sprintf("解析配置失败: %v", err)
+		return result
+	}
+	dsn := connConfig.mysqlDSN()
 
 	// 连接数据库
 	db, err := sql.Open("mysql", dsn)
@@ -117,7 +132,7 @@ func (s *MetadataSyncService) syncMySQLMetadata(ctx context.Context, dataSource
 	defer db.Close()
 
 	// 设置查询超时
-	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout())
 	defer cancel()
 
 	// 验证连接
@@ -128,7 +143,7 @@ func (s *MetadataSyncService) syncMySQLMetadata(ctx context.Context, dataSource
 	}
 
 	// 获取表列表
-	tables, err := s.getMySQLTables(ctx, db, database)
+	tables, err := s.getMySQLTables(ctx, db, connConfig.Database)
 	if err != nil {
 		result.Success = false
 		result.Message = fmt.Sprintf("获取表列表失败: %v", err)
@@ -138,17 +153,20 @@ func (s *MetadataSyncService) syncMySQLMetadata(ctx context.Context, dataSource
 	// 获取每个表的详细信息
 	var tableMetadata []TableMetadata
 	for _, tableName := range tables {
-		metadata, err := s.getMySQLTableMetadata(ctx, db, database, tableName)
+		metadata, err := s.getMySQLTableMetadata(ctx, db, connConfig.Database, tableName)
 		if err != nil {
 			continue // 跳过获取失败的表
 		}
+		if opts.ColumnStats {
+			s.collectColumnStats(ctx, db, mysqlStatsDialect, metadata, opts)
+		}
 		tableMetadata = append(tableMetadata, *metadata)
 	}
 
 	result.Success = true
 	result.Message = fmt.Sprintf("成功同步 %d 个表的元数据", len(tableMetadata))
 	result.Tables = tableMetadata
-	result.Details["database"] = database
+	result.Details["database"] = connConfig.Database
 	result.Details["table_count"] = len(tableMetadata)
 
 	return result
@@ -327,7 +345,7 @@ func (s *MetadataSyncService) getMySQLIndexes(ctx context.Context, db *sql.DB, d
 }
 
 // syncPostgreSQLMetadata 同步PostgreSQL元数据
-func (s *MetadataSyncService) syncPostgreSQLMetadata(ctx context.Context, dataSource *models.DataSource, result *MetadataSyncResult) *MetadataSyncResult {
+func (s *MetadataSyncService) syncPostgreSQLMetadata(ctx context.Context, dataSource *models.DataSource, result *MetadataSyncResult, opts MetadataSyncOptions) *MetadataSyncResult {
 	// 解析配置
 	var config map[string]interface{}
 	if err := json.Unmarshal(dataSource.ConfigData, &config); err != nil {
@@ -337,14 +355,13 @@ func (s *MetadataSyncService) syncPostgreSQLMetadata(ctx context.Context, dataSo
 	}
 
 	// 构建PostgreSQL连接字符串
-	host := config["host"].(string)
-	port := int(config["port"].(float64))
-	username := config["username"].(string)
-	password := config["password"].(string)
-	database := config["database"].(string)
-
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		host, port, username, password, database)
+	connConfig, err := parseDBConnConfig(config)
+	if err != nil {
+		result.Success = false
+		result.Message = fmt.Sprintf("解析配置失败: %v", err)
+		return result
+	}
+	dsn := connConfig.postgresDSN()
 
 	// 连接数据库
 	db, err := sql.Open("postgres", dsn)
@@ -356,7 +373,7 @@ func (s *MetadataSyncService) syncPostgreSQLMetadata(ctx context.Context, dataSo
 	defer db.Close()
 
 	// 设置查询超时
-	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout())
 	defer cancel()
 
 	// 验证连接
@@ -381,13 +398,16 @@ func (s *MetadataSyncService) syncPostgreSQLMetadata(ctx context.Context, dataSo
 		if err != nil {
 			continue // 跳过获取失败的表
 		}
+		if opts.ColumnStats {
+			s.collectColumnStats(ctx, db, postgresStatsDialect, metadata, opts)
+		}
 		tableMetadata = append(tableMetadata, *metadata)
 	}
 
 	result.Success = true
 	result.Message = fmt.Sprintf("成功同步 %d 个表的元数据", len(tableMetadata))
 	result.Tables = tableMetadata
-	result.Details["database"] = database
+	result.Details["database"] = connConfig.Database
 	result.Details["table_count"] = len(tableMetadata)
 
 	return result
@@ -442,9 +462,72 @@ func (s *MetadataSyncService) getPostgreSQLTableMetadata(ctx context.Context, db
 	}
 	metadata.Columns = columns
 
+	// 获取索引信息
+	indexes, primaryColumns, err := s.getPostgreSQLIndexes(ctx, db, tableName)
+	if err == nil {
+		metadata.Indexes = indexes
+		for i := range metadata.Columns {
+			if primaryColumns[metadata.Columns[i].Name] {
+				metadata.Columns[i].IsPrimaryKey = true
+			}
+		}
+	}
+
 	return metadata, nil
 }
 
+// getPostgreSQLIndexes 获取PostgreSQL表的索引信息，同时返回主键包含的列
+func (s *MetadataSyncService) getPostgreSQLIndexes(ctx context.Context, db *sql.DB, tableName string) ([]IndexMetadata, map[string]bool, error) {
+	// 表达式索引的列用pg_get_indexdef取出表达式文本
+	query := `
+		SELECT i.relname, upper(am.amname), ix.indisunique, ix.indisprimary,
+			   COALESCE(a.attname, pg_get_indexdef(ix.indexrelid, k.ord::int, true))
+		FROM pg_index ix
+		JOIN pg_class t ON t.oid = ix.indrelid
+		JOIN pg_class i ON i.oid = ix.indexrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		JOIN pg_am am ON am.oid = i.relam
+		CROSS JOIN LATERAL unnest(ix.indkey::int2[]) WITH ORDINALITY AS k(attnum, ord)
+		LEFT JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum AND k.attnum > 0
+		WHERE n.nspname = 'public' AND t.relname = $1
+		ORDER BY i.relname, k.ord
+	`
+
+	rows, err := db.QueryContext(ctx, query, tableName)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var indexes []IndexMetadata
+	primaryColumns := make(map[string]bool)
+	for rows.Next() {
+		var indexName, indexType, columnName string
+		var isUnique, isPrimary bool
+
+		if err := rows.Scan(&indexName, &indexType, &isUnique, &isPrimary, &columnName); err != nil {
+			continue
+		}
+		if isPrimary {
+			primaryColumns[columnName] = true
+		}
+
+		// 结果按索引名排序，同一索引的列相邻
+		if n := len(indexes); n > 0 && indexes[n-1].Name == indexName {
+			indexes[n-1].Columns = append(indexes[n-1].Columns, columnName)
+			continue
+		}
+		indexes = append(indexes, IndexMetadata{
+			Name:     indexName,
+			Type:     indexType,
+			Columns:  []string{columnName},
+			IsUnique: isUnique,
+		})
+	}
+
+	return indexes, primaryColumns, rows.Err()
+}
+
 // getPostgreSQLColumns 获取PostgreSQL表的列信息
 func (s *MetadataSyncService) getPostgreSQLColumns(ctx context.Context, db *sql.DB, tableName string) ([]ColumnMetadata, error) {
 	query := `
@@ -500,6 +583,256 @@ func (s *MetadataSyncService) getPostgreSQLColumns(ctx context.Context, db *sql.
 	return columns, nil
 }
 
+// syncSQLServerMetadata 同步SQL Server元数据
+func (s *MetadataSyncService) syncSQLServerMetadata(ctx context.Context, dataSource *models.DataSource, result *MetadataSyncResult, opts MetadataSyncOptions) *MetadataSyncResult {
+	// 解析配置
+	var config map[string]interface{}
+	if err := json.Unmarshal(dataSource.ConfigData, &config); err != nil {
+		result.Success = false
+		result.Message = fmt.Sprintf("解析配置失败: %v", err)
+		return result
+	}
+
+	// 连接数据库
+	db, err := openSQLServer(config)
+	if err != nil {
+		result.Success = false
+		result.Message = fmt.Sprintf("SQL Server连接失败: %v", err)
+		return result
+	}
+	defer db.Close()
+
+	// 设置查询超时
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout())
+	defer cancel()
+
+	// 验证连接
+	if err := db.PingContext(ctx); err != nil {
+		result.Success = false
+		result.Message = fmt.Sprintf("SQL Server ping失败: %v", err)
+		return result
+	}
+
+	// 获取表列表（包含所有schema）
+	tables, err := s.getSQLServerTables(ctx, db)
+	if err != nil {
+		result.Success = false
+		result.Message = fmt.Sprintf("获取表列表失败: %v", err)
+		return result
+	}
+
+	// 获取每个表的详细信息
+	var tableMetadata []TableMetadata
+	for _, table := range tables {
+		metadata, err := s.getSQLServerTableMetadata(ctx, db, table[0], table[1])
+		if err != nil {
+			continue // 跳过获取失败的表
+		}
+		if opts.ColumnStats {
+			s.collectColumnStats(ctx, db, sqlServerStatsDialect, metadata, opts)
+		}
+		tableMetadata = append(tableMetadata, *metadata)
+	}
+
+	result.Success = true
+	result.Message = fmt.Sprintf("成功同步 %d 个表的元数据", len(tableMetadata))
+	result.Tables = tableMetadata
+	result.Details["database"] = config["database"]
+	result.Details["table_count"] = len(tableMetadata)
+
+	return result
+}
+
+// getSQLServerTables 获取SQL Server表列表，返回[schema, table]
+func (s *MetadataSyncService) getSQLServerTables(ctx context.Context, db *sql.DB) ([][2]string, error) {
+	query := `
+		SELECT TABLE_SCHEMA, TABLE_NAME
+		FROM INFORMATION_SCHEMA.TABLES
+		WHERE TABLE_TYPE = 'BASE TABLE'
+		ORDER BY TABLE_SCHEMA, TABLE_NAME
+	`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables [][2]string
+	for rows.Next() {
+		var schema, tableName string
+		if err := rows.Scan(&schema, &tableName); err != nil {
+			continue
+		}
+		tables = append(tables, [2]string{schema, tableName})
+	}
+
+	return tables, nil
+}
+
+// getSQLServerTableMetadata 获取SQL Server表的详细元数据
+func (s *MetadataSyncService) getSQLServerTableMetadata(ctx context.Context, db *sql.DB, schema, tableName string) (*TableMetadata, error) {
+	metadata := &TableMetadata{
+		Name:   tableName,
+		Schema: schema,
+	}
+	objectName := schema + "." + tableName
+
+	// 获取表注释（MS_Description扩展属性）
+	commentQuery := `
+		SELECT CAST(ep.value AS NVARCHAR(4000))
+		FROM sys.extended_properties ep
+		WHERE ep.major_id = OBJECT_ID(@p1) AND ep.minor_id = 0 AND ep.name = 'MS_Description'
+	`
+	var comment sql.NullString
+	db.QueryRowContext(ctx, commentQuery, objectName).Scan(&comment)
+	metadata.Comment = comment.String
+
+	// 获取表行数和占用空间
+	statsQuery := `
+		SELECT
+			COALESCE(SUM(CASE WHEN p.index_id IN (0, 1) THEN p.rows ELSE 0 END), 0),
+			COALESCE(SUM(a.total_pages), 0) * 8 * 1024
+		FROM sys.partitions p
+		JOIN sys.allocation_units a ON a.container_id = p.partition_id
+		WHERE p.object_id = OBJECT_ID(@p1)
+	`
+	db.QueryRowContext(ctx, statsQuery, objectName).Scan(&metadata.RowCount, &metadata.Size)
+
+	// 获取列信息
+	columns, err := s.getSQLServerColumns(ctx, db, schema, tableName)
+	if err != nil {
+		return nil, err
+	}
+	metadata.Columns = columns
+
+	// 获取索引信息
+	indexes, err := s.getSQLServerIndexes(ctx, db, objectName)
+	if err == nil {
+		metadata.Indexes = indexes
+	}
+
+	return metadata, nil
+}
+
+// getSQLServerColumns 获取SQL Server表的列信息
+func (s *MetadataSyncService) getSQLServerColumns(ctx context.Context, db *sql.DB, schema, tableName string) ([]ColumnMetadata, error) {
+	query := `
+		SELECT c.COLUMN_NAME, c.DATA_TYPE, c.IS_NULLABLE, c.COLUMN_DEFAULT,
+			   c.CHARACTER_MAXIMUM_LENGTH, c.NUMERIC_PRECISION, c.NUMERIC_SCALE,
+			   COLUMNPROPERTY(OBJECT_ID(@p1 + '.' + @p2), c.COLUMN_NAME, 'IsIdentity'),
+			   CASE WHEN pk.COLUMN_NAME IS NULL THEN 0 ELSE 1 END,
+			   CAST(ep.value AS NVARCHAR(4000))
+		FROM INFORMATION_SCHEMA.COLUMNS c
+		LEFT JOIN (
+			SELECT ku.COLUMN_NAME
+			FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS tc
+			JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE ku
+				ON ku.CONSTRAINT_NAME = tc.CONSTRAINT_NAME AND ku.TABLE_SCHEMA = tc.TABLE_SCHEMA
+			WHERE tc.CONSTRAINT_TYPE = 'PRIMARY KEY' AND tc.TABLE_SCHEMA = @p1 AND tc.TABLE_NAME = @p2
+		) pk ON pk.COLUMN_NAME = c.COLUMN_NAME
+		LEFT JOIN sys.extended_properties ep
+			ON ep.major_id = OBJECT_ID(@p1 + '.' + @p2)
+			AND ep.minor_id = COLUMNPROPERTY(OBJECT_ID(@p1 + '.' + @p2), c.COLUMN_NAME, 'ColumnId')
+			AND ep.name = 'MS_Description'
+		WHERE c.TABLE_SCHEMA = @p1 AND c.TABLE_NAME = @p2
+		ORDER BY c.ORDINAL_POSITION
+	`
+
+	rows, err := db.QueryContext(ctx, query, schema, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []ColumnMetadata
+	for rows.Next() {
+		var col ColumnMetadata
+		var isNullable string
+		var defaultValue, comment sql.NullString
+		var maxLength, precision, scale, isIdentity sql.NullInt64
+		var isPrimaryKey int
+
+		err := rows.Scan(
+			&col.Name, &col.Type, &isNullable, &defaultValue,
+			&maxLength, &precision, &scale,
+			&isIdentity, &isPrimaryKey, &comment,
+		)
+		if err != nil {
+			continue
+		}
+
+		col.IsNullable = (isNullable == "YES")
+		col.IsPrimaryKey = (isPrimaryKey == 1)
+		col.IsAutoIncr = (isIdentity.Int64 == 1)
+
+		if defaultValue.Valid {
+			col.DefaultValue = defaultValue.String
+		}
+		if comment.Valid {
+			col.Comment = comment.String
+		}
+		if maxLength.Valid {
+			length := int(maxLength.Int64)
+			col.Length = &length
+		}
+		if precision.Valid {
+			prec := int(precision.Int64)
+			col.Precision = &prec
+		}
+		if scale.Valid {
+			sc := int(scale.Int64)
+			col.Scale = &sc
+		}
+
+		columns = append(columns, col)
+	}
+
+	return columns, nil
+}
+
+// getSQLServerIndexes 获取SQL Server表的索引信息
+func (s *MetadataSyncService) getSQLServerIndexes(ctx context.Context, db *sql.DB, objectName string) ([]IndexMetadata, error) {
+	query := `
+		SELECT i.name, i.type_desc, col.name, i.is_unique
+		FROM sys.indexes i
+		JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+		JOIN sys.columns col ON col.object_id = ic.object_id AND col.column_id = ic.column_id
+		WHERE i.object_id = OBJECT_ID(@p1) AND i.name IS NOT NULL AND ic.is_included_column = 0
+		ORDER BY i.name, ic.key_ordinal
+	`
+
+	rows, err := db.QueryContext(ctx, query, objectName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var indexes []IndexMetadata
+	for rows.Next() {
+		var indexName, indexType, columnName string
+		var isUnique bool
+
+		if err := rows.Scan(&indexName, &indexType, &columnName, &isUnique); err != nil {
+			continue
+		}
+
+		// 结果按索引名排序，同一索引的列相邻
+		if n := len(indexes); n > 0 && indexes[n-1].Name == indexName {
+			indexes[n-1].Columns = append(indexes[n-1].Columns, columnName)
+			continue
+		}
+		indexes = append(indexes, IndexMetadata{
+			Name:     indexName,
+			Type:     indexType,
+			Columns:  []string{columnName},
+			IsUnique: isUnique,
+		})
+	}
+
+	return indexes, rows.Err()
+}
+
 // syncHJ212Metadata 同步HJ212设备元数据
 func (s *MetadataSyncService) syncHJ212Metadata(ctx context.Context, dataSource *models.DataSource, result *MetadataSyncResult) *MetadataSyncResult {
 	// 解析配置
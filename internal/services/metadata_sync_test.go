@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPostgreSQLIndexes(t *testing.T) {
+	columns := []string{"relname", "amname", "indisunique", "indisprimary", "attname"}
+	tests := []struct {
+		name    string
+		rows    [][]driver.Value
+		indexes []IndexMetadata
+		primary map[string]bool
+	}{
+		{
+			name: "主键、唯一复合索引和表达式索引",
+			rows: [][]driver.Value{
+				{"idx_email_lower", "BTREE", false, false, "lower((email)::text)"},
+				{"idx_tenant_code", "BTREE", true, false, "tenant_id"},
+				{"idx_tenant_code", "BTREE", true, false, "code"},
+				{"users_pkey", "BTREE", true, true, "id"},
+			},
+			indexes: []IndexMetadata{
+				{Name: "idx_email_lower", Type: "BTREE", Columns: []string{"lower((email)::text)"}},
+				{Name: "idx_tenant_code", Type: "BTREE", Columns: []string{"tenant_id", "code"}, IsUnique: true},
+				{Name: "users_pkey", Type: "BTREE", Columns: []string{"id"}, IsUnique: true},
+			},
+			primary: map[string]bool{"id": true},
+		},
+		{
+			name: "复合主键",
+			rows: [][]driver.Value{
+				{"orders_pkey", "BTREE", true, true, "tenant_id"},
+				{"orders_pkey", "BTREE", true, true, "order_id"},
+				{"idx_payload", "GIN", false, false, "payload"},
+			},
+			indexes: []IndexMetadata{
+				{Name: "orders_pkey", Type: "BTREE", Columns: []string{"tenant_id", "order_id"}, IsUnique: true},
+				{Name: "idx_payload", Type: "GIN", Columns: []string{"payload"}},
+			},
+			primary: map[string]bool{"tenant_id": true, "order_id": true},
+		},
+		{name: "没有索引", primary: map[string]bool{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			rows := sqlmock.NewRows(columns)
+			for _, row := range tt.rows {
+				rows.AddRow(row...)
+			}
+			mock.ExpectQuery(`FROM pg_index ix .* WHERE n.nspname = 'public' AND t.relname = \$1`).
+				WithArgs("users").
+				WillReturnRows(rows)
+
+			s := &MetadataSyncService{}
+			indexes, primary, err := s.getPostgreSQLIndexes(context.Background(), db, "users")
+			require.NoError(t, err)
+			assert.Equal(t, tt.indexes, indexes)
+			assert.Equal(t, tt.primary, primary)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
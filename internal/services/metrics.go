@@ -0,0 +1,100 @@
+package services
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/env-data-platform/internal/models"
+)
+
+var (
+	// 正在运行的ETL作业数
+	etlRunningJobs = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "etl_running_jobs",
+			Help: "Number of ETL jobs currently running",
+		},
+	)
+
+	// ETL执行次数，status为success/failed/timeout
+	etlExecutionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "etl_executions_total",
+			Help: "Total number of finished ETL executions",
+		},
+		[]string{"source_type", "status"},
+	)
+
+	// ETL因临时故障触发的自动重试次数
+	etlRetriesTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "etl_retries_total",
+			Help: "Total number of automatic ETL execution retries",
+		},
+	)
+
+	// ETL执行耗时
+	etlExecutionDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "etl_execution_duration_seconds",
+			Help:    "ETL execution duration in seconds",
+			Buckets: []float64{1, 5, 15, 30, 60, 300, 900, 1800, 3600},
+		},
+		[]string{"source_type", "status"},
+	)
+
+	// ETL处理行数，kind为input/output/error/skipped
+	etlRowsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "etl_rows_total",
+			Help: "Total number of rows processed by ETL executions",
+		},
+		[]string{"kind"},
+	)
+
+	// 质量检查次数，status为pass/fail/warning/error，pass与总数相除得到通过率
+	qualityChecksTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "quality_checks_total",
+			Help: "Total number of data quality checks",
+		},
+		[]string{"type", "status"},
+	)
+
+	// 数据源健康巡检次数，result为success/failed
+	dataSourceHealthChecksTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "datasource_health_checks_total",
+			Help: "Total number of data source connectivity health checks",
+		},
+		[]string{"type", "result"},
+	)
+
+	// 质量检查得分分布
+	qualityCheckScore = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "quality_check_score",
+			Help:    "Score of completed data quality checks (0-100)",
+			Buckets: []float64{50, 60, 70, 80, 90, 95, 99, 100},
+		},
+		[]string{"type"},
+	)
+)
+
+// observeETLExecution 记录一次ETL执行的结果、耗时和处理行数
+func observeETLExecution(sourceType string, result *ETLExecutionResult, seconds float64) {
+	etlExecutionsTotal.WithLabelValues(sourceType, result.Status).Inc()
+	etlExecutionDuration.WithLabelValues(sourceType, result.Status).Observe(seconds)
+	etlRowsTotal.WithLabelValues("input").Add(float64(result.InputRows))
+	etlRowsTotal.WithLabelValues("output").Add(float64(result.OutputRows))
+	etlRowsTotal.WithLabelValues("error").Add(float64(result.ErrorRows))
+	etlRowsTotal.WithLabelValues("skipped").Add(float64(result.SkippedRows))
+}
+
+// etlSourceType 作业源数据源类型，用作指标标签
+func etlSourceType(job *models.ETLJob) string {
+	if job.Source == nil {
+		return "unknown"
+	}
+	return job.Source.Type
+}
@@ -0,0 +1,25 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/env-data-platform/internal/models"
+)
+
+func TestObserveETLExecution(t *testing.T) {
+	job := &models.ETLJob{Source: &models.DataSource{Type: "metrics_test"}}
+	before := testutil.ToFloat64(etlRowsTotal.WithLabelValues("skipped"))
+
+	observeETLExecution(etlSourceType(job), &ETLExecutionResult{
+		Status: "success", InputRows: 10, OutputRows: 7, ErrorRows: 1, SkippedRows: 2,
+	}, 1.5)
+	observeETLExecution(etlSourceType(job), &ETLExecutionResult{Status: "failed"}, 0.1)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(etlExecutionsTotal.WithLabelValues("metrics_test", "success")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(etlExecutionsTotal.WithLabelValues("metrics_test", "failed")))
+	assert.Equal(t, before+2, testutil.ToFloat64(etlRowsTotal.WithLabelValues("skipped")))
+	assert.Equal(t, "unknown", etlSourceType(&models.ETLJob{}))
+}
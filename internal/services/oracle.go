@@ -0,0 +1,87 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+)
+
+// oracleDriver go-ora注册的驱动名
+const oracleDriver = "oracle"
+
+// defaultOraclePort Oracle监听默认端口
+const defaultOraclePort = 1521
+
+// buildOracleDSN 根据数据源配置构建Oracle连接字符串。
+// 优先按service_name连接（未配置时使用database），配置了sid时按SID连接；port未配置时使用1521
+func buildOracleDSN(config map[string]interface{}) (string, error) {
+	host, err := configString(config, "host", true)
+	if err != nil {
+		return "", err
+	}
+	port, err := configPort(config, "port", false)
+	if err != nil {
+		return "", err
+	}
+	if port == 0 {
+		port = defaultOraclePort
+	}
+	username, err := configString(config, "username", true)
+	if err != nil {
+		return "", err
+	}
+	password, err := configString(config, "password", false)
+	if err != nil {
+		return "", err
+	}
+	sid, err := configString(config, "sid", false)
+	if err != nil {
+		return "", err
+	}
+	service, err := configString(config, "service_name", false)
+	if err != nil {
+		return "", err
+	}
+	if service == "" {
+		if service, err = configString(config, "database", false); err != nil {
+			return "", err
+		}
+	}
+	if sid == "" && service == "" {
+		return "", fmt.Errorf("数据源配置缺少service_name或sid")
+	}
+
+	u := &url.URL{
+		Scheme: "oracle",
+		User:   url.UserPassword(username, password),
+		Host:   fmt.Sprintf("%s:%d", host, port),
+	}
+	if sid != "" {
+		u.RawQuery = url.Values{"SID": []string{sid}}.Encode()
+	} else {
+		u.Path = "/" + service
+	}
+	return u.String(), nil
+}
+
+// openOracle 按数据源配置打开Oracle连接。驱动在oracle构建标签下注册，未启用时给出明确提示
+func openOracle(config map[string]interface{}) (*sql.DB, error) {
+	if !driverRegistered(oracleDriver) {
+		return nil, fmt.Errorf("未启用Oracle驱动，请使用 -tags oracle 重新构建")
+	}
+	dsn, err := buildOracleDSN(config)
+	if err != nil {
+		return nil, err
+	}
+	return sql.Open(oracleDriver, dsn)
+}
+
+// driverRegistered 判断database/sql驱动是否已注册
+func driverRegistered(name string) bool {
+	for _, driver := range sql.Drivers() {
+		if driver == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,6 @@
+//go:build oracle
+
+package services
+
+// go-ora为纯Go实现的Oracle驱动，体积较大，仅在需要接入Oracle数据源时通过 -tags oracle 编入
+import _ "github.com/sijms/go-ora"
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildOracleDSN(t *testing.T) {
+	config := map[string]interface{}{
+		"host":     "10.0.0.8",
+		"username": "env",
+		"password": "p@ss/word",
+		"database": "ORCLPDB1",
+	}
+
+	dsn, err := buildOracleDSN(config)
+	require.NoError(t, err)
+	u, err := url.Parse(dsn)
+	require.NoError(t, err)
+	assert.Equal(t, "oracle", u.Scheme)
+	assert.Equal(t, "10.0.0.8:1521", u.Host)
+	assert.Equal(t, "/ORCLPDB1", u.Path)
+	password, _ := u.User.Password()
+	assert.Equal(t, "p@ss/word", password)
+
+	// SID优先于服务名
+	config["port"] = "1522"
+	config["sid"] = "ORCL"
+	dsn, err = buildOracleDSN(config)
+	require.NoError(t, err)
+	u, err = url.Parse(dsn)
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.8:1522", u.Host)
+	assert.Empty(t, u.Path)
+	assert.Equal(t, "ORCL", u.Query().Get("SID"))
+
+	delete(config, "sid")
+	delete(config, "database")
+	_, err = buildOracleDSN(config)
+	assert.Error(t, err)
+}
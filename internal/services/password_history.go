@@ -0,0 +1,98 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/env-data-platform/internal/auth"
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/models"
+	"gorm.io/gorm"
+)
+
+// defaultPasswordHistoryCount 默认保留的密码历史条数
+const defaultPasswordHistoryCount = 5
+
+// ErrPasswordReused 新密码与近期使用过的密码相同
+var ErrPasswordReused = errors.New("新密码不能与最近使用过的密码相同")
+
+// PasswordHistoryService 密码历史服务
+type PasswordHistoryService struct {
+	db              *gorm.DB
+	passwordManager *auth.PasswordManager
+	historyCount    int
+}
+
+// NewPasswordHistoryService 创建密码历史服务，historyCount<=0时使用默认值
+func NewPasswordHistoryService(passwordManager *auth.PasswordManager, historyCount int) *PasswordHistoryService {
+	if historyCount <= 0 {
+		historyCount = defaultPasswordHistoryCount
+	}
+
+	return &PasswordHistoryService{
+		db:              database.GetDB(),
+		passwordManager: passwordManager,
+		historyCount:    historyCount,
+	}
+}
+
+// CheckReuse 检查新密码是否与当前密码或最近N次历史密码相同，命中时返回ErrPasswordReused
+func (s *PasswordHistoryService) CheckReuse(userID uint, currentHash, newPassword string) error {
+	hashes := make([]string, 0, s.historyCount+1)
+	if currentHash != "" {
+		hashes = append(hashes, currentHash)
+	}
+
+	var histories []models.PasswordHistory
+	if err := s.db.Where("user_id = ?", userID).
+		Order("id DESC").
+		Limit(s.historyCount).
+		Find(&histories).Error; err != nil {
+		return fmt.Errorf("查询密码历史失败: %w", err)
+	}
+	for _, history := range histories {
+		hashes = append(hashes, history.PasswordHash)
+	}
+
+	for _, hash := range hashes {
+		matched, err := s.passwordManager.VerifyPassword(newPassword, hash)
+		if err != nil {
+			// 历史中可能存在格式无法识别的散列，跳过即可
+			continue
+		}
+		if matched {
+			return ErrPasswordReused
+		}
+	}
+
+	return nil
+}
+
+// Record 在事务中记录新的密码散列，并清理超出保留条数的历史
+func (s *PasswordHistoryService) Record(tx *gorm.DB, userID uint, passwordHash string) error {
+	history := models.PasswordHistory{
+		UserID:       userID,
+		PasswordHash: passwordHash,
+	}
+	if err := tx.Create(&history).Error; err != nil {
+		return fmt.Errorf("记录密码历史失败: %w", err)
+	}
+
+	// 保留最近N条，删除更早的记录
+	var keepIDs []uint
+	if err := tx.Model(&models.PasswordHistory{}).
+		Where("user_id = ?", userID).
+		Order("id DESC").
+		Limit(s.historyCount).
+		Pluck("id", &keepIDs).Error; err != nil {
+		return fmt.Errorf("查询密码历史失败: %w", err)
+	}
+
+	if err := tx.Unscoped().
+		Where("user_id = ? AND id NOT IN ?", userID, keepIDs).
+		Delete(&models.PasswordHistory{}).Error; err != nil {
+		return fmt.Errorf("清理密码历史失败: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/auth"
+	"github.com/env-data-platform/internal/config"
+	"github.com/env-data-platform/internal/database/dbtest"
+	"github.com/env-data-platform/internal/models"
+)
+
+// newTestPasswordHistoryService 返回使用DryRun数据库的服务，查询历史时返回 histories，查询保留id时返回 keepIDs
+func newTestPasswordHistoryService(t *testing.T, historyCount int, histories []models.PasswordHistory, keepIDs []uint) (*PasswordHistoryService, *dbtest.DB) {
+	db := dbtest.New(t)
+	db.On(dbtest.KindQuery, func(tx *gorm.DB) {
+		switch dest := tx.Statement.Dest.(type) {
+		case *[]models.PasswordHistory:
+			*dest = histories
+		case *[]uint:
+			*dest = keepIDs
+		}
+	})
+	return &PasswordHistoryService{db: db.DB, passwordManager: testPasswordManager(), historyCount: historyCount}, db
+}
+
+// testPasswordManager 降低Argon2参数以加快测试
+func testPasswordManager() *auth.PasswordManager {
+	return auth.NewPasswordManagerWithConfig(config.Argon2Config{Memory: 64, Iterations: 1, Parallelism: 1})
+}
+
+func hashPassword(t *testing.T, password string) string {
+	hash, err := testPasswordManager().HashPassword(password)
+	require.NoError(t, err)
+	return hash
+}
+
+func TestPasswordHistoryCheckReuse(t *testing.T) {
+	current := hashPassword(t, "Passw0rd!3")
+	histories := []models.PasswordHistory{
+		{UserID: 1, PasswordHash: hashPassword(t, "Passw0rd!2")},
+		{UserID: 1, PasswordHash: hashPassword(t, "Passw0rd!1")},
+		{UserID: 1, PasswordHash: "not-a-hash"},
+	}
+	s, db := newTestPasswordHistoryService(t, 3, histories, nil)
+
+	// 当前密码及查询到的历史密码都不能再用，无法识别的散列被跳过
+	for _, password := range []string{"Passw0rd!3", "Passw0rd!2", "Passw0rd!1"} {
+		assert.ErrorIs(t, s.CheckReuse(1, current, password), ErrPasswordReused, password)
+	}
+	assert.NoError(t, s.CheckReuse(1, current, "Passw0rd!new"))
+
+	// 只查询该用户最近N条历史
+	statements := db.Statements()
+	require.NotEmpty(t, statements)
+	assert.Equal(t, "SELECT * FROM `env_password_histories` WHERE user_id = ? AND `env_password_histories`.`deleted_at` IS NULL ORDER BY id DESC LIMIT 3", statements[0].SQL)
+	assert.Equal(t, []interface{}{uint(1)}, statements[0].Vars)
+}
+
+func TestPasswordHistoryRecordTrimsOldEntries(t *testing.T) {
+	s, db := newTestPasswordHistoryService(t, 2, nil, []uint{9, 8})
+
+	require.NoError(t, s.Record(s.db, 1, "hash-9"))
+
+	statements := db.Statements()
+	require.Len(t, statements, 3)
+
+	insert := statements[0]
+	assert.Equal(t, dbtest.KindCreate, insert.Kind)
+	assert.Equal(t, "env_password_histories", insert.Table)
+	assert.Contains(t, insert.Vars, uint(1))
+	assert.Contains(t, insert.Vars, "hash-9")
+
+	// 按id倒序取最近2条作为保留记录
+	keep := statements[1]
+	assert.Equal(t, "SELECT `id` FROM `env_password_histories` WHERE user_id = ? AND `env_password_histories`.`deleted_at` IS NULL ORDER BY id DESC LIMIT 2", keep.SQL)
+	assert.Equal(t, []interface{}{uint(1)}, keep.Vars)
+
+	// 只删除该用户保留记录以外的历史
+	trim := statements[2]
+	assert.Equal(t, dbtest.KindDelete, trim.Kind)
+	assert.Equal(t, "DELETE FROM `env_password_histories` WHERE user_id = ? AND id NOT IN (?,?)", trim.SQL)
+	assert.Equal(t, []interface{}{uint(1), uint(9), uint(8)}, trim.Vars)
+}
+
+func TestPasswordHistoryRecordQueryError(t *testing.T) {
+	s, db := newTestPasswordHistoryService(t, 2, nil, nil)
+	db.On(dbtest.KindQuery, func(tx *gorm.DB) { tx.AddError(fmt.Errorf("boom")) })
+
+	// 查询保留记录失败时不执行删除
+	assert.Error(t, s.Record(s.db, 1, "hash"))
+	assert.Empty(t, db.Statements(dbtest.KindDelete))
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/alarm"
+	"github.com/env-data-platform/internal/config"
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/models"
+)
+
+// resetTokenBytes 重置令牌的随机字节数
+const resetTokenBytes = 32
+
+// resetMailTimeout 发送重置邮件的超时时间
+const resetMailTimeout = 30 * time.Second
+
+// ErrResetTokenInvalid 重置令牌不存在、已使用或已过期
+var ErrResetTokenInvalid = errors.New("重置链接无效或已过期")
+
+// PasswordResetService 自助密码重置服务
+type PasswordResetService struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	cfg    config.PasswordResetConfig
+	email  config.EmailNotifyConfig
+}
+
+// NewPasswordResetService 创建密码重置服务
+func NewPasswordResetService(cfg *config.Config, logger *zap.Logger) *PasswordResetService {
+	return &PasswordResetService{
+		db:     database.GetDB(),
+		logger: logger,
+		cfg:    cfg.Security.Password.Reset,
+		email:  cfg.Alarm.Notify.Email,
+	}
+}
+
+// Enabled 是否启用自助密码重置
+func (s *PasswordResetService) Enabled() bool {
+	return s.cfg.Enabled
+}
+
+// Request 为邮箱对应的本地用户生成重置令牌并异步发送重置邮件。
+// 邮箱不存在、用户被禁用或申请过于频繁时静默返回nil，避免通过接口探测账号
+func (s *PasswordResetService) Request(email, requestIP string) error {
+	var user models.User
+	err := s.db.Where("email = ? AND status = ? AND auth_source = ?", email, 1, "local").First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		s.logger.Info("Password reset requested for unknown email", zap.String("ip", requestIP))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("查询用户失败: %w", err)
+	}
+
+	now := time.Now()
+	if s.cfg.RequestInterval > 0 {
+		var recent int64
+		if err := s.db.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND created_at > ?", user.ID, now.Add(-s.cfg.RequestInterval)).
+			Count(&recent).Error; err != nil {
+			return fmt.Errorf("查询重置记录失败: %w", err)
+		}
+		if recent > 0 {
+			s.logger.Info("Password reset request throttled", zap.Uint("user_id", user.ID), zap.String("ip", requestIP))
+			return nil
+		}
+	}
+
+	token, tokenHash, err := generateResetToken()
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 新令牌签发后，之前未使用的令牌作废
+		if err := tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.PasswordResetToken{
+			UserID:    user.ID,
+			TokenHash: tokenHash,
+			ExpiresAt: now.Add(s.cfg.TokenTTL),
+			RequestIP: requestIP,
+		}).Error
+	}); err != nil {
+		return fmt.Errorf("保存重置令牌失败: %w", err)
+	}
+
+	link, err := buildResetLink(s.cfg.ResetURL, token)
+	if err != nil {
+		return err
+	}
+	go s.sendResetMail(user, link)
+
+	s.logger.Info("Password reset token issued", zap.Uint("user_id", user.ID), zap.String("ip", requestIP))
+	return nil
+}
+
+// sendResetMail 发送重置邮件，失败仅记录日志
+func (s *PasswordResetService) sendResetMail(user models.User, link string) {
+	ctx, cancel := context.WithTimeout(context.Background(), resetMailTimeout)
+	defer cancel()
+
+	name := user.RealName
+	if name == "" {
+		name = user.Username
+	}
+	body := fmt.Sprintf("%s，您好：\n\n我们收到了重置您账号(%s)密码的申请。请在%s内打开以下链接设置新密码：\n\n%s\n\n"+
+		"链接仅可使用一次。如果这不是您本人的操作，请忽略本邮件，您的密码不会被修改。",
+		name, user.Username, formatResetTTL(s.cfg.TokenTTL), link)
+
+	if err := alarm.SendEmail(ctx, s.email, []string{user.Email}, "环境数据平台密码重置", body); err != nil {
+		s.logger.Error("Failed to send password reset mail", zap.Uint("user_id", user.ID), zap.Error(err))
+	}
+}
+
+// Lookup 查询有效的重置令牌
+func (s *PasswordResetService) Lookup(token string) (*models.PasswordResetToken, error) {
+	var record models.PasswordResetToken
+	err := s.db.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hashResetToken(token), time.Now()).
+		First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrResetTokenInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询重置令牌失败: %w", err)
+	}
+	return &record, nil
+}
+
+// Consume 在事务中将令牌标记为已使用，令牌已被并发使用时返回ErrResetTokenInvalid
+func (s *PasswordResetService) Consume(tx *gorm.DB, record *models.PasswordResetToken) error {
+	result := tx.Model(&models.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", record.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("更新重置令牌失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrResetTokenInvalid
+	}
+	return nil
+}
+
+// IsSessionRevoked 判断issuedAt签发的令牌是否因用户重置密码或用户被删除而失效
+func IsSessionRevoked(userID uint, issuedAt time.Time) (bool, error) {
+	db := database.GetDB()
+	if db == nil {
+		return false, nil
+	}
+
+	var user models.User
+	err := db.Select("id", "password_changed_at").Where("id = ?", userID).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return sessionRevokedBy(user.PasswordChangedAt, issuedAt), nil
+}
+
+// sessionRevokedBy JWT签发时间精确到秒，早于密码重置时间所在秒的令牌视为失效
+func sessionRevokedBy(passwordChangedAt *time.Time, issuedAt time.Time) bool {
+	if passwordChangedAt == nil {
+		return false
+	}
+	return issuedAt.Before(passwordChangedAt.Truncate(time.Second))
+}
+
+// generateResetToken 生成随机重置令牌，返回令牌明文及其散列
+func generateResetToken() (string, string, error) {
+	buf := make([]byte, resetTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("生成重置令牌失败: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+	return token, hashResetToken(token), nil
+}
+
+// hashResetToken 计算令牌的SHA-256散列，数据库中仅保存散列
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// buildResetLink 将令牌作为token参数附加到重置页面地址
+func buildResetLink(resetURL, token string) (string, error) {
+	u, err := url.Parse(resetURL)
+	if err != nil {
+		return "", fmt.Errorf("重置页面地址无效: %w", err)
+	}
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// formatResetTTL 将有效期格式化为邮件中的可读文本
+func formatResetTTL(ttl time.Duration) string {
+	if ttl >= time.Hour && ttl%time.Hour == 0 {
+		return fmt.Sprintf("%d小时", int(ttl/time.Hour))
+	}
+	return fmt.Sprintf("%d分钟", int(ttl.Round(time.Minute)/time.Minute))
+}
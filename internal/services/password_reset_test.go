@@ -0,0 +1,44 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateResetToken(t *testing.T) {
+	token, hash, err := generateResetToken()
+	require.NoError(t, err)
+	assert.Len(t, token, resetTokenBytes*2)
+	assert.Len(t, hash, 64)
+	assert.Equal(t, hashResetToken(token), hash)
+	assert.NotEqual(t, token, hash)
+
+	other, _, err := generateResetToken()
+	require.NoError(t, err)
+	assert.NotEqual(t, token, other)
+}
+
+func TestBuildResetLink(t *testing.T) {
+	link, err := buildResetLink("https://env.example.com/reset-password?lang=zh", "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, "https://env.example.com/reset-password?lang=zh&token=abc123", link)
+}
+
+func TestSessionRevokedBy(t *testing.T) {
+	changedAt := time.Date(2024, 5, 1, 10, 0, 0, 500_000_000, time.UTC)
+
+	assert.False(t, sessionRevokedBy(nil, changedAt))
+	assert.True(t, sessionRevokedBy(&changedAt, changedAt.Add(-time.Second)))
+	// 与重置同一秒内签发的令牌（即重置后立即登录）仍然有效
+	assert.False(t, sessionRevokedBy(&changedAt, changedAt.Truncate(time.Second)))
+	assert.False(t, sessionRevokedBy(&changedAt, changedAt.Add(time.Minute)))
+}
+
+func TestFormatResetTTL(t *testing.T) {
+	assert.Equal(t, "30分钟", formatResetTTL(30*time.Minute))
+	assert.Equal(t, "2小时", formatResetTTL(2*time.Hour))
+	assert.Equal(t, "90分钟", formatResetTTL(90*time.Minute))
+}
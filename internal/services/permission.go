@@ -0,0 +1,77 @@
+package services
+
+import (
+	"github.com/env-data-platform/internal/auth"
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/models"
+	"gorm.io/gorm"
+)
+
+// PermissionService 用户权限查询服务
+type PermissionService struct {
+	db        *gorm.DB
+	hierarchy *RoleHierarchy
+}
+
+// NewPermissionService 创建权限查询服务
+func NewPermissionService() *PermissionService {
+	db := database.GetDB()
+	return &PermissionService{
+		db:        db,
+		hierarchy: NewRoleHierarchy(db),
+	}
+}
+
+// getUserRoleIDs 查询用户已启用角色及其继承的祖先角色ID
+func (s *PermissionService) getUserRoleIDs(userID uint) ([]uint, error) {
+	var roleIDs []uint
+	if err := s.db.Table("env_user_roles").
+		Joins("JOIN env_roles ON env_roles.id = env_user_roles.role_id").
+		Where("env_user_roles.user_id = ? AND env_roles.status = 1 AND env_roles.deleted_at IS NULL", userID).
+		Pluck("env_user_roles.role_id", &roleIDs).Error; err != nil {
+		return nil, err
+	}
+	return s.hierarchy.ExpandRoleIDs(roleIDs)
+}
+
+// GetUserPermissions 查询用户通过已启用角色（含继承的祖先角色）获得的已启用权限，permType为空时不按类型过滤
+func (s *PermissionService) GetUserPermissions(userID uint, permType string) ([]models.Permission, error) {
+	roleIDs, err := s.getUserRoleIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.hierarchy.permissionsOf(roleIDs, permType)
+}
+
+// GetUserPermissionCodes 查询用户的权限code列表，可直接用于 auth.HasPermission 匹配
+// 拥有或继承超级管理员角色的用户返回通配符"*"
+func (s *PermissionService) GetUserPermissionCodes(userID uint) ([]string, error) {
+	roleIDs, err := s.getUserRoleIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(roleIDs) == 0 {
+		return []string{}, nil
+	}
+
+	var adminCount int64
+	if err := s.db.Model(&models.Role{}).
+		Where("id IN ? AND code = ?", roleIDs, models.RoleAdmin).
+		Count(&adminCount).Error; err != nil {
+		return nil, err
+	}
+	if adminCount > 0 {
+		return []string{auth.PermissionWildcard}, nil
+	}
+
+	permissions, err := s.hierarchy.permissionsOf(roleIDs, "")
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		codes = append(codes, permission.Code)
+	}
+	return codes, nil
+}
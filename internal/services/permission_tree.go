@@ -0,0 +1,74 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/env-data-platform/internal/models"
+)
+
+// BuildPermissionTree 在内存中把权限列表组装成树，顶级权限为根，同级保持列表中的顺序。
+// 父权限不在列表中的权限不出现在树中
+func BuildPermissionTree(permissions []models.Permission) []models.Permission {
+	children := make(map[uint][]int, len(permissions))
+	roots := make([]int, 0, len(permissions))
+	for i, permission := range permissions {
+		if permission.ParentID == nil {
+			roots = append(roots, i)
+			continue
+		}
+		children[*permission.ParentID] = append(children[*permission.ParentID], i)
+	}
+
+	// 每个节点只有一个父节点，从根出发不会遇到环；visited兜底异常数据
+	visited := make([]bool, len(permissions))
+	var build func(i int) models.Permission
+	build = func(i int) models.Permission {
+		visited[i] = true
+		node := permissions[i]
+		node.Children = nil
+		for _, child := range children[node.ID] {
+			if !visited[child] {
+				node.Children = append(node.Children, build(child))
+			}
+		}
+		return node
+	}
+
+	var tree []models.Permission
+	for _, i := range roots {
+		tree = append(tree, build(i))
+	}
+	return tree
+}
+
+// WouldCreateCycle 判断将parentID设为permissionID的父权限后是否形成环，
+// 一次性加载全部权限的父子关系后在内存中沿父链向上查找
+func (s *PermissionService) WouldCreateCycle(permissionID, parentID uint) (bool, error) {
+	var permissions []models.Permission
+	if err := s.db.Select("id", "parent_id").Find(&permissions).Error; err != nil {
+		return false, fmt.Errorf("查询权限层级失败: %w", err)
+	}
+
+	parents := make(map[uint]*uint, len(permissions))
+	for _, permission := range permissions {
+		parents[permission.ID] = permission.ParentID
+	}
+	return permissionCreatesCycle(parents, permissionID, parentID), nil
+}
+
+// permissionCreatesCycle 沿parentID的祖先链查找permissionID，已有数据中存在环时同样视为成环
+func permissionCreatesCycle(parents map[uint]*uint, permissionID, parentID uint) bool {
+	visited := make(map[uint]bool)
+	for current := parentID; ; {
+		if current == permissionID || visited[current] {
+			return true
+		}
+		visited[current] = true
+
+		parent, ok := parents[current]
+		if !ok || parent == nil {
+			return false
+		}
+		current = *parent
+	}
+}
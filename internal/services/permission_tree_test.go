@@ -0,0 +1,62 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/env-data-platform/internal/models"
+)
+
+func permission(id uint, parentID *uint) models.Permission {
+	p := models.Permission{ParentID: parentID}
+	p.ID = id
+	return p
+}
+
+func TestBuildPermissionTree(t *testing.T) {
+	// 1 -> (2 -> 4), 3；5的父权限不在列表中；6 <-> 7 为异常数据中的环
+	permissions := []models.Permission{
+		permission(1, nil),
+		permission(2, uintPtr(1)),
+		permission(3, uintPtr(1)),
+		permission(4, uintPtr(2)),
+		permission(5, uintPtr(99)),
+		permission(6, uintPtr(7)),
+		permission(7, uintPtr(6)),
+		permission(8, nil),
+	}
+
+	tree := BuildPermissionTree(permissions)
+	require.Len(t, tree, 2)
+	assert.Equal(t, uint(1), tree[0].ID)
+	assert.Equal(t, uint(8), tree[1].ID)
+	assert.Empty(t, tree[1].Children)
+
+	require.Len(t, tree[0].Children, 2)
+	assert.Equal(t, uint(2), tree[0].Children[0].ID)
+	assert.Equal(t, uint(3), tree[0].Children[1].ID)
+	require.Len(t, tree[0].Children[0].Children, 1)
+	assert.Equal(t, uint(4), tree[0].Children[0].Children[0].ID)
+
+	// 输入列表不被修改
+	assert.Empty(t, permissions[0].Children)
+	assert.Nil(t, BuildPermissionTree(nil))
+}
+
+func TestPermissionCreatesCycle(t *testing.T) {
+	parents := map[uint]*uint{
+		1: nil,
+		2: uintPtr(1),
+		3: uintPtr(2),
+		6: uintPtr(7),
+		7: uintPtr(6),
+	}
+
+	assert.True(t, permissionCreatesCycle(parents, 1, 3))
+	assert.True(t, permissionCreatesCycle(parents, 2, 2))
+	assert.False(t, permissionCreatesCycle(parents, 3, 1))
+	assert.False(t, permissionCreatesCycle(parents, 1, 99))
+	assert.True(t, permissionCreatesCycle(parents, 1, 6))
+}
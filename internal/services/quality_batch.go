@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/models"
+)
+
+// QualityBatchRunner 质量检查批次执行器，后台逐条执行规则并把进度写入批次记录
+type QualityBatchRunner struct {
+	db      *gorm.DB
+	logger  *zap.Logger
+	checker *QualityChecker
+}
+
+// NewQualityBatchRunner 创建质量检查批次执行器，将已停止实例遗留的未完成批次标记为中断，之后定期巡检
+func NewQualityBatchRunner(logger *zap.Logger, checker *QualityChecker) *QualityBatchRunner {
+	runner := &QualityBatchRunner{
+		db:      database.GetDB(),
+		logger:  logger,
+		checker: checker,
+	}
+	if runner.db != nil {
+		runner.markInterrupted()
+		go runner.watchInterrupted()
+	}
+	return runner
+}
+
+// watchInterrupted 定期标记中断的批次，实例在本进程运行期间停止时其批次也能及时结束
+func (r *QualityBatchRunner) watchInterrupted() {
+	ticker := time.NewTicker(etlRunStaleTimeout())
+	defer ticker.Stop()
+	for range ticker.C {
+		r.markInterrupted()
+	}
+}
+
+// markInterrupted 批次在执行实例的后台goroutine中运行，实例停止后不会继续。
+// 与ETL执行锁相同，执行期间定期刷新心跳，只有心跳超过stale_run_timeout未刷新的批次才标记为中断，
+// 其他实例正在执行的批次不受影响
+func (r *QualityBatchRunner) markInterrupted() {
+	now := time.Now()
+	result := r.db.Model(&models.QualityCheckBatch{}).
+		Where("status = ? AND (run_heartbeat IS NULL OR run_heartbeat < ?)",
+			models.QualityBatchRunning, now.Add(-etlRunStaleTimeout())).
+		Updates(map[string]interface{}{
+			"status":      models.QualityBatchInterrupted,
+			"finished_at": now,
+		})
+	if result.Error != nil {
+		r.logger.Error("Failed to mark interrupted quality check batches", zap.Error(result.Error))
+		return
+	}
+	if result.RowsAffected > 0 {
+		r.logger.Warn("Marked unfinished quality check batches as interrupted",
+			zap.Int64("count", result.RowsAffected))
+	}
+}
+
+// Start 创建批次记录并在后台执行规则，dataSourceID为0表示按规则ID指定
+func (r *QualityBatchRunner) Start(rules []models.QualityRule, dataSourceID uint) (*models.QualityCheckBatch, error) {
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("批次中没有可执行的规则")
+	}
+
+	now := time.Now()
+	batch := &models.QualityCheckBatch{
+		BatchID:      uuid.New().String(),
+		DataSourceID: dataSourceID,
+		Status:       models.QualityBatchRunning,
+		TotalCount:   len(rules),
+		StartedAt:    now,
+		RunOwner:     etlInstanceID,
+		RunHeartbeat: &now,
+	}
+	if err := r.db.Create(batch).Error; err != nil {
+		return nil, fmt.Errorf("创建质量检查批次失败: %v", err)
+	}
+
+	go r.run(batch, rules)
+	return batch, nil
+}
+
+// QualityBatchFilter 质量检查批次列表筛选条件
+type QualityBatchFilter struct {
+	DataSourceID uint   `form:"data_source_id"`
+	Status       string `form:"status"`
+}
+
+// Get 按批次ID查询批次记录
+func (r *QualityBatchRunner) Get(batchID string) (*models.QualityCheckBatch, error) {
+	var batch models.QualityCheckBatch
+	if err := r.db.Where("batch_id = ?", batchID).First(&batch).Error; err != nil {
+		return nil, err
+	}
+	batch.CalculateProgress()
+	return &batch, nil
+}
+
+// List 按开始时间倒序分页查询批次记录
+func (r *QualityBatchRunner) List(filter QualityBatchFilter, offset, limit int) ([]models.QualityCheckBatch, int64, error) {
+	query := r.db.Model(&models.QualityCheckBatch{})
+	if filter.DataSourceID > 0 {
+		query = query.Where("data_source_id = ?", filter.DataSourceID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var batches []models.QualityCheckBatch
+	if err := query.Order("started_at DESC").Offset(offset).Limit(limit).Find(&batches).Error; err != nil {
+		return nil, 0, err
+	}
+	for i := range batches {
+		batches[i].CalculateProgress()
+	}
+	return batches, total, nil
+}
+
+// run 逐条执行规则，每完成一条更新一次进度；执行出错或未达阈值计为失败，平均分只统计执行成功的检查
+func (r *QualityBatchRunner) run(batch *models.QualityCheckBatch, rules []models.QualityRule) {
+	ctx := context.Background()
+	stopHeartbeat := r.startHeartbeat(batch.ID)
+	defer stopHeartbeat()
+
+	var scoreSum float64
+	var scored, failed int
+	for i := range rules {
+		rule := &rules[i]
+		report, err := r.checker.ExecuteQualityCheck(ctx, rule)
+		if err != nil || report.Status != "pass" {
+			failed++
+		}
+		if err != nil {
+			r.logger.Error("Failed to execute quality check in batch",
+				zap.String("batch_id", batch.BatchID),
+				zap.Uint("rule_id", rule.ID),
+				zap.String("rule_name", rule.Name),
+				zap.Error(err))
+		} else {
+			scoreSum += report.Score
+			scored++
+			r.logger.Info("Quality check completed in batch",
+				zap.String("batch_id", batch.BatchID),
+				zap.Uint("rule_id", rule.ID),
+				zap.String("rule_name", rule.Name),
+				zap.String("status", report.Status),
+				zap.Float64("score", report.Score))
+		}
+
+		updates := map[string]interface{}{
+			"completed_count": i + 1,
+			"failed_count":    failed,
+		}
+		if scored > 0 {
+			updates["avg_score"] = scoreSum / float64(scored)
+		}
+		if i == len(rules)-1 {
+			updates["status"] = models.QualityBatchCompleted
+			updates["finished_at"] = time.Now()
+		}
+		// 心跳中断后批次可能已被标记为中断，只更新本实例仍在执行的批次
+		if err := r.db.Model(batch).
+			Where("status = ? AND run_owner = ?", models.QualityBatchRunning, etlInstanceID).
+			Updates(updates).Error; err != nil {
+			r.logger.Error("Failed to update quality check batch progress",
+				zap.String("batch_id", batch.BatchID),
+				zap.Error(err))
+		}
+	}
+}
+
+// startHeartbeat 在批次执行期间定期刷新心跳，返回的函数停止续期
+func (r *QualityBatchRunner) startHeartbeat(id uint) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(etlRunStaleTimeout() / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				r.db.Model(&models.QualityCheckBatch{}).
+					Where("id = ? AND status = ? AND run_owner = ?", id, models.QualityBatchRunning, etlInstanceID).
+					UpdateColumn("run_heartbeat", now)
+			}
+		}
+	}()
+	return func() { close(done) }
+}
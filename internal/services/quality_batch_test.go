@@ -0,0 +1,149 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/config"
+	"github.com/env-data-platform/internal/database/dbtest"
+	"github.com/env-data-platform/internal/models"
+)
+
+func TestQualityBatchMarkInterruptedOnlyStale(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *config.Config
+		expected time.Duration
+	}{
+		{name: "默认超时", expected: defaultETLRunStaleTimeout},
+		{name: "配置超时", cfg: staleRunConfig(2 * time.Minute), expected: 2 * time.Minute},
+		{name: "配置为零时使用默认值", cfg: staleRunConfig(0), expected: defaultETLRunStaleTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := config.GlobalConfig
+			config.GlobalConfig = tt.cfg
+			t.Cleanup(func() { config.GlobalConfig = previous })
+
+			db := dbtest.New(t)
+			r := &QualityBatchRunner{db: db.DB, logger: zap.NewNop()}
+
+			before := time.Now()
+			r.markInterrupted()
+
+			// 只标记心跳过期的运行中批次，其他实例正在执行的批次不受影响
+			statements := db.Statements()
+			require.Len(t, statements, 1)
+			stmt := statements[0]
+			assert.Contains(t, stmt.SQL, "WHERE (status = ? AND (run_heartbeat IS NULL OR run_heartbeat < ?))")
+			assert.Contains(t, stmt.Vars, models.QualityBatchInterrupted)
+			staleBefore := stmt.Vars[len(stmt.Vars)-1].(time.Time)
+			assert.WithinDuration(t, before.Add(-tt.expected), staleBefore, time.Second)
+		})
+	}
+}
+
+func staleRunConfig(timeout time.Duration) *config.Config {
+	cfg := &config.Config{}
+	cfg.ETL.Scheduler.StaleRunTimeout = timeout
+	return cfg
+}
+
+func TestQualityBatchRunnerList(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter QualityBatchFilter
+		where  string
+		vars   []interface{}
+	}{
+		{name: "全部", filter: QualityBatchFilter{}, where: "WHERE `env_quality_check_batches`.`deleted_at` IS NULL"},
+		{name: "按数据源", filter: QualityBatchFilter{DataSourceID: 5}, where: "WHERE data_source_id = ? AND", vars: []interface{}{uint(5)}},
+		{name: "按状态", filter: QualityBatchFilter{Status: models.QualityBatchRunning}, where: "WHERE status = ? AND", vars: []interface{}{models.QualityBatchRunning}},
+		{
+			name:   "数据源和状态",
+			filter: QualityBatchFilter{DataSourceID: 5, Status: models.QualityBatchCompleted},
+			where:  "WHERE data_source_id = ? AND status = ? AND",
+			vars:   []interface{}{uint(5), models.QualityBatchCompleted},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.New(t)
+			db.On(dbtest.KindQuery, func(tx *gorm.DB) {
+				switch dest := tx.Statement.Dest.(type) {
+				case *int64:
+					*dest = 2
+					tx.RowsAffected = 1
+				case *[]models.QualityCheckBatch:
+					*dest = []models.QualityCheckBatch{
+						{TotalCount: 3, CompletedCount: 1},
+						{TotalCount: 4, CompletedCount: 4},
+					}
+				}
+			})
+			r := &QualityBatchRunner{db: db.DB, logger: zap.NewNop()}
+
+			batches, total, err := r.List(tt.filter, 10, 5)
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), total)
+			require.Len(t, batches, 2)
+			// 列表返回时计算完成百分比
+			assert.Equal(t, 33.33, batches[0].Progress)
+			assert.Equal(t, float64(100), batches[1].Progress)
+
+			// 计数与分页查询共用同一查询链；DryRun下链上的SQL不会重置，只断言计数语句的筛选条件
+			statements := db.Statements(dbtest.KindQuery)
+			require.Len(t, statements, 2)
+			assert.Contains(t, statements[0].SQL, "SELECT count(*) FROM `env_quality_check_batches` "+tt.where)
+			require.Len(t, statements[0].Vars, len(tt.vars))
+			for i, v := range tt.vars {
+				assert.Equal(t, v, statements[0].Vars[i])
+			}
+		})
+	}
+}
+
+func TestQualityBatchRunnerStartFailures(t *testing.T) {
+	tests := []struct {
+		name      string
+		rules     []models.QualityRule
+		createErr error
+		expected  string
+		created   bool
+	}{
+		{name: "没有规则", expected: "批次中没有可执行的规则"},
+		{name: "创建批次失败", rules: []models.QualityRule{{Name: "完整性"}}, createErr: errors.New("connection refused"), expected: "创建质量检查批次失败", created: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.New(t)
+			db.On(dbtest.KindCreate, func(tx *gorm.DB) {
+				tx.AddError(tt.createErr)
+			})
+			r := &QualityBatchRunner{db: db.DB, logger: zap.NewNop()}
+
+			batch, err := r.Start(tt.rules, 5)
+			assert.Nil(t, batch)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expected)
+
+			// 批次记录归属本实例并带有心跳，按数据源执行时记录数据源ID
+			creates := db.Statements(dbtest.KindCreate)
+			if !tt.created {
+				assert.Empty(t, creates)
+				return
+			}
+			require.Len(t, creates, 1)
+			assert.Equal(t, "env_quality_check_batches", creates[0].Table)
+			assert.Contains(t, creates[0].Vars, uint(5))
+			assert.Contains(t, creates[0].Vars, etlInstanceID)
+			assert.Contains(t, creates[0].Vars, models.QualityBatchRunning)
+		})
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/env-data-platform/internal/database"
@@ -49,11 +51,14 @@ func (qc *QualityChecker) ExecuteQualityCheck(ctx context.Context, rule *models.
 	// 执行具体的质量检查
 	result, err := qc.executeCheck(ctx, rule)
 	if err != nil {
+		qualityChecksTotal.WithLabelValues(rule.Type, "error").Inc()
 		qc.logger.Error("Quality check failed",
 			zap.Uint("rule_id", rule.ID),
 			zap.Error(err))
 		return nil, err
 	}
+	qualityChecksTotal.WithLabelValues(rule.Type, result.Status).Inc()
+	qualityCheckScore.WithLabelValues(rule.Type).Observe(result.Score)
 
 	// 创建质量报告
 	report := &models.QualityReport{
@@ -67,6 +72,9 @@ func (qc *QualityChecker) ExecuteQualityCheck(ctx context.Context, rule *models.
 		Details:     marshalDetails(result.Details),
 		Suggestions: result.Suggestions,
 	}
+	if samples, ok := result.Details["failure_samples"].([]map[string]interface{}); ok {
+		report.FailureSamples = samples
+	}
 
 	// 保存报告到数据库
 	if err := qc.db.Create(report).Error; err != nil {
@@ -89,6 +97,9 @@ func (qc *QualityChecker) executeCheck(ctx context.Context, rule *models.Quality
 		CheckedAt: time.Now(),
 		Details:   make(map[string]interface{}),
 	}
+	if err := checkImportRule(rule); err != nil {
+		return nil, err
+	}
 
 	switch rule.Type {
 	case "completeness":
@@ -103,6 +114,10 @@ func (qc *QualityChecker) executeCheck(ctx context.Context, rule *models.Quality
 		return qc.checkAccuracy(ctx, rule, result)
 	case "freshness":
 		return qc.checkFreshness(ctx, rule, result)
+	case "outlier":
+		return qc.checkOutlier(ctx, rule, result)
+	case "referential":
+		return qc.checkReferential(ctx, rule, result)
 	default:
 		return nil, fmt.Errorf("不支持的质量检查类型: %s", rule.Type)
 	}
@@ -136,9 +151,10 @@ func (qc *QualityChecker) checkCompleteness(ctx context.Context, rule *models.Qu
 		return nil, fmt.Errorf("查询总记录数失败: %v", err)
 	}
 
+	dialect := newQualityDialect(rule.DataSource.Type)
+
 	// 查询非空记录数
-	nonNullQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s IS NOT NULL AND %s != ''",
-		tableName, columnName, columnName)
+	nonNullQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", tableName, dialect.notEmpty(columnName))
 	if err := db.QueryRowContext(ctx, nonNullQuery).Scan(&result.PassCount); err != nil {
 		return nil, fmt.Errorf("查询非空记录数失败: %v", err)
 	}
@@ -163,6 +179,7 @@ func (qc *QualityChecker) checkCompleteness(ctx context.Context, rule *models.Qu
 	result.Details["null_count"] = result.FailCount
 	result.Details["non_null_count"] = result.PassCount
 	result.Details["completeness_rate"] = result.Score
+	qc.collectFailureSamples(ctx, db, rule, result, columnName, dialect.isEmpty(columnName))
 
 	// 生成建议
 	if result.Status == "fail" {
@@ -226,6 +243,9 @@ func (qc *QualityChecker) checkUniqueness(ctx context.Context, rule *models.Qual
 	result.Details["unique_values"] = result.PassCount
 	result.Details["duplicate_values"] = result.FailCount
 	result.Details["uniqueness_rate"] = result.Score
+	qc.collectFailureSamples(ctx, db, rule, result, columnName,
+		fmt.Sprintf("%s IN (SELECT %s FROM %s WHERE %s IS NOT NULL GROUP BY %s HAVING COUNT(*) > 1)",
+			columnName, columnName, tableName, columnName, columnName))
 
 	// 生成建议
 	if result.Status == "fail" {
@@ -267,36 +287,38 @@ func (qc *QualityChecker) checkValidity(ctx context.Context, rule *models.Qualit
 	}
 	defer db.Close()
 
+	dialect := newQualityDialect(rule.DataSource.Type)
+
 	// 查询总记录数（非空）
-	totalQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s IS NOT NULL AND %s != ''",
-		tableName, columnName, columnName)
+	totalQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", tableName, dialect.notEmpty(columnName))
 	if err := db.QueryRowContext(ctx, totalQuery).Scan(&result.TotalCount); err != nil {
 		return nil, fmt.Errorf("查询总记录数失败: %v", err)
 	}
 
 	// 根据pattern类型进行验证
-	var validQuery string
+	var regex string
 	switch pattern {
 	case "email":
-		validQuery = fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s REGEXP '^[A-Za-z0-9._%%-]+@[A-Za-z0-9.-]+\\.[A-Za-z]{2,}$'",
-			tableName, columnName)
+		regex = "^[A-Za-z0-9._%-]+@[A-Za-z0-9.-]+\\.[A-Za-z]{2,}$"
 	case "phone":
-		validQuery = fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s REGEXP '^[0-9]{10,11}$'",
-			tableName, columnName)
+		regex = "^[0-9]{10,11}$"
 	case "numeric":
-		validQuery = fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s REGEXP '^[0-9]+(\\.[0-9]+)?$'",
-			tableName, columnName)
+		regex = "^[0-9]+(\\.[0-9]+)?$"
 	case "date":
-		validQuery = fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s REGEXP '^[0-9]{4}-[0-9]{2}-[0-9]{2}$'",
-			tableName, columnName)
+		regex = "^[0-9]{4}-[0-9]{2}-[0-9]{2}$"
 	default:
 		// 自定义正则表达式
-		validQuery = fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s REGEXP '%s'",
-			tableName, columnName, pattern)
-	}
-
-	if err := db.QueryRowContext(ctx, validQuery).Scan(&result.PassCount); err != nil {
-		return nil, fmt.Errorf("查询有效记录数失败: %v", err)
+		regex = pattern
+	}
+	match, supported := dialect.regexMatch(columnName, regex)
+	if supported {
+		validQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s AND %s",
+			tableName, dialect.notEmpty(columnName), match)
+		if err := db.QueryRowContext(ctx, validQuery).Scan(&result.PassCount); err != nil {
+			return nil, fmt.Errorf("查询有效记录数失败: %v", err)
+		}
+	} else if err := qc.matchValidityInGo(ctx, db, rule, dialect, regex, result); err != nil {
+		return nil, err
 	}
 
 	result.FailCount = result.TotalCount - result.PassCount
@@ -320,6 +342,10 @@ func (qc *QualityChecker) checkValidity(ctx context.Context, rule *models.Qualit
 	result.Details["valid_count"] = result.PassCount
 	result.Details["invalid_count"] = result.FailCount
 	result.Details["validity_rate"] = result.Score
+	if supported {
+		qc.collectFailureSamples(ctx, db, rule, result, columnName,
+			fmt.Sprintf("%s AND NOT (%s)", dialect.notEmpty(columnName), match))
+	}
 
 	// 生成建议
 	if result.Status == "fail" {
@@ -333,6 +359,68 @@ func (qc *QualityChecker) checkValidity(ctx context.Context, rule *models.Qualit
 	return result, nil
 }
 
+// matchValidityInGo 数据库不支持正则时逐行读取非空值在Go中匹配，同时收集不匹配的样例。
+// 总数以实际读取的行数为准，避免两次查询之间数据变化导致有效数大于总数
+func (qc *QualityChecker) matchValidityInGo(ctx context.Context, db *sql.DB, rule *models.QualityRule, dialect qualityDialect, regex string, result *QualityCheckResult) error {
+	re, err := regexp.Compile(regex)
+	if err != nil {
+		return fmt.Errorf("正则表达式不合法: %v", err)
+	}
+
+	columns := []string{rule.ColumnName}
+	maxSamples := 0
+	if config, err := parseFailureSampleConfig(rule.RuleConfig); err != nil {
+		qc.logger.Warn("Invalid failure sample config", zap.Uint("rule_id", rule.ID), zap.Error(err))
+	} else {
+		columns = config.columns(rule.ColumnName)
+		maxSamples = config.MaxSamples
+	}
+	checked := 0
+	for i, column := range columns {
+		if column == rule.ColumnName {
+			checked = i
+		}
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s",
+		strings.Join(columns, ", "), rule.TargetTable, dialect.notEmpty(rule.ColumnName))
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("查询有效记录数失败: %v", err)
+	}
+	defer rows.Close()
+
+	var total, valid int64
+	samples := make([]map[string]interface{}, 0)
+	values := make([]interface{}, len(columns))
+	ptrs := make([]interface{}, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return fmt.Errorf("读取列数据失败: %v", err)
+		}
+		total++
+		if re.MatchString(fmt.Sprint(rowValue(values[checked]))) {
+			valid++
+		} else if len(samples) < maxSamples {
+			samples = append(samples, rowMap(columns, values))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("读取列数据失败: %v", err)
+	}
+
+	result.TotalCount = total
+	result.PassCount = valid
+	if len(samples) > 0 {
+		result.Details["sample_columns"] = columns
+		result.Details["failure_samples"] = samples
+	}
+	return nil
+}
+
 // checkConsistency 检查一致性（跨表或跨字段一致性）
 func (qc *QualityChecker) checkConsistency(ctx context.Context, rule *models.QualityRule, result *QualityCheckResult) (*QualityCheckResult, error) {
 	// 解析规则配置
@@ -481,8 +569,9 @@ func (qc *QualityChecker) checkFreshness(ctx context.Context, rule *models.Quali
 	}
 
 	// 查询时效性数据（在指定时间范围内的数据）
-	freshQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s IS NOT NULL AND %s >= DATE_SUB(NOW(), INTERVAL %d HOUR)",
-		tableName, timeColumn, timeColumn, int(maxAgeHours))
+	cutoff := newQualityDialect(rule.DataSource.Type).hoursAgo(int(maxAgeHours))
+	freshQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s IS NOT NULL AND %s >= %s",
+		tableName, timeColumn, timeColumn, cutoff)
 	if err := db.QueryRowContext(ctx, freshQuery).Scan(&result.PassCount); err != nil {
 		return nil, fmt.Errorf("查询时效数据失败: %v", err)
 	}
@@ -508,6 +597,8 @@ func (qc *QualityChecker) checkFreshness(ctx context.Context, rule *models.Quali
 	result.Details["fresh_count"] = result.PassCount
 	result.Details["stale_count"] = result.FailCount
 	result.Details["freshness_rate"] = result.Score
+	qc.collectFailureSamples(ctx, db, rule, result, timeColumn,
+		fmt.Sprintf("%s IS NOT NULL AND %s < %s", timeColumn, timeColumn, cutoff))
 
 	// 生成建议
 	if result.Status == "fail" {
@@ -521,54 +612,37 @@ func (qc *QualityChecker) checkFreshness(ctx context.Context, rule *models.Quali
 	return result, nil
 }
 
-// getDataSourceConnection 获取数据源连接
-func (qc *QualityChecker) getDataSourceConnection(dataSource *models.DataSource) (*sql.DB, error) {
-	if dataSource == nil {
-		return nil, fmt.Errorf("数据源不存在")
+// checkImportRule 文件导入的数据源连接的是平台数据库，规则只能检查导入表：
+// 表名必须一致，列名只能是标识符（列名会拼入SQL），且不支持关联其他表的引用完整性检查
+func checkImportRule(rule *models.QualityRule) error {
+	if rule.DataSource == nil || !rule.DataSource.IsImported() {
+		return nil
+	}
+	if rule.Type == "referential" {
+		return fmt.Errorf("%w，不支持引用完整性检查", ErrImportTableOnly)
+	}
+	if err := CheckImportTable(rule.DataSource, rule.TargetTable); err != nil {
+		return err
 	}
 
-	// 解析数据源配置
+	columns := []string{rule.ColumnName}
 	var config map[string]interface{}
-	if err := json.Unmarshal(dataSource.ConfigData, &config); err != nil {
-		return nil, fmt.Errorf("解析数据源配置失败: %v", err)
+	if json.Unmarshal([]byte(rule.RuleConfig), &config) == nil {
+		if column, ok := config["time_column"].(string); ok {
+			columns = append(columns, column)
+		}
 	}
-
-	switch dataSource.Type {
-	case "mysql":
-		return qc.connectMySQL(config)
-	case "postgresql":
-		return qc.connectPostgreSQL(config)
-	default:
-		return nil, fmt.Errorf("不支持的数据源类型: %s", dataSource.Type)
+	for _, column := range columns {
+		if column != "" && !sqlIdentifierRegex.MatchString(column) {
+			return fmt.Errorf("%w，列名 %s 不合法", ErrImportTableOnly, column)
+		}
 	}
+	return nil
 }
 
-// connectMySQL 连接MySQL数据库
-func (qc *QualityChecker) connectMySQL(config map[string]interface{}) (*sql.DB, error) {
-	host := config["host"].(string)
-	port := int(config["port"].(float64))
-	username := config["username"].(string)
-	password := config["password"].(string)
-	database := config["database"].(string)
-
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true&charset=utf8mb4",
-		username, password, host, port, database)
-
-	return sql.Open("mysql", dsn)
-}
-
-// connectPostgreSQL 连接PostgreSQL数据库
-func (qc *QualityChecker) connectPostgreSQL(config map[string]interface{}) (*sql.DB, error) {
-	host := config["host"].(string)
-	port := int(config["port"].(float64))
-	username := config["username"].(string)
-	password := config["password"].(string)
-	database := config["database"].(string)
-
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		host, port, username, password, database)
-
-	return sql.Open("postgres", dsn)
+// getDataSourceConnection 获取数据源连接
+func (qc *QualityChecker) getDataSourceConnection(dataSource *models.DataSource) (*sql.DB, error) {
+	return openDataSourceDB(dataSource)
 }
 
 // marshalDetails 序列化详细信息
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+// qualityDialect 质量检查SQL的方言差异
+type qualityDialect struct {
+	name string
+}
+
+func newQualityDialect(dbType string) qualityDialect {
+	return qualityDialect{name: dbType}
+}
+
+// literal 字符串字面量。MySQL默认把反斜杠当作转义符，正则中的反斜杠需要加倍
+func (d qualityDialect) literal(s string) string {
+	if d.name == "mysql" {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// notEmpty 值非NULL且非空字符串的条件。Oracle中空字符串即NULL，与”比较结果恒为未知
+func (d qualityDialect) notEmpty(column string) string {
+	if d.name == "oracle" {
+		return fmt.Sprintf("%s IS NOT NULL", column)
+	}
+	return fmt.Sprintf("%s IS NOT NULL AND %s != ''", column, column)
+}
+
+// isEmpty 值为NULL或空字符串的条件
+func (d qualityDialect) isEmpty(column string) string {
+	if d.name == "oracle" {
+		return fmt.Sprintf("%s IS NULL", column)
+	}
+	return fmt.Sprintf("%s IS NULL OR %s = ''", column, column)
+}
+
+// regexMatch 列值匹配正则的条件。SQL Server没有正则函数，返回false由调用方在Go中匹配
+func (d qualityDialect) regexMatch(column, regex string) (string, bool) {
+	switch d.name {
+	case "sqlserver":
+		return "", false
+	case "postgresql":
+		return fmt.Sprintf("%s::text ~ %s", column, d.literal(regex)), true
+	case "oracle":
+		return fmt.Sprintf("REGEXP_LIKE(%s, %s)", column, d.literal(regex)), true
+	default:
+		return fmt.Sprintf("%s REGEXP %s", column, d.literal(regex)), true
+	}
+}
+
+// hoursAgo 当前时间减去hours小时的表达式
+func (d qualityDialect) hoursAgo(hours int) string {
+	switch d.name {
+	case "postgresql":
+		return fmt.Sprintf("NOW() - INTERVAL '%d hours'", hours)
+	case "sqlserver":
+		return fmt.Sprintf("DATEADD(HOUR, -%d, GETDATE())", hours)
+	case "oracle":
+		return fmt.Sprintf("SYSTIMESTAMP - NUMTODSINTERVAL(%d, 'HOUR')", hours)
+	default:
+		return fmt.Sprintf("DATE_SUB(NOW(), INTERVAL %d HOUR)", hours)
+	}
+}
+
+// placeholder 第n个（从1开始）绑定参数的占位符
+func (d qualityDialect) placeholder(n int) string {
+	switch d.name {
+	case "postgresql":
+		return fmt.Sprintf("$%d", n)
+	case "sqlserver":
+		return fmt.Sprintf("@p%d", n)
+	case "oracle":
+		return fmt.Sprintf(":%d", n)
+	default:
+		return "?"
+	}
+}
+
+// selectTop 按orderBy排序取前limit行的查询语句，Oracle使用12c起支持的FETCH FIRST
+func (d qualityDialect) selectTop(columns, from, where, orderBy string, limit int) string {
+	switch d.name {
+	case "sqlserver":
+		return fmt.Sprintf("SELECT TOP %d %s FROM %s WHERE %s ORDER BY %s", limit, columns, from, where, orderBy)
+	case "oracle":
+		return fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY %s FETCH FIRST %d ROWS ONLY", columns, from, where, orderBy, limit)
+	default:
+		return fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY %s LIMIT %d", columns, from, where, orderBy, limit)
+	}
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQualityDialect(t *testing.T) {
+	mysql := newQualityDialect("mysql")
+	postgres := newQualityDialect("postgresql")
+	sqlServer := newQualityDialect("sqlserver")
+	oracle := newQualityDialect("oracle")
+
+	match, ok := mysql.regexMatch("email", `^[a-z]+\.com'$`)
+	assert.True(t, ok)
+	assert.Equal(t, `email REGEXP '^[a-z]+\\.com''$'`, match)
+	match, _ = postgres.regexMatch("code", `^\d+$`)
+	assert.Equal(t, `code::text ~ '^\d+$'`, match)
+	match, _ = oracle.regexMatch("code", `^\d+$`)
+	assert.Equal(t, `REGEXP_LIKE(code, '^\d+$')`, match)
+	_, ok = sqlServer.regexMatch("code", `^\d+$`)
+	assert.False(t, ok)
+
+	assert.Equal(t, "name IS NOT NULL AND name != ''", sqlServer.notEmpty("name"))
+	assert.Equal(t, "name IS NOT NULL", oracle.notEmpty("name"))
+	assert.Equal(t, "name IS NULL", oracle.isEmpty("name"))
+
+	assert.Equal(t, "DATE_SUB(NOW(), INTERVAL 24 HOUR)", mysql.hoursAgo(24))
+	assert.Equal(t, "NOW() - INTERVAL '24 hours'", postgres.hoursAgo(24))
+	assert.Equal(t, "DATEADD(HOUR, -24, GETDATE())", sqlServer.hoursAgo(24))
+	assert.Equal(t, "SYSTIMESTAMP - NUMTODSINTERVAL(24, 'HOUR')", oracle.hoursAgo(24))
+
+	assert.Equal(t, "?", mysql.placeholder(1))
+	assert.Equal(t, "@p2", sqlServer.placeholder(2))
+	assert.Equal(t, ":1", oracle.placeholder(1))
+
+	assert.Equal(t, "SELECT id, v FROM t WHERE v IS NULL ORDER BY id FETCH FIRST 5 ROWS ONLY",
+		oracle.selectTop("id, v", "t", "v IS NULL", "id", 5))
+}
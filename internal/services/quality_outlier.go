@@ -0,0 +1,350 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/env-data-platform/internal/models"
+)
+
+// 统计异常检测方法
+const (
+	OutlierMethodZScore = "zscore"
+	OutlierMethodIQR    = "iqr"
+)
+
+// 统计异常检测默认配置
+const (
+	defaultOutlierWindowSize = 1000
+	defaultOutlierMaxSamples = 20
+	defaultZScoreMultiplier  = 3.0
+	defaultIQRMultiplier     = 1.5
+)
+
+// OutlierConfig 统计异常检测规则配置
+type OutlierConfig struct {
+	Method     string  `json:"method"`       // zscore: 均值±N倍标准差；iqr: 四分位距
+	Multiplier float64 `json:"multiplier"`   // 标准差或四分位距的倍数
+	WindowSize int     `json:"window_size"`  // 取最近多少条数据
+	WindowHrs  float64 `json:"window_hours"` // 取最近多少小时的数据，为0时不限制
+	TimeColumn string  `json:"time_column"`  // 数据库表的时间列，用于按时间窗口取数
+	Field      string  `json:"field"`        // HJ212因子取值字段：rtd/avg/max/min
+	DeviceID   string  `json:"device_id"`    // HJ212设备编号，为空时取数据源绑定的设备
+	MaxSamples int     `json:"max_samples"`  // 详情中最多列出的离群样例数
+}
+
+// OutlierSample 离群样例
+type OutlierSample struct {
+	Value    float64   `json:"value"`
+	Deviance float64   `json:"deviance"` // 偏离正常区间的幅度
+	DeviceID string    `json:"device_id,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// OutlierStats 统计异常检测结果
+type OutlierStats struct {
+	Mean     float64 `json:"mean"`
+	StdDev   float64 `json:"std_dev"`
+	Q1       float64 `json:"q1"`
+	Q3       float64 `json:"q3"`
+	Lower    float64 `json:"lower"`
+	Upper    float64 `json:"upper"`
+	Outliers []int   `json:"-"` // 离群值在输入中的下标
+}
+
+// outlierPoint 参与检测的一条数据
+type outlierPoint struct {
+	Value    float64
+	DeviceID string
+	Time     time.Time
+}
+
+// parseOutlierConfig 解析统计异常检测配置并补齐默认值
+func parseOutlierConfig(raw string) (*OutlierConfig, error) {
+	config := &OutlierConfig{}
+	if raw != "" {
+		if err := json.Unmarshal([]byte(raw), config); err != nil {
+			return nil, fmt.Errorf("解析规则配置失败: %v", err)
+		}
+	}
+
+	switch config.Method {
+	case "":
+		config.Method = OutlierMethodZScore
+	case OutlierMethodZScore, OutlierMethodIQR:
+	default:
+		return nil, fmt.Errorf("不支持的异常检测方法: %s", config.Method)
+	}
+
+	if config.Multiplier < 0 {
+		return nil, fmt.Errorf("异常检测倍数不能为负数")
+	}
+	if config.Multiplier == 0 {
+		config.Multiplier = defaultZScoreMultiplier
+		if config.Method == OutlierMethodIQR {
+			config.Multiplier = defaultIQRMultiplier
+		}
+	}
+	if config.WindowSize <= 0 {
+		config.WindowSize = defaultOutlierWindowSize
+	}
+	if config.MaxSamples <= 0 {
+		config.MaxSamples = defaultOutlierMaxSamples
+	}
+	if config.TimeColumn == "" {
+		config.TimeColumn = "created_at"
+	}
+	if config.Field == "" {
+		config.Field = "rtd"
+	}
+	return config, nil
+}
+
+// DetectOutliers 按均值±N倍标准差或IQR计算正常区间并找出离群值
+func DetectOutliers(values []float64, method string, multiplier float64) OutlierStats {
+	var stats OutlierStats
+	if len(values) == 0 {
+		return stats
+	}
+
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	stats.Mean = sum / float64(len(values))
+
+	var sq float64
+	for _, v := range values {
+		sq += (v - stats.Mean) * (v - stats.Mean)
+	}
+	stats.StdDev = math.Sqrt(sq / float64(len(values)))
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	stats.Q1 = quantile(sorted, 0.25)
+	stats.Q3 = quantile(sorted, 0.75)
+
+	if method == OutlierMethodIQR {
+		iqr := stats.Q3 - stats.Q1
+		stats.Lower = stats.Q1 - multiplier*iqr
+		stats.Upper = stats.Q3 + multiplier*iqr
+	} else {
+		stats.Lower = stats.Mean - multiplier*stats.StdDev
+		stats.Upper = stats.Mean + multiplier*stats.StdDev
+	}
+
+	for i, v := range values {
+		if v < stats.Lower || v > stats.Upper {
+			stats.Outliers = append(stats.Outliers, i)
+		}
+	}
+	return stats
+}
+
+// quantile 已排序数据的分位数（线性插值）
+func quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	pos := q * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	if lower == upper {
+		return sorted[lower]
+	}
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(pos-float64(lower))
+}
+
+// checkOutlier 检查统计异常（离群值占比）
+func (qc *QualityChecker) checkOutlier(ctx context.Context, rule *models.QualityRule, result *QualityCheckResult) (*QualityCheckResult, error) {
+	config, err := parseOutlierConfig(rule.RuleConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if rule.ColumnName == "" {
+		return nil, fmt.Errorf("统计异常检测需要指定列名或因子编码")
+	}
+
+	var points []outlierPoint
+	if rule.DataSource != nil && rule.DataSource.Type == models.DataSourceTypeHJ212 {
+		points, err = qc.loadHJ212FactorValues(ctx, rule, config)
+	} else {
+		points, err = qc.loadColumnValues(ctx, rule, config)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]float64, len(points))
+	for i, p := range points {
+		values[i] = p.Value
+	}
+	stats := DetectOutliers(values, config.Method, config.Multiplier)
+
+	result.TotalCount = int64(len(values))
+	result.FailCount = int64(len(stats.Outliers))
+	result.PassCount = result.TotalCount - result.FailCount
+	if result.TotalCount > 0 {
+		result.Score = float64(result.PassCount) / float64(result.TotalCount) * 100
+	} else {
+		result.Score = 100
+	}
+
+	if result.Score >= rule.Threshold {
+		result.Status = "pass"
+	} else {
+		result.Status = "fail"
+	}
+
+	samples := make([]OutlierSample, 0, config.MaxSamples)
+	for _, i := range stats.Outliers {
+		if len(samples) >= config.MaxSamples {
+			break
+		}
+		p := points[i]
+		deviance := p.Value - stats.Upper
+		if p.Value < stats.Lower {
+			deviance = p.Value - stats.Lower
+		}
+		samples = append(samples, OutlierSample{
+			Value:    p.Value,
+			Deviance: deviance,
+			DeviceID: p.DeviceID,
+			Time:     p.Time,
+		})
+	}
+
+	target := rule.TargetTable
+	if target == "" {
+		target = models.DataSourceTypeHJ212
+	}
+
+	// 详细信息
+	result.Details["table_name"] = rule.TargetTable
+	result.Details["column_name"] = rule.ColumnName
+	result.Details["method"] = config.Method
+	result.Details["multiplier"] = config.Multiplier
+	result.Details["window_size"] = config.WindowSize
+	result.Details["window_hours"] = config.WindowHrs
+	result.Details["mean"] = stats.Mean
+	result.Details["std_dev"] = stats.StdDev
+	result.Details["q1"] = stats.Q1
+	result.Details["q3"] = stats.Q3
+	result.Details["lower_bound"] = stats.Lower
+	result.Details["upper_bound"] = stats.Upper
+	result.Details["outlier_count"] = result.FailCount
+	result.Details["outlier_samples"] = samples
+
+	// 生成建议
+	if result.Status == "fail" {
+		result.Suggestions = fmt.Sprintf("%s.%s 共 %d 条数据中有 %d 条超出正常区间 [%.4f, %.4f]，正常率 %.2f%%，低于阈值 %.2f%%。建议核查监测设备状态和离群样例对应时段的数据。",
+			target, rule.ColumnName, result.TotalCount, result.FailCount, stats.Lower, stats.Upper, result.Score, rule.Threshold)
+	} else {
+		result.Suggestions = fmt.Sprintf("%s.%s 的正常率为 %.2f%%，符合质量要求。",
+			target, rule.ColumnName, result.Score)
+	}
+
+	return result, nil
+}
+
+// loadColumnValues 从数据库数据源读取指定列最近窗口内的数值
+func (qc *QualityChecker) loadColumnValues(ctx context.Context, rule *models.QualityRule, config *OutlierConfig) ([]outlierPoint, error) {
+	tableName := rule.TargetTable
+	columnName := rule.ColumnName
+	if tableName == "" {
+		return nil, fmt.Errorf("统计异常检测需要指定表名")
+	}
+
+	db, err := qc.getDataSourceConnection(rule.DataSource)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	dialect := newQualityDialect(rule.DataSource.Type)
+	where := fmt.Sprintf("%s IS NOT NULL", columnName)
+	args := []interface{}{}
+	if config.WindowHrs > 0 {
+		where += fmt.Sprintf(" AND %s >= %s", config.TimeColumn, dialect.placeholder(1))
+		args = append(args, time.Now().Add(-time.Duration(config.WindowHrs*float64(time.Hour))))
+	}
+
+	query := dialect.selectTop(fmt.Sprintf("%s, %s", columnName, config.TimeColumn), tableName, where,
+		config.TimeColumn+" DESC", config.WindowSize)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询列数据失败: %v", err)
+	}
+	defer rows.Close()
+
+	var points []outlierPoint
+	for rows.Next() {
+		var value float64
+		var at time.Time
+		if err := rows.Scan(&value, &at); err != nil {
+			return nil, fmt.Errorf("读取列数据失败: %v", err)
+		}
+		points = append(points, outlierPoint{Value: value, Time: at})
+	}
+	return points, rows.Err()
+}
+
+// loadHJ212FactorValues 从平台接收的HJ212报文中读取指定因子最近窗口内的数值
+func (qc *QualityChecker) loadHJ212FactorValues(ctx context.Context, rule *models.QualityRule, config *OutlierConfig) ([]outlierPoint, error) {
+	deviceID := config.DeviceID
+	if deviceID == "" {
+		deviceID = rule.DataSource.DeviceID
+	}
+
+	query := qc.db.WithContext(ctx).Model(&models.HJ212Data{}).
+		Select("device_id, data_time, received_at, parsed_data").
+		Where("is_valid = ?", true)
+	if deviceID != "" {
+		query = query.Where("device_id = ?", deviceID)
+	}
+	if config.WindowHrs > 0 {
+		query = query.Where("received_at >= ?", time.Now().Add(-time.Duration(config.WindowHrs*float64(time.Hour))))
+	}
+
+	var records []models.HJ212Data
+	if err := query.Order("received_at DESC").Limit(config.WindowSize).Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("查询HJ212数据失败: %v", err)
+	}
+
+	points := make([]outlierPoint, 0, len(records))
+	for _, record := range records {
+		value, ok := hj212FactorValue(record.ParsedData, rule.ColumnName, config.Field)
+		if !ok {
+			continue
+		}
+		at := record.ReceivedAt
+		if record.DataTime != nil {
+			at = *record.DataTime
+		}
+		points = append(points, outlierPoint{Value: value, DeviceID: record.DeviceID, Time: at})
+	}
+	return points, nil
+}
+
+// hj212FactorValue 从解析后的报文数据中取因子的指定字段值
+func hj212FactorValue(parsed models.JSONMap, factorCode, field string) (float64, bool) {
+	if parsed == nil {
+		return 0, false
+	}
+	factors, ok := parsed["factors"].(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+	factorInfo, ok := factors[factorCode].(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+	value, ok := factorInfo[field].(float64)
+	return value, ok
+}
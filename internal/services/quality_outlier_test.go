@@ -0,0 +1,69 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/env-data-platform/internal/models"
+)
+
+func TestDetectOutliers(t *testing.T) {
+	values := []float64{10, 11, 10, 12, 11, 10, 11, 10, 12, 11, 95}
+
+	stats := DetectOutliers(values, OutlierMethodIQR, 1.5)
+	assert.Equal(t, []int{10}, stats.Outliers)
+	assert.InDelta(t, 10, stats.Q1, 1e-9)
+	assert.InDelta(t, 11.5, stats.Q3, 1e-9)
+	assert.InDelta(t, 7.75, stats.Lower, 1e-9)
+	assert.InDelta(t, 13.75, stats.Upper, 1e-9)
+
+	stats = DetectOutliers(values, OutlierMethodZScore, 3)
+	assert.Equal(t, []int{10}, stats.Outliers)
+
+	stats = DetectOutliers([]float64{5, 5, 5}, OutlierMethodZScore, 3)
+	assert.Empty(t, stats.Outliers)
+
+	stats = DetectOutliers(nil, OutlierMethodZScore, 3)
+	assert.Empty(t, stats.Outliers)
+}
+
+func TestParseOutlierConfig(t *testing.T) {
+	config, err := parseOutlierConfig(`{"method":"iqr","window_hours":24}`)
+	require.NoError(t, err)
+	assert.Equal(t, OutlierMethodIQR, config.Method)
+	assert.Equal(t, 1.5, config.Multiplier)
+	assert.Equal(t, 24.0, config.WindowHrs)
+	assert.Equal(t, defaultOutlierWindowSize, config.WindowSize)
+	assert.Equal(t, "rtd", config.Field)
+
+	config, err = parseOutlierConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, OutlierMethodZScore, config.Method)
+	assert.Equal(t, 3.0, config.Multiplier)
+
+	_, err = parseOutlierConfig(`{"method":"mad"}`)
+	assert.Error(t, err)
+	_, err = parseOutlierConfig(`{"multiplier":-1}`)
+	assert.Error(t, err)
+}
+
+func TestHJ212FactorValue(t *testing.T) {
+	parsed := models.JSONMap{
+		"factors": map[string]interface{}{
+			"a21026": map[string]interface{}{"rtd": 12.5, "avg": 11.0},
+		},
+	}
+
+	value, ok := hj212FactorValue(parsed, "a21026", "rtd")
+	assert.True(t, ok)
+	assert.Equal(t, 12.5, value)
+
+	_, ok = hj212FactorValue(parsed, "a21026", "max")
+	assert.False(t, ok)
+	_, ok = hj212FactorValue(parsed, "a34004", "rtd")
+	assert.False(t, ok)
+	_, ok = hj212FactorValue(nil, "a21026", "rtd")
+	assert.False(t, ok)
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/env-data-platform/internal/models"
+)
+
+// defaultReferentialMaxSamples 详情中默认列出的孤儿记录数
+const defaultReferentialMaxSamples = 20
+
+// ReferentialConfig 引用完整性规则配置，规则的表名和列名为子表及其外键列
+type ReferentialConfig struct {
+	ParentTable  string `json:"parent_table"`  // 父表
+	ParentColumn string `json:"parent_column"` // 父表被引用的主键列
+	ChildKey     string `json:"child_key"`     // 子表主键，用于列出孤儿样例，默认id
+	MaxSamples   int    `json:"max_samples"`   // 详情中最多列出的孤儿记录数
+}
+
+// OrphanSample 孤儿记录样例
+type OrphanSample struct {
+	Key        string `json:"key"`         // 子表主键
+	ForeignKey string `json:"foreign_key"` // 在父表中不存在的外键值
+}
+
+// parseReferentialConfig 解析引用完整性配置并补齐默认值，表名和列名只允许标识符以防注入
+func parseReferentialConfig(rule *models.QualityRule) (*ReferentialConfig, error) {
+	config := &ReferentialConfig{}
+	if rule.RuleConfig != "" {
+		if err := json.Unmarshal([]byte(rule.RuleConfig), config); err != nil {
+			return nil, fmt.Errorf("解析规则配置失败: %v", err)
+		}
+	}
+	if config.ChildKey == "" {
+		config.ChildKey = "id"
+	}
+	if config.MaxSamples <= 0 {
+		config.MaxSamples = defaultReferentialMaxSamples
+	}
+
+	switch {
+	case rule.TargetTable == "" || rule.ColumnName == "":
+		return nil, fmt.Errorf("引用完整性检查需要指定子表名和外键列")
+	case config.ParentTable == "" || config.ParentColumn == "":
+		return nil, fmt.Errorf("引用完整性检查需要配置parent_table和parent_column")
+	}
+	for _, table := range []string{rule.TargetTable, config.ParentTable} {
+		if !exportTableRegex.MatchString(table) {
+			return nil, fmt.Errorf("表名 %s 不合法", table)
+		}
+	}
+	for _, column := range []string{rule.ColumnName, config.ParentColumn, config.ChildKey} {
+		if !sqlIdentifierRegex.MatchString(column) {
+			return nil, fmt.Errorf("列名 %s 不合法", column)
+		}
+	}
+	return config, nil
+}
+
+// referentialQueries 引用完整性检查的统计和样例语句，外键为NULL的记录不参与检查
+func referentialQueries(dbType, childTable, foreignKey string, config *ReferentialConfig) (total, orphans, samples string) {
+	where := fmt.Sprintf("c.%s IS NOT NULL", foreignKey)
+	orphanWhere := fmt.Sprintf("%s AND NOT EXISTS (SELECT 1 FROM %s p WHERE p.%s = c.%s)",
+		where, config.ParentTable, config.ParentColumn, foreignKey)
+
+	total = fmt.Sprintf("SELECT COUNT(*) FROM %s c WHERE %s", childTable, where)
+	orphans = fmt.Sprintf("SELECT COUNT(*) FROM %s c WHERE %s", childTable, orphanWhere)
+	samples = newQualityDialect(dbType).selectTop(fmt.Sprintf("c.%s, c.%s", config.ChildKey, foreignKey),
+		childTable+" c", orphanWhere, "c."+config.ChildKey, config.MaxSamples)
+	return total, orphans, samples
+}
+
+// checkReferential 检查引用完整性（外键在父表中不存在的孤儿记录占比）
+func (qc *QualityChecker) checkReferential(ctx context.Context, rule *models.QualityRule, result *QualityCheckResult) (*QualityCheckResult, error) {
+	config, err := parseReferentialConfig(rule)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := qc.getDataSourceConnection(rule.DataSource)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	tableName := rule.TargetTable
+	columnName := rule.ColumnName
+	totalQuery, orphanQuery, sampleQuery := referentialQueries(rule.DataSource.Type, tableName, columnName, config)
+
+	if err := db.QueryRowContext(ctx, totalQuery).Scan(&result.TotalCount); err != nil {
+		return nil, fmt.Errorf("查询总记录数失败: %v", err)
+	}
+	if err := db.QueryRowContext(ctx, orphanQuery).Scan(&result.FailCount); err != nil {
+		return nil, fmt.Errorf("查询孤儿记录数失败: %v", err)
+	}
+	result.PassCount = result.TotalCount - result.FailCount
+
+	samples := make([]OrphanSample, 0, config.MaxSamples)
+	if result.FailCount > 0 {
+		rows, err := db.QueryContext(ctx, sampleQuery)
+		if err != nil {
+			return nil, fmt.Errorf("查询孤儿记录样例失败: %v", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var sample OrphanSample
+			if err := rows.Scan(&sample.Key, &sample.ForeignKey); err != nil {
+				return nil, fmt.Errorf("读取孤儿记录样例失败: %v", err)
+			}
+			samples = append(samples, sample)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("读取孤儿记录样例失败: %v", err)
+		}
+	}
+
+	if result.TotalCount > 0 {
+		result.Score = float64(result.PassCount) / float64(result.TotalCount) * 100
+	} else {
+		result.Score = 100
+	}
+
+	if result.Score >= rule.Threshold {
+		result.Status = "pass"
+	} else {
+		result.Status = "fail"
+	}
+
+	orphanRate := 0.0
+	if result.TotalCount > 0 {
+		orphanRate = float64(result.FailCount) / float64(result.TotalCount) * 100
+	}
+
+	// 详细信息
+	result.Details["table_name"] = tableName
+	result.Details["column_name"] = columnName
+	result.Details["parent_table"] = config.ParentTable
+	result.Details["parent_column"] = config.ParentColumn
+	result.Details["child_key"] = config.ChildKey
+	result.Details["orphan_count"] = result.FailCount
+	result.Details["orphan_rate"] = orphanRate
+	result.Details["integrity_rate"] = result.Score
+	result.Details["orphan_samples"] = samples
+
+	// 生成建议
+	if result.Status == "fail" {
+		result.Suggestions = fmt.Sprintf("%s.%s 共 %d 条记录中有 %d 条引用的 %s.%s 不存在，孤儿记录占比 %.2f%%。建议补录父表数据或按样例主键清理孤儿记录。",
+			tableName, columnName, result.TotalCount, result.FailCount, config.ParentTable, config.ParentColumn, orphanRate)
+	} else {
+		result.Suggestions = fmt.Sprintf("%s.%s 的引用完整性为 %.2f%%，符合质量要求。",
+			tableName, columnName, result.Score)
+	}
+
+	return result, nil
+}
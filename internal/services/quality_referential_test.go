@@ -0,0 +1,40 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/env-data-platform/internal/models"
+)
+
+func TestParseReferentialConfig(t *testing.T) {
+	rule := &models.QualityRule{
+		TargetTable: "env.monitor_data",
+		ColumnName:  "station_id",
+		RuleConfig:  `{"parent_table":"env.stations","parent_column":"id"}`,
+	}
+	config, err := parseReferentialConfig(rule)
+	require.NoError(t, err)
+	assert.Equal(t, "id", config.ChildKey)
+	assert.Equal(t, defaultReferentialMaxSamples, config.MaxSamples)
+
+	total, orphans, samples := referentialQueries("mysql", rule.TargetTable, rule.ColumnName, config)
+	assert.Equal(t, "SELECT COUNT(*) FROM env.monitor_data c WHERE c.station_id IS NOT NULL", total)
+	assert.Equal(t, "SELECT COUNT(*) FROM env.monitor_data c WHERE c.station_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM env.stations p WHERE p.id = c.station_id)", orphans)
+	assert.Equal(t, "SELECT c.id, c.station_id FROM env.monitor_data c WHERE c.station_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM env.stations p WHERE p.id = c.station_id) ORDER BY c.id LIMIT 20", samples)
+
+	_, _, samples = referentialQueries("sqlserver", rule.TargetTable, rule.ColumnName, config)
+	assert.Contains(t, samples, "SELECT TOP 20 c.id, c.station_id FROM")
+
+	for _, invalid := range []*models.QualityRule{
+		{TargetTable: "monitor_data", ColumnName: "station_id", RuleConfig: `{"parent_table":"stations"}`},
+		{TargetTable: "monitor_data", RuleConfig: `{"parent_table":"stations","parent_column":"id"}`},
+		{TargetTable: "monitor_data", ColumnName: "station_id", RuleConfig: `{"parent_table":"stations; DROP TABLE x","parent_column":"id"}`},
+		{TargetTable: "monitor_data", ColumnName: "station_id", RuleConfig: `{"parent_table":"stations","parent_column":"id","child_key":"1=1"}`},
+	} {
+		_, err := parseReferentialConfig(invalid)
+		assert.Error(t, err, invalid.RuleConfig)
+	}
+}
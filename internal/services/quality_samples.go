@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/env-data-platform/internal/models"
+	"go.uber.org/zap"
+)
+
+// 失败样例数量，上限避免报告详情过大
+const (
+	defaultFailureMaxSamples = 10
+	maxFailureSamples        = 100
+)
+
+// FailureSampleConfig 失败样例配置，取自规则配置中的同名字段
+type FailureSampleConfig struct {
+	KeyColumns []string `json:"key_columns"` // 样例中列出的主键或关键列，默认id
+	MaxSamples int      `json:"max_samples"` // 最多列出的失败样例数，默认10，最大100
+}
+
+// parseFailureSampleConfig 解析失败样例配置并补齐默认值，列名只允许标识符以防注入
+func parseFailureSampleConfig(raw string) (*FailureSampleConfig, error) {
+	config := &FailureSampleConfig{}
+	if raw != "" {
+		if err := json.Unmarshal([]byte(raw), config); err != nil {
+			return nil, fmt.Errorf("解析规则配置失败: %v", err)
+		}
+	}
+	if len(config.KeyColumns) == 0 {
+		config.KeyColumns = []string{"id"}
+	}
+	for _, column := range config.KeyColumns {
+		if !sqlIdentifierRegex.MatchString(column) {
+			return nil, fmt.Errorf("列名 %s 不合法", column)
+		}
+	}
+	if config.MaxSamples <= 0 {
+		config.MaxSamples = defaultFailureMaxSamples
+	}
+	if config.MaxSamples > maxFailureSamples {
+		config.MaxSamples = maxFailureSamples
+	}
+	return config, nil
+}
+
+// columns 样例查询的列：关键列加上被检查的列，去重
+func (c *FailureSampleConfig) columns(checked string) []string {
+	columns := append([]string(nil), c.KeyColumns...)
+	if checked == "" {
+		return columns
+	}
+	for _, column := range columns {
+		if column == checked {
+			return columns
+		}
+	}
+	return append(columns, checked)
+}
+
+// failureSampleQuery 失败样例查询语句，按第一个关键列排序保证结果稳定
+func failureSampleQuery(dbType, tableName string, columns []string, where string, limit int) string {
+	return newQualityDialect(dbType).selectTop(strings.Join(columns, ", "), tableName, where, columns[0], limit)
+}
+
+// queryFailureSamples 查询不合格记录样例，每条样例为列名到值的映射
+func queryFailureSamples(ctx context.Context, db *sql.DB, query string) ([]map[string]interface{}, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRowMaps(rows)
+}
+
+// scanRowMaps 把查询结果逐行转换为列名到值的映射，[]byte转为字符串
+func scanRowMaps(rows *sql.Rows) ([]map[string]interface{}, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	samples := make([]map[string]interface{}, 0)
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+
+		samples = append(samples, rowMap(columns, values))
+	}
+	return samples, rows.Err()
+}
+
+// rowMap 一行扫描结果转换为列名到值的映射，[]byte转为字符串
+func rowMap(columns []string, values []interface{}) map[string]interface{} {
+	row := make(map[string]interface{}, len(columns))
+	for i, column := range columns {
+		row[column] = rowValue(values[i])
+	}
+	return row
+}
+
+// rowValue 驱动以[]byte返回的文本值转为字符串
+func rowValue(value interface{}) interface{} {
+	if b, ok := value.([]byte); ok {
+		return string(b)
+	}
+	return value
+}
+
+// collectFailureSamples 按失败条件取若干条不合格记录写入详情的failure_samples。
+// 样例只用于辅助排查，查询失败（如表中没有配置的关键列）时记录告警，不影响检查结果
+func (qc *QualityChecker) collectFailureSamples(ctx context.Context, db *sql.DB, rule *models.QualityRule, result *QualityCheckResult, checked, where string) {
+	if result.FailCount <= 0 {
+		return
+	}
+
+	config, err := parseFailureSampleConfig(rule.RuleConfig)
+	if err != nil {
+		qc.logger.Warn("Invalid failure sample config", zap.Uint("rule_id", rule.ID), zap.Error(err))
+		return
+	}
+
+	columns := config.columns(checked)
+	query := failureSampleQuery(rule.DataSource.Type, rule.TargetTable, columns, where, config.MaxSamples)
+	samples, err := queryFailureSamples(ctx, db, query)
+	if err != nil {
+		qc.logger.Warn("Failed to query failure samples",
+			zap.Uint("rule_id", rule.ID),
+			zap.String("table", rule.TargetTable),
+			zap.Error(err))
+		return
+	}
+
+	result.Details["sample_columns"] = columns
+	result.Details["failure_samples"] = samples
+}
+
+// ReportFailureSamples 从报告详情JSON中取出失败样例，没有样例时返回nil
+func ReportFailureSamples(details string) []map[string]interface{} {
+	if details == "" {
+		return nil
+	}
+	var parsed struct {
+		FailureSamples []map[string]interface{} `json:"failure_samples"`
+	}
+	if err := json.Unmarshal([]byte(details), &parsed); err != nil {
+		return nil
+	}
+	return parsed.FailureSamples
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFailureSampleConfig(t *testing.T) {
+	config, err := parseFailureSampleConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id"}, config.KeyColumns)
+	assert.Equal(t, defaultFailureMaxSamples, config.MaxSamples)
+	assert.Equal(t, []string{"id", "email"}, config.columns("email"))
+
+	config, err = parseFailureSampleConfig(`{"pattern":"email","key_columns":["station_id","data_time"],"max_samples":500}`)
+	require.NoError(t, err)
+	assert.Equal(t, maxFailureSamples, config.MaxSamples)
+	assert.Equal(t, []string{"station_id", "data_time"}, config.columns("data_time"))
+
+	query := failureSampleQuery("mysql", "env.users", config.columns("email"), "email IS NULL OR email = ''", 5)
+	assert.Equal(t, "SELECT station_id, data_time, email FROM env.users WHERE email IS NULL OR email = '' ORDER BY station_id LIMIT 5", query)
+	query = failureSampleQuery("sqlserver", "users", []string{"id"}, "email IS NULL", 5)
+	assert.Equal(t, "SELECT TOP 5 id FROM users WHERE email IS NULL ORDER BY id", query)
+
+	_, err = parseFailureSampleConfig(`{"key_columns":["id; DROP TABLE users"]}`)
+	assert.Error(t, err)
+}
+
+func TestReportFailureSamples(t *testing.T) {
+	details := marshalDetails(map[string]interface{}{
+		"null_count":      int64(2),
+		"failure_samples": []map[string]interface{}{{"id": 1, "email": nil}, {"id": 7, "email": ""}},
+	})
+	samples := ReportFailureSamples(details)
+	require.Len(t, samples, 2)
+	assert.Equal(t, float64(7), samples[1]["id"])
+
+	assert.Nil(t, ReportFailureSamples(""))
+	assert.Nil(t, ReportFailureSamples(`{"null_count":0}`))
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/models"
+)
+
+// QualityAlarmNotifier 定时质量检查失败或低分时的告警接口，由告警检测器实现
+type QualityAlarmNotifier interface {
+	QualityCheckAlarm(rule *models.QualityRule, report *models.QualityReport, checkErr error)
+}
+
+// QualityScheduler 数据质量检查调度器
+type QualityScheduler struct {
+	cron     *cron.Cron
+	rules    map[uint]cron.EntryID
+	mutex    sync.RWMutex
+	logger   *zap.Logger
+	db       *gorm.DB
+	checker  *QualityChecker
+	notifier QualityAlarmNotifier
+}
+
+// NewQualityScheduler 创建质量检查调度器并加载已启用定时检查的规则
+func NewQualityScheduler(logger *zap.Logger, checker *QualityChecker) *QualityScheduler {
+	c := cron.New(cron.WithParser(cronParser))
+
+	scheduler := &QualityScheduler{
+		cron:    c,
+		rules:   make(map[uint]cron.EntryID),
+		logger:  logger,
+		db:      database.GetDB(),
+		checker: checker,
+	}
+
+	c.Start()
+	scheduler.LoadRulesFromDB()
+
+	return scheduler
+}
+
+// SetAlarmNotifier 设置告警通知，为空时只记录日志
+func (s *QualityScheduler) SetAlarmNotifier(notifier QualityAlarmNotifier) {
+	s.notifier = notifier
+}
+
+// LoadRulesFromDB 从数据库加载启用了定时检查的规则
+func (s *QualityScheduler) LoadRulesFromDB() {
+	if s.db == nil {
+		return
+	}
+
+	var rules []models.QualityRule
+	err := s.db.Where("is_enabled = ? AND schedule_enabled = ? AND cron_expr != ''", true, true).Find(&rules).Error
+	if err != nil {
+		s.logger.Error("Failed to load scheduled quality rules", zap.Error(err))
+		return
+	}
+
+	for i := range rules {
+		if err := s.ScheduleRule(&rules[i]); err != nil {
+			s.logger.Error("Failed to schedule quality rule from database",
+				zap.Uint("rule_id", rules[i].ID),
+				zap.Error(err))
+		}
+	}
+
+	s.logger.Info("Loaded scheduled quality rules", zap.Int("count", len(rules)))
+}
+
+// ScheduleRule 按规则的cron表达式挂载定时检查，并写入下次检查时间
+func (s *QualityScheduler) ScheduleRule(rule *models.QualityRule) error {
+	if rule.CronExpr == "" {
+		return fmt.Errorf("cron expression is empty")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if entryID, exists := s.rules[rule.ID]; exists {
+		s.cron.Remove(entryID)
+		delete(s.rules, rule.ID)
+	}
+
+	ruleID := rule.ID
+	entryID, err := s.cron.AddFunc(rule.CronExpr, func() {
+		s.executeScheduledRule(ruleID)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add cron job: %w", err)
+	}
+	s.rules[rule.ID] = entryID
+
+	nextRun := s.cron.Entry(entryID).Next
+	rule.NextCheckAt = &nextRun
+	s.db.Model(&models.QualityRule{}).Where("id = ?", rule.ID).Update("next_check_at", nextRun)
+
+	s.logger.Info("Quality rule scheduled",
+		zap.Uint("rule_id", rule.ID),
+		zap.String("cron_expr", rule.CronExpr),
+		zap.Time("next_run", nextRun))
+
+	return nil
+}
+
+// UnscheduleRule 取消规则的定时检查
+func (s *QualityScheduler) UnscheduleRule(ruleID uint) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if entryID, exists := s.rules[ruleID]; exists {
+		s.cron.Remove(entryID)
+		delete(s.rules, ruleID)
+
+		s.logger.Info("Quality rule unscheduled", zap.Uint("rule_id", ruleID))
+	}
+}
+
+// GetRuleStatus 获取规则的调度状态
+func (s *QualityScheduler) GetRuleStatus(ruleID uint) *JobScheduleStatus {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	status := &JobScheduleStatus{JobID: ruleID}
+	if entryID, exists := s.rules[ruleID]; exists {
+		entry := s.cron.Entry(entryID)
+		status.IsScheduled = true
+		status.NextRun = entry.Next
+		status.PrevRun = entry.Prev
+	}
+	return status
+}
+
+// executeScheduledRule 执行到点的质量检查，失败或分数低于阈值时告警
+func (s *QualityScheduler) executeScheduledRule(ruleID uint) {
+	var rule models.QualityRule
+	if err := s.db.Preload("DataSource").First(&rule, ruleID).Error; err != nil {
+		s.logger.Error("Failed to get quality rule for scheduled check",
+			zap.Uint("rule_id", ruleID),
+			zap.Error(err))
+		return
+	}
+
+	if !rule.IsEnabled || !rule.ScheduleEnabled {
+		s.logger.Info("Skipping unscheduled quality rule", zap.Uint("rule_id", ruleID))
+		return
+	}
+
+	report, err := s.checker.ExecuteQualityCheck(context.Background(), &rule)
+
+	updates := map[string]interface{}{"last_check_at": time.Now()}
+	if status := s.GetRuleStatus(ruleID); status.IsScheduled {
+		updates["next_check_at"] = status.NextRun
+	}
+	s.db.Model(&models.QualityRule{}).Where("id = ?", ruleID).Updates(updates)
+
+	if err != nil {
+		s.logger.Error("Scheduled quality check failed",
+			zap.Uint("rule_id", ruleID),
+			zap.Error(err))
+		s.alarm(&rule, nil, err)
+		return
+	}
+
+	s.logger.Info("Scheduled quality check completed",
+		zap.Uint("rule_id", ruleID),
+		zap.String("status", report.Status),
+		zap.Float64("score", report.Score))
+
+	if report.Score < rule.Threshold {
+		s.alarm(&rule, report, nil)
+	}
+}
+
+// alarm 触发质量告警
+func (s *QualityScheduler) alarm(rule *models.QualityRule, report *models.QualityReport, checkErr error) {
+	if s.notifier == nil {
+		return
+	}
+	s.notifier.QualityCheckAlarm(rule, report, checkErr)
+}
+
+// Stop 停止调度器
+func (s *QualityScheduler) Stop() {
+	s.logger.Info("Stopping quality scheduler")
+	s.cron.Stop()
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/models"
+)
+
+// defaultWeakRules 每张表列出的短板规则数
+const defaultWeakRules = 3
+
+// RuleScore 规则最近一次检查的分数及其对所在表综合分的影响
+type RuleScore struct {
+	RuleID         uint      `json:"rule_id"`
+	RuleName       string    `json:"rule_name"`
+	Type           string    `json:"type"`
+	DataSourceID   uint      `json:"data_source_id"`
+	DataSourceName string    `json:"data_source_name,omitempty"`
+	TableName      string    `json:"table_name"`
+	Weight         float64   `json:"weight"`
+	Score          float64   `json:"score"`
+	CheckTime      time.Time `json:"check_time"`
+	// Impact 该规则使所在表综合分降低的分数，即 权重×(100-分数)/表内总权重
+	Impact float64 `json:"impact"`
+}
+
+// TableQualityScore 一张表（数据源+表名）的加权综合质量分
+type TableQualityScore struct {
+	DataSourceID   uint    `json:"data_source_id"`
+	DataSourceName string  `json:"data_source_name,omitempty"`
+	TableName      string  `json:"table_name"`
+	Score          float64 `json:"score"`
+	TotalWeight    float64 `json:"total_weight"`
+	RuleCount      int     `json:"rule_count"`
+	// WeakRules 拉低综合分最多的规则，按影响从大到小排序
+	WeakRules []RuleScore `json:"weak_rules"`
+}
+
+// QualityScoreService 按规则权重计算综合质量分
+type QualityScoreService struct {
+	db *gorm.DB
+}
+
+// NewQualityScoreService 创建综合质量评分服务
+func NewQualityScoreService() *QualityScoreService {
+	return &QualityScoreService{db: database.GetDB()}
+}
+
+// LatestRuleScores 已启用规则最近一次检查的分数，未检查过的规则不参与评分
+func (s *QualityScoreService) LatestRuleScores() ([]RuleScore, error) {
+	latest := s.db.Model(&models.QualityReport{}).Select("MAX(id)").Group("rule_id")
+	var reports []models.QualityReport
+	if err := s.db.Select("id, rule_id, score, check_time").Where("id IN (?)", latest).Find(&reports).Error; err != nil {
+		return nil, err
+	}
+	if len(reports) == 0 {
+		return []RuleScore{}, nil
+	}
+
+	ruleIDs := make([]uint, len(reports))
+	for i, report := range reports {
+		ruleIDs[i] = report.RuleID
+	}
+	var rules []models.QualityRule
+	if err := s.db.Preload("DataSource", func(db *gorm.DB) *gorm.DB { return db.Select("id, name") }).
+		Where("id IN ? AND is_enabled = ?", ruleIDs, true).Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*models.QualityRule, len(rules))
+	for i := range rules {
+		byID[rules[i].ID] = &rules[i]
+	}
+
+	scores := make([]RuleScore, 0, len(reports))
+	for _, report := range reports {
+		rule, ok := byID[report.RuleID]
+		if !ok {
+			continue
+		}
+		score := RuleScore{
+			RuleID:       rule.ID,
+			RuleName:     rule.Name,
+			Type:         rule.Type,
+			DataSourceID: rule.DataSourceID,
+			TableName:    rule.TargetTable,
+			Weight:       rule.Weight,
+			Score:        report.Score,
+			CheckTime:    report.CheckTime,
+		}
+		if rule.DataSource != nil {
+			score.DataSourceName = rule.DataSource.Name
+		}
+		scores = append(scores, score)
+	}
+	return scores, nil
+}
+
+// TableScores 各表的加权综合分和全部规则，规则按对综合分的影响从大到小排序
+func (s *QualityScoreService) TableScores() ([]TableQualityScore, []RuleScore, error) {
+	scores, err := s.LatestRuleScores()
+	if err != nil {
+		return nil, nil, err
+	}
+	tables, ranked := ComputeWeightedScores(scores, defaultWeakRules)
+	return tables, ranked, nil
+}
+
+// ComputeWeightedScores 按数据源和表名分组计算加权综合分，权重不大于0的规则不参与。
+// 返回各表的综合分（从低到高）以及填好影响值、按影响从大到小排序的规则，
+// 每张表保留影响最大的weakRules条规则作为短板
+func ComputeWeightedScores(scores []RuleScore, weakRules int) ([]TableQualityScore, []RuleScore) {
+	type tableKey struct {
+		dataSourceID uint
+		table        string
+	}
+	index := make(map[tableKey]int)
+	tables := make([]TableQualityScore, 0)
+	members := make([][]int, 0)
+	ranked := make([]RuleScore, 0, len(scores))
+
+	for _, score := range scores {
+		if score.Weight <= 0 {
+			continue
+		}
+		key := tableKey{score.DataSourceID, score.TableName}
+		i, ok := index[key]
+		if !ok {
+			i = len(tables)
+			index[key] = i
+			tables = append(tables, TableQualityScore{
+				DataSourceID:   score.DataSourceID,
+				DataSourceName: score.DataSourceName,
+				TableName:      score.TableName,
+			})
+			members = append(members, nil)
+		}
+		tables[i].TotalWeight += score.Weight
+		tables[i].Score += score.Weight * score.Score
+		tables[i].RuleCount++
+		members[i] = append(members[i], len(ranked))
+		ranked = append(ranked, score)
+	}
+
+	for i := range tables {
+		table := &tables[i]
+		table.Score /= table.TotalWeight
+		for _, j := range members[i] {
+			ranked[j].Impact = ranked[j].Weight * (100 - ranked[j].Score) / table.TotalWeight
+		}
+	}
+	for i := range tables {
+		weak := make([]RuleScore, 0, len(members[i]))
+		for _, j := range members[i] {
+			if ranked[j].Impact > 0 {
+				weak = append(weak, ranked[j])
+			}
+		}
+		sortByImpact(weak)
+		if len(weak) > weakRules {
+			weak = weak[:weakRules]
+		}
+		tables[i].WeakRules = weak
+	}
+
+	sort.SliceStable(tables, func(i, j int) bool { return tables[i].Score < tables[j].Score })
+	sortByImpact(ranked)
+	return tables, ranked
+}
+
+// sortByImpact 按影响从大到小排序，影响相同时权重大的在前
+func sortByImpact(scores []RuleScore) {
+	sort.SliceStable(scores, func(i, j int) bool {
+		if scores[i].Impact != scores[j].Impact {
+			return scores[i].Impact > scores[j].Impact
+		}
+		return scores[i].Weight > scores[j].Weight
+	})
+}
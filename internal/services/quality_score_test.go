@@ -0,0 +1,47 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeWeightedScores(t *testing.T) {
+	scores := []RuleScore{
+		{RuleID: 1, DataSourceID: 1, TableName: "air", Weight: 3, Score: 100},
+		{RuleID: 2, DataSourceID: 1, TableName: "air", Weight: 1, Score: 60},
+		{RuleID: 3, DataSourceID: 1, TableName: "air", Weight: 1, Score: 80},
+		{RuleID: 4, DataSourceID: 1, TableName: "water", Weight: 2, Score: 90},
+		{RuleID: 5, DataSourceID: 2, TableName: "air", Weight: 1, Score: 50},
+		// 权重为0的规则不参与评分
+		{RuleID: 6, DataSourceID: 2, TableName: "air", Weight: 0, Score: 0},
+	}
+
+	tables, ranked := ComputeWeightedScores(scores, 1)
+	require.Len(t, tables, 3)
+	assert.Equal(t, uint(2), tables[0].DataSourceID)
+	assert.InDelta(t, 50, tables[0].Score, 1e-9)
+
+	air := tables[1]
+	assert.Equal(t, "air", air.TableName)
+	assert.InDelta(t, 88, air.Score, 1e-9) // (300+60+80)/5
+	assert.Equal(t, 5.0, air.TotalWeight)
+	assert.Equal(t, 3, air.RuleCount)
+	require.Len(t, air.WeakRules, 1)
+	assert.Equal(t, uint(2), air.WeakRules[0].RuleID)
+	assert.InDelta(t, 8, air.WeakRules[0].Impact, 1e-9)
+
+	assert.InDelta(t, 90, tables[2].Score, 1e-9)
+
+	ids := make([]uint, len(ranked))
+	for i, score := range ranked {
+		ids[i] = score.RuleID
+	}
+	// 影响：5→50，4→10，2→8，3→4，1→0
+	assert.Equal(t, []uint{5, 4, 2, 3, 1}, ids)
+
+	tables, ranked = ComputeWeightedScores(nil, 3)
+	assert.Empty(t, tables)
+	assert.Empty(t, ranked)
+}
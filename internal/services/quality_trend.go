@@ -0,0 +1,256 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/models"
+)
+
+// 趋势统计粒度
+const (
+	TrendIntervalHour = "hour"
+	TrendIntervalDay  = "day"
+)
+
+// 整体趋势的聚合维度
+const (
+	TrendGroupDataSource = "data_source"
+	TrendGroupType       = "type"
+)
+
+// trendBucketFormats 各粒度对应的MySQL时间分桶格式
+var trendBucketFormats = map[string]string{
+	TrendIntervalHour: "%Y-%m-%d %H:00",
+	TrendIntervalDay:  "%Y-%m-%d",
+}
+
+// TrendPoint 质量分数时间序列中的一个点
+type TrendPoint struct {
+	Bucket   string  `json:"bucket"`
+	AvgScore float64 `json:"avg_score"`
+	MinScore float64 `json:"min_score"`
+	MaxScore float64 `json:"max_score"`
+	Count    int64   `json:"count"`
+}
+
+// TrendAnalysis 分数走势分析结果
+type TrendAnalysis struct {
+	Slope            float64 `json:"slope"`             // 每个时间桶的平均分变化（最小二乘）
+	Change           float64 `json:"change"`            // 末点相对首点的变化
+	ConsecutiveDrops int     `json:"consecutive_drops"` // 末尾连续下降的桶数
+	Declining        bool    `json:"declining"`         // 是否持续下降
+}
+
+// TrendSeries 一条规则或一个聚合维度的质量趋势
+type TrendSeries struct {
+	Key      string        `json:"key"`
+	Name     string        `json:"name"`
+	Points   []TrendPoint  `json:"points"`
+	Analysis TrendAnalysis `json:"analysis"`
+}
+
+// TrendQuery 趋势查询条件
+type TrendQuery struct {
+	Start    time.Time
+	End      time.Time
+	Interval string
+	// MinDrops 末尾连续下降达到该桶数视为持续下降
+	MinDrops int
+}
+
+// QualityTrendService 质量分数趋势统计
+type QualityTrendService struct {
+	db *gorm.DB
+}
+
+// NewQualityTrendService 创建质量趋势服务
+func NewQualityTrendService() *QualityTrendService {
+	return &QualityTrendService{db: database.GetDB()}
+}
+
+// AnalyzeTrend 分析分数序列走势，末尾连续下降次数达到minDrops且整体斜率为负时视为持续下降
+func AnalyzeTrend(points []TrendPoint, minDrops int) TrendAnalysis {
+	var analysis TrendAnalysis
+	n := len(points)
+	if n < 2 {
+		return analysis
+	}
+
+	analysis.Change = points[n-1].AvgScore - points[0].AvgScore
+
+	var sumX, sumY, sumXY, sumXX float64
+	for i, p := range points {
+		x := float64(i)
+		sumX += x
+		sumY += p.AvgScore
+		sumXY += x * p.AvgScore
+		sumXX += x * x
+	}
+	fn := float64(n)
+	if denom := fn*sumXX - sumX*sumX; denom != 0 {
+		analysis.Slope = (fn*sumXY - sumX*sumY) / denom
+	}
+
+	for i := n - 1; i > 0 && points[i].AvgScore < points[i-1].AvgScore; i-- {
+		analysis.ConsecutiveDrops++
+	}
+
+	if minDrops < 1 {
+		minDrops = 1
+	}
+	analysis.Declining = analysis.Slope < 0 && analysis.ConsecutiveDrops >= minDrops
+	return analysis
+}
+
+// trendRow 分组查询的原始行
+type trendRow struct {
+	GroupKey string
+	TrendPoint
+}
+
+// bucketExpr 时间分桶表达式
+func bucketExpr(interval string) (string, error) {
+	format, ok := trendBucketFormats[interval]
+	if !ok {
+		return "", fmt.Errorf("不支持的统计粒度: %s", interval)
+	}
+	return fmt.Sprintf("DATE_FORMAT(%s.check_time, '%s')", models.QualityReport{}.TableName(), format), nil
+}
+
+// query 按分组键和时间桶聚合报告分数
+func (s *QualityTrendService) query(q TrendQuery, groupExpr string, scope func(*gorm.DB) *gorm.DB) ([]TrendSeries, error) {
+	bucket, err := bucketExpr(q.Interval)
+	if err != nil {
+		return nil, err
+	}
+	reports := models.QualityReport{}.TableName()
+
+	db := s.db.Table(reports).
+		Select(fmt.Sprintf("%s AS group_key, %s AS bucket, AVG(%s.score) AS avg_score, MIN(%s.score) AS min_score, MAX(%s.score) AS max_score, COUNT(*) AS count",
+			groupExpr, bucket, reports, reports, reports)).
+		Joins(fmt.Sprintf("JOIN %s ON %s.id = %s.rule_id", models.QualityRule{}.TableName(), models.QualityRule{}.TableName(), reports)).
+		Where(fmt.Sprintf("%s.check_time >= ? AND %s.check_time < ?", reports, reports), q.Start, q.End)
+	if scope != nil {
+		db = scope(db)
+	}
+
+	var rows []trendRow
+	if err := db.Group("group_key, bucket").Order("group_key, bucket").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	return buildTrendSeries(rows, q.MinDrops), nil
+}
+
+// buildTrendSeries 将按分组键和时间桶排序的行组装为序列
+func buildTrendSeries(rows []trendRow, minDrops int) []TrendSeries {
+	index := make(map[string]int)
+	var series []TrendSeries
+	for _, row := range rows {
+		i, ok := index[row.GroupKey]
+		if !ok {
+			i = len(series)
+			index[row.GroupKey] = i
+			series = append(series, TrendSeries{Key: row.GroupKey})
+		}
+		series[i].Points = append(series[i].Points, row.TrendPoint)
+	}
+	for i := range series {
+		series[i].Analysis = AnalyzeTrend(series[i].Points, minDrops)
+	}
+	return series
+}
+
+// RuleTrend 单条规则的分数时间序列
+func (s *QualityTrendService) RuleTrend(rule *models.QualityRule, q TrendQuery) (*TrendSeries, error) {
+	rules := models.QualityRule{}.TableName()
+	series, err := s.query(q, rules+".id", func(db *gorm.DB) *gorm.DB {
+		return db.Where(rules+".id = ?", rule.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TrendSeries{Key: fmt.Sprint(rule.ID), Points: []TrendPoint{}}
+	if len(series) > 0 {
+		result = &series[0]
+	}
+	result.Name = rule.Name
+	return result, nil
+}
+
+// OverallTrend 按数据源或规则类型聚合的整体质量趋势
+func (s *QualityTrendService) OverallTrend(groupBy string, q TrendQuery) ([]TrendSeries, error) {
+	rules := models.QualityRule{}.TableName()
+
+	var groupExpr string
+	switch groupBy {
+	case TrendGroupDataSource:
+		groupExpr = rules + ".data_source_id"
+	case TrendGroupType:
+		groupExpr = rules + ".type"
+	case "":
+		groupExpr = "'all'"
+	default:
+		return nil, fmt.Errorf("不支持的聚合维度: %s", groupBy)
+	}
+
+	series, err := s.query(q, groupExpr, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if groupBy == TrendGroupDataSource && len(series) > 0 {
+		var sources []models.DataSource
+		s.db.Select("id, name").Find(&sources)
+		names := make(map[string]string, len(sources))
+		for _, source := range sources {
+			names[fmt.Sprint(source.ID)] = source.Name
+		}
+		for i := range series {
+			series[i].Name = names[series[i].Key]
+		}
+	}
+	return series, nil
+}
+
+// DecliningRules 时间范围内分数持续下降的规则，按下降幅度从大到小排序
+func (s *QualityTrendService) DecliningRules(q TrendQuery) ([]TrendSeries, error) {
+	rules := models.QualityRule{}.TableName()
+	series, err := s.query(q, rules+".id", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	declining := make([]TrendSeries, 0)
+	ids := make([]string, 0)
+	for _, item := range series {
+		if item.Analysis.Declining {
+			declining = append(declining, item)
+			ids = append(ids, item.Key)
+		}
+	}
+	if len(declining) == 0 {
+		return declining, nil
+	}
+
+	var ruleRows []models.QualityRule
+	s.db.Select("id, name").Where("id IN ?", ids).Find(&ruleRows)
+	names := make(map[string]string, len(ruleRows))
+	for _, rule := range ruleRows {
+		names[fmt.Sprint(rule.ID)] = rule.Name
+	}
+	for i := range declining {
+		declining[i].Name = names[declining[i].Key]
+	}
+
+	sort.Slice(declining, func(i, j int) bool {
+		return declining[i].Analysis.Change < declining[j].Analysis.Change
+	})
+	return declining, nil
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func trendPoints(scores ...float64) []TrendPoint {
+	points := make([]TrendPoint, len(scores))
+	for i, score := range scores {
+		points[i] = TrendPoint{AvgScore: score, Count: 1}
+	}
+	return points
+}
+
+func TestAnalyzeTrend(t *testing.T) {
+	analysis := AnalyzeTrend(trendPoints(95, 96, 92, 88, 81), 3)
+	assert.True(t, analysis.Declining)
+	assert.Equal(t, 3, analysis.ConsecutiveDrops)
+	assert.InDelta(t, -14, analysis.Change, 1e-9)
+	assert.Less(t, analysis.Slope, 0.0)
+
+	// 末尾回升不算持续下降
+	analysis = AnalyzeTrend(trendPoints(95, 90, 85, 80, 82), 3)
+	assert.False(t, analysis.Declining)
+	assert.Equal(t, 0, analysis.ConsecutiveDrops)
+
+	// 连续下降次数不足
+	assert.False(t, AnalyzeTrend(trendPoints(90, 92, 91), 3).Declining)
+	assert.Equal(t, TrendAnalysis{}, AnalyzeTrend(trendPoints(90), 1))
+}
+
+func TestBuildTrendSeries(t *testing.T) {
+	rows := []trendRow{
+		{GroupKey: "1", TrendPoint: TrendPoint{Bucket: "2024-03-01", AvgScore: 90}},
+		{GroupKey: "1", TrendPoint: TrendPoint{Bucket: "2024-03-02", AvgScore: 80}},
+		{GroupKey: "2", TrendPoint: TrendPoint{Bucket: "2024-03-01", AvgScore: 70}},
+	}
+	series := buildTrendSeries(rows, 1)
+	assert.Len(t, series, 2)
+	assert.Equal(t, "1", series[0].Key)
+	assert.Len(t, series[0].Points, 2)
+	assert.True(t, series[0].Analysis.Declining)
+	assert.Equal(t, "2", series[1].Key)
+	assert.False(t, series[1].Analysis.Declining)
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/env-data-platform/internal/database"
+	"github.com/env-data-platform/internal/models"
+)
+
+// 角色继承校验错误
+var (
+	ErrRoleInheritanceCycle = errors.New("父角色不能是角色自身或其子孙角色")
+	ErrParentRoleNotFound   = errors.New("父角色不存在")
+)
+
+// roleNode 角色继承关系中的节点
+type roleNode struct {
+	ParentID *uint
+	Status   int
+}
+
+// RoleHierarchy 角色继承关系，子角色自动拥有全部祖先角色的权限。
+// 有效权限在查询时按继承链实时合并，父角色权限变更后子角色立即生效
+type RoleHierarchy struct {
+	db *gorm.DB
+}
+
+// NewRoleHierarchy 创建角色继承关系服务
+func NewRoleHierarchy(db *gorm.DB) *RoleHierarchy {
+	if db == nil {
+		db = database.GetDB()
+	}
+	return &RoleHierarchy{db: db}
+}
+
+// loadNodes 加载全部角色的继承关系，角色数量有限，一次性加载后在内存中遍历
+func (h *RoleHierarchy) loadNodes() (map[uint]roleNode, error) {
+	var roles []models.Role
+	if err := h.db.Select("id", "parent_id", "status").Find(&roles).Error; err != nil {
+		return nil, fmt.Errorf("查询角色继承关系失败: %w", err)
+	}
+
+	nodes := make(map[uint]roleNode, len(roles))
+	for _, role := range roles {
+		nodes[role.ID] = roleNode{ParentID: role.ParentID, Status: role.Status}
+	}
+	return nodes, nil
+}
+
+// ExpandRoleIDs 返回roleIDs及其全部已启用祖先角色的ID
+func (h *RoleHierarchy) ExpandRoleIDs(roleIDs []uint) ([]uint, error) {
+	if len(roleIDs) == 0 {
+		return nil, nil
+	}
+	nodes, err := h.loadNodes()
+	if err != nil {
+		return nil, err
+	}
+	return expandRoleIDs(nodes, roleIDs), nil
+}
+
+// CheckParent 校验将parentID设为roleID的父角色是否合法，parentID为nil表示不继承
+func (h *RoleHierarchy) CheckParent(roleID uint, parentID *uint) error {
+	if parentID == nil {
+		return nil
+	}
+	nodes, err := h.loadNodes()
+	if err != nil {
+		return err
+	}
+	if _, ok := nodes[*parentID]; !ok {
+		return ErrParentRoleNotFound
+	}
+	if createsCycle(nodes, roleID, *parentID) {
+		return ErrRoleInheritanceCycle
+	}
+	return nil
+}
+
+// GetRolePermissions 查询角色的有效权限，包含自身权限和从祖先角色继承的权限
+func (h *RoleHierarchy) GetRolePermissions(roleID uint) ([]models.Permission, error) {
+	roleIDs, err := h.ExpandRoleIDs([]uint{roleID})
+	if err != nil {
+		return nil, err
+	}
+	return h.permissionsOf(roleIDs, "")
+}
+
+// permissionsOf 查询一组角色直接拥有的已启用权限，permType为空时不按类型过滤
+func (h *RoleHierarchy) permissionsOf(roleIDs []uint, permType string) ([]models.Permission, error) {
+	if len(roleIDs) == 0 {
+		return []models.Permission{}, nil
+	}
+
+	query := h.db.Table("env_permissions").
+		Joins("JOIN env_role_permissions ON env_permissions.id = env_role_permissions.permission_id").
+		Where("env_role_permissions.role_id IN ? AND env_permissions.status = 1", roleIDs).
+		Where("env_permissions.deleted_at IS NULL")
+	if permType != "" {
+		query = query.Where("env_permissions.type = ?", permType)
+	}
+
+	var permissions []models.Permission
+	err := query.Select("env_permissions.*").
+		Group("env_permissions.id").
+		Order("env_permissions.sort ASC, env_permissions.id ASC").
+		Find(&permissions).Error
+	return permissions, err
+}
+
+// expandRoleIDs 沿父角色链向上合并角色ID。已禁用或不存在的祖先角色截断继承，
+// 遇到环时停止遍历，保证数据异常时也不会死循环
+func expandRoleIDs(nodes map[uint]roleNode, roleIDs []uint) []uint {
+	seen := make(map[uint]bool)
+	result := make([]uint, 0, len(roleIDs))
+	for _, id := range roleIDs {
+		for current := id; !seen[current]; {
+			seen[current] = true
+			result = append(result, current)
+
+			node, ok := nodes[current]
+			if !ok || node.ParentID == nil {
+				break
+			}
+			parent, ok := nodes[*node.ParentID]
+			if !ok || parent.Status != 1 {
+				break
+			}
+			current = *node.ParentID
+		}
+	}
+	return result
+}
+
+// createsCycle 判断将parentID设为roleID的父角色后是否形成环
+func createsCycle(nodes map[uint]roleNode, roleID, parentID uint) bool {
+	visited := make(map[uint]bool)
+	for current := parentID; ; {
+		if current == roleID {
+			return true
+		}
+		if visited[current] {
+			// 已有数据中存在环，拒绝在其上继续挂接
+			return true
+		}
+		visited[current] = true
+
+		node, ok := nodes[current]
+		if !ok || node.ParentID == nil {
+			return false
+		}
+		current = *node.ParentID
+	}
+}
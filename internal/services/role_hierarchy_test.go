@@ -0,0 +1,49 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func uintPtr(v uint) *uint {
+	return &v
+}
+
+func TestExpandRoleIDs(t *testing.T) {
+	// 1 <- 2 <- 3，4已禁用且为5的父角色，6 <- 7 <- 6 为异常数据中的环
+	nodes := map[uint]roleNode{
+		1: {Status: 1},
+		2: {ParentID: uintPtr(1), Status: 1},
+		3: {ParentID: uintPtr(2), Status: 1},
+		4: {ParentID: uintPtr(1), Status: 0},
+		5: {ParentID: uintPtr(4), Status: 1},
+		6: {ParentID: uintPtr(7), Status: 1},
+		7: {ParentID: uintPtr(6), Status: 1},
+		8: {ParentID: uintPtr(99), Status: 1},
+	}
+
+	assert.Equal(t, []uint{3, 2, 1}, expandRoleIDs(nodes, []uint{3}))
+	assert.Equal(t, []uint{3, 2, 1}, expandRoleIDs(nodes, []uint{3, 1}))
+	assert.Equal(t, []uint{5}, expandRoleIDs(nodes, []uint{5}))
+	assert.Equal(t, []uint{6, 7}, expandRoleIDs(nodes, []uint{6}))
+	assert.Equal(t, []uint{8}, expandRoleIDs(nodes, []uint{8}))
+	assert.Empty(t, expandRoleIDs(nodes, nil))
+}
+
+func TestCreatesCycle(t *testing.T) {
+	nodes := map[uint]roleNode{
+		1: {Status: 1},
+		2: {ParentID: uintPtr(1), Status: 1},
+		3: {ParentID: uintPtr(2), Status: 1},
+		6: {ParentID: uintPtr(7), Status: 1},
+		7: {ParentID: uintPtr(6), Status: 1},
+	}
+
+	assert.False(t, createsCycle(nodes, 3, 1))
+	assert.False(t, createsCycle(nodes, 0, 3))
+	assert.True(t, createsCycle(nodes, 1, 1))
+	assert.True(t, createsCycle(nodes, 1, 3))
+	assert.True(t, createsCycle(nodes, 2, 3))
+	assert.True(t, createsCycle(nodes, 1, 6))
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+
+	_ "github.com/microsoft/go-mssqldb"
+)
+
+// sqlServerDriver go-mssqldb注册的驱动名
+const sqlServerDriver = "sqlserver"
+
+// buildSQLServerDSN 根据数据源配置构建SQL Server连接字符串。
+// 配置了instance时按命名实例连接（由SQL Browser解析端口，port可不填）；encrypt默认disable，兼容未配置证书的旧版本服务器
+func buildSQLServerDSN(config map[string]interface{}) (string, error) {
+	instance, err := configString(config, "instance", false)
+	if err != nil {
+		return "", err
+	}
+	host, err := configString(config, "host", true)
+	if err != nil {
+		return "", err
+	}
+	port, err := configPort(config, "port", instance == "")
+	if err != nil {
+		return "", err
+	}
+	username, err := configString(config, "username", true)
+	if err != nil {
+		return "", err
+	}
+	password, err := configString(config, "password", false)
+	if err != nil {
+		return "", err
+	}
+	database, err := configString(config, "database", true)
+	if err != nil {
+		return "", err
+	}
+	encrypt, err := configString(config, "encrypt", false)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("database", database)
+	query.Set("encrypt", "disable")
+	if encrypt != "" {
+		query.Set("encrypt", encrypt)
+	}
+
+	u := &url.URL{
+		Scheme:   "sqlserver",
+		User:     url.UserPassword(username, password),
+		Host:     fmt.Sprintf("%s:%d", host, port),
+		RawQuery: query.Encode(),
+	}
+	if instance != "" {
+		u.Host = host
+		u.Path = instance
+	}
+	return u.String(), nil
+}
+
+// openSQLServer 按数据源配置打开SQL Server连接
+func openSQLServer(config map[string]interface{}) (*sql.DB, error) {
+	dsn, err := buildSQLServerDSN(config)
+	if err != nil {
+		return nil, err
+	}
+	return sql.Open(sqlServerDriver, dsn)
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildSQLServerDSN(t *testing.T) {
+	config := map[string]interface{}{
+		"host":     "10.0.0.5",
+		"port":     float64(1433),
+		"username": "sa",
+		"password": "p@ss;word",
+		"database": "EnvMonitor",
+	}
+
+	dsn, err := buildSQLServerDSN(config)
+	require.NoError(t, err)
+	u, err := url.Parse(dsn)
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.5:1433", u.Host)
+	password, _ := u.User.Password()
+	assert.Equal(t, "p@ss;word", password)
+	assert.Equal(t, "EnvMonitor", u.Query().Get("database"))
+	assert.Equal(t, "disable", u.Query().Get("encrypt"))
+
+	// 命名实例不指定端口
+	delete(config, "port")
+	config["instance"] = "SQLEXPRESS"
+	config["encrypt"] = "true"
+	dsn, err = buildSQLServerDSN(config)
+	require.NoError(t, err)
+	u, err = url.Parse(dsn)
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.5", u.Host)
+	assert.Equal(t, "/SQLEXPRESS", u.Path)
+	assert.Equal(t, "true", u.Query().Get("encrypt"))
+
+	// 未使用命名实例时端口必填
+	delete(config, "instance")
+	_, err = buildSQLServerDSN(config)
+	assert.EqualError(t, err, "数据源配置缺少port")
+}
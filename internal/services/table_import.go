@@ -0,0 +1,463 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 导入列的推断类型
+const (
+	ImportColumnInteger  = "integer"
+	ImportColumnDecimal  = "decimal"
+	ImportColumnDatetime = "datetime"
+	ImportColumnBoolean  = "boolean"
+	ImportColumnString   = "string"
+	ImportColumnText     = "text"
+)
+
+const (
+	// ImportRowColumn 导入表中记录源文件行号的列，用于对照导入失败报告
+	ImportRowColumn = "_row_no"
+	// importMaxFailures 导入报告中最多列出的失败明细数
+	importMaxFailures = 200
+	// importStringMaxLen 超过该字符数的文本列使用TEXT
+	importStringMaxLen = 255
+)
+
+// importDatetimeLayouts 类型推断支持的日期时间格式
+var importDatetimeLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/1/2 15:04:05",
+	"2006/01/02 15:04",
+	"2006/1/2 15:04",
+	"2006/01/02",
+	"2006/1/2",
+	"20060102150405",
+}
+
+// ImportColumn 导入表的列定义
+type ImportColumn struct {
+	Name     string `json:"name"`     // 表中的列名
+	Header   string `json:"header"`   // 文件中的表头
+	Type     string `json:"type"`     // 推断的类型
+	SQLType  string `json:"sql_type"` // 建表使用的MySQL类型
+	Nullable bool   `json:"nullable"` // 存在空值
+	Layout   string `json:"-"`        // 日期时间列的格式
+}
+
+// ImportFailure 导入失败的行
+type ImportFailure struct {
+	Row    int    `json:"row"`              // 文件中的行号
+	Column string `json:"column,omitempty"` // 出错的列表头
+	Value  string `json:"value,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// ImportReport 表格导入结果
+type ImportReport struct {
+	TableName        string          `json:"table_name"`
+	Columns          []ImportColumn  `json:"columns"`
+	TotalRows        int             `json:"total_rows"`
+	ImportedRows     int             `json:"imported_rows"`
+	FailedRows       int             `json:"failed_rows"`
+	Failures         []ImportFailure `json:"failures"`
+	FailuresOmitted  int             `json:"failures_omitted,omitempty"` // 超出明细上限未列出的失败行数
+	DataSourceID     uint            `json:"data_source_id,omitempty"`
+	DurationMillisec int64           `json:"duration_ms"`
+}
+
+// addFailure 记录失败行，超过明细上限时只计数
+func (r *ImportReport) addFailure(f ImportFailure) {
+	r.FailedRows++
+	if len(r.Failures) >= importMaxFailures {
+		r.FailuresOmitted++
+		return
+	}
+	r.Failures = append(r.Failures, f)
+}
+
+var (
+	importIdentPattern  = regexp.MustCompile(`[^a-z0-9_]+`)
+	importIntegerRegexp = regexp.MustCompile(`^[+-]?\d{1,18}$`)
+)
+
+// ImportTableName 由文件名生成导入表名，带file_id保证唯一，长度不超过MySQL的64字符限制
+func ImportTableName(fileName string, fileID uint) string {
+	base := fileName
+	if idx := strings.LastIndex(base, "."); idx > 0 {
+		base = base[:idx]
+	}
+	base = strings.Trim(importIdentPattern.ReplaceAllString(strings.ToLower(base), "_"), "_")
+	if len(base) > 40 {
+		base = strings.TrimRight(base[:40], "_")
+	}
+	if base == "" {
+		return fmt.Sprintf("import_%d", fileID)
+	}
+	return fmt.Sprintf("import_%d_%s", fileID, base)
+}
+
+// importColumnName 由表头生成列名，非ASCII表头（如中文）使用列序号，重名时追加序号
+func importColumnName(header string, index int, used map[string]bool) string {
+	name := strings.Trim(importIdentPattern.ReplaceAllString(strings.ToLower(strings.TrimSpace(header)), "_"), "_")
+	if len(name) > 60 {
+		name = strings.TrimRight(name[:60], "_")
+	}
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = fmt.Sprintf("col_%d", index+1)
+	}
+	candidate := name
+	for i := 2; used[candidate]; i++ {
+		candidate = fmt.Sprintf("%s_%d", name, i)
+	}
+	used[candidate] = true
+	return candidate
+}
+
+// importTypeRatio 非空值中符合某类型的比例达到该值时采用该类型，其余值作为失败行报告
+const importTypeRatio = 0.95
+
+// InferImportColumns 按非空值推断每列类型：绝大多数值为整数、小数、布尔或同一日期格式时使用对应类型，否则为文本
+func InferImportColumns(table *ImportTable) []ImportColumn {
+	used := make(map[string]bool, len(table.Headers))
+	columns := make([]ImportColumn, len(table.Headers))
+	for i, header := range table.Headers {
+		columns[i] = inferImportColumn(table, i)
+		columns[i].Header = strings.TrimSpace(strings.TrimPrefix(header, "\ufeff"))
+		columns[i].Name = importColumnName(columns[i].Header, i, used)
+	}
+	return columns
+}
+
+// inferImportColumn 推断第index列的类型
+func inferImportColumn(table *ImportTable, index int) ImportColumn {
+	var values, ints, decimals, bools, maxLen int
+	layoutCounts := make([]int, len(importDatetimeLayouts))
+	nullable, leadingZero := false, false
+
+	for _, row := range table.Rows {
+		value := ""
+		if index < len(row) {
+			value = strings.TrimSpace(row[index])
+		}
+		if value == "" {
+			nullable = true
+			continue
+		}
+		values++
+		if n := len([]rune(value)); n > maxLen {
+			maxLen = n
+		}
+
+		// 带前导零的编码（如设备MN、邮编）保持文本
+		if hasLeadingZero(value) {
+			leadingZero = true
+		}
+		if importIntegerRegexp.MatchString(value) {
+			ints++
+		}
+		if isImportDecimal(value) {
+			decimals++
+		}
+		if _, ok := parseImportBool(value); ok {
+			bools++
+		}
+		for i, layout := range importDatetimeLayouts {
+			if _, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+				layoutCounts[i]++
+			}
+		}
+	}
+
+	column := ImportColumn{Nullable: nullable}
+	if values == 0 {
+		column.Type, column.SQLType = ImportColumnString, "VARCHAR(255)"
+		return column
+	}
+
+	enough := func(count int) bool { return float64(count) >= float64(values)*importTypeRatio }
+	bestLayout := 0
+	for i, count := range layoutCounts {
+		if count > layoutCounts[bestLayout] {
+			bestLayout = i
+		}
+	}
+
+	switch {
+	case !leadingZero && enough(ints):
+		column.Type, column.SQLType = ImportColumnInteger, "BIGINT"
+	case !leadingZero && enough(decimals):
+		column.Type, column.SQLType = ImportColumnDecimal, "DOUBLE"
+	case enough(bools):
+		column.Type, column.SQLType = ImportColumnBoolean, "TINYINT(1)"
+	case enough(layoutCounts[bestLayout]):
+		column.Type, column.SQLType, column.Layout = ImportColumnDatetime, "DATETIME", importDatetimeLayouts[bestLayout]
+	case maxLen <= importStringMaxLen:
+		column.Type, column.SQLType = ImportColumnString, "VARCHAR(255)"
+	default:
+		column.Type, column.SQLType = ImportColumnText, "TEXT"
+	}
+	return column
+}
+
+// hasLeadingZero 判断是否为0开头的多位数字，如 007、0123.5
+func hasLeadingZero(value string) bool {
+	digits := strings.TrimLeft(value, "+-")
+	return len(digits) > 1 && digits[0] == '0' && digits[1] != '.'
+}
+
+// isImportDecimal 判断是否为可用DOUBLE精确保存的数值，有效数字超过15位时按文本保存避免丢失精度
+func isImportDecimal(value string) bool {
+	if _, err := strconv.ParseFloat(value, 64); err != nil {
+		return false
+	}
+	mantissa := strings.ToLower(value)
+	if idx := strings.IndexByte(mantissa, 'e'); idx >= 0 {
+		mantissa = mantissa[:idx]
+	}
+	digits := 0
+	for _, ch := range mantissa {
+		if ch >= '0' && ch <= '9' {
+			digits++
+		}
+	}
+	return digits <= 15
+}
+
+// parseImportBool 解析布尔值，支持true/false、是/否
+func parseImportBool(value string) (bool, bool) {
+	switch strings.ToLower(value) {
+	case "true", "是":
+		return true, true
+	case "false", "否":
+		return false, true
+	}
+	return false, false
+}
+
+// convertImportValue 将单元格文本转换为列类型的值，空值为NULL
+func convertImportValue(column *ImportColumn, value string) (interface{}, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	switch column.Type {
+	case ImportColumnInteger:
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("不是有效的整数")
+		}
+		return v, nil
+	case ImportColumnDecimal:
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("不是有效的数值")
+		}
+		return v, nil
+	case ImportColumnBoolean:
+		v, ok := parseImportBool(value)
+		if !ok {
+			return nil, fmt.Errorf("不是有效的布尔值")
+		}
+		return v, nil
+	case ImportColumnDatetime:
+		v, err := time.ParseInLocation(column.Layout, value, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("日期时间格式应为 %s", column.Layout)
+		}
+		return v, nil
+	case ImportColumnString:
+		if len([]rune(value)) > importStringMaxLen {
+			return nil, fmt.Errorf("超过%d个字符", importStringMaxLen)
+		}
+	}
+	return value, nil
+}
+
+// TableImporter 将解析后的表格导入数据库中的新表
+type TableImporter struct {
+	db        *gorm.DB
+	logger    *zap.Logger
+	batchSize int
+}
+
+// NewTableImporter 创建表格导入器，batchSize为每条INSERT的行数
+func NewTableImporter(db *gorm.DB, logger *zap.Logger, batchSize int) *TableImporter {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	return &TableImporter{db: db, logger: logger, batchSize: batchSize}
+}
+
+// Import 推断列类型后建表并分批导入，无法转换的行记入失败报告；
+// 整批写入失败时逐行重试以定位失败行。没有任何行导入成功时删除新建的表并返回错误
+func (i *TableImporter) Import(ctx context.Context, tableName string, table *ImportTable) (*ImportReport, error) {
+	start := time.Now()
+	report := &ImportReport{
+		TableName: tableName,
+		Columns:   InferImportColumns(table),
+		TotalRows: len(table.Rows),
+		Failures:  []ImportFailure{},
+	}
+
+	db := i.db.WithContext(ctx)
+	if err := db.Exec(createImportTableSQL(tableName, report.Columns)).Error; err != nil {
+		return nil, fmt.Errorf("创建数据表失败: %v", err)
+	}
+
+	batch := make([][]interface{}, 0, i.batchSize)
+	rowNumbers := make([]int, 0, i.batchSize)
+	for idx, row := range table.Rows {
+		rowNumber := table.RowNumbers[idx]
+		values, failure := importRowValues(report.Columns, row)
+		if failure != nil {
+			failure.Row = rowNumber
+			report.addFailure(*failure)
+			continue
+		}
+
+		batch = append(batch, append([]interface{}{rowNumber}, values...))
+		rowNumbers = append(rowNumbers, rowNumber)
+		if len(batch) >= i.batchSize {
+			if err := i.insertBatch(ctx, db, tableName, report, batch, rowNumbers); err != nil {
+				return nil, err
+			}
+			batch, rowNumbers = batch[:0], rowNumbers[:0]
+		}
+	}
+	if len(batch) > 0 {
+		if err := i.insertBatch(ctx, db, tableName, report, batch, rowNumbers); err != nil {
+			return nil, err
+		}
+	}
+
+	report.DurationMillisec = time.Since(start).Milliseconds()
+	if report.ImportedRows == 0 && report.TotalRows > 0 {
+		i.DropTable(tableName)
+		return report, fmt.Errorf("所有数据行均导入失败")
+	}
+	return report, nil
+}
+
+// insertBatch 写入一批行，失败时逐行写入；上下文取消时删除新建的表并返回错误
+func (i *TableImporter) insertBatch(ctx context.Context, db *gorm.DB, tableName string, report *ImportReport, batch [][]interface{}, rowNumbers []int) error {
+	columns := make([]string, 0, len(report.Columns)+1)
+	columns = append(columns, ImportRowColumn)
+	for _, column := range report.Columns {
+		columns = append(columns, column.Name)
+	}
+
+	if err := db.Exec(insertImportRowsSQL(tableName, columns, len(batch)), flattenRows(batch)...).Error; err == nil {
+		report.ImportedRows += len(batch)
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		i.DropTable(tableName)
+		return fmt.Errorf("导入已取消: %v", err)
+	}
+
+	for idx, values := range batch {
+		if err := db.Exec(insertImportRowsSQL(tableName, columns, 1), values...).Error; err != nil {
+			report.addFailure(ImportFailure{Row: rowNumbers[idx], Reason: fmt.Sprintf("写入失败: %v", err)})
+			continue
+		}
+		report.ImportedRows++
+	}
+	return nil
+}
+
+// DropTable 删除导入表，用于导入失败或数据源注册失败时回滚
+func (i *TableImporter) DropTable(tableName string) {
+	if err := i.db.Exec("DROP TABLE IF EXISTS " + quoteImportIdent(tableName)).Error; err != nil {
+		i.logger.Error("Failed to drop import table", zap.String("table", tableName), zap.Error(err))
+	}
+}
+
+// importRowValues 按列类型转换一行，列数超出表头或值无法转换时返回失败信息
+func importRowValues(columns []ImportColumn, row []string) ([]interface{}, *ImportFailure) {
+	for idx := len(columns); idx < len(row); idx++ {
+		if strings.TrimSpace(row[idx]) != "" {
+			return nil, &ImportFailure{Reason: fmt.Sprintf("列数%d超过表头列数%d", len(row), len(columns))}
+		}
+	}
+
+	values := make([]interface{}, len(columns))
+	for idx := range columns {
+		raw := ""
+		if idx < len(row) {
+			raw = row[idx]
+		}
+		v, err := convertImportValue(&columns[idx], raw)
+		if err != nil {
+			return nil, &ImportFailure{Column: columns[idx].Header, Value: raw, Reason: err.Error()}
+		}
+		values[idx] = v
+	}
+	return values, nil
+}
+
+// createImportTableSQL 生成导入表的建表语句，表头原文记入列注释
+func createImportTableSQL(tableName string, columns []ImportColumn) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "CREATE TABLE %s (\n  %s INT NOT NULL COMMENT '源文件行号'", quoteImportIdent(tableName), quoteImportIdent(ImportRowColumn))
+	for _, column := range columns {
+		fmt.Fprintf(&b, ",\n  %s %s NULL COMMENT '%s'", quoteImportIdent(column.Name), column.SQLType, escapeImportComment(column.Header))
+	}
+	fmt.Fprintf(&b, ",\n  PRIMARY KEY (%s)\n) DEFAULT CHARSET=utf8mb4", quoteImportIdent(ImportRowColumn))
+	return b.String()
+}
+
+// insertImportRowsSQL 生成多行INSERT语句
+func insertImportRowsSQL(tableName string, columns []string, rows int) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = quoteImportIdent(column)
+	}
+	placeholders := "(" + strings.TrimSuffix(strings.Repeat("?,", len(columns)), ",") + ")"
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "INSERT INTO %s (%s) VALUES ", quoteImportIdent(tableName), strings.Join(quoted, ","))
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(placeholders)
+	}
+	return b.String()
+}
+
+// flattenRows 展开多行参数
+func flattenRows(rows [][]interface{}) []interface{} {
+	var args []interface{}
+	for _, row := range rows {
+		args = append(args, row...)
+	}
+	return args
+}
+
+// quoteImportIdent 引用MySQL标识符，列名和表名已规范为小写字母、数字和下划线
+func quoteImportIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// escapeImportComment 转义列注释中的引号和反斜杠，并截断到MySQL注释长度上限
+func escapeImportComment(comment string) string {
+	if runes := []rune(comment); len(runes) > 255 {
+		comment = string(runes[:255])
+	}
+	comment = strings.ReplaceAll(comment, `\`, `\\`)
+	return strings.ReplaceAll(comment, "'", "''")
+}
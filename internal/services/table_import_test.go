@@ -0,0 +1,95 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportNames(t *testing.T) {
+	assert.Equal(t, "import_7_station_data_2024", ImportTableName("Station Data-2024.xlsx", 7))
+	assert.Equal(t, "import_8", ImportTableName("监测数据.csv", 8))
+
+	used := make(map[string]bool)
+	assert.Equal(t, "station_id", importColumnName(" Station ID ", 0, used))
+	assert.Equal(t, "station_id_2", importColumnName("station-id", 1, used))
+	assert.Equal(t, "col_3", importColumnName("浓度", 2, used))
+	assert.Equal(t, "col_4", importColumnName("2024", 3, used))
+	// 去掉首尾下划线后不会与行号列重名
+	assert.Equal(t, "row_no", importColumnName(ImportRowColumn, 4, used))
+}
+
+func TestInferImportColumns(t *testing.T) {
+	table := &ImportTable{
+		Headers: []string{"\ufeffcount", "value", "mn", "time", "flag", "note", "empty"},
+	}
+	for i := 0; i < 20; i++ {
+		table.Rows = append(table.Rows, []string{"12", "1.5", "0010", "2024-03-01 08:00:00", "是", "x", ""})
+	}
+	// 少量不符合类型的值不影响推断，导入时作为失败行报告
+	table.Rows = append(table.Rows, []string{"abc", "", "0011", "2024-03-01 09:00:00", "否", "y"})
+
+	columns := InferImportColumns(table)
+	require.Len(t, columns, 7)
+	assert.Equal(t, "count", columns[0].Header)
+	assert.Equal(t, ImportColumnInteger, columns[0].Type)
+	assert.Equal(t, ImportColumnDecimal, columns[1].Type)
+	assert.True(t, columns[1].Nullable)
+	assert.Equal(t, ImportColumnString, columns[2].Type)
+	assert.Equal(t, ImportColumnDatetime, columns[3].Type)
+	assert.Equal(t, "2006-01-02 15:04:05", columns[3].Layout)
+	assert.Equal(t, ImportColumnBoolean, columns[4].Type)
+	assert.Equal(t, ImportColumnString, columns[5].Type)
+	assert.Equal(t, "VARCHAR(255)", columns[6].SQLType)
+
+	// 超过15位有效数字的编号按文本保存
+	ids := &ImportTable{Headers: []string{"id"}, Rows: [][]string{{"12345678901234567890"}}}
+	assert.Equal(t, ImportColumnString, InferImportColumns(ids)[0].Type)
+}
+
+func TestImportRowValues(t *testing.T) {
+	columns := []ImportColumn{
+		{Header: "数量", Type: ImportColumnInteger},
+		{Header: "时间", Type: ImportColumnDatetime, Layout: "2006-01-02"},
+	}
+
+	values, failure := importRowValues(columns, []string{" 3 ", "2024-03-01"})
+	require.Nil(t, failure)
+	assert.Equal(t, int64(3), values[0])
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local), values[1])
+
+	// 缺少的列为NULL，多出的空列忽略
+	values, failure = importRowValues(columns, []string{"", "", ""})
+	require.Nil(t, failure)
+	assert.Equal(t, []interface{}{nil, nil}, values)
+
+	_, failure = importRowValues(columns, []string{"abc", "2024-03-01"})
+	require.NotNil(t, failure)
+	assert.Equal(t, "数量", failure.Column)
+	assert.Equal(t, "abc", failure.Value)
+
+	_, failure = importRowValues(columns, []string{"1", "2024-03-01", "extra"})
+	require.NotNil(t, failure)
+	assert.Empty(t, failure.Column)
+}
+
+func TestImportSQL(t *testing.T) {
+	columns := []ImportColumn{{Name: "mn", Header: "设备'MN", SQLType: "VARCHAR(255)"}}
+	assert.Equal(t, "CREATE TABLE `import_1` (\n  `_row_no` INT NOT NULL COMMENT '源文件行号',\n"+
+		"  `mn` VARCHAR(255) NULL COMMENT '设备''MN',\n  PRIMARY KEY (`_row_no`)\n) DEFAULT CHARSET=utf8mb4",
+		createImportTableSQL("import_1", columns))
+	assert.Equal(t, "INSERT INTO `import_1` (`_row_no`,`mn`) VALUES (?,?),(?,?)",
+		insertImportRowsSQL("import_1", []string{ImportRowColumn, "mn"}, 2))
+}
+
+func TestImportReportFailures(t *testing.T) {
+	report := &ImportReport{}
+	for i := 0; i < importMaxFailures+5; i++ {
+		report.addFailure(ImportFailure{Row: i})
+	}
+	assert.Equal(t, importMaxFailures+5, report.FailedRows)
+	assert.Len(t, report.Failures, importMaxFailures)
+	assert.Equal(t, 5, report.FailuresOmitted)
+}
@@ -0,0 +1,416 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// ImportTable 从CSV或Excel解析出的表格，Rows不含表头，RowNumbers为每行在文件中的行号
+type ImportTable struct {
+	Headers    []string
+	Rows       [][]string
+	RowNumbers []int
+}
+
+// errTooManyRows 表格行数超过导入上限
+var errTooManyRows = errors.New("too many rows")
+
+// ReadImportTable 按扩展名解析CSV或XLSX，第一行为表头，跳过全空行；maxRows<=0表示不限制
+func ReadImportTable(data []byte, ext string, maxRows int) (*ImportTable, error) {
+	var (
+		table *ImportTable
+		err   error
+	)
+	switch strings.ToLower(ext) {
+	case ".csv", ".txt":
+		table, err = readCSVTable(data, maxRows)
+	case ".xlsx":
+		table, err = readXLSXTable(data, maxRows)
+	case ".xls":
+		return nil, fmt.Errorf("不支持旧版Excel(.xls)文件，请另存为.xlsx或CSV后导入")
+	default:
+		return nil, fmt.Errorf("不支持的文件格式: %s，仅支持CSV和XLSX", ext)
+	}
+	if errors.Is(err, errTooManyRows) {
+		return nil, fmt.Errorf("数据行数超过导入上限 %d", maxRows)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(table.Headers) == 0 {
+		return nil, fmt.Errorf("文件没有表头")
+	}
+	return table, nil
+}
+
+// add 追加一行，第一行作为表头
+func (t *ImportTable) add(rowNumber int, values []string, maxRows int) error {
+	if isBlankRow(values) {
+		return nil
+	}
+	if t.Headers == nil {
+		t.Headers = values
+		return nil
+	}
+	if maxRows > 0 && len(t.Rows) >= maxRows {
+		return errTooManyRows
+	}
+	t.Rows = append(t.Rows, values)
+	t.RowNumbers = append(t.RowNumbers, rowNumber)
+	return nil
+}
+
+// isBlankRow 判断所有单元格是否为空
+func isBlankRow(values []string) bool {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// readCSVTable 解析CSV，去除UTF-8 BOM；内容不是合法UTF-8时按GB18030解码（Excel中文版另存的CSV）
+func readCSVTable(data []byte, maxRows int) (*ImportTable, error) {
+	data = bytes.TrimPrefix(data, utf8BOM)
+	if !utf8.Valid(data) {
+		decoded, err := simplifiedchinese.GB18030.NewDecoder().Bytes(data)
+		if err != nil {
+			return nil, fmt.Errorf("文件编码无法识别，请保存为UTF-8编码的CSV")
+		}
+		data = decoded
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	table := &ImportTable{}
+	for {
+		values, err := reader.Read()
+		if err == io.EOF {
+			return table, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析CSV失败: %v", err)
+		}
+		line, _ := reader.FieldPos(0)
+		if err := table.add(line, values, maxRows); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// xlsx中用到的XML结构
+type (
+	xlsxRels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	xlsxWorkbookSheets struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	xlsxSharedStrings struct {
+		Items []xlsxRichText `xml:"si"`
+	}
+	xlsxRichText struct {
+		Text string `xml:"t"`
+		Runs []struct {
+			Text string `xml:"t"`
+		} `xml:"r"`
+	}
+	xlsxStyles struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		CellXfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	xlsxCell struct {
+		Ref    string        `xml:"r,attr"`
+		Type   string        `xml:"t,attr"`
+		Style  int           `xml:"s,attr"`
+		Value  string        `xml:"v"`
+		Inline *xlsxRichText `xml:"is"`
+	}
+	xlsxRow struct {
+		Number int        `xml:"r,attr"`
+		Cells  []xlsxCell `xml:"c"`
+	}
+)
+
+// String 富文本单元格的纯文本
+func (t *xlsxRichText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var b strings.Builder
+	for _, r := range t.Runs {
+		b.WriteString(r.Text)
+	}
+	return b.String()
+}
+
+// readXLSXTable 解析XLSX的第一个工作表，单元格取原始值，日期格式的单元格转换为日期时间文本
+func readXLSXTable(data []byte, maxRows int) (*ImportTable, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("文件不是有效的XLSX: %v", err)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var shared xlsxSharedStrings
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodeZipXML(f, &shared); err != nil {
+			return nil, fmt.Errorf("解析共享字符串失败: %v", err)
+		}
+	}
+
+	var dateStyles map[int]bool
+	if f, ok := files["xl/styles.xml"]; ok {
+		var styles xlsxStyles
+		if err := decodeZipXML(f, &styles); err != nil {
+			return nil, fmt.Errorf("解析样式失败: %v", err)
+		}
+		dateStyles = xlsxDateStyles(&styles)
+	}
+
+	sheet, ok := files[sheetPath]
+	if !ok {
+		return nil, fmt.Errorf("XLSX中找不到工作表 %s", sheetPath)
+	}
+	rc, err := sheet.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	table := &ImportTable{}
+	decoder := xml.NewDecoder(rc)
+	lastRow := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return table, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析工作表失败: %v", err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+
+		var row xlsxRow
+		if err := decoder.DecodeElement(&row, &start); err != nil {
+			return nil, fmt.Errorf("解析工作表失败: %v", err)
+		}
+		if row.Number == 0 {
+			row.Number = lastRow + 1
+		}
+		lastRow = row.Number
+
+		values, err := xlsxRowValues(&row, shared.Items, dateStyles)
+		if err != nil {
+			return nil, fmt.Errorf("第%d行: %v", row.Number, err)
+		}
+		if err := table.add(row.Number, values, maxRows); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// firstSheetPath 按workbook.xml中的顺序找到第一个工作表的路径
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	var workbook xlsxWorkbookSheets
+	f, ok := files["xl/workbook.xml"]
+	if !ok {
+		return "", fmt.Errorf("文件不是有效的XLSX: 缺少workbook.xml")
+	}
+	if err := decodeZipXML(f, &workbook); err != nil {
+		return "", fmt.Errorf("解析workbook失败: %v", err)
+	}
+	if len(workbook.Sheets) == 0 {
+		return "", fmt.Errorf("XLSX中没有工作表")
+	}
+
+	var rels xlsxRels
+	if f, ok := files["xl/_rels/workbook.xml.rels"]; ok {
+		if err := decodeZipXML(f, &rels); err != nil {
+			return "", fmt.Errorf("解析workbook关系失败: %v", err)
+		}
+	}
+	for _, rel := range rels.Relationships {
+		if rel.ID != workbook.Sheets[0].RID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+	return "xl/worksheets/sheet1.xml", nil
+}
+
+// decodeZipXML 解码压缩包中的XML文件
+func decodeZipXML(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
+
+// xlsxRowValues 按单元格引用的列号展开一行，缺失的单元格为空
+func xlsxRowValues(row *xlsxRow, shared []xlsxRichText, dateStyles map[int]bool) ([]string, error) {
+	var values []string
+	next := 0
+	for _, cell := range row.Cells {
+		col := next
+		if cell.Ref != "" {
+			var err error
+			if col, err = xlsxColumnIndex(cell.Ref); err != nil {
+				return nil, err
+			}
+		}
+		for len(values) < col {
+			values = append(values, "")
+		}
+
+		value := cell.Value
+		switch cell.Type {
+		case "s":
+			idx, err := strconv.Atoi(cell.Value)
+			if err != nil || idx < 0 || idx >= len(shared) {
+				return nil, fmt.Errorf("单元格%s的共享字符串索引无效", cell.Ref)
+			}
+			value = shared[idx].String()
+		case "inlineStr":
+			if cell.Inline != nil {
+				value = cell.Inline.String()
+			}
+		case "b":
+			if value == "1" {
+				value = "true"
+			} else {
+				value = "false"
+			}
+		case "", "n":
+			if dateStyles[cell.Style] {
+				value = xlsxDateValue(value)
+			}
+		}
+		values = append(values, value)
+		next = col + 1
+	}
+	return values, nil
+}
+
+// xlsxColumnIndex 单元格引用（如AB12）的列序号，从0开始
+func xlsxColumnIndex(ref string) (int, error) {
+	col := 0
+	n := 0
+	for _, ch := range ref {
+		if ch < 'A' || ch > 'Z' {
+			break
+		}
+		col = col*26 + int(ch-'A'+1)
+		n++
+	}
+	if n == 0 || n > 3 {
+		return 0, fmt.Errorf("单元格引用无效: %s", ref)
+	}
+	return col - 1, nil
+}
+
+// xlsxDateStyles 数字格式为日期时间的单元格样式序号
+func xlsxDateStyles(styles *xlsxStyles) map[int]bool {
+	custom := make(map[int]string, len(styles.NumFmts))
+	for _, f := range styles.NumFmts {
+		custom[f.ID] = f.Code
+	}
+
+	dates := make(map[int]bool)
+	for i, xf := range styles.CellXfs {
+		id := xf.NumFmtID
+		if (id >= 14 && id <= 22) || (id >= 45 && id <= 47) {
+			dates[i] = true
+			continue
+		}
+		if code, ok := custom[id]; ok && isDateFormatCode(code) {
+			dates[i] = true
+		}
+	}
+	return dates
+}
+
+// isDateFormatCode 自定义数字格式是否为日期时间：去掉引号内文字、转义字符和颜色等方括号段后含年月日时分秒占位符
+func isDateFormatCode(code string) bool {
+	var b strings.Builder
+	inQuote, inBracket := false, false
+	for i := 0; i < len(code); i++ {
+		ch := code[i]
+		switch {
+		case ch == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case ch == '\\' || ch == '_' || ch == '*':
+			i++
+		case ch == '[':
+			inBracket = true
+		case ch == ']':
+			inBracket = false
+		case !inBracket:
+			b.WriteByte(ch)
+		}
+	}
+	return strings.ContainsAny(strings.ToLower(b.String()), "ymdhs")
+}
+
+// xlsxDateValue Excel日期序列号（1900日期系统）转换为日期时间文本，整数只保留日期
+func xlsxDateValue(value string) string {
+	serial, err := strconv.ParseFloat(value, 64)
+	if err != nil || serial < 0 {
+		return value
+	}
+	seconds := int64(serial*86400 + 0.5)
+	t := excelEpoch.Add(time.Duration(seconds) * time.Second)
+	if seconds%86400 == 0 {
+		return t.Format("2006-01-02")
+	}
+	if serial < 1 {
+		return t.Format("15:04:05")
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// excelEpoch 1900日期系统的零点，已包含Excel将1900年视为闰年的偏差
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func TestReadImportTableCSV(t *testing.T) {
+	data := append(append([]byte{}, utf8BOM...), []byte("站点,浓度\nA01,1.5\n,\nA02,2\n")...)
+	table, err := ReadImportTable(data, ".CSV", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"站点", "浓度"}, table.Headers)
+	// 全空行跳过，行号对应文件中的行
+	assert.Equal(t, [][]string{{"A01", "1.5"}, {"A02", "2"}}, table.Rows)
+	assert.Equal(t, []int{2, 4}, table.RowNumbers)
+
+	// Excel中文版另存的GBK编码CSV
+	gbk, err := simplifiedchinese.GBK.NewEncoder().Bytes([]byte("站点,浓度\n北京,1\n"))
+	require.NoError(t, err)
+	table, err = ReadImportTable(gbk, ".csv", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"站点", "浓度"}, table.Headers)
+	assert.Equal(t, "北京", table.Rows[0][0])
+
+	_, err = ReadImportTable([]byte("a\n1\n2\n3\n"), ".csv", 2)
+	assert.EqualError(t, err, "数据行数超过导入上限 2")
+
+	_, err = ReadImportTable([]byte("\n\n"), ".csv", 0)
+	assert.Error(t, err)
+
+	_, err = ReadImportTable([]byte("x"), ".xls", 0)
+	assert.Error(t, err)
+}
+
+func TestReadImportTableXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := newXLSXWriter(&buf)
+	require.NoError(t, err)
+	require.NoError(t, w.WriteRow([]string{"mn", "value", "remark"}))
+	require.NoError(t, w.WriteRow([]string{"0010", "3.5", ""}))
+	require.NoError(t, w.WriteRow([]string{"0011", "", "备注"}))
+	require.NoError(t, w.Close())
+
+	table, err := ReadImportTable(buf.Bytes(), ".xlsx", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mn", "value", "remark"}, table.Headers)
+	assert.Equal(t, [][]string{{"0010", "3.5", ""}, {"0011", "", "备注"}}, table.Rows)
+	assert.Equal(t, []int{2, 3}, table.RowNumbers)
+}
+
+func TestXLSXRowValues(t *testing.T) {
+	shared := []xlsxRichText{{Text: "名称"}, {Runs: []struct {
+		Text string `xml:"t"`
+	}{{Text: "富"}, {Text: "文本"}}}}
+	row := &xlsxRow{Number: 2, Cells: []xlsxCell{
+		{Ref: "A2", Type: "s", Value: "1"},
+		{Ref: "C2", Value: "45352", Style: 1},
+		{Ref: "D2", Value: "45352.5", Style: 1},
+		{Ref: "E2", Type: "b", Value: "1"},
+		{Ref: "F2", Value: "12.5"},
+	}}
+
+	values, err := xlsxRowValues(row, shared, map[int]bool{1: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"富文本", "", "2024-03-01", "2024-03-01 12:00:00", "true", "12.5"}, values)
+
+	_, err = xlsxRowValues(&xlsxRow{Cells: []xlsxCell{{Ref: "A1", Type: "s", Value: "9"}}}, shared, nil)
+	assert.Error(t, err)
+}
+
+func TestIsDateFormatCode(t *testing.T) {
+	assert.True(t, isDateFormatCode("yyyy-mm-dd"))
+	assert.True(t, isDateFormatCode(`[$-F800]dddd\,\ mmmm\ dd\,\ yyyy`))
+	assert.False(t, isDateFormatCode("0.00"))
+	assert.False(t, isDateFormatCode(`[Red]0.00;"days"`))
+	assert.False(t, isDateFormatCode(`#,##0_);[Red]\(#,##0\)`))
+}
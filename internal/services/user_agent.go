@@ -0,0 +1,165 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/env-data-platform/internal/models"
+)
+
+// UserAgentInfo 从User-Agent解析出的终端信息
+type UserAgentInfo struct {
+	DeviceType string // 设备类型，取值见models.LoginDevice*
+	OS         string // 操作系统及版本，如 Windows 10、iOS 17.1
+	Browser    string // 浏览器或客户端及主版本号，如 Chrome 120
+}
+
+// uaClients 非浏览器客户端的User-Agent前缀及名称
+var uaClients = []struct {
+	prefix string
+	name   string
+}{
+	{"curl/", "curl"},
+	{"wget/", "Wget"},
+	{"python-requests/", "python-requests"},
+	{"python-urllib/", "Python urllib"},
+	{"go-http-client/", "Go http client"},
+	{"postmanruntime/", "Postman"},
+	{"apifox/", "Apifox"},
+	{"okhttp/", "OkHttp"},
+	{"apache-httpclient/", "Apache HttpClient"},
+	{"java/", "Java"},
+}
+
+// uaBrowsers 浏览器识别规则，按顺序匹配：基于Chromium的浏览器和内嵌浏览器需排在Chrome之前
+var uaBrowsers = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"WeChat", regexp.MustCompile(`MicroMessenger/(\d+)`)},
+	{"DingTalk", regexp.MustCompile(`DingTalk/(\d+)`)},
+	{"Edge", regexp.MustCompile(`Edg(?:e|A|iOS)?/(\d+)`)},
+	{"Opera", regexp.MustCompile(`(?:OPR|Opera)/(\d+)`)},
+	{"QQ Browser", regexp.MustCompile(`QQBrowser/(\d+)`)},
+	{"UC Browser", regexp.MustCompile(`UCBrowser/(\d+)`)},
+	{"360 Browser", regexp.MustCompile(`QihooBrowser/(\d+)|360SE()`)},
+	{"Firefox", regexp.MustCompile(`(?:Firefox|FxiOS)/(\d+)`)},
+	{"Chrome", regexp.MustCompile(`(?:Chrome|CriOS)/(\d+)`)},
+	{"Safari", regexp.MustCompile(`Version/(\d+)[\d.]* (?:Mobile/\S+ )?Safari/`)},
+	{"IE", regexp.MustCompile(`MSIE (\d+)|Trident/.*rv:(\d+)`)},
+}
+
+var (
+	uaWindowsPattern = regexp.MustCompile(`Windows NT (\d+\.\d+)`)
+	uaIOSPattern     = regexp.MustCompile(`(?:iPhone|CPU) OS (\d+)(?:_(\d+))?`)
+	uaAndroidPattern = regexp.MustCompile(`Android (\d+(?:\.\d+)?)`)
+	uaHarmonyPattern = regexp.MustCompile(`HarmonyOS[ /]?(\d+(?:\.\d+)?)?`)
+	uaMacPattern     = regexp.MustCompile(`Mac OS X (\d+)[_.](\d+)`)
+)
+
+// windowsVersions Windows NT内核版本对应的发行版，Windows 11仍报告NT 10.0
+var windowsVersions = map[string]string{
+	"10.0": "10",
+	"6.3":  "8.1",
+	"6.2":  "8",
+	"6.1":  "7",
+	"6.0":  "Vista",
+	"5.1":  "XP",
+}
+
+// ParseUserAgent 按常见特征解析User-Agent，无法识别的部分留空，设备类型为unknown
+func ParseUserAgent(ua string) UserAgentInfo {
+	ua = strings.TrimSpace(ua)
+	if ua == "" {
+		return UserAgentInfo{DeviceType: models.LoginDeviceUnknown}
+	}
+
+	lower := strings.ToLower(ua)
+	for _, client := range uaClients {
+		if strings.HasPrefix(lower, client.prefix) {
+			return UserAgentInfo{DeviceType: models.LoginDeviceClient, Browser: client.name}
+		}
+	}
+	if strings.Contains(lower, "bot") || strings.Contains(lower, "spider") || strings.Contains(lower, "crawler") {
+		return UserAgentInfo{DeviceType: models.LoginDeviceBot}
+	}
+
+	return UserAgentInfo{
+		DeviceType: uaDeviceType(ua, lower),
+		OS:         uaOS(ua),
+		Browser:    uaBrowser(ua),
+	}
+}
+
+// uaDeviceType 区分平板、手机和桌面设备
+func uaDeviceType(ua, lower string) string {
+	switch {
+	case strings.Contains(ua, "iPad") || strings.Contains(lower, "tablet"):
+		return models.LoginDeviceTablet
+	case strings.Contains(ua, "Android") && !strings.Contains(ua, "Mobile"):
+		// Android平板的User-Agent不含Mobile
+		return models.LoginDeviceTablet
+	case strings.Contains(ua, "Mobile") || strings.Contains(ua, "iPhone") || strings.Contains(ua, "Android"):
+		return models.LoginDeviceMobile
+	case strings.Contains(ua, "Windows") || strings.Contains(ua, "Macintosh") ||
+		strings.Contains(ua, "Linux") || strings.Contains(ua, "CrOS"):
+		return models.LoginDeviceDesktop
+	default:
+		return models.LoginDeviceUnknown
+	}
+}
+
+// uaOS 解析操作系统及版本
+func uaOS(ua string) string {
+	if m := uaHarmonyPattern.FindStringSubmatch(ua); m != nil {
+		return strings.TrimSpace("HarmonyOS " + m[1])
+	}
+	if m := uaWindowsPattern.FindStringSubmatch(ua); m != nil {
+		if name, ok := windowsVersions[m[1]]; ok {
+			return "Windows " + name
+		}
+		return "Windows"
+	}
+	if strings.Contains(ua, "iPhone") || strings.Contains(ua, "iPad") || strings.Contains(ua, "iPod") {
+		// iOS的User-Agent同时包含like Mac OS X，需先于macOS判断
+		m := uaIOSPattern.FindStringSubmatch(ua)
+		switch {
+		case m == nil:
+			return "iOS"
+		case m[2] != "":
+			return "iOS " + m[1] + "." + m[2]
+		default:
+			return "iOS " + m[1]
+		}
+	}
+	if m := uaAndroidPattern.FindStringSubmatch(ua); m != nil {
+		return "Android " + m[1]
+	}
+	if m := uaMacPattern.FindStringSubmatch(ua); m != nil {
+		return "macOS " + m[1] + "." + m[2]
+	}
+	switch {
+	case strings.Contains(ua, "CrOS"):
+		return "ChromeOS"
+	case strings.Contains(ua, "Linux"):
+		return "Linux"
+	}
+	return ""
+}
+
+// uaBrowser 解析浏览器及主版本号
+func uaBrowser(ua string) string {
+	for _, browser := range uaBrowsers {
+		m := browser.pattern.FindStringSubmatch(ua)
+		if m == nil {
+			continue
+		}
+		for _, version := range m[1:] {
+			if version != "" {
+				return browser.name + " " + version
+			}
+		}
+		return browser.name
+	}
+	return ""
+}
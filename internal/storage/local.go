@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Local 本地磁盘存储
+type Local struct {
+	root string
+}
+
+// NewLocal 创建本地存储，目录在写入时按需创建
+func NewLocal(root string) *Local {
+	return &Local{root: root}
+}
+
+// Type 存储后端类型
+func (l *Local) Type() string {
+	return TypeLocal
+}
+
+// path 将key解析为root下的路径，key中的..无法越出root
+func (l *Local) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if clean == "/" {
+		return "", fmt.Errorf("无效的对象key: %s", key)
+	}
+	return filepath.Join(l.root, clean), nil
+}
+
+// Put 写入文件，先写临时文件再重命名
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	target, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+// Get 打开文件
+func (l *Local) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	target, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(target)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Delete 删除文件
+func (l *Local) Delete(ctx context.Context, key string) error {
+	target, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// PresignGet 本地存储不支持预签名URL
+func (l *Local) PresignGet(ctx context.Context, key, filename string, expire time.Duration) (string, error) {
+	return "", ErrPresignNotSupported
+}
+
+// Locate 返回文件路径
+func (l *Local) Locate(key string) string {
+	target, err := l.path(key)
+	if err != nil {
+		return key
+	}
+	return target
+}
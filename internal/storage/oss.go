@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/env-data-platform/internal/config"
+)
+
+// OSS 阿里云对象存储，使用OSS V1签名
+type OSS struct {
+	cfg    config.OSSStorageConfig
+	scheme string
+	host   string
+	now    func() time.Time
+}
+
+// NewOSS 创建阿里云OSS存储
+func NewOSS(cfg config.OSSStorageConfig) (*OSS, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("OSS存储需要配置endpoint和bucket")
+	}
+
+	scheme := "http"
+	if cfg.UseSSL {
+		scheme = "https"
+	}
+	endpoint := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(cfg.Endpoint, "https://"), "http://"), "/")
+
+	return &OSS{cfg: cfg, scheme: scheme, host: cfg.Bucket + "." + endpoint, now: time.Now}, nil
+}
+
+// Type 存储后端类型
+func (o *OSS) Type() string {
+	return TypeOSS
+}
+
+// Put 上传对象
+func (o *OSS) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	req, err := o.newRequest(ctx, http.MethodPut, key, r)
+	if err != nil {
+		return err
+	}
+	if size >= 0 {
+		req.ContentLength = size
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return o.do(req, key, nil)
+}
+
+// Get 下载对象
+func (o *OSS) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := o.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	var body io.ReadCloser
+	if err := o.do(req, key, &body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// Delete 删除对象
+func (o *OSS) Delete(ctx context.Context, key string) error {
+	req, err := o.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	err = o.do(req, key, nil)
+	if err == ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+// PresignGet 生成预签名下载地址
+func (o *OSS) PresignGet(ctx context.Context, key, filename string, expire time.Duration) (string, error) {
+	expires := strconv.FormatInt(o.now().Add(expire).Unix(), 10)
+
+	// 签名中的子资源不做URL编码
+	resource := o.canonicalResource(key)
+	query := url.Values{}
+	if filename != "" {
+		disposition := contentDisposition(filename)
+		resource += "?response-content-disposition=" + disposition
+		query.Set("response-content-disposition", disposition)
+	}
+
+	stringToSign := http.MethodGet + "\n\n\n" + expires + "\n" + resource
+	query.Set("OSSAccessKeyId", o.cfg.AccessKeyID)
+	query.Set("Expires", expires)
+	query.Set("Signature", o.sign(stringToSign))
+
+	return fmt.Sprintf("%s://%s/%s?%s", o.scheme, o.host, encodeURIComponent(o.objectKey(key), true), query.Encode()), nil
+}
+
+// Locate 返回oss://bucket/key
+func (o *OSS) Locate(key string) string {
+	return "oss://" + o.cfg.Bucket + "/" + o.objectKey(key)
+}
+
+// objectKey 加上前缀的对象key
+func (o *OSS) objectKey(key string) string {
+	return joinKey(o.cfg.Prefix, key)
+}
+
+// canonicalResource 签名使用的资源路径
+func (o *OSS) canonicalResource(key string) string {
+	return "/" + o.cfg.Bucket + "/" + o.objectKey(key)
+}
+
+// newRequest 创建请求，签名在do中完成
+func (o *OSS) newRequest(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, o.scheme+"://"+o.host, body)
+	if err != nil {
+		return nil, err
+	}
+	req.URL.Opaque = "/" + encodeURIComponent(o.objectKey(key), true)
+	return req, nil
+}
+
+// do 签名并发送请求，body非nil时返回响应体
+func (o *OSS) do(req *http.Request, key string, body *io.ReadCloser) error {
+	date := o.now().UTC().Format(http.TimeFormat)
+	req.Header.Set("Date", date)
+
+	stringToSign := strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		date,
+		o.canonicalResource(key),
+	}, "\n")
+	req.Header.Set("Authorization", "OSS "+o.cfg.AccessKeyID+":"+o.sign(stringToSign))
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求对象存储失败: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return responseError(resp)
+	}
+	if body != nil {
+		*body = resp.Body
+		return nil
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// sign 计算HMAC-SHA1签名
+func (o *OSS) sign(stringToSign string) string {
+	mac := hmac.New(sha1.New, []byte(o.cfg.AccessKeySecret))
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
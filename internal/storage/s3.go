@@ -0,0 +1,238 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/env-data-platform/internal/config"
+)
+
+const (
+	s3Algorithm    = "AWS4-HMAC-SHA256"
+	s3UnsignedBody = "UNSIGNED-PAYLOAD"
+	s3TimeFormat   = "20060102T150405Z"
+	s3DateFormat   = "20060102"
+)
+
+// S3 兼容S3协议的对象存储（AWS S3、MinIO等），使用SigV4签名
+type S3 struct {
+	cfg    config.S3StorageConfig
+	scheme string
+	host   string
+	now    func() time.Time
+}
+
+// NewS3 创建S3存储
+func NewS3(cfg config.S3StorageConfig) (*S3, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("S3存储需要配置endpoint和bucket")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+
+	scheme := "http"
+	if cfg.UseSSL {
+		scheme = "https"
+	}
+	host := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(cfg.Endpoint, "https://"), "http://"), "/")
+	if !cfg.PathStyle {
+		host = cfg.Bucket + "." + host
+	}
+
+	return &S3{cfg: cfg, scheme: scheme, host: host, now: time.Now}, nil
+}
+
+// Type 存储后端类型
+func (s *S3) Type() string {
+	return TypeS3
+}
+
+// objectPath 对象的请求路径（已编码）
+func (s *S3) objectPath(key string) string {
+	path := "/" + encodeURIComponent(joinKey(s.cfg.Prefix, key), true)
+	if s.cfg.PathStyle {
+		path = "/" + s.cfg.Bucket + path
+	}
+	return path
+}
+
+// Put 上传对象
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	req, err := s.newRequest(ctx, http.MethodPut, key, r)
+	if err != nil {
+		return err
+	}
+	if size >= 0 {
+		req.ContentLength = size
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return s.do(req, nil)
+}
+
+// Get 下载对象
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	var body io.ReadCloser
+	if err := s.do(req, &body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// Delete 删除对象
+func (s *S3) Delete(ctx context.Context, key string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	err = s.do(req, nil)
+	if err == ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+// PresignGet 生成预签名下载地址
+func (s *S3) PresignGet(ctx context.Context, key, filename string, expire time.Duration) (string, error) {
+	now := s.now().UTC()
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", s3Algorithm)
+	query.Set("X-Amz-Credential", s.cfg.AccessKey+"/"+s.credentialScope(now))
+	query.Set("X-Amz-Date", now.Format(s3TimeFormat))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expire.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+	if filename != "" {
+		query.Set("response-content-disposition", contentDisposition(filename))
+	}
+
+	path := s.objectPath(key)
+	canonicalQuery := canonicalQueryString(query)
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet, path, canonicalQuery, "host:" + s.host + "\n", "host", s3UnsignedBody,
+	}, "\n")
+	signature := s.sign(now, canonicalRequest)
+
+	return fmt.Sprintf("%s://%s%s?%s&X-Amz-Signature=%s", s.scheme, s.host, path, canonicalQuery, signature), nil
+}
+
+// Locate 返回s3://bucket/key
+func (s *S3) Locate(key string) string {
+	return "s3://" + s.cfg.Bucket + "/" + joinKey(s.cfg.Prefix, key)
+}
+
+// newRequest 创建请求，签名在do中完成
+func (s *S3) newRequest(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.scheme+"://"+s.host, body)
+	if err != nil {
+		return nil, err
+	}
+	req.URL.Opaque = s.objectPath(key)
+	return req, nil
+}
+
+// do 签名并发送请求，body非nil时返回响应体
+func (s *S3) do(req *http.Request, body *io.ReadCloser) error {
+	s.signRequest(req)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求对象存储失败: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return responseError(resp)
+	}
+	if body != nil {
+		*body = resp.Body
+		return nil
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// signRequest 为请求添加SigV4 Authorization头，负载不参与签名
+func (s *S3) signRequest(req *http.Request) {
+	now := s.now().UTC()
+	req.Header.Set("X-Amz-Date", now.Format(s3TimeFormat))
+	req.Header.Set("X-Amz-Content-Sha256", s3UnsignedBody)
+
+	signedHeaders := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	var canonicalHeaders strings.Builder
+	for _, name := range signedHeaders {
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = s.host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.Opaque,
+		canonicalQueryString(req.URL.Query()),
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		s3UnsignedBody,
+	}, "\n")
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.cfg.AccessKey, s.credentialScope(now), strings.Join(signedHeaders, ";"), s.sign(now, canonicalRequest)))
+}
+
+// credentialScope 签名凭证范围
+func (s *S3) credentialScope(t time.Time) string {
+	return t.Format(s3DateFormat) + "/" + s.cfg.Region + "/s3/aws4_request"
+}
+
+// sign 计算规范请求的签名
+func (s *S3) sign(t time.Time, canonicalRequest string) string {
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		s3Algorithm, t.Format(s3TimeFormat), s.credentialScope(t), hex.EncodeToString(hash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), t.Format(s3DateFormat))
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQueryString 按参数名排序并按RFC 3986编码查询参数
+func canonicalQueryString(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, key := range keys {
+		for _, value := range query[key] {
+			parts = append(parts, encodeURIComponent(key, false)+"="+encodeURIComponent(value, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/env-data-platform/internal/config"
+)
+
+// 存储后端类型
+const (
+	TypeLocal = "local"
+	TypeS3    = "s3"
+	TypeOSS   = "oss"
+)
+
+var (
+	// ErrNotFound 对象不存在
+	ErrNotFound = errors.New("object not found")
+	// ErrPresignNotSupported 存储后端不支持预签名URL
+	ErrPresignNotSupported = errors.New("presigned url not supported")
+)
+
+// Storage 文件存储接口，key为对象在后端中的相对路径
+type Storage interface {
+	// Type 存储后端类型
+	Type() string
+	// Put 写入对象，size未知时传-1
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get 读取对象，调用方负责关闭
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 删除对象，对象不存在时不报错
+	Delete(ctx context.Context, key string) error
+	// PresignGet 生成限时下载地址，filename非空时下载为该文件名
+	PresignGet(ctx context.Context, key, filename string, expire time.Duration) (string, error)
+	// Locate 对象的可读位置描述，记录在文件记录中
+	Locate(key string) string
+}
+
+// New 根据配置创建存储后端，本地模式的根目录为localDir
+func New(cfg config.StorageConfig, localDir string) (Storage, error) {
+	switch cfg.Type {
+	case "", TypeLocal:
+		return NewLocal(localDir), nil
+	case TypeS3:
+		return NewS3(cfg.S3)
+	case TypeOSS:
+		return NewOSS(cfg.OSS)
+	default:
+		return nil, fmt.Errorf("不支持的存储类型: %s", cfg.Type)
+	}
+}
+
+// httpClient 对象存储请求使用的HTTP客户端，超时由调用方context控制
+var httpClient = &http.Client{}
+
+// responseError 将对象存储的非2xx响应转换为错误
+func responseError(resp *http.Response) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return fmt.Errorf("对象存储返回HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+// contentDisposition 生成附件下载的Content-Disposition
+func contentDisposition(filename string) string {
+	return fmt.Sprintf("attachment; filename*=UTF-8''%s", encodeURIComponent(filename, false))
+}
+
+// encodeURIComponent 按RFC 3986编码，仅保留非保留字符；keepSlash为true时保留'/'
+func encodeURIComponent(s string, keepSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if (ch >= 'A' && ch <= 'Z') || (ch >= 'a' && ch <= 'z') || (ch >= '0' && ch <= '9') ||
+			ch == '-' || ch == '_' || ch == '.' || ch == '~' || (keepSlash && ch == '/') {
+			b.WriteByte(ch)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", ch)
+	}
+	return b.String()
+}
+
+// joinKey 拼接对象前缀和key
+func joinKey(prefix, key string) string {
+	prefix = strings.Trim(prefix, "/")
+	key = strings.TrimLeft(key, "/")
+	if prefix == "" {
+		return key
+	}
+	return prefix + "/" + key
+}
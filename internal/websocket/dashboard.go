@@ -0,0 +1,338 @@
+package websocket
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/env-data-platform/internal/config"
+)
+
+// DashboardChannel 仪表板推送频道
+const DashboardChannel = "dashboard"
+
+// 仪表板推送内容项
+const (
+	DashboardCoreStats     = "core_stats"
+	DashboardLatestAlerts  = "latest_alerts"
+	DashboardETLTaskStatus = "etl_task_status"
+)
+
+// 推送触发原因，数据变化来源的其他取值见services.DashboardTrigger*
+const (
+	DashboardTriggerSubscribe = "subscribe" // 订阅后的首次推送
+	DashboardTriggerInterval  = "interval"  // 按订阅频率定时推送
+	DashboardTriggerAlarm     = "alarm"     // 产生新告警
+)
+
+// dashboardContents 全部推送内容项，订阅未指定内容时推送全部
+var dashboardContents = []string{DashboardCoreStats, DashboardLatestAlerts, DashboardETLTaskStatus}
+
+// dashboardTickInterval 检查定时推送是否到期的间隔
+const dashboardTickInterval = time.Second
+
+// DashboardScope 仪表板订阅者可见的设备范围，由连接时的登录用户决定
+type DashboardScope struct {
+	Unrestricted bool     // 不限制数据范围
+	DeviceIDs    []string // 可见设备MN
+	Regions      []string // 可见区域
+}
+
+// key 数据范围的标识，范围相同的订阅者共用一次快照
+func (s DashboardScope) key() string {
+	if s.Unrestricted {
+		return "*"
+	}
+	ids := append([]string(nil), s.DeviceIDs...)
+	regions := append([]string(nil), s.Regions...)
+	sort.Strings(ids)
+	sort.Strings(regions)
+	return strings.Join(ids, ",") + "|" + strings.Join(regions, ",")
+}
+
+// DashboardSource 按数据范围和内容项生成仪表板快照，返回内容项到数据的映射
+type DashboardSource func(scope DashboardScope, contents []string) map[string]interface{}
+
+// DashboardUpdate 推送给客户端的仪表板数据
+type DashboardUpdate struct {
+	Trigger string                 `json:"trigger"` // 推送原因
+	Content map[string]interface{} `json:"content"` // 按订阅内容项组织的数据
+}
+
+// dashboardSubscription 客户端的仪表板订阅
+type dashboardSubscription struct {
+	interval time.Duration
+	contents []string
+	scope    DashboardScope
+	lastPush time.Time
+}
+
+// DashboardPusher 按订阅频率和数据变化向客户端推送仪表板统计与最新告警
+type DashboardPusher struct {
+	hub    *Hub
+	source DashboardSource
+	config config.DashboardPushConfig
+	logger *zap.Logger
+
+	notify   chan string
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewDashboardPusher 创建仪表板推送器并挂到集线器上，客户端订阅dashboard频道后生效
+func NewDashboardPusher(hub *Hub, source DashboardSource, cfg config.DashboardPushConfig, logger *zap.Logger) *DashboardPusher {
+	p := &DashboardPusher{
+		hub:    hub,
+		source: source,
+		config: cfg,
+		logger: logger,
+		notify: make(chan string, 1),
+		stop:   make(chan struct{}),
+	}
+	hub.dashboard = p
+	return p
+}
+
+// Start 启动推送循环
+func (p *DashboardPusher) Start() {
+	go p.run()
+}
+
+// Stop 停止推送循环
+func (p *DashboardPusher) Stop() {
+	p.stopOnce.Do(func() { close(p.stop) })
+}
+
+// Notify 通知仪表板数据已变化，最小间隔内的多次变化合并为一次推送
+func (p *DashboardPusher) Notify(trigger string) {
+	select {
+	case p.notify <- trigger:
+	default:
+		// 已有待推送的变化，本次合并
+	}
+}
+
+// run 定时检查到期的订阅，并在数据变化时推送给全部订阅者
+func (p *DashboardPusher) run() {
+	ticker := time.NewTicker(dashboardTickInterval)
+	defer ticker.Stop()
+
+	var (
+		pending   string
+		debounce  <-chan time.Time
+		lastEvent time.Time
+	)
+	for {
+		select {
+		case <-p.stop:
+			return
+
+		case trigger := <-p.notify:
+			if debounce != nil {
+				continue
+			}
+			pending = trigger
+			wait := p.config.MinInterval - time.Since(lastEvent)
+			if wait < 0 {
+				wait = 0
+			}
+			debounce = time.After(wait)
+
+		case <-debounce:
+			debounce = nil
+			lastEvent = time.Now()
+			p.push(pending, lastEvent, true)
+
+		case now := <-ticker.C:
+			p.push(DashboardTriggerInterval, now, false)
+		}
+	}
+}
+
+// push 生成快照并推送，all为true时推送给全部订阅者，否则只推送定时到期的订阅者
+// 快照按订阅者的数据范围分组生成，每个范围只查询一次
+func (p *DashboardPusher) push(trigger string, now time.Time, all bool) {
+	type target struct {
+		client   *Client
+		trigger  string
+		contents []string
+	}
+	type scopeGroup struct {
+		scope   DashboardScope
+		targets []target
+		needed  map[string]bool
+	}
+	groups := make(map[string]*scopeGroup)
+	var order []string
+	clients := 0
+	for _, client := range p.hub.dashboardClients() {
+		sub, ok := client.dashboardSubscription()
+		if !ok {
+			continue
+		}
+		t := target{client: client, trigger: trigger, contents: sub.contents}
+		switch {
+		case sub.lastPush.IsZero():
+			t.trigger = DashboardTriggerSubscribe
+		case !all && now.Sub(sub.lastPush) < sub.interval:
+			continue
+		}
+		key := sub.scope.key()
+		group, ok := groups[key]
+		if !ok {
+			group = &scopeGroup{scope: sub.scope, needed: make(map[string]bool)}
+			groups[key] = group
+			order = append(order, key)
+		}
+		group.targets = append(group.targets, t)
+		for _, content := range sub.contents {
+			group.needed[content] = true
+		}
+		clients++
+	}
+	if clients == 0 {
+		return
+	}
+
+	for _, key := range order {
+		group := groups[key]
+		contents := make([]string, 0, len(group.needed))
+		for _, content := range dashboardContents {
+			if group.needed[content] {
+				contents = append(contents, content)
+			}
+		}
+		snapshot := p.source(group.scope, contents)
+
+		for _, t := range group.targets {
+			update := DashboardUpdate{Trigger: t.trigger, Content: make(map[string]interface{}, len(t.contents))}
+			for _, content := range t.contents {
+				if data, ok := snapshot[content]; ok {
+					update.Content[content] = data
+				}
+			}
+			p.hub.sendTo(t.client, Message{
+				Type:      "dashboard",
+				Data:      update,
+				Timestamp: now,
+				Channel:   DashboardChannel,
+			})
+			t.client.markDashboardPushed(now)
+		}
+	}
+	p.logger.Debug("Dashboard pushed",
+		zap.String("trigger", trigger),
+		zap.Int("clients", clients),
+		zap.Int("scopes", len(groups)))
+}
+
+// newSubscription 规范化订阅参数：间隔不得小于最小间隔，未指定时用默认值；内容项去重并忽略未知项
+func (p *DashboardPusher) newSubscription(scope DashboardScope, interval time.Duration, contents []string) *dashboardSubscription {
+	if interval <= 0 {
+		interval = p.config.DefaultInterval
+	}
+	if interval < p.config.MinInterval {
+		interval = p.config.MinInterval
+	}
+
+	requested := make(map[string]bool, len(contents))
+	for _, content := range contents {
+		requested[strings.TrimSpace(content)] = true
+	}
+	selected := make([]string, 0, len(dashboardContents))
+	for _, content := range dashboardContents {
+		if requested[content] {
+			selected = append(selected, content)
+		}
+	}
+	if len(selected) == 0 {
+		selected = dashboardContents
+	}
+	return &dashboardSubscription{interval: interval, contents: selected, scope: scope}
+}
+
+// parseDashboardInterval 解析订阅间隔，支持秒数或"10s"这样的时长，无法解析时返回0使用默认值
+func parseDashboardInterval(value interface{}) time.Duration {
+	switch v := value.(type) {
+	case float64:
+		return time.Duration(v * float64(time.Second))
+	case string:
+		v = strings.TrimSpace(v)
+		if seconds, err := strconv.ParseFloat(v, 64); err == nil {
+			return time.Duration(seconds * float64(time.Second))
+		}
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	return 0
+}
+
+// parseDashboardContents 解析订阅内容项，支持数组或逗号分隔的字符串
+func parseDashboardContents(value interface{}) []string {
+	var contents []string
+	switch v := value.(type) {
+	case string:
+		contents = append(contents, strings.Split(v, ",")...)
+	case []string:
+		for _, item := range v {
+			contents = append(contents, strings.Split(item, ",")...)
+		}
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				contents = append(contents, s)
+			}
+		}
+	}
+	return contents
+}
+
+// subscribeDashboard 订阅仪表板推送，推送未启用时只登记频道；未登录的连接没有数据范围，不能订阅
+func (c *Client) subscribeDashboard(interval time.Duration, contents []string) {
+	c.mu.RLock()
+	scope := c.scope
+	c.mu.RUnlock()
+	if scope == nil {
+		c.hub.logger.Warn("Dashboard subscription rejected for unauthenticated client", zap.String("client_id", c.id))
+		return
+	}
+
+	c.subscribe(DashboardChannel)
+	if c.hub.dashboard == nil {
+		return
+	}
+	sub := c.hub.dashboard.newSubscription(*scope, interval, contents)
+
+	c.mu.Lock()
+	c.dashboard = sub
+	c.mu.Unlock()
+
+	c.hub.logger.Debug("Client subscribed to dashboard",
+		zap.String("client_id", c.id),
+		zap.Duration("interval", sub.interval),
+		zap.Strings("contents", sub.contents))
+}
+
+// dashboardSubscription 返回客户端仪表板订阅的副本
+func (c *Client) dashboardSubscription() (dashboardSubscription, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.dashboard == nil {
+		return dashboardSubscription{}, false
+	}
+	return *c.dashboard, true
+}
+
+// markDashboardPushed 记录最近一次推送时间
+func (c *Client) markDashboardPushed(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.dashboard != nil {
+		c.dashboard.lastPush = t
+	}
+}
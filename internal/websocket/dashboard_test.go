@@ -0,0 +1,140 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/env-data-platform/internal/config"
+)
+
+// receive 读取客户端发送队列中的下一条仪表板推送
+func receive(t *testing.T, client *Client) DashboardUpdate {
+	select {
+	case message := <-client.send:
+		require.Equal(t, DashboardChannel, message.Channel)
+		return message.Data.(DashboardUpdate)
+	case <-time.After(time.Second):
+		t.Fatal("no dashboard push received")
+		return DashboardUpdate{}
+	}
+}
+
+func TestDashboardPusher(t *testing.T) {
+	hub := NewHub(zap.NewNop())
+	go hub.Run()
+
+	var requested [][]string
+	source := func(scope DashboardScope, contents []string) map[string]interface{} {
+		requested = append(requested, contents)
+		snapshot := make(map[string]interface{})
+		for _, content := range contents {
+			snapshot[content] = content + "-data"
+		}
+		return snapshot
+	}
+	pusher := NewDashboardPusher(hub, source, config.DashboardPushConfig{
+		Enabled: true, DefaultInterval: 30 * time.Second, MinInterval: 5 * time.Second,
+	}, zap.NewNop())
+
+	unrestricted := &DashboardScope{Unrestricted: true}
+	stats := NewClient(hub, nil, "stats")
+	stats.scope = unrestricted
+	stats.subscribeDashboard(time.Second, []string{"core_stats", "unknown"})
+	all := NewClient(hub, nil, "all")
+	all.scope = unrestricted
+	all.subscribeDashboard(0, nil)
+	other := NewClient(hub, nil, "other")
+	other.subscribe("alarm")
+	for _, client := range []*Client{stats, all, other} {
+		hub.register <- client
+	}
+
+	sub, _ := stats.dashboardSubscription()
+	assert.Equal(t, 5*time.Second, sub.interval, "间隔不小于最小间隔")
+	assert.Equal(t, []string{DashboardCoreStats}, sub.contents)
+	sub, _ = all.dashboardSubscription()
+	assert.Equal(t, 30*time.Second, sub.interval)
+	assert.Equal(t, dashboardContents, sub.contents)
+
+	// 首次推送只查询一次快照，按各自订阅的内容下发
+	start := time.Now()
+	pusher.push(DashboardTriggerInterval, start, false)
+	update := receive(t, stats)
+	assert.Equal(t, DashboardTriggerSubscribe, update.Trigger)
+	assert.Equal(t, map[string]interface{}{DashboardCoreStats: "core_stats-data"}, update.Content)
+	assert.Len(t, receive(t, all).Content, 3)
+	assert.Equal(t, [][]string{dashboardContents}, requested)
+
+	// 定时推送只发给到期的订阅
+	pusher.push(DashboardTriggerInterval, start.Add(6*time.Second), false)
+	assert.Equal(t, DashboardTriggerInterval, receive(t, stats).Trigger)
+	assert.Equal(t, []string{DashboardCoreStats}, requested[1])
+
+	// 数据变化推送给全部订阅者
+	pusher.push(DashboardTriggerAlarm, start.Add(7*time.Second), true)
+	assert.Equal(t, DashboardTriggerAlarm, receive(t, stats).Trigger)
+	assert.Equal(t, DashboardTriggerAlarm, receive(t, all).Trigger)
+
+	// 取消订阅后不再推送
+	all.unsubscribe(DashboardChannel)
+	pusher.push(DashboardTriggerAlarm, start.Add(8*time.Second), true)
+	receive(t, stats)
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, all.send)
+	assert.Empty(t, other.send)
+}
+
+func TestDashboardPusherScope(t *testing.T) {
+	hub := NewHub(zap.NewNop())
+	go hub.Run()
+
+	var scopes []string
+	source := func(scope DashboardScope, contents []string) map[string]interface{} {
+		scopes = append(scopes, scope.key())
+		return map[string]interface{}{DashboardLatestAlerts: scope.DeviceIDs}
+	}
+	pusher := NewDashboardPusher(hub, source, config.DashboardPushConfig{
+		Enabled: true, DefaultInterval: 30 * time.Second, MinInterval: 5 * time.Second,
+	}, zap.NewNop())
+
+	newClient := func(id string, scope *DashboardScope) *Client {
+		client := NewClient(hub, nil, id)
+		client.scope = scope
+		client.subscribeDashboard(0, []string{DashboardLatestAlerts})
+		hub.register <- client
+		return client
+	}
+	first := newClient("first", &DashboardScope{DeviceIDs: []string{"MN2", "MN1"}})
+	second := newClient("second", &DashboardScope{DeviceIDs: []string{"MN1", "MN2"}})
+	other := newClient("other", &DashboardScope{DeviceIDs: []string{"MN3"}})
+	anonymous := newClient("anonymous", nil)
+
+	// 未登录的连接不能订阅仪表板
+	_, ok := anonymous.dashboardSubscription()
+	assert.False(t, ok)
+
+	// 同一数据范围只生成一次快照，各订阅者只收到自己范围内的数据
+	pusher.push(DashboardTriggerAlarm, time.Now(), true)
+	assert.Equal(t, []string{"MN2", "MN1"}, receive(t, first).Content[DashboardLatestAlerts])
+	assert.Equal(t, []string{"MN2", "MN1"}, receive(t, second).Content[DashboardLatestAlerts])
+	assert.Equal(t, []string{"MN3"}, receive(t, other).Content[DashboardLatestAlerts])
+	assert.ElementsMatch(t, []string{"MN1,MN2|", "MN3|"}, scopes)
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, anonymous.send)
+}
+
+func TestParseDashboardSubscription(t *testing.T) {
+	assert.Equal(t, 10*time.Second, parseDashboardInterval(float64(10)))
+	assert.Equal(t, 1500*time.Millisecond, parseDashboardInterval("1.5"))
+	assert.Equal(t, time.Minute, parseDashboardInterval("1m"))
+	assert.Zero(t, parseDashboardInterval("soon"))
+	assert.Zero(t, parseDashboardInterval(nil))
+
+	assert.Equal(t, []string{"core_stats", "latest_alerts"}, parseDashboardContents("core_stats,latest_alerts"))
+	assert.Equal(t, []string{"a", "b", "c"}, parseDashboardContents([]string{"a,b", "c"}))
+	assert.Equal(t, []string{"a"}, parseDashboardContents([]interface{}{"a", 1}))
+}
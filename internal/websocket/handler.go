@@ -14,6 +14,9 @@ type Handler struct {
 	hub      *Hub
 	upgrader websocket.Upgrader
 	logger   *zap.Logger
+
+	// resolveScope 解析连接用户的仪表板数据范围，未设置或解析失败时不能订阅仪表板
+	resolveScope func(c *gin.Context) (DashboardScope, bool)
 }
 
 // NewHandler 创建新的WebSocket处理器
@@ -34,6 +37,11 @@ func NewHandler(hub *Hub, logger *zap.Logger) *Handler {
 	}
 }
 
+// SetDashboardScopeResolver 设置连接用户的仪表板数据范围解析函数
+func (h *Handler) SetDashboardScopeResolver(resolve func(c *gin.Context) (DashboardScope, bool)) {
+	h.resolveScope = resolve
+}
+
 // HandleWebSocket 处理WebSocket连接
 func (h *Handler) HandleWebSocket(c *gin.Context) {
 	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
@@ -50,9 +58,18 @@ func (h *Handler) HandleWebSocket(c *gin.Context) {
 
 	// 创建客户端
 	client := NewClient(h.hub, conn, clientID)
+	if h.resolveScope != nil {
+		if scope, ok := h.resolveScope(c); ok {
+			client.scope = &scope
+		}
+	}
 
-	// 订阅指定频道
+	// 订阅指定频道，dashboard频道可通过interval和content指定推送频率和内容
 	for _, channel := range channels {
+		if channel == DashboardChannel {
+			client.subscribeDashboard(parseDashboardInterval(c.Query("interval")), parseDashboardContents(c.QueryArray("content")))
+			continue
+		}
 		client.subscribe(channel)
 	}
 
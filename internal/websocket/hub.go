@@ -20,12 +20,14 @@ type Message struct {
 
 // Client WebSocket客户端
 type Client struct {
-	conn     *websocket.Conn
-	send     chan Message
-	hub      *Hub
-	id       string
-	channels map[string]bool // 订阅的频道
-	mu       sync.RWMutex
+	conn      *websocket.Conn
+	send      chan Message
+	hub       *Hub
+	id        string
+	channels  map[string]bool        // 订阅的频道
+	dashboard *dashboardSubscription // 仪表板推送订阅，未订阅时为nil
+	scope     *DashboardScope        // 连接用户的数据范围，未登录时为nil
+	mu        sync.RWMutex
 }
 
 // Hub WebSocket集线器
@@ -34,10 +36,18 @@ type Hub struct {
 	register   chan *Client
 	unregister chan *Client
 	broadcast  chan Message
+	direct     chan directMessage
+	dashboard  *DashboardPusher // 仪表板推送，未启用时为nil
 	logger     *zap.Logger
 	mu         sync.RWMutex
 }
 
+// directMessage 发给单个客户端的消息
+type directMessage struct {
+	client  *Client
+	message Message
+}
+
 // NewHub 创建新的WebSocket集线器
 func NewHub(logger *zap.Logger) *Hub {
 	return &Hub{
@@ -45,6 +55,7 @@ func NewHub(logger *zap.Logger) *Hub {
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		broadcast:  make(chan Message, 256),
+		direct:     make(chan directMessage, 256),
 		logger:     logger,
 	}
 }
@@ -84,10 +95,45 @@ func (h *Hub) Run() {
 				}
 			}
 			h.mu.RUnlock()
+
+		case dm := <-h.direct:
+			h.mu.RLock()
+			if h.clients[dm.client] {
+				select {
+				case dm.client.send <- dm.message:
+				default:
+					h.logger.Warn("Client send buffer is full, dropping message",
+						zap.String("client_id", dm.client.id))
+				}
+			}
+			h.mu.RUnlock()
 		}
 	}
 }
 
+// sendTo 向单个客户端发送消息，由Run统一投递以免写入已关闭的发送队列
+func (h *Hub) sendTo(client *Client, message Message) {
+	select {
+	case h.direct <- directMessage{client: client, message: message}:
+	default:
+		h.logger.Warn("Direct channel is full, dropping message",
+			zap.String("client_id", client.id))
+	}
+}
+
+// dashboardClients 订阅了仪表板推送的客户端
+func (h *Hub) dashboardClients() []*Client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	clients := make([]*Client, 0)
+	for client := range h.clients {
+		if _, ok := client.dashboardSubscription(); ok {
+			clients = append(clients, client)
+		}
+	}
+	return clients
+}
+
 // BroadcastHJ212Data 广播HJ212数据
 func (h *Hub) BroadcastHJ212Data(data *models.HJ212Data) {
 	message := Message{
@@ -153,6 +199,11 @@ func (h *Hub) BroadcastAlarm(event interface{}) {
 	default:
 		h.logger.Warn("Broadcast channel is full, dropping message")
 	}
+
+	// 新告警触发仪表板推送
+	if h.dashboard != nil {
+		h.dashboard.Notify(DashboardTriggerAlarm)
+	}
 }
 
 // GetConnectedClientsCount 获取连接的客户端数量
@@ -187,6 +238,9 @@ func (c *Client) unsubscribe(channel string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	delete(c.channels, channel)
+	if channel == DashboardChannel {
+		c.dashboard = nil
+	}
 }
 
 // writePump 向WebSocket连接写入消息
@@ -261,6 +315,10 @@ func (c *Client) handleMessage(msg map[string]interface{}) {
 	switch msgType {
 	case "subscribe":
 		if channel, ok := msg["channel"].(string); ok {
+			if channel == DashboardChannel {
+				c.subscribeDashboard(parseDashboardInterval(msg["interval"]), parseDashboardContents(msg["content"]))
+				return
+			}
 			c.subscribe(channel)
 			c.hub.logger.Debug("Client subscribed to channel",
 				zap.String("client_id", c.id),
@@ -86,7 +86,8 @@ const API_CONFIG = {
             BASE: '/dashboard',
             OVERVIEW: '/dashboard/overview',
             REALTIME: '/dashboard/realtime',
-            CHARTS: '/dashboard/charts'
+            CHARTS: '/dashboard/charts',
+            ALERT_ACK: (id) => `/dashboard/alerts/${id}/ack`
         },
 
         // 用户管理
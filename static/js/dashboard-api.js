@@ -50,6 +50,25 @@ class DashboardAPI {
         }
     }
 
+    /**
+     * 确认概览中的告警
+     */
+    async acknowledgeAlert(id, remark = '') {
+        try {
+            const response = await this.api.post(API_CONFIG.ENDPOINTS.DASHBOARD.ALERT_ACK(id), { remark });
+            return {
+                success: true,
+                data: response
+            };
+        } catch (error) {
+            console.error('确认告警失败:', error);
+            return {
+                success: false,
+                message: error.message || '确认告警失败'
+            };
+        }
+    }
+
     /**
      * 获取图表数据
      */
@@ -89,6 +108,34 @@ class DashboardAPI {
         console.log('实时数据更新已启动');
     }
 
+    /**
+     * 通过WebSocket接收仪表板推送，未连接WebSocket时退回轮询
+     * @param {WebSocketClient} wsClient - 已建立连接的WebSocket客户端
+     * @param {Object} options - interval为推送间隔秒数，content为需要的内容项
+     */
+    startRealTimePush(wsClient, options = {}) {
+        if (!wsClient) {
+            this.startRealTimeUpdates((options.interval || 30) * 1000);
+            return;
+        }
+
+        this.stopRealTimeUpdates();
+        wsClient.subscribeDashboard((update) => {
+            const content = update.content || {};
+            if (content.core_stats) {
+                this.emit('realTimeUpdate', content.core_stats);
+            }
+            if (content.latest_alerts) {
+                this.emit('alertsUpdate', content.latest_alerts);
+            }
+            if (content.etl_task_status) {
+                this.emit('etlStatusUpdate', content.etl_task_status);
+            }
+        }, options);
+
+        console.log('仪表板WebSocket推送已订阅');
+    }
+
     /**
      * 停止实时数据更新
      */
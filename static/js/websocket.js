@@ -85,6 +85,16 @@ class WebSocketClient {
         // 触发连接成功事件
         this.emit('connected', { timestamp: new Date() });
 
+        // 重连后恢复仪表板订阅
+        if (this.dashboardOptions) {
+            this.send({
+                type: 'subscribe',
+                channel: 'dashboard',
+                interval: this.dashboardOptions.interval,
+                content: this.dashboardOptions.content
+            });
+        }
+
         // 发送认证信息（如果需要）
         if (this.auth.token) {
             this.send({
@@ -128,6 +138,10 @@ class WebSocketClient {
                     this.handleAlarmNotification(data.data);
                     break;
 
+                case 'dashboard':
+                    this.emit('dashboard', data.data);
+                    break;
+
                 case 'etl_status':
                     this.emit('etl_status', data.data);
                     break;
@@ -214,6 +228,27 @@ class WebSocketClient {
         }
     }
 
+    /**
+     * 订阅仪表板推送
+     * @param {Object} options - interval为推送间隔秒数，content为内容项数组（core_stats、latest_alerts、etl_task_status），留空推送全部
+     */
+    subscribeDashboard(callback, options = {}) {
+        if (!this.subscribers.has('dashboard')) {
+            this.subscribers.set('dashboard', new Set());
+        }
+        this.subscribers.get('dashboard').add(callback);
+        this.dashboardOptions = options;
+
+        if (this.isConnected) {
+            this.send({
+                type: 'subscribe',
+                channel: 'dashboard',
+                interval: options.interval,
+                content: options.content
+            });
+        }
+    }
+
     /**
      * 取消订阅
      */